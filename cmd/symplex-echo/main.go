@@ -0,0 +1,254 @@
+// symplex-echo — reference responder for interoperability tests.
+//
+// symplex-echo runs one agent whose behaviour is fixed, so client
+// implementations in other languages have a stable target:
+//
+//   - Its identity is derived from -seed, so its DID and peer ID are the
+//     same on every run with the same seed.
+//   - It accepts an intent iff every requested capability is exactly (byte
+//     for byte, no aliases) one of -caps, and there is at least one.
+//   - An accepted intent gets WorkflowSteps ["validate", "echo", "respond"];
+//     every reply carries ResponseVector [1, 0, 0, 0] and a TrustDelta of
+//     +0.1 (accepted) or -0.1 (rejected).  Reasons are fixed strings.
+//
+// The same responder is reachable over libp2p and over HTTP:
+//
+//	POST /echo/negotiate   IntentMessage JSON in, NegotiationResponse JSON out
+//	GET  /echo/profile     identity, addresses and the fixed behaviour above
+//
+// and the regular gateway API (see package gateway) is served alongside.
+//
+// Usage:
+//
+//	symplex-echo [-listen MULTIADDR] [-http ADDR] [-caps LIST] [-seed TEXT]
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/gateway"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+// Defaults for the flags.
+const (
+	DefaultListen = "/ip4/127.0.0.1/tcp/4040"
+	DefaultHTTP   = "127.0.0.1:8040"
+	DefaultCaps   = "echo,summarise,translate"
+	DefaultSeed   = "symplex-echo"
+	AgentID       = "symplex-echo"
+)
+
+// Fixed reply contents.
+const (
+	ReasonAccepted = "exact capability match"
+	ReasonRejected = "no exact capability match"
+	TrustAccepted  = float32(0.1)
+	TrustRejected  = float32(-0.1)
+)
+
+var (
+	// Workflow is the WorkflowSteps of every accepted intent.
+	Workflow = []string{"validate", "echo", "respond"}
+	// Vector is the ResponseVector of every reply.
+	Vector = []float32{1, 0, 0, 0}
+)
+
+// maxBodyBytes caps /echo/negotiate request bodies.
+const maxBodyBytes = 1 << 20
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("symplex-echo", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	listen := fs.String("listen", DefaultListen, "libp2p listen multiaddr")
+	httpAddr := fs.String("http", DefaultHTTP, "HTTP address for /echo/* and the gateway API")
+	caps := fs.String("caps", DefaultCaps, "comma-separated capabilities to accept")
+	seed := fs.String("seed", DefaultSeed, "text the agent's key is derived from")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 0 {
+		fmt.Fprintln(stderr, "usage: symplex-echo [-listen MULTIADDR] [-http ADDR] [-caps LIST] [-seed TEXT]")
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := serve(ctx, *listen, *httpAddr, splitList(*caps), *seed, stdout); err != nil {
+		fmt.Fprintf(stderr, "symplex-echo: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// serve runs the responder until ctx is cancelled.
+func serve(ctx context.Context, listen, httpAddr string, caps []string, seed string, stdout io.Writer) error {
+	agent, err := newEchoAgent(seed, caps)
+	if err != nil {
+		return err
+	}
+	host, err := p2p.NewHost(ctx, agent, p2p.WithListenAddrs(listen))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = host.Close() }()
+	host.OnIntent(func(_ peer.ID, intent *core.IntentMessage) *core.NegotiationResponse {
+		return respond(agent, intent)
+	})
+
+	var addrs []string
+	for _, a := range host.AddrInfo().Addrs {
+		addrs = append(addrs, fmt.Sprintf("%s/p2p/%s", a, host.PeerID()))
+	}
+	e := &echoServer{agent: agent, peerID: host.PeerID().String(), addrs: addrs}
+
+	ln, err := net.Listen("tcp", httpAddr)
+	if err != nil {
+		return fmt.Errorf("http: %w", err)
+	}
+	srv := &http.Server{Handler: e.handler(gateway.New(host)), ReadHeaderTimeout: 10 * time.Second}
+	served := make(chan error, 1)
+	go func() { served <- srv.Serve(ln) }()
+
+	fmt.Fprintf(stdout, "agent %s (%s)\n", agent.ID, agent.DID)
+	for _, a := range addrs {
+		fmt.Fprintf(stdout, "listening on %s\n", a)
+	}
+	fmt.Fprintf(stdout, "http on http://%s\n", ln.Addr())
+
+	select {
+	case <-ctx.Done():
+		_ = srv.Close()
+		return nil
+	case err = <-served:
+		return fmt.Errorf("http: %w", err)
+	}
+}
+
+// newEchoAgent derives the agent's key from seed.
+func newEchoAgent(seed string, caps []string) (*core.Agent, error) {
+	sum := sha256.Sum256([]byte(seed))
+	return core.NewAgentFromKey(AgentID, caps, ed25519.NewKeyFromSeed(sum[:]))
+}
+
+// respond is the fixed negotiation behaviour described in the package
+// comment.
+func respond(agent *core.Agent, intent *core.IntentMessage) *core.NegotiationResponse {
+	accepted := len(intent.Capabilities) > 0
+	for _, c := range intent.Capabilities {
+		if !contains(agent.Capabilities, c) {
+			accepted = false
+			break
+		}
+	}
+	resp := &core.NegotiationResponse{
+		RequestID:      intent.ID,
+		AgentID:        agent.ID,
+		Accepted:       accepted,
+		DID:            agent.DID.String(),
+		ResponseVector: append([]float32(nil), Vector...),
+		Timestamp:      time.Now().UnixNano(),
+		Reason:         ReasonRejected,
+		TrustDelta:     TrustRejected,
+	}
+	if accepted {
+		resp.WorkflowSteps = append([]string(nil), Workflow...)
+		resp.Reason = ReasonAccepted
+		resp.TrustDelta = TrustAccepted
+	}
+	_ = core.SignResponse(agent, resp)
+	return resp
+}
+
+// echoServer serves the /echo/* endpoints.
+type echoServer struct {
+	agent  *core.Agent
+	peerID string
+	addrs  []string
+}
+
+// Profile is the body of GET /echo/profile.
+type Profile struct {
+	AgentID        string    `json:"agent_id"`
+	DID            string    `json:"did"`
+	PeerID         string    `json:"peer_id"`
+	Addrs          []string  `json:"addrs"`
+	Capabilities   []string  `json:"capabilities"`
+	WorkflowSteps  []string  `json:"workflow_steps"`
+	ResponseVector []float32 `json:"response_vector"`
+}
+
+// handler routes /echo/* to e and everything else to gw.
+func (e *echoServer) handler(gw http.Handler) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /echo/negotiate", e.handleNegotiate)
+	mux.HandleFunc("GET /echo/profile", e.handleProfile)
+	mux.Handle("/", gw)
+	return mux
+}
+
+func (e *echoServer) handleNegotiate(w http.ResponseWriter, r *http.Request) {
+	var intent core.IntentMessage
+	dec := json.NewDecoder(io.LimitReader(r.Body, maxBodyBytes))
+	if err := dec.Decode(&intent); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid intent: " + err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, respond(e.agent, &intent))
+}
+
+func (e *echoServer) handleProfile(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, Profile{
+		AgentID:        e.agent.ID,
+		DID:            e.agent.DID.String(),
+		PeerID:         e.peerID,
+		Addrs:          e.addrs,
+		Capabilities:   e.agent.Capabilities,
+		WorkflowSteps:  Workflow,
+		ResponseVector: Vector,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func TestEchoIdentityIsStable(t *testing.T) {
+	a, err := newEchoAgent(DefaultSeed, splitList(DefaultCaps))
+	if err != nil {
+		t.Fatalf("newEchoAgent: %v", err)
+	}
+	b, _ := newEchoAgent(DefaultSeed, nil)
+	c, _ := newEchoAgent("other", nil)
+	if a.DID.String() != b.DID.String() {
+		t.Errorf("same seed, different DIDs: %s %s", a.DID, b.DID)
+	}
+	if a.DID.String() == c.DID.String() {
+		t.Error("different seeds, same DID")
+	}
+}
+
+func TestRespondExactMatch(t *testing.T) {
+	agent, _ := newEchoAgent(DefaultSeed, []string{"echo", "translate"})
+	cases := []struct {
+		caps []string
+		want bool
+	}{
+		{[]string{"echo"}, true},
+		{[]string{"echo", "translate"}, true},
+		{[]string{"Echo"}, false},
+		{[]string{"echo", "ocr"}, false},
+		{nil, false},
+	}
+	for _, c := range cases {
+		resp := respond(agent, &core.IntentMessage{ID: "i-1", Capabilities: c.caps})
+		if resp.Accepted != c.want {
+			t.Errorf("%v: accepted=%v want %v", c.caps, resp.Accepted, c.want)
+		}
+		if !reflect.DeepEqual(resp.ResponseVector, Vector) {
+			t.Errorf("%v: vector %v", c.caps, resp.ResponseVector)
+		}
+		if len(resp.Signature) == 0 || !core.VerifyResponseSignature(resp, agent.PublicKey()) {
+			t.Errorf("%v: bad signature", c.caps)
+		}
+		if c.want && (!reflect.DeepEqual(resp.WorkflowSteps, Workflow) || resp.Reason != ReasonAccepted || resp.TrustDelta != TrustAccepted) {
+			t.Errorf("%v: accepted reply %+v", c.caps, resp)
+		}
+		if !c.want && (len(resp.WorkflowSteps) != 0 || resp.Reason != ReasonRejected || resp.TrustDelta != TrustRejected) {
+			t.Errorf("%v: rejected reply %+v", c.caps, resp)
+		}
+	}
+}
+
+func TestEchoHTTP(t *testing.T) {
+	agent, _ := newEchoAgent(DefaultSeed, []string{"echo"})
+	e := &echoServer{agent: agent, peerID: "12D3KooWtest", addrs: []string{"/ip4/127.0.0.1/tcp/4040/p2p/12D3KooWtest"}}
+	srv := httptest.NewServer(e.handler(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})))
+	defer srv.Close()
+
+	res, err := http.Post(srv.URL+"/echo/negotiate", "application/json", strings.NewReader(`{"id":"i-2","capabilities":["echo"]}`))
+	if err != nil {
+		t.Fatalf("POST /echo/negotiate: %v", err)
+	}
+	var resp core.NegotiationResponse
+	err = json.NewDecoder(res.Body).Decode(&resp)
+	res.Body.Close()
+	if err != nil || !resp.Accepted || resp.RequestID != "i-2" {
+		t.Errorf("negotiate: %+v, %v", resp, err)
+	}
+
+	res, err = http.Get(srv.URL + "/echo/profile")
+	if err != nil {
+		t.Fatalf("GET /echo/profile: %v", err)
+	}
+	var p Profile
+	err = json.NewDecoder(res.Body).Decode(&p)
+	res.Body.Close()
+	if err != nil || p.DID != agent.DID.String() || p.PeerID != "12D3KooWtest" {
+		t.Errorf("profile: %+v, %v", p, err)
+	}
+
+	res, err = http.Get(srv.URL + "/peers")
+	if err != nil {
+		t.Fatalf("GET /peers: %v", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusTeapot {
+		t.Errorf("/peers not routed to the gateway: %d", res.StatusCode)
+	}
+}
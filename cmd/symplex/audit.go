@@ -0,0 +1,52 @@
+package main
+
+// audit.go — `symplex audit verify`: check audit logs for tampering.
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func runAudit(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] != "verify" {
+		fmt.Fprintln(stderr, "usage: symplex audit verify [flags] AUDIT_LOG...")
+		return 2
+	}
+	fs := flag.NewFlagSet("audit verify", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	did := fs.String("did", "", "require every log to be signed by `DID`")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: symplex audit verify [flags] AUDIT_LOG...")
+		fmt.Fprintln(stderr, "AUDIT_LOG files are written by agents (see the daemon's audit setting).")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	code := 0
+	for _, path := range fs.Args() {
+		signer, records, err := core.VerifyAuditLog(path)
+		if err == nil && *did != "" && len(records) > 0 && signer != *did {
+			err = fmt.Errorf("signed by %s, not %s", signer, *did)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "symplex audit: %s: %v\n", path, err)
+			code = 1
+			continue
+		}
+		if len(records) == 0 {
+			fmt.Fprintf(stdout, "%s: ok, empty\n", path)
+			continue
+		}
+		fmt.Fprintf(stdout, "%s: ok, %d records by %s, head %x\n", path, len(records), signer, records[len(records)-1].Hash)
+	}
+	return code
+}
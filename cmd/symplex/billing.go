@@ -0,0 +1,86 @@
+package main
+
+// billing.go — `symplex billing report`: settle usage from receipt logs.
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func runBilling(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] != "report" {
+		fmt.Fprintln(stderr, "usage: symplex billing report [flags] RECEIPTS...")
+		return 2
+	}
+	fs := flag.NewFlagSet("billing report", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	from := fs.String("from", "", "first `day` (YYYY-MM-DD) or RFC 3339 time included")
+	to := fs.String("to", "", "first `day` (YYYY-MM-DD) or RFC 3339 time excluded")
+	format := fs.String("format", "csv", "output `format`: csv or json")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: symplex billing report [flags] RECEIPTS...")
+		fmt.Fprintln(stderr, "RECEIPTS are receipt logs written by agents (see the daemon's receipts setting).")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 || (*format != "csv" && *format != "json") {
+		fs.Usage()
+		return 2
+	}
+	start, err := parseReportTime(*from)
+	if err != nil {
+		fmt.Fprintf(stderr, "symplex billing: -from: %v\n", err)
+		return 2
+	}
+	end, err := parseReportTime(*to)
+	if err != nil {
+		fmt.Fprintf(stderr, "symplex billing: -to: %v\n", err)
+		return 2
+	}
+
+	var receipts []*core.CompletionReceipt
+	for _, path := range fs.Args() {
+		rs, err := core.ReadReceipts(path)
+		if err != nil {
+			fmt.Fprintf(stderr, "symplex billing: %v\n", err)
+			return 1
+		}
+		receipts = append(receipts, rs...)
+	}
+	rep := core.BuildBillingReport(receipts, start, end)
+
+	if *format == "json" {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(rep)
+	} else {
+		err = rep.WriteCSV(stdout)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "symplex billing: %v\n", err)
+		return 1
+	}
+	for _, r := range rep.Rejected {
+		fmt.Fprintf(stderr, "symplex billing: rejected receipt %s from %s: %s\n", r.IntentID, r.Provider, r.Reason)
+	}
+	return 0
+}
+
+// parseReportTime accepts a date (UTC midnight) or an RFC 3339 time.  An
+// empty string is the zero time, an open end of the range.
+func parseReportTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
@@ -0,0 +1,397 @@
+package main
+
+// client.go — `symplex send-intent`, `symplex peers`, `symplex handshake`,
+// `symplex topology`, `symplex quarantine`, `symplex events`: operator
+// commands that talk to a running daemon's control API.
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/gateway"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+// controlClient calls the gateway API of a daemon.
+type controlClient struct {
+	base  string
+	token string
+	http  *http.Client
+}
+
+// controlFlags registers the flags shared by every client subcommand.
+func controlFlags(fs *flag.FlagSet) func() *controlClient {
+	addr := fs.String("addr", DefaultControlAddr, "daemon control `address`")
+	token := fs.String("token", os.Getenv("SYMPLEX_TOKEN"), "control API bearer token (default $SYMPLEX_TOKEN)")
+	return func() *controlClient {
+		base := *addr
+		if !strings.Contains(base, "://") {
+			base = "http://" + base
+		}
+		return &controlClient{
+			base:  strings.TrimSuffix(base, "/"),
+			token: *token,
+			http:  &http.Client{Timeout: gateway.DefaultRequestTimeout + 5*time.Second},
+		}
+	}
+}
+
+// do sends body (if non-nil) as JSON and decodes the response into out.
+func (c *controlClient) do(method, path string, body, out interface{}) error {
+	var rd io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		rd = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, c.base+path, rd)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return statusError(resp)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// stream GETs path and returns the response body, which the daemon keeps
+// writing to until it or the caller closes it.
+func (c *controlClient) stream(path string) (io.ReadCloser, error) {
+	req, err := http.NewRequest(http.MethodGet, c.base+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	// No timeout: the stream is meant to stay open.
+	resp, err := (&http.Client{Transport: c.http.Transport}).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer func() { _ = resp.Body.Close() }()
+		return nil, statusError(resp)
+	}
+	return resp.Body, nil
+}
+
+// statusError describes a failed API call by its status and, if the body
+// has one, its error message.
+func statusError(resp *http.Response) error {
+	var e struct {
+		Error string `json:"error"`
+	}
+	if json.NewDecoder(resp.Body).Decode(&e) == nil && e.Error != "" {
+		return fmt.Errorf("%s: %s", resp.Status, e.Error)
+	}
+	return fmt.Errorf("%s", resp.Status)
+}
+
+// listFlag collects a repeatable, comma-separated flag.
+type listFlag []string
+
+func (l *listFlag) String() string { return strings.Join(*l, ",") }
+
+func (l *listFlag) Set(v string) error {
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			*l = append(*l, s)
+		}
+	}
+	return nil
+}
+
+func runSendIntent(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("send-intent", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	client := controlFlags(fs)
+	var caps listFlag
+	fs.Var(&caps, "cap", "required `capability` (repeatable or comma-separated)")
+	peerID := fs.String("peer", "", "send to this `peer ID` instead of routing by capability")
+	ttl := fs.Duration("ttl", 0, "intent time-to-live")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: symplex send-intent -cap CAPABILITY [flags] [PAYLOAD]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if len(caps) == 0 || fs.NArg() > 1 {
+		fs.Usage()
+		return 2
+	}
+	req := gateway.IntentRequest{
+		PeerID:       *peerID,
+		Capabilities: caps,
+		Payload:      fs.Arg(0),
+		TTLSeconds:   int64(ttl.Seconds()),
+	}
+	var res gateway.IntentResult
+	if err := client().do(http.MethodPost, "/intents", req, &res); err != nil {
+		fmt.Fprintf(stderr, "symplex send-intent: %v\n", err)
+		return 1
+	}
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(res)
+	if res.Response == nil || !res.Response.Accepted {
+		return 1
+	}
+	return 0
+}
+
+func runPeers(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("peers", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	client := controlFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: symplex peers [flags]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	var peers []gateway.Peer
+	if err := client().do(http.MethodGet, "/peers", nil, &peers); err != nil {
+		fmt.Fprintf(stderr, "symplex peers: %v\n", err)
+		return 1
+	}
+	tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PEER\tAGENT\tTRUST\tCAPABILITIES")
+	for _, p := range peers {
+		fmt.Fprintf(tw, "%s\t%s\t%.2f\t%s\n", p.PeerID, p.AgentID, p.Trust, strings.Join(p.Capabilities, ","))
+	}
+	_ = tw.Flush()
+	return 0
+}
+
+func runHandshake(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("handshake", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	client := controlFlags(fs)
+	var addrs listFlag
+	fs.Var(&addrs, "peer-addr", "peer `multiaddr`, if the daemon cannot find it (repeatable)")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: symplex handshake [flags] PEER_ID")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	var res gateway.HandshakeResult
+	body := gateway.HandshakeRequest{Addrs: addrs}
+	if err := client().do(http.MethodPost, "/handshake/"+fs.Arg(0), body, &res); err != nil {
+		fmt.Fprintf(stderr, "symplex handshake: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "%s is %s (%s), protocol %s\ncapabilities: %s\n",
+		res.PeerID, res.AgentID, res.DID, res.ProtocolVersion, strings.Join(res.Capabilities, ", "))
+	return 0
+}
+
+func runTopology(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("topology", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	client := controlFlags(fs)
+	asJSON := fs.Bool("json", false, "print the raw topology as JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: symplex topology [flags]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	var topo p2p.Topology
+	if err := client().do(http.MethodGet, "/topology", nil, &topo); err != nil {
+		fmt.Fprintf(stderr, "symplex topology: %v\n", err)
+		return 1
+	}
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(topo)
+		return 0
+	}
+
+	tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	if topo.Mesh != "" {
+		fmt.Fprintf(tw, "mesh %s\n\n", topo.Mesh)
+	}
+	fmt.Fprintln(tw, "PEER\tAGENT\tSTATE\tCAPABILITIES")
+	for _, n := range topo.Nodes {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", n.PeerID, n.AgentID, nodeState(n), strings.Join(n.Capabilities, ","))
+	}
+	fmt.Fprintln(tw, "\nFROM\tTO\tDIRECTION\tSTREAMS\tADDR")
+	for _, l := range topo.Links {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\n", l.From, l.To, l.Direction, l.Streams, l.Addr)
+	}
+	fmt.Fprintln(tw, "\nTRUST FROM\tTO\tSCORE")
+	for _, e := range topo.Trust {
+		fmt.Fprintf(tw, "%s\t%s\t%.2f\n", e.From, e.To, e.Score)
+	}
+	_ = tw.Flush()
+	return 0
+}
+
+func runQuarantine(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("quarantine", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	client := controlFlags(fs)
+	release := fs.String("release", "", "lift the quarantine of this `peer ID`")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: symplex quarantine [-release PEER] [flags]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	method, path := http.MethodGet, "/quarantine"
+	if *release != "" {
+		method, path = http.MethodDelete, "/quarantine/"+url.PathEscape(*release)
+	}
+	var entries []core.QuarantineEntry
+	if err := client().do(method, path, nil, &entries); err != nil {
+		fmt.Fprintf(stderr, "symplex quarantine: %v\n", err)
+		return 1
+	}
+	if *release != "" {
+		fmt.Fprintf(stdout, "released %s\n", *release)
+	}
+	if len(entries) == 0 {
+		fmt.Fprintln(stdout, "no peer has errors or is quarantined")
+		return 0
+	}
+	tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PEER\tERRORS\tQUARANTINES\tUNTIL")
+	for _, e := range entries {
+		kinds := make([]string, 0, len(e.Errors))
+		for k, n := range e.Errors {
+			kinds = append(kinds, fmt.Sprintf("%s=%d", k, n))
+		}
+		sort.Strings(kinds)
+		until := "-"
+		if !e.Until.IsZero() {
+			until = e.Until.Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\n", e.Key, strings.Join(kinds, ","), e.Quarantines, until)
+	}
+	_ = tw.Flush()
+	return 0
+}
+
+func runEvents(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("events", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	client := controlFlags(fs)
+	var kinds listFlag
+	fs.Var(&kinds, "kind", "only events of this `kind` (repeatable or comma-separated)")
+	asJSON := fs.Bool("json", false, "print each event as a JSON line")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: symplex events [-kind KIND] [flags]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	path := "/events"
+	if len(kinds) > 0 {
+		path += "?kind=" + url.QueryEscape(kinds.String())
+	}
+	body, err := client().stream(path)
+	if err != nil {
+		fmt.Fprintf(stderr, "symplex events: %v\n", err)
+		return 1
+	}
+	defer func() { _ = body.Close() }()
+
+	sc := bufio.NewScanner(body)
+	for sc.Scan() {
+		data, ok := strings.CutPrefix(sc.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		if *asJSON {
+			fmt.Fprintln(stdout, data)
+			continue
+		}
+		var ev gateway.EventRecord
+		if err = json.Unmarshal([]byte(data), &ev); err != nil {
+			fmt.Fprintf(stderr, "symplex events: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(stdout, "%s  %-20s %s\n", ev.Timestamp.Format(time.TimeOnly), ev.Kind, eventDetails(ev))
+	}
+	if err = sc.Err(); err != nil {
+		fmt.Fprintf(stderr, "symplex events: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// eventDetails lists an event's non-empty fields as key=value pairs.
+func eventDetails(ev gateway.EventRecord) string {
+	var s []string
+	for _, f := range []struct{ key, value string }{
+		{"peer", ev.PeerID},
+		{"agent", ev.AgentID},
+		{"did", ev.DID},
+		{"intent", ev.IntentID},
+		{"caps", strings.Join(ev.Capabilities, ",")},
+		{"reason", ev.Reason},
+	} {
+		if f.value != "" {
+			s = append(s, f.key+"="+f.value)
+		}
+	}
+	if ev.Kind == p2p.EventTrustUpdated || ev.Kind == p2p.EventTrustAttested {
+		s = append(s, fmt.Sprintf("delta=%+.2f trust=%.2f", ev.Delta, ev.Trust))
+	}
+	return strings.Join(s, " ")
+}
+
+// nodeState summarises a topology node's flags, e.g. "connected,managed".
+func nodeState(n p2p.TopologyNode) string {
+	var s []string
+	for _, f := range []struct {
+		on   bool
+		name string
+	}{{n.Self, "self"}, {n.Handshaked, "handshaked"}, {n.Connected, "connected"}, {n.Managed, "managed"}} {
+		if f.on {
+			s = append(s, f.name)
+		}
+	}
+	if len(s) == 0 {
+		return "-"
+	}
+	return strings.Join(s, ",")
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+// AgentConfig describes one agent of a deployment.
+type AgentConfig struct {
+	ID           string                      `json:"id"`
+	Capabilities []string                    `json:"capabilities"`
+	Manifest     []core.CapabilityDescriptor `json:"manifest,omitempty"`
+	Specs        []core.CapabilitySpec       `json:"specs,omitempty"`
+	EmbeddingDim int                         `json:"embedding_dim,omitempty"` // Length of the agent's intent vectors
+}
+
+// StepTemplate is one step of a WorkflowTemplate.
+type StepTemplate struct {
+	ID           string        `json:"id"`
+	Capability   string        `json:"capability"`
+	Payload      string        `json:"payload,omitempty"`
+	IntentVector []float32     `json:"intent_vector,omitempty"`
+	DependsOn    []string      `json:"depends_on,omitempty"`
+	NextStepID   string        `json:"next_step_id,omitempty"`
+	Compensate   *StepTemplate `json:"compensate,omitempty"`
+}
+
+// WorkflowTemplate is a workflow as deployed: a sequential pipeline, or a
+// graph if any step has depends_on.
+type WorkflowTemplate struct {
+	ID    string         `json:"id"`
+	Steps []StepTemplate `json:"steps"`
+}
+
+// IsGraph reports whether w is run as a WorkflowGraph.
+func (w WorkflowTemplate) IsGraph() bool {
+	for _, s := range w.Steps {
+		if len(s.DependsOn) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// WorkflowSteps converts w to orchestrator steps.
+func (w WorkflowTemplate) WorkflowSteps() []p2p.WorkflowStep {
+	out := make([]p2p.WorkflowStep, len(w.Steps))
+	for i, s := range w.Steps {
+		out[i] = s.workflowStep()
+	}
+	return out
+}
+
+func (s StepTemplate) workflowStep() p2p.WorkflowStep {
+	ws := p2p.WorkflowStep{
+		ID:           s.ID,
+		Capability:   s.Capability,
+		IntentVector: s.IntentVector,
+		Payload:      s.Payload,
+		NextStepID:   s.NextStepID,
+		DependsOn:    s.DependsOn,
+	}
+	if s.Compensate != nil {
+		c := s.Compensate.workflowStep()
+		ws.Compensate = &c
+	}
+	return ws
+}
+
+// ConfigFile is the contents of one configuration file.  Every section is
+// optional, so a deployment may keep each agent, the alias table and the
+// workflows in separate files.
+type ConfigFile struct {
+	Agent     *AgentConfig           `json:"agent,omitempty"`
+	Agents    []AgentConfig          `json:"agents,omitempty"`
+	Aliases   []core.CapabilityAlias `json:"aliases,omitempty"`
+	Workflows []WorkflowTemplate     `json:"workflows,omitempty"`
+}
+
+// AllAgents returns the agents defined in f.
+func (f *ConfigFile) AllAgents() []AgentConfig {
+	if f.Agent == nil {
+		return f.Agents
+	}
+	return append([]AgentConfig{*f.Agent}, f.Agents...)
+}
+
+// LoadConfigFile reads a JSON configuration file.  Unknown fields are
+// rejected so typos do not silently disable settings.
+func LoadConfigFile(path string) (*ConfigFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var f ConfigFile
+	if err = dec.Decode(&f); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &f, nil
+}
@@ -0,0 +1,308 @@
+package main
+
+// daemon.go — `symplex daemon`: a long-lived agent run from a config file.
+//
+// The daemon stays connected to its bootstrap peers and serves the
+// gateway API on its control address, which the send-intent, peers and
+// handshake subcommands talk to.
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/gateway"
+	"github.com/olserra/agent-semantic-protocol/internal/yamlconf"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+// DefaultControlAddr is where the daemon serves the gateway API, and where
+// the operator subcommands look for it, unless configured otherwise.
+const DefaultControlAddr = "127.0.0.1:7070"
+
+// drainTimeout bounds how long a stopping daemon waits for intents it is
+// still serving.
+const drainTimeout = 10 * time.Second
+
+// DaemonConfig configures `symplex daemon`.
+type DaemonConfig struct {
+	ID           string   `json:"id"`
+	Capabilities []string `json:"capabilities"`
+	// Identity is the agent's key file, created on first start.  The
+	// passphrase for an encrypted key is read from SYMPLEX_PASSPHRASE.
+	// Without it the agent gets a fresh identity on every start.
+	Identity   string   `json:"identity,omitempty"`
+	Mesh       string   `json:"mesh,omitempty"`      // Only peers in the same mesh are reachable
+	Listen     []string `json:"listen,omitempty"`    // libp2p multiaddrs
+	Bootstrap  []string `json:"bootstrap,omitempty"` // multiaddrs ending in /p2p/<peer ID>
+	TrustStore string   `json:"trust_store,omitempty"`
+	// Discovery is a file the agent saves the peers it has discovered to
+	// every minute and on shutdown, and restores them from on start.
+	Discovery string `json:"discovery,omitempty"`
+	DHT       bool   `json:"dht,omitempty"`
+	// Receipts is a file the agent appends a signed receipt to for every
+	// intent it accepts, priced by Prices (keyed by capability).  See
+	// `symplex billing report`.
+	Receipts string               `json:"receipts,omitempty"`
+	Prices   map[string]core.Cost `json:"prices,omitempty"`
+	// TrustPolicy names the built-in policy ("default", "conservative",
+	// "aggressive" or "outcome-weighted") the agent adjusts its trust in
+	// peers by; TrustPolicies overrides it per capability.
+	TrustPolicy   string            `json:"trust_policy,omitempty"`
+	TrustPolicies map[string]string `json:"trust_policies,omitempty"`
+	// Audit is a file the agent appends a signed, hash-chained record to
+	// for every message it processes.  See `symplex audit verify`.
+	Audit string `json:"audit,omitempty"`
+	// Observers lists the DIDs of monitoring agents granted the observer
+	// role over libp2p (p2p.WithObservers): they may stream the agent's
+	// events but not send it intents.
+	Observers []string `json:"observers,omitempty"`
+	// Control is the gateway's TCP address; ControlToken, if set, is
+	// required as a bearer token.  ObserverToken grants monitoring tools
+	// that speak HTTP read-only access, including `symplex events`, but not
+	// the right to send intents.
+	Control       string `json:"control,omitempty"`
+	ControlToken  string `json:"control_token,omitempty"`
+	ObserverToken string `json:"observer_token,omitempty"`
+	// Metrics, if set, is a TCP address serving Prometheus metrics at
+	// /metrics.
+	Metrics string `json:"metrics,omitempty"`
+}
+
+// LoadDaemonConfig reads a daemon config, as YAML if path ends in .yaml or
+// .yml and as JSON otherwise.  Unknown fields are rejected.
+func LoadDaemonConfig(path string) (*DaemonConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg DaemonConfig
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		err = yamlconf.Unmarshal(data, &cfg)
+	default:
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&cfg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if cfg.ID == "" {
+		return nil, fmt.Errorf("%s: id is required", path)
+	}
+	if cfg.Control == "" {
+		cfg.Control = DefaultControlAddr
+	}
+	return &cfg, nil
+}
+
+// price charges the configured price of the intent's first capability,
+// or nothing if it has none.
+func (c *DaemonConfig) price(intent *core.IntentMessage, _ *core.NegotiationResponse) (core.Cost, bool) {
+	if len(intent.Capabilities) == 0 {
+		return core.Cost{}, true
+	}
+	return c.Prices[intent.Capabilities[0]], true
+}
+
+// trustPolicyOptions resolves TrustPolicy and TrustPolicies.
+func (c *DaemonConfig) trustPolicyOptions() ([]p2p.HostOption, error) {
+	var opts []p2p.HostOption
+	if c.TrustPolicy != "" {
+		p, err := core.TrustPolicyByName(c.TrustPolicy)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, p2p.WithTrustPolicy(p))
+	}
+	for capability, name := range c.TrustPolicies {
+		p, err := core.TrustPolicyByName(name)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", capability, err)
+		}
+		opts = append(opts, p2p.WithCapabilityTrustPolicy(capability, p))
+	}
+	return opts, nil
+}
+
+// bootstrapPeers parses the Bootstrap addresses.
+func (c *DaemonConfig) bootstrapPeers() ([]peer.AddrInfo, error) {
+	out := make([]peer.AddrInfo, 0, len(c.Bootstrap))
+	for _, s := range c.Bootstrap {
+		info, err := peer.AddrInfoFromString(s)
+		if err != nil {
+			return nil, fmt.Errorf("bootstrap %q: %w", s, err)
+		}
+		out = append(out, *info)
+	}
+	return out, nil
+}
+
+func runDaemon(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("daemon", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: symplex daemon CONFIG")
+		fmt.Fprintln(stderr, "CONFIG is a .yaml or .json daemon config.")
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	cfg, err := LoadDaemonConfig(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "symplex daemon: %v\n", err)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err = serveDaemon(ctx, cfg, stdout, stderr); err != nil {
+		fmt.Fprintf(stderr, "symplex daemon: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// serveDaemon runs the agent described by cfg until ctx is cancelled.
+func serveDaemon(ctx context.Context, cfg *DaemonConfig, stdout, stderr io.Writer) error {
+	bootstrap, err := cfg.bootstrapPeers()
+	if err != nil {
+		return err
+	}
+	trustOpts, err := cfg.trustPolicyOptions()
+	if err != nil {
+		return err
+	}
+	var agent *core.Agent
+	if cfg.Identity != "" {
+		agent, err = core.LoadOrCreateAgent(cfg.Identity, cfg.ID, cfg.Capabilities, []byte(os.Getenv("SYMPLEX_PASSPHRASE")))
+	} else {
+		agent, err = core.NewAgent(cfg.ID, cfg.Capabilities)
+	}
+	if err != nil {
+		return err
+	}
+
+	opts := []p2p.HostOption{
+		p2p.WithListenAddrs(cfg.Listen...),
+		p2p.WithMesh(cfg.Mesh),
+		p2p.WithCapabilityAnalytics(nil),
+		p2p.WithPeerManager(p2p.PeerManagerConfig{
+			Bootstrap: bootstrap,
+			OnEvent: func(ev p2p.PeerEvent) {
+				if ev.Err != nil {
+					fmt.Fprintf(stderr, "symplex daemon: peer %s: %s (attempt %d): %v\n", ev.PeerID, ev.Kind, ev.Attempt, ev.Err)
+				} else {
+					fmt.Fprintf(stdout, "peer %s (%s) %s\n", ev.PeerID, ev.AgentID, ev.Kind)
+				}
+			},
+		}),
+	}
+	opts = append(opts, trustOpts...)
+	if cfg.TrustStore != "" {
+		store, err := core.OpenFileTrustStore(cfg.TrustStore)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, p2p.WithTrustStore(store))
+	}
+	if cfg.Discovery != "" {
+		opts = append(opts, p2p.WithDiscoveryFile(cfg.Discovery, 0))
+	}
+	if cfg.DHT {
+		opts = append(opts, p2p.WithDHT(bootstrap...))
+	}
+	if cfg.Receipts != "" {
+		log, err := core.OpenReceiptLog(cfg.Receipts)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = log.Close() }()
+		opts = append(opts, p2p.WithReceipts(log, cfg.price))
+	}
+	if cfg.Audit != "" {
+		audit, err := core.OpenAuditLog(cfg.Audit, agent)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = audit.Close() }()
+		opts = append(opts, p2p.WithAuditLog(audit))
+	}
+	if cfg.Metrics != "" {
+		opts = append(opts, p2p.WithMetrics(nil))
+	}
+	if len(cfg.Observers) > 0 {
+		opts = append(opts, p2p.WithObservers(cfg.Observers...))
+	}
+	host, err := p2p.NewHost(ctx, agent, opts...)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = host.Close() }()
+
+	if cfg.DHT {
+		if err = host.AdvertiseCapabilities(ctx); err != nil {
+			fmt.Fprintf(stderr, "symplex daemon: advertise: %v\n", err)
+		}
+	}
+
+	ln, err := net.Listen("tcp", cfg.Control)
+	if err != nil {
+		return fmt.Errorf("control: %w", err)
+	}
+	srv := &http.Server{Handler: gateway.New(host,
+		gateway.WithToken(cfg.ControlToken),
+		gateway.WithObserverToken(cfg.ObserverToken),
+	)}
+	served := make(chan error, 1)
+	go func() { served <- srv.Serve(ln) }()
+
+	fmt.Fprintf(stdout, "agent %s (%s)\n", agent.ID, agent.DID)
+	for _, a := range host.AddrInfo().Addrs {
+		fmt.Fprintf(stdout, "listening on %s/p2p/%s\n", a, host.PeerID())
+	}
+	fmt.Fprintf(stdout, "control API on http://%s\n", ln.Addr())
+	if cfg.Metrics != "" {
+		addr, err := host.ServeMetrics(cfg.Metrics)
+		if err != nil {
+			_ = srv.Close()
+			return err
+		}
+		fmt.Fprintf(stdout, "metrics on http://%s/metrics\n", addr)
+	}
+
+	select {
+	case <-ctx.Done():
+		_ = srv.Close()
+		// Refuse new intents and let running ones finish before exiting.
+		drainCtx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+		defer cancel()
+		if err = host.Drain(drainCtx); err != nil {
+			fmt.Fprintf(stderr, "symplex daemon: drain: %v (%d intents abandoned)\n", err, len(host.PendingIntents()))
+		}
+		return nil
+	case err = <-served:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return fmt.Errorf("control: %w", err)
+	}
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/gateway"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+func TestLoadDaemonConfig(t *testing.T) {
+	path := writeConfig(t, "agent.yaml", `
+id: summariser
+capabilities: [summarise, translate]
+identity: /var/lib/symplex/key.pem
+listen:
+  - /ip4/0.0.0.0/tcp/4001
+discovery: /var/lib/symplex/peers.json
+dht: true
+observers: ["did:agent-semantic-protocol:watcher"]
+control_token: s3cret
+observer_token: watch
+trust_policy: conservative
+trust_policies:
+  translate: outcome-weighted
+prices:
+  summarise: {amount: 5, currency: credits}
+`)
+	cfg, err := LoadDaemonConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &DaemonConfig{
+		ID:            "summariser",
+		Capabilities:  []string{"summarise", "translate"},
+		Identity:      "/var/lib/symplex/key.pem",
+		Listen:        []string{"/ip4/0.0.0.0/tcp/4001"},
+		Discovery:     "/var/lib/symplex/peers.json",
+		DHT:           true,
+		Observers:     []string{"did:agent-semantic-protocol:watcher"},
+		Control:       DefaultControlAddr,
+		ControlToken:  "s3cret",
+		ObserverToken: "watch",
+		TrustPolicy:   "conservative",
+		TrustPolicies: map[string]string{
+			"translate": "outcome-weighted",
+		},
+		Prices: map[string]core.Cost{
+			"summarise": {Amount: 5, Currency: "credits"},
+		},
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("got %+v\nwant %+v", cfg, want)
+	}
+	if opts, err := cfg.trustPolicyOptions(); err != nil || len(opts) != 2 {
+		t.Errorf("trustPolicyOptions = %d options, %v", len(opts), err)
+	}
+
+	for name, body := range map[string]string{
+		"typo.json":     `{"id": "a", "capabilites": ["x"]}`,
+		"noid.yaml":     `capabilities: [x]`,
+		"typo.yml":      "id: a\nlisten_addr: []",
+		"badpeer.json":  `{"id": "a", "bootstrap": ["not-a-multiaddr"]}`,
+		"badtrust.json": `{"id": "a", "trust_policies": {"x": "lenient"}}`,
+	} {
+		cfg, err := LoadDaemonConfig(writeConfig(t, name, body))
+		if err == nil {
+			_, err = cfg.bootstrapPeers()
+		}
+		if err == nil {
+			_, err = cfg.trustPolicyOptions()
+		}
+		if err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+func TestClientCommands(t *testing.T) {
+	var got gateway.IntentRequest
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /intents", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		_ = json.NewEncoder(w).Encode(gateway.IntentResult{
+			IntentID: "i1", PeerID: "p1",
+			Response: &core.NegotiationResponse{RequestID: "i1", Accepted: true},
+		})
+	})
+	mux.HandleFunc("GET /peers", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode([]gateway.Peer{{PeerID: "p1", AgentID: "nlp", Trust: 0.5, Capabilities: []string{"summarise"}}})
+	})
+	mux.HandleFunc("GET /topology", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(p2p.Topology{
+			Self:  "p0",
+			Nodes: []p2p.TopologyNode{{PeerID: "p0", Self: true}, {PeerID: "p1", AgentID: "nlp", Handshaked: true, Connected: true}},
+			Links: []p2p.TopologyLink{{From: "p0", To: "p1", Direction: "outbound", Streams: 2}},
+		})
+	})
+	var released string
+	mux.HandleFunc("DELETE /quarantine/{peerID}", func(w http.ResponseWriter, r *http.Request) {
+		released = r.PathValue("peerID")
+		_ = json.NewEncoder(w).Encode([]core.QuarantineEntry{{Key: "p2", Errors: map[string]int{"timeout": 2, "decode": 1}}})
+	})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "missing or invalid bearer token"})
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	var out, errOut bytes.Buffer
+	code := run([]string{"send-intent", "-addr", srv.URL, "-token", "tok", "-cap", "summarise,translate", "-ttl", "30s", "hello"}, &out, &errOut)
+	if code != 0 {
+		t.Fatalf("send-intent exit %d: %s", code, errOut.String())
+	}
+	want := gateway.IntentRequest{Capabilities: []string{"summarise", "translate"}, Payload: "hello", TTLSeconds: 30}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("daemon got %+v, want %+v", got, want)
+	}
+	if !strings.Contains(out.String(), `"intent_id": "i1"`) {
+		t.Errorf("send-intent output:\n%s", out.String())
+	}
+
+	out.Reset()
+	if code = run([]string{"peers", "-addr", srv.URL, "-token", "tok"}, &out, &errOut); code != 0 {
+		t.Fatalf("peers exit %d: %s", code, errOut.String())
+	}
+	if !strings.Contains(out.String(), "nlp") || !strings.Contains(out.String(), "summarise") {
+		t.Errorf("peers output:\n%s", out.String())
+	}
+
+	out.Reset()
+	if code = run([]string{"topology", "-addr", srv.URL, "-token", "tok"}, &out, &errOut); code != 0 {
+		t.Fatalf("topology exit %d: %s", code, errOut.String())
+	}
+	if !strings.Contains(out.String(), "handshaked,connected") || !strings.Contains(out.String(), "outbound") {
+		t.Errorf("topology output:\n%s", out.String())
+	}
+
+	out.Reset()
+	if code = run([]string{"quarantine", "-addr", srv.URL, "-token", "tok", "-release", "p1"}, &out, &errOut); code != 0 {
+		t.Fatalf("quarantine exit %d: %s", code, errOut.String())
+	}
+	if released != "p1" || !strings.Contains(out.String(), "released p1") || !strings.Contains(out.String(), "decode=1,timeout=2") {
+		t.Errorf("quarantine released %q, output:\n%s", released, out.String())
+	}
+
+	errOut.Reset()
+	if code = run([]string{"peers", "-addr", srv.URL, "-token", "wrong"}, &out, &errOut); code != 1 {
+		t.Errorf("bad token: exit %d, want 1", code)
+	}
+	if !strings.Contains(errOut.String(), "bearer token") {
+		t.Errorf("bad token error: %s", errOut.String())
+	}
+}
+
+func TestBillingReportCommand(t *testing.T) {
+	provider, _ := core.NewAgent("nlp", []string{"summarise"})
+	requester, _ := core.NewAgent("alice", nil)
+	path := filepath.Join(t.TempDir(), "receipts.jsonl")
+	log, err := core.OpenReceiptLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		intent, _ := core.CreateIntent(requester, nil, []string{"summarise"}, "work")
+		r, err := core.IssueReceipt(provider, intent, core.Cost{Amount: 4, Currency: "credits"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = log.Append(r)
+	}
+	_ = log.Close()
+
+	var out, errOut bytes.Buffer
+	if code := run([]string{"billing", "report", "-from", "2000-01-01", path}, &out, &errOut); code != 0 {
+		t.Fatalf("exit %d: %s", code, errOut.String())
+	}
+	want := provider.DID.String() + "," + requester.DID.String() + ",summarise,credits,3,0,12"
+	if !strings.Contains(out.String(), want) {
+		t.Errorf("report:\n%s\nwant line %s", out.String(), want)
+	}
+
+	out.Reset()
+	if code := run([]string{"billing", "report", "-format", "json", "-to", "2000-01-01", path}, &out, &errOut); code != 0 {
+		t.Fatalf("exit %d: %s", code, errOut.String())
+	}
+	var rep core.BillingReport
+	if err = json.Unmarshal(out.Bytes(), &rep); err != nil || len(rep.Lines) != 0 {
+		t.Errorf("report before 2000: %+v, %v", rep, err)
+	}
+}
+
+func TestAuditVerifyCommand(t *testing.T) {
+	a, _ := core.NewAgent("nlp", []string{"summarise"})
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l, err := core.OpenAuditLog(path, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	intent, _ := core.CreateIntent(a, nil, []string{"summarise"}, "work")
+	_, _ = l.Append(intent, a.DID.String(), "accepted")
+	_ = l.Close()
+
+	var out, errOut bytes.Buffer
+	if code := run([]string{"audit", "verify", "-did", a.DID.String(), path}, &out, &errOut); code != 0 {
+		t.Fatalf("exit %d: %s", code, errOut.String())
+	}
+	if !strings.Contains(out.String(), "ok, 1 records by "+a.DID.String()) {
+		t.Errorf("output: %s", out.String())
+	}
+	if code := run([]string{"audit", "verify", "-did", "did:agent-semantic-protocol:other", path}, &out, &errOut); code != 1 {
+		t.Errorf("wrong signer: exit %d", code)
+	}
+}
+
+func TestScenarioRunCommand(t *testing.T) {
+	pass := writeConfig(t, "pass.yaml", `
+name: accepted
+agents:
+  - {id: alpha, capabilities: []}
+  - {id: beta, capabilities: [ocr]}
+steps:
+  - intent: {from: alpha, to: beta, capabilities: [ocr]}
+    expect: {accepted: true}
+`)
+	fail := writeConfig(t, "fail.yaml", `
+name: wrongly expected
+agents:
+  - {id: alpha, capabilities: []}
+  - {id: beta, capabilities: [ocr]}
+steps:
+  - intent: {from: alpha, to: beta, capabilities: [math]}
+    expect: {accepted: true}
+`)
+	var out, errOut bytes.Buffer
+	if code := run([]string{"scenario", "run", pass}, &out, &errOut); code != 0 {
+		t.Fatalf("exit %d: %s%s", code, out.String(), errOut.String())
+	}
+	if !strings.Contains(out.String(), "PASS accepted (1 steps)") {
+		t.Errorf("output: %s", out.String())
+	}
+	out.Reset()
+	if code := run([]string{"scenario", "run", pass, fail}, &out, &errOut); code != 1 {
+		t.Errorf("failing scenario: exit %d", code)
+	}
+	if !strings.Contains(out.String(), "FAIL wrongly expected\n    step 1: expected accepted=true, got false") {
+		t.Errorf("output: %s", out.String())
+	}
+}
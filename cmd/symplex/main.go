@@ -0,0 +1,76 @@
+// symplex — operator tool for Agent Semantic Protocol deployments.
+//
+// Usage:
+//
+//	symplex validate [-warnings-as-errors] FILE...
+//	symplex daemon CONFIG
+//	symplex send-intent -cap CAPABILITY [-peer ID] [-ttl D] [PAYLOAD]
+//	symplex peers
+//	symplex handshake [-peer-addr MULTIADDR] PEER_ID
+//	symplex topology [-json]
+//	symplex events [-kind KIND] [-json]
+//	symplex billing report [-from DAY] [-to DAY] [-format csv|json] RECEIPTS...
+//	symplex audit verify [-did DID] AUDIT_LOG...
+//	symplex scenario run FILE...
+//
+// send-intent, peers, handshake, topology and events talk to a running daemon's control API
+// (-addr, default 127.0.0.1:7070; -token or $SYMPLEX_TOKEN).  events needs
+// only the daemon's read-only observer_token.
+//
+// Run `symplex help` for the list of subcommands.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// command is one subcommand.  run returns the process exit code.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string, stdout, stderr io.Writer) int
+}
+
+var commands = []command{
+	{"validate", "check agent configs and workflow templates before deployment", runValidate},
+	{"daemon", "run a long-lived agent from a YAML or JSON config", runDaemon},
+	{"send-intent", "send an intent through a running daemon", runSendIntent},
+	{"peers", "list the peers a running daemon knows", runPeers},
+	{"handshake", "make a running daemon handshake with a peer", runHandshake},
+	{"topology", "show the mesh around a running daemon", runTopology},
+	{"quarantine", "list or release the peers a running daemon quarantined", runQuarantine},
+	{"events", "stream a running daemon's events, e.g. for monitoring", runEvents},
+	{"billing", "aggregate signed completion receipts into a billing report", runBilling},
+	{"audit", "verify signed, hash-chained audit logs", runAudit},
+	{"scenario", "run declarative negotiation test scenarios", runScenario},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		usage(stdout)
+		return 0
+	}
+	for _, c := range commands {
+		if c.name == args[0] {
+			return c.run(args[1:], stdout, stderr)
+		}
+	}
+	fmt.Fprintf(stderr, "symplex: unknown command %q\n\n", args[0])
+	usage(stderr)
+	return 2
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: symplex <command> [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-12s %s\n", c.name, c.summary)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+// scenario.go — `symplex scenario run`: play declarative negotiation scenarios.
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/olserra/agent-semantic-protocol/scenario"
+)
+
+func runScenario(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] != "run" {
+		fmt.Fprintln(stderr, "usage: symplex scenario run FILE...")
+		return 2
+	}
+	fs := flag.NewFlagSet("scenario run", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: symplex scenario run FILE...")
+		fmt.Fprintln(stderr, "FILE is a YAML or JSON scenario; see the scenario package.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	code := 0
+	for _, path := range fs.Args() {
+		s, err := scenario.Load(path)
+		var res *scenario.Result
+		if err == nil {
+			res, err = scenario.Run(s)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "symplex scenario: %v\n", err)
+			code = 1
+			continue
+		}
+		if res.Passed() {
+			fmt.Fprintf(stdout, "PASS %s (%d steps)\n", res.Scenario, len(res.Steps))
+			continue
+		}
+		code = 1
+		fmt.Fprintf(stdout, "FAIL %s\n", res.Scenario)
+		for _, f := range res.Failures() {
+			fmt.Fprintf(stdout, "    %s\n", f)
+		}
+	}
+	return code
+}
@@ -0,0 +1,385 @@
+package main
+
+// validate.go — `symplex validate`: static checks for deployment configs.
+//
+// All files are loaded into one view of the deployment before checking, so
+// a workflow in one file is checked against the agents defined in others.
+// Errors make the command exit 1; warnings are printed but only fail the
+// run with -warnings-as-errors.
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+// Problem is one finding of the validator.
+type Problem struct {
+	File    string
+	Where   string // e.g. `workflow "ingest" step "ocr"`
+	Msg     string
+	Warning bool
+}
+
+func (p Problem) String() string {
+	sev := "error"
+	if p.Warning {
+		sev = "warning"
+	}
+	return fmt.Sprintf("%s: %s: %s: %s", p.File, sev, p.Where, p.Msg)
+}
+
+// namedConfig is a loaded file and its path.
+type namedConfig struct {
+	path string
+	cfg  *ConfigFile
+}
+
+func runValidate(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	strict := fs.Bool("warnings-as-errors", false, "fail on warnings too")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: symplex validate [-warnings-as-errors] FILE...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	var files []namedConfig
+	for _, path := range fs.Args() {
+		cfg, err := LoadConfigFile(path)
+		if err != nil {
+			fmt.Fprintf(stdout, "%s: error: %v\n", path, err)
+			return 1
+		}
+		files = append(files, namedConfig{path, cfg})
+	}
+
+	problems := Validate(files)
+	failed := false
+	for _, p := range problems {
+		fmt.Fprintln(stdout, p)
+		failed = failed || !p.Warning || *strict
+	}
+	if failed {
+		return 1
+	}
+	fmt.Fprintf(stdout, "ok: %d file(s), %d warning(s)\n", len(files), len(problems))
+	return 0
+}
+
+// validator accumulates problems across files.
+type validator struct {
+	problems []Problem
+
+	aliases   *core.CapabilityAliases
+	agents    map[string]string                 // agent ID → file
+	providers map[string][]string               // canonical capability → agent IDs
+	contracts map[string][]*core.CapabilitySpec // canonical capability → each provider's spec, nil if it has none
+	dim       int                               // Mesh-wide embedding dimension, 0 if unknown
+	dimAgent  string
+}
+
+func (v *validator) errorf(file, where, format string, args ...interface{}) {
+	v.problems = append(v.problems, Problem{File: file, Where: where, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) warnf(file, where, format string, args ...interface{}) {
+	v.problems = append(v.problems, Problem{File: file, Where: where, Msg: fmt.Sprintf(format, args...), Warning: true})
+}
+
+// Validate checks a deployment made of files and returns every problem
+// found, in file order.
+func Validate(files []namedConfig) []Problem {
+	v := &validator{
+		aliases:   core.NewCapabilityAliases(),
+		agents:    make(map[string]string),
+		providers: make(map[string][]string),
+		contracts: make(map[string][]*core.CapabilitySpec),
+	}
+	for _, f := range files {
+		for _, a := range f.cfg.Aliases {
+			v.aliases.Add(a)
+		}
+	}
+	for _, f := range files {
+		for _, a := range f.cfg.AllAgents() {
+			v.checkAgent(f.path, a)
+		}
+	}
+	for _, f := range files {
+		v.checkAliases(f.path, f.cfg.Aliases)
+	}
+	workflows := make(map[string]string)
+	for _, f := range files {
+		for _, w := range f.cfg.Workflows {
+			where := fmt.Sprintf("workflow %q", w.ID)
+			if w.ID == "" {
+				v.errorf(f.path, "workflow", "missing id")
+			} else if other, dup := workflows[w.ID]; dup {
+				v.errorf(f.path, where, "also defined in %s", other)
+			}
+			workflows[w.ID] = f.path
+			v.checkWorkflow(f.path, where, w)
+		}
+	}
+	return v.problems
+}
+
+func (v *validator) checkAgent(file string, a AgentConfig) {
+	where := fmt.Sprintf("agent %q", a.ID)
+	if a.ID == "" {
+		v.errorf(file, "agent", "missing id")
+	} else if other, dup := v.agents[a.ID]; dup {
+		v.errorf(file, where, "also defined in %s", other)
+	}
+	v.agents[a.ID] = file
+
+	if len(a.Capabilities) == 0 {
+		v.warnf(file, where, "offers no capabilities")
+	}
+	offered := make(map[string]bool)
+	for _, c := range a.Capabilities {
+		if c == "" {
+			v.errorf(file, where, "empty capability name")
+			continue
+		}
+		if alias, ok := v.aliases.Lookup(c); ok && alias.Deprecated {
+			v.warnf(file, where, "capability %q is a deprecated alias of %q", c, alias.Canonical)
+		}
+		canon := v.aliases.Canonical(c)
+		if offered[canon] {
+			v.warnf(file, where, "capability %q listed more than once", canon)
+			continue
+		}
+		offered[canon] = true
+		v.providers[canon] = append(v.providers[canon], a.ID)
+	}
+
+	if a.EmbeddingDim > 0 {
+		switch {
+		case v.dim == 0:
+			v.dim, v.dimAgent = a.EmbeddingDim, a.ID
+		case v.dim != a.EmbeddingDim:
+			v.errorf(file, where, "embedding_dim %d does not match %d used by agent %q", a.EmbeddingDim, v.dim, v.dimAgent)
+		}
+	}
+	specs := make(map[string]*core.CapabilitySpec)
+	for i, sp := range a.Specs {
+		swhere := fmt.Sprintf("%s spec %q", where, sp.Name)
+		if err := sp.Validate(); err != nil {
+			v.errorf(file, swhere, "%v", err)
+			continue
+		}
+		canon := v.aliases.Canonical(sp.Name)
+		switch {
+		case !offered[canon]:
+			v.errorf(file, swhere, "specifies a capability the agent does not offer")
+		case specs[canon] != nil:
+			v.warnf(file, swhere, "capability specified more than once")
+		default:
+			specs[canon] = &a.Specs[i]
+		}
+	}
+	for canon := range offered {
+		v.contracts[canon] = append(v.contracts[canon], specs[canon])
+	}
+
+	for _, d := range a.Manifest {
+		dwhere := fmt.Sprintf("%s manifest %q", where, d.Name)
+		if !offered[v.aliases.Canonical(d.Name)] {
+			v.errorf(file, dwhere, "describes a capability the agent does not offer")
+		}
+		for _, ex := range d.Examples {
+			if n := len(ex.IntentVector); n > 0 && a.EmbeddingDim > 0 && n != a.EmbeddingDim {
+				v.errorf(file, dwhere, "example %q: intent vector has %d dimensions, agent uses %d", ex.Name, n, a.EmbeddingDim)
+			}
+		}
+	}
+}
+
+func (v *validator) checkAliases(file string, aliases []core.CapabilityAlias) {
+	for _, a := range aliases {
+		where := fmt.Sprintf("alias %q", a.Alias)
+		switch {
+		case a.Alias == "" || a.Canonical == "":
+			v.errorf(file, where, "alias and canonical names are both required")
+		case a.Alias == a.Canonical:
+			v.warnf(file, where, "maps to itself")
+		case len(v.agents) > 0 && len(v.providers[v.aliases.Canonical(a.Canonical)]) == 0:
+			v.warnf(file, where, "canonical capability %q is not offered by any agent", a.Canonical)
+		}
+	}
+}
+
+func (v *validator) checkWorkflow(file, where string, w WorkflowTemplate) {
+	if len(w.Steps) == 0 {
+		v.errorf(file, where, "has no steps")
+		return
+	}
+	for _, s := range w.Steps {
+		v.checkStep(file, fmt.Sprintf("%s step %q", where, s.ID), s)
+		if s.Compensate != nil {
+			v.checkStep(file, fmt.Sprintf("%s step %q compensation", where, s.ID), *s.Compensate)
+		}
+	}
+
+	// upstream[id] lists the steps whose results step id may reference.
+	var upstream map[string][]string
+	if w.IsGraph() {
+		upstream = v.graphUpstream(file, where, w)
+	} else {
+		upstream = v.sequentialUpstream(file, where, w)
+	}
+	if upstream == nil {
+		return // Structure is broken; reference checks would only add noise.
+	}
+	ids := make([]string, len(w.Steps))
+	for i, s := range w.Steps {
+		ids[i] = s.ID
+	}
+	for _, s := range w.Steps {
+		if _, reached := upstream[s.ID]; !reached {
+			continue
+		}
+		swhere := fmt.Sprintf("%s step %q", where, s.ID)
+		for _, ref := range p2p.StepReferences(s.Payload) {
+			switch {
+			case !slices.Contains(ids, ref):
+				v.errorf(file, swhere, "payload references unknown step %q", ref)
+			case !slices.Contains(upstream[s.ID], ref):
+				v.errorf(file, swhere, "payload references step %q, which does not run before it", ref)
+			}
+		}
+		if s.Compensate != nil {
+			for _, ref := range p2p.StepReferences(s.Compensate.Payload) {
+				if !slices.Contains(ids, ref) {
+					v.errorf(file, swhere+" compensation", "payload references unknown step %q", ref)
+				}
+			}
+		}
+	}
+}
+
+func (v *validator) checkStep(file, where string, s StepTemplate) {
+	if s.ID == "" {
+		v.errorf(file, where, "missing id")
+	}
+	if s.Capability == "" {
+		v.errorf(file, where, "missing capability")
+	} else if canon := v.aliases.Canonical(s.Capability); len(v.agents) > 0 && len(v.providers[canon]) == 0 {
+		v.errorf(file, where, "unknown capability %q: no agent offers it", s.Capability)
+	}
+	if n := len(s.IntentVector); n > 0 && v.dim > 0 && n != v.dim {
+		v.errorf(file, where, "intent vector has %d dimensions, agents use %d", n, v.dim)
+	}
+	if s.Capability != "" && len(p2p.StepReferences(s.Payload)) == 0 {
+		v.checkPayload(file, where, s)
+	}
+}
+
+// checkPayload reports a step whose payload no provider's params schema
+// accepts.  Like the orchestrator, it is satisfied by any one provider, and
+// providers without a spec accept anything.  Payloads that reference other
+// steps are only known at run time and are not checked.
+func (v *validator) checkPayload(file, where string, s StepTemplate) {
+	var first error
+	for _, sp := range v.contracts[v.aliases.Canonical(s.Capability)] {
+		if sp == nil {
+			return
+		}
+		err := sp.ValidateParams([]byte(s.Payload))
+		if err == nil {
+			return
+		}
+		if first == nil {
+			first = err
+		}
+	}
+	if first != nil {
+		v.errorf(file, where, "payload rejected by every provider: %v", first)
+	}
+}
+
+// graphUpstream checks w as a WorkflowGraph and returns each step's
+// transitive dependencies, or nil if the graph is invalid.
+func (v *validator) graphUpstream(file, where string, w WorkflowTemplate) map[string][]string {
+	g, err := p2p.NewWorkflowGraph(w.WorkflowSteps()...)
+	if err != nil {
+		v.errorf(file, where, "%v", err)
+		return nil
+	}
+	deps := make(map[string][]string, len(w.Steps))
+	for _, s := range w.Steps {
+		if s.NextStepID != "" {
+			v.warnf(file, fmt.Sprintf("%s step %q", where, s.ID), "next_step_id is ignored in graph workflows")
+		}
+		deps[s.ID] = s.DependsOn
+	}
+	levels, _ := g.Levels()
+	upstream := make(map[string][]string, len(w.Steps))
+	for _, level := range levels {
+		for _, id := range level {
+			var all []string
+			for _, d := range deps[id] {
+				all = append(all, d)
+				all = append(all, upstream[d]...)
+			}
+			slices.Sort(all)
+			upstream[id] = slices.Compact(all)
+		}
+	}
+	return upstream
+}
+
+// sequentialUpstream walks w as RunSequential would and returns, for each
+// step, the steps run before it, or nil if the walk is invalid.
+func (v *validator) sequentialUpstream(file, where string, w WorkflowTemplate) map[string][]string {
+	index := make(map[string]int, len(w.Steps))
+	for i, s := range w.Steps {
+		if _, dup := index[s.ID]; dup {
+			v.errorf(file, where, "duplicate step %q", s.ID)
+			return nil
+		}
+		index[s.ID] = i
+	}
+	for _, s := range w.Steps {
+		if _, ok := index[s.NextStepID]; s.NextStepID != "" && !ok {
+			v.errorf(file, fmt.Sprintf("%s step %q", where, s.ID), "unknown next step %q", s.NextStepID)
+			return nil
+		}
+	}
+
+	upstream := make(map[string][]string, len(w.Steps))
+	var ran []string
+	for i := 0; i < len(w.Steps); {
+		s := w.Steps[i]
+		if _, seen := upstream[s.ID]; seen {
+			v.errorf(file, fmt.Sprintf("%s step %q", where, s.ID), "would run twice (next_step_id loop)")
+			return nil
+		}
+		upstream[s.ID] = slices.Clone(ran)
+		ran = append(ran, s.ID)
+		if s.NextStepID != "" {
+			i = index[s.NextStepID]
+		} else {
+			i++
+		}
+	}
+	for _, s := range w.Steps {
+		if _, reached := upstream[s.ID]; !reached {
+			v.warnf(file, fmt.Sprintf("%s step %q", where, s.ID), "is never run")
+		}
+	}
+	return upstream
+}
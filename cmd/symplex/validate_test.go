@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, name, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestValidateClean(t *testing.T) {
+	agents := writeConfig(t, "agents.json", `{
+		"agents": [
+			{"id": "ocr", "capabilities": ["ocr"], "embedding_dim": 3},
+			{"id": "nlp", "capabilities": ["summarisation"], "embedding_dim": 3}
+		],
+		"aliases": [{"alias": "summarize", "canonical": "summarisation"}]
+	}`)
+	workflows := writeConfig(t, "workflows.json", `{
+		"workflows": [{
+			"id": "ingest",
+			"steps": [
+				{"id": "scan", "capability": "ocr", "intent_vector": [1, 0, 0]},
+				{"id": "sum", "capability": "summarize", "payload": "{{steps.scan.output}}"}
+			]
+		}]
+	}`)
+	var out bytes.Buffer
+	if code := run([]string{"validate", agents, workflows}, &out, &out); code != 0 {
+		t.Fatalf("exit %d:\n%s", code, out.String())
+	}
+}
+
+func TestValidateFindsProblems(t *testing.T) {
+	path := writeConfig(t, "bad.json", `{
+		"agents": [
+			{"id": "a", "capabilities": ["ocr"], "embedding_dim": 3,
+			 "manifest": [{"name": "translate"}]},
+			{"id": "b", "capabilities": ["nlp"], "embedding_dim": 4}
+		],
+		"workflows": [
+			{"id": "seq", "steps": [
+				{"id": "one", "capability": "ocr", "payload": "{{steps.two.output}}"},
+				{"id": "two", "capability": "video", "intent_vector": [1, 2]}
+			]},
+			{"id": "dag", "steps": [
+				{"id": "x", "capability": "ocr", "depends_on": ["y"]},
+				{"id": "y", "capability": "ocr", "depends_on": ["x"]}
+			]}
+		]
+	}`)
+	var out bytes.Buffer
+	if code := run([]string{"validate", path}, &out, &out); code != 1 {
+		t.Fatalf("exit %d, want 1:\n%s", code, out.String())
+	}
+	for _, want := range []string{
+		`embedding_dim 4 does not match 3`,
+		`manifest "translate": describes a capability the agent does not offer`,
+		`payload references step "two", which does not run before it`,
+		`unknown capability "video"`,
+		`intent vector has 2 dimensions`,
+		`dependency cycle`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output lacks %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestValidateCapabilitySpecs(t *testing.T) {
+	path := writeConfig(t, "specs.json", `{
+		"agents": [
+			{"id": "a", "capabilities": ["ocr"], "specs": [
+				{"name": "ocr", "version": "1.0.0", "params_schema": {
+					"type": "object", "required": ["url"], "properties": {"url": {"type": "string"}}}},
+				{"name": "video", "version": "1"}
+			]}
+		],
+		"workflows": [
+			{"id": "scan", "steps": [
+				{"id": "good", "capability": "ocr", "payload": "{\"url\": \"s3://doc\"}"},
+				{"id": "bad", "capability": "ocr", "payload": "{\"path\": \"/doc\"}"},
+				{"id": "later", "capability": "ocr", "payload": "{{steps.good.output}}"}
+			]}
+		]
+	}`)
+	var out bytes.Buffer
+	if code := run([]string{"validate", path}, &out, &out); code != 1 {
+		t.Fatalf("exit %d, want 1:\n%s", code, out.String())
+	}
+	for _, want := range []string{
+		`spec "video": capability spec video: version "1" is not a semantic version`,
+		`step "bad": payload rejected by every provider`,
+		`missing required property "url"`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output lacks %q:\n%s", want, out.String())
+		}
+	}
+	for _, unwanted := range []string{`step "good"`, `step "later"`} {
+		if strings.Contains(out.String(), unwanted) {
+			t.Errorf("output reports %s:\n%s", unwanted, out.String())
+		}
+	}
+}
+
+func TestValidateRejectsUnknownFields(t *testing.T) {
+	path := writeConfig(t, "typo.json", `{"agent": {"id": "a", "capabilties": ["ocr"]}}`)
+	var out bytes.Buffer
+	if code := run([]string{"validate", path}, &out, &out); code != 1 {
+		t.Fatalf("exit %d, want 1:\n%s", code, out.String())
+	}
+}
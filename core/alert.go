@@ -0,0 +1,158 @@
+package core
+
+// alert.go — Signed, mesh-wide protocol alerts.
+//
+// An AlertMessage announces something every agent should act on quickly: a
+// compromised key, a capability being withdrawn, or a free-form advisory.
+// Alerts carry the issuer's public key, so any hop can verify them without a
+// prior handshake, and are flooded peer-to-peer: each host forwards an alert
+// it has not seen before with Hops decremented, and drops it at zero.
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultAlertHops is the forwarding budget of a freshly issued alert.
+const DefaultAlertHops = 6
+
+// ErrAlertStale is returned by CheckAlertTime for an alert issued too long
+// ago, or too far in the future, to be accepted.
+var ErrAlertStale = errors.New("alert: timestamp outside the accepted window")
+
+// NewAlert creates and signs an alert from issuer.
+func NewAlert(issuer *Agent, kind AlertKind, subject, detail string) (*AlertMessage, error) {
+	id, err := issuer.randomID()
+	if err != nil {
+		return nil, err
+	}
+	m := &AlertMessage{
+		ID:        id,
+		Kind:      kind,
+		IssuerDID: issuer.DID.String(),
+		IssuerKey: issuer.PublicKey(),
+		Subject:   subject,
+		Detail:    detail,
+		Timestamp: now(),
+		Hops:      DefaultAlertHops,
+	}
+	sig, err := issuer.Sign(alertSigningData(m))
+	if err != nil {
+		return nil, fmt.Errorf("alert: sign: %w", err)
+	}
+	m.Signature = sig
+	return m, nil
+}
+
+// VerifyAlert checks that the alert is signed and that the embedded key
+// belongs to IssuerDID.  Unlike intents, unsigned alerts are always rejected.
+func VerifyAlert(m *AlertMessage) error {
+	if len(m.Signature) == 0 {
+		return fmt.Errorf("alert %s: unsigned", m.ID)
+	}
+	d, err := ParseDID(m.IssuerDID)
+	if err != nil {
+		return fmt.Errorf("alert %s: %w", m.ID, err)
+	}
+	if !d.ValidateBinding(m.IssuerKey) {
+		return fmt.Errorf("alert %s: issuer key does not match %s", m.ID, m.IssuerDID)
+	}
+	pub, err := DIDFromPublicKey(m.IssuerKey)
+	if err != nil {
+		return fmt.Errorf("alert %s: %w", m.ID, err)
+	}
+	if !pub.Verify(alertSigningData(m), m.Signature) {
+		return fmt.Errorf("alert %s: %w", m.ID, ErrSignatureInvalid)
+	}
+	return nil
+}
+
+// CheckAlertTime rejects an alert issued more than maxAge before now or more
+// than MaxClockOffset after it.  A host that remembers alerts for maxAge
+// can then recognise every replay it accepts.
+func CheckAlertTime(m *AlertMessage, maxAge time.Duration, now time.Time) error {
+	t := time.Unix(0, m.Timestamp)
+	if t.Before(now.Add(-maxAge)) || t.After(now.Add(MaxClockOffset)) {
+		return fmt.Errorf("alert %s: %w", m.ID, ErrAlertStale)
+	}
+	return nil
+}
+
+// AlertAuthorized reports whether the issuer may make this claim.  Agents
+// may always report their own key as compromised and recall their own
+// capabilities; claims about another DID need an issuer that isAuthority
+// accepts (isAuthority may be nil).
+func AlertAuthorized(m *AlertMessage, isAuthority func(did string) bool) bool {
+	if m.Kind != AlertKeyCompromise || m.Subject == m.IssuerDID {
+		return true
+	}
+	return isAuthority != nil && isAuthority(m.IssuerDID)
+}
+
+// ApplyAlert performs the local policy effects of a verified, authorized
+// alert on behalf of self:
+//
+//   - AlertKeyCompromise: trust in the subject DID drops to 0 and it is
+//     removed from the discovery registry.
+//   - AlertCapabilityRecall: the capability is removed from the issuer's
+//     registry profile, which keeps its expiry.
+//   - AlertAdvisory: no automatic effect.
+func ApplyAlert(self *Agent, m *AlertMessage, trust *TrustGraph, reg *DiscoveryRegistry) error {
+	switch m.Kind {
+	case AlertKeyCompromise:
+		if _, ok := reg.FindByDID(m.Subject); ok {
+			reg.Remove(m.Subject)
+		}
+		return trust.Set(self.DID.String(), m.Subject, 0)
+	case AlertCapabilityRecall:
+		reg.RemoveCapability(m.IssuerDID, m.Subject)
+	}
+	return nil
+}
+
+// AlertCache remembers recently seen alerts so floods terminate.  Alerts are
+// keyed by issuer and signed content, so neither a reused ID nor a
+// re-encoded copy slips past it.  It is concurrency-safe.
+type AlertCache struct {
+	mu   sync.Mutex
+	ttl  time.Duration
+	seen map[string]time.Time
+}
+
+// NewAlertCache remembers alerts for ttl.
+func NewAlertCache(ttl time.Duration) *AlertCache {
+	return &AlertCache{ttl: ttl, seen: make(map[string]time.Time)}
+}
+
+// Seen records m and reports whether it had already been recorded.
+func (c *AlertCache) Seen(m *AlertMessage) bool {
+	sum := sha256.Sum256(alertSigningData(m))
+	id := m.IssuerDID + "/" + hex.EncodeToString(sum[:])
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := time.Now()
+	for k, exp := range c.seen {
+		if t.After(exp) {
+			delete(c.seen, k)
+		}
+	}
+	if _, ok := c.seen[id]; ok {
+		return true
+	}
+	c.seen[id] = t.Add(c.ttl)
+	return false
+}
+
+// alertSigningData is the encoded alert with the mutable Hops field and the
+// signature cleared.
+func alertSigningData(m *AlertMessage) []byte {
+	c := *m
+	c.Hops = 0
+	c.Signature = nil
+	data, _ := c.Encode()
+	return data
+}
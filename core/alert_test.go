@@ -0,0 +1,124 @@
+package core_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func TestAlertSignAndVerify(t *testing.T) {
+	issuer, _ := core.NewAgent("issuer", nil)
+	alert, err := core.NewAlert(issuer, core.AlertAdvisory, "", "rotate keys")
+	if err != nil {
+		t.Fatalf("NewAlert: %v", err)
+	}
+
+	data, _ := alert.Encode()
+	decoded, err := core.DecodeAlertMessage(data)
+	if err != nil {
+		t.Fatalf("DecodeAlertMessage: %v", err)
+	}
+	decoded.Hops-- // forwarding must not invalidate the signature
+	if err = core.VerifyAlert(decoded); err != nil {
+		t.Errorf("VerifyAlert after forwarding: %v", err)
+	}
+
+	decoded.Detail = "ignore previous advisory"
+	if core.VerifyAlert(decoded) == nil {
+		t.Error("expected tampered alert to fail verification")
+	}
+}
+
+func TestApplyKeyCompromiseAlert(t *testing.T) {
+	self, _ := core.NewAgent("self", nil)
+	victim, _ := core.NewAgent("victim", []string{"nlp"})
+	other, _ := core.NewAgent("other", nil)
+
+	trust := core.NewTrustGraph()
+	_ = trust.Set(self.DID.String(), victim.DID.String(), 0.9)
+	reg := core.NewDiscoveryRegistry()
+	reg.Announce(core.AgentProfile{AgentID: victim.ID, DID: victim.DID.String(), Capabilities: victim.Capabilities}, 0)
+
+	forged, _ := core.NewAlert(other, core.AlertKeyCompromise, victim.DID.String(), "")
+	if core.AlertAuthorized(forged, nil) {
+		t.Error("third-party compromise claim must need an authority")
+	}
+
+	alert, _ := core.NewAlert(victim, core.AlertKeyCompromise, victim.DID.String(), "leaked")
+	if !core.AlertAuthorized(alert, nil) {
+		t.Fatal("self-reported compromise should be authorized")
+	}
+	if err := core.ApplyAlert(self, alert, trust, reg); err != nil {
+		t.Fatalf("ApplyAlert: %v", err)
+	}
+	if got := trust.Get(self.DID.String(), victim.DID.String()); got != 0 {
+		t.Errorf("trust after compromise: got %v want 0", got)
+	}
+	if _, ok := reg.FindByDID(victim.DID.String()); ok {
+		t.Error("compromised agent still in registry")
+	}
+}
+
+func TestAlertCacheDedup(t *testing.T) {
+	alert, _ := core.NewAlert(mustAgent(t, "issuer"), core.AlertAdvisory, "", "rotate keys")
+	c := core.NewAlertCache(time.Minute)
+	if c.Seen(alert) {
+		t.Error("first sighting reported as seen")
+	}
+	fwd := *alert
+	fwd.Hops--
+	if !c.Seen(&fwd) {
+		t.Error("forwarded copy not deduplicated")
+	}
+
+	// Another alert reusing the ID is not mistaken for the first.
+	other, _ := core.NewAlert(mustAgent(t, "other"), core.AlertAdvisory, "", "rotate keys")
+	other.ID = alert.ID
+	if c.Seen(other) {
+		t.Error("alert from another issuer deduplicated by ID")
+	}
+}
+
+func TestCheckAlertTime(t *testing.T) {
+	alert, _ := core.NewAlert(mustAgent(t, "issuer"), core.AlertAdvisory, "", "")
+	now := time.Unix(0, alert.Timestamp)
+	if err := core.CheckAlertTime(alert, time.Hour, now.Add(30*time.Minute)); err != nil {
+		t.Errorf("fresh alert: %v", err)
+	}
+	if err := core.CheckAlertTime(alert, time.Hour, now.Add(2*time.Hour)); !errors.Is(err, core.ErrAlertStale) {
+		t.Errorf("old alert: got %v, want ErrAlertStale", err)
+	}
+	if err := core.CheckAlertTime(alert, time.Hour, now.Add(-time.Hour)); !errors.Is(err, core.ErrAlertStale) {
+		t.Errorf("future alert: got %v, want ErrAlertStale", err)
+	}
+}
+
+func TestCapabilityRecallKeepsExpiry(t *testing.T) {
+	self, issuer := mustAgent(t, "self"), mustAgent(t, "issuer")
+	reg := core.NewDiscoveryRegistry()
+	reg.Announce(core.AgentProfile{AgentID: issuer.ID, DID: issuer.DID.String(), Capabilities: []string{"ocr", "nlp"}}, 1)
+
+	alert, _ := core.NewAlert(issuer, core.AlertCapabilityRecall, "ocr", "")
+	if err := core.ApplyAlert(self, alert, core.NewTrustGraph(), reg); err != nil {
+		t.Fatalf("ApplyAlert: %v", err)
+	}
+	p, ok := reg.FindByDID(issuer.DID.String())
+	if !ok || len(p.Capabilities) != 1 || p.Capabilities[0] != "nlp" {
+		t.Fatalf("after recall: %+v", p)
+	}
+	time.Sleep(1100 * time.Millisecond)
+	if _, ok = reg.FindByDID(issuer.DID.String()); ok {
+		t.Error("recall made the entry permanent")
+	}
+}
+
+func mustAgent(t *testing.T, id string) *core.Agent {
+	t.Helper()
+	a, err := core.NewAgent(id, nil)
+	if err != nil {
+		t.Fatalf("NewAgent: %v", err)
+	}
+	return a
+}
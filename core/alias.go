@@ -0,0 +1,132 @@
+package core
+
+// alias.go — Capability name aliases.
+//
+// Capability names drift as a mesh grows ("code-gen", "codegen",
+// "code-generation").  A CapabilityAliases table maps every alternative
+// spelling to one canonical name.  Registries and negotiation handlers
+// normalise through it, so a peer announcing a legacy name is still found by
+// a search for the canonical one, and vice versa.  Aliases marked Deprecated
+// are additionally reported to a DeprecationTracker when peers use them.
+//
+//	aliases := core.NewCapabilityAliases(
+//		core.CapabilityAlias{Alias: "code-gen", Canonical: "code-generation", Deprecated: true},
+//	)
+//	registry.SetAliases(aliases)
+
+import (
+	"sort"
+	"sync"
+)
+
+// CapabilityAlias maps one alternative capability name to its canonical form.
+type CapabilityAlias struct {
+	Alias      string `json:"alias"`
+	Canonical  string `json:"canonical"`
+	Deprecated bool   `json:"deprecated,omitempty"` // Peers should stop using Alias
+	Note       string `json:"note,omitempty"`       // Free-form migration hint
+}
+
+// CapabilityAliases is a concurrency-safe alias table.  A nil table maps
+// every name to itself.
+type CapabilityAliases struct {
+	mu      sync.RWMutex
+	aliases map[string]CapabilityAlias
+}
+
+// NewCapabilityAliases creates a table holding aliases.
+func NewCapabilityAliases(aliases ...CapabilityAlias) *CapabilityAliases {
+	t := &CapabilityAliases{aliases: make(map[string]CapabilityAlias)}
+	for _, a := range aliases {
+		t.Add(a)
+	}
+	return t
+}
+
+// Add registers (or replaces) an alias.  Chains are resolved on insertion,
+// so an alias of an alias maps straight to the final canonical name.
+func (t *CapabilityAliases) Add(a CapabilityAlias) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if next, ok := t.aliases[a.Canonical]; ok {
+		a.Canonical = next.Canonical
+	}
+	if a.Alias == a.Canonical {
+		return
+	}
+	for k, existing := range t.aliases {
+		if existing.Canonical == a.Alias {
+			existing.Canonical = a.Canonical
+			t.aliases[k] = existing
+		}
+	}
+	t.aliases[a.Alias] = a
+}
+
+// Canonical returns the canonical name for name.
+func (t *CapabilityAliases) Canonical(name string) string {
+	if t == nil {
+		return name
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if a, ok := t.aliases[name]; ok {
+		return a.Canonical
+	}
+	return name
+}
+
+// Lookup returns the alias entry for name, if name is an alias.
+func (t *CapabilityAliases) Lookup(name string) (CapabilityAlias, bool) {
+	if t == nil {
+		return CapabilityAlias{}, false
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	a, ok := t.aliases[name]
+	return a, ok
+}
+
+// Normalize returns caps with every name canonicalised and duplicates
+// removed, preserving first-seen order.  caps itself is not modified.
+func (t *CapabilityAliases) Normalize(caps []string) []string {
+	if t == nil || caps == nil {
+		return caps
+	}
+	out := make([]string, 0, len(caps))
+	seen := make(map[string]struct{}, len(caps))
+	for _, c := range caps {
+		c = t.Canonical(c)
+		if _, dup := seen[c]; dup {
+			continue
+		}
+		seen[c] = struct{}{}
+		out = append(out, c)
+	}
+	return out
+}
+
+// All returns every alias, ordered by alias name.
+func (t *CapabilityAliases) All() []CapabilityAlias {
+	if t == nil {
+		return nil
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	out := make([]CapabilityAlias, 0, len(t.aliases))
+	for _, a := range t.aliases {
+		out = append(out, a)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Alias < out[j].Alias })
+	return out
+}
+
+// RegisterDeprecations marks every deprecated alias as a legacy capability
+// on d, so peers still using it show up in the deprecation summary.
+func (t *CapabilityAliases) RegisterDeprecations(d *DeprecationTracker) {
+	for _, a := range t.All() {
+		if a.Deprecated {
+			d.DeprecateCapability(a.Alias, a.Canonical)
+		}
+	}
+}
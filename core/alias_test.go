@@ -0,0 +1,66 @@
+package core_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func TestCapabilityAliasesNormalize(t *testing.T) {
+	aliases := core.NewCapabilityAliases(
+		core.CapabilityAlias{Alias: "codegen", Canonical: "code-gen"},
+		core.CapabilityAlias{Alias: "code-gen", Canonical: "code-generation", Deprecated: true},
+	)
+	if c := aliases.Canonical("codegen"); c != "code-generation" {
+		t.Errorf("chained alias: got %q", c)
+	}
+	got := aliases.Normalize([]string{"code-gen", "nlp", "code-generation", "codegen"})
+	if want := []string{"code-generation", "nlp"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Normalize: got %v want %v", got, want)
+	}
+	var none *core.CapabilityAliases
+	if c := none.Canonical("x"); c != "x" {
+		t.Errorf("nil table: got %q", c)
+	}
+}
+
+func TestRegistryMatchesAliases(t *testing.T) {
+	aliases := core.NewCapabilityAliases(core.CapabilityAlias{Alias: "code-gen", Canonical: "code-generation"})
+	reg := core.NewDiscoveryRegistry()
+	reg.SetAliases(aliases)
+	reg.Announce(core.AgentProfile{AgentID: "old", Capabilities: []string{"code-gen"}}, 0)
+
+	for _, q := range []string{"code-gen", "code-generation"} {
+		if found := reg.FindByCapability(q); len(found) != 1 {
+			t.Errorf("FindByCapability(%q): got %d profiles", q, len(found))
+		}
+	}
+}
+
+func TestNegotiationHandlerAliases(t *testing.T) {
+	aliases := core.NewCapabilityAliases(core.CapabilityAlias{Alias: "code-gen", Canonical: "code-generation"})
+	provider, _ := core.NewAgent("p", []string{"code-gen"})
+	requester, _ := core.NewAgent("r", nil)
+	intent, _ := core.CreateIntent(requester, nil, []string{"code-generation"}, "x")
+
+	resp, _ := core.DefaultNegotiationHandler(provider)(intent)
+	if resp.Accepted {
+		t.Error("without aliases the names should not match")
+	}
+	resp, _ = core.DefaultNegotiationHandlerWithAliases(provider, aliases)(intent)
+	if !resp.Accepted {
+		t.Errorf("with aliases: rejected: %s", resp.Reason)
+	}
+}
+
+func TestAliasDeprecationsReported(t *testing.T) {
+	aliases := core.NewCapabilityAliases(core.CapabilityAlias{Alias: "code-gen", Canonical: "code-generation", Deprecated: true})
+	d := core.NewDeprecationTracker()
+	aliases.RegisterDeprecations(d)
+	d.ObserveCapabilities("did:old", []string{"code-gen"})
+	s := d.Summary()
+	if len(s) != 1 || s[0].Kind != core.DeprecatedCapability {
+		t.Errorf("got %+v", s)
+	}
+}
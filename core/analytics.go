@@ -0,0 +1,222 @@
+package core
+
+// analytics.go — Capability usage analytics.
+//
+// CapabilityAnalytics counts how an agent's capabilities are used: how
+// often each is requested, how often the agent accepts, how long it takes
+// to answer and how many distinct agents ask.  Samples are rolled up into
+// fixed ring buffers per window — sixty 1-second buckets for the last
+// minute, sixty 1-minute buckets for the last hour and twenty-four 1-hour
+// buckets for the last day — so memory stays bounded however busy the
+// agent is.  Query results can steer pricing, scaling or which
+// capabilities to announce.
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Analytics windows accepted by CapabilityAnalytics.Query.
+const (
+	WindowMinute = time.Minute
+	WindowHour   = time.Hour
+	WindowDay    = 24 * time.Hour
+)
+
+// analyticsRings lists the ring buffers kept per capability.
+var analyticsRings = []struct {
+	window, resolution time.Duration
+}{
+	{WindowMinute, time.Second},
+	{WindowHour, time.Minute},
+	{WindowDay, time.Hour},
+}
+
+// UsageSample is one answered request for a capability.
+type UsageSample struct {
+	Capability   string
+	RequesterDID string
+	Accepted     bool
+	Latency      time.Duration // Time taken to answer
+	At           time.Time     // Zero means now
+}
+
+// CapabilityUsage summarises the use of one capability over a window.
+type CapabilityUsage struct {
+	Capability       string        `json:"capability"`
+	Window           time.Duration `json:"window_ns"`
+	Invocations      int           `json:"invocations"`
+	Accepted         int           `json:"accepted"`
+	AcceptanceRate   float64       `json:"acceptance_rate"` // Accepted / Invocations; 0 without invocations
+	MeanLatency      time.Duration `json:"mean_latency_ns"`
+	MaxLatency       time.Duration `json:"max_latency_ns"`
+	UniqueRequesters int           `json:"unique_requesters"`
+}
+
+// CapabilityAnalytics keeps rolling usage statistics per capability.  It is
+// safe for concurrent use.
+type CapabilityAnalytics struct {
+	mu    sync.Mutex
+	rings map[string][]*usageRing // capability → one ring per analyticsRings entry
+}
+
+// NewCapabilityAnalytics returns an empty CapabilityAnalytics.
+func NewCapabilityAnalytics() *CapabilityAnalytics {
+	return &CapabilityAnalytics{rings: make(map[string][]*usageRing)}
+}
+
+// Record adds one sample.
+func (a *CapabilityAnalytics) Record(s UsageSample) {
+	if s.At.IsZero() {
+		s.At = time.Now()
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	rs := a.rings[s.Capability]
+	if rs == nil {
+		rs = make([]*usageRing, len(analyticsRings))
+		for i, r := range analyticsRings {
+			rs[i] = newUsageRing(r.window, r.resolution)
+		}
+		a.rings[s.Capability] = rs
+	}
+	for _, r := range rs {
+		r.add(s)
+	}
+}
+
+// Query summarises capability over the window ending now.  window must be
+// WindowMinute, WindowHour or WindowDay.
+func (a *CapabilityAnalytics) Query(capability string, window time.Duration) (CapabilityUsage, error) {
+	return a.QueryAt(capability, window, time.Now())
+}
+
+// QueryAt is Query for the window ending at now.
+func (a *CapabilityAnalytics) QueryAt(capability string, window time.Duration, now time.Time) (CapabilityUsage, error) {
+	i, err := ringIndex(window)
+	if err != nil {
+		return CapabilityUsage{}, err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	u := CapabilityUsage{Capability: capability, Window: window}
+	if rs := a.rings[capability]; rs != nil {
+		rs[i].summarise(&u, now)
+	}
+	return u, nil
+}
+
+// Snapshot summarises every capability with invocations in the window
+// ending now, busiest first.  It is the export format of the analytics:
+// it marshals to JSON as it is.
+func (a *CapabilityAnalytics) Snapshot(window time.Duration) ([]CapabilityUsage, error) {
+	i, err := ringIndex(window)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	a.mu.Lock()
+	out := []CapabilityUsage{}
+	for c, rs := range a.rings {
+		u := CapabilityUsage{Capability: c, Window: window}
+		rs[i].summarise(&u, now)
+		if u.Invocations > 0 {
+			out = append(out, u)
+		}
+	}
+	a.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Invocations != out[j].Invocations {
+			return out[i].Invocations > out[j].Invocations
+		}
+		return out[i].Capability < out[j].Capability
+	})
+	return out, nil
+}
+
+func ringIndex(window time.Duration) (int, error) {
+	for i, r := range analyticsRings {
+		if r.window == window {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("analytics: unsupported window %s", window)
+}
+
+// usageRing holds one window of samples in fixed-size buckets.  Bucket i
+// covers slot i mod len(buckets), where a slot is At truncated to the
+// resolution; a bucket holding an older slot is stale and reset on reuse.
+type usageRing struct {
+	resolution time.Duration
+	buckets    []usageBucket
+}
+
+type usageBucket struct {
+	slot         int64
+	invocations  int
+	accepted     int
+	latencySum   time.Duration
+	latencyMax   time.Duration
+	requesterDID map[string]struct{}
+}
+
+func newUsageRing(window, resolution time.Duration) *usageRing {
+	return &usageRing{resolution: resolution, buckets: make([]usageBucket, window/resolution)}
+}
+
+func (r *usageRing) slot(t time.Time) int64 { return t.UnixNano() / int64(r.resolution) }
+
+func (r *usageRing) add(s UsageSample) {
+	slot := r.slot(s.At)
+	b := &r.buckets[slot%int64(len(r.buckets))]
+	if b.slot != slot {
+		if b.slot > slot {
+			return // Older than the window the ring still covers
+		}
+		*b = usageBucket{slot: slot}
+	}
+	b.invocations++
+	if s.Accepted {
+		b.accepted++
+	}
+	b.latencySum += s.Latency
+	if s.Latency > b.latencyMax {
+		b.latencyMax = s.Latency
+	}
+	if s.RequesterDID != "" {
+		if b.requesterDID == nil {
+			b.requesterDID = make(map[string]struct{})
+		}
+		b.requesterDID[s.RequesterDID] = struct{}{}
+	}
+}
+
+// summarise adds the buckets within the window ending at now to u.
+func (r *usageRing) summarise(u *CapabilityUsage, now time.Time) {
+	last := r.slot(now)
+	first := last - int64(len(r.buckets)) + 1
+	requesters := map[string]struct{}{}
+	var latency time.Duration
+	for i := range r.buckets {
+		b := &r.buckets[i]
+		if b.invocations == 0 || b.slot < first || b.slot > last {
+			continue
+		}
+		u.Invocations += b.invocations
+		u.Accepted += b.accepted
+		latency += b.latencySum
+		if b.latencyMax > u.MaxLatency {
+			u.MaxLatency = b.latencyMax
+		}
+		for d := range b.requesterDID {
+			requesters[d] = struct{}{}
+		}
+	}
+	if u.Invocations > 0 {
+		u.AcceptanceRate = float64(u.Accepted) / float64(u.Invocations)
+		u.MeanLatency = latency / time.Duration(u.Invocations)
+	}
+	u.UniqueRequesters = len(requesters)
+}
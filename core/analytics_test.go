@@ -0,0 +1,54 @@
+package core_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func TestCapabilityAnalyticsWindows(t *testing.T) {
+	a := core.NewCapabilityAnalytics()
+	now := time.Now()
+	a.Record(core.UsageSample{Capability: "ocr", RequesterDID: "did:a", Accepted: true, Latency: 10 * time.Millisecond, At: now})
+	a.Record(core.UsageSample{Capability: "ocr", RequesterDID: "did:b", Accepted: false, Latency: 30 * time.Millisecond, At: now})
+	a.Record(core.UsageSample{Capability: "ocr", RequesterDID: "did:a", Accepted: true, Latency: 20 * time.Millisecond, At: now.Add(-10 * time.Minute)})
+
+	u, err := a.QueryAt("ocr", core.WindowMinute, now)
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if u.Invocations != 2 || u.Accepted != 1 || u.AcceptanceRate != 0.5 || u.UniqueRequesters != 2 {
+		t.Errorf("last minute: %+v", u)
+	}
+	if u.MeanLatency != 20*time.Millisecond || u.MaxLatency != 30*time.Millisecond {
+		t.Errorf("latency: mean %s max %s", u.MeanLatency, u.MaxLatency)
+	}
+
+	if u, _ = a.QueryAt("ocr", core.WindowHour, now); u.Invocations != 3 || u.UniqueRequesters != 2 {
+		t.Errorf("last hour: %+v", u)
+	}
+	// A day later everything has rolled out of every window.
+	if u, _ = a.QueryAt("ocr", core.WindowDay, now.Add(25*time.Hour)); u.Invocations != 0 {
+		t.Errorf("after a day: %+v", u)
+	}
+	if _, err = a.Query("ocr", 5*time.Minute); err == nil {
+		t.Error("unsupported window accepted")
+	}
+}
+
+func TestCapabilityAnalyticsSnapshot(t *testing.T) {
+	a := core.NewCapabilityAnalytics()
+	for i := 0; i < 3; i++ {
+		a.Record(core.UsageSample{Capability: "nlp", Accepted: true})
+	}
+	a.Record(core.UsageSample{Capability: "ocr", Accepted: true})
+
+	got, err := a.Snapshot(core.WindowHour)
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+	if len(got) != 2 || got[0].Capability != "nlp" || got[0].Invocations != 3 || got[1].Capability != "ocr" {
+		t.Errorf("snapshot: %+v", got)
+	}
+}
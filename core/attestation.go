@@ -0,0 +1,177 @@
+package core
+
+// attestation.go — Trust attestations exchanged between peers.
+//
+// A TrustAttestation is agent A's signed statement "I trust C this much".
+// A sends it to B; B verifies A's signature, checks it against its
+// AttestationPolicy and records the score as the edge A->C in its own
+// TrustGraph.  B's direct trust in C is untouched, but ComputeTransitiveTrust
+// now finds the path B->A->C, so reputation earned with A carries over to B
+// in proportion to how much B trusts A.
+//
+// Like revocation lists, attestations are snapshots: a newer attestation
+// from the same issuer about the same subject replaces the older one, and
+// replaying an old one has no effect.
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultAttestationTTL is how long an attestation stays valid when its
+// issuer does not say otherwise.
+const DefaultAttestationTTL = 24 * time.Hour
+
+// ErrAttestationPolicy is returned by AttestationRegistry.Accept for
+// verified attestations that the receiver's policy refuses.
+var ErrAttestationPolicy = fmt.Errorf("attestation: refused by policy")
+
+// NewTrustAttestation creates and signs issuer's statement that it trusts
+// subjectDID with score, valid for ttl (never expiring when ttl is 0).
+func NewTrustAttestation(issuer *Agent, subjectDID string, score float32, ttl time.Duration) (*TrustAttestation, error) {
+	a := &TrustAttestation{
+		IssuerDID:  issuer.DID.String(),
+		IssuerKey:  issuer.PublicKey(),
+		SubjectDID: subjectDID,
+		Score:      clamp(score),
+		IssuedAt:   now(),
+	}
+	if ttl > 0 {
+		a.ExpiresAt = a.IssuedAt + int64(ttl)
+	}
+	sig, err := issuer.Sign(attestationSigningData(a))
+	if err != nil {
+		return nil, fmt.Errorf("attestation: sign: %w", err)
+	}
+	a.Signature = sig
+	return a, nil
+}
+
+// VerifyTrustAttestation checks that a is well formed and signed by the key
+// bound to IssuerDID.  Unsigned attestations are always rejected.
+func VerifyTrustAttestation(a *TrustAttestation) error {
+	if len(a.Signature) == 0 {
+		return fmt.Errorf("attestation: from %s is unsigned", a.IssuerDID)
+	}
+	if a.SubjectDID == "" || a.SubjectDID == a.IssuerDID {
+		return fmt.Errorf("attestation: from %s has an invalid subject %q", a.IssuerDID, a.SubjectDID)
+	}
+	if math.IsNaN(float64(a.Score)) || a.Score < 0 || a.Score > 1 {
+		return fmt.Errorf("attestation: score %v outside [0,1]", a.Score)
+	}
+	d, err := ParseDID(a.IssuerDID)
+	if err != nil {
+		return fmt.Errorf("attestation: %w", err)
+	}
+	if !d.ValidateBinding(a.IssuerKey) {
+		return fmt.Errorf("attestation: issuer key does not match %s", a.IssuerDID)
+	}
+	pub, err := DIDFromPublicKey(a.IssuerKey)
+	if err != nil {
+		return fmt.Errorf("attestation: %w", err)
+	}
+	if !pub.Verify(attestationSigningData(a), a.Signature) {
+		return fmt.Errorf("attestation: %w from %s", ErrSignatureInvalid, a.IssuerDID)
+	}
+	return nil
+}
+
+// Expired reports whether the attestation has an expiry at or before t.
+func (m *TrustAttestation) Expired(t time.Time) bool {
+	return m.ExpiresAt != 0 && t.UnixNano() >= m.ExpiresAt
+}
+
+// AttestationPolicy decides which verified attestations an agent adopts.
+// The zero policy adopts every verified, unexpired attestation.
+type AttestationPolicy struct {
+	// MinIssuerTrust is the direct trust the receiver must have in the
+	// issuer, so strangers cannot vouch for each other.
+	MinIssuerTrust float32
+
+	// MaxAge refuses attestations issued longer ago; 0 for no limit.
+	MaxAge time.Duration
+
+	// Issuers, when set, lists the only DIDs whose attestations count.
+	Issuers []string
+}
+
+// AttestationRegistry keeps the newest accepted attestation per issuer and
+// subject and mirrors it into a TrustGraph.  It is concurrency-safe.
+type AttestationRegistry struct {
+	mu     sync.Mutex
+	self   string
+	trust  *TrustGraph
+	policy AttestationPolicy
+	latest map[string]*TrustAttestation // keyed by trustKey(issuer, subject)
+}
+
+// NewAttestationRegistry creates a registry that records attestations
+// accepted on behalf of self into trust.
+func NewAttestationRegistry(self *Agent, trust *TrustGraph, policy AttestationPolicy) *AttestationRegistry {
+	return &AttestationRegistry{
+		self:   self.DID.String(),
+		trust:  trust,
+		policy: policy,
+		latest: make(map[string]*TrustAttestation),
+	}
+}
+
+// Accept verifies a, applies the policy and, if a is newer than what the
+// registry holds for its issuer and subject, records it.  Policy refusals
+// wrap ErrAttestationPolicy.
+func (r *AttestationRegistry) Accept(a *TrustAttestation) error {
+	if err := VerifyTrustAttestation(a); err != nil {
+		return err
+	}
+	t := time.Now()
+	switch {
+	case a.IssuerDID == r.self:
+		return fmt.Errorf("%w: issued by this agent", ErrAttestationPolicy)
+	case a.Expired(t):
+		return fmt.Errorf("%w: expired", ErrAttestationPolicy)
+	case r.policy.MaxAge > 0 && t.Sub(time.Unix(0, a.IssuedAt)) > r.policy.MaxAge:
+		return fmt.Errorf("%w: older than %s", ErrAttestationPolicy, r.policy.MaxAge)
+	case len(r.policy.Issuers) > 0 && !slices.Contains(r.policy.Issuers, a.IssuerDID):
+		return fmt.Errorf("%w: issuer %s not allowed", ErrAttestationPolicy, a.IssuerDID)
+	}
+	if got := r.trust.Get(r.self, a.IssuerDID); got < r.policy.MinIssuerTrust {
+		return fmt.Errorf("%w: trust in issuer %.2f below %.2f", ErrAttestationPolicy, got, r.policy.MinIssuerTrust)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	k := trustKey(a.IssuerDID, a.SubjectDID)
+	if prev, ok := r.latest[k]; ok && prev.IssuedAt >= a.IssuedAt {
+		return nil
+	}
+	r.latest[k] = a
+	return r.trust.Set(a.IssuerDID, a.SubjectDID, a.Score)
+}
+
+// About returns the unexpired attestations held about subjectDID, ordered
+// by issuer.
+func (r *AttestationRegistry) About(subjectDID string) []*TrustAttestation {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t := time.Now()
+	var out []*TrustAttestation
+	for _, a := range r.latest {
+		if a.SubjectDID == subjectDID && !a.Expired(t) {
+			out = append(out, a)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].IssuerDID < out[j].IssuerDID })
+	return out
+}
+
+// attestationSigningData is the encoded attestation without its signature.
+func attestationSigningData(a *TrustAttestation) []byte {
+	c := *a
+	c.Signature = nil
+	data, _ := c.Encode()
+	return data
+}
@@ -0,0 +1,93 @@
+package core_test
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func TestTrustAttestationRoundTrip(t *testing.T) {
+	issuer, _ := core.NewAgent("a", nil)
+	subject, _ := core.NewAgent("c", nil)
+	a, err := core.NewTrustAttestation(issuer, subject.DID.String(), 0.75, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []core.Codec{core.ProtobufCodec, core.JSONCodec, core.CBORCodec} {
+		frame, err := core.FrameWith(c, a)
+		if err != nil {
+			t.Fatalf("%s: %v", c.Name(), err)
+		}
+		got, err := core.DecodeFrame(frame)
+		if err != nil {
+			t.Fatalf("%s: %v", c.Name(), err)
+		}
+		if !reflect.DeepEqual(got, a) {
+			t.Errorf("%s: got %+v, want %+v", c.Name(), got, a)
+		}
+		if err = core.VerifyTrustAttestation(got.(*core.TrustAttestation)); err != nil {
+			t.Errorf("%s: %v", c.Name(), err)
+		}
+	}
+
+	forged := *a
+	forged.Score = 1
+	if core.VerifyTrustAttestation(&forged) == nil {
+		t.Error("modified score verified")
+	}
+	self, _ := core.NewTrustAttestation(issuer, issuer.DID.String(), 1, 0)
+	if core.VerifyTrustAttestation(self) == nil {
+		t.Error("self-attestation verified")
+	}
+}
+
+func TestAttestationRegistry(t *testing.T) {
+	self, _ := core.NewAgent("b", nil)
+	friend, _ := core.NewAgent("a", nil)
+	stranger, _ := core.NewAgent("x", nil)
+	subject, _ := core.NewAgent("c", nil)
+	tg := core.NewTrustGraph()
+	_ = tg.Set(self.DID.String(), friend.DID.String(), 0.8)
+	_ = tg.Set(self.DID.String(), stranger.DID.String(), 0.1)
+	reg := core.NewAttestationRegistry(self, tg, core.AttestationPolicy{MinIssuerTrust: 0.5, MaxAge: time.Hour})
+
+	older, _ := core.NewTrustAttestation(friend, subject.DID.String(), 0.9, time.Hour)
+	newer, _ := core.NewTrustAttestation(friend, subject.DID.String(), 0.5, time.Hour)
+	if err := reg.Accept(newer); err != nil {
+		t.Fatal(err)
+	}
+	// Replaying an older attestation changes nothing.
+	if err := reg.Accept(older); err != nil {
+		t.Fatal(err)
+	}
+	if got := tg.ComputeTransitiveTrust(self.DID.String(), subject.DID.String(), 2); math.Abs(float64(got-0.4)) > 1e-6 {
+		t.Errorf("transitive trust through attestation: got %v, want 0.4", got)
+	}
+	if got := tg.Get(self.DID.String(), subject.DID.String()); got != 0 {
+		t.Errorf("direct trust changed to %v", got)
+	}
+	if about := reg.About(subject.DID.String()); len(about) != 1 || about[0] != newer {
+		t.Errorf("About: %+v", about)
+	}
+
+	weak, _ := core.NewTrustAttestation(stranger, subject.DID.String(), 1, time.Hour)
+	expired, _ := core.NewTrustAttestation(friend, stranger.DID.String(), 1, time.Nanosecond)
+	own, _ := core.NewTrustAttestation(self, subject.DID.String(), 1, time.Hour)
+	for name, a := range map[string]*core.TrustAttestation{"untrusted issuer": weak, "expired": expired, "own": own} {
+		if err := reg.Accept(a); !errors.Is(err, core.ErrAttestationPolicy) {
+			t.Errorf("%s: got %v", name, err)
+		}
+	}
+
+	allow := core.NewAttestationRegistry(self, core.NewTrustGraph(), core.AttestationPolicy{Issuers: []string{friend.DID.String()}})
+	if err := allow.Accept(weak); !errors.Is(err, core.ErrAttestationPolicy) {
+		t.Errorf("issuer outside allow list: got %v", err)
+	}
+	if err := allow.Accept(newer); err != nil {
+		t.Errorf("allowed issuer: %v", err)
+	}
+}
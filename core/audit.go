@@ -0,0 +1,231 @@
+package core
+
+// audit.go — Signed, hash-chained audit log.
+//
+// An AuditLog appends one record per processed message to a JSON-lines
+// file: the message's SHA-256, the peer, the agent's decision and the hash
+// of the previous record, all signed with the agent's DID key.  Each
+// record's hash covers its predecessor's, so editing, inserting or removing
+// a record breaks every hash after it, and only the key holder can re-sign
+// a rewritten chain.  VerifyAuditLog checks a file without talking to the
+// agent; publishing the Head now and then (say, in an alert or a
+// transparency log) also pins the chain against wholesale rewriting.
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// AuditRecord is one entry of an AuditLog.
+type AuditRecord struct {
+	Seq         uint64      `json:"seq"` // 1 for the first record
+	MsgType     MessageType `json:"msg_type"`
+	MessageID   string      `json:"message_id,omitempty"` // Intent or request ID, for lookups
+	MessageHash []byte      `json:"message_hash"`         // SHA-256 of the protobuf encoding
+	PeerDID     string      `json:"peer_did,omitempty"`
+	Decision    string      `json:"decision,omitempty"` // e.g. "accepted", "rejected", "error"
+	RecordedAt  int64       `json:"recorded_at,string"` // Unix nanoseconds
+	PrevHash    []byte      `json:"prev_hash,omitempty"`
+	Hash        []byte      `json:"hash"`
+	SignerKey   []byte      `json:"signer_key"` // Ed25519 public key of the recording agent
+	Signature   []byte      `json:"signature"`  // Signer's signature of Hash
+}
+
+// AuditLog appends signed, hash-chained records to a file.  It is safe for
+// concurrent use.
+type AuditLog struct {
+	mu     sync.Mutex
+	f      *os.File
+	path   string
+	signer *Agent
+	seq    uint64
+	head   []byte
+}
+
+// OpenAuditLog opens the audit log at path for appending, creating it if
+// needed, with records signed by signer.  An existing log is verified first
+// and continued from its last record; it must have been written by signer.
+func OpenAuditLog(path string, signer *Agent) (*AuditLog, error) {
+	l := &AuditLog{path: path, signer: signer}
+	records, err := ReadAuditLog(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if len(records) > 0 {
+		did, err := VerifyAuditRecords(records)
+		if err != nil {
+			return nil, fmt.Errorf("audit: %s: %w", path, err)
+		}
+		if did != signer.DID.String() {
+			return nil, fmt.Errorf("audit: %s was written by %s, not %s", path, did, signer.DID)
+		}
+		last := records[len(records)-1]
+		l.seq, l.head = last.Seq, last.Hash
+	}
+	l.f, err = os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("audit: open %s: %w", path, err)
+	}
+	return l, nil
+}
+
+// Append records msg, exchanged with peerDID, and the decision taken on it.
+func (l *AuditLog) Append(msg Encoder, peerDID, decision string) (*AuditRecord, error) {
+	data, err := msg.Encode()
+	if err != nil {
+		return nil, fmt.Errorf("audit: encode: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	r := &AuditRecord{
+		MsgType:     msg.MsgType(),
+		MessageID:   auditMessageID(msg),
+		MessageHash: sum[:],
+		PeerDID:     peerDID,
+		Decision:    decision,
+		RecordedAt:  now(),
+		SignerKey:   l.signer.PublicKey(),
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	r.Seq = l.seq + 1
+	r.PrevHash = l.head
+	r.Hash = r.chainHash()
+	if r.Signature, err = l.signer.Sign(r.Hash); err != nil {
+		return nil, fmt.Errorf("audit: sign: %w", err)
+	}
+	line, err := json.Marshal(r)
+	if err != nil {
+		return nil, fmt.Errorf("audit: encode: %w", err)
+	}
+	if _, err = l.f.Write(append(line, '\n')); err != nil {
+		return nil, fmt.Errorf("audit: write %s: %w", l.path, err)
+	}
+	l.seq, l.head = r.Seq, r.Hash
+	return r, nil
+}
+
+// Head returns the hash of the last record, in hex, or "" for an empty log.
+func (l *AuditLog) Head() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return hex.EncodeToString(l.head)
+}
+
+// Close closes the underlying file.
+func (l *AuditLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.f.Close()
+}
+
+// ReadAuditLog reads every record in a file written by AuditLog without
+// verifying them.
+func ReadAuditLog(path string) ([]*AuditRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	var out []*AuditRecord
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 1<<20)
+	for line := 1; sc.Scan(); line++ {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var r AuditRecord
+		if err = json.Unmarshal(sc.Bytes(), &r); err != nil {
+			return nil, fmt.Errorf("audit: %s:%d: %w", path, line, err)
+		}
+		out = append(out, &r)
+	}
+	if err = sc.Err(); err != nil {
+		return nil, fmt.Errorf("audit: read %s: %w", path, err)
+	}
+	return out, nil
+}
+
+// VerifyAuditLog reads and verifies the audit log at path, returning the
+// signer's DID and the records.
+func VerifyAuditLog(path string) (string, []*AuditRecord, error) {
+	records, err := ReadAuditLog(path)
+	if err != nil {
+		return "", nil, err
+	}
+	did, err := VerifyAuditRecords(records)
+	return did, records, err
+}
+
+// VerifyAuditRecords checks that records form one unbroken chain from the
+// start of a log, all signed by the same key, and returns the signer's DID.
+func VerifyAuditRecords(records []*AuditRecord) (string, error) {
+	if len(records) == 0 {
+		return "", nil
+	}
+	signer, err := DIDFromPublicKey(records[0].SignerKey)
+	if err != nil {
+		return "", fmt.Errorf("audit: record 1: signer key: %w", err)
+	}
+	var prev []byte
+	for i, r := range records {
+		switch {
+		case r.Seq != uint64(i+1):
+			return "", fmt.Errorf("audit: record %d: sequence %d out of order", i+1, r.Seq)
+		case !bytes.Equal(r.PrevHash, prev):
+			return "", fmt.Errorf("audit: record %d: does not follow record %d", r.Seq, i)
+		case !bytes.Equal(r.chainHash(), r.Hash):
+			return "", fmt.Errorf("audit: record %d: hash mismatch", r.Seq)
+		case !bytes.Equal(r.SignerKey, records[0].SignerKey):
+			return "", fmt.Errorf("audit: record %d: signed by a different key", r.Seq)
+		case !signer.Verify(r.Hash, r.Signature):
+			return "", fmt.Errorf("audit: record %d: %w", r.Seq, ErrSignatureInvalid)
+		}
+		prev = r.Hash
+	}
+	return signer.String(), nil
+}
+
+// chainHash is sha256 over the previous hash and every other field but the
+// signature, length-prefixed so that fields cannot bleed into each other.
+func (r *AuditRecord) chainHash() []byte {
+	h := sha256.New()
+	var n [8]byte
+	put := func(b []byte) {
+		binary.BigEndian.PutUint64(n[:], uint64(len(b)))
+		h.Write(n[:])
+		h.Write(b)
+	}
+	put(r.PrevHash)
+	binary.BigEndian.PutUint64(n[:], r.Seq)
+	h.Write(n[:])
+	h.Write([]byte{byte(r.MsgType)})
+	put([]byte(r.MessageID))
+	put(r.MessageHash)
+	put([]byte(r.PeerDID))
+	put([]byte(r.Decision))
+	binary.BigEndian.PutUint64(n[:], uint64(r.RecordedAt))
+	h.Write(n[:])
+	put(r.SignerKey)
+	return h.Sum(nil)
+}
+
+// auditMessageID returns the ID operators look messages up by.
+func auditMessageID(msg Encoder) string {
+	switch m := msg.(type) {
+	case *IntentMessage:
+		return m.ID
+	case *NegotiationResponse:
+		return m.RequestID
+	case *WorkflowMessage:
+		return m.WorkflowID
+	}
+	return ""
+}
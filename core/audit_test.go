@@ -0,0 +1,70 @@
+package core_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func TestAuditLogChain(t *testing.T) {
+	a, _ := core.NewAgent("a", []string{"nlp"})
+	peer, _ := core.NewAgent("peer", nil)
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	l, err := core.OpenAuditLog(path, a)
+	if err != nil {
+		t.Fatalf("OpenAuditLog: %v", err)
+	}
+	intent, _ := core.CreateIntent(peer, nil, []string{"nlp"}, "x")
+	if _, err = l.Append(intent, peer.DID.String(), "accepted"); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	_ = l.Close()
+
+	// Reopening continues the chain.
+	if l, err = core.OpenAuditLog(path, a); err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	r, err := l.Append(intent, peer.DID.String(), "rejected")
+	if err != nil || r.Seq != 2 {
+		t.Fatalf("Append after reopen: %+v %v", r, err)
+	}
+	head := l.Head()
+	_ = l.Close()
+
+	did, records, err := core.VerifyAuditLog(path)
+	if err != nil || did != a.DID.String() || len(records) != 2 || records[0].MessageID != intent.ID {
+		t.Fatalf("VerifyAuditLog: %s %d %v", did, len(records), err)
+	}
+	if head != fmt.Sprintf("%x", records[1].Hash) {
+		t.Errorf("head %s does not match the last record", head)
+	}
+	if !bytes.Equal(records[1].PrevHash, records[0].Hash) {
+		t.Error("records not chained")
+	}
+
+	// Another agent may not continue the log.
+	if _, err = core.OpenAuditLog(path, peer); err == nil {
+		t.Error("log reopened by another signer")
+	}
+
+	// Rewriting a decision breaks the chain.
+	data, _ := os.ReadFile(path)
+	tampered := strings.Replace(string(data), `"decision":"rejected"`, `"decision":"accepted"`, 1)
+	_ = os.WriteFile(path, []byte(tampered), 0o600)
+	if _, _, err = core.VerifyAuditLog(path); err == nil {
+		t.Error("tampered log verified")
+	}
+
+	// So does dropping the first record.
+	lines := strings.SplitAfter(string(data), "\n")
+	_ = os.WriteFile(path, []byte(strings.Join(lines[1:], "")), 0o600)
+	if _, _, err = core.VerifyAuditLog(path); err == nil {
+		t.Error("truncated log verified")
+	}
+}
@@ -0,0 +1,109 @@
+package core
+
+// billing.go — Aggregating completion receipts into billing reports.
+//
+// A report covers a time range and has one line per provider, requester,
+// capability and currency, so two teams or companies can settle usage from
+// the receipts either of them holds.  Receipts that fail verification are
+// listed as rejected rather than billed, and a receipt seen twice (same
+// provider and intent) is billed once.
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// BillingLine aggregates the receipts of one provider, requester,
+// capability and currency.
+type BillingLine struct {
+	Provider   string `json:"provider"`
+	Requester  string `json:"requester"`
+	Capability string `json:"capability"`
+	Currency   string `json:"currency,omitempty"`
+	Count      int    `json:"count"`
+	Units      int64  `json:"units"`
+	Amount     int64  `json:"amount"`
+}
+
+// RejectedReceipt is a receipt left out of a report.
+type RejectedReceipt struct {
+	IntentID string `json:"intent_id"`
+	Provider string `json:"provider"`
+	Reason   string `json:"reason"`
+}
+
+// BillingReport is the result of BuildBillingReport.
+type BillingReport struct {
+	From     time.Time         `json:"from"`
+	To       time.Time         `json:"to"`
+	Lines    []BillingLine     `json:"lines"`
+	Rejected []RejectedReceipt `json:"rejected,omitempty"`
+}
+
+// BuildBillingReport aggregates the receipts completed in [from, to).  A
+// zero from or to leaves that end of the range open.
+func BuildBillingReport(receipts []*CompletionReceipt, from, to time.Time) *BillingReport {
+	type key struct{ provider, requester, capability, currency string }
+	rep := &BillingReport{From: from, To: to, Lines: []BillingLine{}}
+	lines := make(map[key]*BillingLine)
+	seen := make(map[[2]string]bool)
+	for _, r := range receipts {
+		t := r.Time()
+		if (!from.IsZero() && t.Before(from)) || (!to.IsZero() && !t.Before(to)) {
+			continue
+		}
+		if err := r.Verify(); err != nil {
+			rep.Rejected = append(rep.Rejected, RejectedReceipt{IntentID: r.IntentID, Provider: r.Provider, Reason: err.Error()})
+			continue
+		}
+		id := [2]string{r.Provider, r.IntentID}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		k := key{r.Provider, r.Requester, r.Capability, r.Cost.Currency}
+		l := lines[k]
+		if l == nil {
+			l = &BillingLine{Provider: k.provider, Requester: k.requester, Capability: k.capability, Currency: k.currency}
+			lines[k] = l
+		}
+		l.Count++
+		l.Units += r.Cost.Units
+		l.Amount += r.Cost.Amount
+	}
+	for _, l := range lines {
+		rep.Lines = append(rep.Lines, *l)
+	}
+	sort.Slice(rep.Lines, func(i, j int) bool {
+		a, b := rep.Lines[i], rep.Lines[j]
+		if a.Provider != b.Provider {
+			return a.Provider < b.Provider
+		}
+		if a.Requester != b.Requester {
+			return a.Requester < b.Requester
+		}
+		if a.Capability != b.Capability {
+			return a.Capability < b.Capability
+		}
+		return a.Currency < b.Currency
+	})
+	return rep
+}
+
+// WriteCSV writes the report's lines as CSV with a header row.  Rejected
+// receipts are not included.
+func (rep *BillingReport) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"provider", "requester", "capability", "currency", "count", "units", "amount"})
+	for _, l := range rep.Lines {
+		_ = cw.Write([]string{
+			l.Provider, l.Requester, l.Capability, l.Currency,
+			strconv.Itoa(l.Count), strconv.FormatInt(l.Units, 10), strconv.FormatInt(l.Amount, 10),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,88 @@
+package core_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func TestBillingReport(t *testing.T) {
+	provider, _ := core.NewAgent("nlp", []string{"summarise", "translate"})
+	alice, _ := core.NewAgent("alice", nil)
+	bob, _ := core.NewAgent("bob", nil)
+
+	issue := func(from *core.Agent, capability string, amount int64) *core.CompletionReceipt {
+		intent, err := core.CreateIntent(from, nil, []string{capability}, "work")
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := core.IssueReceipt(provider, intent, core.Cost{Amount: amount, Currency: "credits", Units: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	path := filepath.Join(t.TempDir(), "receipts.jsonl")
+	log, err := core.OpenReceiptLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := issue(alice, "summarise", 5)
+	forged := issue(bob, "summarise", 1)
+	forged.Cost.Amount = 0
+	for _, r := range []*core.CompletionReceipt{
+		first,
+		first, // logged twice, billed once
+		issue(alice, "summarise", 7),
+		issue(alice, "translate", 3),
+		issue(bob, "summarise", 2),
+		forged,
+	} {
+		if err = log.Append(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = log.Close()
+
+	receipts, err := core.ReadReceipts(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(receipts) != 6 {
+		t.Fatalf("read %d receipts, want 6", len(receipts))
+	}
+	rep := core.BuildBillingReport(receipts, time.Time{}, time.Time{})
+	if len(rep.Lines) != 3 {
+		t.Fatalf("got %d lines, want 3: %+v", len(rep.Lines), rep.Lines)
+	}
+	var aliceSum *core.BillingLine
+	for i, l := range rep.Lines {
+		if l.Requester == alice.DID.String() && l.Capability == "summarise" {
+			aliceSum = &rep.Lines[i]
+		}
+	}
+	if aliceSum == nil || aliceSum.Count != 2 || aliceSum.Amount != 12 || aliceSum.Units != 20 {
+		t.Errorf("alice/summarise line = %+v, want 2 receipts, 12 credits, 20 units", aliceSum)
+	}
+	if len(rep.Rejected) != 1 || rep.Rejected[0].IntentID != forged.IntentID {
+		t.Errorf("rejected = %+v, want the forged receipt", rep.Rejected)
+	}
+
+	if got := core.BuildBillingReport(receipts, time.Now().Add(time.Hour), time.Time{}); len(got.Lines) != 0 {
+		t.Errorf("future range billed %+v", got.Lines)
+	}
+
+	var buf bytes.Buffer
+	if err = rep.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	rows := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(rows) != 4 || rows[0] != "provider,requester,capability,currency,count,units,amount" {
+		t.Errorf("CSV:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,137 @@
+package core
+
+// bufpool.go — Pooled frame encoding.
+//
+// Frame and FrameV2 take an encoded payload and copy it into a freshly
+// allocated frame, so every message sent costs two allocations the size of
+// its payload.  WriteFrame and WriteFrameV2 instead reserve the frame
+// header at the front of a pooled buffer, let an Appender encode straight
+// after it, fill the header in and write the result.  For large
+// IntentVector payloads on hot paths this leaves nothing to allocate.
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"sync"
+)
+
+// Appender is implemented by messages that can append their protobuf
+// encoding to a buffer instead of allocating a new one.
+type Appender interface {
+	AppendEncode(b []byte) ([]byte, error)
+}
+
+// maxPooledFrame is the largest buffer returned to the pool; bigger ones
+// are left to the garbage collector so one outsized frame does not pin its
+// memory for good.
+const maxPooledFrame = 1 << 20
+
+var framePool = sync.Pool{New: func() any {
+	b := make([]byte, 0, 4096)
+	return &b
+}}
+
+func getFrameBuf() *[]byte { return framePool.Get().(*[]byte) }
+
+func putFrameBuf(b *[]byte) {
+	if cap(*b) > maxPooledFrame {
+		return
+	}
+	*b = (*b)[:0]
+	framePool.Put(b)
+}
+
+// AppendPayload appends msg, encoded with c, to b.  Protobuf messages that
+// implement Appender are encoded in place.
+func AppendPayload(b []byte, c Codec, msg Encoder) ([]byte, error) {
+	if a, ok := msg.(Appender); ok && c.ID() == CodecProtobuf {
+		return a.AppendEncode(b)
+	}
+	payload, err := c.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	return append(b, payload...), nil
+}
+
+// WriteFrame encodes msg with c and writes it to w as a v1 frame, like
+// FrameWith but through a pooled buffer.
+func WriteFrame(w io.Writer, c Codec, msg Encoder) error {
+	bp := getFrameBuf()
+	defer putFrameBuf(bp)
+	buf, err := AppendPayload(append((*bp)[:0], 0, 0, 0, 0, 0), c, msg)
+	if err != nil {
+		return err
+	}
+	*bp = buf
+	binary.BigEndian.PutUint32(buf[:4], uint32(len(buf)-4))
+	buf[4] = FrameType(msg.MsgType(), c.ID())
+	_, err = w.Write(buf)
+	return err
+}
+
+// WriteFrameV2 encodes msg with c and writes it to w as a v2 frame, like
+// FrameWithV2 but through a pooled buffer.  Payloads that get compressed
+// take the FrameV2 path, since DEFLATE needs a buffer of its own.
+func WriteFrameV2(w io.Writer, c Codec, msg Encoder, compress bool) error {
+	bp := getFrameBuf()
+	defer putFrameBuf(bp)
+	buf, err := AppendPayload(append((*bp)[:0], FrameV2Magic, 0, 0, 0, 0, 0, 0), c, msg)
+	if err != nil {
+		return err
+	}
+	*bp = buf
+	return finishFrameV2(w, bp, FrameType(msg.MsgType(), c.ID()), 0, compress)
+}
+
+// WriteQuantizedFrame is WriteFrameV2 with the vectors of a protobuf
+// payload packed as q (see QuantizedVector).  Other codecs, messages
+// without vectors, and messages with a signed vector that packing would
+// change are written as by WriteFrameV2.
+func WriteQuantizedFrame(w io.Writer, c Codec, msg Encoder, compress bool, q Quantization) error {
+	if q == QuantizeNone || c.ID() != CodecProtobuf || !hasVectors(msg.MsgType()) || !keepsSignatures(msg, q) {
+		return WriteFrameV2(w, c, msg, compress)
+	}
+	src := getFrameBuf()
+	defer putFrameBuf(src)
+	payload, err := AppendPayload((*src)[:0], c, msg)
+	if err != nil {
+		return err
+	}
+	*src = payload
+	bp := getFrameBuf()
+	defer putFrameBuf(bp)
+	buf, err := quantizePayload(append((*bp)[:0], FrameV2Magic, 0, 0, 0, 0, 0, 0), msg.MsgType(), payload, q)
+	if err != nil {
+		return err
+	}
+	*bp = buf
+	return finishFrameV2(w, bp, FrameType(msg.MsgType(), c.ID()), q.frameFlag(), compress)
+}
+
+// finishFrameV2 fills in the header reserved at the front of *bp, which is
+// followed by the payload, appends the checksum and writes the frame to w.
+func finishFrameV2(w io.Writer, bp *[]byte, frameType byte, flags FrameFlags, compress bool) error {
+	buf := *bp
+	if compress && len(buf)-7 >= compressMin {
+		frame, err := frameV2(frameType, flags, buf[7:], true)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(frame)
+		return err
+	}
+	total := len(buf) - 6
+	if total > MaxFrameSize {
+		return fmt.Errorf("%w: %d bytes exceeds the %d byte limit", ErrFrameTooLarge, total, MaxFrameSize)
+	}
+	buf[1] = byte(flags)
+	binary.BigEndian.PutUint32(buf[2:6], uint32(total))
+	buf[6] = frameType
+	buf = binary.BigEndian.AppendUint32(buf, crc32.Checksum(buf[6:], castagnoli))
+	*bp = buf
+	_, err := w.Write(buf)
+	return err
+}
@@ -0,0 +1,95 @@
+package core_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func largeIntent(b testing.TB) *core.IntentMessage {
+	a, _ := core.NewAgent("bench", []string{"nlp"})
+	vec := make([]float32, 4096)
+	for i := range vec {
+		vec[i] = float32(i%97) / 97
+	}
+	intent, err := core.CreateIntent(a, vec, []string{"nlp"}, `{"task":"embed"}`)
+	if err != nil {
+		b.Fatalf("CreateIntent: %v", err)
+	}
+	return intent
+}
+
+func TestWriteFrameMatchesFrameWith(t *testing.T) {
+	intent := largeIntent(t)
+	batch := &core.IntentBatch{Intents: []*core.IntentMessage{intent, intent}}
+	for _, c := range []core.Codec{core.ProtobufCodec, core.JSONCodec} {
+		for _, msg := range []core.Encoder{intent, batch} {
+			want, _ := core.FrameWith(c, msg)
+			var got bytes.Buffer
+			if err := core.WriteFrame(&got, c, msg); err != nil {
+				t.Fatalf("WriteFrame: %v", err)
+			}
+			if !bytes.Equal(got.Bytes(), want) {
+				t.Errorf("%s %T: WriteFrame differs from FrameWith", c.Name(), msg)
+			}
+
+			for _, compress := range []bool{false, true} {
+				got.Reset()
+				if err := core.WriteFrameV2(&got, c, msg, compress); err != nil {
+					t.Fatalf("WriteFrameV2: %v", err)
+				}
+				want, _ := core.FrameWithV2(c, msg, compress)
+				if !bytes.Equal(got.Bytes(), want) {
+					t.Errorf("%s %T compress=%v: WriteFrameV2 differs from FrameWithV2", c.Name(), msg, compress)
+				}
+			}
+		}
+	}
+
+	var got bytes.Buffer
+	_ = core.WriteFrame(&got, core.ProtobufCodec, batch)
+	typ, payload, _, err := core.ReadFrame(&got)
+	if err != nil || core.MessageType(typ) != core.MsgIntentBatch {
+		t.Fatalf("ReadFrame: type %d, %v", typ, err)
+	}
+	decoded, err := core.DecodeIntentBatch(payload)
+	if err != nil || len(decoded.Intents) != 2 || len(decoded.Intents[1].IntentVector) != len(intent.IntentVector) {
+		t.Fatalf("DecodeIntentBatch: %+v, %v", decoded, err)
+	}
+}
+
+func BenchmarkFrameWith(b *testing.B) {
+	intent := largeIntent(b)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		frame, _ := core.FrameWith(core.ProtobufCodec, intent)
+		_, _ = io.Discard.Write(frame)
+	}
+}
+
+func BenchmarkWriteFrame(b *testing.B) {
+	intent := largeIntent(b)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = core.WriteFrame(io.Discard, core.ProtobufCodec, intent)
+	}
+}
+
+func BenchmarkFrameWithV2(b *testing.B) {
+	intent := largeIntent(b)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		frame, _ := core.FrameWithV2(core.ProtobufCodec, intent, false)
+		_, _ = io.Discard.Write(frame)
+	}
+}
+
+func BenchmarkWriteFrameV2(b *testing.B) {
+	intent := largeIntent(b)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = core.WriteFrameV2(io.Discard, core.ProtobufCodec, intent, false)
+	}
+}
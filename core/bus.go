@@ -0,0 +1,337 @@
+package core
+
+// bus.go — In-process negotiation between agents.
+//
+// A NegotiationBus lets agents in one process negotiate without a network,
+// for tests, examples and multi-agent simulations.  Agents register a
+// NegotiationHandler under their ID, optionally with the capabilities they
+// serve, and are then reached by ID (Negotiate, NegotiateAsync), by
+// capability (NegotiateCapability) or all at once (BroadcastIntent).
+//
+// Capabilities are registered as path.Match patterns, so an agent serving
+// "translate-*" is routed intents for "translate-fr" and "translate-de",
+// and one serving "*" is a catch-all.  An exact name beats a pattern.
+//
+// Every negotiation passes through the bus's middleware chain before
+// reaching the handler.  LoggingMiddleware, ValidationMiddleware and
+// MetricsMiddleware cover the common cases:
+//
+//	bus.Use(core.MetricsMiddleware(reg), core.ValidationMiddleware(keys))
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/metrics"
+)
+
+// BusHandler answers an intent addressed to the agent registered as target.
+type BusHandler func(ctx context.Context, target string, intent *IntentMessage) (*NegotiationResponse, error)
+
+// BusMiddleware wraps a BusHandler, to observe, alter or refuse the
+// negotiations passing through it.
+type BusMiddleware func(next BusHandler) BusHandler
+
+// BusResult is the outcome of one negotiation on a bus.
+type BusResult struct {
+	AgentID  string
+	Response *NegotiationResponse
+	Err      error
+}
+
+// NegotiationBus enables in-process agents to negotiate without a real network,
+// suitable for tests and examples.
+type NegotiationBus struct {
+	mu         sync.RWMutex
+	agents     map[string]busAgent // keyed by agentID
+	middleware []BusMiddleware
+	log        Logger
+}
+
+type busAgent struct {
+	handler      NegotiationHandler
+	capabilities []string // path.Match patterns
+}
+
+// NewNegotiationBus creates an empty NegotiationBus.
+func NewNegotiationBus() *NegotiationBus {
+	return &NegotiationBus{agents: make(map[string]busAgent), log: DiscardLogger()}
+}
+
+// SetLogger logs every negotiation on the bus to l.
+func (b *NegotiationBus) SetLogger(l Logger) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.log = l
+}
+
+// Register attaches a handler for the given agentID.  The agent is reached
+// by ID only; see RegisterCapabilities.
+func (b *NegotiationBus) Register(agentID string, h NegotiationHandler) {
+	b.RegisterCapabilities(agentID, nil, h)
+}
+
+// RegisterCapabilities attaches a handler for agentID that NegotiateCapability
+// also routes intents to when every required capability matches one of
+// capabilities.  Patterns use path.Match syntax.
+func (b *NegotiationBus) RegisterCapabilities(agentID string, capabilities []string, h NegotiationHandler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.agents[agentID] = busAgent{handler: h, capabilities: capabilities}
+}
+
+// Unregister removes agentID from the bus.
+func (b *NegotiationBus) Unregister(agentID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.agents, agentID)
+}
+
+// Agents returns the registered agent IDs, sorted.
+func (b *NegotiationBus) Agents() []string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	ids := make([]string, 0, len(b.agents))
+	for id := range b.agents {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Use appends mw to the middleware chain.  The first middleware added is
+// the outermost, so it sees a negotiation first and its answer last.
+func (b *NegotiationBus) Use(mw ...BusMiddleware) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.middleware = append(b.middleware, mw...)
+}
+
+// Negotiate sends an intent to targetAgentID and returns the response.
+func (b *NegotiationBus) Negotiate(targetAgentID string, intent *IntentMessage) (*NegotiationResponse, error) {
+	return b.NegotiateContext(context.Background(), targetAgentID, intent)
+}
+
+// NegotiateContext is Negotiate with a context for the middleware chain.
+// Handlers are synchronous and do not see ctx.
+func (b *NegotiationBus) NegotiateContext(ctx context.Context, targetAgentID string, intent *IntentMessage) (*NegotiationResponse, error) {
+	b.mu.RLock()
+	log := b.log
+	h := b.dispatch
+	for i := len(b.middleware) - 1; i >= 0; i-- {
+		h = b.middleware[i](h)
+	}
+	b.mu.RUnlock()
+	start := time.Now()
+	resp, err := h(ctx, targetAgentID, intent)
+	logNegotiation(ctx, log, targetAgentID, intent, start, resp, err)
+	return resp, err
+}
+
+// NegotiateAsync negotiates in a new goroutine and delivers the result on
+// the returned channel, which receives exactly one value.  If ctx ends
+// first, the result carries ctx's error and the handler's answer is
+// dropped.
+func (b *NegotiationBus) NegotiateAsync(ctx context.Context, targetAgentID string, intent *IntentMessage) <-chan BusResult {
+	out := make(chan BusResult, 1)
+	done := make(chan BusResult, 1)
+	go func() {
+		resp, err := b.NegotiateContext(ctx, targetAgentID, intent)
+		done <- BusResult{AgentID: targetAgentID, Response: resp, Err: err}
+	}()
+	go func() {
+		select {
+		case r := <-done:
+			out <- r
+		case <-ctx.Done():
+			out <- BusResult{AgentID: targetAgentID, Err: ctx.Err()}
+		}
+	}()
+	return out
+}
+
+// BroadcastIntent sends intent to every registered agent in parallel and
+// returns their results, keyed by agent ID, once all have answered or ctx
+// has ended.
+func (b *NegotiationBus) BroadcastIntent(ctx context.Context, intent *IntentMessage) map[string]BusResult {
+	ids := b.Agents()
+	pending := make([]<-chan BusResult, len(ids))
+	for i, id := range ids {
+		pending[i] = b.NegotiateAsync(ctx, id, intent)
+	}
+	results := make(map[string]BusResult, len(ids))
+	for _, ch := range pending {
+		r := <-ch
+		results[r.AgentID] = r
+	}
+	return results
+}
+
+// NegotiateCapability sends intent to the agent Route ranks first and
+// reports which agent answered.  It fails with ErrCapabilityMissing if no
+// agent serves every required capability.
+func (b *NegotiationBus) NegotiateCapability(ctx context.Context, intent *IntentMessage) (BusResult, error) {
+	ids := b.Route(intent.Capabilities)
+	if len(ids) == 0 {
+		return BusResult{}, fmt.Errorf("negotiation: %w: no agent serves %v", ErrCapabilityMissing, intent.Capabilities)
+	}
+	resp, err := b.NegotiateContext(ctx, ids[0], intent)
+	return BusResult{AgentID: ids[0], Response: resp, Err: err}, err
+}
+
+// Route returns the agents registered with capabilities matching every one
+// of required, best match first: agents matching more of required exactly
+// rank higher, then those with longer patterns, then by agent ID.
+func (b *NegotiationBus) Route(required []string) []string {
+	type candidate struct {
+		id           string
+		exact, width int
+	}
+	b.mu.RLock()
+	var found []candidate
+	for id, a := range b.agents {
+		if len(a.capabilities) == 0 {
+			continue
+		}
+		c := candidate{id: id}
+		ok := true
+		for _, want := range required {
+			exact, width, matched := matchCapability(a.capabilities, want)
+			if !matched {
+				ok = false
+				break
+			}
+			if exact {
+				c.exact++
+			}
+			c.width += width
+		}
+		if ok {
+			found = append(found, c)
+		}
+	}
+	b.mu.RUnlock()
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].exact != found[j].exact {
+			return found[i].exact > found[j].exact
+		}
+		if found[i].width != found[j].width {
+			return found[i].width > found[j].width
+		}
+		return found[i].id < found[j].id
+	})
+	ids := make([]string, len(found))
+	for i, c := range found {
+		ids[i] = c.id
+	}
+	return ids
+}
+
+// matchCapability reports whether any of patterns matches capability,
+// whether one does so exactly, and the length of the longest match.
+// Malformed patterns match nothing.
+func matchCapability(patterns []string, capability string) (exact bool, width int, matched bool) {
+	for _, p := range patterns {
+		if p == capability {
+			return true, len(p), true
+		}
+		if ok, err := path.Match(p, capability); err == nil && ok {
+			matched = true
+			width = max(width, len(p))
+		}
+	}
+	return false, width, matched
+}
+
+// dispatch ends the middleware chain with the target's handler.
+func (b *NegotiationBus) dispatch(_ context.Context, target string, intent *IntentMessage) (*NegotiationResponse, error) {
+	b.mu.RLock()
+	a, ok := b.agents[target]
+	b.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("negotiation: no handler for agent %q", target)
+	}
+	return a.handler(intent)
+}
+
+// LoggingMiddleware logs every negotiation passing through it to l, as
+// SetLogger does for the bus as a whole.
+func LoggingMiddleware(l Logger) BusMiddleware {
+	return func(next BusHandler) BusHandler {
+		return func(ctx context.Context, target string, intent *IntentMessage) (*NegotiationResponse, error) {
+			start := time.Now()
+			resp, err := next(ctx, target, intent)
+			logNegotiation(ctx, l, target, intent, start, resp, err)
+			return resp, err
+		}
+	}
+}
+
+// ValidationMiddleware refuses, with an error, intents that have no ID or
+// DID, whose structured ID does not match their content (CheckIntentID),
+// or that have expired.  With keys set, signed intents must also verify
+// against the key keys returns for their DID; intents from DIDs it has no
+// key for pass unverified.
+func ValidationMiddleware(keys func(did string) []byte) BusMiddleware {
+	return func(next BusHandler) BusHandler {
+		return func(ctx context.Context, target string, intent *IntentMessage) (*NegotiationResponse, error) {
+			if err := validateBusIntent(intent, keys); err != nil {
+				return nil, fmt.Errorf("negotiation: intent %q: %w", intent.ID, err)
+			}
+			return next(ctx, target, intent)
+		}
+	}
+}
+
+func validateBusIntent(intent *IntentMessage, keys func(did string) []byte) error {
+	switch {
+	case intent.ID == "":
+		return fmt.Errorf("no intent ID")
+	case intent.DID == "":
+		return fmt.Errorf("no sender DID")
+	case intent.Expired(time.Now()):
+		return fmt.Errorf("expired")
+	}
+	if err := CheckIntentID(intent); err != nil {
+		return err
+	}
+	if keys == nil {
+		return nil
+	}
+	if key := keys(intent.DID); key != nil && !VerifyIntentSignature(intent, key) {
+		return ErrSignatureInvalid
+	}
+	return nil
+}
+
+// MetricsMiddleware records every negotiation passing through it in reg:
+// asp_bus_negotiations_total by agent and decision ("accepted", "rejected"
+// or "error") and asp_bus_negotiation_duration_seconds by agent.
+func MetricsMiddleware(reg *metrics.Registry) BusMiddleware {
+	count := reg.Counter("asp_bus_negotiations_total", "Negotiations on a NegotiationBus, by agent and decision.", "agent", "decision")
+	latency := reg.Histogram("asp_bus_negotiation_duration_seconds", "Latency of negotiations on a NegotiationBus.", nil, "agent")
+	return func(next BusHandler) BusHandler {
+		return func(ctx context.Context, target string, intent *IntentMessage) (*NegotiationResponse, error) {
+			start := time.Now()
+			resp, err := next(ctx, target, intent)
+			latency.Observe(time.Since(start).Seconds(), target)
+			count.Inc(target, Decision(resp, err))
+			return resp, err
+		}
+	}
+}
+
+func logNegotiation(ctx context.Context, l Logger, target string, intent *IntentMessage, start time.Time, resp *NegotiationResponse, err error) {
+	level := slog.LevelInfo
+	if err != nil {
+		level = slog.LevelWarn
+	}
+	l.Log(ctx, level, "negotiation",
+		LogKeyPeer, target, LogKeyMsgType, "intent", LogKeyIntentID, intent.ID,
+		LogKeyLatency, time.Since(start), LogKeyDecision, Decision(resp, err))
+}
@@ -0,0 +1,128 @@
+package core_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/metrics"
+)
+
+func TestNegotiationBusAsyncAndBroadcast(t *testing.T) {
+	a, _ := core.NewAgent("a", []string{"ocr"})
+	b, _ := core.NewAgent("b", []string{"translate"})
+	bus := core.NewNegotiationBus()
+	bus.Register("a", core.DefaultNegotiationHandler(a))
+	bus.Register("b", core.DefaultNegotiationHandler(b))
+
+	intent, _ := core.CreateIntent(a, nil, []string{"ocr"}, "scan")
+	r := <-bus.NegotiateAsync(context.Background(), "a", intent)
+	if r.Err != nil || r.AgentID != "a" || !r.Response.Accepted {
+		t.Fatalf("async result: %+v", r)
+	}
+
+	results := bus.BroadcastIntent(context.Background(), intent)
+	if len(results) != 2 || !results["a"].Response.Accepted || results["b"].Response.Accepted {
+		t.Errorf("broadcast: %+v", results)
+	}
+
+	block := make(chan struct{})
+	defer close(block)
+	bus.Register("slow", func(*core.IntentMessage) (*core.NegotiationResponse, error) {
+		<-block
+		return nil, nil
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if r := <-bus.NegotiateAsync(ctx, "slow", intent); !errors.Is(r.Err, context.DeadlineExceeded) {
+		t.Errorf("slow agent: %v, want deadline exceeded", r.Err)
+	}
+}
+
+func TestNegotiationBusWildcardRouting(t *testing.T) {
+	bus := core.NewNegotiationBus()
+	reply := func(id string) core.NegotiationHandler {
+		return func(in *core.IntentMessage) (*core.NegotiationResponse, error) {
+			return &core.NegotiationResponse{RequestID: in.ID, AgentID: id, Accepted: true}, nil
+		}
+	}
+	bus.RegisterCapabilities("fallback", []string{"*"}, reply("fallback"))
+	bus.RegisterCapabilities("translator", []string{"translate-*"}, reply("translator"))
+	bus.RegisterCapabilities("french", []string{"translate-fr"}, reply("french"))
+	bus.Register("direct", reply("direct"))
+
+	for _, tc := range []struct {
+		caps []string
+		want []string
+	}{
+		{[]string{"translate-fr"}, []string{"french", "translator", "fallback"}},
+		{[]string{"translate-de"}, []string{"translator", "fallback"}},
+		{[]string{"ocr"}, []string{"fallback"}},
+	} {
+		if got := bus.Route(tc.caps); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Route(%v) = %v, want %v", tc.caps, got, tc.want)
+		}
+	}
+
+	a, _ := core.NewAgent("a", nil)
+	intent, _ := core.CreateIntent(a, nil, []string{"translate-de"}, "hallo")
+	r, err := bus.NegotiateCapability(context.Background(), intent)
+	if err != nil || r.AgentID != "translator" || r.Response.AgentID != "translator" {
+		t.Errorf("NegotiateCapability: %+v, %v", r, err)
+	}
+	bus.Unregister("fallback")
+	intent, _ = core.CreateIntent(a, nil, []string{"ocr"}, "scan")
+	if _, err := bus.NegotiateCapability(context.Background(), intent); !errors.Is(err, core.ErrCapabilityMissing) {
+		t.Errorf("unserved capability: %v, want ErrCapabilityMissing", err)
+	}
+}
+
+func TestNegotiationBusMiddleware(t *testing.T) {
+	a, _ := core.NewAgent("a", []string{"ocr"})
+	mallory, _ := core.NewAgent("mallory", nil)
+	reg := metrics.NewRegistry()
+	bus := core.NewNegotiationBus()
+	bus.Register("a", core.DefaultNegotiationHandler(a))
+
+	var order []string
+	trace := func(name string) core.BusMiddleware {
+		return func(next core.BusHandler) core.BusHandler {
+			return func(ctx context.Context, target string, in *core.IntentMessage) (*core.NegotiationResponse, error) {
+				order = append(order, name)
+				return next(ctx, target, in)
+			}
+		}
+	}
+	keys := map[string][]byte{a.DID.String(): a.PublicKey()}
+	bus.Use(trace("outer"), core.MetricsMiddleware(reg),
+		core.ValidationMiddleware(func(did string) []byte { return keys[did] }), trace("inner"))
+
+	intent, _ := core.CreateIntent(a, nil, []string{"ocr"}, "scan")
+	if _, err := bus.Negotiate("a", intent); err != nil {
+		t.Fatalf("Negotiate: %v", err)
+	}
+	if !reflect.DeepEqual(order, []string{"outer", "inner"}) {
+		t.Errorf("middleware order %v", order)
+	}
+
+	// An intent claiming a's DID but signed by another key.
+	forged, _ := core.CreateIntent(mallory, nil, []string{"ocr"}, "scan")
+	forged.DID = a.DID.String()
+	forged.ID = "forged"
+	if _, err := bus.Negotiate("a", forged); !errors.Is(err, core.ErrSignatureInvalid) {
+		t.Errorf("forged intent: %v, want ErrSignatureInvalid", err)
+	}
+	forged.ID = ""
+	if _, err := bus.Negotiate("a", forged); err == nil || !strings.Contains(err.Error(), "no intent ID") {
+		t.Errorf("intent without ID: %v", err)
+	}
+
+	c := reg.Counter("asp_bus_negotiations_total", "", "agent", "decision")
+	if c.Value("a", "accepted") != 1 || c.Value("a", "error") != 2 {
+		t.Errorf("counts: accepted %v, error %v", c.Value("a", "accepted"), c.Value("a", "error"))
+	}
+}
@@ -0,0 +1,90 @@
+package core
+
+// capspec.go — Typed capability contracts.
+//
+// A capability string names what an agent does but not what it needs.  A
+// CapabilitySpec adds a semantic version and JSON Schemas for the payload a
+// capability takes and the result it returns.  Agents advertise specs in
+// handshakes and capability announcements; requesters check a workflow
+// step's payload against the executor's params schema before dispatching
+// it, so a malformed step fails locally instead of on the remote agent.
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+)
+
+// CapabilitySpec is the contract of one capability.
+type CapabilitySpec struct {
+	Name         string          `json:"name"`                    // Capability string, as listed in Agent.Capabilities
+	Version      string          `json:"version,omitempty"`       // Semantic version of the contract, e.g. "1.2.0"
+	ParamsSchema json.RawMessage `json:"params_schema,omitempty"` // JSON Schema for the intent payload
+	ResultSchema json.RawMessage `json:"result_schema,omitempty"` // JSON Schema for the result
+}
+
+var semverPattern = regexp.MustCompile(`^(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?$`)
+
+// Validate checks that s has a name, a well-formed semantic version if any,
+// and schemas that compile.
+func (s CapabilitySpec) Validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("capability spec: missing name")
+	}
+	if s.Version != "" && !semverPattern.MatchString(s.Version) {
+		return fmt.Errorf("capability spec %s: version %q is not a semantic version", s.Name, s.Version)
+	}
+	if _, err := CompileSchema(s.ParamsSchema); err != nil {
+		return fmt.Errorf("capability spec %s: params %w", s.Name, err)
+	}
+	if _, err := CompileSchema(s.ResultSchema); err != nil {
+		return fmt.Errorf("capability spec %s: result %w", s.Name, err)
+	}
+	return nil
+}
+
+// ValidateParams checks payload against the params schema.  Specs without
+// one accept any payload; errors wrap ErrSchemaViolation when the payload,
+// rather than the schema, is at fault.
+func (s CapabilitySpec) ValidateParams(payload []byte) error {
+	return s.validate("params", s.ParamsSchema, payload)
+}
+
+// ValidateResult checks result against the result schema, like
+// ValidateParams.
+func (s CapabilitySpec) ValidateResult(result []byte) error {
+	return s.validate("result", s.ResultSchema, result)
+}
+
+func (s CapabilitySpec) validate(what string, schema json.RawMessage, data []byte) error {
+	if len(schema) == 0 {
+		return nil
+	}
+	sc, err := CompileSchema(schema)
+	if err != nil {
+		return fmt.Errorf("capability %s: %s %w", s.Name, what, err)
+	}
+	if err := sc.Validate(data); err != nil {
+		return fmt.Errorf("capability %s: %s: %w", s.Name, what, err)
+	}
+	return nil
+}
+
+// FindSpec returns the spec for capability in specs.
+func FindSpec(specs []CapabilitySpec, capability string) (CapabilitySpec, bool) {
+	for _, s := range specs {
+		if s.Name == capability {
+			return s, true
+		}
+	}
+	return CapabilitySpec{}, false
+}
+
+func copySpecs(s []CapabilitySpec) []CapabilitySpec {
+	if s == nil {
+		return nil
+	}
+	out := make([]CapabilitySpec, len(s))
+	copy(out, s)
+	return out
+}
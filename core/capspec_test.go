@@ -0,0 +1,162 @@
+package core_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+const ocrParams = `{
+	"type": "object",
+	"required": ["url"],
+	"additionalProperties": false,
+	"properties": {
+		"url":   {"type": "string", "pattern": "^s3://"},
+		"pages": {"type": "integer", "minimum": 1, "maximum": 100},
+		"langs": {"type": "array", "items": {"enum": ["en", "de", "pt"]}, "maxItems": 2},
+		"mode":  {"oneOf": [{"const": "fast"}, {"type": "string", "minLength": 8}]}
+	}
+}`
+
+func TestSchemaValidate(t *testing.T) {
+	s, err := core.CompileSchema([]byte(ocrParams))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		doc  string
+		want string // "" for valid
+	}{
+		{`{"url": "s3://a"}`, ""},
+		{`{"url": "s3://a", "pages": 3, "langs": ["en", "pt"], "mode": "fast"}`, ""},
+		{`{"url": "s3://a", "mode": "thorough"}`, ""},
+		{`{}`, `$: missing required property "url"`},
+		{`[]`, `$: expected object, got array`},
+		{`{"url": "http://a"}`, `$.url: does not match "^s3://"`},
+		{`{"url": "s3://a", "pages": 2.5}`, `$.pages: expected integer, got number`},
+		{`{"url": "s3://a", "pages": 0}`, `$.pages: 0 is less than 1`},
+		{`{"url": "s3://a", "langs": ["fr"]}`, `$.langs[0]: "fr" is not one of ["en","de","pt"]`},
+		{`{"url": "s3://a", "langs": ["en", "de", "pt"]}`, `$.langs: 3 items, more than 2`},
+		{`{"url": "s3://a", "mode": "slow"}`, `$.mode: matches 0 of oneOf`},
+		{`{"url": "s3://a", "extra": 1}`, `$: unexpected property "extra"`},
+		{`not json`, `invalid JSON`},
+	}
+	for _, c := range cases {
+		err := s.Validate([]byte(c.doc))
+		switch {
+		case c.want == "" && err != nil:
+			t.Errorf("%s: unexpected error %v", c.doc, err)
+		case c.want != "" && err == nil:
+			t.Errorf("%s: accepted, want %q", c.doc, c.want)
+		case c.want != "" && !strings.Contains(err.Error(), c.want):
+			t.Errorf("%s: error %q, want %q", c.doc, err, c.want)
+		case err != nil && !errors.Is(err, core.ErrSchemaViolation):
+			t.Errorf("%s: error %v does not wrap ErrSchemaViolation", c.doc, err)
+		}
+	}
+}
+
+func TestCompileSchemaRejectsUnsupported(t *testing.T) {
+	for _, schema := range []string{
+		`{"$ref": "#/definitions/x"}`,
+		`{"type": "float"}`,
+		`{"properties": {"a": {"minLength": -1}}}`,
+		`{"pattern": "("}`,
+		`{"anyOf": []}`,
+		`"string"`,
+	} {
+		if _, err := core.CompileSchema([]byte(schema)); err == nil {
+			t.Errorf("%s: compiled, want an error", schema)
+		}
+	}
+	s, err := core.CompileSchema([]byte(`{"title": "Anything", "description": "annotations only"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Validate([]byte(`[1, "two"]`)); err != nil {
+		t.Errorf("annotation-only schema: %v", err)
+	}
+}
+
+func TestCapabilitySpecValidate(t *testing.T) {
+	good := core.CapabilitySpec{Name: "ocr", Version: "1.2.0-beta.1", ParamsSchema: json.RawMessage(ocrParams)}
+	if err := good.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for _, bad := range []core.CapabilitySpec{
+		{Version: "1.0.0"},
+		{Name: "ocr", Version: "v1"},
+		{Name: "ocr", Version: "01.0.0"},
+		{Name: "ocr", ResultSchema: json.RawMessage(`{"type": 3}`)},
+	} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("%+v: valid, want an error", bad)
+		}
+	}
+
+	if err := good.ValidateParams([]byte(`{"url": "s3://x"}`)); err != nil {
+		t.Error(err)
+	}
+	if err := good.ValidateParams([]byte(`{"url": 1}`)); !errors.Is(err, core.ErrSchemaViolation) {
+		t.Errorf("bad params: got %v, want ErrSchemaViolation", err)
+	}
+	if err := good.ValidateResult([]byte(`anything at all`)); err != nil {
+		t.Errorf("spec without result schema: %v", err)
+	}
+}
+
+func TestCapabilitySpecsInHandshake(t *testing.T) {
+	spec := core.CapabilitySpec{
+		Name:         "ocr",
+		Version:      "1.0.0",
+		ParamsSchema: json.RawMessage(`{"type":"object","required":["url"]}`),
+		ResultSchema: json.RawMessage(`{"type":"string"}`),
+	}
+	initiator, _ := core.NewAgent("init", nil)
+	responder, _ := core.NewAgent("ocr", []string{"ocr"})
+	responder.Specs = []core.CapabilitySpec{spec}
+
+	hs, _ := core.StartHandshake(initiator)
+	resp, err := core.RespondHandshake(responder, hs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := resp.Encode()
+	decoded, err := core.DecodeHandshakeMessage(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := core.NewHandshakeResult(decoded)
+	if !reflect.DeepEqual(result.PeerSpecs, responder.Specs) {
+		t.Errorf("PeerSpecs = %+v, want %+v", result.PeerSpecs, responder.Specs)
+	}
+
+	ann := core.BuildAnnouncement(responder, 60)
+	for _, c := range []core.Codec{core.ProtobufCodec, core.JSONCodec, core.CBORCodec} {
+		frame, err := core.FrameWith(c, ann)
+		if err != nil {
+			t.Fatalf("%s: %v", c.Name(), err)
+		}
+		got, err := core.DecodeFrame(frame)
+		if err != nil {
+			t.Fatalf("%s: %v", c.Name(), err)
+		}
+		if specs := got.(*core.CapabilityAnnouncement).Specs; !reflect.DeepEqual(specs, responder.Specs) {
+			t.Errorf("%s: announcement specs = %+v", c.Name(), specs)
+		}
+	}
+
+	reg := core.NewDiscoveryRegistry()
+	reg.AnnounceFromMessage(ann)
+	p, ok := reg.FindByDID(responder.DID.String())
+	if !ok {
+		t.Fatal("announced agent not registered")
+	}
+	if got, ok := core.FindSpec(p.Specs, "ocr"); !ok || got.Version != "1.0.0" {
+		t.Errorf("FindSpec = %+v, %v", got, ok)
+	}
+}
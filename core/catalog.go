@@ -0,0 +1,177 @@
+package core
+
+// catalog.go — Capability semantics for fuzzy discovery.
+//
+// Aliases (alias.go) fix known spellings, but a mesh also meets names nobody
+// listed.  A CapabilityCatalog gives each capability a description and an
+// embedding vector; two capabilities whose vectors have a cosine similarity
+// at or above the catalog threshold are treated as the same thing.  A
+// DiscoveryRegistry with a catalog falls back to this semantic match when
+// no agent offers the exact name requested.
+//
+//	catalog := core.NewCapabilityCatalog(0)
+//	_ = catalog.Describe(ctx, embedder, "code-generation", "write source code from a description")
+//	_ = catalog.Describe(ctx, embedder, "code-gen", "generate program code")
+//	registry.SetCatalog(catalog)
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/olserra/agent-semantic-protocol/embeddings"
+)
+
+// DefaultCapabilityThreshold is the cosine similarity at which two catalog
+// entries match when no threshold is given.
+const DefaultCapabilityThreshold = 0.85
+
+// CapabilitySemantics describes what one capability means.
+type CapabilitySemantics struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Vector      []float32 `json:"vector"`
+}
+
+// CapabilityCatalog maps capability names to their semantics.  It is
+// concurrency-safe, and a nil catalog matches names exactly.
+type CapabilityCatalog struct {
+	mu        sync.RWMutex
+	entries   map[string]CapabilitySemantics
+	threshold float64
+}
+
+// NewCapabilityCatalog creates an empty catalog matching at threshold
+// (DefaultCapabilityThreshold if 0).
+func NewCapabilityCatalog(threshold float64, entries ...CapabilitySemantics) *CapabilityCatalog {
+	if threshold <= 0 {
+		threshold = DefaultCapabilityThreshold
+	}
+	c := &CapabilityCatalog{entries: make(map[string]CapabilitySemantics), threshold: threshold}
+	for _, s := range entries {
+		c.Add(s)
+	}
+	return c
+}
+
+// Threshold returns the similarity at which capabilities match.
+func (c *CapabilityCatalog) Threshold() float64 { return c.threshold }
+
+// Add registers (or replaces) the semantics of s.Name.
+func (c *CapabilityCatalog) Add(s CapabilitySemantics) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[s.Name] = s
+}
+
+// Describe embeds description (or name, if description is empty) with e
+// and registers the result for name.
+func (c *CapabilityCatalog) Describe(ctx context.Context, e embeddings.Embedder, name, description string) error {
+	text := description
+	if text == "" {
+		text = name
+	}
+	vec, err := e.Embed(ctx, text)
+	if err != nil {
+		return fmt.Errorf("catalog: describe %q: %w", name, err)
+	}
+	c.Add(CapabilitySemantics{Name: name, Description: description, Vector: vec})
+	return nil
+}
+
+// Lookup returns the semantics registered for name.
+func (c *CapabilityCatalog) Lookup(name string) (CapabilitySemantics, bool) {
+	if c == nil {
+		return CapabilitySemantics{}, false
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	s, ok := c.entries[name]
+	return s, ok
+}
+
+// Similarity returns the cosine similarity of a and b.  Identical names
+// score 1; ok is false if either name has no vector.
+func (c *CapabilityCatalog) Similarity(a, b string) (score float64, ok bool) {
+	if a == b {
+		return 1, true
+	}
+	sa, okA := c.Lookup(a)
+	sb, okB := c.Lookup(b)
+	if !okA || !okB || len(sa.Vector) == 0 || len(sa.Vector) != len(sb.Vector) {
+		return 0, false
+	}
+	return CosineSimilarity(sa.Vector, sb.Vector), true
+}
+
+// VectorSimilarity returns the best cosine similarity between vector and
+// the catalog vectors of capabilities, clamped to [0,1].  ok is false if
+// none of them has a vector of the same dimension.
+func (c *CapabilityCatalog) VectorSimilarity(vector []float32, capabilities ...string) (score float64, ok bool) {
+	for _, name := range capabilities {
+		s, found := c.Lookup(name)
+		if !found || len(s.Vector) == 0 || len(s.Vector) != len(vector) {
+			continue
+		}
+		if sim := CosineSimilarity(vector, s.Vector); !ok || sim > score {
+			score, ok = sim, true
+		}
+	}
+	return math.Max(score, 0), ok
+}
+
+// Matches reports whether a and b name the same capability: equal names, or
+// a similarity at or above the threshold.
+func (c *CapabilityCatalog) Matches(a, b string) bool {
+	if a == b {
+		return true
+	}
+	if c == nil {
+		return false
+	}
+	s, ok := c.Similarity(a, b)
+	return ok && s >= c.threshold
+}
+
+// All returns every entry, ordered by name.
+func (c *CapabilityCatalog) All() []CapabilitySemantics {
+	if c == nil {
+		return nil
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	out := make([]CapabilitySemantics, 0, len(c.entries))
+	for _, s := range c.entries {
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// coverage returns how well available covers required: the lowest, over
+// required capabilities, of the best similarity to any available one.  ok
+// is false if some required capability has no match.
+func (c *CapabilityCatalog) coverage(available, required []string) (score float64, ok bool) {
+	score = 1
+	for _, r := range required {
+		best, found := 0.0, false
+		for _, a := range available {
+			if !c.Matches(r, a) {
+				continue
+			}
+			s, _ := c.Similarity(r, a)
+			if !found || s > best {
+				best, found = s, true
+			}
+		}
+		if !found {
+			return 0, false
+		}
+		if best < score {
+			score = best
+		}
+	}
+	return score, true
+}
@@ -0,0 +1,111 @@
+package core_test
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/embeddings"
+)
+
+func TestCatalogSemanticFallback(t *testing.T) {
+	catalog := core.NewCapabilityCatalog(0.9,
+		core.CapabilitySemantics{Name: "code-generation", Vector: []float32{1, 0.1, 0}},
+		core.CapabilitySemantics{Name: "code-gen", Vector: []float32{0.95, 0.12, 0.02}},
+		core.CapabilitySemantics{Name: "translation", Vector: []float32{0, 0.2, 1}},
+	)
+	reg := core.NewDiscoveryRegistry()
+	reg.Announce(core.AgentProfile{AgentID: "coder", Capabilities: []string{"code-gen"}}, 0)
+	reg.Announce(core.AgentProfile{AgentID: "translator", Capabilities: []string{"translation"}}, 0)
+
+	if got := reg.FindByCapability("code-generation"); len(got) != 0 {
+		t.Fatalf("without a catalog: got %+v", got)
+	}
+	reg.SetCatalog(catalog)
+	got := reg.FindByCapability("code-generation")
+	if len(got) != 1 || got[0].AgentID != "coder" {
+		t.Errorf("semantic match: got %+v", got)
+	}
+	if got = reg.FindByCapability("code-generation", "translation"); len(got) != 0 {
+		t.Errorf("no agent covers both: got %+v", got)
+	}
+	// Exact matches win over semantic ones.
+	reg.Announce(core.AgentProfile{AgentID: "exact", Capabilities: []string{"code-generation"}}, 0)
+	if got = reg.FindByCapability("code-generation"); len(got) != 1 || got[0].AgentID != "exact" {
+		t.Errorf("exact match: got %+v", got)
+	}
+}
+
+func TestCatalogDescribe(t *testing.T) {
+	catalog := core.NewCapabilityCatalog(0)
+	e := embeddings.NewHashing(64)
+	if err := catalog.Describe(context.Background(), e, "ocr", "extract text from scanned images"); err != nil {
+		t.Fatal(err)
+	}
+	s, ok := catalog.Lookup("ocr")
+	if !ok || len(s.Vector) != 64 || s.Description == "" {
+		t.Errorf("got %+v, %v", s, ok)
+	}
+	if _, ok = catalog.Similarity("ocr", "unknown"); ok {
+		t.Error("similarity to an unknown capability should not be ok")
+	}
+}
+
+func TestNegotiationSimilarityScore(t *testing.T) {
+	catalog := core.NewCapabilityCatalog(0.9,
+		core.CapabilitySemantics{Name: "ocr", Vector: []float32{1, 0, 0}},
+		core.CapabilitySemantics{Name: "translate", Vector: []float32{0, 1, 0}},
+	)
+	worker, _ := core.NewAgent("worker", []string{"ocr", "translate"})
+	sender, _ := core.NewAgent("sender", nil)
+	h := core.DefaultNegotiationHandlerWithOptions(worker, core.NegotiationOptions{Catalog: catalog, MinSimilarity: 0.5})
+
+	for _, c := range []struct {
+		vector []float32
+		score  float32
+		want   bool
+	}{
+		{[]float32{0.6, 0.8, 0}, 0.8, true}, // Best of the agent's capabilities
+		{[]float32{0, 0, 1}, 0, false},      // Orthogonal: below MinSimilarity
+		{nil, 0, true},                      // Unscored intents are not refused
+	} {
+		intent, _ := core.CreateIntent(sender, c.vector, []string{"ocr"}, "scan")
+		resp, err := h(intent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Accepted != c.want || math.Abs(float64(resp.SimilarityScore-c.score)) > 1e-6 {
+			t.Errorf("%v: accepted=%t score=%v (%s)", c.vector, resp.Accepted, resp.SimilarityScore, resp.Reason)
+		}
+		data, _ := resp.Encode()
+		decoded, err := core.DecodeNegotiationResponse(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded.SimilarityScore != resp.SimilarityScore {
+			t.Errorf("round trip: score %v, want %v", decoded.SimilarityScore, resp.SimilarityScore)
+		}
+	}
+}
+
+func TestRankAssessed(t *testing.T) {
+	candidates := []core.AgentProfile{
+		{AgentID: "close", EmbeddingVector: []float32{1, 0}},
+		{AgentID: "far", EmbeddingVector: []float32{0.6, 0.8}},
+	}
+	assessed := map[string]float32{"close": 0.2, "far": 1}
+	rank := func(weight float64) string {
+		return core.RankAssessed([]float32{1, 0}, candidates, assessed, weight)[0].AgentID
+	}
+	if got := rank(0); got != "close" {
+		t.Errorf("weight 0: first = %s", got)
+	}
+	// close: 0.5*1 + 0.5*0.2 = 0.6; far: 0.5*0.6 + 0.5*1 = 0.8
+	if got := rank(0.5); got != "far" {
+		t.Errorf("weight 0.5: first = %s", got)
+	}
+	if got := core.RankAssessed([]float32{1, 0}, candidates, nil, 1)[0].AgentID; got != "close" {
+		t.Errorf("no assessments: first = %s", got)
+	}
+}
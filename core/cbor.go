@@ -0,0 +1,533 @@
+package core
+
+// cbor.go — CBOR (RFC 8949) payload codec.
+//
+// CBOR suits constrained agents that cannot carry a protobuf runtime: every
+// microcontroller SDK ships a small CBOR library.  Messages are CBOR maps
+// keyed by the same snake_case names as the JSON codec, so a CBOR payload is
+// the JSON payload in binary form, except that int64 values are integers and
+// bytes are byte strings rather than strings.
+//
+// The encoder is deterministic: map keys are sorted (RFC 8949 §4.2.1) and
+// struct fields are written in declaration order.  The decoder ignores
+// unknown keys, accepts half-, single- and double-precision floats, and
+// rejects indefinite-length items.
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// cborMaxDepth bounds nesting when decoding untrusted payloads.
+const cborMaxDepth = 32
+
+// CBOR major types.
+const (
+	cborUint   = 0
+	cborNegInt = 1
+	cborBytes  = 2
+	cborText   = 3
+	cborArray  = 4
+	cborMap    = 5
+	cborTag    = 6
+)
+
+// CBOR simple values and float heads (major type 7).
+const (
+	cborFalse   = 0xf4
+	cborTrue    = 0xf5
+	cborNull    = 0xf6
+	cborUndef   = 0xf7
+	cborFloat16 = 0xf9
+	cborFloat32 = 0xfa
+	cborFloat64 = 0xfb
+)
+
+type cborCodec struct{}
+
+func (cborCodec) ID() CodecID  { return CodecCBOR }
+func (cborCodec) Name() string { return "cbor" }
+
+func (cborCodec) Marshal(msg Encoder) ([]byte, error) {
+	return cborAppend(nil, reflect.ValueOf(msg))
+}
+
+func (c cborCodec) Unmarshal(t MessageType, data []byte) (Encoder, error) {
+	return c.unmarshal(t, data, DefaultDecodeLimits)
+}
+
+func (cborCodec) unmarshal(t MessageType, data []byte, l DecodeLimits) (Encoder, error) {
+	m, err := newMessage(t)
+	if err != nil {
+		return nil, err
+	}
+	d := cborDecoder{data: data, limits: l}
+	v := reflect.ValueOf(m).Elem()
+	if err = d.decode(v, 0); err != nil {
+		var le *LimitError
+		if errors.As(err, &le) {
+			return nil, within(v.Type().Name(), err)
+		}
+		return nil, fmt.Errorf("cbor: %w", err)
+	}
+	if d.off != len(d.data) {
+		return nil, fmt.Errorf("cbor: %d trailing bytes", len(d.data)-d.off)
+	}
+	return m, nil
+}
+
+// ------------------------------------------------------------------ struct fields
+
+type cborField struct {
+	name      string
+	index     int
+	omitEmpty bool
+}
+
+var cborFields sync.Map // reflect.Type → []cborField
+
+// cborFieldsOf returns t's fields as named by their json tags.  Fields
+// tagged "-" are skipped.
+func cborFieldsOf(t reflect.Type) []cborField {
+	if f, ok := cborFields.Load(t); ok {
+		return f.([]cborField)
+	}
+	var fields []cborField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = sf.Name
+		}
+		fields = append(fields, cborField{
+			name:      name,
+			index:     i,
+			omitEmpty: strings.Contains(","+opts+",", ",omitempty,"),
+		})
+	}
+	cborFields.Store(t, fields)
+	return fields
+}
+
+// cborEmpty mirrors encoding/json's omitempty rule.
+func cborEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
+
+// ------------------------------------------------------------------ encoding
+
+func cborHead(b []byte, major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return append(b, major<<5|byte(n))
+	case n <= math.MaxUint8:
+		return append(b, major<<5|24, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, major<<5|25), uint16(n))
+	case n <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, major<<5|26), uint32(n))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, major<<5|27), n)
+	}
+}
+
+func cborAppend(b []byte, v reflect.Value) ([]byte, error) {
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			return append(b, cborTrue), nil
+		}
+		return append(b, cborFalse), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n := v.Int(); n < 0 {
+			return cborHead(b, cborNegInt, uint64(-1-n)), nil
+		}
+		return cborHead(b, cborUint, uint64(v.Int())), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return cborHead(b, cborUint, v.Uint()), nil
+	case reflect.Float32:
+		return binary.BigEndian.AppendUint32(append(b, cborFloat32), math.Float32bits(float32(v.Float()))), nil
+	case reflect.Float64:
+		return binary.BigEndian.AppendUint64(append(b, cborFloat64), math.Float64bits(v.Float())), nil
+	case reflect.String:
+		return append(cborHead(b, cborText, uint64(v.Len())), v.String()...), nil
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return append(cborHead(b, cborBytes, uint64(v.Len())), v.Bytes()...), nil
+		}
+		b = cborHead(b, cborArray, uint64(v.Len()))
+		for i := 0; i < v.Len(); i++ {
+			var err error
+			if b, err = cborAppend(b, v.Index(i)); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("cbor: unsupported map key %s", v.Type().Key())
+		}
+		keys := v.MapKeys()
+		// Deterministic order: shorter keys first, then bytewise.
+		sort.Slice(keys, func(i, j int) bool {
+			a, c := keys[i].String(), keys[j].String()
+			if len(a) != len(c) {
+				return len(a) < len(c)
+			}
+			return a < c
+		})
+		b = cborHead(b, cborMap, uint64(len(keys)))
+		for _, k := range keys {
+			b = append(cborHead(b, cborText, uint64(k.Len())), k.String()...)
+			var err error
+			if b, err = cborAppend(b, v.MapIndex(k)); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case reflect.Struct:
+		fields := cborFieldsOf(v.Type())
+		n := 0
+		for _, f := range fields {
+			if !f.omitEmpty || !cborEmpty(v.Field(f.index)) {
+				n++
+			}
+		}
+		b = cborHead(b, cborMap, uint64(n))
+		for _, f := range fields {
+			fv := v.Field(f.index)
+			if f.omitEmpty && cborEmpty(fv) {
+				continue
+			}
+			b = append(cborHead(b, cborText, uint64(len(f.name))), f.name...)
+			var err error
+			if b, err = cborAppend(b, fv); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case reflect.Ptr:
+		if v.IsNil() {
+			return append(b, cborNull), nil
+		}
+		return cborAppend(b, v.Elem())
+	}
+	return nil, fmt.Errorf("cbor: unsupported type %s", v.Type())
+}
+
+// ------------------------------------------------------------------ decoding
+
+type cborDecoder struct {
+	data   []byte
+	off    int
+	limits DecodeLimits // Checked before each string, array and map is built
+}
+
+// head reads an item's initial byte and argument.  For floats the argument
+// is the raw IEEE 754 bits.
+func (d *cborDecoder) head() (major byte, arg uint64, err error) {
+	if d.off >= len(d.data) {
+		return 0, 0, fmt.Errorf("unexpected end of data")
+	}
+	ib := d.data[d.off]
+	d.off++
+	major, info := ib>>5, ib&0x1f
+	var size int
+	switch {
+	case info < 24:
+		return major, uint64(info), nil
+	case info == 24:
+		size = 1
+	case info == 25:
+		size = 2
+	case info == 26:
+		size = 4
+	case info == 27:
+		size = 8
+	default:
+		return 0, 0, fmt.Errorf("indefinite-length or reserved item 0x%02x", ib)
+	}
+	if len(d.data)-d.off < size {
+		return 0, 0, fmt.Errorf("unexpected end of data")
+	}
+	for _, c := range d.data[d.off : d.off+size] {
+		arg = arg<<8 | uint64(c)
+	}
+	d.off += size
+	return major, arg, nil
+}
+
+// take returns the next n bytes of a string item.
+func (d *cborDecoder) take(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)-d.off) {
+		return nil, fmt.Errorf("string length %d exceeds data", n)
+	}
+	s := d.data[d.off : d.off+int(n)]
+	d.off += int(n)
+	return s, nil
+}
+
+// count validates an array or map length against the remaining data, so a
+// forged length cannot force a large allocation.
+func (d *cborDecoder) count(n uint64, perItem int) (int, error) {
+	if n > uint64(len(d.data)-d.off)/uint64(perItem) {
+		return 0, fmt.Errorf("length %d exceeds data", n)
+	}
+	return int(n), nil
+}
+
+func (d *cborDecoder) decode(v reflect.Value, depth int) error {
+	if depth > cborMaxDepth {
+		return fmt.Errorf("nesting exceeds %d levels", cborMaxDepth)
+	}
+	start := d.off
+	major, arg, err := d.head()
+	if err != nil {
+		return err
+	}
+	if ib := d.data[start]; ib == cborNull || ib == cborUndef {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if ib := d.data[start]; ib != cborTrue && ib != cborFalse {
+			return d.mismatch(start, v)
+		}
+		v.SetBool(d.data[start] == cborTrue)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var n int64
+		switch {
+		case major == cborUint && arg <= math.MaxInt64:
+			n = int64(arg)
+		case major == cborNegInt && arg <= math.MaxInt64:
+			n = -1 - int64(arg)
+		default:
+			return d.mismatch(start, v)
+		}
+		if v.OverflowInt(n) {
+			return fmt.Errorf("%d overflows %s", n, v.Type())
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if major != cborUint || v.OverflowUint(arg) {
+			return d.mismatch(start, v)
+		}
+		v.SetUint(arg)
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		switch ib := d.data[start]; {
+		case ib == cborFloat16:
+			f = float64(cborHalf(uint16(arg)))
+		case ib == cborFloat32:
+			f = float64(math.Float32frombits(uint32(arg)))
+		case ib == cborFloat64:
+			f = math.Float64frombits(arg)
+		case major == cborUint:
+			f = float64(arg)
+		case major == cborNegInt:
+			f = -1 - float64(arg)
+		default:
+			return d.mismatch(start, v)
+		}
+		v.SetFloat(f)
+	case reflect.String:
+		if major != cborText {
+			return d.mismatch(start, v)
+		}
+		s, terr := d.take(arg)
+		if terr != nil {
+			return terr
+		}
+		if err = limitErr(bound("string length", d.limits.MaxStringLen, len(s)), ""); err != nil {
+			return err
+		}
+		v.SetString(string(s))
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			if major != cborBytes {
+				return d.mismatch(start, v)
+			}
+			s, terr := d.take(arg)
+			if terr != nil {
+				return terr
+			}
+			v.SetBytes(append([]byte(nil), s...))
+			return nil
+		}
+		if major != cborArray {
+			return d.mismatch(start, v)
+		}
+		var n int
+		if n, err = d.count(arg, 1); err != nil {
+			return err
+		}
+		kind, limit := "repeated field", d.limits.MaxRepeated
+		if v.Type().Elem().Kind() == reflect.Float32 {
+			kind, limit = "vector length", d.limits.MaxVectorLen
+		}
+		if err = limitErr(bound(kind, limit, n), ""); err != nil {
+			return err
+		}
+		s := reflect.MakeSlice(v.Type(), n, n)
+		for i := 0; i < n; i++ {
+			if err = d.decode(s.Index(i), depth+1); err != nil {
+				return within("["+strconv.Itoa(i)+"]", err)
+			}
+		}
+		v.Set(s)
+	case reflect.Map:
+		if major != cborMap || v.Type().Key().Kind() != reflect.String {
+			return d.mismatch(start, v)
+		}
+		var n int
+		if n, err = d.count(arg, 2); err != nil {
+			return err
+		}
+		if err = limitErr(bound("map entries", d.limits.MaxMapEntries, n), ""); err != nil {
+			return err
+		}
+		m := reflect.MakeMapWithSize(v.Type(), n)
+		for i := 0; i < n; i++ {
+			k := reflect.New(v.Type().Key()).Elem()
+			if err = d.decode(k, depth+1); err != nil {
+				return within(" key", err)
+			}
+			e := reflect.New(v.Type().Elem()).Elem()
+			if err = d.decode(e, depth+1); err != nil {
+				return within(" value", err)
+			}
+			m.SetMapIndex(k, e)
+		}
+		v.Set(m)
+	case reflect.Struct:
+		if major != cborMap {
+			return d.mismatch(start, v)
+		}
+		var n int
+		if n, err = d.count(arg, 2); err != nil {
+			return err
+		}
+		fields := cborFieldsOf(v.Type())
+		for i := 0; i < n; i++ {
+			var key string
+			if err = d.decode(reflect.ValueOf(&key).Elem(), depth+1); err != nil {
+				return err
+			}
+			idx := -1
+			for _, f := range fields {
+				if f.name == key {
+					idx = f.index
+					break
+				}
+			}
+			if idx < 0 {
+				if err = d.skip(depth + 1); err != nil {
+					return err
+				}
+				continue
+			}
+			if err = d.decode(v.Field(idx), depth+1); err != nil {
+				if errors.As(err, new(*LimitError)) {
+					return within("."+key, err)
+				}
+				return fmt.Errorf("%s: %w", key, err)
+			}
+		}
+	case reflect.Ptr:
+		d.off = start
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return d.decode(v.Elem(), depth+1)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// skip consumes one item of any type.
+func (d *cborDecoder) skip(depth int) error {
+	if depth > cborMaxDepth {
+		return fmt.Errorf("nesting exceeds %d levels", cborMaxDepth)
+	}
+	major, arg, err := d.head()
+	if err != nil {
+		return err
+	}
+	switch major {
+	case cborBytes, cborText:
+		_, err = d.take(arg)
+		return err
+	case cborArray, cborMap:
+		per := 1
+		if major == cborMap {
+			per = 2
+		}
+		var n int
+		if n, err = d.count(arg, per); err != nil {
+			return err
+		}
+		for i := 0; i < n*per; i++ {
+			if err = d.skip(depth + 1); err != nil {
+				return err
+			}
+		}
+	case cborTag: // skip the tagged item
+		return d.skip(depth + 1)
+	}
+	return nil
+}
+
+func (d *cborDecoder) mismatch(off int, v reflect.Value) error {
+	return fmt.Errorf("cannot decode item 0x%02x at offset %d into %s", d.data[off], off, v.Type())
+}
+
+// cborHalf converts an IEEE 754 half-precision value to float32.
+func cborHalf(h uint16) float32 {
+	sign := uint32(h>>15) << 31
+	exp := uint32(h>>10) & 0x1f
+	frac := uint32(h) & 0x3ff
+	switch {
+	case exp == 0x1f: // Inf / NaN
+		return math.Float32frombits(sign | 0xff<<23 | frac<<13)
+	case exp == 0 && frac == 0:
+		return math.Float32frombits(sign)
+	case exp == 0: // subnormal
+		f := float32(frac) / (1 << 24)
+		if sign != 0 {
+			return -f
+		}
+		return f
+	}
+	return math.Float32frombits(sign | (exp+112)<<23 | frac<<13)
+}
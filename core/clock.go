@@ -0,0 +1,104 @@
+package core
+
+// clock.go — Peer clock-offset estimation and skew compensation.
+//
+// Message timestamps are Unix nanoseconds from each sender's own clock, and
+// agents' clocks are not synchronised.  During the handshake the initiator
+// performs an NTP-style exchange:
+//
+//	T1  initiator sends HandshakeMessage           (Timestamp)
+//	T2  responder receives it                      (ReceivedAt, echoed T1 in EchoTimestamp)
+//	T3  responder sends its HandshakeMessage       (Timestamp)
+//	T4  initiator receives the response            (local clock)
+//
+//	offset = ((T2 - T1) + (T3 - T4)) / 2   // peer clock minus local clock
+//	delay  = (T4 - T1) - (T3 - T2)         // network round trip
+//
+// The responder only sees T1 and T2, so its estimate is one-way and biased
+// by the network delay; it is still good enough to catch grossly wrong clocks.
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// EstimateClockOffset computes the NTP offset (peer minus local) and round-trip
+// delay from the four exchange timestamps, all in Unix nanoseconds.
+func EstimateClockOffset(t1, t2, t3, t4 int64) (offset, delay time.Duration) {
+	offset = time.Duration(((t2 - t1) + (t3 - t4)) / 2)
+	delay = time.Duration((t4 - t1) - (t3 - t2))
+	if delay < 0 {
+		delay = 0
+	}
+	return offset, delay
+}
+
+// HandshakeClockOffset estimates the responder's clock offset from its
+// HandshakeMessage, received locally at receivedAt.  ok is false when the
+// responder did not echo our timestamp (e.g. an older peer).
+func HandshakeClockOffset(resp *HandshakeMessage, receivedAt int64) (offset, delay time.Duration, ok bool) {
+	if resp.EchoTimestamp == 0 || resp.ReceivedAt == 0 {
+		return 0, 0, false
+	}
+	offset, delay = EstimateClockOffset(resp.EchoTimestamp, resp.ReceivedAt, resp.Timestamp, receivedAt)
+	return offset, delay, true
+}
+
+// MaxClockOffset bounds the offsets a ClockSkewTable records.  A peer whose
+// clock is further off than this is not compensated beyond it, so its
+// timestamps fail a timestamp window instead of widening it.
+const MaxClockOffset = 10 * time.Minute
+
+// ClockSkewTable stores the estimated clock offset of each peer, keyed by a
+// peer identifier the transport authenticates (hosts use the libp2p peer
+// ID), never by a DID the peer merely claims.  All methods are
+// concurrency-safe.
+type ClockSkewTable struct {
+	mu      sync.RWMutex
+	offsets map[string]time.Duration
+}
+
+// NewClockSkewTable creates an empty table.
+func NewClockSkewTable() *ClockSkewTable {
+	return &ClockSkewTable{offsets: make(map[string]time.Duration)}
+}
+
+// Record stores offset (peer clock minus local clock) for peer, clamped to
+// ±MaxClockOffset.
+func (c *ClockSkewTable) Record(peer string, offset time.Duration) {
+	offset = min(max(offset, -MaxClockOffset), MaxClockOffset)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.offsets[peer] = offset
+}
+
+// Offset returns the recorded offset for peer.
+func (c *ClockSkewTable) Offset(peer string) (time.Duration, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	o, ok := c.offsets[peer]
+	return o, ok
+}
+
+// ToLocal converts a timestamp taken on peer's clock to the local clock.
+// Timestamps from peers with no recorded offset are returned unchanged.
+func (c *ClockSkewTable) ToLocal(peer string, ts int64) int64 {
+	o, _ := c.Offset(peer)
+	return ts - int64(o)
+}
+
+// CheckTimestamp reports whether ts, sent by peer, lies within
+// [now-maxAge, now+maxFuture] once compensated for peer's clock offset.
+// A zero bound disables that side of the check.
+func (c *ClockSkewTable) CheckTimestamp(peer string, ts int64, maxAge, maxFuture time.Duration) error {
+	local := time.Unix(0, c.ToLocal(peer, ts))
+	n := time.Now()
+	if maxAge > 0 && local.Before(n.Add(-maxAge)) {
+		return fmt.Errorf("clock: timestamp from %s is %s old (max %s)", peer, n.Sub(local).Round(time.Millisecond), maxAge)
+	}
+	if maxFuture > 0 && local.After(n.Add(maxFuture)) {
+		return fmt.Errorf("clock: timestamp from %s is %s in the future (max %s)", peer, local.Sub(n).Round(time.Millisecond), maxFuture)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package core_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func TestEstimateClockOffset(t *testing.T) {
+	// Peer clock runs 5s ahead; each leg takes 10ms; responder spends 1ms.
+	skew := int64(5 * time.Second)
+	leg := int64(10 * time.Millisecond)
+	t1 := int64(1_000_000_000)
+	t2 := t1 + leg + skew
+	t3 := t2 + int64(time.Millisecond)
+	t4 := t3 - skew + leg
+
+	offset, delay := core.EstimateClockOffset(t1, t2, t3, t4)
+	if offset != time.Duration(skew) {
+		t.Errorf("offset: got %v, want %v", offset, time.Duration(skew))
+	}
+	if delay != time.Duration(2*leg) {
+		t.Errorf("delay: got %v, want %v", delay, time.Duration(2*leg))
+	}
+}
+
+func TestHandshakeClockOffset(t *testing.T) {
+	alice, _ := core.NewAgent("alice", nil)
+	bob, _ := core.NewAgent("bob", nil)
+
+	hs, err := core.StartHandshake(alice)
+	if err != nil {
+		t.Fatalf("StartHandshake: %v", err)
+	}
+	resp, err := core.RespondHandshake(bob, hs)
+	if err != nil {
+		t.Fatalf("RespondHandshake: %v", err)
+	}
+	if resp.EchoTimestamp != hs.Timestamp || resp.ReceivedAt == 0 {
+		t.Fatalf("response does not carry clock-sync fields: %+v", resp)
+	}
+
+	offset, _, ok := core.HandshakeClockOffset(resp, time.Now().UnixNano())
+	if !ok {
+		t.Fatal("expected an offset estimate")
+	}
+	if offset < -time.Second || offset > time.Second {
+		t.Errorf("same-host offset should be near zero, got %v", offset)
+	}
+
+	resp.EchoTimestamp = 0
+	if _, _, ok = core.HandshakeClockOffset(resp, time.Now().UnixNano()); ok {
+		t.Error("expected no estimate without echoed timestamp")
+	}
+}
+
+func TestClockSkewTableCheckTimestamp(t *testing.T) {
+	c := core.NewClockSkewTable()
+	const did = "did:agent-semantic-protocol:peer"
+	ahead := time.Now().Add(time.Minute).UnixNano()
+
+	if err := c.CheckTimestamp(did, ahead, 0, 10*time.Second); err == nil {
+		t.Error("expected uncompensated future timestamp to be rejected")
+	}
+	c.Record(did, time.Minute)
+	if err := c.CheckTimestamp(did, ahead, 10*time.Second, 10*time.Second); err != nil {
+		t.Errorf("compensated timestamp rejected: %v", err)
+	}
+	if err := c.CheckTimestamp(did, time.Now().UnixNano(), 10*time.Second, 0); err == nil {
+		t.Error("expected stale compensated timestamp to be rejected")
+	}
+}
+
+func TestClockSkewTableClampsOffsets(t *testing.T) {
+	c := core.NewClockSkewTable()
+	c.Record("ahead", 24*time.Hour)
+	c.Record("behind", -24*time.Hour)
+	if o, _ := c.Offset("ahead"); o != core.MaxClockOffset {
+		t.Errorf("ahead: got %v, want %v", o, core.MaxClockOffset)
+	}
+	if o, _ := c.Offset("behind"); o != -core.MaxClockOffset {
+		t.Errorf("behind: got %v, want %v", o, -core.MaxClockOffset)
+	}
+	// A day-old timestamp stays stale however far off the peer claims to be.
+	if err := c.CheckTimestamp("behind", time.Now().Add(-24*time.Hour).UnixNano(), time.Minute, time.Minute); err == nil {
+		t.Error("clamped offset still admitted a day-old timestamp")
+	}
+}
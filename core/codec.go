@@ -0,0 +1,387 @@
+package core
+
+// codec.go — Pluggable payload encodings.
+//
+// Protobuf is the native wire format, but a Frame can carry any registered
+// Codec.  The codec is identified by the top two bits of the frame's type
+// byte, so a protobuf frame is byte-for-byte what it was before codecs
+// existed:
+//
+//	type byte = CodecID<<6 | MessageType
+//
+// Peers advertise the codecs they would like to receive in
+// HandshakeMessage.Codecs; the responder picks the first one it supports
+// (NegotiateCodec) and echoes it back.  Receivers accept every registered
+// codec regardless of what was negotiated.
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CodecID identifies a payload encoding inside a frame's type byte.
+type CodecID byte
+
+const (
+	CodecProtobuf CodecID = 0
+	CodecJSON     CodecID = 1
+	CodecCBOR     CodecID = 2
+)
+
+const (
+	codecShift  = 6
+	msgTypeMask = 1<<codecShift - 1
+)
+
+// Codec serialises messages for the wire.
+type Codec interface {
+	ID() CodecID
+	Name() string // Advertised in HandshakeMessage.Codecs
+	Marshal(msg Encoder) ([]byte, error)
+	Unmarshal(t MessageType, data []byte) (Encoder, error)
+}
+
+// ProtobufCodec is the native protowire encoding (Encode / Decode).
+var ProtobufCodec Codec = protobufCodec{}
+
+// JSONCodec encodes messages as JSON objects with snake_case field names
+// matching proto/asp.proto.
+var JSONCodec Codec = jsonCodec{}
+
+// CBORCodec encodes messages as CBOR maps with the JSON codec's field names
+// (see cbor.go).  It is intended for constrained devices.
+var CBORCodec Codec = cborCodec{}
+
+var (
+	codecsMu     sync.RWMutex
+	codecsByID   = map[CodecID]Codec{}
+	codecsByName = map[string]Codec{}
+)
+
+func init() {
+	RegisterCodec(ProtobufCodec)
+	RegisterCodec(JSONCodec)
+	RegisterCodec(CBORCodec)
+}
+
+// RegisterCodec makes c available for framing and negotiation.  It panics
+// if c's ID does not fit in a frame's type byte.
+func RegisterCodec(c Codec) {
+	if c.ID() > 1<<(8-codecShift)-1 {
+		panic(fmt.Sprintf("codec %q: id %d out of range", c.Name(), c.ID()))
+	}
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	codecsByID[c.ID()] = c
+	codecsByName[c.Name()] = c
+}
+
+// CodecByName returns the registered codec advertised as name.
+func CodecByName(name string) (Codec, bool) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	c, ok := codecsByName[name]
+	return c, ok
+}
+
+// CodecByID returns the registered codec with the given ID.
+func CodecByID(id CodecID) (Codec, bool) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	c, ok := codecsByID[id]
+	return c, ok
+}
+
+// NegotiateCodec returns the first codec in offered that is registered, or
+// ProtobufCodec if none is.
+func NegotiateCodec(offered []string) Codec {
+	for _, name := range offered {
+		if c, ok := CodecByName(name); ok {
+			return c
+		}
+	}
+	return ProtobufCodec
+}
+
+// FrameType combines a message type and codec into a frame's type byte.
+func FrameType(t MessageType, c CodecID) byte {
+	return byte(c)<<codecShift | byte(t)&msgTypeMask
+}
+
+// SplitFrameType is the inverse of FrameType.
+func SplitFrameType(b byte) (MessageType, CodecID) {
+	return MessageType(b & msgTypeMask), CodecID(b >> codecShift)
+}
+
+// FrameWith encodes msg with c and frames it.
+func FrameWith(c Codec, msg Encoder) ([]byte, error) {
+	payload, err := c.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	frame := Frame(msg.MsgType(), payload)
+	frame[4] = FrameType(msg.MsgType(), c.ID())
+	return frame, nil
+}
+
+// DecodeFrame unframes and decodes a message in any registered codec
+// within DefaultDecodeLimits.
+func DecodeFrame(frame []byte) (Encoder, error) {
+	return DefaultDecodeLimits.DecodeFrame(frame)
+}
+
+// DecodeFrame unframes and decodes a message in any registered codec
+// within l.
+func (l DecodeLimits) DecodeFrame(frame []byte) (Encoder, error) {
+	b, payload, err := Unframe(frame)
+	if err != nil {
+		return nil, err
+	}
+	t, id := SplitFrameType(byte(b))
+	c, ok := CodecByID(id)
+	if !ok {
+		return nil, fmt.Errorf("unknown codec id %d", id)
+	}
+	return l.Unmarshal(c, t, payload)
+}
+
+// Transcode converts a payload in codec id to protobuf, so code that only
+// understands the native encoding can handle it.  The payload is decoded
+// within DefaultDecodeLimits.
+func Transcode(id CodecID, t MessageType, payload []byte) ([]byte, error) {
+	return DefaultDecodeLimits.Transcode(id, t, payload)
+}
+
+// Transcode is the package-level Transcode, decoding within l.  Protobuf
+// payloads are returned as they are, to be decoded by the caller.
+func (l DecodeLimits) Transcode(id CodecID, t MessageType, payload []byte) ([]byte, error) {
+	if id == CodecProtobuf {
+		return payload, nil
+	}
+	c, ok := CodecByID(id)
+	if !ok {
+		return nil, fmt.Errorf("unknown codec id %d", id)
+	}
+	m, err := l.Unmarshal(c, t, payload)
+	if err != nil {
+		return nil, err
+	}
+	return m.Encode()
+}
+
+// Unmarshal decodes data, a message of type t encoded with c, within l.
+// The built-in codecs check the limits as they decode; other codecs'
+// messages are checked once Unmarshal returns.
+func (l DecodeLimits) Unmarshal(c Codec, t MessageType, data []byte) (Encoder, error) {
+	if lc, ok := c.(limitedCodec); ok {
+		return lc.unmarshal(t, data, l)
+	}
+	m, err := c.Unmarshal(t, data)
+	if err != nil {
+		return nil, err
+	}
+	if err = l.afterDecode(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// limitedCodec is implemented by the built-in codecs, which enforce
+// DecodeLimits while they decode.
+type limitedCodec interface {
+	unmarshal(t MessageType, data []byte, l DecodeLimits) (Encoder, error)
+}
+
+// newMessage returns an empty message of type t.
+func newMessage(t MessageType) (Encoder, error) {
+	switch t {
+	case MsgHandshake:
+		return &HandshakeMessage{}, nil
+	case MsgIntent:
+		return &IntentMessage{}, nil
+	case MsgNegotiation:
+		return &NegotiationResponse{}, nil
+	case MsgWorkflow:
+		return &WorkflowMessage{}, nil
+	case MsgCapability:
+		return &CapabilityAnnouncement{}, nil
+	case MsgCounter:
+		return &CounterOffer{}, nil
+	case MsgAlert:
+		return &AlertMessage{}, nil
+	case MsgRevocation:
+		return &RevocationList{}, nil
+	case MsgAttestation:
+		return &TrustAttestation{}, nil
+	case MsgForward:
+		return &ForwardEnvelope{}, nil
+	case MsgIntentBatch:
+		return &IntentBatch{}, nil
+	case MsgResponseBatch:
+		return &ResponseBatch{}, nil
+	case MsgPing:
+		return &PingMessage{}, nil
+	case MsgPong:
+		return &PongMessage{}, nil
+	case MsgWorkflowResult:
+		return &WorkflowResult{}, nil
+	case MsgKeyRotation:
+		return &KeyRotation{}, nil
+	case MsgObserve:
+		return &ObserveRequest{}, nil
+	case MsgEvent:
+		return &EventMessage{}, nil
+	default:
+		return nil, fmt.Errorf("unknown message type: 0x%02x", t)
+	}
+}
+
+// ------------------------------------------------------------------ built-in codecs
+
+type protobufCodec struct{}
+
+func (protobufCodec) ID() CodecID                         { return CodecProtobuf }
+func (protobufCodec) Name() string                        { return "protobuf" }
+func (protobufCodec) Marshal(msg Encoder) ([]byte, error) { return msg.Encode() }
+
+func (c protobufCodec) Unmarshal(t MessageType, data []byte) (Encoder, error) {
+	return c.unmarshal(t, data, DefaultDecodeLimits)
+}
+
+func (protobufCodec) unmarshal(t MessageType, data []byte, l DecodeLimits) (Encoder, error) {
+	m, err := l.Decode(t, data)
+	if err != nil {
+		return nil, err
+	}
+	return m.(Encoder), nil
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) ID() CodecID                         { return CodecJSON }
+func (jsonCodec) Name() string                        { return "json" }
+func (jsonCodec) Marshal(msg Encoder) ([]byte, error) { return json.Marshal(msg) }
+
+func (c jsonCodec) Unmarshal(t MessageType, data []byte) (Encoder, error) {
+	return c.unmarshal(t, data, DefaultDecodeLimits)
+}
+
+// unmarshal checks data against l token by token, guided by the message's
+// type, before encoding/json builds anything from it.
+func (jsonCodec) unmarshal(t MessageType, data []byte, l DecodeLimits) (Encoder, error) {
+	m, err := newMessage(t)
+	if err != nil {
+		return nil, err
+	}
+	typ := reflect.TypeOf(m).Elem()
+	if err = l.scanJSON(json.NewDecoder(bytes.NewReader(data)), typ); err != nil {
+		var le *LimitError
+		if errors.As(err, &le) {
+			return nil, within(typ.Name(), err)
+		}
+		return nil, fmt.Errorf("json: %w", err)
+	}
+	if err = json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("json: %w", err)
+	}
+	return m, nil
+}
+
+// scanJSON reads one JSON value from dec, to be decoded into a value of
+// type t, and checks it against l.  A nil t, for unknown fields and values
+// that do not match their field, is skipped unchecked: encoding/json
+// ignores the former and rejects the latter.
+func (l DecodeLimits) scanJSON(dec *json.Decoder, t reflect.Type) error {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		if s, isStr := tok.(string); isStr && t != nil && t.Kind() == reflect.String {
+			return limitErr(bound("string length", l.MaxStringLen, len(s)), "")
+		}
+		return nil
+	}
+	switch {
+	case delim == '[':
+		var elem reflect.Type
+		kind, limit := "repeated field", l.MaxRepeated
+		if t != nil && t.Kind() == reflect.Slice {
+			elem = t.Elem()
+			if elem.Kind() == reflect.Float32 {
+				kind, limit = "vector length", l.MaxVectorLen
+			}
+		} else {
+			limit = 0
+		}
+		for n := 0; dec.More(); n++ {
+			if err = limitErr(bound(kind, limit, n+1), ""); err != nil {
+				return err
+			}
+			if err = l.scanJSON(dec, elem); err != nil {
+				return within("["+strconv.Itoa(n)+"]", err)
+			}
+		}
+	case t != nil && t.Kind() == reflect.Map:
+		for n := 0; dec.More(); n++ {
+			if tok, err = dec.Token(); err != nil {
+				return err
+			}
+			if err = limitErr(bound("map entries", l.MaxMapEntries, n+1), ""); err != nil {
+				return err
+			}
+			if err = limitErr(bound("string length", l.MaxStringLen, len(tok.(string))), " key"); err != nil {
+				return err
+			}
+			if err = l.scanJSON(dec, t.Elem()); err != nil {
+				return within(" value", err)
+			}
+		}
+	default:
+		var fields []cborField
+		if t != nil && t.Kind() == reflect.Struct {
+			fields = cborFieldsOf(t)
+		}
+		for dec.More() {
+			if tok, err = dec.Token(); err != nil {
+				return err
+			}
+			key := tok.(string)
+			var ft reflect.Type
+			if f, found := jsonField(fields, key); found {
+				ft = t.Field(f.index).Type
+			}
+			if err = l.scanJSON(dec, ft); err != nil {
+				return within("."+key, err)
+			}
+		}
+	}
+	_, err = dec.Token() // Closing delimiter
+	return err
+}
+
+// jsonField finds the field named key as encoding/json does: an exact
+// match first, then a case-insensitive one.
+func jsonField(fields []cborField, key string) (cborField, bool) {
+	for _, f := range fields {
+		if f.name == key {
+			return f, true
+		}
+	}
+	for _, f := range fields {
+		if strings.EqualFold(f.name, key) {
+			return f, true
+		}
+	}
+	return cborField{}, false
+}
@@ -0,0 +1,200 @@
+package core_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func TestProtobufFrameUnchanged(t *testing.T) {
+	a, _ := core.NewAgent("a", []string{"nlp"})
+	intent, _ := core.CreateIntent(a, []float32{0.1}, []string{"nlp"}, "hi")
+	payload, _ := intent.Encode()
+
+	frame, err := core.FrameWith(core.ProtobufCodec, intent)
+	if err != nil {
+		t.Fatalf("FrameWith: %v", err)
+	}
+	if !bytes.Equal(frame, core.Frame(core.MsgIntent, payload)) {
+		t.Error("protobuf frame differs from core.Frame")
+	}
+}
+
+func TestJSONCodecRoundTrip(t *testing.T) {
+	a, _ := core.NewAgent("a", []string{"nlp"})
+	intent, _ := core.CreateIntent(a, []float32{0.25, -1}, []string{"nlp"}, "summarise")
+	intent.Metadata["k"] = "v"
+	_ = core.SignIntent(a, intent)
+	hs, _ := core.StartHandshake(a)
+	resp, _ := core.DefaultNegotiationHandler(a)(intent)
+
+	msgs := []core.Encoder{
+		intent,
+		hs,
+		resp,
+		&core.WorkflowMessage{WorkflowID: "wf", StepID: "s1", Params: map[string]string{"x": "1"}, Timestamp: 1},
+		&core.CapabilityAnnouncement{AgentID: "a", DID: a.DID.String(), Capabilities: []string{"nlp"}, TTL: 60},
+	}
+	for _, m := range msgs {
+		frame, err := core.FrameWith(core.JSONCodec, m)
+		if err != nil {
+			t.Fatalf("%T: FrameWith: %v", m, err)
+		}
+		got, err := core.DecodeFrame(frame)
+		if err != nil {
+			t.Fatalf("%T: DecodeFrame: %v", m, err)
+		}
+		// Compare after a protobuf pass so both sides are normalised.
+		want, _ := m.Encode()
+		have, _ := got.Encode()
+		wantMsg, _ := core.Decode(m.MsgType(), want)
+		haveMsg, _ := core.Decode(m.MsgType(), have)
+		if !reflect.DeepEqual(wantMsg, haveMsg) {
+			t.Errorf("%T: JSON round trip changed the message", m)
+		}
+		if in, ok := got.(*core.IntentMessage); ok && !core.VerifyIntentSignature(in, a.PublicKey()) {
+			t.Error("intent signature invalid after JSON round trip")
+		}
+	}
+}
+
+func TestJSONFieldNames(t *testing.T) {
+	a, _ := core.NewAgent("a", nil)
+	intent, _ := core.CreateIntent(a, nil, []string{"nlp"}, "x")
+	data, err := core.JSONCodec.Marshal(intent)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	var fields map[string]interface{}
+	if err = json.Unmarshal(data, &fields); err != nil {
+		t.Fatalf("not JSON: %v", err)
+	}
+	// int64 timestamps are strings so JavaScript clients keep full precision.
+	if _, ok := fields["timestamp"].(string); !ok {
+		t.Errorf("timestamp: got %T", fields["timestamp"])
+	}
+	if strings.Contains(string(data), "Logger") {
+		t.Error("Logger leaked into JSON")
+	}
+}
+
+func TestNegotiateCodec(t *testing.T) {
+	if c := core.NegotiateCodec([]string{"msgpack", "json", "protobuf"}); c.Name() != "json" {
+		t.Errorf("got %s want json", c.Name())
+	}
+	if c := core.NegotiateCodec(nil); c.ID() != core.CodecProtobuf {
+		t.Errorf("got %s want protobuf", c.Name())
+	}
+}
+
+func TestCBORCodecRoundTrip(t *testing.T) {
+	a, _ := core.NewAgent("a", []string{"nlp"})
+	intent, _ := core.CreateIntent(a, []float32{0.25, -1, 3e-8}, []string{"nlp"}, "summarise")
+	intent.Metadata["k"] = "v"
+	intent.Timestamp = -intent.Timestamp // exercise negative integers
+	hs, _ := core.StartHandshake(a)
+	hs.Codecs = []string{"cbor"}
+	resp, _ := core.DefaultNegotiationHandler(a)(intent)
+	rl, _ := core.NewRevocationList(a, 3, []core.RevocationEntry{{DID: "did:x", Reason: "lost", RevokedAt: 1 << 40}})
+
+	msgs := []core.Encoder{
+		intent,
+		hs,
+		resp,
+		&core.WorkflowMessage{WorkflowID: "wf", StepID: "s1", Params: map[string]string{"x": "1", "yy": "2"}, Timestamp: 1},
+		&core.CapabilityAnnouncement{AgentID: "a", DID: a.DID.String(), Capabilities: []string{"nlp"}, TTL: 60},
+		&core.CounterOffer{RequestID: "r", Round: 2, Action: core.CounterPropose, Terms: core.OfferTerms{Price: 1.5, MaxLatencyMs: 300}},
+		&core.AlertMessage{ID: "al", Kind: core.AlertKeyCompromise, Subject: "did:x", Hops: 4},
+		rl,
+	}
+	for _, m := range msgs {
+		frame, err := core.FrameWith(core.CBORCodec, m)
+		if err != nil {
+			t.Fatalf("%T: FrameWith: %v", m, err)
+		}
+		got, err := core.DecodeFrame(frame)
+		if err != nil {
+			t.Fatalf("%T: DecodeFrame: %v", m, err)
+		}
+		want, _ := m.Encode()
+		have, _ := got.Encode()
+		wantMsg, _ := core.Decode(m.MsgType(), want)
+		haveMsg, _ := core.Decode(m.MsgType(), have)
+		if !reflect.DeepEqual(wantMsg, haveMsg) {
+			t.Errorf("%T: CBOR round trip changed the message", m)
+		}
+	}
+	if err := core.VerifyRevocationList(rl); err != nil {
+		t.Errorf("revocation list: %v", err)
+	}
+}
+
+func TestCBORDeterministicAndCompact(t *testing.T) {
+	a, _ := core.NewAgent("a", []string{"nlp"})
+	vec := make([]float32, 64)
+	for i := range vec {
+		vec[i] = float32(i) / 64
+	}
+	intent, _ := core.CreateIntent(a, vec, []string{"nlp"}, "x")
+	for i := 0; i < 8; i++ {
+		intent.Metadata[strings.Repeat("k", i+1)] = "v"
+	}
+
+	first, err := core.CBORCodec.Marshal(intent)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		again, _ := core.CBORCodec.Marshal(intent)
+		if !bytes.Equal(first, again) {
+			t.Fatal("CBOR encoding is not deterministic")
+		}
+	}
+	js, _ := core.JSONCodec.Marshal(intent)
+	if len(first) >= len(js) {
+		t.Errorf("CBOR %d bytes, JSON %d bytes", len(first), len(js))
+	}
+}
+
+func TestCBORInterop(t *testing.T) {
+	// Hand-encoded, as a constrained client might produce it: a half-float
+	// trust_score, an unknown key and a null for a missing field.
+	data := []byte{
+		0xa4,                      // map(4)
+		0x62, 'i', 'd', 0x61, 'x', // "id": "x"
+		0x6b, 't', 'r', 'u', 's', 't', '_', 's', 'c', 'o', 'r', 'e', 0xf9, 0x38, 0x00, // "trust_score": 0.5 (float16)
+		0x63, 'f', 'o', 'o', 0x82, 0x01, 0x02, // "foo": [1, 2]
+		0x68, 'm', 'e', 't', 'a', 'd', 'a', 't', 'a', 0xf6, // "metadata": null
+	}
+	m, err := core.CBORCodec.Unmarshal(core.MsgIntent, data)
+	if err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	in := m.(*core.IntentMessage)
+	if in.ID != "x" || in.TrustScore != 0.5 || in.Metadata != nil {
+		t.Errorf("got %+v", in)
+	}
+}
+
+func TestCBORRejectsMalformed(t *testing.T) {
+	cases := map[string][]byte{
+		"truncated":      {0xa1, 0x62, 'i'},
+		"trailing":       {0xa0, 0x00},
+		"forged length":  {0xa1, 0x6e, 'c', 'a', 'p', 'a', 'b', 'i', 'l', 'i', 't', 'i', 'e', 's', 0x9a, 0xff, 0xff, 0xff, 0xff},
+		"indefinite map": {0xbf, 0xff},
+		"wrong type":     {0xa1, 0x62, 'i', 'd', 0x01},
+	}
+	for name, data := range cases {
+		if _, err := core.CBORCodec.Unmarshal(core.MsgIntent, data); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+	deep := append(bytes.Repeat([]byte{0xa1, 0x61, 'z'}, 100), 0x00)
+	if _, err := core.CBORCodec.Unmarshal(core.MsgIntent, deep); err == nil {
+		t.Error("deep nesting: expected error")
+	}
+}
@@ -0,0 +1,68 @@
+package core
+
+// cost.go — Negotiating on economics.
+//
+// A requester bounds what serving an intent may cost in its Budget; a
+// responder states what it expects serving it to take in the Cost of its
+// NegotiationResponse.  A responder whose estimate exceeds the budget
+// should reject with ReasonOverBudget rather than accept, and a requester
+// treats an accepting response over budget as a rejection (CheckCost).
+// Neither field is signed, so both are claims to be settled against
+// CompletionReceipts afterwards.
+
+import (
+	"fmt"
+	"time"
+)
+
+// ReasonOverBudget is the Reason of a NegotiationResponse rejecting an
+// intent it could not serve within the intent's Budget.
+const ReasonOverBudget = "over_budget"
+
+// ErrOverBudget is returned (wrapped) for a cost estimate beyond a budget.
+var ErrOverBudget = fmt.Errorf("cost: estimate exceeds the budget")
+
+// Budget bounds what serving an intent may cost.  Zero limits are unset.
+type Budget struct {
+	MaxDuration int64  `json:"max_duration,string,omitempty"` // Nanoseconds
+	MaxUnits    int64  `json:"max_units,omitempty"`           // Metered quantity, e.g. tokens
+	MaxAmount   int64  `json:"max_amount,omitempty"`          // In the currency's smallest unit
+	Currency    string `json:"currency,omitempty"`            // Currency of MaxAmount
+}
+
+// CostEstimate is what a responder expects serving an intent to take.
+type CostEstimate struct {
+	Duration int64  `json:"duration,string,omitempty"` // Nanoseconds
+	Units    int64  `json:"units,omitempty"`           // Metered quantity, e.g. tokens
+	Amount   int64  `json:"amount,omitempty"`          // In the currency's smallest unit
+	Currency string `json:"currency,omitempty"`
+}
+
+// Admits returns an error wrapping ErrOverBudget if c exceeds any limit
+// of b.  A nil budget or estimate admits anything.  A priced estimate in
+// another currency than a budget with MaxAmount exceeds it.
+func (b *Budget) Admits(c *CostEstimate) error {
+	if b == nil || c == nil {
+		return nil
+	}
+	switch {
+	case b.MaxDuration > 0 && c.Duration > b.MaxDuration:
+		return fmt.Errorf("%w: duration %v, limit %v", ErrOverBudget, time.Duration(c.Duration), time.Duration(b.MaxDuration))
+	case b.MaxUnits > 0 && c.Units > b.MaxUnits:
+		return fmt.Errorf("%w: %d units, limit %d", ErrOverBudget, c.Units, b.MaxUnits)
+	case b.MaxAmount > 0 && c.Amount > 0 && c.Currency != b.Currency:
+		return fmt.Errorf("%w: priced in %q, budget in %q", ErrOverBudget, c.Currency, b.Currency)
+	case b.MaxAmount > 0 && c.Amount > b.MaxAmount:
+		return fmt.Errorf("%w: amount %d %s, limit %d", ErrOverBudget, c.Amount, c.Currency, b.MaxAmount)
+	}
+	return nil
+}
+
+// CheckCost returns an error wrapping ErrOverBudget if resp accepts intent
+// at a cost beyond intent's Budget.
+func CheckCost(intent *IntentMessage, resp *NegotiationResponse) error {
+	if !resp.Accepted {
+		return nil
+	}
+	return intent.Budget.Admits(resp.Cost)
+}
@@ -0,0 +1,61 @@
+package core_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func TestCostRoundTrip(t *testing.T) {
+	agent, _ := core.NewAgent("a", []string{"nlp"})
+	intent, _ := core.CreateIntent(agent, nil, []string{"nlp"}, "x")
+	intent.Budget = &core.Budget{MaxDuration: int64(time.Minute), MaxUnits: 1000, MaxAmount: 250, Currency: "USD"}
+	data, _ := intent.Encode()
+	decoded, err := core.DecodeIntentMessage(data)
+	if err != nil || !reflect.DeepEqual(decoded.Budget, intent.Budget) {
+		t.Fatalf("Budget = %+v, %v; want %+v", decoded.Budget, err, intent.Budget)
+	}
+
+	resp := &core.NegotiationResponse{RequestID: intent.ID, Accepted: true,
+		Cost: &core.CostEstimate{Duration: int64(time.Second), Units: 400, Amount: 120, Currency: "USD"}}
+	data, _ = resp.Encode()
+	decodedResp, err := core.DecodeNegotiationResponse(data)
+	if err != nil || !reflect.DeepEqual(decodedResp.Cost, resp.Cost) {
+		t.Fatalf("Cost = %+v, %v; want %+v", decodedResp.Cost, err, resp.Cost)
+	}
+}
+
+func TestBudgetAdmits(t *testing.T) {
+	budget := &core.Budget{MaxDuration: int64(time.Minute), MaxUnits: 1000, MaxAmount: 250, Currency: "USD"}
+	for name, tc := range map[string]struct {
+		budget *core.Budget
+		cost   *core.CostEstimate
+		over   bool
+	}{
+		"within":         {budget, &core.CostEstimate{Duration: int64(time.Second), Units: 10, Amount: 250, Currency: "USD"}, false},
+		"no estimate":    {budget, nil, false},
+		"no budget":      {nil, &core.CostEstimate{Amount: 1 << 40}, false},
+		"too slow":       {budget, &core.CostEstimate{Duration: int64(time.Hour)}, true},
+		"too many units": {budget, &core.CostEstimate{Units: 1001}, true},
+		"too expensive":  {budget, &core.CostEstimate{Amount: 251, Currency: "USD"}, true},
+		"other currency": {budget, &core.CostEstimate{Amount: 1, Currency: "EUR"}, true},
+	} {
+		err := tc.budget.Admits(tc.cost)
+		if over := errors.Is(err, core.ErrOverBudget); over != tc.over || (err != nil && !over) {
+			t.Errorf("%s: Admits = %v; want over budget %v", name, err, tc.over)
+		}
+	}
+
+	intent := &core.IntentMessage{Budget: budget}
+	rejected := &core.NegotiationResponse{Cost: &core.CostEstimate{Units: 5000}}
+	if err := core.CheckCost(intent, rejected); err != nil {
+		t.Errorf("CheckCost on a rejection = %v", err)
+	}
+	rejected.Accepted = true
+	if err := core.CheckCost(intent, rejected); !errors.Is(err, core.ErrOverBudget) {
+		t.Errorf("CheckCost = %v; want ErrOverBudget", err)
+	}
+}
@@ -0,0 +1,196 @@
+package core
+
+// credential.go — Verifiable credentials backing capability claims.
+//
+// Anyone can announce a capability; a VerifiableCredential lets a third
+// party vouch for it.  An issuer (a certification body, an operator's
+// registry agent) signs a claim such as "certified code-generation agent"
+// about a subject DID.  Agents carry their credentials in Agent.Credentials
+// and present them in handshakes and capability announcements.  Handshakes
+// fail on a credential that does not verify or is about another agent.  A
+// CredentialPolicy then decides which intents need which credentials.
+//
+// The fields follow the W3C Verifiable Credentials data model, and
+// MarshalW3C renders one as a W3C credential document.  The proof is an
+// Ed25519 signature over the protobuf encoding, as for TrustAttestation,
+// not a JSON-LD Data Integrity proof, so generic VC verifiers cannot check
+// it.
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// ReasonCredentialRequired is the Reason of a NegotiationResponse
+// rejecting an intent whose sender lacks a credential the responder's
+// CredentialPolicy requires.
+const ReasonCredentialRequired = "credential_required"
+
+// ErrCredentialRequired is returned (wrapped) by CredentialPolicy.Check
+// when a required credential is missing.
+var ErrCredentialRequired = fmt.Errorf("credential: required credential missing")
+
+// NewCredential creates and signs issuer's claims about subjectDID under
+// types, valid for ttl (never expiring when ttl is 0).
+func NewCredential(issuer *Agent, subjectDID string, types []string, claims map[string]string, ttl time.Duration) (VerifiableCredential, error) {
+	c := VerifiableCredential{
+		Types:      slices.Clone(types),
+		IssuerDID:  issuer.DID.String(),
+		IssuerKey:  issuer.PublicKey(),
+		SubjectDID: subjectDID,
+		IssuedAt:   now(),
+	}
+	if len(claims) > 0 {
+		c.Claims = make(map[string]string, len(claims))
+		for k, v := range claims {
+			c.Claims[k] = v
+		}
+	}
+	if ttl > 0 {
+		c.ExpiresAt = c.IssuedAt + int64(ttl)
+	}
+	id := make([]byte, 16)
+	if err := issuer.random(id); err != nil {
+		return c, fmt.Errorf("credential: id generation: %w", err)
+	}
+	c.ID = "urn:asp:credential:" + hex.EncodeToString(id)
+	sig, err := issuer.Sign(credentialSigningData(c))
+	if err != nil {
+		return c, fmt.Errorf("credential: sign: %w", err)
+	}
+	c.Signature = sig
+	return c, nil
+}
+
+// VerifyCredential checks that c is well formed and signed by the key
+// bound to IssuerDID.  It does not check expiry.
+func VerifyCredential(c VerifiableCredential) error {
+	if len(c.Signature) == 0 {
+		return fmt.Errorf("credential: from %s is unsigned", c.IssuerDID)
+	}
+	if c.SubjectDID == "" || len(c.Types) == 0 {
+		return fmt.Errorf("credential: from %s names no subject or type", c.IssuerDID)
+	}
+	d, err := ParseDID(c.IssuerDID)
+	if err != nil {
+		return fmt.Errorf("credential: %w", err)
+	}
+	if !d.ValidateBinding(c.IssuerKey) {
+		return fmt.Errorf("credential: issuer key does not match %s", c.IssuerDID)
+	}
+	pub, err := DIDFromPublicKey(c.IssuerKey)
+	if err != nil {
+		return fmt.Errorf("credential: %w", err)
+	}
+	if !pub.Verify(credentialSigningData(c), c.Signature) {
+		return fmt.Errorf("credential: %w from %s", ErrSignatureInvalid, c.IssuerDID)
+	}
+	return nil
+}
+
+// VerifyPeerCredentials checks every credential a peer presented as did:
+// each must verify and be about did.
+func VerifyPeerCredentials(did string, creds []VerifiableCredential) error {
+	for _, c := range creds {
+		if err := VerifyCredential(c); err != nil {
+			return err
+		}
+		if c.SubjectDID != did {
+			return fmt.Errorf("credential: %s is about %s, not %s", c.ID, c.SubjectDID, did)
+		}
+	}
+	return nil
+}
+
+// Expired reports whether c has an expiry at or before t.
+func (c VerifiableCredential) Expired(t time.Time) bool {
+	return c.ExpiresAt != 0 && t.UnixNano() >= c.ExpiresAt
+}
+
+// HasType reports whether c is of type typ.
+func (c VerifiableCredential) HasType(typ string) bool {
+	return slices.Contains(c.Types, typ)
+}
+
+// MarshalW3C renders c as a W3C Verifiable Credential document.
+func (c VerifiableCredential) MarshalW3C() ([]byte, error) {
+	subject := map[string]any{"id": c.SubjectDID}
+	for k, v := range c.Claims {
+		if k != "id" {
+			subject[k] = v
+		}
+	}
+	doc := map[string]any{
+		"@context":          []string{"https://www.w3.org/2018/credentials/v1"},
+		"id":                c.ID,
+		"type":              append([]string{"VerifiableCredential"}, c.Types...),
+		"issuer":            c.IssuerDID,
+		"issuanceDate":      time.Unix(0, c.IssuedAt).UTC().Format(time.RFC3339),
+		"credentialSubject": subject,
+		"proof": map[string]any{
+			"type":               "Ed25519Signature2020",
+			"created":            time.Unix(0, c.IssuedAt).UTC().Format(time.RFC3339),
+			"verificationMethod": c.IssuerDID,
+			"proofPurpose":       "assertionMethod",
+			"proofValue":         base64.RawURLEncoding.EncodeToString(c.Signature),
+		},
+	}
+	if c.ExpiresAt != 0 {
+		doc["expirationDate"] = time.Unix(0, c.ExpiresAt).UTC().Format(time.RFC3339)
+	}
+	return json.Marshal(doc)
+}
+
+// credentialSigningData is the encoded credential without its signature.
+func credentialSigningData(c VerifiableCredential) []byte {
+	c.Signature = nil
+	return encodeCredential(c)
+}
+
+// CredentialRequirement requires the senders of some intents to hold an
+// unexpired credential of Type.
+type CredentialRequirement struct {
+	Capability string   // Intents requiring this capability; "" for every intent
+	Type       string   // Credential type required
+	Issuers    []string // DIDs whose credentials count; empty for any issuer
+}
+
+// CredentialPolicy is a set of requirements, all of which must be met.
+// The zero policy requires nothing.
+type CredentialPolicy struct {
+	Requirements []CredentialRequirement
+}
+
+// Validate checks that p describes a usable policy.
+func (p CredentialPolicy) Validate() error {
+	for _, r := range p.Requirements {
+		if r.Type == "" {
+			return fmt.Errorf("credential policy: requirement for %q names no type", r.Capability)
+		}
+	}
+	return nil
+}
+
+// Check returns an error wrapping ErrCredentialRequired unless creds, the
+// verified credentials of subjectDID, meet every requirement that applies
+// to capabilities at t.
+func (p CredentialPolicy) Check(capabilities []string, subjectDID string, creds []VerifiableCredential, t time.Time) error {
+	for _, r := range p.Requirements {
+		if r.Capability != "" && !slices.Contains(capabilities, r.Capability) {
+			continue
+		}
+		if !slices.ContainsFunc(creds, func(c VerifiableCredential) bool { return r.satisfiedBy(c, subjectDID, t) }) {
+			return fmt.Errorf("%w: %s", ErrCredentialRequired, r.Type)
+		}
+	}
+	return nil
+}
+
+func (r CredentialRequirement) satisfiedBy(c VerifiableCredential, subjectDID string, t time.Time) bool {
+	return c.SubjectDID == subjectDID && c.HasType(r.Type) && !c.Expired(t) &&
+		(len(r.Issuers) == 0 || slices.Contains(r.Issuers, c.IssuerDID))
+}
@@ -0,0 +1,123 @@
+package core_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func TestCredentialRoundTrip(t *testing.T) {
+	issuer, _ := core.NewAgent("certifier", nil)
+	coder, _ := core.NewAgent("coder", []string{"code-generation"})
+	cred, err := core.NewCredential(issuer, coder.DID.String(), []string{"CertifiedAgent"},
+		map[string]string{"capability": "code-generation", "level": "gold"}, time.Hour)
+	if err != nil {
+		t.Fatalf("NewCredential: %v", err)
+	}
+	if err := core.VerifyCredential(cred); err != nil {
+		t.Fatalf("VerifyCredential: %v", err)
+	}
+	coder.Credentials = []core.VerifiableCredential{cred}
+
+	ann := core.BuildAnnouncement(coder, 60)
+	data, _ := ann.Encode()
+	decoded, err := core.DecodeCapabilityAnnouncement(data)
+	if err != nil || !reflect.DeepEqual(decoded.Credentials, ann.Credentials) {
+		t.Fatalf("decoded credentials = %+v, %v; want %+v", decoded.Credentials, err, ann.Credentials)
+	}
+	if err := core.VerifyCredential(decoded.Credentials[0]); err != nil {
+		t.Errorf("decoded credential: %v", err)
+	}
+
+	tampered := decoded.Credentials[0]
+	tampered.Claims = map[string]string{"capability": "code-generation", "level": "platinum"}
+	if err := core.VerifyCredential(tampered); !errors.Is(err, core.ErrSignatureInvalid) {
+		t.Errorf("tampered credential: %v; want an invalid signature", err)
+	}
+
+	doc, err := cred.MarshalW3C()
+	if err != nil {
+		t.Fatalf("MarshalW3C: %v", err)
+	}
+	var w3c struct {
+		Type    []string          `json:"type"`
+		Issuer  string            `json:"issuer"`
+		Subject map[string]string `json:"credentialSubject"`
+	}
+	if err := json.Unmarshal(doc, &w3c); err != nil {
+		t.Fatalf("W3C document: %v", err)
+	}
+	if w3c.Issuer != issuer.DID.String() || w3c.Subject["id"] != coder.DID.String() || w3c.Subject["level"] != "gold" ||
+		!reflect.DeepEqual(w3c.Type, []string{"VerifiableCredential", "CertifiedAgent"}) {
+		t.Errorf("W3C document = %s", doc)
+	}
+}
+
+func TestHandshakeVerifiesCredentials(t *testing.T) {
+	issuer, _ := core.NewAgent("certifier", nil)
+	alice, _ := core.NewAgent("alice", nil)
+	bob, _ := core.NewAgent("bob", []string{"code-generation"})
+	cred, _ := core.NewCredential(issuer, bob.DID.String(), []string{"CertifiedAgent"}, nil, 0)
+
+	bob.Credentials = []core.VerifiableCredential{cred}
+	init, _ := core.StartHandshake(alice)
+	resp, err := core.RespondHandshake(bob, init)
+	if err != nil {
+		t.Fatalf("RespondHandshake: %v", err)
+	}
+	if err := core.FinishHandshake(init.Challenge, resp); err != nil {
+		t.Fatalf("FinishHandshake: %v", err)
+	}
+	if got := core.NewHandshakeResult(resp).PeerCredentials; len(got) != 1 || got[0].ID != cred.ID {
+		t.Errorf("PeerCredentials = %+v", got)
+	}
+
+	// Alice presenting Bob's credential as her own fails the handshake.
+	alice.Credentials = []core.VerifiableCredential{cred}
+	init, _ = core.StartHandshake(alice)
+	if _, err := core.RespondHandshake(bob, init); err == nil || !strings.Contains(err.Error(), "is about") {
+		t.Errorf("RespondHandshake with a borrowed credential: %v", err)
+	}
+}
+
+func TestCredentialPolicy(t *testing.T) {
+	issuer, _ := core.NewAgent("certifier", nil)
+	rogue, _ := core.NewAgent("rogue", nil)
+	subject, _ := core.NewAgent("coder", nil)
+	did := subject.DID.String()
+	certified, _ := core.NewCredential(issuer, did, []string{"CertifiedAgent"}, nil, 0)
+	selfMade, _ := core.NewCredential(rogue, did, []string{"CertifiedAgent"}, nil, 0)
+	expired, _ := core.NewCredential(issuer, did, []string{"CertifiedAgent"}, nil, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	policy := core.CredentialPolicy{Requirements: []core.CredentialRequirement{
+		{Capability: "code-generation", Type: "CertifiedAgent", Issuers: []string{issuer.DID.String()}},
+	}}
+	if err := policy.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	for name, tc := range map[string]struct {
+		caps  []string
+		creds []core.VerifiableCredential
+		ok    bool
+	}{
+		"certified":        {[]string{"code-generation"}, []core.VerifiableCredential{certified}, true},
+		"other capability": {[]string{"summarise"}, nil, true},
+		"none":             {[]string{"code-generation"}, nil, false},
+		"wrong issuer":     {[]string{"code-generation"}, []core.VerifiableCredential{selfMade}, false},
+		"expired":          {[]string{"code-generation"}, []core.VerifiableCredential{expired}, false},
+	} {
+		err := policy.Check(tc.caps, did, tc.creds, time.Now())
+		if (err == nil) != tc.ok || (err != nil && !errors.Is(err, core.ErrCredentialRequired)) {
+			t.Errorf("%s: Check = %v; want ok %v", name, err, tc.ok)
+		}
+	}
+	if err := (core.CredentialPolicy{Requirements: []core.CredentialRequirement{{}}}).Validate(); err == nil {
+		t.Error("Validate accepted a requirement without a type")
+	}
+}
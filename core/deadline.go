@@ -0,0 +1,44 @@
+package core
+
+// deadline.go — Service-level bounds carried by intents.
+//
+// Besides ExpiresAt, after which an intent must not be served at all, a
+// sender can say by when it needs the work done (Deadline) and how long it
+// will wait for any one answer (MaxLatency).  DefaultNegotiationHandler
+// rejects intents it expects to miss either bound with
+// ReasonDeadlineUnmeetable, given the service times in its
+// NegotiationOptions.  Requesters bound their own waiting by TimeLimit.
+// Neither field is signed.
+
+import "time"
+
+// ReasonDeadlineUnmeetable is the Reason of a NegotiationResponse
+// rejecting an intent the agent does not expect to serve within its
+// Deadline or MaxLatency.
+const ReasonDeadlineUnmeetable = "deadline_unmeetable"
+
+// TimeLimit returns how long from now serving m may take: the shorter of
+// MaxLatency and the time left until Deadline.  ok is false if m sets
+// neither.  A Deadline already past gives a limit of zero.
+func (m *IntentMessage) TimeLimit(now time.Time) (limit time.Duration, ok bool) {
+	if m.MaxLatency > 0 {
+		limit, ok = time.Duration(m.MaxLatency), true
+	}
+	if m.Deadline != 0 {
+		left := max(time.Duration(m.Deadline-now.UnixNano()), 0)
+		if !ok || left < limit {
+			limit, ok = left, true
+		}
+	}
+	return limit, ok
+}
+
+// CanMeet reports whether serving m within expected, starting now, keeps
+// within its Deadline and MaxLatency.
+func (m *IntentMessage) CanMeet(now time.Time, expected time.Duration) bool {
+	limit, ok := m.TimeLimit(now)
+	if !ok {
+		return true
+	}
+	return limit > 0 && expected <= limit
+}
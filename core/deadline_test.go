@@ -0,0 +1,67 @@
+package core_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func TestIntentTimeLimit(t *testing.T) {
+	now := time.Now()
+	for name, tc := range map[string]struct {
+		intent core.IntentMessage
+		limit  time.Duration
+		ok     bool
+	}{
+		"unbounded":       {core.IntentMessage{}, 0, false},
+		"latency":         {core.IntentMessage{MaxLatency: int64(time.Second)}, time.Second, true},
+		"deadline":        {core.IntentMessage{Deadline: now.Add(time.Minute).UnixNano()}, time.Minute, true},
+		"tighter latency": {core.IntentMessage{Deadline: now.Add(time.Minute).UnixNano(), MaxLatency: int64(time.Second)}, time.Second, true},
+		"past deadline":   {core.IntentMessage{Deadline: now.Add(-time.Minute).UnixNano()}, 0, true},
+	} {
+		if limit, ok := tc.intent.TimeLimit(now); limit != tc.limit || ok != tc.ok {
+			t.Errorf("%s: TimeLimit = %v, %v; want %v, %v", name, limit, ok, tc.limit, tc.ok)
+		}
+	}
+}
+
+func TestNegotiationHandlerDeadline(t *testing.T) {
+	agent, _ := core.NewAgent("translator", []string{"translate"})
+	sender, _ := core.NewAgent("sender", nil)
+	handler := core.DefaultNegotiationHandlerWithOptions(agent, core.NegotiationOptions{
+		ServiceTimes: map[string]time.Duration{"translate": 2 * time.Second},
+	})
+
+	for name, tc := range map[string]struct {
+		deadline, maxLatency time.Duration
+		accepted             bool
+	}{
+		"no bounds":     {0, 0, true},
+		"ample":         {time.Minute, 10 * time.Second, true},
+		"tight latency": {0, time.Second, false},
+		"near deadline": {time.Second, 0, false},
+		"past deadline": {-time.Second, 0, false},
+	} {
+		intent, _ := core.CreateIntent(sender, nil, []string{"translate"}, "hola")
+		if tc.deadline != 0 {
+			intent.Deadline = time.Now().Add(tc.deadline).UnixNano()
+		}
+		intent.MaxLatency = int64(tc.maxLatency)
+		resp, err := handler(intent)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if resp.Accepted != tc.accepted || (!tc.accepted && resp.Reason != core.ReasonDeadlineUnmeetable) {
+			t.Errorf("%s: accepted=%v reason=%q; want accepted=%v", name, resp.Accepted, resp.Reason, tc.accepted)
+		}
+	}
+
+	intent, _ := core.CreateIntent(sender, nil, []string{"translate"}, "hola")
+	intent.Deadline, intent.MaxLatency = time.Now().Add(time.Hour).UnixNano(), int64(time.Minute)
+	data, _ := intent.Encode()
+	decoded, err := core.DecodeIntentMessage(data)
+	if err != nil || decoded.Deadline != intent.Deadline || decoded.MaxLatency != intent.MaxLatency {
+		t.Errorf("decoded bounds = %d, %d, %v; want %d, %d", decoded.Deadline, decoded.MaxLatency, err, intent.Deadline, intent.MaxLatency)
+	}
+}
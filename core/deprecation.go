@@ -0,0 +1,238 @@
+package core
+
+// deprecation.go — Tracking deprecated behaviour observed from peers.
+//
+// Operators rarely control every agent in a mesh.  A DeprecationTracker
+// records each time a peer relies on something slated for removal (an older
+// protocol version, v1 frames, unsigned messages, legacy capability names, a
+// handshake without clock-sync fields) so that upgrades can be planned from data rather
+// than guesswork.  Every observation fires the optional event callback; the
+// aggregated Summary is suited to periodic logging (see StartSummaryLoop).
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DeprecationKind identifies one deprecated behaviour.
+type DeprecationKind string
+
+const (
+	DeprecatedVersion          DeprecationKind = "old-protocol-version"
+	DeprecatedUnsignedIntent   DeprecationKind = "unsigned-intent"
+	DeprecatedUnsignedResponse DeprecationKind = "unsigned-response"
+	DeprecatedCapability       DeprecationKind = "legacy-capability"
+	DeprecatedNoClockSync      DeprecationKind = "no-clock-sync"
+	DeprecatedFrameV1          DeprecationKind = "v1-frames"
+)
+
+// MaxDeprecationPeers bounds the distinct peers a DeprecationTracker
+// remembers per kind.  Peer DIDs come off the wire unauthenticated, so
+// observations from further peers still count but are not listed.
+const MaxDeprecationPeers = 1024
+
+// DefaultDeprecationSummaryInterval is used by StartSummaryLoop when the
+// interval given is not positive.
+const DefaultDeprecationSummaryInterval = 10 * time.Minute
+
+// DeprecationEvent is a single observation of deprecated behaviour.
+type DeprecationEvent struct {
+	Kind    DeprecationKind
+	PeerDID string
+	Detail  string
+	At      time.Time
+}
+
+// DeprecationSummary aggregates all observations of one kind.
+type DeprecationSummary struct {
+	Kind     DeprecationKind
+	Count    int      // Total observations
+	Peers    []string // Distinct peer DIDs, sorted; at most MaxDeprecationPeers
+	LastSeen time.Time
+}
+
+// String renders the summary as a single log line.
+func (s DeprecationSummary) String() string {
+	return fmt.Sprintf("deprecated %s: %d occurrence(s) from %d peer(s), last %s",
+		s.Kind, s.Count, len(s.Peers), s.LastSeen.Format(time.RFC3339))
+}
+
+// DeprecationTracker records deprecated behaviour per kind and peer.
+// All methods are concurrency-safe.
+type DeprecationTracker struct {
+	mu       sync.Mutex
+	counts   map[DeprecationKind]map[string]int
+	overflow map[DeprecationKind]int // observations from peers past the cap
+	lastSeen map[DeprecationKind]time.Time
+	legacy   map[string]string // deprecated capability → replacement
+	onEvent  func(DeprecationEvent)
+}
+
+// NewDeprecationTracker creates an empty tracker.
+func NewDeprecationTracker() *DeprecationTracker {
+	return &DeprecationTracker{
+		counts:   make(map[DeprecationKind]map[string]int),
+		overflow: make(map[DeprecationKind]int),
+		lastSeen: make(map[DeprecationKind]time.Time),
+		legacy:   make(map[string]string),
+	}
+}
+
+// OnEvent registers fn to be called synchronously for every observation.
+func (d *DeprecationTracker) OnEvent(fn func(DeprecationEvent)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.onEvent = fn
+}
+
+// DeprecateCapability marks capability name old as legacy.  replacement may
+// be empty if the capability is being removed outright.
+func (d *DeprecationTracker) DeprecateCapability(old, replacement string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.legacy[old] = replacement
+}
+
+// Report records one observation of kind from peerDID.
+func (d *DeprecationTracker) Report(kind DeprecationKind, peerDID, detail string) {
+	ev := DeprecationEvent{Kind: kind, PeerDID: peerDID, Detail: detail, At: time.Now()}
+	d.mu.Lock()
+	peers := d.counts[kind]
+	if peers == nil {
+		peers = make(map[string]int)
+		d.counts[kind] = peers
+	}
+	if _, ok := peers[peerDID]; ok || len(peers) < MaxDeprecationPeers {
+		peers[peerDID]++
+	} else {
+		d.overflow[kind]++
+	}
+	d.lastSeen[kind] = ev.At
+	fn := d.onEvent
+	d.mu.Unlock()
+	if fn != nil {
+		fn(ev)
+	}
+}
+
+// ObserveHandshake reports a handshake from an older protocol version or one
+// that lacks the clock-sync fields a current responder would send.
+func (d *DeprecationTracker) ObserveHandshake(m *HandshakeMessage, isResponse bool) {
+	if versionLess(m.Version, ProtocolVersion) {
+		d.Report(DeprecatedVersion, m.DID, fmt.Sprintf("peer speaks %q, current is %s", m.Version, ProtocolVersion))
+	}
+	if isResponse && m.EchoTimestamp == 0 {
+		d.Report(DeprecatedNoClockSync, m.DID, "handshake response without echo_timestamp")
+	}
+	d.ObserveCapabilities(m.DID, m.Capabilities)
+}
+
+// ObserveIntent reports an unsigned intent and legacy required capabilities.
+func (d *DeprecationTracker) ObserveIntent(m *IntentMessage) {
+	if len(m.Signature) == 0 {
+		d.Report(DeprecatedUnsignedIntent, m.DID, "intent "+m.ID)
+	}
+	d.ObserveCapabilities(m.DID, m.Capabilities)
+}
+
+// ObserveResponse reports an unsigned NegotiationResponse.
+func (d *DeprecationTracker) ObserveResponse(m *NegotiationResponse) {
+	if len(m.Signature) == 0 {
+		d.Report(DeprecatedUnsignedResponse, m.DID, "response to "+m.RequestID)
+	}
+}
+
+// ObserveCapabilities reports every capability in caps that was marked
+// legacy with DeprecateCapability.
+func (d *DeprecationTracker) ObserveCapabilities(peerDID string, caps []string) {
+	for _, c := range caps {
+		d.mu.Lock()
+		repl, ok := d.legacy[c]
+		d.mu.Unlock()
+		if !ok {
+			continue
+		}
+		detail := c
+		if repl != "" {
+			detail += " (use " + repl + ")"
+		}
+		d.Report(DeprecatedCapability, peerDID, detail)
+	}
+}
+
+// Summary returns aggregated observations, ordered by kind.
+func (d *DeprecationTracker) Summary() []DeprecationSummary {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := make([]DeprecationSummary, 0, len(d.counts))
+	for kind, peers := range d.counts {
+		s := DeprecationSummary{Kind: kind, Count: d.overflow[kind], LastSeen: d.lastSeen[kind]}
+		for did, n := range peers {
+			s.Count += n
+			s.Peers = append(s.Peers, did)
+		}
+		sort.Strings(s.Peers)
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Kind < out[j].Kind })
+	return out
+}
+
+// StartSummaryLoop calls fn with the current Summary every interval until
+// done is closed.  Intervals with nothing to report are skipped.  A
+// non-positive interval means DefaultDeprecationSummaryInterval.
+func (d *DeprecationTracker) StartSummaryLoop(interval time.Duration, done <-chan struct{}, fn func([]DeprecationSummary)) {
+	if interval <= 0 {
+		interval = DefaultDeprecationSummaryInterval
+	}
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				if s := d.Summary(); len(s) > 0 {
+					fn(s)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+}
+
+// versionLess reports whether dotted version a is older than b.  An empty or
+// unparseable version counts as older.
+func versionLess(a, b string) bool {
+	pa, oka := parseVersion(a)
+	pb, _ := parseVersion(b)
+	if !oka {
+		return true
+	}
+	for i := range pa {
+		if pa[i] != pb[i] {
+			return pa[i] < pb[i]
+		}
+	}
+	return false
+}
+
+func parseVersion(v string) ([3]int, bool) {
+	var out [3]int
+	parts := strings.Split(v, ".")
+	if v == "" || len(parts) > 3 {
+		return out, false
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return out, false
+		}
+		out[i] = n
+	}
+	return out, true
+}
@@ -0,0 +1,65 @@
+package core_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func TestDeprecationTrackerSummary(t *testing.T) {
+	d := core.NewDeprecationTracker()
+	d.DeprecateCapability("summarize", "summarisation")
+
+	var events []core.DeprecationEvent
+	d.OnEvent(func(ev core.DeprecationEvent) { events = append(events, ev) })
+
+	d.ObserveHandshake(&core.HandshakeMessage{DID: "did:a", Version: "0.9.0", Capabilities: []string{"summarize"}}, true)
+	d.ObserveHandshake(&core.HandshakeMessage{DID: "did:b", Version: core.ProtocolVersion, EchoTimestamp: 1}, true)
+	d.ObserveIntent(&core.IntentMessage{ID: "i1", DID: "did:a"})
+	d.ObserveIntent(&core.IntentMessage{ID: "i2", DID: "did:b"})
+	d.ObserveResponse(&core.NegotiationResponse{RequestID: "i1", DID: "did:b", Signature: []byte{1}})
+
+	// did:a: version, no-clock-sync, legacy capability, unsigned intent.
+	// did:b: unsigned intent.
+	if len(events) != 5 {
+		t.Fatalf("expected 5 events, got %d: %+v", len(events), events)
+	}
+
+	got := map[core.DeprecationKind]core.DeprecationSummary{}
+	for _, s := range d.Summary() {
+		got[s.Kind] = s
+	}
+	if s := got[core.DeprecatedUnsignedIntent]; s.Count != 2 || len(s.Peers) != 2 {
+		t.Errorf("unsigned-intent summary: %+v", s)
+	}
+	if s := got[core.DeprecatedVersion]; s.Count != 1 || s.Peers[0] != "did:a" {
+		t.Errorf("old-version summary: %+v", s)
+	}
+	if _, ok := got[core.DeprecatedUnsignedResponse]; ok {
+		t.Error("signed response must not be reported")
+	}
+	if _, ok := got[core.DeprecatedCapability]; !ok {
+		t.Error("legacy capability not reported")
+	}
+}
+
+func TestDeprecationTrackerCapsPeers(t *testing.T) {
+	d := core.NewDeprecationTracker()
+	for i := range core.MaxDeprecationPeers + 10 {
+		d.ObserveIntent(&core.IntentMessage{ID: "i", DID: fmt.Sprintf("did:%d", i)})
+	}
+	d.ObserveIntent(&core.IntentMessage{ID: "i", DID: "did:0"})
+	s := d.Summary()
+	if len(s) != 1 {
+		t.Fatalf("summary: %+v", s)
+	}
+	if len(s[0].Peers) != core.MaxDeprecationPeers || s[0].Count != core.MaxDeprecationPeers+11 {
+		t.Errorf("summary: %d peers, count %d", len(s[0].Peers), s[0].Count)
+	}
+
+	// A non-positive interval falls back to the default instead of panicking.
+	done := make(chan struct{})
+	d.StartSummaryLoop(0, done, func([]core.DeprecationSummary) {})
+	close(done)
+}
@@ -11,10 +11,12 @@ package core
 
 import (
 	"crypto/ed25519"
-	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"io"
+	"strings"
+	"sync"
 )
 
 // DID represents a Agent Semantic Protocol Decentralized Identifier.
@@ -28,7 +30,13 @@ type DID struct {
 
 // NewDID generates a fresh Ed25519 key-pair and derives a DID from it.
 func NewDID() (*DID, error) {
-	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	return NewDIDFrom(nil)
+}
+
+// NewDIDFrom is NewDID with the key read from r, or from crypto/rand if r
+// is nil.  FOR TESTS ONLY when r is predictable; see SeededEntropy.
+func NewDIDFrom(r io.Reader) (*DID, error) {
+	pub, priv, err := newKey(r)
 	if err != nil {
 		return nil, fmt.Errorf("did: key generation failed: %w", err)
 	}
@@ -126,36 +134,88 @@ var ErrNoPrivateKey = fmt.Errorf("did: private key not available")
 
 // ------------------------------------------------------------------ trust graph
 
-// TrustGraph stores peer-to-peer trust scores in memory.
-// It is concurrency-safe — lock before read/write.
+// TrustGraph stores peer-to-peer trust scores in memory, optionally writing
+// every change through to a persistent TrustStore.
+// It is concurrency-safe.
 type TrustGraph struct {
+	mu     sync.RWMutex
 	scores map[string]float32 // key: "did:agent-semantic-protocol:<from>-><to>"
+	store  TrustStore         // nil for a purely in-memory graph
 }
 
-// NewTrustGraph creates an empty TrustGraph.
+// NewTrustGraph creates an empty in-memory TrustGraph.
 func NewTrustGraph() *TrustGraph {
 	return &TrustGraph{scores: make(map[string]float32)}
 }
 
+// NewTrustGraphFromStore creates a TrustGraph seeded from store's current
+// contents.  Subsequent Set/Apply calls are written through to store, so the
+// graph survives a restart.
+func NewTrustGraphFromStore(store TrustStore) (*TrustGraph, error) {
+	snap, err := store.Snapshot()
+	if err != nil {
+		return nil, fmt.Errorf("trust: load snapshot: %w", err)
+	}
+	tg := &TrustGraph{scores: make(map[string]float32, len(snap)), store: store}
+	for k, v := range snap {
+		tg.scores[k] = clamp(v)
+	}
+	return tg, nil
+}
+
 // Set stores the trust score that `from` assigns to `to`.
-func (tg *TrustGraph) Set(from, to string, score float32) {
-	tg.scores[trustKey(from, to)] = clamp(score)
+// The in-memory score is always updated; the returned error reports a
+// failure to persist it.
+func (tg *TrustGraph) Set(from, to string, score float32) error {
+	tg.mu.Lock()
+	defer tg.mu.Unlock()
+	score = clamp(score)
+	tg.scores[trustKey(from, to)] = score
+	if tg.store != nil {
+		return tg.store.Set(from, to, score)
+	}
+	return nil
 }
 
 // Get returns the trust score that `from` has assigned to `to`.
 // Returns 0 if no entry exists.
 func (tg *TrustGraph) Get(from, to string) float32 {
+	tg.mu.RLock()
+	defer tg.mu.RUnlock()
 	return tg.scores[trustKey(from, to)]
 }
 
 // Apply adds delta to the existing score (clamped to [0,1]).
-func (tg *TrustGraph) Apply(from, to string, delta float32) {
+// Like Set, it returns only persistence errors.
+func (tg *TrustGraph) Apply(from, to string, delta float32) error {
+	tg.mu.Lock()
+	defer tg.mu.Unlock()
 	k := trustKey(from, to)
 	tg.scores[k] = clamp(tg.scores[k] + delta)
+	if tg.store != nil {
+		return tg.store.Set(from, to, tg.scores[k])
+	}
+	return nil
+}
+
+// Snapshot returns a copy of every score keyed by "<from>-><to>".
+func (tg *TrustGraph) Snapshot() map[string]float32 {
+	tg.mu.RLock()
+	defer tg.mu.RUnlock()
+	out := make(map[string]float32, len(tg.scores))
+	for k, v := range tg.scores {
+		out[k] = v
+	}
+	return out
 }
 
 func trustKey(from, to string) string { return from + "->" + to }
 
+// SplitTrustKey splits a "<from>-><to>" key as returned by Snapshot.
+func SplitTrustKey(key string) (from, to string, ok bool) {
+	return strings.Cut(key, "->")
+}
+
 func clamp(v float32) float32 {
 	if v < 0 {
 		return 0
@@ -4,9 +4,17 @@ package core
 //
 // Agents announce their capabilities via CapabilityAnnouncement messages.
 // The registry indexes them for fast lookup, supporting TTL-based expiry.
+//
+// Entries are keyed by DID, the one identifier an agent cannot claim
+// without its key.  AgentIDs are free-form, so several DIDs may announce
+// the same one; the registry keeps them all, indexes them by AgentID and
+// reports the clash through OnConflict instead of letting the newest
+// announcement replace an unrelated agent.
 
 import (
 	"fmt"
+	"slices"
+	"sort"
 	"sync"
 	"time"
 )
@@ -14,31 +22,82 @@ import (
 // DiscoveryRegistry stores announced capability profiles from peer agents.
 // All methods are concurrency-safe.
 type DiscoveryRegistry struct {
-	mu      sync.RWMutex
-	entries map[string]*registryEntry // keyed by AgentID
+	mu         sync.RWMutex
+	entries    map[string]*registryEntry // keyed by DID (AgentID for profiles without one)
+	byAgent    map[string][]string       // AgentID → entry keys, in announcement order
+	aliases    *CapabilityAliases        // nil: names are used as announced
+	catalog    *CapabilityCatalog        // nil: no semantic fallback
+	onConflict func(AgentIDConflict)
+
+	// vectors indexes the EmbeddingVectors of reachable entries, one
+	// graph per dimension, for FindBySimilarity.
+	vectors map[int]*vectorIndex
 }
 
 type registryEntry struct {
-	profile   AgentProfile
-	expiresAt time.Time // zero value means no expiry
+	profile     AgentProfile
+	expiresAt   time.Time // zero value means no expiry
+	unreachable bool      // set while the transport has no connection
+}
+
+// AgentIDConflict reports a DID announcing an AgentID that other live
+// entries already use.
+type AgentIDConflict struct {
+	AgentID string
+	DID     string   // The DID that just announced AgentID
+	Others  []string // DIDs already registered under AgentID
+	At      time.Time
 }
 
 // NewDiscoveryRegistry creates an empty registry.
 func NewDiscoveryRegistry() *DiscoveryRegistry {
-	return &DiscoveryRegistry{entries: make(map[string]*registryEntry)}
+	return &DiscoveryRegistry{
+		entries: make(map[string]*registryEntry),
+		byAgent: make(map[string][]string),
+		vectors: make(map[int]*vectorIndex),
+	}
 }
 
-// Announce registers or updates an agent's capability profile.
-// ttlSeconds == 0 means the entry never expires.
-func (r *DiscoveryRegistry) Announce(profile AgentProfile, ttlSeconds int64) {
+// SetAliases makes the registry store and search capabilities by their
+// canonical names.  Entries already registered are normalised too.
+func (r *DiscoveryRegistry) SetAliases(aliases *CapabilityAliases) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.aliases = aliases
+	for _, e := range r.entries {
+		e.profile.Capabilities = aliases.Normalize(e.profile.Capabilities)
+	}
+}
+
+// SetCatalog enables semantic fallback in FindByCapability.
+func (r *DiscoveryRegistry) SetCatalog(catalog *CapabilityCatalog) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.catalog = catalog
+}
+
+// OnConflict registers fn to be called synchronously whenever an
+// announcement gives an AgentID a second live DID.
+func (r *DiscoveryRegistry) OnConflict(fn func(AgentIDConflict)) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	r.onConflict = fn
+}
 
+// Announce registers or updates an agent's capability profile.
+// ttlSeconds == 0 means the entry never expires.
+func (r *DiscoveryRegistry) Announce(profile AgentProfile, ttlSeconds int64) {
+	profile.Capabilities = r.aliases.Normalize(profile.Capabilities)
 	var exp time.Time
 	if ttlSeconds > 0 {
 		exp = time.Now().Add(time.Duration(ttlSeconds) * time.Second)
 	}
-	r.entries[profile.AgentID] = &registryEntry{profile: profile, expiresAt: exp}
+	r.mu.Lock()
+	conflict, fn := r.put(&registryEntry{profile: profile, expiresAt: exp}), r.onConflict
+	r.mu.Unlock()
+	if conflict != nil && fn != nil {
+		fn(*conflict)
+	}
 }
 
 // AnnounceFromMessage registers the agent described by a CapabilityAnnouncement.
@@ -47,54 +106,205 @@ func (r *DiscoveryRegistry) AnnounceFromMessage(msg *CapabilityAnnouncement) {
 		AgentID:      msg.AgentID,
 		DID:          msg.DID,
 		Capabilities: append([]string(nil), msg.Capabilities...),
+		Specs:        copySpecs(msg.Specs),
+		Provenance:   copyProvenance(msg.Provenance),
+		Credentials:  slices.Clone(msg.Credentials),
 	}, msg.TTL)
 }
 
-// Remove deletes an agent's entry from the registry.
-func (r *DiscoveryRegistry) Remove(agentID string) {
+// Remove deletes the entry for id, a DID, or every entry claiming id as
+// their AgentID.
+func (r *DiscoveryRegistry) Remove(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, k := range r.resolve(id) {
+		r.drop(k)
+	}
+}
+
+// SetReachable marks the entries for id, a DID or an AgentID, reachable or
+// not.  Unreachable agents stay registered but are left out of
+// FindByCapability until marked reachable again or re-announced.  Unknown
+// ids are ignored.
+func (r *DiscoveryRegistry) SetReachable(id string, reachable bool) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	delete(r.entries, agentID)
+	for _, k := range r.resolve(id) {
+		r.entries[k].unreachable = !reachable
+		r.index(k)
+	}
 }
 
-// FindByCapability returns all live agents that declare ALL of required capabilities.
+// SetLiveness records what a HealthMonitor observed of the entry for did.
+// Unhealthy agents stay registered but are left out of FindByCapability
+// and FindBySimilarity until a probe finds them healthy again.  Unknown
+// DIDs are ignored.
+func (r *DiscoveryRegistry) SetLiveness(did string, l Liveness) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, ok := r.entries[did]
+	if !ok {
+		return
+	}
+	e.profile.Liveness = &l
+	r.index(did)
+}
+
+// RemoveCapability withdraws capability from the entry for did, leaving its
+// expiry and reachability as they were.  Unknown DIDs are ignored.
+func (r *DiscoveryRegistry) RemoveCapability(did, capability string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, ok := r.entries[did]
+	if !ok {
+		return
+	}
+	e.profile.Capabilities = slices.DeleteFunc(slices.Clone(e.profile.Capabilities),
+		func(c string) bool { return c == capability })
+}
+
+// Reachable reports whether an entry for id, a DID or an AgentID, is
+// registered and not marked unreachable.
+func (r *DiscoveryRegistry) Reachable(id string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, k := range r.resolve(id) {
+		if e := r.entries[k]; !e.isExpired() && !e.unreachable {
+			return true
+		}
+	}
+	return false
+}
+
+// FindByCapability returns all live, reachable, healthy agents that
+// declare ALL of required capabilities, ordered by DID.
+// If none does and the registry has a catalog, it returns the agents whose
+// capabilities match semantically instead, best match first.
 func (r *DiscoveryRegistry) FindByCapability(required ...string) []AgentProfile {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
+	required = r.aliases.Normalize(required)
 
 	var results []AgentProfile
-	for _, e := range r.entries {
-		if e.isExpired() {
+	for _, e := range r.sorted() {
+		if e.isExpired() || !e.available() {
 			continue
 		}
 		if hasAll(e.profile.Capabilities, required) {
 			results = append(results, e.profile)
 		}
 	}
+	if len(results) > 0 || r.catalog == nil {
+		return results
+	}
+
+	type scored struct {
+		profile AgentProfile
+		score   float64
+	}
+	var fuzzy []scored
+	for _, e := range r.sorted() {
+		if e.isExpired() || !e.available() {
+			continue
+		}
+		if s, ok := r.catalog.coverage(e.profile.Capabilities, required); ok {
+			fuzzy = append(fuzzy, scored{e.profile, s})
+		}
+	}
+	sort.SliceStable(fuzzy, func(i, j int) bool { return fuzzy[i].score > fuzzy[j].score })
+	for _, f := range fuzzy {
+		results = append(results, f.profile)
+	}
 	return results
 }
 
+// FindBySimilarity returns up to k live, reachable, healthy agents whose
+// EmbeddingVector has a cosine similarity of at least threshold with
+// vector, most similar first.  Only vectors of the same dimension are
+// compared.  The search goes through an approximate nearest-neighbour
+// index, so in a large registry it may occasionally miss a close match in
+// exchange for not comparing vector with every agent.
+func (r *DiscoveryRegistry) FindBySimilarity(vector []float32, k int, threshold float64) []AgentProfile {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	x := r.vectors[len(vector)]
+	if k <= 0 || x == nil {
+		return nil
+	}
+	// Expired entries stay indexed until evicted, so widen the search
+	// until k live ones are found or the index is exhausted.
+	for ef := k; ; ef *= 2 {
+		hits := x.Search(vector, ef)
+		var out []AgentProfile
+		for _, h := range hits {
+			if h.sim < threshold {
+				return out
+			}
+			if e := r.entries[x.Key(h.id)]; !e.isExpired() {
+				out = append(out, e.profile)
+				if len(out) == k {
+					return out
+				}
+			}
+		}
+		if len(hits) < ef || ef >= x.Len() {
+			return out
+		}
+	}
+}
+
 // FindByDID returns the profile registered for a specific DID, or false.
 func (r *DiscoveryRegistry) FindByDID(did string) (AgentProfile, bool) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
-	for _, e := range r.entries {
-		if e.isExpired() {
-			continue
+	e, ok := r.entries[did]
+	if !ok || e.isExpired() || e.profile.DID != did {
+		return AgentProfile{}, false
+	}
+	return e.profile, true
+}
+
+// FindByAgentID returns the live profiles announcing agentID, oldest
+// announcement first.  More than one means the AgentID is contested.
+func (r *DiscoveryRegistry) FindByAgentID(agentID string) []AgentProfile {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var out []AgentProfile
+	for _, k := range r.byAgent[agentID] {
+		if e := r.entries[k]; !e.isExpired() {
+			out = append(out, e.profile)
 		}
-		if e.profile.DID == did {
-			return e.profile, true
+	}
+	return out
+}
+
+// Conflicts returns the AgentIDs that more than one live DID announces,
+// sorted.
+func (r *DiscoveryRegistry) Conflicts() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var out []string
+	for id, keys := range r.byAgent {
+		live := 0
+		for _, k := range keys {
+			if !r.entries[k].isExpired() {
+				live++
+			}
+		}
+		if live > 1 {
+			out = append(out, id)
 		}
 	}
-	return AgentProfile{}, false
+	sort.Strings(out)
+	return out
 }
 
-// All returns a snapshot of all live profiles.
+// All returns a snapshot of all live profiles, ordered by DID.
 func (r *DiscoveryRegistry) All() []AgentProfile {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	var out []AgentProfile
-	for _, e := range r.entries {
+	for _, e := range r.sorted() {
 		if !e.isExpired() {
 			out = append(out, e.profile)
 		}
@@ -102,14 +312,66 @@ func (r *DiscoveryRegistry) All() []AgentProfile {
 	return out
 }
 
+// DiscoveryRecord is a registry entry in portable form (see AgentSnapshot).
+type DiscoveryRecord struct {
+	Profile   AgentProfile `json:"profile"`
+	ExpiresAt int64        `json:"expires_at,omitempty"` // Unix nanoseconds; 0 = never
+}
+
+// Export returns every live entry with its expiry.
+func (r *DiscoveryRegistry) Export() []DiscoveryRecord {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var out []DiscoveryRecord
+	for _, e := range r.sorted() {
+		if e.isExpired() {
+			continue
+		}
+		rec := DiscoveryRecord{Profile: e.profile}
+		if !e.expiresAt.IsZero() {
+			rec.ExpiresAt = e.expiresAt.UnixNano()
+		}
+		out = append(out, rec)
+	}
+	return out
+}
+
+// Import adds records, keeping their original expiry.  Records that have
+// already expired are skipped.  AgentID conflicts are reported as for
+// Announce.
+func (r *DiscoveryRegistry) Import(records []DiscoveryRecord) {
+	var conflicts []AgentIDConflict
+	r.mu.Lock()
+	for _, rec := range records {
+		e := &registryEntry{profile: rec.Profile}
+		e.profile.Capabilities = r.aliases.Normalize(e.profile.Capabilities)
+		if rec.ExpiresAt != 0 {
+			e.expiresAt = time.Unix(0, rec.ExpiresAt)
+		}
+		if e.isExpired() {
+			continue
+		}
+		if c := r.put(e); c != nil {
+			conflicts = append(conflicts, *c)
+		}
+	}
+	fn := r.onConflict
+	r.mu.Unlock()
+	if fn != nil {
+		for _, c := range conflicts {
+			fn(c)
+		}
+	}
+}
+
 // Evict removes all expired entries and returns the count removed.
 func (r *DiscoveryRegistry) Evict() int {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	n := 0
-	for id, e := range r.entries {
+	for k, e := range r.entries {
 		if e.isExpired() {
-			delete(r.entries, id)
+			r.drop(k)
 			n++
 		}
 	}
@@ -134,6 +396,7 @@ func (r *DiscoveryRegistry) StartEvictionLoop(interval time.Duration, done <-cha
 }
 
 // BuildAnnouncement creates a CapabilityAnnouncement for the given agent.
+// It is unsigned: sign it with SignAnnouncement once it is complete.
 func BuildAnnouncement(agent *Agent, ttlSeconds int64) *CapabilityAnnouncement {
 	caps := make([]string, len(agent.Capabilities))
 	copy(caps, agent.Capabilities)
@@ -143,7 +406,53 @@ func BuildAnnouncement(agent *Agent, ttlSeconds int64) *CapabilityAnnouncement {
 		Capabilities: caps,
 		Timestamp:    now(),
 		TTL:          ttlSeconds,
+		Specs:        copySpecs(agent.Specs),
+		Credentials:  slices.Clone(agent.Credentials),
+	}
+}
+
+// SignAnnouncement sets m.PublicKey to agent's key and m.Signature to its
+// signature of the rest of m.  Any later change to m invalidates it.
+func SignAnnouncement(agent *Agent, m *CapabilityAnnouncement) error {
+	m.PublicKey = agent.PublicKey()
+	sig, err := agent.Sign(announcementSigningData(m))
+	if err != nil {
+		return fmt.Errorf("announcement: sign: %w", err)
+	}
+	m.Signature = sig
+	return nil
+}
+
+// VerifyAnnouncement checks that m is signed by the key bound to m.DID.
+// Unsigned announcements fail.
+func VerifyAnnouncement(m *CapabilityAnnouncement) error {
+	if len(m.Signature) == 0 {
+		return fmt.Errorf("announcement: from %s is unsigned", m.DID)
 	}
+	d, err := ParseDID(m.DID)
+	if err != nil {
+		return fmt.Errorf("announcement: %w", err)
+	}
+	if !d.ValidateBinding(m.PublicKey) {
+		return fmt.Errorf("announcement: key does not match %s", m.DID)
+	}
+	pub, err := DIDFromPublicKey(m.PublicKey)
+	if err != nil {
+		return fmt.Errorf("announcement: %w", err)
+	}
+	if !pub.Verify(announcementSigningData(m), m.Signature) {
+		return fmt.Errorf("announcement: %w from %s", ErrSignatureInvalid, m.DID)
+	}
+	return nil
+}
+
+// announcementSigningData is what an announcement's signature covers: its
+// encoding without Signature.
+func announcementSigningData(m *CapabilityAnnouncement) []byte {
+	c := *m
+	c.Signature = nil
+	data, _ := c.Encode()
+	return data
 }
 
 // CapabilitySetDiff computes which of required are absent from available.
@@ -170,6 +479,118 @@ func SummariseRegistry(r *DiscoveryRegistry) string {
 
 // ------------------------------------------------------------------ helpers
 
+// entryKey is the key of profile's entry: its DID, or its AgentID for
+// profiles announced without one.
+func entryKey(profile AgentProfile) string {
+	if profile.DID != "" {
+		return profile.DID
+	}
+	return profile.AgentID
+}
+
+// put stores e, replacing the entry for the same key, and returns the
+// conflict it creates, if any.  r.mu must be held.
+func (r *DiscoveryRegistry) put(e *registryEntry) *AgentIDConflict {
+	k, id := entryKey(e.profile), e.profile.AgentID
+	if prev, ok := r.entries[k]; ok && prev.profile.AgentID != id {
+		r.drop(k)
+	} else if ok && e.profile.Liveness == nil {
+		// A re-announcement does not make a dead agent answer.
+		e.profile.Liveness = prev.profile.Liveness
+	}
+	_, existed := r.entries[k]
+	r.entries[k] = e
+	r.index(k)
+	if existed {
+		return nil
+	}
+	var others []string
+	for _, o := range r.byAgent[id] {
+		if !r.entries[o].isExpired() {
+			others = append(others, o)
+		}
+	}
+	r.byAgent[id] = append(r.byAgent[id], k)
+	if len(others) == 0 {
+		return nil
+	}
+	return &AgentIDConflict{AgentID: id, DID: e.profile.DID, Others: others, At: time.Now()}
+}
+
+// drop deletes the entry for k and its AgentID index.  r.mu must be held.
+func (r *DiscoveryRegistry) drop(k string) {
+	e, ok := r.entries[k]
+	if !ok {
+		return
+	}
+	delete(r.entries, k)
+	if x := r.vectors[len(e.profile.EmbeddingVector)]; x != nil {
+		x.Remove(k)
+	}
+	id := e.profile.AgentID
+	keys := r.byAgent[id]
+	for i, o := range keys {
+		if o == k {
+			keys = append(keys[:i:i], keys[i+1:]...)
+			break
+		}
+	}
+	if len(keys) == 0 {
+		delete(r.byAgent, id)
+	} else {
+		r.byAgent[id] = keys
+	}
+}
+
+// index brings the vector index up to date with the entry for k, which
+// must exist: its vector is indexed if it has one and is available.  r.mu
+// must be held.
+func (r *DiscoveryRegistry) index(k string) {
+	for _, x := range r.vectors {
+		x.Remove(k)
+	}
+	e := r.entries[k]
+	dim := len(e.profile.EmbeddingVector)
+	if dim == 0 || !e.available() {
+		return
+	}
+	x := r.vectors[dim]
+	if x == nil {
+		x = newVectorIndex()
+		r.vectors[dim] = x
+	}
+	x.Insert(k, e.profile.EmbeddingVector)
+}
+
+// resolve returns the entry keys id refers to: its own entry if id is a
+// DID, or every entry announcing id as its AgentID.  r.mu must be held.
+func (r *DiscoveryRegistry) resolve(id string) []string {
+	if _, ok := r.entries[id]; ok {
+		return []string{id}
+	}
+	return r.byAgent[id]
+}
+
+// sorted returns the entries ordered by key.  r.mu must be held.
+func (r *DiscoveryRegistry) sorted() []*registryEntry {
+	keys := make([]string, 0, len(r.entries))
+	for k := range r.entries {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make([]*registryEntry, len(keys))
+	for i, k := range keys {
+		out[i] = r.entries[k]
+	}
+	return out
+}
+
+// available reports whether the entry may be returned by lookups: it is
+// reachable and not known to be unhealthy.
+func (e *registryEntry) available() bool {
+	return !e.unreachable && (e.profile.Liveness == nil || e.profile.Liveness.Healthy)
+}
+
 func (e *registryEntry) isExpired() bool {
 	if e.expiresAt.IsZero() {
 		return false
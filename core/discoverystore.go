@@ -0,0 +1,178 @@
+package core
+
+// discoverystore.go — Persisting a DiscoveryRegistry across restarts.
+//
+// Save writes every live entry to a JSON file with the TTL it has left,
+// atomically and under a SHA-256 checksum; Load reads it back, so a
+// rebooted agent knows its peers again without waiting for them to
+// re-announce.  Time spent down counts against the saved TTLs: an entry
+// with a minute left that is loaded two minutes later is skipped.
+//
+// Load refuses, and leaves the registry untouched, a file whose version is
+// unknown, whose checksum does not match or that holds a profile whose
+// public key does not belong to its DID.
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const discoveryFileVersion = 1
+
+// discoveryFile is the on-disk form of a registry.
+type discoveryFile struct {
+	Version  int             `json:"version"`
+	SavedAt  int64           `json:"saved_at,string"` // Unix nanoseconds
+	Checksum string          `json:"checksum"`        // Hex SHA-256 of Records, compacted
+	Records  json.RawMessage `json:"records"`
+}
+
+// savedRecord is one entry in a discoveryFile.
+type savedRecord struct {
+	Profile AgentProfile `json:"profile"`
+	TTL     int64        `json:"ttl,string,omitempty"` // Nanoseconds left at SavedAt; 0 = never expires
+}
+
+// Save writes the registry's live entries to path, replacing it
+// atomically.
+func (r *DiscoveryRegistry) Save(path string) error {
+	saved := time.Now()
+	var records []savedRecord
+	for _, rec := range r.Export() {
+		s := savedRecord{Profile: rec.Profile}
+		if rec.ExpiresAt != 0 {
+			s.TTL = max(rec.ExpiresAt-saved.UnixNano(), 1)
+		}
+		records = append(records, s)
+	}
+	body, err := json.Marshal(records)
+	if err != nil {
+		return fmt.Errorf("discovery: encode: %w", err)
+	}
+	sum := sha256.Sum256(body)
+	data, err := json.MarshalIndent(discoveryFile{
+		Version:  discoveryFileVersion,
+		SavedAt:  saved.UnixNano(),
+		Checksum: hex.EncodeToString(sum[:]),
+		Records:  body,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("discovery: encode: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("discovery: create temp: %w", err)
+	}
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("discovery: write: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("discovery: close: %w", err)
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("discovery: rename: %w", err)
+	}
+	return nil
+}
+
+// Load adds the entries saved in path by Save and returns how many it
+// restored.  Entries whose TTL ran out while the file sat on disk are
+// skipped.  A missing file restores nothing and is not an error.
+func (r *DiscoveryRegistry) Load(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("discovery: read %s: %w", path, err)
+	}
+	var f discoveryFile
+	if err = json.Unmarshal(data, &f); err != nil {
+		return 0, fmt.Errorf("discovery: parse %s: %w", path, err)
+	}
+	if f.Version != discoveryFileVersion {
+		return 0, fmt.Errorf("discovery: %s: unsupported version %d", path, f.Version)
+	}
+	var body bytes.Buffer
+	if err = json.Compact(&body, f.Records); err != nil {
+		return 0, fmt.Errorf("discovery: parse %s: %w", path, err)
+	}
+	if sum := sha256.Sum256(body.Bytes()); hex.EncodeToString(sum[:]) != f.Checksum {
+		return 0, fmt.Errorf("discovery: %s: checksum mismatch", path)
+	}
+	var saved []savedRecord
+	if err = json.Unmarshal(f.Records, &saved); err != nil {
+		return 0, fmt.Errorf("discovery: parse %s: %w", path, err)
+	}
+	records := make([]DiscoveryRecord, 0, len(saved))
+	for _, s := range saved {
+		if err = checkSavedProfile(s.Profile); err != nil {
+			return 0, fmt.Errorf("discovery: %s: %w", path, err)
+		}
+		rec := DiscoveryRecord{Profile: s.Profile}
+		if s.TTL > 0 {
+			rec.ExpiresAt = f.SavedAt + s.TTL
+			if rec.ExpiresAt <= now() {
+				continue
+			}
+		}
+		records = append(records, rec)
+	}
+	r.Import(records)
+	return len(records), nil
+}
+
+// StartSnapshotLoop saves the registry to path every interval until done
+// is closed.  Errors are passed to onError, if set.  Call Save after
+// closing done to keep the final state.
+func (r *DiscoveryRegistry) StartSnapshotLoop(path string, interval time.Duration, done <-chan struct{}, onError func(error)) {
+	save := func() {
+		if err := r.Save(path); err != nil && onError != nil {
+			onError(err)
+		}
+	}
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				save()
+			case <-done:
+				return
+			}
+		}
+	}()
+}
+
+// checkSavedProfile checks that a loaded profile's identifiers hang
+// together: a parseable DID, and a public key, if any, that the DID was
+// derived from.
+func checkSavedProfile(p AgentProfile) error {
+	if p.DID == "" {
+		if p.AgentID == "" {
+			return fmt.Errorf("profile without DID or agent ID")
+		}
+		return nil
+	}
+	if _, err := ParseDID(p.DID); err != nil {
+		return fmt.Errorf("profile %s: %w", p.DID, err)
+	}
+	if len(p.PublicKey) == 0 {
+		return nil
+	}
+	d, err := DIDFromPublicKey(p.PublicKey)
+	if err != nil || d.String() != p.DID {
+		return fmt.Errorf("profile %s: public key does not match DID", p.DID)
+	}
+	return nil
+}
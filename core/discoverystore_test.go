@@ -0,0 +1,104 @@
+package core_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func TestDiscoveryRegistrySaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "peers.json")
+	a, _ := core.NewAgent("translator", []string{"translate"})
+	b, _ := core.NewAgent("summariser", []string{"summarise"})
+
+	r := core.NewDiscoveryRegistry()
+	r.Announce(core.AgentProfile{
+		AgentID:         a.ID,
+		DID:             a.DID.String(),
+		Capabilities:    a.Capabilities,
+		EmbeddingVector: []float32{1, 0},
+		PublicKey:       a.DID.PublicKey(),
+	}, 0)
+	r.Announce(core.AgentProfile{AgentID: b.ID, DID: b.DID.String(), Capabilities: b.Capabilities}, 3600)
+	r.Import([]core.DiscoveryRecord{{
+		Profile:   core.AgentProfile{AgentID: "fleeting", DID: "did:agent-semantic-protocol:fleeting"},
+		ExpiresAt: time.Now().Add(50 * time.Millisecond).UnixNano(),
+	}})
+	if err := r.Save(path); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	restored := core.NewDiscoveryRegistry()
+	n, err := restored.Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("Load restored %d entries, want 2 (the expired one skipped)", n)
+	}
+	got, ok := restored.FindByDID(a.DID.String())
+	want, _ := r.FindByDID(a.DID.String())
+	if !ok || !reflect.DeepEqual(got, want) {
+		t.Errorf("restored profile = %+v, want %+v", got, want)
+	}
+	if got := restored.FindBySimilarity([]float32{1, 0}, 1, 0.99); len(got) != 1 || got[0].DID != a.DID.String() {
+		t.Errorf("restored vector not indexed: %v", got)
+	}
+	recs := restored.Export()
+	for _, rec := range recs {
+		if rec.Profile.DID == b.DID.String() {
+			if left := time.Until(time.Unix(0, rec.ExpiresAt)); left <= 0 || left > time.Hour {
+				t.Errorf("restored TTL = %v, want just under an hour", left)
+			}
+		}
+	}
+
+	if n, err := core.NewDiscoveryRegistry().Load(filepath.Join(t.TempDir(), "missing.json")); n != 0 || err != nil {
+		t.Errorf("Load(missing file) = %d, %v; want 0, nil", n, err)
+	}
+}
+
+func TestDiscoveryRegistryLoadRejects(t *testing.T) {
+	dir := t.TempDir()
+	a, _ := core.NewAgent("translator", []string{"translate"})
+	b, _ := core.NewAgent("impostor", nil)
+
+	good := core.NewDiscoveryRegistry()
+	good.Announce(core.AgentProfile{AgentID: a.ID, DID: a.DID.String(), Capabilities: a.Capabilities, PublicKey: a.DID.PublicKey()}, 0)
+	tampered := filepath.Join(dir, "tampered.json")
+	if err := good.Save(tampered); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(tampered)
+	if err := os.WriteFile(tampered, bytes.Replace(data, []byte("translate"), []byte("transmute"), 1), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	forged := core.NewDiscoveryRegistry()
+	forged.Announce(core.AgentProfile{AgentID: a.ID, DID: a.DID.String(), PublicKey: b.DID.PublicKey()}, 0)
+	mismatched := filepath.Join(dir, "mismatched.json")
+	if err := forged.Save(mismatched); err != nil {
+		t.Fatal(err)
+	}
+
+	for path, want := range map[string]string{
+		tampered:   "checksum mismatch",
+		mismatched: "public key does not match DID",
+	} {
+		r := core.NewDiscoveryRegistry()
+		n, err := r.Load(path)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Load(%s) = %d, %v; want error containing %q", filepath.Base(path), n, err, want)
+		}
+		if len(r.All()) != 0 {
+			t.Errorf("Load(%s) left %d entries in the registry", filepath.Base(path), len(r.All()))
+		}
+	}
+}
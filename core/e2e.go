@@ -0,0 +1,214 @@
+package core
+
+// e2e.go — End-to-end encrypted intent payloads.
+//
+// libp2p encrypts each hop, but every agent an intent passes through, and
+// every log it is written to, sees the payload.  An agent can instead seal
+// the payload for one recipient DID:
+//
+//   - Each agent has an X25519 key-agreement key derived from its Ed25519
+//     seed.  It advertises the public half in handshakes, signed with its
+//     DID key so nobody can substitute their own.
+//   - The sender generates a one-time X25519 key, computes the shared secret
+//     with the recipient's key and derives an AES-256-GCM key from it with
+//     HKDF-SHA256.  The ciphertext replaces Payload in EncryptedPayload and
+//     is authenticated together with the intent ID and both DIDs.
+//   - The intent signature covers the sealed payload, so intermediaries can
+//     still verify who sent it without being able to read it.
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+)
+
+// ErrNotRecipient is returned when decrypting a payload sealed for a
+// different DID.
+var ErrNotRecipient = fmt.Errorf("e2e: payload is sealed for another recipient")
+
+// keyAgreementContext separates key-agreement signatures from every other
+// use of the DID key.
+const keyAgreementContext = "agent-semantic-protocol key agreement\x00"
+
+// payloadKDFInfo labels the keys derived for payload encryption.
+const payloadKDFInfo = "agent-semantic-protocol intent payload v1"
+
+// KeyAgreementKey returns the agent's X25519 public key.
+func (a *Agent) KeyAgreementKey() []byte {
+	k, err := a.keyAgreement()
+	if err != nil {
+		return nil
+	}
+	return k.PublicKey().Bytes()
+}
+
+// keyAgreement derives the agent's X25519 private key from its Ed25519 seed
+// the way Ed25519 derives its own scalar, so the key needs no storage of its
+// own and survives identity export and import.
+func (a *Agent) keyAgreement() (*ecdh.PrivateKey, error) {
+	if len(a.privKey) < 32 {
+		return nil, fmt.Errorf("e2e: agent %s has no private key", a.ID)
+	}
+	h := sha512.Sum512(a.privKey[:32])
+	return ecdh.X25519().NewPrivateKey(h[:32])
+}
+
+// signKeyAgreement returns the agent's key-agreement key and its signature.
+func (a *Agent) signKeyAgreement() (key, sig []byte) {
+	key = a.KeyAgreementKey()
+	if key == nil {
+		return nil, nil
+	}
+	sig, err := a.Sign(append([]byte(keyAgreementContext), key...))
+	if err != nil {
+		return nil, nil
+	}
+	return key, sig
+}
+
+// PeerKeyAgreement returns the key-agreement key advertised in m after
+// checking that m's DID key signed it.  It returns nil, nil if m offers
+// none.
+func PeerKeyAgreement(m *HandshakeMessage) ([]byte, error) {
+	if len(m.KeyAgreement) == 0 {
+		return nil, nil
+	}
+	d, err := ParseDID(m.DID)
+	if err != nil {
+		return nil, fmt.Errorf("e2e: %w", err)
+	}
+	if !d.ValidateBinding(m.PublicKey) {
+		return nil, fmt.Errorf("e2e: DID/key binding mismatch for %s", m.DID)
+	}
+	pub, err := DIDFromPublicKey(m.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("e2e: %w", err)
+	}
+	if !pub.Verify(append([]byte(keyAgreementContext), m.KeyAgreement...), m.KeyAgreementSig) {
+		return nil, fmt.Errorf("e2e: key agreement from %s: %w", m.DID, ErrSignatureInvalid)
+	}
+	return append([]byte(nil), m.KeyAgreement...), nil
+}
+
+// EncryptIntentPayload seals intent.Payload for recipientDID, whose X25519
+// key is recipientKey, and re-signs the intent as sender.  Payload is
+// cleared; intents without a payload are left alone.
+func EncryptIntentPayload(sender *Agent, intent *IntentMessage, recipientDID string, recipientKey []byte) error {
+	if intent.Payload == "" {
+		return nil
+	}
+	if intent.DID != sender.DID.String() {
+		return fmt.Errorf("e2e: intent %s was not created by %s", intent.ID, sender.ID)
+	}
+	peer, err := ecdh.X25519().NewPublicKey(recipientKey)
+	if err != nil {
+		return fmt.Errorf("e2e: recipient key: %w", err)
+	}
+	seed := make([]byte, 32)
+	if err = sender.random(seed); err != nil {
+		return fmt.Errorf("e2e: ephemeral key: %w", err)
+	}
+	eph, err := ecdh.X25519().NewPrivateKey(seed)
+	if err != nil {
+		return fmt.Errorf("e2e: ephemeral key: %w", err)
+	}
+	sealed := &EncryptedPayload{
+		RecipientDID: recipientDID,
+		EphemeralKey: eph.PublicKey().Bytes(),
+	}
+	aead, err := payloadCipher(eph, peer, sealed.EphemeralKey, recipientKey)
+	if err != nil {
+		return err
+	}
+	sealed.Nonce = make([]byte, aead.NonceSize())
+	if err = sender.random(sealed.Nonce); err != nil {
+		return fmt.Errorf("e2e: nonce: %w", err)
+	}
+	sealed.Ciphertext = aead.Seal(nil, sealed.Nonce, []byte(intent.Payload), payloadAAD(intent, recipientDID))
+
+	intent.Payload = ""
+	intent.EncryptedPayload = sealed
+	if err = SignIntent(sender, intent); err != nil {
+		return fmt.Errorf("e2e: %w", err)
+	}
+	return nil
+}
+
+// DecryptIntentPayload opens intent's sealed payload as recipient, restoring
+// Payload and clearing EncryptedPayload.  Verify the intent's signature
+// first: afterwards it no longer matches.  Intents without a sealed payload
+// are left alone.
+func DecryptIntentPayload(recipient *Agent, intent *IntentMessage) error {
+	sealed := intent.EncryptedPayload
+	if sealed == nil {
+		return nil
+	}
+	if sealed.RecipientDID != recipient.DID.String() {
+		return fmt.Errorf("%w: %s", ErrNotRecipient, sealed.RecipientDID)
+	}
+	priv, err := recipient.keyAgreement()
+	if err != nil {
+		return err
+	}
+	eph, err := ecdh.X25519().NewPublicKey(sealed.EphemeralKey)
+	if err != nil {
+		return fmt.Errorf("e2e: ephemeral key: %w", err)
+	}
+	aead, err := payloadCipher(priv, eph, sealed.EphemeralKey, priv.PublicKey().Bytes())
+	if err != nil {
+		return err
+	}
+	if len(sealed.Nonce) != aead.NonceSize() {
+		return fmt.Errorf("e2e: invalid nonce")
+	}
+	plain, err := aead.Open(nil, sealed.Nonce, sealed.Ciphertext, payloadAAD(intent, sealed.RecipientDID))
+	if err != nil {
+		return fmt.Errorf("e2e: decrypt: %w", err)
+	}
+	intent.Payload = string(plain)
+	intent.EncryptedPayload = nil
+	return nil
+}
+
+// payloadCipher derives the AES-256-GCM cipher shared by priv and pub.  The
+// salt binds the key to both public keys.
+func payloadCipher(priv *ecdh.PrivateKey, pub *ecdh.PublicKey, ephemeralKey, recipientKey []byte) (cipher.AEAD, error) {
+	shared, err := priv.ECDH(pub)
+	if err != nil {
+		return nil, fmt.Errorf("e2e: key agreement: %w", err)
+	}
+	salt := append(append([]byte(nil), ephemeralKey...), recipientKey...)
+	key, err := hkdf.Key(sha256.New, shared, salt, payloadKDFInfo, 32)
+	if err != nil {
+		return nil, fmt.Errorf("e2e: derive key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("e2e: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// payloadAAD ties a ciphertext to its intent, sender and recipient.
+func payloadAAD(intent *IntentMessage, recipientDID string) []byte {
+	var b bytes.Buffer
+	for _, s := range []string{intent.ID, intent.DID, recipientDID} {
+		b.WriteString(s)
+		b.WriteByte(0)
+	}
+	return b.Bytes()
+}
+
+// intentSigningData is what an intent's signature covers: its encoding
+// without Signature, sealed payload included.
+func intentSigningData(m *IntentMessage) []byte {
+	c := *m
+	c.Signature = nil
+	data, _ := c.Encode()
+	return data
+}
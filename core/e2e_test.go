@@ -0,0 +1,81 @@
+package core_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func TestSealedIntentPayload(t *testing.T) {
+	sender, _ := core.NewAgent("sender", nil)
+	recipient, _ := core.NewAgent("recipient", []string{"ocr"})
+	other, _ := core.NewAgent("other", nil)
+
+	// The recipient's key reaches the sender through the handshake.
+	hs, _ := core.StartHandshake(sender)
+	resp, err := core.RespondHandshake(recipient, hs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = core.FinishHandshake(hs.Challenge, resp); err != nil {
+		t.Fatal(err)
+	}
+	key := core.NewHandshakeResult(resp).PeerKeyAgreement
+	if len(key) != 32 {
+		t.Fatalf("PeerKeyAgreement = %x", key)
+	}
+
+	intent, _ := core.CreateIntent(sender, nil, []string{"ocr"}, `{"url":"s3://secret"}`)
+	if err = core.EncryptIntentPayload(sender, intent, recipient.DID.String(), key); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := intent.Encode()
+	got, err := core.DecodeIntentMessage(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Payload != "" || got.EncryptedPayload == nil {
+		t.Fatalf("payload not sealed: %+v", got)
+	}
+	if !core.VerifyIntentSignature(got, sender.PublicKey()) {
+		t.Error("signature over the sealed payload does not verify")
+	}
+
+	copyOf := func(m *core.IntentMessage) *core.IntentMessage {
+		cp := *m
+		sealed := *m.EncryptedPayload
+		cp.EncryptedPayload = &sealed
+		return &cp
+	}
+	if err = core.DecryptIntentPayload(other, copyOf(got)); !errors.Is(err, core.ErrNotRecipient) {
+		t.Errorf("other agent: got %v, want ErrNotRecipient", err)
+	}
+	tampered := copyOf(got)
+	tampered.ID = "replayed-under-another-id"
+	if err = core.DecryptIntentPayload(recipient, tampered); err == nil {
+		t.Error("ciphertext opened under a different intent ID")
+	}
+
+	if err = core.DecryptIntentPayload(recipient, got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Payload != `{"url":"s3://secret"}` || got.EncryptedPayload != nil {
+		t.Errorf("decrypted = %q, %+v", got.Payload, got.EncryptedPayload)
+	}
+}
+
+func TestHandshakeRejectsForeignKeyAgreement(t *testing.T) {
+	initiator, _ := core.NewAgent("init", nil)
+	responder, _ := core.NewAgent("resp", nil)
+	mallory, _ := core.NewAgent("mallory", nil)
+
+	hs, _ := core.StartHandshake(initiator)
+	hs.KeyAgreement = mallory.KeyAgreementKey()
+	if _, err := core.RespondHandshake(responder, hs); err == nil {
+		t.Error("handshake accepted a key agreement key not signed by the sender")
+	}
+	if _, err := core.PeerKeyAgreement(&core.HandshakeMessage{DID: initiator.DID.String()}); err != nil {
+		t.Errorf("no key offered: %v", err)
+	}
+}
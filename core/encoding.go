@@ -9,7 +9,10 @@ package core
 import (
 	"encoding/binary"
 	"fmt"
+	"maps"
 	"math"
+	"slices"
+	"strconv"
 
 	"google.golang.org/protobuf/encoding/protowire"
 )
@@ -70,18 +73,38 @@ func (e *enc) packedF32(field protowire.Number, fs []float32) {
 	if len(fs) == 0 {
 		return
 	}
-	packed := make([]byte, 0, len(fs)*4)
+	e.buf = protowire.AppendTag(e.buf, field, protowire.BytesType)
+	e.buf = protowire.AppendVarint(e.buf, uint64(len(fs)*4))
 	for _, f := range fs {
-		packed = binary.LittleEndian.AppendUint32(packed, math.Float32bits(f))
+		e.buf = binary.LittleEndian.AppendUint32(e.buf, math.Float32bits(f))
 	}
+}
+
+// nested encodes a length-delimited field whose value fn appends to the
+// buffer.  The value is written in place and shifted past its length
+// prefix afterwards, so no intermediate slice is allocated.
+func (e *enc) nested(field protowire.Number, fn func([]byte) ([]byte, error)) error {
 	e.buf = protowire.AppendTag(e.buf, field, protowire.BytesType)
-	e.buf = protowire.AppendBytes(e.buf, packed)
+	start := len(e.buf)
+	buf, err := fn(e.buf)
+	if err != nil {
+		return err
+	}
+	n := len(buf) - start
+	size := protowire.SizeVarint(uint64(n))
+	buf = append(buf, make([]byte, size)...)
+	copy(buf[start+size:], buf[start:start+n])
+	protowire.AppendVarint(buf[start:start], uint64(n))
+	e.buf = buf
+	return nil
 }
 
-// strMap encodes a map[string]string as proto3 map entries.
+// strMap encodes a map[string]string as proto3 map entries, in key order
+// so that signatures over the encoding are reproducible.
 // Each entry is a nested message: field 1 = key, field 2 = value.
 func (e *enc) strMap(field protowire.Number, m map[string]string) {
-	for k, v := range m {
+	for _, k := range slices.Sorted(maps.Keys(m)) {
+		v := m[k]
 		var entry []byte
 		entry = protowire.AppendTag(entry, 1, protowire.BytesType)
 		entry = protowire.AppendString(entry, k)
@@ -139,8 +162,11 @@ func decodeStrMapEntry(b []byte) (key, val string, err error) {
 // ------------------------------------------------------------------ IntentMessage
 
 // Encode serialises m into the Protobuf wire format.
-func (m *IntentMessage) Encode() ([]byte, error) {
-	e := &enc{}
+func (m *IntentMessage) Encode() ([]byte, error) { return m.AppendEncode(nil) }
+
+// AppendEncode appends the Protobuf encoding of m to b.
+func (m *IntentMessage) AppendEncode(b []byte) ([]byte, error) {
+	e := &enc{buf: b}
 	e.str(1, m.ID)
 	e.packedF32(2, m.IntentVector)
 	e.strs(3, m.Capabilities)
@@ -150,11 +176,24 @@ func (m *IntentMessage) Encode() ([]byte, error) {
 	e.f32(7, m.TrustScore)
 	e.strMap(8, m.Metadata)
 	e.bytes(9, m.Signature)
+	e.i64(10, m.ExpiresAt)
+	if m.EncryptedPayload != nil {
+		e.bytes(11, encodeEncryptedPayload(m.EncryptedPayload))
+	}
+	if m.Budget != nil {
+		e.bytes(12, encodeBudget(m.Budget))
+	}
+	e.i64(13, m.Deadline)
+	e.i64(14, m.MaxLatency)
 	return e.buf, nil
 }
 
 // DecodeIntentMessage deserialises an IntentMessage from wire bytes.
 func DecodeIntentMessage(data []byte) (*IntentMessage, error) {
+	return DefaultDecodeLimits.DecodeIntentMessage(data)
+}
+
+func decodeIntentMessage(data []byte, l DecodeLimits) (*IntentMessage, error) {
 	m := &IntentMessage{Metadata: make(map[string]string)}
 	for len(data) > 0 {
 		num, typ, n := protowire.ConsumeTag(data)
@@ -169,6 +208,9 @@ func DecodeIntentMessage(data []byte) (*IntentMessage, error) {
 			if n2 < 0 {
 				return nil, fmt.Errorf("intent: invalid id")
 			}
+			if err := l.str(".id", s); err != nil {
+				return nil, err
+			}
 			m.ID = s
 			data = data[n2:]
 		case 2:
@@ -176,6 +218,9 @@ func DecodeIntentMessage(data []byte) (*IntentMessage, error) {
 			if n2 < 0 {
 				return nil, fmt.Errorf("intent: invalid intent_vector")
 			}
+			if err := l.vector(".intent_vector", len(b)/4); err != nil {
+				return nil, err
+			}
 			m.IntentVector = decodePackedF32(b)
 			data = data[n2:]
 		case 3:
@@ -183,6 +228,12 @@ func DecodeIntentMessage(data []byte) (*IntentMessage, error) {
 			if n2 < 0 {
 				return nil, fmt.Errorf("intent: invalid capability")
 			}
+			if err := l.repeated(".capabilities", len(m.Capabilities)+1); err != nil {
+				return nil, err
+			}
+			if err := l.strAt(".capabilities", len(m.Capabilities), s); err != nil {
+				return nil, err
+			}
 			m.Capabilities = append(m.Capabilities, s)
 			data = data[n2:]
 		case 4:
@@ -190,6 +241,9 @@ func DecodeIntentMessage(data []byte) (*IntentMessage, error) {
 			if n2 < 0 {
 				return nil, fmt.Errorf("intent: invalid did")
 			}
+			if err := l.str(".did", s); err != nil {
+				return nil, err
+			}
 			m.DID = s
 			data = data[n2:]
 		case 5:
@@ -197,6 +251,9 @@ func DecodeIntentMessage(data []byte) (*IntentMessage, error) {
 			if n2 < 0 {
 				return nil, fmt.Errorf("intent: invalid payload")
 			}
+			if err := l.str(".payload", s); err != nil {
+				return nil, err
+			}
 			m.Payload = s
 			data = data[n2:]
 		case 6:
@@ -222,6 +279,9 @@ func DecodeIntentMessage(data []byte) (*IntentMessage, error) {
 			if err != nil {
 				return nil, err
 			}
+			if err := l.entry(".metadata", m.Metadata, k, v); err != nil {
+				return nil, err
+			}
 			m.Metadata[k] = v
 			data = data[n2:]
 		case 9:
@@ -231,6 +291,49 @@ func DecodeIntentMessage(data []byte) (*IntentMessage, error) {
 			}
 			m.Signature = append([]byte(nil), b...)
 			data = data[n2:]
+		case 10:
+			v, n2 := protowire.ConsumeVarint(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("intent: invalid expires_at")
+			}
+			m.ExpiresAt = int64(v)
+			data = data[n2:]
+		case 11:
+			b, n2 := protowire.ConsumeBytes(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("intent: invalid encrypted_payload")
+			}
+			ep, err := decodeEncryptedPayload(b, l)
+			if err != nil {
+				return nil, within(".encrypted_payload", err)
+			}
+			m.EncryptedPayload = ep
+			data = data[n2:]
+		case 12:
+			b, n2 := protowire.ConsumeBytes(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("intent: invalid budget")
+			}
+			budget, err := decodeBudget(b, l)
+			if err != nil {
+				return nil, within(".budget", err)
+			}
+			m.Budget = budget
+			data = data[n2:]
+		case 13:
+			v, n2 := protowire.ConsumeVarint(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("intent: invalid deadline")
+			}
+			m.Deadline = int64(v)
+			data = data[n2:]
+		case 14:
+			v, n2 := protowire.ConsumeVarint(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("intent: invalid max_latency")
+			}
+			m.MaxLatency = int64(v)
+			data = data[n2:]
 		default:
 			n2 := protowire.ConsumeFieldValue(num, typ, data)
 			if n2 < 0 {
@@ -242,6 +345,15 @@ func DecodeIntentMessage(data []byte) (*IntentMessage, error) {
 	return m, nil
 }
 
+// DecodeIntentMessage decodes an IntentMessage within l.
+func (l DecodeLimits) DecodeIntentMessage(data []byte) (*IntentMessage, error) {
+	m, err := decodeIntentMessage(data, l)
+	if err != nil {
+		return nil, within("IntentMessage", err)
+	}
+	return m, nil
+}
+
 // ------------------------------------------------------------------ HandshakeMessage
 
 // Encode serialises m into the Protobuf wire format.
@@ -255,11 +367,33 @@ func (m *HandshakeMessage) Encode() ([]byte, error) {
 	e.bytes(6, m.PublicKey)
 	e.bytes(7, m.Challenge)
 	e.bytes(8, m.ChallengeResponse)
+	for _, d := range m.Manifest {
+		e.bytes(9, encodeCapabilityDescriptor(d))
+	}
+	e.i64(10, m.EchoTimestamp)
+	e.i64(11, m.ReceivedAt)
+	e.strs(12, m.Codecs)
+	e.strs(13, m.Versions)
+	e.str(14, m.Mesh)
+	for _, sp := range m.Specs {
+		e.bytes(15, encodeCapabilitySpec(sp))
+	}
+	e.bytes(16, m.KeyAgreement)
+	e.bytes(17, m.KeyAgreementSig)
+	e.strs(18, m.Features)
+	e.i64(19, m.MaxResultSize)
+	for _, c := range m.Credentials {
+		e.bytes(20, encodeCredential(c))
+	}
 	return e.buf, nil
 }
 
 // DecodeHandshakeMessage deserialises a HandshakeMessage from wire bytes.
 func DecodeHandshakeMessage(data []byte) (*HandshakeMessage, error) {
+	return DefaultDecodeLimits.DecodeHandshakeMessage(data)
+}
+
+func decodeHandshakeMessage(data []byte, l DecodeLimits) (*HandshakeMessage, error) {
 	m := &HandshakeMessage{}
 	for len(data) > 0 {
 		num, typ, n := protowire.ConsumeTag(data)
@@ -274,6 +408,9 @@ func DecodeHandshakeMessage(data []byte) (*HandshakeMessage, error) {
 			if n2 < 0 {
 				return nil, fmt.Errorf("handshake: invalid agent_id")
 			}
+			if err := l.str(".agent_id", s); err != nil {
+				return nil, err
+			}
 			m.AgentID = s
 			data = data[n2:]
 		case 2:
@@ -281,6 +418,9 @@ func DecodeHandshakeMessage(data []byte) (*HandshakeMessage, error) {
 			if n2 < 0 {
 				return nil, fmt.Errorf("handshake: invalid did")
 			}
+			if err := l.str(".did", s); err != nil {
+				return nil, err
+			}
 			m.DID = s
 			data = data[n2:]
 		case 3:
@@ -288,6 +428,12 @@ func DecodeHandshakeMessage(data []byte) (*HandshakeMessage, error) {
 			if n2 < 0 {
 				return nil, fmt.Errorf("handshake: invalid capability")
 			}
+			if err := l.repeated(".capabilities", len(m.Capabilities)+1); err != nil {
+				return nil, err
+			}
+			if err := l.strAt(".capabilities", len(m.Capabilities), s); err != nil {
+				return nil, err
+			}
 			m.Capabilities = append(m.Capabilities, s)
 			data = data[n2:]
 		case 4:
@@ -295,6 +441,9 @@ func DecodeHandshakeMessage(data []byte) (*HandshakeMessage, error) {
 			if n2 < 0 {
 				return nil, fmt.Errorf("handshake: invalid version")
 			}
+			if err := l.str(".version", s); err != nil {
+				return nil, err
+			}
 			m.Version = s
 			data = data[n2:]
 		case 5:
@@ -325,120 +474,136 @@ func DecodeHandshakeMessage(data []byte) (*HandshakeMessage, error) {
 			}
 			m.ChallengeResponse = append([]byte(nil), b...)
 			data = data[n2:]
-		default:
-			n2 := protowire.ConsumeFieldValue(num, typ, data)
+		case 9:
+			b, n2 := protowire.ConsumeBytes(data)
 			if n2 < 0 {
-				return nil, fmt.Errorf("handshake: unknown field %d", num)
+				return nil, fmt.Errorf("handshake: invalid manifest entry")
+			}
+			if err := l.repeated(".manifest", len(m.Manifest)+1); err != nil {
+				return nil, err
+			}
+			d, err := decodeCapabilityDescriptor(b, l)
+			if err != nil {
+				return nil, within(".manifest["+strconv.Itoa(len(m.Manifest))+"]", err)
 			}
+			m.Manifest = append(m.Manifest, d)
 			data = data[n2:]
-		}
-	}
-	return m, nil
-}
-
-// ------------------------------------------------------------------ NegotiationResponse
-
-// Encode serialises m into the Protobuf wire format.
-func (m *NegotiationResponse) Encode() ([]byte, error) {
-	e := &enc{}
-	e.str(1, m.RequestID)
-	e.str(2, m.AgentID)
-	e.boolean(3, m.Accepted)
-	e.strs(4, m.WorkflowSteps)
-	e.str(5, m.DID)
-	e.packedF32(6, m.ResponseVector)
-	e.i64(7, m.Timestamp)
-	e.str(8, m.Reason)
-	e.f32(9, m.TrustDelta)
-	e.bytes(10, m.Signature)
-	return e.buf, nil
-}
-
-// DecodeNegotiationResponse deserialises a NegotiationResponse from wire bytes.
-func DecodeNegotiationResponse(data []byte) (*NegotiationResponse, error) {
-	m := &NegotiationResponse{}
-	for len(data) > 0 {
-		num, typ, n := protowire.ConsumeTag(data)
-		if n < 0 {
-			return nil, fmt.Errorf("negoresp: invalid tag")
-		}
-		data = data[n:]
-
-		switch num {
-		case 1:
-			s, n2 := protowire.ConsumeString(data)
+		case 10:
+			v, n2 := protowire.ConsumeVarint(data)
 			if n2 < 0 {
-				return nil, fmt.Errorf("negoresp: invalid request_id")
+				return nil, fmt.Errorf("handshake: invalid echo_timestamp")
 			}
-			m.RequestID = s
+			m.EchoTimestamp = int64(v)
 			data = data[n2:]
-		case 2:
-			s, n2 := protowire.ConsumeString(data)
+		case 11:
+			v, n2 := protowire.ConsumeVarint(data)
 			if n2 < 0 {
-				return nil, fmt.Errorf("negoresp: invalid agent_id")
+				return nil, fmt.Errorf("handshake: invalid received_at")
 			}
-			m.AgentID = s
+			m.ReceivedAt = int64(v)
 			data = data[n2:]
-		case 3:
-			v, n2 := protowire.ConsumeVarint(data)
+		case 12:
+			s, n2 := protowire.ConsumeString(data)
 			if n2 < 0 {
-				return nil, fmt.Errorf("negoresp: invalid accepted")
+				return nil, fmt.Errorf("handshake: invalid codec")
 			}
-			m.Accepted = v != 0
+			if err := l.repeated(".codecs", len(m.Codecs)+1); err != nil {
+				return nil, err
+			}
+			if err := l.strAt(".codecs", len(m.Codecs), s); err != nil {
+				return nil, err
+			}
+			m.Codecs = append(m.Codecs, s)
 			data = data[n2:]
-		case 4:
+		case 13:
 			s, n2 := protowire.ConsumeString(data)
 			if n2 < 0 {
-				return nil, fmt.Errorf("negoresp: invalid workflow_step")
+				return nil, fmt.Errorf("handshake: invalid version")
 			}
-			m.WorkflowSteps = append(m.WorkflowSteps, s)
+			if err := l.repeated(".versions", len(m.Versions)+1); err != nil {
+				return nil, err
+			}
+			if err := l.strAt(".versions", len(m.Versions), s); err != nil {
+				return nil, err
+			}
+			m.Versions = append(m.Versions, s)
 			data = data[n2:]
-		case 5:
+		case 14:
 			s, n2 := protowire.ConsumeString(data)
 			if n2 < 0 {
-				return nil, fmt.Errorf("negoresp: invalid did")
+				return nil, fmt.Errorf("handshake: invalid mesh")
 			}
-			m.DID = s
+			if err := l.str(".mesh", s); err != nil {
+				return nil, err
+			}
+			m.Mesh = s
 			data = data[n2:]
-		case 6:
+		case 15:
 			b, n2 := protowire.ConsumeBytes(data)
 			if n2 < 0 {
-				return nil, fmt.Errorf("negoresp: invalid response_vector")
+				return nil, fmt.Errorf("handshake: invalid spec")
 			}
-			m.ResponseVector = decodePackedF32(b)
+			if err := l.repeated(".specs", len(m.Specs)+1); err != nil {
+				return nil, err
+			}
+			sp, err := decodeCapabilitySpec(b, l)
+			if err != nil {
+				return nil, within(".specs["+strconv.Itoa(len(m.Specs))+"]", err)
+			}
+			m.Specs = append(m.Specs, sp)
 			data = data[n2:]
-		case 7:
-			v, n2 := protowire.ConsumeVarint(data)
+		case 16:
+			b, n2 := protowire.ConsumeBytes(data)
 			if n2 < 0 {
-				return nil, fmt.Errorf("negoresp: invalid timestamp")
+				return nil, fmt.Errorf("handshake: invalid key_agreement")
 			}
-			m.Timestamp = int64(v)
+			m.KeyAgreement = append([]byte(nil), b...)
 			data = data[n2:]
-		case 8:
+		case 17:
+			b, n2 := protowire.ConsumeBytes(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("handshake: invalid key_agreement_sig")
+			}
+			m.KeyAgreementSig = append([]byte(nil), b...)
+			data = data[n2:]
+		case 18:
 			s, n2 := protowire.ConsumeString(data)
 			if n2 < 0 {
-				return nil, fmt.Errorf("negoresp: invalid reason")
+				return nil, fmt.Errorf("handshake: invalid features")
 			}
-			m.Reason = s
+			if err := l.repeated(".features", len(m.Features)+1); err != nil {
+				return nil, err
+			}
+			if err := l.strAt(".features", len(m.Features), s); err != nil {
+				return nil, err
+			}
+			m.Features = append(m.Features, s)
 			data = data[n2:]
-		case 9:
-			v, n2 := protowire.ConsumeFixed32(data)
+		case 19:
+			v, n2 := protowire.ConsumeVarint(data)
 			if n2 < 0 {
-				return nil, fmt.Errorf("negoresp: invalid trust_delta")
+				return nil, fmt.Errorf("handshake: invalid max_result_size")
 			}
-			m.TrustDelta = math.Float32frombits(v)
+			m.MaxResultSize = int64(v)
 			data = data[n2:]
-		case 10:
+		case 20:
 			b, n2 := protowire.ConsumeBytes(data)
 			if n2 < 0 {
-				return nil, fmt.Errorf("negoresp: invalid signature")
+				return nil, fmt.Errorf("handshake: invalid credential")
 			}
-			m.Signature = append([]byte(nil), b...)
+			if err := l.repeated(".credentials", len(m.Credentials)+1); err != nil {
+				return nil, err
+			}
+			c, err := decodeCredential(b, l)
+			if err != nil {
+				return nil, within(".credentials["+strconv.Itoa(len(m.Credentials))+"]", err)
+			}
+			m.Credentials = append(m.Credentials, c)
 			data = data[n2:]
 		default:
 			n2 := protowire.ConsumeFieldValue(num, typ, data)
 			if n2 < 0 {
-				return nil, fmt.Errorf("negoresp: unknown field %d", num)
+				return nil, fmt.Errorf("handshake: unknown field %d", num)
 			}
 			data = data[n2:]
 		}
@@ -446,43 +611,32 @@ func DecodeNegotiationResponse(data []byte) (*NegotiationResponse, error) {
 	return m, nil
 }
 
-// ------------------------------------------------------------------ WorkflowMessage
-
-// Encode serialises m into the Protobuf wire format.
-func (m *WorkflowMessage) Encode() ([]byte, error) {
-	e := &enc{}
-	e.str(1, m.WorkflowID)
-	e.str(2, m.StepID)
-	e.str(3, m.NextStepID)
-	e.str(4, m.AgentID)
-	e.str(5, m.DID)
-	e.str(6, m.Action)
-	e.strMap(7, m.Params)
-	e.str(8, m.ResultChan)
-	e.i64(9, m.Timestamp)
-	return e.buf, nil
+// DecodeHandshakeMessage decodes a HandshakeMessage within l.
+func (l DecodeLimits) DecodeHandshakeMessage(data []byte) (*HandshakeMessage, error) {
+	m, err := decodeHandshakeMessage(data, l)
+	if err != nil {
+		return nil, within("HandshakeMessage", err)
+	}
+	return m, nil
 }
 
-// ------------------------------------------------------------------ CapabilityAnnouncement
+// ------------------------------------------------------------------ CapabilitySpec (nested)
 
-// Encode serialises m into the Protobuf wire format.
-func (m *CapabilityAnnouncement) Encode() ([]byte, error) {
+func encodeCapabilitySpec(sp CapabilitySpec) []byte {
 	e := &enc{}
-	e.str(1, m.AgentID)
-	e.str(2, m.DID)
-	e.strs(3, m.Capabilities)
-	e.i64(4, m.Timestamp)
-	e.i64(5, m.TTL)
-	return e.buf, nil
+	e.str(1, sp.Name)
+	e.str(2, sp.Version)
+	e.bytes(3, sp.ParamsSchema)
+	e.bytes(4, sp.ResultSchema)
+	return e.buf
 }
 
-// DecodeCapabilityAnnouncement deserialises a CapabilityAnnouncement from wire bytes.
-func DecodeCapabilityAnnouncement(data []byte) (*CapabilityAnnouncement, error) {
-	m := &CapabilityAnnouncement{}
+func decodeCapabilitySpec(data []byte, l DecodeLimits) (CapabilitySpec, error) {
+	var sp CapabilitySpec
 	for len(data) > 0 {
 		num, typ, n := protowire.ConsumeTag(data)
 		if n < 0 {
-			return nil, fmt.Errorf("capability: invalid tag")
+			return sp, fmt.Errorf("spec: invalid tag")
 		}
 		data = data[n:]
 
@@ -490,73 +644,2642 @@ func DecodeCapabilityAnnouncement(data []byte) (*CapabilityAnnouncement, error)
 		case 1:
 			s, n2 := protowire.ConsumeString(data)
 			if n2 < 0 {
-				return nil, fmt.Errorf("capability: invalid agent_id")
+				return sp, fmt.Errorf("spec: invalid name")
 			}
-			m.AgentID = s
+			if err := l.str(".name", s); err != nil {
+				return sp, err
+			}
+			sp.Name = s
 			data = data[n2:]
 		case 2:
 			s, n2 := protowire.ConsumeString(data)
 			if n2 < 0 {
-				return nil, fmt.Errorf("capability: invalid did")
+				return sp, fmt.Errorf("spec: invalid version")
 			}
-			m.DID = s
+			if err := l.str(".version", s); err != nil {
+				return sp, err
+			}
+			sp.Version = s
 			data = data[n2:]
 		case 3:
-			s, n2 := protowire.ConsumeString(data)
+			b, n2 := protowire.ConsumeBytes(data)
 			if n2 < 0 {
-				return nil, fmt.Errorf("capability: invalid capability")
+				return sp, fmt.Errorf("spec: invalid params_schema")
 			}
-			m.Capabilities = append(m.Capabilities, s)
+			sp.ParamsSchema = append([]byte(nil), b...)
 			data = data[n2:]
 		case 4:
-			v, n2 := protowire.ConsumeVarint(data)
-			if n2 < 0 {
-				return nil, fmt.Errorf("capability: invalid timestamp")
-			}
-			m.Timestamp = int64(v)
-			data = data[n2:]
-		case 5:
-			v, n2 := protowire.ConsumeVarint(data)
+			b, n2 := protowire.ConsumeBytes(data)
 			if n2 < 0 {
-				return nil, fmt.Errorf("capability: invalid ttl")
+				return sp, fmt.Errorf("spec: invalid result_schema")
 			}
-			m.TTL = int64(v)
+			sp.ResultSchema = append([]byte(nil), b...)
 			data = data[n2:]
 		default:
 			n2 := protowire.ConsumeFieldValue(num, typ, data)
 			if n2 < 0 {
-				return nil, fmt.Errorf("capability: unknown field %d", num)
+				return sp, fmt.Errorf("spec: unknown field %d", num)
 			}
 			data = data[n2:]
 		}
 	}
-	return m, nil
+	return sp, nil
 }
 
-// ------------------------------------------------------------------ framing
+// ------------------------------------------------------------------ ProvenanceTag (nested)
 
-// Frame wraps encoded message bytes with a 4-byte big-endian length prefix
-// and a 1-byte message type, ready to be sent over a stream.
-//
-// Layout: [4 bytes: uint32 frame length] [1 byte: MessageType] [N bytes: payload]
-func Frame(msgType MessageType, payload []byte) []byte {
-	total := 1 + len(payload)
-	frame := make([]byte, 4+total)
-	binary.BigEndian.PutUint32(frame[:4], uint32(total))
-	frame[4] = byte(msgType)
-	copy(frame[5:], payload)
-	return frame
+func encodeProvenanceTag(tag ProvenanceTag) []byte {
+	e := &enc{}
+	e.str(1, tag.Mesh)
+	e.str(2, tag.Gateway)
+	e.strs(3, tag.Capabilities)
+	return e.buf
 }
 
-// Unframe reads one framed message, returning the type and raw payload.
-// The caller must supply at least 5 bytes (4-byte header + type byte).
-func Unframe(frame []byte) (MessageType, []byte, error) {
-	if len(frame) < 5 {
-		return 0, nil, fmt.Errorf("frame too short (%d bytes)", len(frame))
-	}
-	total := int(binary.BigEndian.Uint32(frame[:4]))
-	if len(frame) < 4+total {
-		return 0, nil, fmt.Errorf("frame incomplete: need %d bytes, have %d", 4+total, len(frame))
+func decodeProvenanceTag(data []byte, l DecodeLimits) (ProvenanceTag, error) {
+	var tag ProvenanceTag
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return tag, fmt.Errorf("provenance: invalid tag")
+		}
+		data = data[n:]
+
+		switch num {
+		case 1:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return tag, fmt.Errorf("provenance: invalid mesh")
+			}
+			if err := l.str(".mesh", s); err != nil {
+				return tag, err
+			}
+			tag.Mesh = s
+			data = data[n2:]
+		case 2:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return tag, fmt.Errorf("provenance: invalid gateway")
+			}
+			if err := l.str(".gateway", s); err != nil {
+				return tag, err
+			}
+			tag.Gateway = s
+			data = data[n2:]
+		case 3:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return tag, fmt.Errorf("provenance: invalid capability")
+			}
+			if err := l.repeated(".capabilities", len(tag.Capabilities)+1); err != nil {
+				return tag, err
+			}
+			if err := l.strAt(".capabilities", len(tag.Capabilities), s); err != nil {
+				return tag, err
+			}
+			tag.Capabilities = append(tag.Capabilities, s)
+			data = data[n2:]
+		default:
+			n2 := protowire.ConsumeFieldValue(num, typ, data)
+			if n2 < 0 {
+				return tag, fmt.Errorf("provenance: unknown field %d", num)
+			}
+			data = data[n2:]
+		}
+	}
+	return tag, nil
+}
+
+// ------------------------------------------------------------------ VerifiableCredential (nested)
+
+func encodeCredential(c VerifiableCredential) []byte {
+	e := &enc{}
+	e.str(1, c.ID)
+	e.strs(2, c.Types)
+	e.str(3, c.IssuerDID)
+	e.bytes(4, c.IssuerKey)
+	e.str(5, c.SubjectDID)
+	// Claims go in key order, so the encoding the issuer signs is stable.
+	for _, k := range slices.Sorted(maps.Keys(c.Claims)) {
+		e.strMap(6, map[string]string{k: c.Claims[k]})
+	}
+	e.i64(7, c.IssuedAt)
+	e.i64(8, c.ExpiresAt)
+	e.bytes(9, c.Signature)
+	return e.buf
+}
+
+func decodeCredential(data []byte, l DecodeLimits) (VerifiableCredential, error) {
+	var c VerifiableCredential
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return c, fmt.Errorf("credential: invalid tag")
+		}
+		data = data[n:]
+
+		switch num {
+		case 1:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return c, fmt.Errorf("credential: invalid id")
+			}
+			if err := l.str(".id", s); err != nil {
+				return c, err
+			}
+			c.ID = s
+			data = data[n2:]
+		case 2:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return c, fmt.Errorf("credential: invalid type")
+			}
+			if err := l.repeated(".types", len(c.Types)+1); err != nil {
+				return c, err
+			}
+			if err := l.strAt(".types", len(c.Types), s); err != nil {
+				return c, err
+			}
+			c.Types = append(c.Types, s)
+			data = data[n2:]
+		case 3:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return c, fmt.Errorf("credential: invalid issuer_did")
+			}
+			if err := l.str(".issuer_did", s); err != nil {
+				return c, err
+			}
+			c.IssuerDID = s
+			data = data[n2:]
+		case 4:
+			b, n2 := protowire.ConsumeBytes(data)
+			if n2 < 0 {
+				return c, fmt.Errorf("credential: invalid issuer_key")
+			}
+			c.IssuerKey = append([]byte(nil), b...)
+			data = data[n2:]
+		case 5:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return c, fmt.Errorf("credential: invalid subject_did")
+			}
+			if err := l.str(".subject_did", s); err != nil {
+				return c, err
+			}
+			c.SubjectDID = s
+			data = data[n2:]
+		case 6:
+			b, n2 := protowire.ConsumeBytes(data)
+			if n2 < 0 {
+				return c, fmt.Errorf("credential: invalid claim")
+			}
+			k, v, err := decodeStrMapEntry(b)
+			if err != nil {
+				return c, err
+			}
+			if c.Claims == nil {
+				c.Claims = make(map[string]string)
+			}
+			if err := l.entry(".claims", c.Claims, k, v); err != nil {
+				return c, err
+			}
+			c.Claims[k] = v
+			data = data[n2:]
+		case 7:
+			v, n2 := protowire.ConsumeVarint(data)
+			if n2 < 0 {
+				return c, fmt.Errorf("credential: invalid issued_at")
+			}
+			c.IssuedAt = int64(v)
+			data = data[n2:]
+		case 8:
+			v, n2 := protowire.ConsumeVarint(data)
+			if n2 < 0 {
+				return c, fmt.Errorf("credential: invalid expires_at")
+			}
+			c.ExpiresAt = int64(v)
+			data = data[n2:]
+		case 9:
+			b, n2 := protowire.ConsumeBytes(data)
+			if n2 < 0 {
+				return c, fmt.Errorf("credential: invalid signature")
+			}
+			c.Signature = append([]byte(nil), b...)
+			data = data[n2:]
+		default:
+			n2 := protowire.ConsumeFieldValue(num, typ, data)
+			if n2 < 0 {
+				return c, fmt.Errorf("credential: unknown field %d", num)
+			}
+			data = data[n2:]
+		}
+	}
+	return c, nil
+}
+
+// ------------------------------------------------------------------ EncryptedPayload (nested)
+
+func encodeEncryptedPayload(p *EncryptedPayload) []byte {
+	e := &enc{}
+	e.str(1, p.RecipientDID)
+	e.bytes(2, p.EphemeralKey)
+	e.bytes(3, p.Nonce)
+	e.bytes(4, p.Ciphertext)
+	return e.buf
+}
+
+func decodeEncryptedPayload(data []byte, l DecodeLimits) (*EncryptedPayload, error) {
+	p := &EncryptedPayload{}
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, fmt.Errorf("sealed: invalid tag")
+		}
+		data = data[n:]
+
+		switch num {
+		case 1:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("sealed: invalid recipient_did")
+			}
+			if err := l.str(".recipient_did", s); err != nil {
+				return nil, err
+			}
+			p.RecipientDID = s
+			data = data[n2:]
+		case 2:
+			b, n2 := protowire.ConsumeBytes(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("sealed: invalid ephemeral_key")
+			}
+			p.EphemeralKey = append([]byte(nil), b...)
+			data = data[n2:]
+		case 3:
+			b, n2 := protowire.ConsumeBytes(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("sealed: invalid nonce")
+			}
+			p.Nonce = append([]byte(nil), b...)
+			data = data[n2:]
+		case 4:
+			b, n2 := protowire.ConsumeBytes(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("sealed: invalid ciphertext")
+			}
+			p.Ciphertext = append([]byte(nil), b...)
+			data = data[n2:]
+		default:
+			n2 := protowire.ConsumeFieldValue(num, typ, data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("sealed: unknown field %d", num)
+			}
+			data = data[n2:]
+		}
+	}
+	return p, nil
+}
+
+// ------------------------------------------------------------------ ResultPayload (nested)
+
+func encodeResultPayload(r *ResultPayload) []byte {
+	e := &enc{}
+	e.str(1, r.ContentType)
+	e.bytes(2, r.Data)
+	return e.buf
+}
+
+func decodeResultPayload(data []byte, l DecodeLimits) (*ResultPayload, error) {
+	r := &ResultPayload{}
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, fmt.Errorf("result: invalid tag")
+		}
+		data = data[n:]
+
+		switch num {
+		case 1:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("result: invalid content_type")
+			}
+			if err := l.str(".content_type", s); err != nil {
+				return nil, err
+			}
+			r.ContentType = s
+			data = data[n2:]
+		case 2:
+			b, n2 := protowire.ConsumeBytes(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("result: invalid data")
+			}
+			r.Data = append([]byte(nil), b...)
+			data = data[n2:]
+		default:
+			n2 := protowire.ConsumeFieldValue(num, typ, data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("result: unknown field %d", num)
+			}
+			data = data[n2:]
+		}
+	}
+	return r, nil
+}
+
+func encodeBid(b *Bid) []byte {
+	e := &enc{}
+	e.f32(1, b.Cost)
+	e.i64(2, b.ETA)
+	e.f32(3, b.Confidence)
+	return e.buf
+}
+
+func decodeBid(data []byte, l DecodeLimits) (*Bid, error) {
+	b := &Bid{}
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, fmt.Errorf("bid: invalid tag")
+		}
+		data = data[n:]
+
+		switch num {
+		case 1:
+			v, n2 := protowire.ConsumeFixed32(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("bid: invalid cost")
+			}
+			b.Cost = math.Float32frombits(v)
+			data = data[n2:]
+		case 2:
+			v, n2 := protowire.ConsumeVarint(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("bid: invalid eta")
+			}
+			b.ETA = int64(v)
+			data = data[n2:]
+		case 3:
+			v, n2 := protowire.ConsumeFixed32(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("bid: invalid confidence")
+			}
+			b.Confidence = math.Float32frombits(v)
+			data = data[n2:]
+		default:
+			n2 := protowire.ConsumeFieldValue(num, typ, data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("bid: unknown field %d", num)
+			}
+			data = data[n2:]
+		}
+	}
+	return b, nil
+}
+
+func encodeBudget(b *Budget) []byte {
+	e := &enc{}
+	e.i64(1, b.MaxDuration)
+	e.i64(2, b.MaxUnits)
+	e.i64(3, b.MaxAmount)
+	e.str(4, b.Currency)
+	return e.buf
+}
+
+func decodeBudget(data []byte, l DecodeLimits) (*Budget, error) {
+	b := &Budget{}
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, fmt.Errorf("budget: invalid tag")
+		}
+		data = data[n:]
+
+		switch num {
+		case 1:
+			v, n2 := protowire.ConsumeVarint(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("budget: invalid max_duration")
+			}
+			b.MaxDuration = int64(v)
+			data = data[n2:]
+		case 2:
+			v, n2 := protowire.ConsumeVarint(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("budget: invalid max_units")
+			}
+			b.MaxUnits = int64(v)
+			data = data[n2:]
+		case 3:
+			v, n2 := protowire.ConsumeVarint(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("budget: invalid max_amount")
+			}
+			b.MaxAmount = int64(v)
+			data = data[n2:]
+		case 4:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("budget: invalid currency")
+			}
+			if err := l.str(".currency", s); err != nil {
+				return nil, err
+			}
+			b.Currency = s
+			data = data[n2:]
+		default:
+			n2 := protowire.ConsumeFieldValue(num, typ, data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("budget: unknown field %d", num)
+			}
+			data = data[n2:]
+		}
+	}
+	return b, nil
+}
+
+func encodeCostEstimate(c *CostEstimate) []byte {
+	e := &enc{}
+	e.i64(1, c.Duration)
+	e.i64(2, c.Units)
+	e.i64(3, c.Amount)
+	e.str(4, c.Currency)
+	return e.buf
+}
+
+func decodeCostEstimate(data []byte, l DecodeLimits) (*CostEstimate, error) {
+	c := &CostEstimate{}
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, fmt.Errorf("cost: invalid tag")
+		}
+		data = data[n:]
+
+		switch num {
+		case 1:
+			v, n2 := protowire.ConsumeVarint(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("cost: invalid duration")
+			}
+			c.Duration = int64(v)
+			data = data[n2:]
+		case 2:
+			v, n2 := protowire.ConsumeVarint(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("cost: invalid units")
+			}
+			c.Units = int64(v)
+			data = data[n2:]
+		case 3:
+			v, n2 := protowire.ConsumeVarint(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("cost: invalid amount")
+			}
+			c.Amount = int64(v)
+			data = data[n2:]
+		case 4:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("cost: invalid currency")
+			}
+			if err := l.str(".currency", s); err != nil {
+				return nil, err
+			}
+			c.Currency = s
+			data = data[n2:]
+		default:
+			n2 := protowire.ConsumeFieldValue(num, typ, data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("cost: unknown field %d", num)
+			}
+			data = data[n2:]
+		}
+	}
+	return c, nil
+}
+
+// ------------------------------------------------------------------ CapabilityDescriptor (nested)
+
+func encodeCapabilityDescriptor(d CapabilityDescriptor) []byte {
+	e := &enc{}
+	e.str(1, d.Name)
+	e.str(2, d.Description)
+	for _, ex := range d.Examples {
+		x := &enc{}
+		x.str(1, ex.Name)
+		x.packedF32(2, ex.IntentVector)
+		x.str(3, ex.Payload)
+		x.boolean(4, ex.Expect.Accepted)
+		x.i64(5, int64(ex.Expect.MinWorkflowSteps))
+		x.strs(6, ex.Expect.StepsContain)
+		x.str(7, ex.Expect.ReasonContains)
+		// Always emit the example, even if every field is zero.
+		e.buf = protowire.AppendTag(e.buf, 3, protowire.BytesType)
+		e.buf = protowire.AppendBytes(e.buf, x.buf)
+	}
+	return e.buf
+}
+
+func decodeCapabilityDescriptor(data []byte, l DecodeLimits) (CapabilityDescriptor, error) {
+	var d CapabilityDescriptor
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return d, fmt.Errorf("descriptor: invalid tag")
+		}
+		data = data[n:]
+
+		switch num {
+		case 1:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return d, fmt.Errorf("descriptor: invalid name")
+			}
+			if err := l.str(".name", s); err != nil {
+				return d, err
+			}
+			d.Name = s
+			data = data[n2:]
+		case 2:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return d, fmt.Errorf("descriptor: invalid description")
+			}
+			if err := l.str(".description", s); err != nil {
+				return d, err
+			}
+			d.Description = s
+			data = data[n2:]
+		case 3:
+			b, n2 := protowire.ConsumeBytes(data)
+			if n2 < 0 {
+				return d, fmt.Errorf("descriptor: invalid example")
+			}
+			if err := l.repeated(".examples", len(d.Examples)+1); err != nil {
+				return d, err
+			}
+			ex, err := decodeCapabilityExample(b, l)
+			if err != nil {
+				return d, within(".examples["+strconv.Itoa(len(d.Examples))+"]", err)
+			}
+			d.Examples = append(d.Examples, ex)
+			data = data[n2:]
+		default:
+			n2 := protowire.ConsumeFieldValue(num, typ, data)
+			if n2 < 0 {
+				return d, fmt.Errorf("descriptor: unknown field %d", num)
+			}
+			data = data[n2:]
+		}
+	}
+	return d, nil
+}
+
+func decodeCapabilityExample(data []byte, l DecodeLimits) (CapabilityExample, error) {
+	var ex CapabilityExample
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return ex, fmt.Errorf("example: invalid tag")
+		}
+		data = data[n:]
+
+		switch num {
+		case 1:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return ex, fmt.Errorf("example: invalid name")
+			}
+			if err := l.str(".name", s); err != nil {
+				return ex, err
+			}
+			ex.Name = s
+			data = data[n2:]
+		case 2:
+			b, n2 := protowire.ConsumeBytes(data)
+			if n2 < 0 {
+				return ex, fmt.Errorf("example: invalid intent_vector")
+			}
+			if err := l.vector(".intent_vector", len(b)/4); err != nil {
+				return ex, err
+			}
+			ex.IntentVector = decodePackedF32(b)
+			data = data[n2:]
+		case 3:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return ex, fmt.Errorf("example: invalid payload")
+			}
+			if err := l.str(".payload", s); err != nil {
+				return ex, err
+			}
+			ex.Payload = s
+			data = data[n2:]
+		case 4:
+			v, n2 := protowire.ConsumeVarint(data)
+			if n2 < 0 {
+				return ex, fmt.Errorf("example: invalid expect_accepted")
+			}
+			ex.Expect.Accepted = v != 0
+			data = data[n2:]
+		case 5:
+			v, n2 := protowire.ConsumeVarint(data)
+			if n2 < 0 {
+				return ex, fmt.Errorf("example: invalid expect_min_steps")
+			}
+			ex.Expect.MinWorkflowSteps = int(v)
+			data = data[n2:]
+		case 6:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return ex, fmt.Errorf("example: invalid expect_steps_contain")
+			}
+			if err := l.repeated(".expect.steps_contain", len(ex.Expect.StepsContain)+1); err != nil {
+				return ex, err
+			}
+			if err := l.strAt(".expect.steps_contain", len(ex.Expect.StepsContain), s); err != nil {
+				return ex, err
+			}
+			ex.Expect.StepsContain = append(ex.Expect.StepsContain, s)
+			data = data[n2:]
+		case 7:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return ex, fmt.Errorf("example: invalid expect_reason_contains")
+			}
+			if err := l.str(".expect.reason_contains", s); err != nil {
+				return ex, err
+			}
+			ex.Expect.ReasonContains = s
+			data = data[n2:]
+		default:
+			n2 := protowire.ConsumeFieldValue(num, typ, data)
+			if n2 < 0 {
+				return ex, fmt.Errorf("example: unknown field %d", num)
+			}
+			data = data[n2:]
+		}
+	}
+	return ex, nil
+}
+
+// ------------------------------------------------------------------ NegotiationResponse
+
+// Encode serialises m into the Protobuf wire format.
+func (m *NegotiationResponse) Encode() ([]byte, error) {
+	e := &enc{}
+	e.str(1, m.RequestID)
+	e.str(2, m.AgentID)
+	e.boolean(3, m.Accepted)
+	e.strs(4, m.WorkflowSteps)
+	e.str(5, m.DID)
+	e.packedF32(6, m.ResponseVector)
+	e.i64(7, m.Timestamp)
+	e.str(8, m.Reason)
+	e.f32(9, m.TrustDelta)
+	e.bytes(10, m.Signature)
+	e.f32(11, m.SimilarityScore)
+	e.i64(12, m.RetryAfter)
+	e.strs(13, m.RelayPath)
+	e.bytes(14, m.ResponderKey)
+	if m.Result != nil {
+		e.bytes(15, encodeResultPayload(m.Result))
+	}
+	if m.Bid != nil {
+		e.bytes(16, encodeBid(m.Bid))
+	}
+	if m.Cost != nil {
+		e.bytes(17, encodeCostEstimate(m.Cost))
+	}
+	return e.buf, nil
+}
+
+// DecodeNegotiationResponse deserialises a NegotiationResponse from wire bytes.
+func DecodeNegotiationResponse(data []byte) (*NegotiationResponse, error) {
+	return DefaultDecodeLimits.DecodeNegotiationResponse(data)
+}
+
+func decodeNegotiationResponse(data []byte, l DecodeLimits) (*NegotiationResponse, error) {
+	m := &NegotiationResponse{}
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, fmt.Errorf("negoresp: invalid tag")
+		}
+		data = data[n:]
+
+		switch num {
+		case 1:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("negoresp: invalid request_id")
+			}
+			if err := l.str(".request_id", s); err != nil {
+				return nil, err
+			}
+			m.RequestID = s
+			data = data[n2:]
+		case 2:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("negoresp: invalid agent_id")
+			}
+			if err := l.str(".agent_id", s); err != nil {
+				return nil, err
+			}
+			m.AgentID = s
+			data = data[n2:]
+		case 3:
+			v, n2 := protowire.ConsumeVarint(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("negoresp: invalid accepted")
+			}
+			m.Accepted = v != 0
+			data = data[n2:]
+		case 4:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("negoresp: invalid workflow_step")
+			}
+			if err := l.repeated(".workflow_steps", len(m.WorkflowSteps)+1); err != nil {
+				return nil, err
+			}
+			if err := l.strAt(".workflow_steps", len(m.WorkflowSteps), s); err != nil {
+				return nil, err
+			}
+			m.WorkflowSteps = append(m.WorkflowSteps, s)
+			data = data[n2:]
+		case 5:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("negoresp: invalid did")
+			}
+			if err := l.str(".did", s); err != nil {
+				return nil, err
+			}
+			m.DID = s
+			data = data[n2:]
+		case 6:
+			b, n2 := protowire.ConsumeBytes(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("negoresp: invalid response_vector")
+			}
+			if err := l.vector(".response_vector", len(b)/4); err != nil {
+				return nil, err
+			}
+			m.ResponseVector = decodePackedF32(b)
+			data = data[n2:]
+		case 7:
+			v, n2 := protowire.ConsumeVarint(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("negoresp: invalid timestamp")
+			}
+			m.Timestamp = int64(v)
+			data = data[n2:]
+		case 8:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("negoresp: invalid reason")
+			}
+			if err := l.str(".reason", s); err != nil {
+				return nil, err
+			}
+			m.Reason = s
+			data = data[n2:]
+		case 9:
+			v, n2 := protowire.ConsumeFixed32(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("negoresp: invalid trust_delta")
+			}
+			m.TrustDelta = math.Float32frombits(v)
+			data = data[n2:]
+		case 10:
+			b, n2 := protowire.ConsumeBytes(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("negoresp: invalid signature")
+			}
+			m.Signature = append([]byte(nil), b...)
+			data = data[n2:]
+		case 11:
+			v, n2 := protowire.ConsumeFixed32(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("negoresp: invalid similarity_score")
+			}
+			m.SimilarityScore = math.Float32frombits(v)
+			data = data[n2:]
+		case 12:
+			v, n2 := protowire.ConsumeVarint(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("negoresp: invalid retry_after")
+			}
+			m.RetryAfter = int64(v)
+			data = data[n2:]
+		case 13:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("negoresp: invalid relay_path")
+			}
+			if err := l.repeated(".relay_path", len(m.RelayPath)+1); err != nil {
+				return nil, err
+			}
+			if err := l.strAt(".relay_path", len(m.RelayPath), s); err != nil {
+				return nil, err
+			}
+			m.RelayPath = append(m.RelayPath, s)
+			data = data[n2:]
+		case 14:
+			b, n2 := protowire.ConsumeBytes(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("negoresp: invalid responder_key")
+			}
+			m.ResponderKey = append([]byte(nil), b...)
+			data = data[n2:]
+		case 15:
+			b, n2 := protowire.ConsumeBytes(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("negoresp: invalid result")
+			}
+			r, err := decodeResultPayload(b, l)
+			if err != nil {
+				return nil, within(".result", err)
+			}
+			m.Result = r
+			data = data[n2:]
+		case 16:
+			b, n2 := protowire.ConsumeBytes(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("negoresp: invalid bid")
+			}
+			bid, err := decodeBid(b, l)
+			if err != nil {
+				return nil, within(".bid", err)
+			}
+			m.Bid = bid
+			data = data[n2:]
+		case 17:
+			b, n2 := protowire.ConsumeBytes(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("negoresp: invalid cost")
+			}
+			c, err := decodeCostEstimate(b, l)
+			if err != nil {
+				return nil, within(".cost", err)
+			}
+			m.Cost = c
+			data = data[n2:]
+		default:
+			n2 := protowire.ConsumeFieldValue(num, typ, data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("negoresp: unknown field %d", num)
+			}
+			data = data[n2:]
+		}
+	}
+	return m, nil
+}
+
+// DecodeNegotiationResponse decodes a NegotiationResponse within l.
+func (l DecodeLimits) DecodeNegotiationResponse(data []byte) (*NegotiationResponse, error) {
+	m, err := decodeNegotiationResponse(data, l)
+	if err != nil {
+		return nil, within("NegotiationResponse", err)
+	}
+	return m, nil
+}
+
+// ------------------------------------------------------------------ WorkflowMessage
+
+// Encode serialises m into the Protobuf wire format.
+func (m *WorkflowMessage) Encode() ([]byte, error) {
+	e := &enc{}
+	e.str(1, m.WorkflowID)
+	e.str(2, m.StepID)
+	e.str(3, m.NextStepID)
+	e.str(4, m.AgentID)
+	e.str(5, m.DID)
+	e.str(6, m.Action)
+	e.strMap(7, m.Params)
+	e.str(8, m.ResultChan)
+	e.i64(9, m.Timestamp)
+	return e.buf, nil
+}
+
+// DecodeWorkflowMessage deserialises a WorkflowMessage from wire bytes.
+func DecodeWorkflowMessage(data []byte) (*WorkflowMessage, error) {
+	return DefaultDecodeLimits.DecodeWorkflowMessage(data)
+}
+
+func decodeWorkflowMessage(data []byte, l DecodeLimits) (*WorkflowMessage, error) {
+	m := &WorkflowMessage{Params: make(map[string]string)}
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, fmt.Errorf("workflow: invalid tag")
+		}
+		data = data[n:]
+
+		switch num {
+		case 1:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("workflow: invalid workflow_id")
+			}
+			if err := l.str(".workflow_id", s); err != nil {
+				return nil, err
+			}
+			m.WorkflowID = s
+			data = data[n2:]
+		case 2:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("workflow: invalid step_id")
+			}
+			if err := l.str(".step_id", s); err != nil {
+				return nil, err
+			}
+			m.StepID = s
+			data = data[n2:]
+		case 3:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("workflow: invalid next_step_id")
+			}
+			if err := l.str(".next_step_id", s); err != nil {
+				return nil, err
+			}
+			m.NextStepID = s
+			data = data[n2:]
+		case 4:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("workflow: invalid agent_id")
+			}
+			if err := l.str(".agent_id", s); err != nil {
+				return nil, err
+			}
+			m.AgentID = s
+			data = data[n2:]
+		case 5:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("workflow: invalid did")
+			}
+			if err := l.str(".did", s); err != nil {
+				return nil, err
+			}
+			m.DID = s
+			data = data[n2:]
+		case 6:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("workflow: invalid action")
+			}
+			if err := l.str(".action", s); err != nil {
+				return nil, err
+			}
+			m.Action = s
+			data = data[n2:]
+		case 7:
+			b, n2 := protowire.ConsumeBytes(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("workflow: invalid params entry")
+			}
+			k, v, err := decodeStrMapEntry(b)
+			if err != nil {
+				return nil, err
+			}
+			if err := l.entry(".params", m.Params, k, v); err != nil {
+				return nil, err
+			}
+			m.Params[k] = v
+			data = data[n2:]
+		case 8:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("workflow: invalid result_chan")
+			}
+			if err := l.str(".result_chan", s); err != nil {
+				return nil, err
+			}
+			m.ResultChan = s
+			data = data[n2:]
+		case 9:
+			v, n2 := protowire.ConsumeVarint(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("workflow: invalid timestamp")
+			}
+			m.Timestamp = int64(v)
+			data = data[n2:]
+		default:
+			n2 := protowire.ConsumeFieldValue(num, typ, data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("workflow: unknown field %d", num)
+			}
+			data = data[n2:]
+		}
+	}
+	return m, nil
+}
+
+// DecodeWorkflowMessage decodes a WorkflowMessage within l.
+func (l DecodeLimits) DecodeWorkflowMessage(data []byte) (*WorkflowMessage, error) {
+	m, err := decodeWorkflowMessage(data, l)
+	if err != nil {
+		return nil, within("WorkflowMessage", err)
+	}
+	return m, nil
+}
+
+// ------------------------------------------------------------------ CapabilityAnnouncement
+
+// Encode serialises m into the Protobuf wire format.
+func (m *CapabilityAnnouncement) Encode() ([]byte, error) {
+	e := &enc{}
+	e.str(1, m.AgentID)
+	e.str(2, m.DID)
+	e.strs(3, m.Capabilities)
+	e.i64(4, m.Timestamp)
+	e.i64(5, m.TTL)
+	for _, sp := range m.Specs {
+		e.bytes(6, encodeCapabilitySpec(sp))
+	}
+	for _, tag := range m.Provenance {
+		e.bytes(7, encodeProvenanceTag(tag))
+	}
+	for _, c := range m.Credentials {
+		e.bytes(8, encodeCredential(c))
+	}
+	e.bytes(9, m.PublicKey)
+	e.bytes(10, m.Signature)
+	return e.buf, nil
+}
+
+// DecodeCapabilityAnnouncement deserialises a CapabilityAnnouncement from wire bytes.
+func DecodeCapabilityAnnouncement(data []byte) (*CapabilityAnnouncement, error) {
+	return DefaultDecodeLimits.DecodeCapabilityAnnouncement(data)
+}
+
+func decodeCapabilityAnnouncement(data []byte, l DecodeLimits) (*CapabilityAnnouncement, error) {
+	m := &CapabilityAnnouncement{}
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, fmt.Errorf("capability: invalid tag")
+		}
+		data = data[n:]
+
+		switch num {
+		case 1:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("capability: invalid agent_id")
+			}
+			if err := l.str(".agent_id", s); err != nil {
+				return nil, err
+			}
+			m.AgentID = s
+			data = data[n2:]
+		case 2:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("capability: invalid did")
+			}
+			if err := l.str(".did", s); err != nil {
+				return nil, err
+			}
+			m.DID = s
+			data = data[n2:]
+		case 3:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("capability: invalid capability")
+			}
+			if err := l.repeated(".capabilities", len(m.Capabilities)+1); err != nil {
+				return nil, err
+			}
+			if err := l.strAt(".capabilities", len(m.Capabilities), s); err != nil {
+				return nil, err
+			}
+			m.Capabilities = append(m.Capabilities, s)
+			data = data[n2:]
+		case 4:
+			v, n2 := protowire.ConsumeVarint(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("capability: invalid timestamp")
+			}
+			m.Timestamp = int64(v)
+			data = data[n2:]
+		case 5:
+			v, n2 := protowire.ConsumeVarint(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("capability: invalid ttl")
+			}
+			m.TTL = int64(v)
+			data = data[n2:]
+		case 6:
+			b, n2 := protowire.ConsumeBytes(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("capability: invalid spec")
+			}
+			if err := l.repeated(".specs", len(m.Specs)+1); err != nil {
+				return nil, err
+			}
+			sp, err := decodeCapabilitySpec(b, l)
+			if err != nil {
+				return nil, within(".specs["+strconv.Itoa(len(m.Specs))+"]", err)
+			}
+			m.Specs = append(m.Specs, sp)
+			data = data[n2:]
+		case 7:
+			b, n2 := protowire.ConsumeBytes(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("capability: invalid provenance")
+			}
+			if err := l.repeated(".provenance", len(m.Provenance)+1); err != nil {
+				return nil, err
+			}
+			tag, err := decodeProvenanceTag(b, l)
+			if err != nil {
+				return nil, within(".provenance["+strconv.Itoa(len(m.Provenance))+"]", err)
+			}
+			m.Provenance = append(m.Provenance, tag)
+			data = data[n2:]
+		case 8:
+			b, n2 := protowire.ConsumeBytes(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("capability: invalid credential")
+			}
+			if err := l.repeated(".credentials", len(m.Credentials)+1); err != nil {
+				return nil, err
+			}
+			c, err := decodeCredential(b, l)
+			if err != nil {
+				return nil, within(".credentials["+strconv.Itoa(len(m.Credentials))+"]", err)
+			}
+			m.Credentials = append(m.Credentials, c)
+			data = data[n2:]
+		case 9:
+			b, n2 := protowire.ConsumeBytes(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("capability: invalid public_key")
+			}
+			m.PublicKey = append([]byte(nil), b...)
+			data = data[n2:]
+		case 10:
+			b, n2 := protowire.ConsumeBytes(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("capability: invalid signature")
+			}
+			m.Signature = append([]byte(nil), b...)
+			data = data[n2:]
+		default:
+			n2 := protowire.ConsumeFieldValue(num, typ, data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("capability: unknown field %d", num)
+			}
+			data = data[n2:]
+		}
+	}
+	return m, nil
+}
+
+// DecodeCapabilityAnnouncement decodes a CapabilityAnnouncement within l.
+func (l DecodeLimits) DecodeCapabilityAnnouncement(data []byte) (*CapabilityAnnouncement, error) {
+	m, err := decodeCapabilityAnnouncement(data, l)
+	if err != nil {
+		return nil, within("CapabilityAnnouncement", err)
+	}
+	return m, nil
+}
+
+// ------------------------------------------------------------------ CounterOffer
+
+// Encode serialises m into the Protobuf wire format.
+func (m *CounterOffer) Encode() ([]byte, error) {
+	e := &enc{}
+	e.str(1, m.RequestID)
+	e.i64(2, int64(m.Round))
+	e.str(3, m.AgentID)
+	e.str(4, m.DID)
+	e.i64(5, int64(m.Action))
+	e.strs(6, m.Terms.Capabilities)
+	e.strs(7, m.Terms.WorkflowSteps)
+	e.f32(8, m.Terms.Price)
+	e.i64(9, m.Terms.MaxLatencyMs)
+	e.str(10, m.Reason)
+	e.i64(11, m.Timestamp)
+	e.bytes(12, m.Signature)
+	return e.buf, nil
+}
+
+// DecodeCounterOffer deserialises a CounterOffer from wire bytes.
+func DecodeCounterOffer(data []byte) (*CounterOffer, error) {
+	return DefaultDecodeLimits.DecodeCounterOffer(data)
+}
+
+func decodeCounterOffer(data []byte, l DecodeLimits) (*CounterOffer, error) {
+	m := &CounterOffer{}
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, fmt.Errorf("counter: invalid tag")
+		}
+		data = data[n:]
+
+		switch num {
+		case 1:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("counter: invalid request_id")
+			}
+			if err := l.str(".request_id", s); err != nil {
+				return nil, err
+			}
+			m.RequestID = s
+			data = data[n2:]
+		case 2:
+			v, n2 := protowire.ConsumeVarint(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("counter: invalid round")
+			}
+			m.Round = int32(v)
+			data = data[n2:]
+		case 3:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("counter: invalid agent_id")
+			}
+			if err := l.str(".agent_id", s); err != nil {
+				return nil, err
+			}
+			m.AgentID = s
+			data = data[n2:]
+		case 4:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("counter: invalid did")
+			}
+			if err := l.str(".did", s); err != nil {
+				return nil, err
+			}
+			m.DID = s
+			data = data[n2:]
+		case 5:
+			v, n2 := protowire.ConsumeVarint(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("counter: invalid action")
+			}
+			m.Action = CounterAction(v)
+			data = data[n2:]
+		case 6:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("counter: invalid capability")
+			}
+			if err := l.repeated(".terms.capabilities", len(m.Terms.Capabilities)+1); err != nil {
+				return nil, err
+			}
+			if err := l.strAt(".terms.capabilities", len(m.Terms.Capabilities), s); err != nil {
+				return nil, err
+			}
+			m.Terms.Capabilities = append(m.Terms.Capabilities, s)
+			data = data[n2:]
+		case 7:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("counter: invalid workflow_step")
+			}
+			if err := l.repeated(".terms.workflow_steps", len(m.Terms.WorkflowSteps)+1); err != nil {
+				return nil, err
+			}
+			if err := l.strAt(".terms.workflow_steps", len(m.Terms.WorkflowSteps), s); err != nil {
+				return nil, err
+			}
+			m.Terms.WorkflowSteps = append(m.Terms.WorkflowSteps, s)
+			data = data[n2:]
+		case 8:
+			v, n2 := protowire.ConsumeFixed32(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("counter: invalid price")
+			}
+			m.Terms.Price = math.Float32frombits(v)
+			data = data[n2:]
+		case 9:
+			v, n2 := protowire.ConsumeVarint(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("counter: invalid max_latency_ms")
+			}
+			m.Terms.MaxLatencyMs = int64(v)
+			data = data[n2:]
+		case 10:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("counter: invalid reason")
+			}
+			if err := l.str(".reason", s); err != nil {
+				return nil, err
+			}
+			m.Reason = s
+			data = data[n2:]
+		case 11:
+			v, n2 := protowire.ConsumeVarint(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("counter: invalid timestamp")
+			}
+			m.Timestamp = int64(v)
+			data = data[n2:]
+		case 12:
+			b, n2 := protowire.ConsumeBytes(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("counter: invalid signature")
+			}
+			m.Signature = append([]byte(nil), b...)
+			data = data[n2:]
+		default:
+			n2 := protowire.ConsumeFieldValue(num, typ, data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("counter: unknown field %d", num)
+			}
+			data = data[n2:]
+		}
+	}
+	return m, nil
+}
+
+// DecodeCounterOffer decodes a CounterOffer within l.
+func (l DecodeLimits) DecodeCounterOffer(data []byte) (*CounterOffer, error) {
+	m, err := decodeCounterOffer(data, l)
+	if err != nil {
+		return nil, within("CounterOffer", err)
+	}
+	return m, nil
+}
+
+// ------------------------------------------------------------------ AlertMessage
+
+// Encode serialises m into the Protobuf wire format.
+func (m *AlertMessage) Encode() ([]byte, error) {
+	e := &enc{}
+	e.str(1, m.ID)
+	e.i64(2, int64(m.Kind))
+	e.str(3, m.IssuerDID)
+	e.bytes(4, m.IssuerKey)
+	e.str(5, m.Subject)
+	e.str(6, m.Detail)
+	e.i64(7, m.Timestamp)
+	e.i64(8, int64(m.Hops))
+	e.bytes(9, m.Signature)
+	return e.buf, nil
+}
+
+// DecodeAlertMessage deserialises an AlertMessage from wire bytes.
+func DecodeAlertMessage(data []byte) (*AlertMessage, error) {
+	return DefaultDecodeLimits.DecodeAlertMessage(data)
+}
+
+func decodeAlertMessage(data []byte, l DecodeLimits) (*AlertMessage, error) {
+	m := &AlertMessage{}
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, fmt.Errorf("alert: invalid tag")
+		}
+		data = data[n:]
+
+		switch num {
+		case 1:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("alert: invalid id")
+			}
+			if err := l.str(".id", s); err != nil {
+				return nil, err
+			}
+			m.ID = s
+			data = data[n2:]
+		case 2:
+			v, n2 := protowire.ConsumeVarint(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("alert: invalid kind")
+			}
+			m.Kind = AlertKind(v)
+			data = data[n2:]
+		case 3:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("alert: invalid issuer_did")
+			}
+			if err := l.str(".issuer_did", s); err != nil {
+				return nil, err
+			}
+			m.IssuerDID = s
+			data = data[n2:]
+		case 4:
+			b, n2 := protowire.ConsumeBytes(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("alert: invalid issuer_key")
+			}
+			m.IssuerKey = append([]byte(nil), b...)
+			data = data[n2:]
+		case 5:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("alert: invalid subject")
+			}
+			if err := l.str(".subject", s); err != nil {
+				return nil, err
+			}
+			m.Subject = s
+			data = data[n2:]
+		case 6:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("alert: invalid detail")
+			}
+			if err := l.str(".detail", s); err != nil {
+				return nil, err
+			}
+			m.Detail = s
+			data = data[n2:]
+		case 7:
+			v, n2 := protowire.ConsumeVarint(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("alert: invalid timestamp")
+			}
+			m.Timestamp = int64(v)
+			data = data[n2:]
+		case 8:
+			v, n2 := protowire.ConsumeVarint(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("alert: invalid hops")
+			}
+			m.Hops = int32(v)
+			data = data[n2:]
+		case 9:
+			b, n2 := protowire.ConsumeBytes(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("alert: invalid signature")
+			}
+			m.Signature = append([]byte(nil), b...)
+			data = data[n2:]
+		default:
+			n2 := protowire.ConsumeFieldValue(num, typ, data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("alert: unknown field %d", num)
+			}
+			data = data[n2:]
+		}
+	}
+	return m, nil
+}
+
+// DecodeAlertMessage decodes an AlertMessage within l.
+func (l DecodeLimits) DecodeAlertMessage(data []byte) (*AlertMessage, error) {
+	m, err := decodeAlertMessage(data, l)
+	if err != nil {
+		return nil, within("AlertMessage", err)
+	}
+	return m, nil
+}
+
+// ------------------------------------------------------------------ RevocationList
+
+// Encode serialises m into the Protobuf wire format.
+func (m *RevocationList) Encode() ([]byte, error) {
+	e := &enc{}
+	e.str(1, m.IssuerDID)
+	e.bytes(2, m.IssuerKey)
+	e.i64(3, m.Sequence)
+	e.i64(4, m.IssuedAt)
+	for _, r := range m.Entries {
+		x := &enc{}
+		x.str(1, r.DID)
+		x.str(2, r.Reason)
+		x.i64(3, r.RevokedAt)
+		e.buf = protowire.AppendTag(e.buf, 5, protowire.BytesType)
+		e.buf = protowire.AppendBytes(e.buf, x.buf)
+	}
+	e.bytes(6, m.Signature)
+	return e.buf, nil
+}
+
+// DecodeRevocationList deserialises a RevocationList from wire bytes.
+func DecodeRevocationList(data []byte) (*RevocationList, error) {
+	return DefaultDecodeLimits.DecodeRevocationList(data)
+}
+
+func decodeRevocationList(data []byte, l DecodeLimits) (*RevocationList, error) {
+	m := &RevocationList{}
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, fmt.Errorf("revocation: invalid tag")
+		}
+		data = data[n:]
+
+		switch num {
+		case 1:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("revocation: invalid issuer_did")
+			}
+			if err := l.str(".issuer_did", s); err != nil {
+				return nil, err
+			}
+			m.IssuerDID = s
+			data = data[n2:]
+		case 2:
+			b, n2 := protowire.ConsumeBytes(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("revocation: invalid issuer_key")
+			}
+			m.IssuerKey = append([]byte(nil), b...)
+			data = data[n2:]
+		case 3:
+			v, n2 := protowire.ConsumeVarint(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("revocation: invalid sequence")
+			}
+			m.Sequence = int64(v)
+			data = data[n2:]
+		case 4:
+			v, n2 := protowire.ConsumeVarint(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("revocation: invalid issued_at")
+			}
+			m.IssuedAt = int64(v)
+			data = data[n2:]
+		case 5:
+			b, n2 := protowire.ConsumeBytes(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("revocation: invalid entry")
+			}
+			if err := l.repeated(".entries", len(m.Entries)+1); err != nil {
+				return nil, err
+			}
+			r, err := decodeRevocationEntry(b, l)
+			if err != nil {
+				return nil, within(".entries["+strconv.Itoa(len(m.Entries))+"]", err)
+			}
+			m.Entries = append(m.Entries, r)
+			data = data[n2:]
+		case 6:
+			b, n2 := protowire.ConsumeBytes(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("revocation: invalid signature")
+			}
+			m.Signature = append([]byte(nil), b...)
+			data = data[n2:]
+		default:
+			n2 := protowire.ConsumeFieldValue(num, typ, data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("revocation: unknown field %d", num)
+			}
+			data = data[n2:]
+		}
+	}
+	return m, nil
+}
+
+// DecodeRevocationList decodes a RevocationList within l.
+func (l DecodeLimits) DecodeRevocationList(data []byte) (*RevocationList, error) {
+	m, err := decodeRevocationList(data, l)
+	if err != nil {
+		return nil, within("RevocationList", err)
+	}
+	return m, nil
+}
+
+func decodeRevocationEntry(data []byte, l DecodeLimits) (RevocationEntry, error) {
+	var r RevocationEntry
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return r, fmt.Errorf("revocation entry: invalid tag")
+		}
+		data = data[n:]
+
+		switch num {
+		case 1:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return r, fmt.Errorf("revocation entry: invalid did")
+			}
+			if err := l.str(".did", s); err != nil {
+				return r, err
+			}
+			r.DID = s
+			data = data[n2:]
+		case 2:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return r, fmt.Errorf("revocation entry: invalid reason")
+			}
+			if err := l.str(".reason", s); err != nil {
+				return r, err
+			}
+			r.Reason = s
+			data = data[n2:]
+		case 3:
+			v, n2 := protowire.ConsumeVarint(data)
+			if n2 < 0 {
+				return r, fmt.Errorf("revocation entry: invalid revoked_at")
+			}
+			r.RevokedAt = int64(v)
+			data = data[n2:]
+		default:
+			n2 := protowire.ConsumeFieldValue(num, typ, data)
+			if n2 < 0 {
+				return r, fmt.Errorf("revocation entry: unknown field %d", num)
+			}
+			data = data[n2:]
+		}
+	}
+	return r, nil
+}
+
+// ------------------------------------------------------------------ TrustAttestation
+
+// Encode serialises m into the Protobuf wire format.
+func (m *TrustAttestation) Encode() ([]byte, error) {
+	e := &enc{}
+	e.str(1, m.IssuerDID)
+	e.bytes(2, m.IssuerKey)
+	e.str(3, m.SubjectDID)
+	e.f32(4, m.Score)
+	e.i64(5, m.IssuedAt)
+	e.i64(6, m.ExpiresAt)
+	e.bytes(7, m.Signature)
+	return e.buf, nil
+}
+
+// DecodeTrustAttestation deserialises a TrustAttestation from wire bytes.
+func DecodeTrustAttestation(data []byte) (*TrustAttestation, error) {
+	return DefaultDecodeLimits.DecodeTrustAttestation(data)
+}
+
+func decodeTrustAttestation(data []byte, l DecodeLimits) (*TrustAttestation, error) {
+	m := &TrustAttestation{}
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, fmt.Errorf("attestation: invalid tag")
+		}
+		data = data[n:]
+
+		switch num {
+		case 1:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("attestation: invalid issuer_did")
+			}
+			if err := l.str(".issuer_did", s); err != nil {
+				return nil, err
+			}
+			m.IssuerDID = s
+			data = data[n2:]
+		case 2:
+			b, n2 := protowire.ConsumeBytes(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("attestation: invalid issuer_key")
+			}
+			m.IssuerKey = append([]byte(nil), b...)
+			data = data[n2:]
+		case 3:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("attestation: invalid subject_did")
+			}
+			if err := l.str(".subject_did", s); err != nil {
+				return nil, err
+			}
+			m.SubjectDID = s
+			data = data[n2:]
+		case 4:
+			v, n2 := protowire.ConsumeFixed32(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("attestation: invalid score")
+			}
+			m.Score = math.Float32frombits(v)
+			data = data[n2:]
+		case 5:
+			v, n2 := protowire.ConsumeVarint(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("attestation: invalid issued_at")
+			}
+			m.IssuedAt = int64(v)
+			data = data[n2:]
+		case 6:
+			v, n2 := protowire.ConsumeVarint(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("attestation: invalid expires_at")
+			}
+			m.ExpiresAt = int64(v)
+			data = data[n2:]
+		case 7:
+			b, n2 := protowire.ConsumeBytes(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("attestation: invalid signature")
+			}
+			m.Signature = append([]byte(nil), b...)
+			data = data[n2:]
+		default:
+			n2 := protowire.ConsumeFieldValue(num, typ, data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("attestation: unknown field %d", num)
+			}
+			data = data[n2:]
+		}
+	}
+	return m, nil
+}
+
+// DecodeTrustAttestation decodes a TrustAttestation within l.
+func (l DecodeLimits) DecodeTrustAttestation(data []byte) (*TrustAttestation, error) {
+	m, err := decodeTrustAttestation(data, l)
+	if err != nil {
+		return nil, within("TrustAttestation", err)
+	}
+	return m, nil
+}
+
+// ------------------------------------------------------------------ KeyRotation
+
+// Encode serialises m into the Protobuf wire format.
+func (m *KeyRotation) Encode() ([]byte, error) {
+	e := &enc{}
+	e.str(1, m.OldDID)
+	e.bytes(2, m.OldKey)
+	e.str(3, m.NewDID)
+	e.bytes(4, m.NewKey)
+	e.str(5, m.Reason)
+	e.i64(6, m.RotatedAt)
+	e.bytes(7, m.Signature)
+	e.bytes(8, m.NewSignature)
+	return e.buf, nil
+}
+
+// DecodeKeyRotation deserialises a KeyRotation from wire bytes.
+func DecodeKeyRotation(data []byte) (*KeyRotation, error) {
+	return DefaultDecodeLimits.DecodeKeyRotation(data)
+}
+
+func decodeKeyRotation(data []byte, l DecodeLimits) (*KeyRotation, error) {
+	m := &KeyRotation{}
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, fmt.Errorf("rotation: invalid tag")
+		}
+		data = data[n:]
+
+		switch num {
+		case 1:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("rotation: invalid old_did")
+			}
+			if err := l.str(".old_did", s); err != nil {
+				return nil, err
+			}
+			m.OldDID = s
+			data = data[n2:]
+		case 2:
+			b, n2 := protowire.ConsumeBytes(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("rotation: invalid old_key")
+			}
+			m.OldKey = append([]byte(nil), b...)
+			data = data[n2:]
+		case 3:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("rotation: invalid new_did")
+			}
+			if err := l.str(".new_did", s); err != nil {
+				return nil, err
+			}
+			m.NewDID = s
+			data = data[n2:]
+		case 4:
+			b, n2 := protowire.ConsumeBytes(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("rotation: invalid new_key")
+			}
+			m.NewKey = append([]byte(nil), b...)
+			data = data[n2:]
+		case 5:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("rotation: invalid reason")
+			}
+			if err := l.str(".reason", s); err != nil {
+				return nil, err
+			}
+			m.Reason = s
+			data = data[n2:]
+		case 6:
+			v, n2 := protowire.ConsumeVarint(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("rotation: invalid rotated_at")
+			}
+			m.RotatedAt = int64(v)
+			data = data[n2:]
+		case 7:
+			b, n2 := protowire.ConsumeBytes(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("rotation: invalid signature")
+			}
+			m.Signature = append([]byte(nil), b...)
+			data = data[n2:]
+		case 8:
+			b, n2 := protowire.ConsumeBytes(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("rotation: invalid new_signature")
+			}
+			m.NewSignature = append([]byte(nil), b...)
+			data = data[n2:]
+		default:
+			n2 := protowire.ConsumeFieldValue(num, typ, data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("rotation: unknown field %d", num)
+			}
+			data = data[n2:]
+		}
+	}
+	return m, nil
+}
+
+// DecodeKeyRotation decodes a KeyRotation within l.
+func (l DecodeLimits) DecodeKeyRotation(data []byte) (*KeyRotation, error) {
+	m, err := decodeKeyRotation(data, l)
+	if err != nil {
+		return nil, within("KeyRotation", err)
+	}
+	return m, nil
+}
+
+// ------------------------------------------------------------------ ForwardEnvelope
+
+// Encode serialises m into the Protobuf wire format.
+func (m *ForwardEnvelope) Encode() ([]byte, error) {
+	e := &enc{}
+	if m.Intent != nil {
+		b, err := m.Intent.Encode()
+		if err != nil {
+			return nil, err
+		}
+		e.bytes(1, b)
+	}
+	e.bytes(2, m.OriginKey)
+	e.str(3, m.RelayDID)
+	e.bytes(4, m.RelayKey)
+	e.strs(5, m.Path)
+	e.i64(6, int64(m.Hops))
+	e.i64(7, m.Timestamp)
+	e.bytes(8, m.Signature)
+	return e.buf, nil
+}
+
+// DecodeForwardEnvelope deserialises a ForwardEnvelope from wire bytes.
+func DecodeForwardEnvelope(data []byte) (*ForwardEnvelope, error) {
+	return DefaultDecodeLimits.DecodeForwardEnvelope(data)
+}
+
+func decodeForwardEnvelope(data []byte, l DecodeLimits) (*ForwardEnvelope, error) {
+	m := &ForwardEnvelope{}
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, fmt.Errorf("forward: invalid tag")
+		}
+		data = data[n:]
+
+		switch num {
+		case 1:
+			b, n2 := protowire.ConsumeBytes(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("forward: invalid intent")
+			}
+			intent, err := decodeIntentMessage(b, l)
+			if err != nil {
+				return nil, fmt.Errorf("forward: %w", within(".intent", err))
+			}
+			m.Intent = intent
+			data = data[n2:]
+		case 2:
+			b, n2 := protowire.ConsumeBytes(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("forward: invalid origin_key")
+			}
+			m.OriginKey = append([]byte(nil), b...)
+			data = data[n2:]
+		case 3:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("forward: invalid relay_did")
+			}
+			if err := l.str(".relay_did", s); err != nil {
+				return nil, err
+			}
+			m.RelayDID = s
+			data = data[n2:]
+		case 4:
+			b, n2 := protowire.ConsumeBytes(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("forward: invalid relay_key")
+			}
+			m.RelayKey = append([]byte(nil), b...)
+			data = data[n2:]
+		case 5:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("forward: invalid path")
+			}
+			if err := l.repeated(".path", len(m.Path)+1); err != nil {
+				return nil, err
+			}
+			if err := l.strAt(".path", len(m.Path), s); err != nil {
+				return nil, err
+			}
+			m.Path = append(m.Path, s)
+			data = data[n2:]
+		case 6:
+			v, n2 := protowire.ConsumeVarint(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("forward: invalid hops")
+			}
+			m.Hops = int32(v)
+			data = data[n2:]
+		case 7:
+			v, n2 := protowire.ConsumeVarint(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("forward: invalid timestamp")
+			}
+			m.Timestamp = int64(v)
+			data = data[n2:]
+		case 8:
+			b, n2 := protowire.ConsumeBytes(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("forward: invalid signature")
+			}
+			m.Signature = append([]byte(nil), b...)
+			data = data[n2:]
+		default:
+			n2 := protowire.ConsumeFieldValue(num, typ, data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("forward: unknown field %d", num)
+			}
+			data = data[n2:]
+		}
+	}
+	return m, nil
+}
+
+// DecodeForwardEnvelope decodes a ForwardEnvelope within l.
+func (l DecodeLimits) DecodeForwardEnvelope(data []byte) (*ForwardEnvelope, error) {
+	m, err := decodeForwardEnvelope(data, l)
+	if err != nil {
+		return nil, within("ForwardEnvelope", err)
+	}
+	return m, nil
+}
+
+// ------------------------------------------------------------------ IntentBatch
+
+// Encode serialises m to protobuf wire bytes.
+func (m *IntentBatch) Encode() ([]byte, error) { return m.AppendEncode(nil) }
+
+// AppendEncode appends the Protobuf encoding of m to b.
+func (m *IntentBatch) AppendEncode(b []byte) ([]byte, error) {
+	e := &enc{buf: b}
+	for _, intent := range m.Intents {
+		if err := e.nested(1, intent.AppendEncode); err != nil {
+			return nil, err
+		}
+	}
+	return e.buf, nil
+}
+
+// DecodeIntentBatch deserialises an IntentBatch from wire bytes.
+func DecodeIntentBatch(data []byte) (*IntentBatch, error) {
+	return DefaultDecodeLimits.DecodeIntentBatch(data)
+}
+
+func decodeIntentBatch(data []byte, l DecodeLimits) (*IntentBatch, error) {
+	m := &IntentBatch{}
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, fmt.Errorf("intentbatch: invalid tag")
+		}
+		data = data[n:]
+
+		switch num {
+		case 1:
+			b, n2 := protowire.ConsumeBytes(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("intentbatch: invalid intent")
+			}
+			if err := l.repeated(".intents", len(m.Intents)+1); err != nil {
+				return nil, err
+			}
+			intent, err := decodeIntentMessage(b, l)
+			if err != nil {
+				return nil, fmt.Errorf("intentbatch: %w", within(".intents["+strconv.Itoa(len(m.Intents))+"]", err))
+			}
+			m.Intents = append(m.Intents, intent)
+			data = data[n2:]
+		default:
+			n2 := protowire.ConsumeFieldValue(num, typ, data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("intentbatch: unknown field %d", num)
+			}
+			data = data[n2:]
+		}
+	}
+	return m, nil
+}
+
+// DecodeIntentBatch decodes an IntentBatch within l.
+func (l DecodeLimits) DecodeIntentBatch(data []byte) (*IntentBatch, error) {
+	m, err := decodeIntentBatch(data, l)
+	if err != nil {
+		return nil, within("IntentBatch", err)
+	}
+	return m, nil
+}
+
+// ------------------------------------------------------------------ ResponseBatch
+
+// Encode serialises m to protobuf wire bytes.
+func (m *ResponseBatch) Encode() ([]byte, error) {
+	e := &enc{}
+	for _, resp := range m.Responses {
+		b, err := resp.Encode()
+		if err != nil {
+			return nil, err
+		}
+		e.bytes(1, b)
+	}
+	return e.buf, nil
+}
+
+// DecodeResponseBatch deserialises a ResponseBatch from wire bytes.
+func DecodeResponseBatch(data []byte) (*ResponseBatch, error) {
+	return DefaultDecodeLimits.DecodeResponseBatch(data)
+}
+
+func decodeResponseBatch(data []byte, l DecodeLimits) (*ResponseBatch, error) {
+	m := &ResponseBatch{}
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, fmt.Errorf("responsebatch: invalid tag")
+		}
+		data = data[n:]
+
+		switch num {
+		case 1:
+			b, n2 := protowire.ConsumeBytes(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("responsebatch: invalid response")
+			}
+			if err := l.repeated(".responses", len(m.Responses)+1); err != nil {
+				return nil, err
+			}
+			resp, err := decodeNegotiationResponse(b, l)
+			if err != nil {
+				return nil, fmt.Errorf("responsebatch: %w", within(".responses["+strconv.Itoa(len(m.Responses))+"]", err))
+			}
+			m.Responses = append(m.Responses, resp)
+			data = data[n2:]
+		default:
+			n2 := protowire.ConsumeFieldValue(num, typ, data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("responsebatch: unknown field %d", num)
+			}
+			data = data[n2:]
+		}
+	}
+	return m, nil
+}
+
+// DecodeResponseBatch decodes a ResponseBatch within l.
+func (l DecodeLimits) DecodeResponseBatch(data []byte) (*ResponseBatch, error) {
+	m, err := decodeResponseBatch(data, l)
+	if err != nil {
+		return nil, within("ResponseBatch", err)
+	}
+	return m, nil
+}
+
+// ------------------------------------------------------------------ PingMessage
+
+// Encode serialises m to protobuf wire bytes.
+func (m *PingMessage) Encode() ([]byte, error) {
+	e := &enc{}
+	e.bytes(1, m.Nonce)
+	e.i64(2, m.SentAt)
+	return e.buf, nil
+}
+
+// DecodePingMessage deserialises a PingMessage from wire bytes.
+func DecodePingMessage(data []byte) (*PingMessage, error) {
+	return DefaultDecodeLimits.DecodePingMessage(data)
+}
+
+func decodePingMessage(data []byte, l DecodeLimits) (*PingMessage, error) {
+	m := &PingMessage{}
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, fmt.Errorf("ping: invalid tag")
+		}
+		data = data[n:]
+
+		switch num {
+		case 1:
+			b, n2 := protowire.ConsumeBytes(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("ping: invalid nonce")
+			}
+			m.Nonce = append([]byte(nil), b...)
+			data = data[n2:]
+		case 2:
+			v, n2 := protowire.ConsumeVarint(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("ping: invalid sent_at")
+			}
+			m.SentAt = int64(v)
+			data = data[n2:]
+		default:
+			n2 := protowire.ConsumeFieldValue(num, typ, data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("ping: unknown field %d", num)
+			}
+			data = data[n2:]
+		}
+	}
+	return m, nil
+}
+
+// DecodePingMessage decodes a PingMessage within l.
+func (l DecodeLimits) DecodePingMessage(data []byte) (*PingMessage, error) {
+	m, err := decodePingMessage(data, l)
+	if err != nil {
+		return nil, within("PingMessage", err)
+	}
+	return m, nil
+}
+
+// ------------------------------------------------------------------ PongMessage
+
+// Encode serialises m to protobuf wire bytes.
+func (m *PongMessage) Encode() ([]byte, error) {
+	e := &enc{}
+	e.bytes(1, m.Nonce)
+	e.i64(2, m.SentAt)
+	e.i64(3, m.RepliedAt)
+	return e.buf, nil
+}
+
+// DecodePongMessage deserialises a PongMessage from wire bytes.
+func DecodePongMessage(data []byte) (*PongMessage, error) {
+	return DefaultDecodeLimits.DecodePongMessage(data)
+}
+
+func decodePongMessage(data []byte, l DecodeLimits) (*PongMessage, error) {
+	m := &PongMessage{}
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, fmt.Errorf("pong: invalid tag")
+		}
+		data = data[n:]
+
+		switch num {
+		case 1:
+			b, n2 := protowire.ConsumeBytes(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("pong: invalid nonce")
+			}
+			m.Nonce = append([]byte(nil), b...)
+			data = data[n2:]
+		case 2:
+			v, n2 := protowire.ConsumeVarint(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("pong: invalid sent_at")
+			}
+			m.SentAt = int64(v)
+			data = data[n2:]
+		case 3:
+			v, n2 := protowire.ConsumeVarint(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("pong: invalid replied_at")
+			}
+			m.RepliedAt = int64(v)
+			data = data[n2:]
+		default:
+			n2 := protowire.ConsumeFieldValue(num, typ, data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("pong: unknown field %d", num)
+			}
+			data = data[n2:]
+		}
+	}
+	return m, nil
+}
+
+// DecodePongMessage decodes a PongMessage within l.
+func (l DecodeLimits) DecodePongMessage(data []byte) (*PongMessage, error) {
+	m, err := decodePongMessage(data, l)
+	if err != nil {
+		return nil, within("PongMessage", err)
+	}
+	return m, nil
+}
+
+// ------------------------------------------------------------------ WorkflowResult
+
+// Encode serialises m to protobuf wire bytes.
+func (m *WorkflowResult) Encode() ([]byte, error) {
+	e := &enc{}
+	e.str(1, m.WorkflowID)
+	e.str(2, m.StepID)
+	e.str(3, m.RequestID)
+	e.str(4, m.DID)
+	e.str(5, m.Status)
+	if m.Output != nil {
+		e.bytes(6, encodeResultPayload(m.Output))
+	}
+	e.str(7, m.Error)
+	e.i64(8, m.Timestamp)
+	e.bytes(9, m.Signature)
+	return e.buf, nil
+}
+
+// DecodeWorkflowResult deserialises a WorkflowResult from wire bytes.
+func DecodeWorkflowResult(data []byte) (*WorkflowResult, error) {
+	return DefaultDecodeLimits.DecodeWorkflowResult(data)
+}
+
+func decodeWorkflowResult(data []byte, l DecodeLimits) (*WorkflowResult, error) {
+	m := &WorkflowResult{}
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, fmt.Errorf("workflowresult: invalid tag")
+		}
+		data = data[n:]
+
+		switch num {
+		case 1:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("workflowresult: invalid workflow_id")
+			}
+			if err := l.str(".workflow_id", s); err != nil {
+				return nil, err
+			}
+			m.WorkflowID = s
+			data = data[n2:]
+		case 2:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("workflowresult: invalid step_id")
+			}
+			if err := l.str(".step_id", s); err != nil {
+				return nil, err
+			}
+			m.StepID = s
+			data = data[n2:]
+		case 3:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("workflowresult: invalid request_id")
+			}
+			if err := l.str(".request_id", s); err != nil {
+				return nil, err
+			}
+			m.RequestID = s
+			data = data[n2:]
+		case 4:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("workflowresult: invalid did")
+			}
+			if err := l.str(".did", s); err != nil {
+				return nil, err
+			}
+			m.DID = s
+			data = data[n2:]
+		case 5:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("workflowresult: invalid status")
+			}
+			if err := l.str(".status", s); err != nil {
+				return nil, err
+			}
+			m.Status = s
+			data = data[n2:]
+		case 6:
+			b, n2 := protowire.ConsumeBytes(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("workflowresult: invalid output")
+			}
+			out, err := decodeResultPayload(b, l)
+			if err != nil {
+				return nil, fmt.Errorf("workflowresult: %w", within(".output", err))
+			}
+			m.Output = out
+			data = data[n2:]
+		case 7:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("workflowresult: invalid error")
+			}
+			if err := l.str(".error", s); err != nil {
+				return nil, err
+			}
+			m.Error = s
+			data = data[n2:]
+		case 8:
+			v, n2 := protowire.ConsumeVarint(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("workflowresult: invalid timestamp")
+			}
+			m.Timestamp = int64(v)
+			data = data[n2:]
+		case 9:
+			b, n2 := protowire.ConsumeBytes(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("workflowresult: invalid signature")
+			}
+			m.Signature = append([]byte(nil), b...)
+			data = data[n2:]
+		default:
+			n2 := protowire.ConsumeFieldValue(num, typ, data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("workflowresult: unknown field %d", num)
+			}
+			data = data[n2:]
+		}
+	}
+	return m, nil
+}
+
+// DecodeWorkflowResult decodes a WorkflowResult within l.
+func (l DecodeLimits) DecodeWorkflowResult(data []byte) (*WorkflowResult, error) {
+	m, err := decodeWorkflowResult(data, l)
+	if err != nil {
+		return nil, within("WorkflowResult", err)
+	}
+	return m, nil
+}
+
+// ------------------------------------------------------------------ ObserveRequest
+
+// Encode serialises m to protobuf wire bytes.
+func (m *ObserveRequest) Encode() ([]byte, error) {
+	e := &enc{}
+	e.strs(1, m.Kinds)
+	return e.buf, nil
+}
+
+// DecodeObserveRequest deserialises an ObserveRequest from wire bytes.
+func DecodeObserveRequest(data []byte) (*ObserveRequest, error) {
+	return DefaultDecodeLimits.DecodeObserveRequest(data)
+}
+
+func decodeObserveRequest(data []byte, l DecodeLimits) (*ObserveRequest, error) {
+	m := &ObserveRequest{}
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, fmt.Errorf("observe: invalid tag")
+		}
+		data = data[n:]
+
+		switch num {
+		case 1:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("observe: invalid kind")
+			}
+			if err := l.repeated(".kinds", len(m.Kinds)+1); err != nil {
+				return nil, err
+			}
+			if err := l.strAt(".kinds", len(m.Kinds), s); err != nil {
+				return nil, err
+			}
+			m.Kinds = append(m.Kinds, s)
+			data = data[n2:]
+		default:
+			n2 := protowire.ConsumeFieldValue(num, typ, data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("observe: unknown field %d", num)
+			}
+			data = data[n2:]
+		}
+	}
+	return m, nil
+}
+
+// DecodeObserveRequest decodes an ObserveRequest within l.
+func (l DecodeLimits) DecodeObserveRequest(data []byte) (*ObserveRequest, error) {
+	m, err := decodeObserveRequest(data, l)
+	if err != nil {
+		return nil, within("ObserveRequest", err)
+	}
+	return m, nil
+}
+
+// ------------------------------------------------------------------ EventMessage
+
+// Encode serialises m to protobuf wire bytes.
+func (m *EventMessage) Encode() ([]byte, error) {
+	e := &enc{}
+	e.str(1, m.Kind)
+	e.str(2, m.PeerID)
+	e.str(3, m.AgentID)
+	e.str(4, m.DID)
+	e.str(5, m.IntentID)
+	e.strs(6, m.Capabilities)
+	e.str(7, m.Reason)
+	e.f32(8, m.Delta)
+	e.f32(9, m.Trust)
+	e.i64(10, m.Timestamp)
+	e.i64(11, m.Dropped)
+	return e.buf, nil
+}
+
+// DecodeEventMessage deserialises an EventMessage from wire bytes.
+func DecodeEventMessage(data []byte) (*EventMessage, error) {
+	return DefaultDecodeLimits.DecodeEventMessage(data)
+}
+
+func decodeEventMessage(data []byte, l DecodeLimits) (*EventMessage, error) {
+	m := &EventMessage{}
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, fmt.Errorf("event: invalid tag")
+		}
+		data = data[n:]
+
+		switch num {
+		case 1:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("event: invalid kind")
+			}
+			if err := l.str(".kind", s); err != nil {
+				return nil, err
+			}
+			m.Kind = s
+			data = data[n2:]
+		case 2:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("event: invalid peer_id")
+			}
+			if err := l.str(".peer_id", s); err != nil {
+				return nil, err
+			}
+			m.PeerID = s
+			data = data[n2:]
+		case 3:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("event: invalid agent_id")
+			}
+			if err := l.str(".agent_id", s); err != nil {
+				return nil, err
+			}
+			m.AgentID = s
+			data = data[n2:]
+		case 4:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("event: invalid did")
+			}
+			if err := l.str(".did", s); err != nil {
+				return nil, err
+			}
+			m.DID = s
+			data = data[n2:]
+		case 5:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("event: invalid intent_id")
+			}
+			if err := l.str(".intent_id", s); err != nil {
+				return nil, err
+			}
+			m.IntentID = s
+			data = data[n2:]
+		case 6:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("event: invalid capability")
+			}
+			if err := l.repeated(".capabilities", len(m.Capabilities)+1); err != nil {
+				return nil, err
+			}
+			if err := l.strAt(".capabilities", len(m.Capabilities), s); err != nil {
+				return nil, err
+			}
+			m.Capabilities = append(m.Capabilities, s)
+			data = data[n2:]
+		case 7:
+			s, n2 := protowire.ConsumeString(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("event: invalid reason")
+			}
+			if err := l.str(".reason", s); err != nil {
+				return nil, err
+			}
+			m.Reason = s
+			data = data[n2:]
+		case 8:
+			v, n2 := protowire.ConsumeFixed32(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("event: invalid delta")
+			}
+			m.Delta = math.Float32frombits(v)
+			data = data[n2:]
+		case 9:
+			v, n2 := protowire.ConsumeFixed32(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("event: invalid trust")
+			}
+			m.Trust = math.Float32frombits(v)
+			data = data[n2:]
+		case 10:
+			v, n2 := protowire.ConsumeVarint(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("event: invalid timestamp")
+			}
+			m.Timestamp = int64(v)
+			data = data[n2:]
+		case 11:
+			v, n2 := protowire.ConsumeVarint(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("event: invalid dropped")
+			}
+			m.Dropped = int64(v)
+			data = data[n2:]
+		default:
+			n2 := protowire.ConsumeFieldValue(num, typ, data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("event: unknown field %d", num)
+			}
+			data = data[n2:]
+		}
+	}
+	return m, nil
+}
+
+// DecodeEventMessage decodes an EventMessage within l.
+func (l DecodeLimits) DecodeEventMessage(data []byte) (*EventMessage, error) {
+	m, err := decodeEventMessage(data, l)
+	if err != nil {
+		return nil, within("EventMessage", err)
+	}
+	return m, nil
+}
+
+// ------------------------------------------------------------------ framing
+
+// Frame wraps encoded message bytes with a 4-byte big-endian length prefix
+// and a 1-byte message type, ready to be sent over a stream.
+//
+// Layout: [4 bytes: uint32 frame length] [1 byte: MessageType] [N bytes: payload]
+func Frame(msgType MessageType, payload []byte) []byte {
+	total := 1 + len(payload)
+	frame := make([]byte, 4+total)
+	binary.BigEndian.PutUint32(frame[:4], uint32(total))
+	frame[4] = byte(msgType)
+	copy(frame[5:], payload)
+	return frame
+}
+
+// Unframe reads one framed message, returning the type and raw payload.
+// The caller must supply at least 5 bytes (4-byte header + type byte).
+func Unframe(frame []byte) (MessageType, []byte, error) {
+	if len(frame) < 5 {
+		return 0, nil, fmt.Errorf("frame too short (%d bytes)", len(frame))
+	}
+	total := int(binary.BigEndian.Uint32(frame[:4]))
+	if total < 1 {
+		return 0, nil, fmt.Errorf("frame: invalid length %d", total)
+	}
+	if len(frame) < 4+total {
+		return 0, nil, fmt.Errorf("frame incomplete: need %d bytes, have %d", 4+total, len(frame))
 	}
 	msgType := MessageType(frame[4])
 	payload := frame[5 : 4+total]
@@ -565,13 +3288,49 @@ func Unframe(frame []byte) (MessageType, []byte, error) {
 
 // Decode dispatches to the appropriate Decode* function based on msgType.
 func Decode(msgType MessageType, data []byte) (interface{}, error) {
+	return DefaultDecodeLimits.Decode(msgType, data)
+}
+
+// Decode dispatches to the appropriate Decode* method of l based on
+// msgType.
+func (l DecodeLimits) Decode(msgType MessageType, data []byte) (interface{}, error) {
 	switch msgType {
 	case MsgHandshake:
-		return DecodeHandshakeMessage(data)
+		return l.DecodeHandshakeMessage(data)
 	case MsgIntent:
-		return DecodeIntentMessage(data)
+		return l.DecodeIntentMessage(data)
 	case MsgNegotiation:
-		return DecodeNegotiationResponse(data)
+		return l.DecodeNegotiationResponse(data)
+	case MsgWorkflow:
+		return l.DecodeWorkflowMessage(data)
+	case MsgCapability:
+		return l.DecodeCapabilityAnnouncement(data)
+	case MsgCounter:
+		return l.DecodeCounterOffer(data)
+	case MsgAlert:
+		return l.DecodeAlertMessage(data)
+	case MsgRevocation:
+		return l.DecodeRevocationList(data)
+	case MsgAttestation:
+		return l.DecodeTrustAttestation(data)
+	case MsgForward:
+		return l.DecodeForwardEnvelope(data)
+	case MsgIntentBatch:
+		return l.DecodeIntentBatch(data)
+	case MsgResponseBatch:
+		return l.DecodeResponseBatch(data)
+	case MsgPing:
+		return l.DecodePingMessage(data)
+	case MsgPong:
+		return l.DecodePongMessage(data)
+	case MsgWorkflowResult:
+		return l.DecodeWorkflowResult(data)
+	case MsgKeyRotation:
+		return l.DecodeKeyRotation(data)
+	case MsgObserve:
+		return l.DecodeObserveRequest(data)
+	case MsgEvent:
+		return l.DecodeEventMessage(data)
 	default:
 		return nil, fmt.Errorf("unknown message type: 0x%02x", msgType)
 	}
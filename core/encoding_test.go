@@ -20,6 +20,7 @@ func TestIntentMessageRoundTrip(t *testing.T) {
 		TrustScore:   0.75,
 		Metadata:     map[string]string{"source": "unit-test", "priority": "high"},
 	}
+	original.WithTTL(time.Minute)
 
 	encoded, err := original.Encode()
 	if err != nil {
@@ -68,6 +69,9 @@ func TestIntentMessageRoundTrip(t *testing.T) {
 	if decoded.TrustScore != original.TrustScore {
 		t.Errorf("TrustScore: got %v want %v", decoded.TrustScore, original.TrustScore)
 	}
+	if decoded.ExpiresAt != original.ExpiresAt {
+		t.Errorf("ExpiresAt: got %d want %d", decoded.ExpiresAt, original.ExpiresAt)
+	}
 	if decoded.Metadata["source"] != original.Metadata["source"] {
 		t.Errorf("Metadata[source]: got %q want %q",
 			decoded.Metadata["source"], original.Metadata["source"])
@@ -101,6 +105,8 @@ func TestHandshakeMessageRoundTrip(t *testing.T) {
 		PublicKey:         []byte("fake32bytepublickey0000000000000"),
 		Challenge:         []byte("challenge-nonce-32bytes-padding-"),
 		ChallengeResponse: []byte("signature-bytes"),
+		Versions:          []string{"1.1.0", "1.0.0"},
+		Mesh:              "prod",
 	}
 
 	encoded, err := original.Encode()
@@ -121,6 +127,12 @@ func TestHandshakeMessageRoundTrip(t *testing.T) {
 	if decoded.Version != original.Version {
 		t.Errorf("Version: got %q want %q", decoded.Version, original.Version)
 	}
+	if len(decoded.Versions) != 2 || decoded.Versions[1] != "1.0.0" {
+		t.Errorf("Versions: got %v", decoded.Versions)
+	}
+	if decoded.Mesh != "prod" {
+		t.Errorf("Mesh: got %q", decoded.Mesh)
+	}
 	if string(decoded.PublicKey) != string(original.PublicKey) {
 		t.Errorf("PublicKey mismatch")
 	}
@@ -171,6 +183,107 @@ func TestNegotiationResponseRoundTrip(t *testing.T) {
 	}
 }
 
+func TestNegotiationResponseBidRoundTrip(t *testing.T) {
+	original := &core.NegotiationResponse{
+		RequestID: "req-abc",
+		Accepted:  true,
+		Bid:       &core.Bid{Cost: 2.5, ETA: int64(3 * time.Second), Confidence: 0.8},
+	}
+	encoded, err := original.Encode()
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	decoded, err := core.DecodeNegotiationResponse(encoded)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if decoded.Bid == nil || *decoded.Bid != *original.Bid {
+		t.Errorf("Bid: got %+v want %+v", decoded.Bid, original.Bid)
+	}
+}
+
+// ------------------------------------------------------------------ WorkflowMessage
+
+func TestIntentBatchRoundTrip(t *testing.T) {
+	agent, _ := core.NewAgent("a", []string{"nlp"})
+	i1, _ := core.CreateIntent(agent, []float32{0.5}, []string{"nlp"}, "one")
+	i2, _ := core.CreateIntent(agent, nil, []string{"ocr"}, "two")
+
+	data, err := (&core.IntentBatch{Intents: []*core.IntentMessage{i1, i2}}).Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	batch, err := core.DecodeIntentBatch(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batch.Intents) != 2 || batch.Intents[1].ID != i2.ID || batch.Intents[0].Payload != "one" {
+		t.Errorf("intents: %+v", batch.Intents)
+	}
+	if !core.VerifyIntentSignature(batch.Intents[0], agent.PublicKey()) {
+		t.Error("signature does not survive batching")
+	}
+
+	resps := &core.ResponseBatch{Responses: []*core.NegotiationResponse{
+		{RequestID: i1.ID, Accepted: true},
+		{RequestID: i2.ID, Reason: "missing capabilities"},
+	}}
+	data, err = resps.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := core.Decode(core.MsgResponseBatch, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := decoded.(*core.ResponseBatch)
+	if len(got.Responses) != 2 || !got.Responses[0].Accepted || got.Responses[1].Reason != "missing capabilities" {
+		t.Errorf("responses: %+v", got.Responses)
+	}
+}
+
+func TestWorkflowMessageRoundTrip(t *testing.T) {
+	original := &core.WorkflowMessage{
+		WorkflowID: "wf-1",
+		StepID:     "step-1",
+		NextStepID: "step-2",
+		AgentID:    "agent-gamma",
+		DID:        "did:agent-semantic-protocol:0123",
+		Action:     "summarise",
+		Params:     map[string]string{"lang": "en"},
+		ResultChan: "/results/wf-1",
+		Timestamp:  42,
+	}
+
+	encoded, err := original.Encode()
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	decoded, err := core.DecodeWorkflowMessage(encoded)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+
+	if decoded.WorkflowID != original.WorkflowID || decoded.StepID != original.StepID ||
+		decoded.NextStepID != original.NextStepID || decoded.Action != original.Action {
+		t.Errorf("ids/action mismatch: got %+v", decoded)
+	}
+	if decoded.Params["lang"] != "en" {
+		t.Errorf("Params[lang]: got %q want %q", decoded.Params["lang"], "en")
+	}
+	if decoded.ResultChan != original.ResultChan || decoded.Timestamp != original.Timestamp {
+		t.Errorf("ResultChan/Timestamp mismatch: got %+v", decoded)
+	}
+
+	generic, err := core.Decode(core.MsgWorkflow, encoded)
+	if err != nil {
+		t.Fatalf("core.Decode: %v", err)
+	}
+	if wf, ok := generic.(*core.WorkflowMessage); !ok || wf.StepID != "step-1" {
+		t.Errorf("core.Decode(MsgWorkflow): got %#v", generic)
+	}
+}
+
 // ------------------------------------------------------------------ framing
 
 func TestFrameUnframe(t *testing.T) {
@@ -300,4 +413,61 @@ func TestDiscoveryRegistry(t *testing.T) {
 	if len(results3) != 0 {
 		t.Errorf("FindByCapability(unknown): expected 0, got %d", len(results3))
 	}
+
+	reg.SetReachable("agent-2", false)
+	if got := reg.FindByCapability("nlp"); len(got) != 1 || got[0].AgentID != "agent-1" {
+		t.Errorf("FindByCapability(nlp) with agent-2 unreachable: %v", got)
+	}
+	if reg.Reachable("agent-2") || !reg.Reachable("agent-1") {
+		t.Error("Reachable does not reflect SetReachable")
+	}
+	reg.SetReachable("agent-2", true)
+	if got := reg.FindByCapability("code-gen"); len(got) != 1 {
+		t.Errorf("FindByCapability(code-gen) after reconnect: %v", got)
+	}
+}
+
+func TestDiscoveryRegistryAgentIDConflict(t *testing.T) {
+	reg := core.NewDiscoveryRegistry()
+	var conflicts []core.AgentIDConflict
+	reg.OnConflict(func(c core.AgentIDConflict) { conflicts = append(conflicts, c) })
+
+	honest := core.AgentProfile{AgentID: "agent-1", DID: "did:agent-semantic-protocol:aa", Capabilities: []string{"nlp"}}
+	spoof := core.AgentProfile{AgentID: "agent-1", DID: "did:agent-semantic-protocol:ff", Capabilities: []string{"payments"}}
+	reg.Announce(honest, 0)
+	reg.Announce(honest, 0) // Re-announcing is not a conflict
+	reg.Announce(spoof, 0)
+
+	if p, ok := reg.FindByDID(honest.DID); !ok || p.Capabilities[0] != "nlp" {
+		t.Errorf("honest entry overwritten: %+v, %v", p, ok)
+	}
+	if got := reg.FindByAgentID("agent-1"); len(got) != 2 || got[0].DID != honest.DID {
+		t.Errorf("FindByAgentID = %+v", got)
+	}
+	if got := reg.FindByCapability("nlp"); len(got) != 1 || got[0].DID != honest.DID {
+		t.Errorf("FindByCapability(nlp) = %+v", got)
+	}
+	if len(conflicts) != 1 || conflicts[0].DID != spoof.DID || len(conflicts[0].Others) != 1 || conflicts[0].Others[0] != honest.DID {
+		t.Errorf("conflicts = %+v", conflicts)
+	}
+	if got := reg.Conflicts(); len(got) != 1 || got[0] != "agent-1" {
+		t.Errorf("Conflicts() = %v", got)
+	}
+
+	// DIDs address one entry; AgentIDs address every claimant.
+	reg.SetReachable(spoof.DID, false)
+	if !reg.Reachable(honest.DID) || reg.Reachable(spoof.DID) || !reg.Reachable("agent-1") {
+		t.Error("SetReachable by DID affected the wrong entry")
+	}
+	reg.Remove(spoof.DID)
+	if got := reg.All(); len(got) != 1 || got[0].DID != honest.DID {
+		t.Errorf("after Remove(DID): %+v", got)
+	}
+	if len(reg.Conflicts()) != 0 {
+		t.Error("conflict outlived the spoofing entry")
+	}
+	reg.Remove("agent-1")
+	if len(reg.All()) != 0 {
+		t.Errorf("after Remove(AgentID): %+v", reg.All())
+	}
 }
@@ -0,0 +1,39 @@
+package core
+
+// errors.go — Errors callers can test for.
+//
+// Failures a caller may want to handle are reported with sentinel errors,
+// wrapped with context by fmt.Errorf's %w, so errors.Is finds them however
+// deeply they are wrapped.  Failures with structured detail use error types
+// that unwrap to a sentinel or a cause, so errors.As recovers the detail.
+//
+// Protocol failures:
+//
+//	ErrCapabilityMissing   no peer, or no usable peer, offers a capability
+//	ErrSignatureInvalid    a message, credential or challenge response does not verify
+//	ErrVersionMismatch     two peers share no protocol version
+//	ErrMeshMismatch        a peer belongs to another mesh
+//	ErrFrameTooLarge       a frame or its decoded payload exceeds MaxFrameSize
+//	ErrFrameChecksum       a v2 frame's checksum does not match
+//	ErrDecodeLimit         a decoded message exceeds DecodeLimits (*LimitError)
+//	ErrResultTooLarge      an inline result exceeds the negotiated limit
+//	ErrHopLimit            a forwarded intent ran out of hops
+//
+// Policy refusals: ErrCredentialRequired, ErrOverBudget, ErrAttestationPolicy,
+// ErrFederationDenied, ErrUntrustedIssuer, ErrConflictingRotation and
+// ErrSchemaViolation.  The p2p package adds transport failures, among them
+// ErrPeerUnreachable, ErrPeerDisconnected and ErrProtocolMismatch.
+
+import "fmt"
+
+// ErrCapabilityMissing is returned when no peer, or no usable peer, offers
+// a required capability.
+var ErrCapabilityMissing = fmt.Errorf("capability missing")
+
+// ErrSignatureInvalid is returned when a signature does not verify against
+// the key it claims.  Unsigned messages are reported separately.
+var ErrSignatureInvalid = fmt.Errorf("invalid signature")
+
+// ErrFrameTooLarge is returned for frames, and decompressed or dequantized
+// payloads, larger than MaxFrameSize.
+var ErrFrameTooLarge = fmt.Errorf("frame: too large")
@@ -0,0 +1,42 @@
+package core_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func TestSignatureErrors(t *testing.T) {
+	a, _ := core.NewAgent("a", nil)
+	att, _ := core.NewTrustAttestation(a, "did:x", 0.5, time.Hour)
+	att.Score = 1
+	rl, _ := core.NewRevocationList(a, 1, nil)
+	rl.Sequence = 2
+	alert, _ := core.NewAlert(a, core.AlertKeyCompromise, a.DID.String(), "")
+	alert.Detail = "forged"
+
+	for name, err := range map[string]error{
+		"attestation": core.VerifyTrustAttestation(att),
+		"revocation":  core.VerifyRevocationList(rl),
+		"alert":       core.VerifyAlert(alert),
+	} {
+		if !errors.Is(err, core.ErrSignatureInvalid) {
+			t.Errorf("%s: %v; want ErrSignatureInvalid", name, err)
+		}
+	}
+}
+
+func TestFrameTooLarge(t *testing.T) {
+	if _, err := core.FrameV2(byte(core.MsgIntent), make([]byte, core.MaxFrameSize), false); !errors.Is(err, core.ErrFrameTooLarge) {
+		t.Errorf("FrameV2: %v; want ErrFrameTooLarge", err)
+	}
+	var hdr [4]byte
+	binary.BigEndian.PutUint32(hdr[:], core.MaxFrameSize+1)
+	if _, _, _, err := core.ReadFrame(bytes.NewReader(hdr[:])); !errors.Is(err, core.ErrFrameTooLarge) {
+		t.Errorf("ReadFrame: %v; want ErrFrameTooLarge", err)
+	}
+}
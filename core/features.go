@@ -0,0 +1,70 @@
+package core
+
+// features.go — Optional subsystems advertised at handshake time.
+//
+// Besides the features a protocol version implies (see version.go), each
+// side of a handshake lists in HandshakeMessage.Features the optional
+// subsystems it is willing to use, so a host can avoid a streamed intent, a
+// compressed frame or a session the peer would not understand instead of
+// failing halfway through.  A handshake without Features comes from a peer
+// that predates the flags; it is assumed to support what such builds did,
+// LegacyFeatures.
+
+import "slices"
+
+// Optional subsystems a peer may advertise.
+const (
+	FeatureStreaming           Feature = "streaming"            // Answers intents with progress updates (StreamMetadataKey)
+	FeatureCompression         Feature = "compression"          // Reads compressed v2 frames
+	FeatureCounterOffers       Feature = "counter-offers"       // Bargains through CounterOffers
+	FeatureSignedAnnouncements Feature = "signed-announcements" // Signs and verifies CapabilityAnnouncements
+	FeatureSessions            Feature = "sessions"             // Accepts multiplexed session streams
+	FeatureIntentBatch         Feature = "intent-batch"         // Answers IntentBatches
+	FeatureQuantizedVectors    Feature = "quantized-vectors"    // Reads v2 frames with quantized vectors
+	FeaturePing                Feature = "ping"                 // Answers PingMessages
+)
+
+// DefaultFeatures returns the optional subsystems this build advertises.
+func DefaultFeatures() []Feature {
+	return []Feature{FeatureStreaming, FeatureCompression, FeatureCounterOffers, FeatureSignedAnnouncements, FeatureSessions, FeatureIntentBatch, FeatureQuantizedVectors, FeaturePing}
+}
+
+// LegacyFeatures returns the subsystems assumed of a peer whose handshake
+// carries no Features.
+func LegacyFeatures() []Feature {
+	return []Feature{FeatureStreaming, FeatureCompression, FeatureCounterOffers, FeatureSessions}
+}
+
+// FeatureNames returns fs as the strings carried in HandshakeMessage.Features.
+func FeatureNames(fs []Feature) []string {
+	out := make([]string, len(fs))
+	for i, f := range fs {
+		out[i] = string(f)
+	}
+	return out
+}
+
+// PeerFeatures returns the optional subsystems m's sender advertises.
+// Unknown names are kept, so newer flags survive a round trip.
+func PeerFeatures(m *HandshakeMessage) []Feature {
+	if len(m.Features) == 0 {
+		return LegacyFeatures()
+	}
+	out := make([]Feature, len(m.Features))
+	for i, name := range m.Features {
+		out[i] = Feature(name)
+	}
+	return out
+}
+
+// CommonFeatures returns the features in ours that theirs also lists, in
+// the order of ours.
+func CommonFeatures(ours, theirs []Feature) []Feature {
+	var out []Feature
+	for _, f := range ours {
+		if slices.Contains(theirs, f) {
+			out = append(out, f)
+		}
+	}
+	return out
+}
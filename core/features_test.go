@@ -0,0 +1,46 @@
+package core_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func TestHandshakeFeatures(t *testing.T) {
+	a, _ := core.NewAgent("a", nil)
+	b, _ := core.NewAgent("b", nil)
+
+	ours, _ := core.StartHandshake(a)
+	ours.Features = core.FeatureNames([]core.Feature{core.FeatureStreaming, "future-thing"})
+	data, err := ours.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := core.DecodeHandshakeMessage(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := core.PeerFeatures(decoded)
+	if !slices.Equal(got, []core.Feature{core.FeatureStreaming, "future-thing"}) {
+		t.Errorf("PeerFeatures = %v", got)
+	}
+
+	resp, err := core.RespondHandshake(b, decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := core.NewHandshakeResult(resp)
+	if !slices.Equal(res.PeerFeatures, core.DefaultFeatures()) {
+		t.Errorf("PeerFeatures = %v, want %v", res.PeerFeatures, core.DefaultFeatures())
+	}
+	if common := core.CommonFeatures(core.DefaultFeatures(), got); !slices.Equal(common, []core.Feature{core.FeatureStreaming}) {
+		t.Errorf("CommonFeatures = %v", common)
+	}
+
+	// A peer from before feature flags is assumed to have the legacy set.
+	resp.Features = nil
+	if got = core.PeerFeatures(resp); !slices.Equal(got, core.LegacyFeatures()) {
+		t.Errorf("legacy PeerFeatures = %v", got)
+	}
+}
@@ -0,0 +1,105 @@
+package core
+
+// federation.go — Federating independent meshes.
+//
+// Meshes are isolated by design (see mesh.go).  A federation gateway is one
+// agent with a host in each of two meshes.  Into one mesh it re-announces
+// the capabilities of the other that a FederationPolicy exports, tagged
+// with a ProvenanceTag, and it proxies the intents that arrive for them:
+// each is re-issued with ProxyIntent, signed by the gateway, to an agent of
+// the source mesh, and answered with ProxyResponse, signed by the gateway
+// again.  Neither mesh sees the other's DIDs or keys; the gateway vouches
+// for both sides, and the policy decides what crosses.
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// ReasonFederationDenied is the Reason of a NegotiationResponse from a
+// gateway whose FederationPolicy refuses to proxy the intent.
+const ReasonFederationDenied = "federation_denied"
+
+// ErrFederationDenied is returned when a FederationPolicy refuses an intent.
+var ErrFederationDenied = fmt.Errorf("federation: denied by policy")
+
+// FederationPolicy controls what a gateway lets across a mesh boundary.
+type FederationPolicy struct {
+	Capabilities []string // Capabilities exported; nothing is exported if empty
+	Requesters   []string // DIDs that may use them; anyone if empty
+}
+
+// Exports reports whether p exports capability.
+func (p *FederationPolicy) Exports(capability string) bool {
+	return slices.Contains(p.Capabilities, capability)
+}
+
+// Admit checks that p lets intent across: every capability it needs must
+// be exported and, if p lists requesters, its sender must be one of them.
+func (p *FederationPolicy) Admit(intent *IntentMessage) error {
+	for _, c := range intent.Capabilities {
+		if !p.Exports(c) {
+			return fmt.Errorf("%w: capability %q is not exported", ErrFederationDenied, c)
+		}
+	}
+	if len(p.Requesters) > 0 && !slices.Contains(p.Requesters, intent.DID) {
+		return fmt.Errorf("%w: requester %s", ErrFederationDenied, intent.DID)
+	}
+	return nil
+}
+
+// ProxyIntent re-issues intent, received by gateway, for the mesh that
+// serves it: the same capabilities, vector, payload, expiry and time bounds
+// under a new ID signed by gateway.
+func ProxyIntent(gateway *Agent, intent *IntentMessage) (*IntentMessage, error) {
+	proxied, err := CreateIntent(gateway, intent.IntentVector, intent.Capabilities, intent.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("federation: %w", err)
+	}
+	proxied.ExpiresAt = intent.ExpiresAt
+	proxied.Deadline, proxied.MaxLatency = intent.Deadline, intent.MaxLatency
+	if err = SignIntent(gateway, proxied); err != nil {
+		return nil, fmt.Errorf("federation: %w", err)
+	}
+	return proxied, nil
+}
+
+// ProxyResponse answers intent on behalf of gateway with the outcome of
+// resp, the answer to its proxied copy.  The trust delta the source mesh
+// suggested concerns the gateway and is not passed on.
+func ProxyResponse(gateway *Agent, intent *IntentMessage, resp *NegotiationResponse) (*NegotiationResponse, error) {
+	out := &NegotiationResponse{
+		RequestID:       intent.ID,
+		AgentID:         gateway.ID,
+		Accepted:        resp.Accepted,
+		WorkflowSteps:   slices.Clone(resp.WorkflowSteps),
+		DID:             gateway.DID.String(),
+		ResponseVector:  slices.Clone(resp.ResponseVector),
+		Timestamp:       time.Now().UnixNano(),
+		Reason:          resp.Reason,
+		SimilarityScore: resp.SimilarityScore,
+		RetryAfter:      resp.RetryAfter,
+		Result:          resp.Result,
+	}
+	if err := SignResponse(gateway, out); err != nil {
+		return nil, fmt.Errorf("federation: %w", err)
+	}
+	return out, nil
+}
+
+// Federated reports whether p was announced by a federation gateway on
+// behalf of another mesh.
+func (p *AgentProfile) Federated() bool { return len(p.Provenance) > 0 }
+
+func copyProvenance(tags []ProvenanceTag) []ProvenanceTag {
+	if tags == nil {
+		return nil
+	}
+	out := make([]ProvenanceTag, len(tags))
+	for i, tag := range tags {
+		tag.Capabilities = slices.Clone(tag.Capabilities)
+		out[i] = tag
+	}
+	return out
+}
@@ -0,0 +1,97 @@
+package core_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func TestFederationPolicy(t *testing.T) {
+	requester, _ := core.NewAgent("requester", nil)
+	stranger, _ := core.NewAgent("stranger", nil)
+	p := core.FederationPolicy{
+		Capabilities: []string{"translate", "summarize"},
+		Requesters:   []string{requester.DID.String()},
+	}
+
+	for _, tc := range []struct {
+		sender *core.Agent
+		caps   []string
+		ok     bool
+	}{
+		{requester, []string{"translate"}, true},
+		{requester, []string{"translate", "summarize"}, true},
+		{requester, []string{"translate", "billing"}, false},
+		{stranger, []string{"translate"}, false},
+	} {
+		intent, _ := core.CreateIntent(tc.sender, nil, tc.caps, "")
+		err := p.Admit(intent)
+		if (err == nil) != tc.ok {
+			t.Errorf("Admit(%s, %v) = %v, want ok=%v", tc.sender.ID, tc.caps, err, tc.ok)
+		}
+		if err != nil && !errors.Is(err, core.ErrFederationDenied) {
+			t.Errorf("Admit error %v does not wrap ErrFederationDenied", err)
+		}
+	}
+}
+
+func TestProxyIntent(t *testing.T) {
+	requester, _ := core.NewAgent("requester", nil)
+	gateway, _ := core.NewAgent("gateway", nil)
+	intent, _ := core.CreateIntent(requester, []float32{1, 0}, []string{"translate"}, "bonjour")
+	intent.ExpiresAt = 42
+
+	proxied, err := core.ProxyIntent(gateway, intent)
+	if err != nil {
+		t.Fatalf("ProxyIntent: %v", err)
+	}
+	if proxied.DID != gateway.DID.String() || proxied.ID == intent.ID || proxied.Payload != intent.Payload || proxied.ExpiresAt != 42 {
+		t.Fatalf("ProxyIntent = %+v", proxied)
+	}
+	if !core.VerifyIntentSignature(proxied, gateway.DID.PublicKey()) {
+		t.Fatal("proxied intent not signed by the gateway")
+	}
+
+	resp := &core.NegotiationResponse{
+		RequestID:  proxied.ID,
+		Accepted:   true,
+		Reason:     "done",
+		TrustDelta: 0.3,
+		Result:     &core.ResultPayload{ContentType: "text/plain", Data: []byte("hello")},
+	}
+	out, err := core.ProxyResponse(gateway, intent, resp)
+	if err != nil {
+		t.Fatalf("ProxyResponse: %v", err)
+	}
+	if out.RequestID != intent.ID || out.DID != gateway.DID.String() || !out.Accepted || out.TrustDelta != 0 {
+		t.Fatalf("ProxyResponse = %+v", out)
+	}
+	if !core.VerifyResponseSignature(out, gateway.DID.PublicKey()) {
+		t.Fatal("proxied response not signed by the gateway")
+	}
+}
+
+func TestAnnouncementProvenance(t *testing.T) {
+	gateway, _ := core.NewAgent("gateway", []string{"route"})
+	ann := core.BuildAnnouncement(gateway, 60)
+	ann.Capabilities = append(ann.Capabilities, "translate")
+	ann.Provenance = []core.ProvenanceTag{{Mesh: "partner", Gateway: gateway.DID.String(), Capabilities: []string{"translate"}}}
+
+	data, _ := ann.Encode()
+	decoded, err := core.DecodeCapabilityAnnouncement(data)
+	if err != nil {
+		t.Fatalf("DecodeCapabilityAnnouncement: %v", err)
+	}
+	if !reflect.DeepEqual(decoded.Provenance, ann.Provenance) {
+		t.Fatalf("Provenance = %+v, want %+v", decoded.Provenance, ann.Provenance)
+	}
+
+	r := core.NewDiscoveryRegistry()
+	r.AnnounceFromMessage(decoded)
+	profile, ok := r.FindByDID(gateway.DID.String())
+	if !ok || !profile.Federated() || profile.Provenance[0].Mesh != "partner" {
+		t.Fatalf("registered profile = %+v", profile)
+	}
+}
@@ -0,0 +1,173 @@
+package core
+
+// frame.go — Version 2 frames.
+//
+// A v2 frame adds a flags byte and a CRC-32C checksum to the v1 frame (see
+// Frame), and may carry a DEFLATE-compressed payload:
+//
+//	[0xA5] [flags] [4-byte BE length] [type byte] [payload] [4-byte BE CRC-32C]
+//
+// length counts the type byte and payload; the checksum covers both as
+// sent.  A v1 frame starts with the top byte of a length below
+// MaxFrameSize, which is always zero, so ReadFrame tells the two formats
+// apart by their first byte.  Receivers accept both; senders use v2 only
+// with peers whose negotiated version has FeatureFrameV2.
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// MaxFrameSize bounds the length field of a frame, and the decompressed
+// size of a v2 payload.
+const MaxFrameSize = 4 * 1024 * 1024
+
+// FrameV2Magic is the first byte of every v2 frame.
+const FrameV2Magic byte = 0xA5
+
+// FrameFlags are the bits of a v2 frame's flags byte.
+type FrameFlags byte
+
+const (
+	FrameCompressed     FrameFlags = 1 << iota // Payload is raw DEFLATE
+	FrameInt8Vectors                           // Vectors in the payload are int8 (see QuantizedVector)
+	FrameFloat16Vectors                        // Vectors in the payload are float16
+)
+
+// compressMin is the payload size from which FrameV2 tries compression.
+const compressMin = 1024
+
+// ErrFrameChecksum is returned when a v2 frame's checksum does not match.
+var ErrFrameChecksum = fmt.Errorf("frame: checksum mismatch")
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// FrameV2 builds a v2 frame.  With compress, payloads of 1 KiB or more are
+// compressed when that makes them smaller.
+func FrameV2(frameType byte, payload []byte, compress bool) ([]byte, error) {
+	return frameV2(frameType, 0, payload, compress)
+}
+
+// frameV2 is FrameV2 with further flags set.
+func frameV2(frameType byte, flags FrameFlags, payload []byte, compress bool) ([]byte, error) {
+	if compress && len(payload) >= compressMin {
+		var buf bytes.Buffer
+		w, _ := flate.NewWriter(&buf, flate.DefaultCompression)
+		if _, err := w.Write(payload); err != nil {
+			return nil, fmt.Errorf("frame: compress: %w", err)
+		}
+		if err := w.Close(); err != nil {
+			return nil, fmt.Errorf("frame: compress: %w", err)
+		}
+		if buf.Len() < len(payload) {
+			payload = buf.Bytes()
+			flags |= FrameCompressed
+		}
+	}
+	total := 1 + len(payload)
+	if total > MaxFrameSize {
+		return nil, fmt.Errorf("%w: %d bytes exceeds the %d byte limit", ErrFrameTooLarge, total, MaxFrameSize)
+	}
+	frame := make([]byte, 6+total+4)
+	frame[0] = FrameV2Magic
+	frame[1] = byte(flags)
+	binary.BigEndian.PutUint32(frame[2:6], uint32(total))
+	frame[6] = frameType
+	copy(frame[7:], payload)
+	binary.BigEndian.PutUint32(frame[6+total:], crc32.Checksum(frame[6:6+total], castagnoli))
+	return frame, nil
+}
+
+// FrameWithV2 encodes msg with c and frames it as v2.
+func FrameWithV2(c Codec, msg Encoder, compress bool) ([]byte, error) {
+	payload, err := c.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	return FrameV2(FrameType(msg.MsgType(), c.ID()), payload, compress)
+}
+
+// ReadFrame reads one v1 or v2 frame from r and returns its type byte (see
+// SplitFrameType), its payload, decompressed, and the frame version.
+func ReadFrame(r io.Reader) (frameType byte, payload []byte, version int, err error) {
+	var first [1]byte
+	if _, err = io.ReadFull(r, first[:]); err != nil {
+		return 0, nil, 0, fmt.Errorf("frame header: %w", err)
+	}
+	if first[0] != FrameV2Magic {
+		var rest [3]byte
+		if _, err = io.ReadFull(r, rest[:]); err != nil {
+			return 0, nil, 0, fmt.Errorf("frame header: %w", err)
+		}
+		n := int(binary.BigEndian.Uint32([]byte{first[0], rest[0], rest[1], rest[2]}))
+		body, err := readFrameBody(r, n)
+		if err != nil {
+			return 0, nil, 0, err
+		}
+		return body[0], body[1:], 1, nil
+	}
+
+	var hdr [5]byte
+	if _, err = io.ReadFull(r, hdr[:]); err != nil {
+		return 0, nil, 0, fmt.Errorf("frame header: %w", err)
+	}
+	flags := FrameFlags(hdr[0])
+	body, err := readFrameBody(r, int(binary.BigEndian.Uint32(hdr[1:])))
+	if err != nil {
+		return 0, nil, 0, err
+	}
+	var sum [4]byte
+	if _, err = io.ReadFull(r, sum[:]); err != nil {
+		return 0, nil, 0, fmt.Errorf("frame checksum: %w", err)
+	}
+	if binary.BigEndian.Uint32(sum[:]) != crc32.Checksum(body, castagnoli) {
+		return 0, nil, 0, ErrFrameChecksum
+	}
+	payload = body[1:]
+	if flags&FrameCompressed != 0 {
+		zr := flate.NewReader(bytes.NewReader(payload))
+		defer zr.Close()
+		payload, err = io.ReadAll(io.LimitReader(zr, MaxFrameSize+1))
+		if err != nil {
+			return 0, nil, 0, fmt.Errorf("frame: decompress: %w", err)
+		}
+		if len(payload) > MaxFrameSize {
+			return 0, nil, 0, fmt.Errorf("%w: decompressed payload exceeds %d bytes", ErrFrameTooLarge, MaxFrameSize)
+		}
+	}
+	q, err := frameQuantization(flags)
+	if err != nil {
+		return 0, nil, 0, err
+	}
+	if q != QuantizeNone {
+		msgType, codec := SplitFrameType(body[0])
+		if codec != CodecProtobuf {
+			return 0, nil, 0, fmt.Errorf("frame: quantized vectors in a codec %d payload", codec)
+		}
+		if payload, err = dequantizePayload(nil, msgType, payload, q); err != nil {
+			return 0, nil, 0, fmt.Errorf("frame: %w", err)
+		}
+		if len(payload) > MaxFrameSize {
+			return 0, nil, 0, fmt.Errorf("%w: dequantized payload exceeds %d bytes", ErrFrameTooLarge, MaxFrameSize)
+		}
+	}
+	return body[0], payload, 2, nil
+}
+
+func readFrameBody(r io.Reader, n int) ([]byte, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("frame: invalid length %d", n)
+	}
+	if n > MaxFrameSize {
+		return nil, fmt.Errorf("%w: length %d exceeds %d", ErrFrameTooLarge, n, MaxFrameSize)
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, fmt.Errorf("frame body: %w", err)
+	}
+	return body, nil
+}
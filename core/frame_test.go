@@ -0,0 +1,51 @@
+package core_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func TestReadFrameDualStack(t *testing.T) {
+	a, _ := core.NewAgent("a", []string{"nlp"})
+	intent, _ := core.CreateIntent(a, nil, []string{"nlp"}, strings.Repeat("compressible ", 200))
+	want, _ := intent.Encode()
+
+	v1, _ := core.FrameWith(core.ProtobufCodec, intent)
+	v2, err := core.FrameWithV2(core.ProtobufCodec, intent, true)
+	if err != nil {
+		t.Fatalf("FrameWithV2: %v", err)
+	}
+	if v2[0] != core.FrameV2Magic || core.FrameFlags(v2[1])&core.FrameCompressed == 0 || len(v2) >= len(v1) {
+		t.Errorf("v2 frame not compressed: %d bytes vs %d", len(v2), len(v1))
+	}
+
+	stream := bytes.NewReader(append(append([]byte(nil), v1...), v2...))
+	for _, wantVersion := range []int{1, 2} {
+		typ, payload, version, err := core.ReadFrame(stream)
+		if err != nil {
+			t.Fatalf("ReadFrame v%d: %v", wantVersion, err)
+		}
+		if mt, _ := core.SplitFrameType(typ); mt != core.MsgIntent || version != wantVersion || !bytes.Equal(payload, want) {
+			t.Errorf("v%d: type %v version %d, payload equal %v", wantVersion, mt, version, bytes.Equal(payload, want))
+		}
+	}
+
+	v2[len(v2)-5] ^= 0xff
+	if _, _, _, err = core.ReadFrame(bytes.NewReader(v2)); !errors.Is(err, core.ErrFrameChecksum) {
+		t.Errorf("corrupted frame: got %v, want ErrFrameChecksum", err)
+	}
+}
+
+func TestFrameV2SmallPayloadUncompressed(t *testing.T) {
+	f, err := core.FrameV2(byte(core.MsgIntent), []byte("tiny"), true)
+	if err != nil || core.FrameFlags(f[1]) != 0 {
+		t.Fatalf("flags %v, err %v", f[1], err)
+	}
+	if _, p, _, err := core.ReadFrame(bytes.NewReader(f)); err != nil || string(p) != "tiny" {
+		t.Errorf("ReadFrame: %q %v", p, err)
+	}
+}
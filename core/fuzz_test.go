@@ -0,0 +1,105 @@
+package core_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+// fuzzSeeds returns a populated message of every type.
+func fuzzSeeds(f *testing.F) []core.Encoder {
+	f.Helper()
+	a, err := core.NewAgent("a", []string{"nlp"})
+	if err != nil {
+		f.Fatal(err)
+	}
+	intent, _ := core.CreateIntent(a, []float32{0.25, -1, 3e-8}, []string{"nlp"}, "summarise")
+	intent.Metadata["k"] = "v"
+	hs, _ := core.StartHandshake(a)
+	resp, _ := core.DefaultNegotiationHandler(a)(intent)
+	rl, _ := core.NewRevocationList(a, 3, []core.RevocationEntry{{DID: "did:x", Reason: "lost", RevokedAt: 1 << 40}})
+	att, _ := core.NewTrustAttestation(a, "did:x", 0.5, 0)
+	_, rot, _ := a.Rotate("")
+	return []core.Encoder{
+		intent,
+		hs,
+		resp,
+		&core.WorkflowMessage{WorkflowID: "wf", StepID: "s1", Params: map[string]string{"x": "1"}, Timestamp: 1},
+		&core.CapabilityAnnouncement{AgentID: "a", DID: a.DID.String(), Capabilities: []string{"nlp"}, TTL: 60},
+		&core.CounterOffer{RequestID: "r", Round: 2, Action: core.CounterPropose, Terms: core.OfferTerms{Price: 1.5}},
+		&core.AlertMessage{ID: "al", Kind: core.AlertKeyCompromise, Subject: "did:x", Hops: 4},
+		rl,
+		att,
+		&core.ForwardEnvelope{Intent: intent, RelayDID: "did:r", Path: []string{"did:r"}, Hops: 1},
+		&core.IntentBatch{Intents: []*core.IntentMessage{intent, intent}},
+		&core.ResponseBatch{Responses: []*core.NegotiationResponse{resp}},
+		&core.PingMessage{Nonce: []byte{1, 2, 3}, SentAt: 7},
+		&core.PongMessage{Nonce: []byte{1, 2, 3}, SentAt: 7, RepliedAt: 8},
+		&core.WorkflowResult{WorkflowID: "wf", StepID: "s1", Status: core.WorkflowStatusCompleted, Timestamp: 9},
+		rot,
+		&core.ObserveRequest{Kinds: []string{"trust_updated", "intent_rejected"}},
+		&core.EventMessage{Kind: "trust_updated", DID: "did:x", Delta: 0.1, Trust: 0.6, Timestamp: 10, Dropped: 2},
+	}
+}
+
+// FuzzDecode feeds every protobuf decoder arbitrary bytes.  Whatever
+// decodes must encode again and decode to a message that does too.
+func FuzzDecode(f *testing.F) {
+	for _, m := range fuzzSeeds(f) {
+		data, err := m.Encode()
+		if err != nil {
+			f.Fatalf("%T: %v", m, err)
+		}
+		f.Add(byte(m.MsgType()), data)
+		f.Add(byte(m.MsgType()), data[:len(data)/2])
+	}
+	f.Fuzz(func(t *testing.T, typ byte, data []byte) {
+		msg, err := core.Decode(core.MessageType(typ), data)
+		if err != nil {
+			return
+		}
+		again, err := msg.(core.Encoder).Encode()
+		if err != nil {
+			t.Fatalf("re-encoding %T: %v", msg, err)
+		}
+		if _, err = core.Decode(core.MessageType(typ), again); err != nil {
+			t.Fatalf("re-decoding %T: %v", msg, err)
+		}
+	})
+}
+
+// FuzzDecodeFrame feeds arbitrary frames to every codec.
+func FuzzDecodeFrame(f *testing.F) {
+	for _, m := range fuzzSeeds(f) {
+		for _, c := range []core.Codec{core.ProtobufCodec, core.JSONCodec, core.CBORCodec} {
+			frame, err := core.FrameWith(c, m)
+			if err != nil {
+				f.Fatalf("%T/%s: %v", m, c.Name(), err)
+			}
+			f.Add(frame)
+		}
+	}
+	f.Fuzz(func(t *testing.T, frame []byte) {
+		_, _ = core.DecodeFrame(frame)
+	})
+}
+
+// FuzzReadFrame feeds arbitrary streams to the v1 and v2 frame reader,
+// which also decompresses and dequantizes payloads.
+func FuzzReadFrame(f *testing.F) {
+	for _, m := range fuzzSeeds(f) {
+		payload, _ := m.Encode()
+		f.Add(core.Frame(m.MsgType(), payload))
+		for _, compress := range []bool{false, true} {
+			frame, err := core.FrameV2(byte(m.MsgType()), payload, compress)
+			if err != nil {
+				f.Fatalf("%T: %v", m, err)
+			}
+			f.Add(frame)
+		}
+	}
+	f.Fuzz(func(t *testing.T, stream []byte) {
+		_, _, _, _ = core.ReadFrame(bytes.NewReader(stream))
+	})
+}
@@ -12,9 +12,8 @@ package core
 //       |         [capabilities exchanged] |
 
 import (
-	"crypto/rand"
 	"fmt"
-	"io"
+	"slices"
 	"time"
 )
 
@@ -24,9 +23,10 @@ const challengeSize = 32 // bytes
 // It embeds a random challenge nonce that the responder must sign.
 func StartHandshake(agent *Agent) (*HandshakeMessage, error) {
 	nonce := make([]byte, challengeSize)
-	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+	if err := agent.random(nonce); err != nil {
 		return nil, fmt.Errorf("handshake: nonce generation: %w", err)
 	}
+	kx, kxSig := agent.signKeyAgreement()
 	return &HandshakeMessage{
 		AgentID:      agent.ID,
 		DID:          agent.DID.String(),
@@ -35,19 +35,31 @@ func StartHandshake(agent *Agent) (*HandshakeMessage, error) {
 		Timestamp:    time.Now().UnixNano(),
 		PublicKey:    agent.PublicKey(),
 		Challenge:    nonce,
+		Manifest:     copyManifest(agent.Manifest),
+		Versions:     SupportedVersions(),
+		Specs:        copySpecs(agent.Specs),
+		Features:     FeatureNames(DefaultFeatures()),
+		Credentials:  slices.Clone(agent.Credentials),
+
+		KeyAgreement:    kx,
+		KeyAgreementSig: kxSig,
+		MaxResultSize:   DefaultMaxResultSize,
 	}, nil
 }
 
 // RespondHandshake processes an incoming HandshakeMessage and builds the
-// response.  It verifies the sender's DID/key binding and signs the nonce.
+// response.  It verifies the sender's DID/key binding, selects the protocol
+// version (failing with ErrVersionMismatch) and signs the nonce.
 func RespondHandshake(responder *Agent, incoming *HandshakeMessage) (*HandshakeMessage, error) {
-	// Verify DID binding: the embedded public key must hash to the claimed DID.
-	peerDID, err := ParseDID(incoming.DID)
+	receivedAt := time.Now().UnixNano()
+
+	version, err := NegotiateVersion(OfferedVersions(incoming))
 	if err != nil {
-		return nil, fmt.Errorf("handshake: peer DID invalid: %w", err)
+		return nil, err
 	}
-	if !peerDID.ValidateBinding(incoming.PublicKey) {
-		return nil, fmt.Errorf("handshake: DID/key binding mismatch for %s", incoming.AgentID)
+
+	if err = CheckPeerIdentity(incoming); err != nil {
+		return nil, fmt.Errorf("handshake: %w", err)
 	}
 
 	// Sign the peer's challenge with our private key.
@@ -58,25 +70,66 @@ func RespondHandshake(responder *Agent, incoming *HandshakeMessage) (*HandshakeM
 
 	// Generate our own challenge.
 	nonce := make([]byte, challengeSize)
-	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+	if err := responder.random(nonce); err != nil {
 		return nil, fmt.Errorf("handshake: nonce generation: %w", err)
 	}
 
+	kx, kxSig := responder.signKeyAgreement()
+	var codecs []string
+	if len(incoming.Codecs) > 0 {
+		codecs = []string{version.NegotiateCodec(incoming.Codecs).Name()}
+	}
+
 	return &HandshakeMessage{
 		AgentID:           responder.ID,
 		DID:               responder.DID.String(),
 		Capabilities:      responder.Capabilities,
-		Version:           ProtocolVersion,
+		Version:           version.Version,
 		Timestamp:         time.Now().UnixNano(),
 		PublicKey:         responder.PublicKey(),
 		Challenge:         nonce,
 		ChallengeResponse: sig,
+		Manifest:          copyManifest(responder.Manifest),
+		EchoTimestamp:     incoming.Timestamp,
+		ReceivedAt:        receivedAt,
+		Codecs:            codecs,
+		Specs:             copySpecs(responder.Specs),
+		KeyAgreement:      kx,
+		KeyAgreementSig:   kxSig,
+		Features:          FeatureNames(DefaultFeatures()),
+		MaxResultSize:     DefaultMaxResultSize,
+		Credentials:       slices.Clone(responder.Credentials),
 	}, nil
 }
 
+// CheckPeerIdentity verifies what a peer's HandshakeMessage claims about
+// itself: the embedded public key must hash to the claimed DID, and the
+// key-agreement key and credentials must be signed by it.  Hosts must check
+// it before trusting the message's key, whoever builds the reply.
+func CheckPeerIdentity(m *HandshakeMessage) error {
+	peerDID, err := ParseDID(m.DID)
+	if err != nil {
+		return fmt.Errorf("peer DID invalid: %w", err)
+	}
+	if !peerDID.ValidateBinding(m.PublicKey) {
+		return fmt.Errorf("DID/key binding mismatch for %s", m.AgentID)
+	}
+	if _, err = PeerKeyAgreement(m); err != nil {
+		return err
+	}
+	return VerifyPeerCredentials(m.DID, m.Credentials)
+}
+
 // FinishHandshake verifies the responder's signature over our original challenge.
 // originalChallenge is the nonce sent in the initiator's HandshakeMessage.
+// A response without a challenge response fails with ErrSignatureInvalid.
 func FinishHandshake(originalChallenge []byte, response *HandshakeMessage) error {
+	if _, err := AcceptedVersion(response); err != nil {
+		return err
+	}
+	if len(response.ChallengeResponse) == 0 {
+		return fmt.Errorf("handshake finish: %s sent no challenge response: %w", response.AgentID, ErrSignatureInvalid)
+	}
 	peerDID, err := ParseDID(response.DID)
 	if err != nil {
 		return fmt.Errorf("handshake finish: peer DID invalid: %w", err)
@@ -91,7 +144,13 @@ func FinishHandshake(originalChallenge []byte, response *HandshakeMessage) error
 		return fmt.Errorf("handshake finish: invalid public key: %w", err)
 	}
 	if !d.Verify(originalChallenge, response.ChallengeResponse) {
-		return fmt.Errorf("handshake finish: challenge signature invalid for %s", response.AgentID)
+		return fmt.Errorf("handshake finish: challenge response from %s: %w", response.AgentID, ErrSignatureInvalid)
+	}
+	if _, err = PeerKeyAgreement(response); err != nil {
+		return fmt.Errorf("handshake finish: %w", err)
+	}
+	if err = VerifyPeerCredentials(response.DID, response.Credentials); err != nil {
+		return fmt.Errorf("handshake finish: %w", err)
 	}
 	return nil
 }
@@ -102,6 +161,12 @@ type HandshakeResult struct {
 	PeerDID          string
 	PeerCapabilities []string
 	PeerPublicKey    []byte
+	PeerManifest     []CapabilityDescriptor
+	PeerSpecs        []CapabilitySpec
+	PeerKeyAgreement []byte    // X25519 key for sealed payloads; nil if not offered
+	PeerFeatures     []Feature // Optional subsystems the peer supports
+	PeerMaxResult    int64     // Largest inline result the peer accepts; zero for none
+	PeerCredentials  []VerifiableCredential
 	ProtocolVersion  string
 	CompletedAt      time.Time
 }
@@ -116,6 +181,12 @@ func NewHandshakeResult(resp *HandshakeMessage) HandshakeResult {
 		PeerDID:          resp.DID,
 		PeerCapabilities: caps,
 		PeerPublicKey:    append([]byte(nil), resp.PublicKey...),
+		PeerManifest:     copyManifest(resp.Manifest),
+		PeerSpecs:        copySpecs(resp.Specs),
+		PeerKeyAgreement: append([]byte(nil), resp.KeyAgreement...),
+		PeerFeatures:     PeerFeatures(resp),
+		PeerMaxResult:    resp.MaxResultSize,
+		PeerCredentials:  slices.Clone(resp.Credentials),
 		ProtocolVersion:  resp.Version,
 		CompletedAt:      time.Now(),
 	}
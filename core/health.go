@@ -0,0 +1,81 @@
+package core
+
+// health.go — The built-in health capability.
+//
+// An agent that exposes HealthCapability answers a health intent with a
+// HealthStatus describing itself.  The status travels as JSON in the
+// response's Reason, which the response signature covers, so a sweep
+// across the mesh needs no infrastructure beyond the protocol itself.
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// HealthCapability is the capability of agents that report their health.
+const HealthCapability = "symplex.health"
+
+// HealthStatus is an agent's report on itself.
+type HealthStatus struct {
+	AgentID       string        `json:"agent_id"`
+	DID           string        `json:"did"`
+	Version       string        `json:"version"`        // Protocol version
+	UptimeSeconds float64       `json:"uptime_seconds"` // Since the host started
+	Executing     int           `json:"executing"`      // Intents being served
+	Queued        int           `json:"queued"`         // Intents waiting for a slot
+	Peers         int           `json:"peers"`          // Connected peers
+	Draining      bool          `json:"draining,omitempty"`
+	LastErrors    []HealthError `json:"last_errors,omitempty"` // Most recent last
+	CheckedAt     time.Time     `json:"checked_at"`
+}
+
+// HealthError is one recent failure reported in a HealthStatus.
+type HealthError struct {
+	At    time.Time `json:"at"`
+	Op    string    `json:"op"` // What failed, e.g. "read"
+	Error string    `json:"error"`
+}
+
+// NewHealthIntent creates a signed health intent from sender.
+func NewHealthIntent(sender *Agent) (*IntentMessage, error) {
+	return CreateIntent(sender, nil, []string{HealthCapability}, "")
+}
+
+// IsHealthIntent reports whether intent asks for the responder's health.
+func IsHealthIntent(intent *IntentMessage) bool {
+	return len(intent.Capabilities) == 1 && intent.Capabilities[0] == HealthCapability
+}
+
+// HealthResponse answers intent with status, signed by agent.
+func HealthResponse(agent *Agent, intent *IntentMessage, status HealthStatus) (*NegotiationResponse, error) {
+	doc, err := json.Marshal(status)
+	if err != nil {
+		return nil, fmt.Errorf("health: %w", err)
+	}
+	resp := &NegotiationResponse{
+		RequestID: intent.ID,
+		AgentID:   agent.ID,
+		Accepted:  true,
+		DID:       agent.DID.String(),
+		Timestamp: now(),
+		Reason:    string(doc),
+	}
+	if err = SignResponse(agent, resp); err != nil {
+		return nil, fmt.Errorf("health: %w", err)
+	}
+	return resp, nil
+}
+
+// ParseHealthResponse extracts the HealthStatus from the answer to a health
+// intent.
+func ParseHealthResponse(resp *NegotiationResponse) (*HealthStatus, error) {
+	if !resp.Accepted {
+		return nil, fmt.Errorf("health: %s refused: %s", resp.AgentID, resp.Reason)
+	}
+	var s HealthStatus
+	if err := json.Unmarshal([]byte(resp.Reason), &s); err != nil {
+		return nil, fmt.Errorf("health: %s: %w", resp.AgentID, err)
+	}
+	return &s, nil
+}
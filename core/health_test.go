@@ -0,0 +1,43 @@
+package core_test
+
+import (
+	"testing"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func TestHealthResponse(t *testing.T) {
+	asker, _ := core.NewAgent("asker", nil)
+	agent, _ := core.NewAgent("agent", []string{core.HealthCapability})
+
+	intent, err := core.NewHealthIntent(asker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !core.IsHealthIntent(intent) {
+		t.Fatal("health intent not recognised")
+	}
+	resp, err := core.HealthResponse(agent, intent, core.HealthStatus{
+		AgentID:    agent.ID,
+		Version:    core.ProtocolVersion,
+		Executing:  2,
+		LastErrors: []core.HealthError{{Op: "read", Error: "stream reset"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !core.VerifyResponseSignature(resp, agent.PublicKey()) {
+		t.Error("health response signature does not verify")
+	}
+	status, err := core.ParseHealthResponse(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Executing != 2 || len(status.LastErrors) != 1 || status.LastErrors[0].Op != "read" {
+		t.Errorf("status: %+v", status)
+	}
+
+	if _, err = core.ParseHealthResponse(&core.NegotiationResponse{Reason: "missing capabilities"}); err == nil {
+		t.Error("expected an error for a refused health intent")
+	}
+}
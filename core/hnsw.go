@@ -0,0 +1,301 @@
+package core
+
+// hnsw.go — Approximate nearest-neighbour index over embedding vectors.
+//
+// DiscoveryRegistry.FindBySimilarity would otherwise compare the query with
+// every registered vector.  vectorIndex is a Hierarchical Navigable Small
+// World graph (Malkov & Yashunin, 2016): every vector is a node linked to
+// its closest neighbours on layer 0 and, with geometrically falling
+// probability, on sparser layers above.  A search descends greedily from
+// the top layer and finishes with a beam search of width ef on layer 0, so
+// it visits O(log n) nodes instead of n.
+//
+// Vectors are stored normalised, so cosine similarity is a dot product.
+// Removing a node links its neighbours to each other to keep the graph
+// connected; links to removed nodes left elsewhere are skipped by searches
+// and dropped as the nodes holding them are relinked.  Once removed nodes
+// outnumber live ones the graph is rebuilt.
+
+import (
+	"container/heap"
+	"math"
+	"math/rand"
+	"slices"
+	"sort"
+)
+
+const (
+	hnswM              = 16  // Links per node on upper layers
+	hnswM0             = 32  // Links per node on layer 0
+	hnswEfConstruction = 100 // Beam width while inserting
+	hnswEfSearch       = 64  // Minimum beam width while searching
+)
+
+// hnswLevelMult scales the exponential distribution of node levels.
+var hnswLevelMult = 1 / math.Log(hnswM)
+
+type hnswNode struct {
+	key   string
+	vec   []float32 // Normalised
+	links [][]int   // Neighbour IDs per layer, 0 first
+}
+
+// vectorIndex is an HNSW graph over vectors of one dimension.  It is not
+// safe for concurrent writes; searches may run concurrently with each
+// other.
+type vectorIndex struct {
+	nodes    []*hnswNode // Indexed by node ID; nil once removed
+	ids      map[string]int
+	entry    int // Node ID searches start from; -1 when empty
+	maxLevel int
+	dead     int // Removed slots in nodes
+	rng      *rand.Rand
+}
+
+// simItem is a node and its similarity to a query.
+type simItem struct {
+	id  int
+	sim float64
+}
+
+func newVectorIndex() *vectorIndex {
+	return &vectorIndex{ids: make(map[string]int), entry: -1, rng: rand.New(rand.NewSource(1))}
+}
+
+// Len returns the number of vectors indexed.
+func (x *vectorIndex) Len() int { return len(x.ids) }
+
+// Insert indexes v under key, replacing any vector already there.  Zero
+// vectors are not indexed.
+func (x *vectorIndex) Insert(key string, v []float32) {
+	x.Remove(key)
+	q := normalised(v)
+	if q == nil {
+		return
+	}
+	level := int(-math.Log(1-x.rng.Float64()) * hnswLevelMult)
+	id := len(x.nodes)
+	x.nodes = append(x.nodes, &hnswNode{key: key, vec: q, links: make([][]int, level+1)})
+	x.ids[key] = id
+	if x.entry < 0 {
+		x.entry, x.maxLevel = id, level
+		return
+	}
+
+	ep := []simItem{{x.entry, x.sim(q, x.entry)}}
+	for l := x.maxLevel; l > level; l-- {
+		ep = x.searchLayer(q, ep, 1, l)[:1]
+	}
+	for l := min(level, x.maxLevel); l >= 0; l-- {
+		found := x.searchLayer(q, ep, hnswEfConstruction, l)
+		neighbours := found[:min(len(found), maxLinks(l))]
+		for _, n := range neighbours {
+			x.nodes[id].links[l] = append(x.nodes[id].links[l], n.id)
+			x.link(n.id, id, l)
+		}
+		ep = found
+	}
+	if level > x.maxLevel {
+		x.entry, x.maxLevel = id, level
+	}
+}
+
+// Remove drops the vector indexed under key, if any.
+func (x *vectorIndex) Remove(key string) {
+	id, ok := x.ids[key]
+	if !ok {
+		return
+	}
+	node := x.nodes[id]
+	delete(x.ids, key)
+	x.nodes[id] = nil
+	x.dead++
+	if len(x.ids) == 0 {
+		*x = vectorIndex{ids: x.ids, entry: -1, rng: x.rng}
+		return
+	}
+	if x.dead > len(x.ids) {
+		x.rebuild()
+		return
+	}
+
+	// Link the node's neighbours to each other in its place.
+	for l, links := range node.links {
+		for _, n := range links {
+			if x.nodes[n] == nil {
+				continue
+			}
+			x.unlink(n, id, l)
+			for _, m := range links {
+				if m != n && x.nodes[m] != nil {
+					x.link(n, m, l)
+				}
+			}
+		}
+	}
+	if id == x.entry {
+		x.entry, x.maxLevel = -1, -1
+		for i, n := range x.nodes {
+			if n != nil && len(n.links)-1 > x.maxLevel {
+				x.entry, x.maxLevel = i, len(n.links)-1
+			}
+		}
+	}
+}
+
+// Search returns up to ef indexed keys most similar to v, most similar
+// first, with their cosine similarity.  Larger ef finds the true nearest
+// neighbours more reliably.
+func (x *vectorIndex) Search(v []float32, ef int) []simItem {
+	if x.entry < 0 {
+		return nil
+	}
+	q := normalised(v)
+	if q == nil {
+		return nil
+	}
+	ep := []simItem{{x.entry, x.sim(q, x.entry)}}
+	for l := x.maxLevel; l > 0; l-- {
+		ep = x.searchLayer(q, ep, 1, l)[:1]
+	}
+	return x.searchLayer(q, ep, max(ef, hnswEfSearch), 0)
+}
+
+// Key returns the key of node id.
+func (x *vectorIndex) Key(id int) string { return x.nodes[id].key }
+
+// searchLayer is a beam search of width ef on layer l from ep, returning
+// the closest nodes found, most similar first.
+func (x *vectorIndex) searchLayer(q []float32, ep []simItem, ef, l int) []simItem {
+	visited := make(map[int]struct{}, ef*4)
+	cand := &simHeap{max: true}
+	res := &simHeap{}
+	for _, e := range ep {
+		visited[e.id] = struct{}{}
+		heap.Push(cand, e)
+		heap.Push(res, e)
+	}
+	for cand.Len() > 0 {
+		c := heap.Pop(cand).(simItem)
+		if res.Len() >= ef && c.sim < res.items[0].sim {
+			break
+		}
+		for _, n := range x.nodes[c.id].links[l] {
+			if _, seen := visited[n]; seen || x.nodes[n] == nil {
+				continue
+			}
+			visited[n] = struct{}{}
+			s := x.sim(q, n)
+			if res.Len() < ef || s > res.items[0].sim {
+				heap.Push(cand, simItem{n, s})
+				heap.Push(res, simItem{n, s})
+				if res.Len() > ef {
+					heap.Pop(res)
+				}
+			}
+		}
+	}
+	out := res.items
+	sort.Slice(out, func(i, j int) bool { return out[i].sim > out[j].sim })
+	return out
+}
+
+// link adds a link from n to m on layer l, keeping only the maxLinks(l)
+// neighbours most similar to n.
+func (x *vectorIndex) link(n, m, l int) {
+	node := x.nodes[n]
+	if slices.Contains(node.links[l], m) {
+		return
+	}
+	links := node.links[l][:0]
+	for _, o := range node.links[l] {
+		if x.nodes[o] != nil {
+			links = append(links, o)
+		}
+	}
+	links = append(links, m)
+	if len(links) > maxLinks(l) {
+		scored := make([]simItem, len(links))
+		for i, o := range links {
+			scored[i] = simItem{o, x.sim(node.vec, o)}
+		}
+		sort.Slice(scored, func(i, j int) bool { return scored[i].sim > scored[j].sim })
+		links = links[:maxLinks(l)]
+		for i := range links {
+			links[i] = scored[i].id
+		}
+	}
+	node.links[l] = links
+}
+
+// unlink removes the link from n to m on layer l.
+func (x *vectorIndex) unlink(n, m, l int) {
+	links := x.nodes[n].links[l]
+	for i, o := range links {
+		if o == m {
+			x.nodes[n].links[l] = append(links[:i], links[i+1:]...)
+			return
+		}
+	}
+}
+
+// rebuild re-inserts every live node into a fresh graph.
+func (x *vectorIndex) rebuild() {
+	live := make([]*hnswNode, 0, len(x.ids))
+	for _, n := range x.nodes {
+		if n != nil {
+			live = append(live, n)
+		}
+	}
+	*x = vectorIndex{ids: make(map[string]int, len(live)), entry: -1, rng: x.rng}
+	for _, n := range live {
+		x.Insert(n.key, n.vec)
+	}
+}
+
+func (x *vectorIndex) sim(q []float32, id int) float64 {
+	dot, _, _ := dotNorms(q, x.nodes[id].vec)
+	return dot
+}
+
+func maxLinks(l int) int {
+	if l == 0 {
+		return hnswM0
+	}
+	return hnswM
+}
+
+// normalised returns v scaled to unit length, or nil if v is zero.
+func normalised(v []float32) []float32 {
+	_, n, _ := dotNorms(v, v)
+	if n == 0 {
+		return nil
+	}
+	scale := 1 / math.Sqrt(n)
+	out := make([]float32, len(v))
+	for i, f := range v {
+		out[i] = float32(float64(f) * scale)
+	}
+	return out
+}
+
+// simHeap is a heap of simItems, least similar on top unless max is set.
+type simHeap struct {
+	items []simItem
+	max   bool
+}
+
+func (h *simHeap) Len() int { return len(h.items) }
+func (h *simHeap) Less(i, j int) bool {
+	if h.max {
+		return h.items[i].sim > h.items[j].sim
+	}
+	return h.items[i].sim < h.items[j].sim
+}
+func (h *simHeap) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
+func (h *simHeap) Push(x any)    { h.items = append(h.items, x.(simItem)) }
+func (h *simHeap) Pop() any {
+	x := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return x
+}
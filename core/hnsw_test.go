@@ -0,0 +1,112 @@
+package core_test
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func announceAll(r *core.DiscoveryRegistry, agents []core.AgentProfile) {
+	for _, a := range agents {
+		a.DID = "did:agent-semantic-protocol:" + a.AgentID
+		r.Announce(a, 0)
+	}
+}
+
+// recall returns the fraction of the true k nearest agents to q, among
+// live, that FindBySimilarity returns.
+func recall(r *core.DiscoveryRegistry, live []core.AgentProfile, q []float32, k int) float64 {
+	want := core.TopK(q, live, k)
+	got := r.FindBySimilarity(q, k, -1)
+	hit := 0
+	for _, w := range want {
+		if slices.ContainsFunc(got, func(p core.AgentProfile) bool { return p.AgentID == w.AgentID }) {
+			hit++
+		}
+	}
+	return float64(hit) / float64(len(want))
+}
+
+func TestFindBySimilarity(t *testing.T) {
+	rng := rand.New(rand.NewSource(5))
+	agents := registry(rng, 2000, 32)
+	r := core.NewDiscoveryRegistry()
+	announceAll(r, agents)
+
+	var live []core.AgentProfile
+	for _, a := range agents {
+		if a.EmbeddingVector != nil {
+			live = append(live, a)
+		}
+	}
+	var total float64
+	for i := 0; i < 50; i++ {
+		total += recall(r, live, randVector(rng, 32), 10)
+	}
+	if avg := total / 50; avg < 0.95 {
+		t.Errorf("recall@10 = %.3f, want >= 0.95", avg)
+	}
+
+	// An agent's own vector finds it first.
+	if got := r.FindBySimilarity(live[7].EmbeddingVector, 1, 0.99); len(got) != 1 || got[0].AgentID != live[7].AgentID {
+		t.Errorf("FindBySimilarity(own vector) = %v, want %s", got, live[7].AgentID)
+	}
+	// Nothing is that similar to a vector pointing away from everyone.
+	if got := r.FindBySimilarity(randVector(rng, 32), 5, 0.999); len(got) != 0 {
+		t.Errorf("FindBySimilarity above threshold 0.999 = %d agents", len(got))
+	}
+	if got := r.FindBySimilarity(randVector(rng, 8), 5, -1); len(got) != 0 {
+		t.Errorf("FindBySimilarity with another dimension = %d agents", len(got))
+	}
+
+	// Removed and unreachable agents drop out; the rest stay findable.
+	var kept []core.AgentProfile
+	for i, a := range live {
+		switch {
+		case i%3 == 0:
+			r.Remove("did:agent-semantic-protocol:" + a.AgentID)
+		case i%3 == 1:
+			r.SetReachable("did:agent-semantic-protocol:"+a.AgentID, false)
+		default:
+			kept = append(kept, a)
+		}
+	}
+	total = 0
+	for i := 0; i < 50; i++ {
+		q := randVector(rng, 32)
+		for _, p := range r.FindBySimilarity(q, 10, -1) {
+			if !slices.ContainsFunc(kept, func(k core.AgentProfile) bool { return k.AgentID == p.AgentID }) {
+				t.Fatalf("FindBySimilarity returned removed or unreachable %s", p.AgentID)
+			}
+		}
+		total += recall(r, kept, q, 10)
+	}
+	if avg := total / 50; avg < 0.95 {
+		t.Errorf("recall@10 after removals = %.3f, want >= 0.95", avg)
+	}
+
+	r.SetReachable("did:agent-semantic-protocol:"+live[1].AgentID, true)
+	if got := r.FindBySimilarity(live[1].EmbeddingVector, 1, 0.99); len(got) != 1 || got[0].AgentID != live[1].AgentID {
+		t.Errorf("agent not found again after becoming reachable: %v", got)
+	}
+}
+
+func BenchmarkFindBySimilarity(b *testing.B) {
+	rng := rand.New(rand.NewSource(6))
+	agents := registry(rng, 10000, 128)
+	r := core.NewDiscoveryRegistry()
+	announceAll(r, agents)
+	q := randVector(rng, 128)
+	b.Run("index", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			r.FindBySimilarity(q, 10, 0)
+		}
+	})
+	b.Run("scan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			core.TopK(q, r.All(), 10)
+		}
+	})
+}
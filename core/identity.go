@@ -0,0 +1,298 @@
+package core
+
+// identity.go — Persisting an agent's Ed25519 key so its DID survives restarts.
+//
+// Two on-disk formats are supported:
+//
+//   - PEM: a PKCS#8 "PRIVATE KEY" block, or, when a passphrase is given, an
+//     "ENCRYPTED AGENT KEY" block holding the PKCS#8 bytes sealed with
+//     AES-256-GCM under a PBKDF2-SHA256 key.  KDF parameters travel in the
+//     PEM headers.
+//   - libp2p: the protobuf PrivateKey message used by go-libp2p
+//     (crypto.MarshalPrivateKey), so the same file can seed a libp2p host.
+//
+// LoadAgent detects the format automatically.
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const (
+	pemPrivateKey    = "PRIVATE KEY"
+	pemEncryptedKey  = "ENCRYPTED AGENT KEY"
+	kdfName          = "pbkdf2-sha256"
+	kdfIterations    = 600_000
+	libp2pKeyEd25519 = 1 // pb.KeyType_Ed25519
+)
+
+// ErrBadPassphrase is returned by LoadAgent when an encrypted key cannot be
+// decrypted with the given passphrase.
+var ErrBadPassphrase = fmt.Errorf("identity: wrong passphrase or corrupted key")
+
+// NewAgentFromKey creates an Agent from an existing Ed25519 private key.
+func NewAgentFromKey(id string, capabilities []string, priv ed25519.PrivateKey) (*Agent, error) {
+	if len(priv) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("identity: expected %d-byte private key, got %d", ed25519.PrivateKeySize, len(priv))
+	}
+	pub := priv.Public().(ed25519.PublicKey)
+	d := didFromKey(pub, priv)
+	return &Agent{
+		ID:           id,
+		DID:          d,
+		Capabilities: capabilities,
+		pubKey:       d.pubKey,
+		privKey:      d.privKey,
+	}, nil
+}
+
+// LoadAgent reads the key file at path (PEM or libp2p format) and returns
+// an Agent with that identity.  passphrase is required for encrypted PEM keys
+// and ignored otherwise.
+func LoadAgent(path, id string, capabilities []string, passphrase []byte) (*Agent, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("identity: read %s: %w", path, err)
+	}
+	priv, err := parseIdentity(data, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return NewAgentFromKey(id, capabilities, priv)
+}
+
+// LoadOrCreateAgent loads the identity at path, or creates a new agent and
+// saves its identity there if the file does not exist yet.
+func LoadOrCreateAgent(path, id string, capabilities []string, passphrase []byte) (*Agent, error) {
+	a, err := LoadAgent(path, id, capabilities, passphrase)
+	if err == nil || !errors.Is(err, os.ErrNotExist) {
+		return a, err
+	}
+	if a, err = NewAgent(id, capabilities); err != nil {
+		return nil, err
+	}
+	if err = a.SaveIdentity(path, passphrase); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// SaveIdentity writes the agent's private key to path as PEM, encrypted when
+// passphrase is non-empty.  The file is created with mode 0600.
+func (a *Agent) SaveIdentity(path string, passphrase []byte) error {
+	der, err := x509.MarshalPKCS8PrivateKey(ed25519.PrivateKey(a.privKey))
+	if err != nil {
+		return fmt.Errorf("identity: marshal key: %w", err)
+	}
+	block := &pem.Block{Type: pemPrivateKey, Bytes: der}
+	if len(passphrase) > 0 {
+		if block, err = sealPEM(pemEncryptedKey, der, passphrase); err != nil {
+			return err
+		}
+	}
+	return writeKeyFile(path, pem.EncodeToMemory(block))
+}
+
+// SaveIdentityLibp2p writes the agent's private key to path in go-libp2p's
+// marshalled format.  This format has no encryption.
+func (a *Agent) SaveIdentityLibp2p(path string) error {
+	return writeKeyFile(path, a.MarshalLibp2pKey())
+}
+
+// MarshalLibp2pKey returns the private key in go-libp2p's marshalled format,
+// suitable for crypto.UnmarshalPrivateKey.
+func (a *Agent) MarshalLibp2pKey() []byte {
+	var b []byte
+	b = protowire.AppendTag(b, 1, protowire.VarintType)
+	b = protowire.AppendVarint(b, libp2pKeyEd25519)
+	b = protowire.AppendTag(b, 2, protowire.BytesType)
+	b = protowire.AppendBytes(b, a.privKey)
+	return b
+}
+
+// ------------------------------------------------------------------ helpers
+
+func parseIdentity(data, passphrase []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return parseLibp2pKey(data)
+	}
+	der := block.Bytes
+	switch block.Type {
+	case pemPrivateKey:
+	case pemEncryptedKey:
+		if len(passphrase) == 0 {
+			return nil, fmt.Errorf("identity: key is encrypted but no passphrase was given")
+		}
+		var err error
+		if der, err = openPEM(block, passphrase); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("identity: unsupported PEM block %q", block.Type)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("identity: parse PKCS#8: %w", err)
+	}
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("identity: key is %T, not Ed25519", key)
+	}
+	return priv, nil
+}
+
+func parseLibp2pKey(data []byte) (ed25519.PrivateKey, error) {
+	var keyType uint64
+	var raw []byte
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, fmt.Errorf("identity: not a PEM or libp2p key")
+		}
+		data = data[n:]
+		switch num {
+		case 1:
+			v, n2 := protowire.ConsumeVarint(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("identity: invalid libp2p key type")
+			}
+			keyType = v
+			data = data[n2:]
+		case 2:
+			b, n2 := protowire.ConsumeBytes(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("identity: invalid libp2p key data")
+			}
+			raw = b
+			data = data[n2:]
+		default:
+			n2 := protowire.ConsumeFieldValue(num, typ, data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("identity: unknown libp2p key field %d", num)
+			}
+			data = data[n2:]
+		}
+	}
+	if keyType != libp2pKeyEd25519 {
+		return nil, fmt.Errorf("identity: libp2p key type %d is not Ed25519", keyType)
+	}
+	// go-libp2p historically appended a redundant copy of the public key.
+	if len(raw) == ed25519.PrivateKeySize+ed25519.PublicKeySize {
+		raw = raw[:ed25519.PrivateKeySize]
+	}
+	if len(raw) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("identity: libp2p Ed25519 key has %d bytes", len(raw))
+	}
+	return ed25519.PrivateKey(append([]byte(nil), raw...)), nil
+}
+
+// sealPEM encrypts data under passphrase into a PEM block of blockType,
+// with the KDF parameters in its headers.
+func sealPEM(blockType string, data, passphrase []byte) (*pem.Block, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("identity: salt: %w", err)
+	}
+	gcm, err := keyCipher(passphrase, salt, kdfIterations)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("identity: nonce: %w", err)
+	}
+	return &pem.Block{
+		Type: blockType,
+		Headers: map[string]string{
+			"KDF":        kdfName,
+			"Iterations": strconv.Itoa(kdfIterations),
+			"Salt":       hex.EncodeToString(salt),
+			"Nonce":      hex.EncodeToString(nonce),
+		},
+		Bytes: gcm.Seal(nil, nonce, data, nil),
+	}, nil
+}
+
+// openPEM decrypts a block written by sealPEM.
+func openPEM(block *pem.Block, passphrase []byte) ([]byte, error) {
+	if block.Headers["KDF"] != kdfName {
+		return nil, fmt.Errorf("identity: unsupported KDF %q", block.Headers["KDF"])
+	}
+	iter, err := strconv.Atoi(block.Headers["Iterations"])
+	if err != nil || iter <= 0 {
+		return nil, fmt.Errorf("identity: invalid KDF iterations")
+	}
+	salt, err := hex.DecodeString(block.Headers["Salt"])
+	if err != nil {
+		return nil, fmt.Errorf("identity: invalid salt")
+	}
+	nonce, err := hex.DecodeString(block.Headers["Nonce"])
+	if err != nil {
+		return nil, fmt.Errorf("identity: invalid nonce")
+	}
+	gcm, err := keyCipher(passphrase, salt, iter)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("identity: invalid nonce")
+	}
+	der, err := gcm.Open(nil, nonce, block.Bytes, nil)
+	if err != nil {
+		return nil, ErrBadPassphrase
+	}
+	return der, nil
+}
+
+func keyCipher(passphrase, salt []byte, iter int) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, string(passphrase), salt, iter, 32)
+	if err != nil {
+		return nil, fmt.Errorf("identity: derive key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("identity: cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// writeKeyFile atomically writes data to path with mode 0600.
+func writeKeyFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("identity: create temp: %w", err)
+	}
+	if err = tmp.Chmod(0o600); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("identity: chmod: %w", err)
+	}
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("identity: write: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("identity: close: %w", err)
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("identity: rename: %w", err)
+	}
+	return nil
+}
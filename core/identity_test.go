@@ -0,0 +1,62 @@
+package core_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func TestSaveLoadIdentity(t *testing.T) {
+	dir := t.TempDir()
+	a, _ := core.NewAgent("alpha", []string{"nlp"})
+
+	cases := []struct {
+		name       string
+		save       func(path string) error
+		passphrase []byte
+	}{
+		{"pem", func(p string) error { return a.SaveIdentity(p, nil) }, nil},
+		{"pem-encrypted", func(p string) error { return a.SaveIdentity(p, []byte("s3cret")) }, []byte("s3cret")},
+		{"libp2p", a.SaveIdentityLibp2p, nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(dir, tc.name+".key")
+			if err := tc.save(path); err != nil {
+				t.Fatalf("save: %v", err)
+			}
+			loaded, err := core.LoadAgent(path, "alpha", []string{"nlp"}, tc.passphrase)
+			if err != nil {
+				t.Fatalf("LoadAgent: %v", err)
+			}
+			if loaded.DID.String() != a.DID.String() {
+				t.Errorf("DID changed: %s != %s", loaded.DID, a.DID)
+			}
+			sig, err := loaded.Sign([]byte("x"))
+			if err != nil || !a.DID.Verify([]byte("x"), sig) {
+				t.Errorf("loaded key cannot sign for the original DID: %v", err)
+			}
+		})
+	}
+
+	if _, err := core.LoadAgent(filepath.Join(dir, "pem-encrypted.key"), "alpha", nil, []byte("wrong")); !errors.Is(err, core.ErrBadPassphrase) {
+		t.Errorf("expected ErrBadPassphrase, got %v", err)
+	}
+}
+
+func TestLoadOrCreateAgent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "id.pem")
+	first, err := core.LoadOrCreateAgent(path, "alpha", nil, nil)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	second, err := core.LoadOrCreateAgent(path, "alpha", nil, nil)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if first.DID.String() != second.DID.String() {
+		t.Errorf("identity not stable across restarts: %s != %s", first.DID, second.DID)
+	}
+}
@@ -0,0 +1,218 @@
+package core
+
+// intentid.go — Deterministic intent IDs namespaced by the requester's DID.
+//
+// Random IDs can repeat across restarts as far as an idempotency cache or a
+// receipt store is concerned, and say nothing about where they came from.
+// An IntentIDGenerator builds IDs from three parts:
+//
+//	<did-id>.<sequence>.<content-hash>
+//
+// <did-id> is the method-specific part of the sender's DID, <sequence> a
+// per-agent counter that only increases (also across restarts, when the
+// generator is given a file), and <content-hash> the first 8 bytes of a
+// SHA-256 over the DID, sequence, payload, capabilities and vector, in hex.
+// ParseIntentID recovers the parts and IntentOrigin the sender's DID.
+//
+//	ids, _ := core.NewIntentIDGenerator(agent.DID, "agent.seq")
+//	agent.IntentIDs = ids // CreateIntent now uses structured IDs
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// intentIDBlock is how many sequence numbers are reserved per write of the
+// sequence file.  A crash skips at most this many numbers; none are reused.
+const intentIDBlock = 1024
+
+// intentIDHashLen is the length of the content hash in hex characters.
+const intentIDHashLen = 16
+
+// IntentID is a parsed structured intent ID.
+type IntentID struct {
+	DID  string // Full sender DID
+	Seq  uint64
+	Hash string // Hex content hash
+}
+
+// String formats id as it appears on the wire.
+func (id IntentID) String() string {
+	d, err := ParseDID(id.DID)
+	method := id.DID
+	if err == nil {
+		method = d.ID
+	}
+	return method + "." + strconv.FormatUint(id.Seq, 10) + "." + id.Hash
+}
+
+// ParseIntentID splits a structured intent ID.  It fails for IDs not made
+// by an IntentIDGenerator, such as the random IDs CreateIntent uses by
+// default.
+func ParseIntentID(s string) (IntentID, error) {
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 || parts[0] == "" || len(parts[2]) != intentIDHashLen {
+		return IntentID{}, fmt.Errorf("intent id: not a structured id: %q", s)
+	}
+	seq, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return IntentID{}, fmt.Errorf("intent id: bad sequence in %q", s)
+	}
+	if _, err = hex.DecodeString(parts[2]); err != nil {
+		return IntentID{}, fmt.Errorf("intent id: bad hash in %q", s)
+	}
+	return IntentID{DID: "did:agent-semantic-protocol:" + parts[0], Seq: seq, Hash: parts[2]}, nil
+}
+
+// IntentOrigin returns the DID of the agent that created the intent with
+// ID s.  ok is false for unstructured IDs.
+func IntentOrigin(s string) (did string, ok bool) {
+	id, err := ParseIntentID(s)
+	if err != nil {
+		return "", false
+	}
+	return id.DID, true
+}
+
+// CheckIntentID reports whether intent's ID is consistent with the intent:
+// an unstructured ID always is; a structured one must name intent.DID as its
+// origin and carry the hash of the intent's content.  The hash covers the
+// plaintext payload, so it is not checked while the payload is sealed.
+func CheckIntentID(intent *IntentMessage) error {
+	id, err := ParseIntentID(intent.ID)
+	if err != nil {
+		return nil
+	}
+	if id.DID != intent.DID {
+		return fmt.Errorf("intent id: origin %s does not match sender %s", id.DID, intent.DID)
+	}
+	if intent.EncryptedPayload != nil {
+		return nil
+	}
+	if h := intentContentHash(id.DID, id.Seq, intent.Payload, intent.Capabilities, intent.IntentVector); h != id.Hash {
+		return fmt.Errorf("intent id: content hash mismatch")
+	}
+	return nil
+}
+
+// IntentIDGenerator issues structured intent IDs for one agent.  It is
+// concurrency-safe.
+type IntentIDGenerator struct {
+	mu       sync.Mutex
+	did      string
+	path     string
+	next     uint64 // Next sequence number to hand out
+	reserved uint64 // Numbers below this are covered by the sequence file
+}
+
+// NewIntentIDGenerator creates a generator for did.  If path is not empty
+// the sequence is persisted there, so IDs keep increasing across restarts;
+// the file is created on first use.
+func NewIntentIDGenerator(did *DID, path string) (*IntentIDGenerator, error) {
+	g := &IntentIDGenerator{did: did.String(), path: path}
+	if path == "" {
+		return g, nil
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return g, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("intent id: read %s: %w", path, err)
+	}
+	n, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("intent id: parse %s: %w", path, err)
+	}
+	g.next, g.reserved = n, n
+	return g, nil
+}
+
+// Next returns the ID for an intent with the given content.
+func (g *IntentIDGenerator) Next(payload string, capabilities []string, vector []float32) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.path != "" && g.next >= g.reserved {
+		if err := g.reserve(g.next + intentIDBlock); err != nil {
+			return "", err
+		}
+	}
+	seq := g.next
+	g.next++
+	hash := intentContentHash(g.did, seq, payload, capabilities, vector)
+	return IntentID{DID: g.did, Seq: seq, Hash: hash}.String(), nil
+}
+
+// Seq returns the sequence number the next ID will carry.
+func (g *IntentIDGenerator) Seq() uint64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.next
+}
+
+// reserve records upTo in the sequence file.  Caller must hold g.mu.
+func (g *IntentIDGenerator) reserve(upTo uint64) error {
+	tmp, err := os.CreateTemp(filepath.Dir(g.path), filepath.Base(g.path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("intent id: create temp: %w", err)
+	}
+	if _, err = tmp.WriteString(strconv.FormatUint(upTo, 10) + "\n"); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("intent id: write: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("intent id: close: %w", err)
+	}
+	if err = os.Rename(tmp.Name(), g.path); err != nil {
+		return fmt.Errorf("intent id: rename: %w", err)
+	}
+	g.reserved = upTo
+	return nil
+}
+
+// intentContentHash hashes the parts of an intent its ID commits to.
+// Metadata is left out: hosts add keys to it after the ID is assigned.
+func intentContentHash(did string, seq uint64, payload string, capabilities []string, vector []float32) string {
+	h := sha256.New()
+	var buf [8]byte
+	writeField := func(b []byte) {
+		binary.BigEndian.PutUint64(buf[:], uint64(len(b)))
+		h.Write(buf[:])
+		h.Write(b)
+	}
+	writeField([]byte(did))
+	binary.BigEndian.PutUint64(buf[:], seq)
+	h.Write(buf[:])
+	writeField([]byte(payload))
+	binary.BigEndian.PutUint64(buf[:], uint64(len(capabilities)))
+	h.Write(buf[:])
+	for _, c := range capabilities {
+		writeField([]byte(c))
+	}
+	binary.BigEndian.PutUint64(buf[:], uint64(len(vector)))
+	h.Write(buf[:])
+	for _, f := range vector {
+		binary.BigEndian.PutUint32(buf[:4], math.Float32bits(f))
+		h.Write(buf[:4])
+	}
+	return hex.EncodeToString(h.Sum(nil))[:intentIDHashLen]
+}
+
+// newIntentID returns a structured ID if a has a generator, else a random
+// one.
+func (a *Agent) newIntentID(payload string, capabilities []string, vector []float32) (string, error) {
+	if a.IntentIDs == nil {
+		return a.randomID()
+	}
+	return a.IntentIDs.Next(payload, capabilities, vector)
+}
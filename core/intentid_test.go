@@ -0,0 +1,66 @@
+package core_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func TestIntentIDs(t *testing.T) {
+	a, _ := core.NewAgent("a", []string{"nlp"})
+	path := filepath.Join(t.TempDir(), "a.seq")
+	ids, err := core.NewIntentIDGenerator(a.DID, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.IntentIDs = ids
+
+	first, _ := core.CreateIntent(a, []float32{0.1, 0.2}, []string{"nlp"}, "summarise")
+	second, _ := core.CreateIntent(a, []float32{0.1, 0.2}, []string{"nlp"}, "summarise")
+	if first.ID == second.ID {
+		t.Fatalf("identical content got the same ID %q", first.ID)
+	}
+	id, err := core.ParseIntentID(first.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.DID != a.DID.String() || id.Seq != 0 {
+		t.Errorf("parsed %+v, want DID %s and Seq 0", id, a.DID)
+	}
+	if origin, ok := core.IntentOrigin(second.ID); !ok || origin != a.DID.String() {
+		t.Errorf("IntentOrigin = %q, %v", origin, ok)
+	}
+	if err = core.CheckIntentID(first); err != nil {
+		t.Errorf("CheckIntentID: %v", err)
+	}
+	first.Payload = "tampered"
+	if core.CheckIntentID(first) == nil {
+		t.Error("CheckIntentID accepted a changed payload")
+	}
+	first.Payload = "summarise"
+	first.DID = "did:agent-semantic-protocol:other"
+	if core.CheckIntentID(first) == nil {
+		t.Error("CheckIntentID accepted a foreign origin")
+	}
+
+	// A restart continues the sequence instead of reusing it.
+	restarted, err := core.NewIntentIDGenerator(a.DID, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next, _ := restarted.Next("summarise", []string{"nlp"}, []float32{0.1, 0.2})
+	if id, _ = core.ParseIntentID(next); id.Seq < 2 {
+		t.Errorf("sequence after restart = %d, want >= 2", id.Seq)
+	}
+
+	// Random IDs stay valid and carry no origin.
+	b, _ := core.NewAgent("b", nil)
+	plain, _ := core.CreateIntent(b, nil, nil, "")
+	if _, ok := core.IntentOrigin(plain.ID); ok {
+		t.Error("random ID reported an origin")
+	}
+	if err = core.CheckIntentID(plain); err != nil {
+		t.Errorf("CheckIntentID on random ID: %v", err)
+	}
+}
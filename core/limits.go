@@ -0,0 +1,205 @@
+package core
+
+// limits.go — Bounds on decoded messages.
+//
+// A frame is at most MaxFrameSize bytes, but within that a peer can still
+// send a million-entry metadata map or a vector no model produces.  Every
+// decoder takes a DecodeLimits and checks each field as it reads it, so it
+// fails with a *LimitError, which wraps ErrDecodeLimit, before an
+// oversized message is built.  The package-level Decode* functions and
+// codecs use DefaultDecodeLimits; the DecodeLimits methods of the same
+// names use the receiver, which lets each host choose its own.  Fields are
+// named by their JSON tags, so "IntentMessage.metadata" is the intent's
+// metadata map.  Byte fields are bounded only by the frame size.
+//
+// Codecs registered from outside this package cannot check as they go;
+// their messages are walked by reflection after Unmarshal instead.
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ErrDecodeLimit is wrapped by every LimitError.
+var ErrDecodeLimit = fmt.Errorf("decode: limit exceeded")
+
+// DecodeLimits bounds the fields of decoded messages.  A zero field
+// disables that bound.
+type DecodeLimits struct {
+	MaxVectorLen  int // Elements in a float vector
+	MaxMapEntries int // Entries in a map, such as an intent's metadata
+	MaxStringLen  int // Bytes in a string
+	MaxRepeated   int // Elements in any other repeated field
+}
+
+// DefaultDecodeLimits are the limits of the package-level decoders and of
+// hosts without WithDecodeLimits.
+var DefaultDecodeLimits = DecodeLimits{
+	MaxVectorLen:  16384,
+	MaxMapEntries: 256,
+	MaxStringLen:  1 << 20,
+	MaxRepeated:   65536,
+}
+
+// Validate reports negative limits.
+func (l DecodeLimits) Validate() error {
+	if l.MaxVectorLen < 0 || l.MaxMapEntries < 0 || l.MaxStringLen < 0 || l.MaxRepeated < 0 {
+		return fmt.Errorf("decode limits: negative limit in %+v", l)
+	}
+	return nil
+}
+
+// LimitError reports a decoded field over a DecodeLimits bound.
+type LimitError struct {
+	Field string // Path of the field, e.g. "WorkflowMessage.steps[2].params"
+	Kind  string // "vector length", "map entries", "string length" or "repeated field"
+	Limit int
+	Got   int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("decode: %s: %s %d exceeds %d", e.Field, e.Kind, e.Got, e.Limit)
+}
+
+func (e *LimitError) Unwrap() error { return ErrDecodeLimit }
+
+// afterDecode checks m, decoded by a codec that does not enforce limits
+// itself, against l.
+func (l DecodeLimits) afterDecode(m any) error {
+	v := reflect.ValueOf(m)
+	if err := l.check(v); err != nil {
+		err.Field = reflect.Indirect(v).Type().Name() + err.Field
+		return err
+	}
+	return nil
+}
+
+// str bounds the string s read into field.
+func (l DecodeLimits) str(field, s string) error {
+	return limitErr(bound("string length", l.MaxStringLen, len(s)), field)
+}
+
+// strAt bounds the string s read as element i of the repeated field.
+func (l DecodeLimits) strAt(field string, i int, s string) error {
+	return limitErr(bound("string length", l.MaxStringLen, len(s)), field+"["+strconv.Itoa(i)+"]")
+}
+
+// repeated bounds a repeated field about to hold n elements.
+func (l DecodeLimits) repeated(field string, n int) error {
+	return limitErr(bound("repeated field", l.MaxRepeated, n), field)
+}
+
+// vector bounds a float vector field of n elements.
+func (l DecodeLimits) vector(field string, n int) error {
+	return limitErr(bound("vector length", l.MaxVectorLen, n), field)
+}
+
+// entry bounds storing k and v in the map field m.
+func (l DecodeLimits) entry(field string, m map[string]string, k, v string) error {
+	n := len(m)
+	if _, ok := m[k]; !ok {
+		n++
+	}
+	if err := bound("map entries", l.MaxMapEntries, n); err != nil {
+		return limitErr(err, field)
+	}
+	if err := bound("string length", l.MaxStringLen, len(k)); err != nil {
+		return limitErr(err, field+" key")
+	}
+	return limitErr(bound("string length", l.MaxStringLen, len(v)), field+" value")
+}
+
+// limitErr names the field of err, or returns nil if err is nil.
+func limitErr(err *LimitError, field string) error {
+	if err == nil {
+		return nil
+	}
+	err.Field = field
+	return err
+}
+
+// within prefixes the field of a LimitError from a nested decoder with
+// field, the path of the nested message.  Other errors pass unchanged.
+func within(field string, err error) error {
+	var le *LimitError
+	if errors.As(err, &le) {
+		le.Field = field + le.Field
+	}
+	return err
+}
+
+// check walks v.  Paths are built only on failure, innermost segment
+// first, so a message within its limits costs no allocations.
+func (l DecodeLimits) check(v reflect.Value) *LimitError {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return l.check(v.Elem())
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			if err := l.check(v.Field(i)); err != nil {
+				err.Field = "." + limitFieldName(f) + err.Field
+				return err
+			}
+		}
+	case reflect.String:
+		return bound("string length", l.MaxStringLen, v.Len())
+	case reflect.Map:
+		if err := bound("map entries", l.MaxMapEntries, v.Len()); err != nil {
+			return err
+		}
+		for it := v.MapRange(); it.Next(); {
+			if err := l.check(it.Key()); err != nil {
+				err.Field = " key" + err.Field
+				return err
+			}
+			if err := l.check(it.Value()); err != nil {
+				err.Field = " value" + err.Field
+				return err
+			}
+		}
+	case reflect.Slice:
+		switch v.Type().Elem().Kind() {
+		case reflect.Uint8:
+			return nil
+		case reflect.Float32:
+			return bound("vector length", l.MaxVectorLen, v.Len())
+		}
+		if err := bound("repeated field", l.MaxRepeated, v.Len()); err != nil {
+			return err
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := l.check(v.Index(i)); err != nil {
+				err.Field = "[" + strconv.Itoa(i) + "]" + err.Field
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func bound(kind string, limit, got int) *LimitError {
+	if limit > 0 && got > limit {
+		return &LimitError{Kind: kind, Limit: limit, Got: got}
+	}
+	return nil
+}
+
+// limitFieldName is f's JSON name, or its Go name without a tag.
+func limitFieldName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return f.Name
+	}
+	return name
+}
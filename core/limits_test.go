@@ -0,0 +1,61 @@
+package core_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func TestDecodeLimits(t *testing.T) {
+	a, _ := core.NewAgent("a", []string{"nlp"})
+	limits := core.DecodeLimits{MaxVectorLen: 4, MaxMapEntries: 2, MaxStringLen: 128, MaxRepeated: 3}
+
+	for _, c := range []struct {
+		name  string
+		msg   core.Encoder
+		field string
+	}{
+		{"vector", &core.IntentMessage{IntentVector: make([]float32, 5)}, "IntentMessage.intent_vector"},
+		{"metadata", &core.IntentMessage{Metadata: map[string]string{"a": "", "b": "", "c": ""}}, "IntentMessage.metadata"},
+		{"string", &core.IntentMessage{Payload: strings.Repeat("x", 129)}, "IntentMessage.payload"},
+		{"repeated", &core.HandshakeMessage{Capabilities: []string{"a", "b", "c", "d"}}, "HandshakeMessage.capabilities"},
+		{"nested", &core.WorkflowMessage{Params: map[string]string{"k": strings.Repeat("v", 129)}}, "WorkflowMessage.params value"},
+		{"batched", &core.IntentBatch{Intents: []*core.IntentMessage{{}, {DID: strings.Repeat("d", 129)}}}, "IntentBatch.intents[1].did"},
+	} {
+		for _, codec := range []core.Codec{core.ProtobufCodec, core.JSONCodec, core.CBORCodec} {
+			frame, err := core.FrameWith(codec, c.msg)
+			if err != nil {
+				t.Fatalf("%s/%s: %v", c.name, codec.Name(), err)
+			}
+			if _, err = core.DecodeFrame(frame); err != nil {
+				t.Errorf("%s/%s: default limits: %v", c.name, codec.Name(), err)
+			}
+			_, err = limits.DecodeFrame(frame)
+			var le *core.LimitError
+			if !errors.Is(err, core.ErrDecodeLimit) || !errors.As(err, &le) || !strings.HasSuffix(le.Field, c.field) {
+				t.Errorf("%s/%s: %v; want a LimitError on %s", c.name, codec.Name(), err, c.field)
+			}
+		}
+	}
+
+	intent, _ := core.CreateIntent(a, []float32{1, 2}, []string{"nlp"}, "short")
+	data, _ := intent.Encode()
+	if _, err := limits.DecodeIntentMessage(data); err != nil {
+		t.Errorf("intent within the limits: %v", err)
+	}
+
+	// The limit is hit while decoding: the malformed tail is never reached.
+	over, _ := (&core.IntentMessage{Metadata: map[string]string{"a": "", "b": "", "c": ""}}).Encode()
+	if _, err := limits.DecodeIntentMessage(append(over, 0xff)); !errors.Is(err, core.ErrDecodeLimit) {
+		t.Errorf("oversized intent with a bad tail: %v", err)
+	}
+	if _, err := core.DecodeIntentMessage(append(over, 0xff)); err == nil || errors.Is(err, core.ErrDecodeLimit) {
+		t.Errorf("bad tail within the default limits: %v", err)
+	}
+
+	if err := (core.DecodeLimits{MaxStringLen: -1}).Validate(); err == nil {
+		t.Error("negative limit accepted")
+	}
+}
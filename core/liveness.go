@@ -0,0 +1,236 @@
+package core
+
+// liveness.go — Probing registered peers for liveness.
+//
+// A peer can stay in the DiscoveryRegistry long after it stopped
+// answering: its announcement TTL has not run out, or it never had one.
+// A HealthMonitor probes every registered DID each Interval (a ping on
+// the wire; see p2p.WithHealthMonitor), smooths the round-trip times and
+// keeps the share of the last Window probes that were answered.  After
+// MaxFailures probes in a row go unanswered the peer is unhealthy, and
+// the registry leaves it out of FindByCapability and FindBySimilarity
+// until a probe is answered again.  The result is kept in each profile's
+// Liveness.
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrNotProbed is returned by a ProbeFunc for a peer it has no way to
+// reach, such as one only heard of through gossip.  The peer's liveness
+// is left as it was.
+var ErrNotProbed = fmt.Errorf("liveness: peer cannot be probed")
+
+// ProbeFunc checks that the agent described by profile answers, and
+// returns the round-trip time.
+type ProbeFunc func(ctx context.Context, profile AgentProfile) (time.Duration, error)
+
+// Liveness is what a HealthMonitor has observed of one peer.
+type Liveness struct {
+	Healthy      bool          `json:"healthy"`
+	RTT          time.Duration `json:"rtt,omitempty"`       // Smoothed round-trip time of answered probes
+	Availability float64       `json:"availability"`        // Share of the last Window probes answered
+	Failures     int           `json:"failures,omitempty"`  // Unanswered probes since the last answered one
+	LastSeen     time.Time     `json:"last_seen,omitempty"` // When a probe was last answered
+	CheckedAt    time.Time     `json:"checked_at"`
+}
+
+// HealthMonitorConfig tunes a HealthMonitor.  Zero fields take their
+// defaults.
+type HealthMonitorConfig struct {
+	Interval    time.Duration // Between probe rounds; default 30s
+	Timeout     time.Duration // Per probe; default 5s
+	MaxFailures int           // Unanswered probes in a row that make a peer unhealthy; default 3
+	Window      int           // Probes Availability is computed over; default 20
+	Parallelism int           // Probes in flight at once; default 8
+}
+
+// Validate checks that c describes a usable configuration.
+func (c HealthMonitorConfig) Validate() error {
+	if c.Interval < 0 || c.Timeout < 0 {
+		return fmt.Errorf("health monitor: durations must not be negative")
+	}
+	if c.MaxFailures < 0 || c.Window < 0 || c.Parallelism < 0 {
+		return fmt.Errorf("health monitor: counts must not be negative")
+	}
+	return nil
+}
+
+func (c HealthMonitorConfig) withDefaults() HealthMonitorConfig {
+	if c.Interval == 0 {
+		c.Interval = 30 * time.Second
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 5 * time.Second
+	}
+	if c.MaxFailures == 0 {
+		c.MaxFailures = 3
+	}
+	if c.Window == 0 {
+		c.Window = 20
+	}
+	if c.Parallelism == 0 {
+		c.Parallelism = 8
+	}
+	return c
+}
+
+// HealthMonitor probes the peers in a DiscoveryRegistry and records their
+// Liveness there.  All methods are concurrency-safe.
+type HealthMonitor struct {
+	registry *DiscoveryRegistry
+	probe    ProbeFunc
+	cfg      HealthMonitorConfig
+
+	mu       sync.Mutex
+	peers    map[string]*probeHistory // keyed by DID
+	onChange func(did string, l Liveness)
+}
+
+// probeHistory is the recent probe results for one peer.
+type probeHistory struct {
+	answered []bool // Ring of the last Window results
+	next     int
+	live     Liveness
+}
+
+// NewHealthMonitor creates a monitor for the peers in r, probed with
+// probe.
+func NewHealthMonitor(r *DiscoveryRegistry, probe ProbeFunc, cfg HealthMonitorConfig) (*HealthMonitor, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &HealthMonitor{
+		registry: r,
+		probe:    probe,
+		cfg:      cfg.withDefaults(),
+		peers:    make(map[string]*probeHistory),
+	}, nil
+}
+
+// OnChange registers fn to be called whenever a peer becomes unhealthy or
+// healthy again.
+func (m *HealthMonitor) OnChange(fn func(did string, l Liveness)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onChange = fn
+}
+
+// Liveness returns what the monitor has observed of did, or false if it
+// has not probed it.
+func (m *HealthMonitor) Liveness(did string) (Liveness, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h, ok := m.peers[did]
+	if !ok {
+		return Liveness{}, false
+	}
+	return h.live, true
+}
+
+// ProbeAll probes every registered peer with a DID once and returns when
+// all probes are done.
+func (m *HealthMonitor) ProbeAll(ctx context.Context) {
+	records := m.registry.Export()
+	registered := make(map[string]bool, len(records))
+	sem := make(chan struct{}, m.cfg.Parallelism)
+	var wg sync.WaitGroup
+	for _, rec := range records {
+		p := rec.Profile
+		if p.DID == "" {
+			continue
+		}
+		registered[p.DID] = true
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			pctx, cancel := context.WithTimeout(ctx, m.cfg.Timeout)
+			rtt, err := m.probe(pctx, p)
+			cancel()
+			if err == ErrNotProbed || ctx.Err() != nil {
+				return
+			}
+			m.record(p.DID, rtt, err == nil)
+		}()
+	}
+	wg.Wait()
+
+	// Forget peers that left the registry.
+	m.mu.Lock()
+	for did := range m.peers {
+		if !registered[did] {
+			delete(m.peers, did)
+		}
+	}
+	m.mu.Unlock()
+}
+
+// Start probes every Interval until done is closed.
+func (m *HealthMonitor) Start(done <-chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-done
+		cancel()
+	}()
+	go func() {
+		t := time.NewTicker(m.cfg.Interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				m.ProbeAll(ctx)
+			case <-done:
+				return
+			}
+		}
+	}()
+}
+
+// record adds the result of one probe of did.
+func (m *HealthMonitor) record(did string, rtt time.Duration, answered bool) {
+	m.mu.Lock()
+	h, ok := m.peers[did]
+	if !ok {
+		h = &probeHistory{live: Liveness{Healthy: true}}
+		m.peers[did] = h
+	}
+	if len(h.answered) < m.cfg.Window {
+		h.answered = append(h.answered, answered)
+	} else {
+		h.answered[h.next] = answered
+		h.next = (h.next + 1) % m.cfg.Window
+	}
+	wasHealthy := h.live.Healthy
+	l := &h.live
+	l.CheckedAt = time.Now()
+	if answered {
+		l.Failures = 0
+		l.LastSeen = l.CheckedAt
+		if l.RTT == 0 {
+			l.RTT = rtt
+		} else {
+			l.RTT += (rtt - l.RTT) / 8
+		}
+	} else {
+		l.Failures++
+	}
+	l.Healthy = l.Failures < m.cfg.MaxFailures
+	n := 0
+	for _, a := range h.answered {
+		if a {
+			n++
+		}
+	}
+	l.Availability = float64(n) / float64(len(h.answered))
+	live, fn := *l, m.onChange
+	m.mu.Unlock()
+
+	m.registry.SetLiveness(did, live)
+	if live.Healthy != wasHealthy && fn != nil {
+		fn(did, live)
+	}
+}
@@ -0,0 +1,105 @@
+package core_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func TestPingPongRoundTrip(t *testing.T) {
+	ping := &core.PingMessage{Nonce: []byte{1, 2, 3, 4}, SentAt: 42}
+	data, _ := ping.Encode()
+	decoded, err := core.Decode(core.MsgPing, data)
+	if err != nil || !reflect.DeepEqual(decoded, ping) {
+		t.Fatalf("ping = %+v, %v; want %+v", decoded, err, ping)
+	}
+	pong := &core.PongMessage{Nonce: ping.Nonce, SentAt: ping.SentAt, RepliedAt: 43}
+	data, _ = pong.Encode()
+	decoded, err = core.Decode(core.MsgPong, data)
+	if err != nil || !reflect.DeepEqual(decoded, pong) {
+		t.Fatalf("pong = %+v, %v; want %+v", decoded, err, pong)
+	}
+}
+
+func TestHealthMonitor(t *testing.T) {
+	r := core.NewDiscoveryRegistry()
+	for _, id := range []string{"alive", "flaky", "gossiped"} {
+		r.Announce(core.AgentProfile{AgentID: id, DID: "did:agent-semantic-protocol:" + id, Capabilities: []string{"translate"}}, 0)
+	}
+
+	var mu sync.Mutex
+	down := false
+	probe := func(_ context.Context, p core.AgentProfile) (time.Duration, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case p.AgentID == "gossiped":
+			return 0, core.ErrNotProbed
+		case p.AgentID == "flaky" && down:
+			return 0, errors.New("timeout")
+		}
+		return 10 * time.Millisecond, nil
+	}
+	m, err := core.NewHealthMonitor(r, probe, core.HealthMonitorConfig{MaxFailures: 2, Window: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var changes []string
+	m.OnChange(func(did string, l core.Liveness) {
+		changes = append(changes, did[len("did:agent-semantic-protocol:"):]+map[bool]string{true: "+", false: "-"}[l.Healthy])
+	})
+
+	ctx := context.Background()
+	m.ProbeAll(ctx)
+	mu.Lock()
+	down = true
+	mu.Unlock()
+	m.ProbeAll(ctx)
+	if got := len(r.FindByCapability("translate")); got != 3 {
+		t.Fatalf("after one failed probe: %d agents found, want 3", got)
+	}
+	m.ProbeAll(ctx)
+
+	l, ok := m.Liveness("did:agent-semantic-protocol:flaky")
+	if !ok || l.Healthy || l.Failures != 2 || l.Availability != 1.0/3 || l.RTT != 10*time.Millisecond {
+		t.Fatalf("flaky liveness = %+v", l)
+	}
+	if _, ok = m.Liveness("did:agent-semantic-protocol:gossiped"); ok {
+		t.Error("unprobeable peer has a liveness")
+	}
+	var found []string
+	for _, p := range r.FindByCapability("translate") {
+		found = append(found, p.AgentID)
+	}
+	if !reflect.DeepEqual(found, []string{"alive", "gossiped"}) {
+		t.Errorf("FindByCapability = %v, want the unhealthy agent left out", found)
+	}
+	if p, _ := r.FindByDID("did:agent-semantic-protocol:flaky"); p.Liveness == nil || p.Liveness.Healthy {
+		t.Errorf("profile liveness = %+v", p.Liveness)
+	}
+
+	// Re-announcing does not make it healthy; answering does.
+	r.Announce(core.AgentProfile{AgentID: "flaky", DID: "did:agent-semantic-protocol:flaky", Capabilities: []string{"translate"}}, 0)
+	if got := len(r.FindByCapability("translate")); got != 2 {
+		t.Errorf("after re-announcement: %d agents found, want 2", got)
+	}
+	mu.Lock()
+	down = false
+	mu.Unlock()
+	m.ProbeAll(ctx)
+	if got := len(r.FindByCapability("translate")); got != 3 {
+		t.Errorf("after recovery: %d agents found, want 3", got)
+	}
+	if !reflect.DeepEqual(changes, []string{"flaky-", "flaky+"}) {
+		t.Errorf("changes = %v", changes)
+	}
+
+	if _, err = core.NewHealthMonitor(r, probe, core.HealthMonitorConfig{Timeout: -1}); err == nil {
+		t.Error("negative timeout accepted")
+	}
+}
@@ -1,29 +1,109 @@
 package core
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"fmt"
+	"log/slog"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 )
 
-// Logger provides functionality for auditable logging.
-type Logger struct {
+// Logger receives structured log records: a level, a message and
+// alternating keys and values, as in slog.  *slog.Logger satisfies it, so
+// any slog.Handler can be a sink.  Records use the LogKey* keys for the
+// fields they share.
+type Logger interface {
+	Log(ctx context.Context, level slog.Level, msg string, args ...interface{})
+}
+
+// Keys of the structured fields in protocol log records.
+const (
+	LogKeyPeer     = "peer"     // PeerID, or agent ID on a NegotiationBus
+	LogKeyMsgType  = "msg_type" // e.g. "intent", "handshake"
+	LogKeyIntentID = "intent_id"
+	LogKeyLatency  = "latency"  // time.Duration
+	LogKeyDecision = "decision" // "accepted", "rejected" or "error"
+)
+
+// DiscardLogger returns a Logger that drops every record.
+func DiscardLogger() Logger { return slog.New(slog.DiscardHandler) }
+
+// Decision returns the LogKeyDecision value for resp and err.
+func Decision(resp *NegotiationResponse, err error) string {
+	switch {
+	case err != nil || resp == nil:
+		return "error"
+	case resp.Accepted:
+		return "accepted"
+	default:
+		return "rejected"
+	}
+}
+
+// MultiLogger returns a Logger that passes every record to each of loggers.
+func MultiLogger(loggers ...Logger) Logger { return multiLogger(loggers) }
+
+type multiLogger []Logger
+
+func (m multiLogger) Log(ctx context.Context, level slog.Level, msg string, args ...interface{}) {
+	for _, l := range m {
+		l.Log(ctx, level, msg, args...)
+	}
+}
+
+// FileLogger is a Logger sink appending one line per record to a file, for
+// audit trails:
+//
+//	2026-10-16T09:00:00Z | ID: <intent_id> | Type: <msg_type> | Details: msg key=value ...
+//
+// It implements Prunable.
+type FileLogger struct {
+	mu      sync.Mutex
+	path    string
 	logFile *os.File
 }
 
-// NewLogger initializes a new Logger instance.
-func NewLogger(filePath string) (*Logger, error) {
+// NewFileLogger opens (or creates) the log file at filePath for appending.
+func NewFileLogger(filePath string) (*FileLogger, error) {
 	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open log file: %w", err)
 	}
-	return &Logger{logFile: file}, nil
+	return &FileLogger{path: filePath, logFile: file}, nil
+}
+
+// Log implements Logger.  Write errors are dropped; use LogMessage to see
+// them.
+func (l *FileLogger) Log(_ context.Context, level slog.Level, msg string, args ...interface{}) {
+	var id, typ string
+	details := []string{level.String(), msg}
+	r := slog.NewRecord(time.Time{}, level, msg, 0)
+	r.Add(args...)
+	r.Attrs(func(a slog.Attr) bool {
+		switch a.Key {
+		case LogKeyIntentID:
+			id = a.Value.String()
+		case LogKeyMsgType:
+			typ = a.Value.String()
+		default:
+			details = append(details, a.Key+"="+a.Value.String())
+		}
+		return true
+	})
+	_ = l.LogMessage(id, typ, strings.Join(details, " "))
 }
 
 // LogMessage writes a log entry for a processed message.
-func (l *Logger) LogMessage(messageID string, messageType string, details string) error {
+func (l *FileLogger) LogMessage(messageID string, messageType string, details string) error {
 	timestamp := time.Now().Format(time.RFC3339)
 	logEntry := fmt.Sprintf("%s | ID: %s | Type: %s | Details: %s\n", timestamp, messageID, messageType, details)
+	l.mu.Lock()
+	defer l.mu.Unlock()
 	if _, err := l.logFile.WriteString(logEntry); err != nil {
 		return fmt.Errorf("failed to write log entry: %w", err)
 	}
@@ -31,6 +111,106 @@ func (l *Logger) LogMessage(messageID string, messageType string, details string
 }
 
 // Close closes the log file.
-func (l *Logger) Close() error {
+func (l *FileLogger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
 	return l.logFile.Close()
 }
+
+// Usage implements Prunable: one entry per log line.
+func (l *FileLogger) Usage() (int, int64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	lines, err := l.readLines()
+	if err != nil {
+		return 0, 0, err
+	}
+	var size int64
+	for _, ln := range lines {
+		size += int64(len(ln))
+	}
+	return len(lines), size, nil
+}
+
+// Prune implements Prunable.  The log file is rewritten atomically; lines
+// whose timestamp cannot be parsed are treated as recent.
+func (l *FileLogger) Prune(before time.Time, maxEntries int, maxBytes int64) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	lines, err := l.readLines()
+	if err != nil {
+		return 0, err
+	}
+
+	kept := lines[:0]
+	var size int64
+	for _, ln := range lines {
+		ts, _, _ := strings.Cut(string(ln), " | ")
+		if t, perr := time.Parse(time.RFC3339, ts); perr == nil && !before.IsZero() && t.Before(before) {
+			continue
+		}
+		kept = append(kept, ln)
+		size += int64(len(ln))
+	}
+	for len(kept) > 0 && ((maxEntries > 0 && len(kept) > maxEntries) || (maxBytes > 0 && size > maxBytes)) {
+		size -= int64(len(kept[0]))
+		kept = kept[1:]
+	}
+	evicted := len(lines) - len(kept)
+	if evicted == 0 {
+		return 0, nil
+	}
+	return evicted, l.rewrite(bytes.Join(kept, nil))
+}
+
+// readLines returns the log's lines including their trailing newlines.
+func (l *FileLogger) readLines() ([][]byte, error) {
+	f, err := os.Open(l.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read log file: %w", err)
+	}
+	defer f.Close()
+	var lines [][]byte
+	r := bufio.NewReader(f)
+	for {
+		ln, rerr := r.ReadBytes('\n')
+		if len(ln) > 0 {
+			lines = append(lines, ln)
+		}
+		if rerr != nil {
+			break
+		}
+	}
+	return lines, nil
+}
+
+// rewrite replaces the log's contents with data and reopens it for appending.
+func (l *FileLogger) rewrite(data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(l.path), filepath.Base(l.path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to rewrite log file: %w", err)
+	}
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to rewrite log file: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to rewrite log file: %w", err)
+	}
+	if err = os.Chmod(tmp.Name(), 0644); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to rewrite log file: %w", err)
+	}
+	if err = os.Rename(tmp.Name(), l.path); err != nil {
+		return fmt.Errorf("failed to rewrite log file: %w", err)
+	}
+	file, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	_ = l.logFile.Close()
+	l.logFile = file
+	return nil
+}
@@ -0,0 +1,65 @@
+package core_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func TestNegotiationBusLogs(t *testing.T) {
+	var buf bytes.Buffer
+	a, _ := core.NewAgent("beta", []string{"ocr"})
+	bus := core.NewNegotiationBus()
+	bus.SetLogger(slog.New(slog.NewJSONHandler(&buf, nil)))
+	bus.Register("beta", core.DefaultNegotiationHandler(a))
+
+	intent, _ := core.CreateIntent(a, nil, []string{"ocr"}, "scan")
+	if _, err := bus.Negotiate("beta", intent); err != nil {
+		t.Fatalf("Negotiate: %v", err)
+	}
+	var rec map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatalf("log record %q: %v", buf.String(), err)
+	}
+	if rec[core.LogKeyPeer] != "beta" || rec[core.LogKeyIntentID] != intent.ID ||
+		rec[core.LogKeyDecision] != "accepted" || rec[core.LogKeyMsgType] != "intent" {
+		t.Errorf("record: %v", rec)
+	}
+	if _, ok := rec[core.LogKeyLatency]; !ok {
+		t.Errorf("no latency in %v", rec)
+	}
+}
+
+func TestFileLoggerSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	fl, err := core.NewFileLogger(path)
+	if err != nil {
+		t.Fatalf("NewFileLogger: %v", err)
+	}
+	defer fl.Close()
+	var buf bytes.Buffer
+	l := core.MultiLogger(fl, slog.New(slog.NewTextHandler(&buf, nil)))
+
+	l.Log(context.Background(), slog.LevelInfo, "intent answered",
+		core.LogKeyIntentID, "i1", core.LogKeyMsgType, "intent", core.LogKeyLatency, 2*time.Millisecond)
+
+	data, _ := os.ReadFile(path)
+	line := string(data)
+	if !strings.Contains(line, "| ID: i1 | Type: intent | Details: INFO intent answered latency=2ms") {
+		t.Errorf("file line: %q", line)
+	}
+	if !strings.Contains(buf.String(), "intent_id=i1") {
+		t.Errorf("second sink: %q", buf.String())
+	}
+	if n, _, _ := fl.Usage(); n != 1 {
+		t.Errorf("Usage: %d lines", n)
+	}
+}
@@ -0,0 +1,134 @@
+package core
+
+// manifest.go — Capability descriptors with example intents.
+//
+// A bare capability string says nothing about how an agent actually behaves.
+// Agents may publish a manifest of CapabilityDescriptors in their handshake;
+// each descriptor can carry example intents together with the response shape
+// the agent promises to produce.  A requester runs the examples once after
+// the handshake (see CapabilityExample.Verify) before routing real traffic.
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ProbeMetadataKey marks an IntentMessage as a manifest probe so responders
+// can skip side effects, billing, or logging for it.
+const ProbeMetadataKey = "probe"
+
+// CapabilityDescriptor documents one capability listed in an agent's manifest.
+type CapabilityDescriptor struct {
+	Name        string              `json:"name"`                  // Capability string, as listed in Agent.Capabilities
+	Description string              `json:"description,omitempty"` // Human-readable summary
+	Examples    []CapabilityExample `json:"examples,omitempty"`
+}
+
+// CapabilityExample is a sample intent plus the response shape the
+// advertising agent promises to return for it.
+type CapabilityExample struct {
+	Name         string           `json:"name"`
+	IntentVector []float32        `json:"intent_vector,omitempty"`
+	Payload      string           `json:"payload,omitempty"`
+	Expect       ExpectedResponse `json:"expect"`
+}
+
+// ExpectedResponse describes the observable shape of a NegotiationResponse.
+// Zero-valued fields are not checked, except Accepted.
+type ExpectedResponse struct {
+	Accepted         bool     `json:"accepted"`
+	MinWorkflowSteps int      `json:"min_workflow_steps,omitempty"` // Minimum number of workflow steps
+	StepsContain     []string `json:"steps_contain,omitempty"`      // Substrings that must each appear in some workflow step
+	ReasonContains   string   `json:"reason_contains,omitempty"`    // Substring that must appear in Reason
+}
+
+// ProbeIntent builds the signed IntentMessage that exercises ex against the
+// capability named capability.
+func (ex CapabilityExample) ProbeIntent(sender *Agent, capability string) (*IntentMessage, error) {
+	intent, err := CreateIntent(sender, ex.IntentVector, []string{capability}, ex.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("probe %q: %w", ex.Name, err)
+	}
+	intent.Metadata[ProbeMetadataKey] = "true"
+	if err = SignIntent(sender, intent); err != nil {
+		return nil, fmt.Errorf("probe %q: %w", ex.Name, err)
+	}
+	return intent, nil
+}
+
+// Check compares resp against ex.Expect and describes the first mismatch.
+func (ex CapabilityExample) Check(resp *NegotiationResponse) error {
+	want := ex.Expect
+	if resp.Accepted != want.Accepted {
+		return fmt.Errorf("probe %q: accepted=%v, manifest promises %v (reason: %s)",
+			ex.Name, resp.Accepted, want.Accepted, resp.Reason)
+	}
+	if len(resp.WorkflowSteps) < want.MinWorkflowSteps {
+		return fmt.Errorf("probe %q: %d workflow steps, manifest promises at least %d",
+			ex.Name, len(resp.WorkflowSteps), want.MinWorkflowSteps)
+	}
+	for _, sub := range want.StepsContain {
+		if !anyContains(resp.WorkflowSteps, sub) {
+			return fmt.Errorf("probe %q: no workflow step contains %q", ex.Name, sub)
+		}
+	}
+	if want.ReasonContains != "" && !strings.Contains(resp.Reason, want.ReasonContains) {
+		return fmt.Errorf("probe %q: reason %q does not contain %q", ex.Name, resp.Reason, want.ReasonContains)
+	}
+	return nil
+}
+
+// Verify runs ex against capability through negotiate (e.g. a NegotiationBus
+// or p2p round trip) and checks the response.
+func (ex CapabilityExample) Verify(
+	sender *Agent,
+	capability string,
+	negotiate func(*IntentMessage) (*NegotiationResponse, error),
+) error {
+	intent, err := ex.ProbeIntent(sender, capability)
+	if err != nil {
+		return err
+	}
+	resp, err := negotiate(intent)
+	if err != nil {
+		return fmt.Errorf("probe %q: %w", ex.Name, err)
+	}
+	if resp.RequestID != intent.ID {
+		return fmt.Errorf("probe %q: response answers %q, not %q", ex.Name, resp.RequestID, intent.ID)
+	}
+	return ex.Check(resp)
+}
+
+// VerifyManifest runs every example in manifest and returns the first failure.
+func VerifyManifest(
+	sender *Agent,
+	manifest []CapabilityDescriptor,
+	negotiate func(*IntentMessage) (*NegotiationResponse, error),
+) error {
+	for _, d := range manifest {
+		for _, ex := range d.Examples {
+			if err := ex.Verify(sender, d.Name, negotiate); err != nil {
+				return fmt.Errorf("manifest %s: %w", d.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func anyContains(ss []string, sub string) bool {
+	for _, s := range ss {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
+
+func copyManifest(m []CapabilityDescriptor) []CapabilityDescriptor {
+	if m == nil {
+		return nil
+	}
+	out := make([]CapabilityDescriptor, len(m))
+	copy(out, m)
+	return out
+}
@@ -0,0 +1,84 @@
+package core_test
+
+import (
+	"testing"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func summariserManifest() []core.CapabilityDescriptor {
+	return []core.CapabilityDescriptor{{
+		Name:        "summarisation",
+		Description: "Summarise plain-text documents",
+		Examples: []core.CapabilityExample{{
+			Name:         "short-doc",
+			IntentVector: []float32{0.9, 0.1},
+			Payload:      "summarise: the quick brown fox",
+			Expect: core.ExpectedResponse{
+				Accepted:         true,
+				MinWorkflowSteps: 3,
+				StepsContain:     []string{"execute:summarisation"},
+			},
+		}},
+	}}
+}
+
+func TestVerifyManifestHonestAgent(t *testing.T) {
+	requester, _ := core.NewAgent("req", nil)
+	responder, _ := core.NewAgent("resp", []string{"summarisation"})
+	responder.Manifest = summariserManifest()
+
+	bus := core.NewNegotiationBus()
+	bus.Register("resp", core.DefaultNegotiationHandler(responder))
+
+	err := core.VerifyManifest(requester, responder.Manifest, func(i *core.IntentMessage) (*core.NegotiationResponse, error) {
+		if i.Metadata[core.ProbeMetadataKey] != "true" {
+			t.Error("probe intent should carry the probe metadata key")
+		}
+		return bus.Negotiate("resp", i)
+	})
+	if err != nil {
+		t.Errorf("VerifyManifest: %v", err)
+	}
+}
+
+func TestVerifyManifestDetectsMismatch(t *testing.T) {
+	requester, _ := core.NewAgent("req", nil)
+	// Advertises summarisation in its manifest but does not actually provide it.
+	liar, _ := core.NewAgent("liar", []string{"storage"})
+	liar.Manifest = summariserManifest()
+
+	bus := core.NewNegotiationBus()
+	bus.Register("liar", core.DefaultNegotiationHandler(liar))
+
+	err := core.VerifyManifest(requester, liar.Manifest, func(i *core.IntentMessage) (*core.NegotiationResponse, error) {
+		return bus.Negotiate("liar", i)
+	})
+	if err == nil {
+		t.Error("expected VerifyManifest to fail for an agent that rejects its own example")
+	}
+}
+
+func TestHandshakeManifestRoundTrip(t *testing.T) {
+	agent, _ := core.NewAgent("resp", []string{"summarisation"})
+	agent.Manifest = summariserManifest()
+
+	hs, err := core.StartHandshake(agent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, _ := hs.Encode()
+	decoded, err := core.DecodeHandshakeMessage(encoded)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if len(decoded.Manifest) != 1 || len(decoded.Manifest[0].Examples) != 1 {
+		t.Fatalf("manifest not preserved: %+v", decoded.Manifest)
+	}
+	ex := decoded.Manifest[0].Examples[0]
+	want := agent.Manifest[0].Examples[0]
+	if ex.Payload != want.Payload || !ex.Expect.Accepted || ex.Expect.MinWorkflowSteps != 3 ||
+		len(ex.Expect.StepsContain) != 1 || len(ex.IntentVector) != 2 {
+		t.Errorf("example mismatch: got %+v want %+v", ex, want)
+	}
+}
@@ -0,0 +1,490 @@
+package core
+
+// memory.go — Per-peer conversation memory.
+//
+// LLM-backed agents negotiate with the same counterparts again and again.  A
+// MemoryStore keeps short summaries and embeddings of past exchanges keyed by
+// peer DID, so a handler can recall what it agreed with (or refused to) a
+// peer before.  Handlers reach the sender's memory through the context:
+//
+//	if mem, ok := core.PeerMemoryFromContext(ctx); ok {
+//		past, _ := mem.Similar(intent.IntentVector, 3)
+//		...
+//	}
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryEntry is one remembered exchange with a peer.
+type MemoryEntry struct {
+	IntentID  string    `json:"intent_id,omitempty"`
+	Summary   string    `json:"summary"`
+	Embedding []float32 `json:"embedding,omitempty"`
+	Accepted  bool      `json:"accepted"`
+	Reason    string    `json:"reason,omitempty"`
+	Timestamp int64     `json:"timestamp"` // Unix nanoseconds
+}
+
+// MemoryStore persists MemoryEntries per peer DID.
+// Implementations must be concurrency-safe.
+type MemoryStore interface {
+	// Append records e for did, evicting the oldest entries beyond the
+	// store's per-peer limit.
+	Append(did string, e MemoryEntry) error
+	// Entries returns every entry for did, oldest first.
+	Entries(did string) ([]MemoryEntry, error)
+	// Forget deletes everything remembered about did.
+	Forget(did string) error
+}
+
+// MemoryExporter is a MemoryStore that can list everything it holds, so it
+// can be carried in an AgentSnapshot.
+type MemoryExporter interface {
+	// AllEntries returns every peer's entries, oldest first, keyed by DID.
+	AllEntries() (map[string][]MemoryEntry, error)
+}
+
+// DefaultMemoryPerPeer is the per-peer limit used when a store is given 0.
+const DefaultMemoryPerPeer = 256
+
+// MaxMemoryPeers bounds how many peers a store remembers.  Once it is
+// reached, Append for a peer not yet remembered fails with ErrMemoryFull
+// until Forget or Prune makes room.
+const MaxMemoryPeers = 4096
+
+// ErrMemoryFull is returned by Append when the store already remembers
+// MaxMemoryPeers peers.
+var ErrMemoryFull = fmt.Errorf("memory: too many peers")
+
+// MemorySummaryLen is how much of a payload the host keeps as a summary
+// when it records an exchange automatically.
+const MemorySummaryLen = 200
+
+// ------------------------------------------------------------------ in-memory store
+
+// InMemoryStore is a MemoryStore that lives only as long as the process.
+type InMemoryStore struct {
+	mu      sync.Mutex
+	max     int
+	entries map[string][]MemoryEntry
+}
+
+// NewInMemoryStore creates a store keeping at most maxPerPeer entries per DID.
+func NewInMemoryStore(maxPerPeer int) *InMemoryStore {
+	if maxPerPeer <= 0 {
+		maxPerPeer = DefaultMemoryPerPeer
+	}
+	return &InMemoryStore{max: maxPerPeer, entries: make(map[string][]MemoryEntry)}
+}
+
+// Append implements MemoryStore.
+func (s *InMemoryStore) Append(did string, e MemoryEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.entries[did]; !ok && len(s.entries) >= MaxMemoryPeers {
+		return ErrMemoryFull
+	}
+	s.entries[did] = trimMemory(append(s.entries[did], e), s.max)
+	return nil
+}
+
+// Entries implements MemoryStore.
+func (s *InMemoryStore) Entries(did string) ([]MemoryEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]MemoryEntry(nil), s.entries[did]...), nil
+}
+
+// Forget implements MemoryStore.
+func (s *InMemoryStore) Forget(did string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, did)
+	return nil
+}
+
+// Usage implements Prunable.
+func (s *InMemoryStore) Usage() (int, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n, size := memoryUsage(s.entries)
+	return n, size, nil
+}
+
+// Prune implements Prunable.
+func (s *InMemoryStore) Prune(before time.Time, maxEntries int, maxBytes int64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return pruneMemory(s.entries, before, maxEntries, maxBytes), nil
+}
+
+// AllEntries implements MemoryExporter.
+func (s *InMemoryStore) AllEntries() (map[string][]MemoryEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[string][]MemoryEntry, len(s.entries))
+	for did, entries := range s.entries {
+		out[did] = append([]MemoryEntry(nil), entries...)
+	}
+	return out, nil
+}
+
+// ------------------------------------------------------------------ file store
+
+// FileMemoryStore is a MemoryStore keeping one JSON file per peer in a
+// directory.  Files are rewritten atomically on every Append.
+type FileMemoryStore struct {
+	mu  sync.Mutex
+	dir string
+	max int
+}
+
+// OpenFileMemoryStore uses dir (created if missing) for per-peer files.
+func OpenFileMemoryStore(dir string, maxPerPeer int) (*FileMemoryStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("memory: create %s: %w", dir, err)
+	}
+	if maxPerPeer <= 0 {
+		maxPerPeer = DefaultMemoryPerPeer
+	}
+	return &FileMemoryStore{dir: dir, max: maxPerPeer}, nil
+}
+
+// Append implements MemoryStore.
+func (s *FileMemoryStore) Append(did string, e MemoryEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries, err := s.read(did)
+	if err != nil {
+		return err
+	}
+	if entries == nil {
+		if err = s.checkRoom(); err != nil {
+			return err
+		}
+	}
+	return s.write(did, trimMemory(append(entries, e), s.max))
+}
+
+// Entries implements MemoryStore.
+func (s *FileMemoryStore) Entries(did string) ([]MemoryEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.read(did)
+}
+
+// Forget implements MemoryStore.
+func (s *FileMemoryStore) Forget(did string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.Remove(s.path(did)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("memory: forget %s: %w", did, err)
+	}
+	return nil
+}
+
+// Usage implements Prunable.
+func (s *FileMemoryStore) Usage() (int, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	all, err := s.readAll()
+	if err != nil {
+		return 0, 0, err
+	}
+	n, size := memoryUsage(all)
+	return n, size, nil
+}
+
+// Prune implements Prunable.  Peers left without entries lose their file.
+func (s *FileMemoryStore) Prune(before time.Time, maxEntries int, maxBytes int64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	all, err := s.readAll()
+	if err != nil {
+		return 0, err
+	}
+	counts := make(map[string]int, len(all))
+	for did, entries := range all {
+		counts[did] = len(entries)
+	}
+	n := pruneMemory(all, before, maxEntries, maxBytes)
+	for did, count := range counts {
+		entries, ok := all[did]
+		switch {
+		case !ok:
+			if err = os.Remove(s.path(did)); err != nil && !os.IsNotExist(err) {
+				return n, fmt.Errorf("memory: prune %s: %w", did, err)
+			}
+		case len(entries) != count:
+			if err = s.write(did, entries); err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// AllEntries implements MemoryExporter.
+func (s *FileMemoryStore) AllEntries() (map[string][]MemoryEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.readAll()
+}
+
+// readAll loads every peer's entries, keyed by DID.
+func (s *FileMemoryStore) readAll() (map[string][]MemoryEntry, error) {
+	files, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("memory: list %s: %w", s.dir, err)
+	}
+	all := make(map[string][]MemoryEntry)
+	for _, f := range files {
+		name, ok := strings.CutSuffix(f.Name(), ".json")
+		if !ok || f.IsDir() {
+			continue
+		}
+		did, err := url.PathUnescape(name)
+		if err != nil {
+			continue
+		}
+		entries, err := s.read(did)
+		if err != nil {
+			return nil, err
+		}
+		all[did] = entries
+	}
+	return all, nil
+}
+
+// checkRoom fails with ErrMemoryFull if the directory already holds
+// MaxMemoryPeers peer files.
+func (s *FileMemoryStore) checkRoom() error {
+	files, err := os.ReadDir(s.dir)
+	if err != nil {
+		return fmt.Errorf("memory: list %s: %w", s.dir, err)
+	}
+	n := 0
+	for _, f := range files {
+		if !f.IsDir() && strings.HasSuffix(f.Name(), ".json") {
+			n++
+		}
+	}
+	if n >= MaxMemoryPeers {
+		return ErrMemoryFull
+	}
+	return nil
+}
+
+func (s *FileMemoryStore) path(did string) string {
+	return filepath.Join(s.dir, url.PathEscape(did)+".json")
+}
+
+func (s *FileMemoryStore) read(did string) ([]MemoryEntry, error) {
+	data, err := os.ReadFile(s.path(did))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("memory: read %s: %w", did, err)
+	}
+	var entries []MemoryEntry
+	if err = json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("memory: parse %s: %w", did, err)
+	}
+	return entries, nil
+}
+
+func (s *FileMemoryStore) write(did string, entries []MemoryEntry) error {
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return fmt.Errorf("memory: encode: %w", err)
+	}
+	path := s.path(did)
+	tmp, err := os.CreateTemp(s.dir, filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("memory: create temp: %w", err)
+	}
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("memory: write: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("memory: close: %w", err)
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("memory: rename: %w", err)
+	}
+	return nil
+}
+
+// ------------------------------------------------------------------ per-peer view
+
+// PeerMemory is a MemoryStore scoped to one peer.
+type PeerMemory struct {
+	DID   string
+	Store MemoryStore
+}
+
+// Remember appends e for the peer.
+func (m PeerMemory) Remember(e MemoryEntry) error { return m.Store.Append(m.DID, e) }
+
+// Recent returns up to n of the newest entries, newest first.
+func (m PeerMemory) Recent(n int) ([]MemoryEntry, error) {
+	entries, err := m.Store.Entries(m.DID)
+	if err != nil {
+		return nil, err
+	}
+	n = max(n, 0)
+	out := make([]MemoryEntry, 0, n)
+	for i := len(entries) - 1; i >= 0 && len(out) < n; i-- {
+		out = append(out, entries[i])
+	}
+	return out, nil
+}
+
+// Similar returns up to k entries whose embeddings are closest to vector by
+// cosine similarity, best first.  Entries without embeddings are skipped.
+func (m PeerMemory) Similar(vector []float32, k int) ([]MemoryEntry, error) {
+	entries, err := m.Store.Entries(m.DID)
+	if err != nil {
+		return nil, err
+	}
+	type scored struct {
+		e MemoryEntry
+		s float64
+	}
+	ranked := make([]scored, 0, len(entries))
+	for _, e := range entries {
+		if len(e.Embedding) > 0 {
+			ranked = append(ranked, scored{e, CosineSimilarity(vector, e.Embedding)})
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].s > ranked[j].s })
+	if k = max(k, 0); len(ranked) > k {
+		ranked = ranked[:k]
+	}
+	out := make([]MemoryEntry, len(ranked))
+	for i, r := range ranked {
+		out[i] = r.e
+	}
+	return out, nil
+}
+
+type peerMemoryKey struct{}
+
+// ContextWithPeerMemory returns a context carrying m.
+func ContextWithPeerMemory(ctx context.Context, m PeerMemory) context.Context {
+	return context.WithValue(ctx, peerMemoryKey{}, m)
+}
+
+// PeerMemoryFromContext returns the sender's memory placed in ctx by the
+// host, if a MemoryStore is configured.
+func PeerMemoryFromContext(ctx context.Context) (PeerMemory, bool) {
+	m, ok := ctx.Value(peerMemoryKey{}).(PeerMemory)
+	return m, ok
+}
+
+// MemoryEntryFor summarises an exchange as a MemoryEntry.
+func MemoryEntryFor(intent *IntentMessage, resp *NegotiationResponse) MemoryEntry {
+	summary := intent.Payload
+	if len(summary) > MemorySummaryLen {
+		summary = summary[:MemorySummaryLen]
+	}
+	e := MemoryEntry{
+		IntentID:  intent.ID,
+		Summary:   summary,
+		Embedding: append([]float32(nil), intent.IntentVector...),
+		Timestamp: now(),
+	}
+	if resp != nil {
+		e.Accepted = resp.Accepted
+		e.Reason = resp.Reason
+	}
+	return e
+}
+
+func trimMemory(entries []MemoryEntry, max int) []MemoryEntry {
+	if len(entries) > max {
+		entries = append([]MemoryEntry(nil), entries[len(entries)-max:]...)
+	}
+	return entries
+}
+
+// memoryEntrySize approximates an entry's stored size as its JSON length.
+func memoryEntrySize(e MemoryEntry) int64 {
+	data, _ := json.Marshal(e)
+	return int64(len(data))
+}
+
+func memoryUsage(all map[string][]MemoryEntry) (int, int64) {
+	var n int
+	var size int64
+	for _, entries := range all {
+		n += len(entries)
+		for _, e := range entries {
+			size += memoryEntrySize(e)
+		}
+	}
+	return n, size
+}
+
+// pruneMemory applies Prunable.Prune semantics to all in place, relying on
+// each peer's entries being oldest first.  Peers left empty are deleted.
+func pruneMemory(all map[string][]MemoryEntry, before time.Time, maxEntries int, maxBytes int64) int {
+	type ref struct {
+		did  string
+		ts   int64
+		size int64
+	}
+	var evicted int
+	var refs []ref
+	var total int64
+	for did, entries := range all {
+		kept := entries[:0]
+		for _, e := range entries {
+			if !before.IsZero() && e.Timestamp < before.UnixNano() {
+				evicted++
+				continue
+			}
+			kept = append(kept, e)
+			size := memoryEntrySize(e)
+			refs = append(refs, ref{did, e.Timestamp, size})
+			total += size
+		}
+		all[did] = kept
+	}
+
+	drop := make(map[string]int)
+	remaining := len(refs)
+	sort.SliceStable(refs, func(i, j int) bool { return refs[i].ts < refs[j].ts })
+	for _, r := range refs {
+		if (maxEntries <= 0 || remaining <= maxEntries) && (maxBytes <= 0 || total <= maxBytes) {
+			break
+		}
+		drop[r.did]++
+		remaining--
+		total -= r.size
+	}
+	for did, k := range drop {
+		all[did] = all[did][k:]
+		evicted += k
+	}
+	for did, entries := range all {
+		if len(entries) == 0 {
+			delete(all, did)
+		}
+	}
+	return evicted
+}
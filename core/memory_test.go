@@ -0,0 +1,97 @@
+package core_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func TestFileMemoryStorePersistsAndTrims(t *testing.T) {
+	dir := t.TempDir()
+	const did = "did:agent-semantic-protocol:peer"
+
+	s, err := core.OpenFileMemoryStore(dir, 2)
+	if err != nil {
+		t.Fatalf("OpenFileMemoryStore: %v", err)
+	}
+	for _, sum := range []string{"first", "second", "third"} {
+		if err = s.Append(did, core.MemoryEntry{Summary: sum}); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+
+	reopened, err := core.OpenFileMemoryStore(dir, 2)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	recent, err := core.PeerMemory{DID: did, Store: reopened}.Recent(5)
+	if err != nil {
+		t.Fatalf("Recent: %v", err)
+	}
+	if len(recent) != 2 || recent[0].Summary != "third" || recent[1].Summary != "second" {
+		t.Errorf("unexpected entries after trim: %+v", recent)
+	}
+
+	if err = reopened.Forget(did); err != nil {
+		t.Fatalf("Forget: %v", err)
+	}
+	if entries, _ := reopened.Entries(did); len(entries) != 0 {
+		t.Errorf("Forget left %d entries", len(entries))
+	}
+}
+
+func TestPeerMemorySimilarAndContext(t *testing.T) {
+	m := core.PeerMemory{DID: "did:x", Store: core.NewInMemoryStore(0)}
+	_ = m.Remember(core.MemoryEntry{Summary: "code", Embedding: []float32{1, 0}})
+	_ = m.Remember(core.MemoryEntry{Summary: "poetry", Embedding: []float32{0, 1}})
+	_ = m.Remember(core.MemoryEntry{Summary: "no vector"})
+
+	got, err := m.Similar([]float32{0.9, 0.1}, 1)
+	if err != nil {
+		t.Fatalf("Similar: %v", err)
+	}
+	if len(got) != 1 || got[0].Summary != "code" {
+		t.Errorf("Similar: got %+v", got)
+	}
+
+	ctx := core.ContextWithPeerMemory(context.Background(), m)
+	if pm, ok := core.PeerMemoryFromContext(ctx); !ok || pm.DID != "did:x" {
+		t.Error("peer memory not recoverable from context")
+	}
+	if _, ok := core.PeerMemoryFromContext(context.Background()); ok {
+		t.Error("empty context should carry no memory")
+	}
+}
+
+func TestPeerMemoryNegativeCounts(t *testing.T) {
+	m := core.PeerMemory{DID: "did:x", Store: core.NewInMemoryStore(0)}
+	_ = m.Remember(core.MemoryEntry{Summary: "code", Embedding: []float32{1, 0}})
+	if got, err := m.Recent(-1); err != nil || len(got) != 0 {
+		t.Errorf("Recent(-1): %+v, %v", got, err)
+	}
+	if got, err := m.Similar([]float32{1, 0}, -1); err != nil || len(got) != 0 {
+		t.Errorf("Similar(-1): %+v, %v", got, err)
+	}
+}
+
+func TestMemoryStoreCapsPeers(t *testing.T) {
+	s := core.NewInMemoryStore(1)
+	for i := range core.MaxMemoryPeers {
+		if err := s.Append(fmt.Sprintf("did:%d", i), core.MemoryEntry{}); err != nil {
+			t.Fatalf("Append %d: %v", i, err)
+		}
+	}
+	if err := s.Append("did:new", core.MemoryEntry{}); !errors.Is(err, core.ErrMemoryFull) {
+		t.Errorf("new peer past the cap: got %v, want ErrMemoryFull", err)
+	}
+	if err := s.Append("did:0", core.MemoryEntry{}); err != nil {
+		t.Errorf("remembered peer past the cap: %v", err)
+	}
+	_ = s.Forget("did:1")
+	if err := s.Append("did:new", core.MemoryEntry{}); err != nil {
+		t.Errorf("new peer after Forget: %v", err)
+	}
+}
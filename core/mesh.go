@@ -0,0 +1,52 @@
+package core
+
+// mesh.go — Mesh names: isolating deployments that share a network.
+//
+// Hosts with different mesh names use different libp2p protocol IDs and so
+// never exchange streams (see p2p.WithMesh).  The name also travels in the
+// handshake, so a peer that somehow reaches the wrong mesh is refused with
+// ErrMeshMismatch instead of being served.
+
+import "fmt"
+
+// ErrMeshMismatch is returned when a handshake crosses mesh boundaries.
+var ErrMeshMismatch = fmt.Errorf("handshake: peer belongs to a different mesh")
+
+// ValidMeshName reports whether name can be used as a mesh name: empty (the
+// default mesh) or one path segment of letters, digits, '-', '_' and '.'.
+func ValidMeshName(name string) bool {
+	if name == "." || name == ".." {
+		return false
+	}
+	for _, c := range name {
+		if !(c == '-' || c == '_' || c == '.' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckMesh compares our mesh name with the one in a peer's handshake.
+func CheckMesh(ours string, peer *HandshakeMessage) error {
+	if peer.Mesh == ours {
+		return nil
+	}
+	return fmt.Errorf("%w: peer is in %s, we are in %s", ErrMeshMismatch, meshLabel(peer.Mesh), meshLabel(ours))
+}
+
+// MeshMismatchReply is the handshake a responder sends to an initiator from
+// another mesh: its identity and mesh name only.
+func MeshMismatchReply(responder *Agent, mesh string) *HandshakeMessage {
+	return &HandshakeMessage{
+		AgentID: responder.ID,
+		DID:     responder.DID.String(),
+		Mesh:    mesh,
+	}
+}
+
+func meshLabel(name string) string {
+	if name == "" {
+		return "the default mesh"
+	}
+	return fmt.Sprintf("mesh %q", name)
+}
@@ -0,0 +1,31 @@
+package core_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func TestCheckMesh(t *testing.T) {
+	a, _ := core.NewAgent("a", nil)
+	if err := core.CheckMesh("prod", &core.HandshakeMessage{Mesh: "prod"}); err != nil {
+		t.Errorf("same mesh: %v", err)
+	}
+	if err := core.CheckMesh("", &core.HandshakeMessage{}); err != nil {
+		t.Errorf("default mesh: %v", err)
+	}
+	err := core.CheckMesh("prod", core.MeshMismatchReply(a, "staging"))
+	if !errors.Is(err, core.ErrMeshMismatch) {
+		t.Fatalf("got %v, want ErrMeshMismatch", err)
+	}
+	if want := `handshake: peer belongs to a different mesh: peer is in mesh "staging", we are in mesh "prod"`; err.Error() != want {
+		t.Errorf("error %q, want %q", err, want)
+	}
+
+	for name, ok := range map[string]bool{"": true, "prod": true, "eu-west_1.b": true, "a/b": false, "..": false, "x y": false} {
+		if core.ValidMeshName(name) != ok {
+			t.Errorf("ValidMeshName(%q) = %v", name, !ok)
+		}
+	}
+}
@@ -9,14 +9,13 @@ package core
 // maintained by the receiving agent.
 
 import (
+	"context"
+	"encoding/hex"
 	"fmt"
 	"math"
-	"sort"
-	"sync"
 	"time"
 
-	"crypto/rand"
-	"encoding/hex"
+	"github.com/olserra/agent-semantic-protocol/embeddings"
 )
 
 // NegotiationHandler is a callback invoked when an agent receives an intent.
@@ -26,13 +25,93 @@ type NegotiationHandler func(intent *IntentMessage) (*NegotiationResponse, error
 // DefaultNegotiationHandler builds a NegotiationHandler that accepts any
 // intent whose required capabilities are all present in provided.
 func DefaultNegotiationHandler(agent *Agent) NegotiationHandler {
+	return DefaultNegotiationHandlerWithAliases(agent, nil)
+}
+
+// DefaultNegotiationHandlerWithAliases is DefaultNegotiationHandler with
+// both the required and the provided capabilities normalised through
+// aliases, so a legacy name on either side still matches.
+func DefaultNegotiationHandlerWithAliases(agent *Agent, aliases *CapabilityAliases) NegotiationHandler {
+	return DefaultNegotiationHandlerWithOptions(agent, NegotiationOptions{Aliases: aliases})
+}
+
+// NegotiationOptions configure DefaultNegotiationHandlerWithOptions.
+type NegotiationOptions struct {
+	Aliases *CapabilityAliases // Normalise capability names; nil for none
+
+	// With Trust set, intents from senders the agent has no direct trust
+	// score for are refused unless the agent's transitive trust in them,
+	// over at most MaxTrustHops edges (DefaultTrustHops when 0), reaches
+	// MinTrust.
+	Trust        *TrustGraph
+	MinTrust     float32
+	MaxTrustHops int
+
+	// With Catalog set, the handler scores the intent vector against the
+	// catalog vectors of the agent's capabilities and reports the best
+	// match as SimilarityScore.  Intents scoring below MinSimilarity are
+	// refused; intents it cannot score are not.
+	Catalog       *CapabilityCatalog
+	MinSimilarity float32
+
+	// TrustPolicy sets the TrustDelta suggested to the requester;
+	// DefaultTrustPolicy when nil.
+	TrustPolicy TrustPolicy
+
+	// ServiceTimes holds how long the agent expects to take per
+	// capability, by canonical name when Aliases is set.  Intents whose Deadline or MaxLatency the slowest of
+	// their capabilities would miss are refused with
+	// ReasonDeadlineUnmeetable; so are intents whose Deadline has passed.
+	ServiceTimes map[string]time.Duration
+}
+
+// DefaultNegotiationHandlerWithOptions is DefaultNegotiationHandler
+// configured by opts.
+func DefaultNegotiationHandlerWithOptions(agent *Agent, opts NegotiationOptions) NegotiationHandler {
+	hops := opts.MaxTrustHops
+	if hops == 0 {
+		hops = DefaultTrustHops
+	}
+	policy := opts.TrustPolicy
+	if policy == nil {
+		policy = DefaultTrustPolicy
+	}
 	return func(intent *IntentMessage) (*NegotiationResponse, error) {
-		missing := missingCapabilities(intent.Capabilities, agent.Capabilities)
+		missing := missingCapabilities(opts.Aliases.Normalize(intent.Capabilities), opts.Aliases.Normalize(agent.Capabilities))
 		accepted := len(missing) == 0
 
+		// Senders with a direct score have a track record here; others
+		// must be vouched for by peers the agent trusts.
+		trusted, trust := true, float32(0)
+		if opts.Trust != nil && !opts.Trust.hasEdge(agent.DID.String(), intent.DID) {
+			trust = opts.Trust.ComputeTransitiveTrust(agent.DID.String(), intent.DID, hops)
+			trusted = trust >= opts.MinTrust
+		}
+
+		var similarity float32
+		assessed := false
+		if opts.Catalog != nil {
+			var s float64
+			s, assessed = opts.Catalog.VectorSimilarity(intent.IntentVector, agent.Capabilities...)
+			similarity = float32(s)
+		}
+
 		reason := "all capabilities available"
-		if !accepted {
+		switch {
+		case intent.Expired(time.Now()):
+			accepted = false
+			reason = "intent expired"
+		case !trusted:
+			accepted = false
+			reason = fmt.Sprintf("insufficient transitive trust: %.2f below %.2f", trust, opts.MinTrust)
+		case !accepted:
 			reason = fmt.Sprintf("missing capabilities: %v", missing)
+		case assessed && similarity < opts.MinSimilarity:
+			accepted = false
+			reason = fmt.Sprintf("intent similarity %.2f below %.2f", similarity, opts.MinSimilarity)
+		case !intent.CanMeet(time.Now(), serviceTime(opts.ServiceTimes, opts.Aliases.Normalize(intent.Capabilities))):
+			accepted = false
+			reason = ReasonDeadlineUnmeetable
 		}
 
 		steps := []string{}
@@ -49,15 +128,27 @@ func DefaultNegotiationHandler(agent *Agent) NegotiationHandler {
 			ResponseVector: reflectVector(intent.IntentVector),
 			Timestamp:      time.Now().UnixNano(),
 			Reason:         reason,
-			TrustDelta:     trustDelta(accepted),
+			TrustDelta:     policy.TrustDelta(TrustOutcome{Capability: intentCapability(intent), Accepted: accepted}),
+		}
+		if assessed {
+			resp.SimilarityScore = similarity
 		}
-		if sig, err := agent.DID.Sign([]byte(resp.RequestID + resp.Reason)); err == nil {
-			resp.Signature = sig
+		if err := SignResponse(agent, resp); err != nil {
+			return nil, err
 		}
 		return resp, nil
 	}
 }
 
+// serviceTime returns the longest of times for capabilities.
+func serviceTime(times map[string]time.Duration, capabilities []string) time.Duration {
+	var d time.Duration
+	for _, c := range capabilities {
+		d = max(d, times[c])
+	}
+	return d
+}
+
 // CreateIntent constructs an IntentMessage ready to be sent.
 func CreateIntent(
 	sender *Agent,
@@ -65,7 +156,7 @@ func CreateIntent(
 	requiredCapabilities []string,
 	payload string,
 ) (*IntentMessage, error) {
-	id, err := randomID()
+	id, err := sender.newIntentID(payload, requiredCapabilities, intentVector)
 	if err != nil {
 		return nil, err
 	}
@@ -79,12 +170,38 @@ func CreateIntent(
 		TrustScore:   0.5,
 		Metadata:     map[string]string{"protocol": ProtocolVersion},
 	}
-	sig, err := sender.DID.Sign([]byte(intent.ID + intent.Payload))
+	if err = SignIntent(sender, intent); err != nil {
+		return nil, fmt.Errorf("CreateIntent: %w", err)
+	}
+	return intent, nil
+}
+
+// SignIntent sets intent.Signature to sender's signature over every other
+// field of the intent.  CreateIntent signs the intents it returns; call
+// SignIntent again after changing one.
+func SignIntent(sender *Agent, intent *IntentMessage) error {
+	sig, err := sender.Sign(intentSigningData(intent))
 	if err != nil {
-		return nil, fmt.Errorf("CreateIntent: sign: %w", err)
+		return fmt.Errorf("sign intent: %w", err)
 	}
 	intent.Signature = sig
-	return intent, nil
+	return nil
+}
+
+// CreateIntentFromText embeds text with e and returns a signed intent
+// carrying the vector, with text as its payload.
+func CreateIntentFromText(
+	ctx context.Context,
+	sender *Agent,
+	e embeddings.Embedder,
+	requiredCapabilities []string,
+	text string,
+) (*IntentMessage, error) {
+	vec, err := e.Embed(ctx, text)
+	if err != nil {
+		return nil, fmt.Errorf("CreateIntentFromText: embed: %w", err)
+	}
+	return CreateIntent(sender, vec, requiredCapabilities, text)
 }
 
 // CosineSimilarity returns the cosine similarity of two equal-length vectors.
@@ -93,12 +210,7 @@ func CosineSimilarity(a, b []float32) float64 {
 	if len(a) != len(b) || len(a) == 0 {
 		return 0
 	}
-	var dot, normA, normB float64
-	for i := range a {
-		dot += float64(a[i]) * float64(b[i])
-		normA += float64(a[i]) * float64(a[i])
-		normB += float64(b[i]) * float64(b[i])
-	}
+	dot, normA, normB := dotNorms(a, b)
 	if normA == 0 || normB == 0 {
 		return 0
 	}
@@ -107,22 +219,18 @@ func CosineSimilarity(a, b []float32) float64 {
 
 // RankCandidates sorts a list of agents by cosine similarity to the intent
 // vector, highest first.  Agents without a registered embedding vector are
-// ranked last.
+// ranked last.  Callers that want only the best few should use TopK.
 func RankCandidates(intentVector []float32, candidates []AgentProfile) []AgentProfile {
-	type ranked struct {
-		profile AgentProfile
-		score   float64
-	}
-	rs := make([]ranked, len(candidates))
-	for i, c := range candidates {
-		rs[i] = ranked{c, CosineSimilarity(intentVector, c.EmbeddingVector)}
-	}
-	sort.Slice(rs, func(i, j int) bool { return rs[i].score > rs[j].score })
-	out := make([]AgentProfile, len(rs))
-	for i, r := range rs {
-		out[i] = r.profile
-	}
-	return out
+	return RankAssessed(intentVector, candidates, nil, 0)
+}
+
+// RankAssessed is RankCandidates for agents that have answered the intent
+// and reported a SimilarityScore, keyed by AgentID in assessed.  Each
+// agent's local cosine similarity is blended with its own assessment as
+// (1-weight)*local + weight*assessed; agents without an assessment count
+// their local score for both.  A weight of 0 ranks like RankCandidates.
+func RankAssessed(intentVector []float32, candidates []AgentProfile, assessed map[string]float32, weight float64) []AgentProfile {
+	return TopKAssessed(intentVector, candidates, assessed, weight, len(candidates))
 }
 
 // AgentProfile holds a peer agent's public capability profile for ranking.
@@ -130,12 +238,19 @@ type AgentProfile struct {
 	AgentID         string
 	DID             string
 	Capabilities    []string
-	EmbeddingVector []float32 // Optional representative vector for the agent
-	PublicKey       []byte    // Ed25519 public key; set after a handshake
+	EmbeddingVector []float32              // Optional representative vector for the agent
+	PublicKey       []byte                 // Ed25519 public key; set after a handshake
+	Manifest        []CapabilityDescriptor // Capability examples; set after a handshake
+	Specs           []CapabilitySpec       // Capability contracts; set from handshakes and announcements
+	KeyAgreement    []byte                 // X25519 key for sealed payloads; set after a handshake
+	Provenance      []ProvenanceTag        // Federation gateways its capabilities came through; set from announcements
+	Liveness        *Liveness              // Set by a HealthMonitor once it has probed the agent
+	Credentials     []VerifiableCredential // Issuers' claims about the agent; set from handshakes and announcements
 }
 
 // VerifyIntentSignature returns true if intent.Signature is a valid Ed25519
-// signature of (intent.ID + intent.Payload) by the owner of pubKey.
+// signature of the rest of the intent (see SignIntent) by the owner of
+// pubKey.
 // Returns true when Signature is empty (unsigned messages are accepted).
 func VerifyIntentSignature(intent *IntentMessage, pubKey []byte) bool {
 	if len(intent.Signature) == 0 {
@@ -145,11 +260,11 @@ func VerifyIntentSignature(intent *IntentMessage, pubKey []byte) bool {
 	if err != nil {
 		return false
 	}
-	return d.Verify([]byte(intent.ID+intent.Payload), intent.Signature)
+	return d.Verify(intentSigningData(intent), intent.Signature)
 }
 
 // VerifyResponseSignature returns true if resp.Signature is a valid Ed25519
-// signature of (resp.RequestID + resp.Reason) by the owner of pubKey.
+// signature of the response (see SignResponse) by the owner of pubKey.
 // Returns true when Signature is empty (unsigned messages are accepted).
 func VerifyResponseSignature(resp *NegotiationResponse, pubKey []byte) bool {
 	if len(resp.Signature) == 0 {
@@ -159,39 +274,7 @@ func VerifyResponseSignature(resp *NegotiationResponse, pubKey []byte) bool {
 	if err != nil {
 		return false
 	}
-	return d.Verify([]byte(resp.RequestID+resp.Reason), resp.Signature)
-}
-
-// ------------------------------------------------------------------ in-process negotiation bus
-
-// NegotiationBus enables in-process agents to negotiate without a real network,
-// suitable for tests and examples.
-type NegotiationBus struct {
-	mu       sync.RWMutex
-	handlers map[string]NegotiationHandler // keyed by agentID
-}
-
-// NewNegotiationBus creates an empty NegotiationBus.
-func NewNegotiationBus() *NegotiationBus {
-	return &NegotiationBus{handlers: make(map[string]NegotiationHandler)}
-}
-
-// Register attaches a handler for the given agentID.
-func (b *NegotiationBus) Register(agentID string, h NegotiationHandler) {
-	b.mu.Lock()
-	defer b.mu.Unlock()
-	b.handlers[agentID] = h
-}
-
-// Negotiate sends an intent to targetAgentID and returns the response.
-func (b *NegotiationBus) Negotiate(targetAgentID string, intent *IntentMessage) (*NegotiationResponse, error) {
-	b.mu.RLock()
-	h, ok := b.handlers[targetAgentID]
-	b.mu.RUnlock()
-	if !ok {
-		return nil, fmt.Errorf("negotiation: no handler for agent %q", targetAgentID)
-	}
-	return h(intent)
+	return d.Verify(responseSigningData(resp), resp.Signature)
 }
 
 // ------------------------------------------------------------------ helpers
@@ -234,28 +317,10 @@ func reflectVector(v []float32) []float32 {
 	return out
 }
 
-func trustDelta(accepted bool) float32 {
-	if accepted {
-		return 0.05
-	}
-	return -0.02
-}
-
-func randomID() (string, error) {
+func (a *Agent) randomID() (string, error) {
 	b := make([]byte, 16)
-	if _, err := rand.Read(b); err != nil {
+	if err := a.random(b); err != nil {
 		return "", fmt.Errorf("randomID: %w", err)
 	}
 	return hex.EncodeToString(b), nil
 }
-
-// LogIntentMessage logs an intent message.
-func LogIntentMessage(intent *IntentMessage) error {
-	if intent.Logger != nil {
-		logErr := intent.Logger.LogMessage(intent.ID, "IntentMessage", fmt.Sprintf("Capabilities: %v, Payload: %s", intent.Capabilities, intent.Payload))
-		if logErr != nil {
-			return fmt.Errorf("failed to log intent message: %w", logErr)
-		}
-	}
-	return nil
-}
@@ -0,0 +1,15 @@
+package core
+
+// observe.go — Read-only observers.
+//
+// A host may grant DIDs the observer role: they may subscribe to its events
+// with an ObserveRequest, but the host refuses their intents.
+
+// ReasonObserverOnly is the Reason of a NegotiationResponse refusing an
+// intent because its sender holds the observer role, which grants no
+// execution rights.
+const ReasonObserverOnly = "observer_only"
+
+// ReasonNotObserver is the Reason of a NegotiationResponse refusing an
+// ObserveRequest from a peer without the observer role.
+const ReasonNotObserver = "not_observer"
@@ -0,0 +1,46 @@
+package core_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func TestObserveMessagesRoundTrip(t *testing.T) {
+	req := &core.ObserveRequest{Kinds: []string{"trust_updated", "intent_rejected"}}
+	ev := &core.EventMessage{
+		Kind:         "intent_rejected",
+		PeerID:       "12D3KooW",
+		AgentID:      "beta",
+		DID:          "did:x",
+		IntentID:     "i1",
+		Capabilities: []string{"ocr", "nlp"},
+		Reason:       core.ReasonObserverOnly,
+		Delta:        -0.05,
+		Trust:        0.45,
+		Timestamp:    42,
+		Dropped:      3,
+	}
+	for _, m := range []core.Encoder{req, ev} {
+		for _, c := range []core.Codec{core.ProtobufCodec, core.JSONCodec, core.CBORCodec} {
+			frame, err := core.FrameWith(c, m)
+			if err != nil {
+				t.Fatalf("%T/%s: %v", m, c.Name(), err)
+			}
+			typ, payload, err := core.Unframe(frame)
+			if err != nil {
+				t.Fatalf("%T/%s: %v", m, c.Name(), err)
+			}
+			msgType, codec := core.SplitFrameType(byte(typ))
+			pb, err := core.Transcode(codec, msgType, payload)
+			if err != nil {
+				t.Fatalf("%T/%s: Transcode: %v", m, c.Name(), err)
+			}
+			decoded, err := core.Decode(msgType, pb)
+			if err != nil || !reflect.DeepEqual(decoded, m) {
+				t.Errorf("%T/%s = %+v, %v; want %+v", m, c.Name(), decoded, err, m)
+			}
+		}
+	}
+}
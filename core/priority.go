@@ -0,0 +1,97 @@
+package core
+
+// priority.go — Scheduling hints carried by intents.
+//
+// An intent's sender can say how urgently it wants the intent served and
+// what share of a larger budget it may consume.  Both travel in Metadata so
+// peers that do not understand them simply ignore them; the deadline travels
+// as ExpiresAt, which every receiver already enforces.
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Metadata keys for scheduling hints.
+const (
+	PriorityMetadataKey    = "priority"
+	BudgetShareMetadataKey = "budget_share"
+)
+
+// Priority is how urgently an intent's sender wants it served.  The zero
+// value is PriorityNormal.
+type Priority int
+
+const (
+	PriorityLow Priority = iota - 1
+	PriorityNormal
+	PriorityHigh
+	PriorityCritical
+)
+
+// String returns the name used for p in Metadata.
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityNormal:
+		return "normal"
+	case PriorityHigh:
+		return "high"
+	case PriorityCritical:
+		return "critical"
+	default:
+		return fmt.Sprintf("Priority(%d)", int(p))
+	}
+}
+
+// ParsePriority is the inverse of Priority.String.
+func ParsePriority(s string) (Priority, error) {
+	for p := PriorityLow; p <= PriorityCritical; p++ {
+		if s == p.String() {
+			return p, nil
+		}
+	}
+	return PriorityNormal, fmt.Errorf("unknown priority %q", s)
+}
+
+// Priority returns m's priority, PriorityNormal if it has none or an
+// unknown one.
+func (m *IntentMessage) Priority() Priority {
+	p, _ := ParsePriority(m.Metadata[PriorityMetadataKey])
+	return p
+}
+
+// SetPriority records p in m's Metadata.  PriorityNormal is left implicit.
+func (m *IntentMessage) SetPriority(p Priority) {
+	if p == PriorityNormal {
+		delete(m.Metadata, PriorityMetadataKey)
+		return
+	}
+	if m.Metadata == nil {
+		m.Metadata = make(map[string]string)
+	}
+	m.Metadata[PriorityMetadataKey] = p.String()
+}
+
+// BudgetShare returns the share of its workflow's budget that m may
+// consume, and whether it carries one.
+func (m *IntentMessage) BudgetShare() (float64, bool) {
+	s, ok := m.Metadata[BudgetShareMetadataKey]
+	if !ok {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v < 0 {
+		return 0, false
+	}
+	return v, true
+}
+
+// SetBudgetShare records budget in m's Metadata.
+func (m *IntentMessage) SetBudgetShare(budget float64) {
+	if m.Metadata == nil {
+		m.Metadata = make(map[string]string)
+	}
+	m.Metadata[BudgetShareMetadataKey] = strconv.FormatFloat(budget, 'g', -1, 64)
+}
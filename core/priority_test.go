@@ -0,0 +1,50 @@
+package core_test
+
+import (
+	"testing"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func TestIntentPriority(t *testing.T) {
+	a, _ := core.NewAgent("a", []string{"nlp"})
+	intent, _ := core.CreateIntent(a, nil, []string{"nlp"}, "summarise")
+	if p := intent.Priority(); p != core.PriorityNormal {
+		t.Errorf("default priority: %v", p)
+	}
+	intent.SetPriority(core.PriorityCritical)
+	intent.SetBudgetShare(2.5)
+	if core.VerifyIntentSignature(intent, a.PublicKey()) {
+		t.Error("the signature does not cover the hints")
+	}
+	if err := core.SignIntent(a, intent); err != nil {
+		t.Fatalf("SignIntent: %v", err)
+	}
+
+	data, _ := intent.Encode()
+	got, err := core.DecodeIntentMessage(data)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if p := got.Priority(); p != core.PriorityCritical {
+		t.Errorf("priority after round trip: %v", p)
+	}
+	if b, ok := got.BudgetShare(); !ok || b != 2.5 {
+		t.Errorf("budget share: %v %v", b, ok)
+	}
+	if !core.VerifyIntentSignature(got, a.PublicKey()) {
+		t.Error("signed hints did not survive the round trip")
+	}
+
+	got.SetPriority(core.PriorityNormal)
+	if _, ok := got.Metadata[core.PriorityMetadataKey]; ok {
+		t.Error("normal priority should not be stored")
+	}
+	got.Metadata[core.PriorityMetadataKey] = "urgent"
+	if p := got.Priority(); p != core.PriorityNormal {
+		t.Errorf("unknown priority read as %v", p)
+	}
+	if _, err := core.ParsePriority("urgent"); err == nil {
+		t.Error("expected an error for an unknown priority")
+	}
+}
@@ -0,0 +1,54 @@
+package core
+
+// progress.go — Incremental progress updates for streamed intents.
+//
+// A plain intent is request/response.  When the initiator sets
+// Metadata[StreamMetadataKey] = "true", a responder that supports streaming
+// keeps the stream open after its NegotiationResponse and sends a sequence
+// of WorkflowMessages describing the work as it happens.  The WorkflowID of
+// every update is the ID of the intent being served; Action holds one of the
+// Progress* kinds below and Params carries the details.
+
+// StreamMetadataKey marks an IntentMessage as requesting streamed progress.
+const StreamMetadataKey = "stream"
+
+// Progress kinds, carried in WorkflowMessage.Action.
+const (
+	ProgressStepStarted   = "progress.step_started"
+	ProgressStepCompleted = "progress.step_completed"
+	ProgressPartialOutput = "progress.partial_output"
+	ProgressFailed        = "progress.failed"
+)
+
+// Well-known WorkflowMessage.Params keys for progress updates.
+const (
+	ProgressParamOutput = "output"
+	ProgressParamError  = "error"
+)
+
+// NewProgress builds a progress update for the streamed intent intentID.
+// output is stored under ProgressParamOutput when non-empty.
+func NewProgress(agent *Agent, intentID, stepID, kind, output string) *WorkflowMessage {
+	params := map[string]string{}
+	if output != "" {
+		params[ProgressParamOutput] = output
+	}
+	return &WorkflowMessage{
+		WorkflowID: intentID,
+		StepID:     stepID,
+		AgentID:    agent.ID,
+		DID:        agent.DID.String(),
+		Action:     kind,
+		Params:     params,
+		Timestamp:  now(),
+	}
+}
+
+// IsProgress reports whether m is a streamed-intent progress update.
+func (m *WorkflowMessage) IsProgress() bool {
+	switch m.Action {
+	case ProgressStepStarted, ProgressStepCompleted, ProgressPartialOutput, ProgressFailed:
+		return true
+	}
+	return false
+}
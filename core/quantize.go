@@ -0,0 +1,362 @@
+package core
+
+// quantize.go — Quantized intent and response vectors.
+//
+// Embedding vectors dominate the size of IntentMessages and
+// NegotiationResponses.  A v2 frame whose flags carry FrameInt8Vectors or
+// FrameFloat16Vectors holds a protobuf payload in which every
+// IntentMessage.intent_vector and NegotiationResponse.response_vector,
+// including those inside batches, is quantized instead of packed float32:
+//
+//	int8:    [4-byte LE float32 scale] [one int8 per element]
+//	float16: [one 2-byte LE IEEE 754 half per element]
+//
+// ReadFrame expands them again, so decoders only ever see float32.  Only
+// the vectors are lossy.  A signed intent covers its vector, so
+// WriteQuantizedFrame sends float32 vectors instead whenever packing would
+// change a signed one; senders that want their signed vectors packed round
+// them with QuantizeVector before signing.
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Quantization selects how vectors are packed on the wire.
+type Quantization byte
+
+const (
+	QuantizeNone    Quantization = iota // float32, 4 bytes per element
+	QuantizeInt8                        // Symmetric int8 with one scale per vector, 1 byte per element
+	QuantizeFloat16                     // IEEE 754 half precision, 2 bytes per element
+)
+
+// String returns the name of q.
+func (q Quantization) String() string {
+	switch q {
+	case QuantizeNone:
+		return "none"
+	case QuantizeInt8:
+		return "int8"
+	case QuantizeFloat16:
+		return "float16"
+	}
+	return fmt.Sprintf("quantization(%d)", byte(q))
+}
+
+// Validate checks that q is a known quantization.
+func (q Quantization) Validate() error {
+	if q > QuantizeFloat16 {
+		return fmt.Errorf("quantize: unknown %s", q)
+	}
+	return nil
+}
+
+// frameFlag returns the v2 frame flag announcing q.
+func (q Quantization) frameFlag() FrameFlags {
+	switch q {
+	case QuantizeInt8:
+		return FrameInt8Vectors
+	case QuantizeFloat16:
+		return FrameFloat16Vectors
+	}
+	return 0
+}
+
+// frameQuantization returns the quantization announced by flags.
+func frameQuantization(flags FrameFlags) (Quantization, error) {
+	switch flags & (FrameInt8Vectors | FrameFloat16Vectors) {
+	case 0:
+		return QuantizeNone, nil
+	case FrameInt8Vectors:
+		return QuantizeInt8, nil
+	case FrameFloat16Vectors:
+		return QuantizeFloat16, nil
+	}
+	return 0, fmt.Errorf("frame: conflicting vector quantization flags")
+}
+
+// ------------------------------------------------------------------ vectors
+
+// QuantizedVector is a vector packed as Kind.
+type QuantizedVector struct {
+	Kind  Quantization
+	Scale float32 // Value of one int8 step; unused for float16
+	Data  []byte  // One int8, or two little-endian bytes of float16, per element
+}
+
+// QuantizeVector packs v as q.  QuantizeNone packs it as little-endian
+// float32.
+func QuantizeVector(v []float32, q Quantization) QuantizedVector {
+	qv := QuantizedVector{Kind: q}
+	switch q {
+	case QuantizeInt8:
+		var maxAbs float64
+		for _, f := range v {
+			maxAbs = math.Max(maxAbs, math.Abs(float64(f)))
+		}
+		qv.Data = make([]byte, len(v))
+		if maxAbs == 0 {
+			return qv
+		}
+		qv.Scale = float32(maxAbs / 127)
+		for i, f := range v {
+			qv.Data[i] = byte(int8(math.Round(float64(f) / float64(qv.Scale))))
+		}
+	case QuantizeFloat16:
+		qv.Data = make([]byte, 0, 2*len(v))
+		for _, f := range v {
+			qv.Data = binary.LittleEndian.AppendUint16(qv.Data, float32ToF16(f))
+		}
+	default:
+		qv.Data = make([]byte, 0, 4*len(v))
+		for _, f := range v {
+			qv.Data = binary.LittleEndian.AppendUint32(qv.Data, math.Float32bits(f))
+		}
+	}
+	return qv
+}
+
+// Len returns the number of elements in v.
+func (v QuantizedVector) Len() int {
+	switch v.Kind {
+	case QuantizeInt8:
+		return len(v.Data)
+	case QuantizeFloat16:
+		return len(v.Data) / 2
+	}
+	return len(v.Data) / 4
+}
+
+// At returns element i of v.
+func (v QuantizedVector) At(i int) float32 {
+	switch v.Kind {
+	case QuantizeInt8:
+		return float32(int8(v.Data[i])) * v.Scale
+	case QuantizeFloat16:
+		return f16ToFloat32(binary.LittleEndian.Uint16(v.Data[2*i:]))
+	}
+	return math.Float32frombits(binary.LittleEndian.Uint32(v.Data[4*i:]))
+}
+
+// Floats expands v to float32.
+func (v QuantizedVector) Floats() []float32 {
+	out := make([]float32, v.Len())
+	for i := range out {
+		out[i] = v.At(i)
+	}
+	return out
+}
+
+// QuantizedCosineSimilarity is CosineSimilarity for quantized vectors.  Two
+// int8 vectors are compared in integer arithmetic, since their scales
+// cancel out; other pairs are expanded element by element.
+func QuantizedCosineSimilarity(a, b QuantizedVector) float64 {
+	n := a.Len()
+	if n != b.Len() || n == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	if a.Kind == QuantizeInt8 && b.Kind == QuantizeInt8 {
+		var d, na, nb int64
+		for i := 0; i < n; i++ {
+			x, y := int64(int8(a.Data[i])), int64(int8(b.Data[i]))
+			d += x * y
+			na += x * x
+			nb += y * y
+		}
+		dot, normA, normB = float64(d), float64(na), float64(nb)
+	} else {
+		for i := 0; i < n; i++ {
+			x, y := float64(a.At(i)), float64(b.At(i))
+			dot += x * y
+			normA += x * x
+			normB += y * y
+		}
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// float32ToF16 converts f to IEEE 754 half precision, rounding to nearest.
+// Values beyond the half range become infinities.
+func float32ToF16(f float32) uint16 {
+	b := math.Float32bits(f)
+	sign := uint16(b>>16) & 0x8000
+	exp := int(b>>23&0xff) - 127 + 15
+	mant := b & 0x7fffff
+	switch {
+	case b>>23&0xff == 0xff:
+		if mant != 0 {
+			return sign | 0x7e00
+		}
+		return sign | 0x7c00
+	case exp >= 0x1f:
+		return sign | 0x7c00
+	case exp <= 0:
+		if exp < -10 {
+			return sign
+		}
+		mant |= 0x800000
+		shift := uint(14 - exp)
+		h := mant >> shift
+		if mant>>(shift-1)&1 != 0 {
+			h++
+		}
+		return sign | uint16(h)
+	}
+	h := uint32(exp)<<10 | mant>>13
+	if mant&0x1000 != 0 {
+		h++
+	}
+	return sign | uint16(h)
+}
+
+// f16ToFloat32 converts an IEEE 754 half to float32.
+func f16ToFloat32(h uint16) float32 {
+	sign := uint32(h&0x8000) << 16
+	exp := uint32(h>>10) & 0x1f
+	mant := uint32(h & 0x3ff)
+	switch exp {
+	case 0x1f:
+		return math.Float32frombits(sign | 0x7f800000 | mant<<13)
+	case 0:
+		f := float32(mant) / (1 << 24)
+		if sign != 0 {
+			f = -f
+		}
+		return f
+	}
+	return math.Float32frombits(sign | (exp+127-15)<<23 | mant<<13)
+}
+
+// ------------------------------------------------------------------ payloads
+
+// vectorFields are the packed float fields quantization rewrites, and
+// batchFields the fields holding nested messages that carry them.
+var (
+	vectorFields = map[MessageType]protowire.Number{MsgIntent: 2, MsgNegotiation: 6}
+	batchFields  = map[MessageType]MessageType{MsgIntentBatch: MsgIntent, MsgResponseBatch: MsgNegotiation}
+)
+
+// keepsSignatures reports whether packing the vectors of msg as q leaves
+// every vector a signature covers as it was.
+func keepsSignatures(msg Encoder, q Quantization) bool {
+	switch m := msg.(type) {
+	case *IntentMessage:
+		return len(m.Signature) == 0 || quantizesExactly(m.IntentVector, q)
+	case *IntentBatch:
+		for _, in := range m.Intents {
+			if !keepsSignatures(in, q) {
+				return false
+			}
+		}
+	case *NegotiationResponse:
+		return len(m.Signature) == 0 || quantizesExactly(m.ResponseVector, q)
+	case *ResponseBatch:
+		for _, r := range m.Responses {
+			if !keepsSignatures(r, q) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// quantizesExactly reports whether v comes back bit for bit once packed as
+// q, as it does when it was already rounded by QuantizeVector with q.
+func quantizesExactly(v []float32, q Quantization) bool {
+	got := QuantizeVector(v, q)
+	for i, f := range v {
+		if math.Float32bits(got.At(i)) != math.Float32bits(f) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasVectors reports whether messages of type t carry vectors.
+func hasVectors(t MessageType) bool {
+	_, ok := vectorFields[t]
+	_, batch := batchFields[t]
+	return ok || batch
+}
+
+// quantizePayload appends to dst the protobuf payload of type t with its
+// vectors packed as q.
+func quantizePayload(dst []byte, t MessageType, payload []byte, q Quantization) ([]byte, error) {
+	return rewriteVectors(dst, t, payload, func(b, packed []byte) ([]byte, error) {
+		if len(packed)%4 != 0 {
+			return nil, fmt.Errorf("quantize: vector of %d bytes", len(packed))
+		}
+		v := decodePackedF32(packed)
+		if q == QuantizeInt8 {
+			qv := QuantizeVector(v, q)
+			b = binary.LittleEndian.AppendUint32(b, math.Float32bits(qv.Scale))
+			return append(b, qv.Data...), nil
+		}
+		for _, f := range v {
+			b = binary.LittleEndian.AppendUint16(b, float32ToF16(f))
+		}
+		return b, nil
+	})
+}
+
+// dequantizePayload is the inverse of quantizePayload.
+func dequantizePayload(dst []byte, t MessageType, payload []byte, q Quantization) ([]byte, error) {
+	return rewriteVectors(dst, t, payload, func(b, packed []byte) ([]byte, error) {
+		qv := QuantizedVector{Kind: q, Data: packed}
+		if q == QuantizeInt8 {
+			if len(packed) < 4 {
+				return nil, fmt.Errorf("quantize: int8 vector without scale")
+			}
+			qv.Scale = math.Float32frombits(binary.LittleEndian.Uint32(packed))
+			qv.Data = packed[4:]
+		} else if len(packed)%2 != 0 {
+			return nil, fmt.Errorf("quantize: float16 vector of %d bytes", len(packed))
+		}
+		for i := 0; i < qv.Len(); i++ {
+			b = binary.LittleEndian.AppendUint32(b, math.Float32bits(qv.At(i)))
+		}
+		return b, nil
+	})
+}
+
+// rewriteVectors copies the protobuf payload of type t to dst, passing the
+// value of every vector field through fn.
+func rewriteVectors(dst []byte, t MessageType, payload []byte, fn func(dst, vec []byte) ([]byte, error)) ([]byte, error) {
+	field, isVector := vectorFields[t]
+	inner, isBatch := batchFields[t]
+	e := &enc{buf: dst}
+	for len(payload) > 0 {
+		num, typ, n := protowire.ConsumeTag(payload)
+		if n < 0 {
+			return nil, fmt.Errorf("quantize: invalid tag")
+		}
+		m := protowire.ConsumeFieldValue(num, typ, payload[n:])
+		if m < 0 {
+			return nil, fmt.Errorf("quantize: invalid field %d", num)
+		}
+		if typ == protowire.BytesType && (isVector && num == field || isBatch && num == 1) {
+			value, _ := protowire.ConsumeBytes(payload[n:])
+			err := e.nested(num, func(b []byte) ([]byte, error) {
+				if isBatch {
+					return rewriteVectors(b, inner, value, fn)
+				}
+				return fn(b, value)
+			})
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			e.buf = append(e.buf, payload[:n+m]...)
+		}
+		payload = payload[n+m:]
+	}
+	return e.buf, nil
+}
@@ -0,0 +1,136 @@
+package core_test
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func TestQuantizeVector(t *testing.T) {
+	a := []float32{0.8, -0.2, 0.05, 0.4, -0.9, 0, 1e-5, 65504}
+	b := []float32{0.7, -0.1, 0.2, 0.3, -0.8, 0.1, 0, 60000}
+	for _, q := range []core.Quantization{core.QuantizeNone, core.QuantizeInt8, core.QuantizeFloat16} {
+		qa, qb := core.QuantizeVector(a, q), core.QuantizeVector(b, q)
+		if qa.Len() != len(a) {
+			t.Fatalf("%s: Len = %d, want %d", q, qa.Len(), len(a))
+		}
+		for i, f := range qa.Floats() {
+			if d := math.Abs(float64(f - a[i])); d > math.Abs(float64(a[i]))/100+float64(qa.Scale) {
+				t.Errorf("%s: element %d = %v, want about %v", q, i, f, a[i])
+			}
+		}
+		want := core.CosineSimilarity(a, b)
+		if got := core.QuantizedCosineSimilarity(qa, qb); math.Abs(got-want) > 0.01 {
+			t.Errorf("%s: cosine = %v, want about %v", q, got, want)
+		}
+	}
+	if got := core.QuantizedCosineSimilarity(core.QuantizeVector(a, core.QuantizeInt8), core.QuantizeVector(a, core.QuantizeFloat16)); math.Abs(got-1) > 0.01 {
+		t.Errorf("mixed cosine = %v, want about 1", got)
+	}
+}
+
+func TestQuantizedFrame(t *testing.T) {
+	intent := largeIntent(t)
+	intent.Signature = nil
+	resp := &core.NegotiationResponse{RequestID: intent.ID, Accepted: true, ResponseVector: intent.IntentVector[:384]}
+	batch := &core.IntentBatch{Intents: []*core.IntentMessage{intent, intent}}
+
+	for _, q := range []core.Quantization{core.QuantizeInt8, core.QuantizeFloat16} {
+		for _, msg := range []core.Encoder{intent, resp, batch} {
+			var plain, packed bytes.Buffer
+			_ = core.WriteFrameV2(&plain, core.ProtobufCodec, msg, false)
+			if err := core.WriteQuantizedFrame(&packed, core.ProtobufCodec, msg, false, q); err != nil {
+				t.Fatalf("WriteQuantizedFrame: %v", err)
+			}
+			if packed.Len() >= plain.Len()*3/4 {
+				t.Errorf("%s %T: %d bytes quantized, %d plain", q, msg, packed.Len(), plain.Len())
+			}
+			typ, payload, _, err := core.ReadFrame(&packed)
+			if err != nil {
+				t.Fatalf("%s %T: ReadFrame: %v", q, msg, err)
+			}
+			decoded, err := core.Decode(core.MessageType(typ), payload)
+			if err != nil {
+				t.Fatalf("%s %T: Decode: %v", q, msg, err)
+			}
+			var vec, want []float32
+			switch m := decoded.(type) {
+			case *core.IntentMessage:
+				vec, want = m.IntentVector, intent.IntentVector
+			case *core.NegotiationResponse:
+				vec, want = m.ResponseVector, resp.ResponseVector
+			case *core.IntentBatch:
+				if len(m.Intents) != 2 || m.Intents[0].ID != intent.ID {
+					t.Fatalf("%s: batch = %+v", q, m)
+				}
+				vec, want = m.Intents[1].IntentVector, intent.IntentVector
+			}
+			if got := core.CosineSimilarity(vec, want); got < 0.999 {
+				t.Errorf("%s %T: cosine with the original = %v", q, msg, got)
+			}
+		}
+	}
+
+	// Messages without vectors and other codecs are left alone.
+	var a, b bytes.Buffer
+	_ = core.WriteFrameV2(&a, core.JSONCodec, intent, false)
+	_ = core.WriteQuantizedFrame(&b, core.JSONCodec, intent, false, core.QuantizeInt8)
+	if !bytes.Equal(a.Bytes(), b.Bytes()) {
+		t.Error("JSON frame was quantized")
+	}
+}
+
+// TestQuantizedFrameKeepsSignatures verifies that a signed vector travels
+// as float32 unless it was rounded before signing.
+func TestQuantizedFrameKeepsSignatures(t *testing.T) {
+	a, _ := core.NewAgent("a", nil)
+	vec := make([]float32, 1024)
+	for i := range vec {
+		vec[i] = float32(i%97) / 97
+	}
+	intent, _ := core.CreateIntent(a, vec, []string{"nlp"}, "")
+	for _, q := range []core.Quantization{core.QuantizeInt8, core.QuantizeFloat16} {
+		var plain bytes.Buffer
+		_ = core.WriteFrameV2(&plain, core.ProtobufCodec, intent, false)
+		rounded := *intent
+		rounded.IntentVector = core.QuantizeVector(vec, q).Floats()
+		if err := core.SignIntent(a, &rounded); err != nil {
+			t.Fatalf("SignIntent: %v", err)
+		}
+		for _, msg := range []*core.IntentMessage{intent, &rounded} {
+			var packed bytes.Buffer
+			if err := core.WriteQuantizedFrame(&packed, core.ProtobufCodec, msg, false, q); err != nil {
+				t.Fatalf("WriteQuantizedFrame: %v", err)
+			}
+			isRounded := msg == &rounded
+			if quantized := packed.Len() < plain.Len()*3/4; quantized != isRounded {
+				t.Errorf("%s: %d byte frame, %d as float32, rounded=%v", q, packed.Len(), plain.Len(), isRounded)
+			}
+			_, payload, _, err := core.ReadFrame(&packed)
+			if err != nil {
+				t.Fatalf("ReadFrame: %v", err)
+			}
+			got, err := core.DecodeIntentMessage(payload)
+			if err != nil {
+				t.Fatalf("DecodeIntentMessage: %v", err)
+			}
+			if !core.VerifyIntentSignature(got, a.PublicKey()) {
+				t.Errorf("%s: signature broken in transit, rounded=%v", q, isRounded)
+			}
+		}
+
+		// Signed responses are held to the same rule.
+		resp := &core.NegotiationResponse{RequestID: intent.ID, Accepted: true, ResponseVector: vec}
+		_ = core.SignResponse(a, resp)
+		var packed bytes.Buffer
+		if err := core.WriteQuantizedFrame(&packed, core.ProtobufCodec, resp, false, q); err != nil {
+			t.Fatalf("WriteQuantizedFrame: %v", err)
+		}
+		_, payload, _, _ := core.ReadFrame(&packed)
+		if got, err := core.DecodeNegotiationResponse(payload); err != nil || !core.VerifyResponseSignature(got, a.PublicKey()) {
+			t.Errorf("%s: response signature broken in transit: %v", q, err)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package core
+
+// quarantine.go — Per-peer error budgets.
+//
+// Every key (a peer ID or DID) may commit ErrorBudget.MaxErrors errors —
+// undecodable messages, timeouts, SLA breaches — within a sliding Window.
+// The error that exhausts the budget quarantines the key for BaseBackoff,
+// doubling with each further quarantine up to MaxBackoff, so a peer that
+// keeps misbehaving is shut out for longer each time.  A key that stays out
+// of quarantine for MaxBackoff starts over from BaseBackoff.
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ReasonQuarantined is the Reason of a NegotiationResponse refusing an
+// intent because its sender is quarantined.  RetryAfter says when the
+// quarantine ends.
+const ReasonQuarantined = "quarantined"
+
+// Kinds of errors counted against a peer's budget.
+const (
+	PeerErrorDecode  = "decode"  // A message from the peer could not be decoded
+	PeerErrorTimeout = "timeout" // The peer did not answer in time
+	PeerErrorSLA     = "sla"     // The peer breached a latency SLA
+)
+
+// ErrorBudget configures a Quarantine.
+type ErrorBudget struct {
+	MaxErrors   int           // Errors allowed within Window; the next one quarantines
+	Window      time.Duration // Default 1m
+	BaseBackoff time.Duration // First quarantine period; default 30s
+	MaxBackoff  time.Duration // Longest quarantine period; default 1h
+}
+
+// Validate checks that b describes a usable budget.
+func (b ErrorBudget) Validate() error {
+	if b.MaxErrors < 0 {
+		return fmt.Errorf("error budget: max errors must not be negative")
+	}
+	if b.Window < 0 || b.BaseBackoff < 0 || b.MaxBackoff < 0 {
+		return fmt.Errorf("error budget: durations must not be negative")
+	}
+	if b.MaxBackoff > 0 && b.MaxBackoff < b.withDefaults().BaseBackoff {
+		return fmt.Errorf("error budget: max backoff %s is below base backoff %s", b.MaxBackoff, b.withDefaults().BaseBackoff)
+	}
+	return nil
+}
+
+func (b ErrorBudget) withDefaults() ErrorBudget {
+	if b.Window == 0 {
+		b.Window = time.Minute
+	}
+	if b.BaseBackoff == 0 {
+		b.BaseBackoff = 30 * time.Second
+	}
+	if b.MaxBackoff == 0 {
+		b.MaxBackoff = time.Hour
+	}
+	return b
+}
+
+// QuarantineEntry describes one key in a Quarantine.
+type QuarantineEntry struct {
+	Key         string         `json:"key"`
+	Errors      map[string]int `json:"errors,omitempty"` // Errors by kind within the window
+	Quarantines int            `json:"quarantines"`      // Quarantines so far, which set the next period
+	Until       time.Time      `json:"until,omitempty"`  // End of the current quarantine; zero if none
+}
+
+// Quarantine tracks error budgets per key.  It is concurrency-safe.
+type Quarantine struct {
+	mu     sync.Mutex
+	budget ErrorBudget
+	keys   map[string]*errorRecord
+}
+
+type errorRecord struct {
+	errors  []peerError
+	strikes int       // Quarantines so far
+	until   time.Time // End of the current or last quarantine
+}
+
+type peerError struct {
+	at   time.Time
+	kind string
+}
+
+// NewQuarantine creates a Quarantine applying budget to every key.
+func NewQuarantine(budget ErrorBudget) (*Quarantine, error) {
+	if err := budget.Validate(); err != nil {
+		return nil, err
+	}
+	return &Quarantine{budget: budget.withDefaults(), keys: make(map[string]*errorRecord)}, nil
+}
+
+// Record counts an error of kind against key.  If it exhausts key's budget,
+// key is quarantined and Record returns the end of the quarantine.
+func (q *Quarantine) Record(key, kind string) (until time.Time, quarantined bool) {
+	return q.RecordAt(key, kind, time.Now())
+}
+
+// RecordAt is Record at time t.  Errors committed while key is quarantined
+// are not counted.
+func (q *Quarantine) RecordAt(key, kind string, t time.Time) (until time.Time, quarantined bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	r, ok := q.keys[key]
+	if !ok {
+		r = &errorRecord{}
+		q.keys[key] = r
+	}
+	if t.Before(r.until) {
+		return time.Time{}, false
+	}
+	if r.strikes > 0 && t.Sub(r.until) >= q.budget.MaxBackoff {
+		r.strikes = 0
+	}
+	r.errors = append(r.prune(t.Add(-q.budget.Window)), peerError{at: t, kind: kind})
+	if len(r.errors) <= q.budget.MaxErrors {
+		return time.Time{}, false
+	}
+	r.strikes++
+	r.errors = nil
+	r.until = t.Add(q.backoff(r.strikes))
+	return r.until, true
+}
+
+// backoff returns the quarantine period for the given strike.
+func (q *Quarantine) backoff(strikes int) time.Duration {
+	d := q.budget.BaseBackoff
+	for i := 1; i < strikes && d < q.budget.MaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, q.budget.MaxBackoff)
+}
+
+// prune drops the errors committed before cutoff.
+func (r *errorRecord) prune(cutoff time.Time) []peerError {
+	i := 0
+	for i < len(r.errors) && r.errors[i].at.Before(cutoff) {
+		i++
+	}
+	return r.errors[i:]
+}
+
+// Quarantined reports whether key is quarantined, and until when.
+func (q *Quarantine) Quarantined(key string) (until time.Time, ok bool) {
+	return q.QuarantinedAt(key, time.Now())
+}
+
+// QuarantinedAt is Quarantined at time t.
+func (q *Quarantine) QuarantinedAt(key string, t time.Time) (until time.Time, ok bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	r, found := q.keys[key]
+	if !found || !t.Before(r.until) {
+		return time.Time{}, false
+	}
+	return r.until, true
+}
+
+// Release ends key's quarantine at once and clears its errors.  The
+// quarantine still counts towards the period of the next one.  It reports
+// whether key was quarantined.
+func (q *Quarantine) Release(key string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	r, found := q.keys[key]
+	if !found {
+		return false
+	}
+	now := time.Now()
+	was := now.Before(r.until)
+	if was {
+		r.until = now
+	}
+	r.errors = nil
+	return was
+}
+
+// Entries lists every key with errors in the window or a quarantine that
+// has not ended at t, sorted by key.
+func (q *Quarantine) Entries(t time.Time) []QuarantineEntry {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var out []QuarantineEntry
+	for key, r := range q.keys {
+		r.errors = r.prune(t.Add(-q.budget.Window))
+		e := QuarantineEntry{Key: key, Quarantines: r.strikes}
+		if t.Before(r.until) {
+			e.Until = r.until
+		}
+		for _, pe := range r.errors {
+			if e.Errors == nil {
+				e.Errors = make(map[string]int)
+			}
+			e.Errors[pe.kind]++
+		}
+		if e.Errors != nil || !e.Until.IsZero() {
+			out = append(out, e)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+// Prune drops the keys with no errors in the window, no quarantine in force
+// and no strike left to remember at t, and returns how many it dropped.
+func (q *Quarantine) Prune(t time.Time) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := 0
+	for key, r := range q.keys {
+		r.errors = r.prune(t.Add(-q.budget.Window))
+		if len(r.errors) == 0 && t.Sub(r.until) >= q.budget.MaxBackoff {
+			delete(q.keys, key)
+			n++
+		}
+	}
+	return n
+}
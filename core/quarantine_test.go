@@ -0,0 +1,84 @@
+package core_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func TestQuarantineBackoff(t *testing.T) {
+	q, err := core.NewQuarantine(core.ErrorBudget{MaxErrors: 2, Window: time.Minute, BaseBackoff: time.Second, MaxBackoff: 3 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t0 := time.Unix(1000, 0)
+
+	// Errors outside the window do not add up.
+	q.RecordAt("p", core.PeerErrorDecode, t0)
+	q.RecordAt("p", core.PeerErrorDecode, t0.Add(time.Minute+time.Second))
+	if _, quarantined := q.RecordAt("p", core.PeerErrorTimeout, t0.Add(time.Minute+2*time.Second)); quarantined {
+		t.Fatal("quarantined with only two errors in the window")
+	}
+
+	// The third error in the window quarantines, for longer each time.
+	now := t0.Add(time.Minute + 2*time.Second)
+	for _, want := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second} {
+		var until time.Time
+		var quarantined bool
+		for i := 0; i < 3 && !quarantined; i++ {
+			until, quarantined = q.RecordAt("p", core.PeerErrorSLA, now)
+		}
+		if !quarantined || until.Sub(now) != want {
+			t.Fatalf("quarantined %v until +%s, want +%s", quarantined, until.Sub(now), want)
+		}
+		if _, ok := q.QuarantinedAt("p", now.Add(want-time.Millisecond)); !ok {
+			t.Error("not quarantined before the period ends")
+		}
+		if _, ok := q.QuarantinedAt("p", until); ok {
+			t.Error("still quarantined once the period ended")
+		}
+		now = until
+	}
+
+	entries := q.Entries(now.Add(-time.Millisecond))
+	if len(entries) != 1 || entries[0].Key != "p" || entries[0].Quarantines != 3 || entries[0].Until.IsZero() {
+		t.Errorf("entries: %+v", entries)
+	}
+
+	// After MaxBackoff without a quarantine the period starts over.
+	now = now.Add(3 * time.Second)
+	var until time.Time
+	for i := 0; i < 3; i++ {
+		until, _ = q.RecordAt("p", core.PeerErrorDecode, now)
+	}
+	if until.Sub(now) != time.Second {
+		t.Errorf("period after a clean spell = %s, want 1s", until.Sub(now))
+	}
+}
+
+func TestQuarantineRelease(t *testing.T) {
+	q, _ := core.NewQuarantine(core.ErrorBudget{})
+	if _, quarantined := q.Record("p", core.PeerErrorDecode); !quarantined {
+		t.Fatal("a zero budget should quarantine on the first error")
+	}
+	if _, ok := q.Quarantined("p"); !ok {
+		t.Fatal("not quarantined")
+	}
+	if !q.Release("p") {
+		t.Error("Release reported no quarantine")
+	}
+	if _, ok := q.Quarantined("p"); ok {
+		t.Error("still quarantined after Release")
+	}
+	if q.Release("p") || q.Release("unknown") {
+		t.Error("Release of a free key reported a quarantine")
+	}
+
+	if _, err := core.NewQuarantine(core.ErrorBudget{MaxErrors: -1}); err == nil {
+		t.Error("expected an error for a negative budget")
+	}
+	if _, err := core.NewQuarantine(core.ErrorBudget{BaseBackoff: time.Minute, MaxBackoff: time.Second}); err == nil {
+		t.Error("expected an error for max backoff below base backoff")
+	}
+}
@@ -0,0 +1,125 @@
+package core
+
+// ratelimit.go — Per-peer token buckets.
+//
+// Each key (a peer ID or DID) owns a bucket holding up to Burst tokens that
+// refills at Rate tokens per second.  Every request takes one token; a
+// request finding the bucket empty is refused together with the time until
+// a token will be available, so the sender can back off instead of retrying
+// blindly.
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// ReasonRateLimited is the Reason of a NegotiationResponse refusing an
+// intent because its sender exceeded the responder's rate limit.
+const ReasonRateLimited = "rate_limited"
+
+// RateLimited reports whether m refuses an intent because of the
+// responder's rate limit, and how long the sender should back off.
+func (m *NegotiationResponse) RateLimited() (retryAfter time.Duration, ok bool) {
+	if m.Accepted || m.Reason != ReasonRateLimited {
+		return 0, false
+	}
+	return time.Duration(m.RetryAfter), true
+}
+
+// RateLimit configures a RateLimiter.
+type RateLimit struct {
+	Rate  float64 // Sustained requests per second
+	Burst int     // Requests allowed at once; 0 means max(1, Rate)
+}
+
+// Validate checks that l describes a usable limit.
+func (l RateLimit) Validate() error {
+	if !(l.Rate > 0) || math.IsInf(l.Rate, 0) {
+		return fmt.Errorf("rate limit: rate must be positive")
+	}
+	if l.Burst < 0 {
+		return fmt.Errorf("rate limit: burst must not be negative")
+	}
+	return nil
+}
+
+func (l RateLimit) burst() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return math.Max(1, math.Floor(l.Rate))
+}
+
+// RateLimiter keeps a token bucket per key.  It is concurrency-safe.
+type RateLimiter struct {
+	mu      sync.Mutex
+	limit   RateLimit
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter creates a limiter applying limit to every key.
+func NewRateLimiter(limit RateLimit) (*RateLimiter, error) {
+	if err := limit.Validate(); err != nil {
+		return nil, err
+	}
+	return &RateLimiter{limit: limit, buckets: make(map[string]*bucket)}, nil
+}
+
+// Allow takes a token from key's bucket.  If none is left it reports false
+// and how long until one will be.
+func (l *RateLimiter) Allow(key string) (retryAfter time.Duration, ok bool) {
+	return l.AllowAt(key, time.Now())
+}
+
+// AllowAt is Allow at time t.  Times earlier than a key's last request are
+// treated as equal to it.
+func (l *RateLimiter) AllowAt(key string, t time.Time) (retryAfter time.Duration, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	max := l.limit.burst()
+	b, found := l.buckets[key]
+	if !found {
+		b = &bucket{tokens: max, last: t}
+		l.buckets[key] = b
+	}
+	if t.After(b.last) {
+		b.tokens = math.Min(max, b.tokens+t.Sub(b.last).Seconds()*l.limit.Rate)
+		b.last = t
+	}
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0, true
+	}
+	wait := (1 - b.tokens) / l.limit.Rate
+	return time.Duration(math.Ceil(wait * float64(time.Second))), false
+}
+
+// Forget drops key's bucket; its next request starts with a full one.
+func (l *RateLimiter) Forget(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.buckets, key)
+}
+
+// Prune drops the buckets that have refilled completely by t, which behave
+// exactly like new ones, and returns how many it dropped.
+func (l *RateLimiter) Prune(t time.Time) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	max := l.limit.burst()
+	n := 0
+	for k, b := range l.buckets {
+		if b.tokens+t.Sub(b.last).Seconds()*l.limit.Rate >= max {
+			delete(l.buckets, k)
+			n++
+		}
+	}
+	return n
+}
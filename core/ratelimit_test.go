@@ -0,0 +1,60 @@
+package core_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func TestRateLimiter(t *testing.T) {
+	l, err := core.NewRateLimiter(core.RateLimit{Rate: 2, Burst: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t0 := time.Unix(1000, 0)
+	for i := 0; i < 3; i++ {
+		if _, ok := l.AllowAt("a", t0); !ok {
+			t.Fatalf("request %d within burst refused", i)
+		}
+	}
+	wait, ok := l.AllowAt("a", t0)
+	if ok || wait != 500*time.Millisecond {
+		t.Errorf("over burst: ok=%t wait=%s, want refused for 500ms", ok, wait)
+	}
+	if _, ok = l.AllowAt("b", t0); !ok {
+		t.Error("keys must not share a bucket")
+	}
+	if _, ok = l.AllowAt("a", t0.Add(500*time.Millisecond)); !ok {
+		t.Error("refill after 500ms refused")
+	}
+
+	if n := l.Prune(t0.Add(time.Second)); n != 1 {
+		t.Errorf("Prune = %d, want 1 (only b has refilled)", n)
+	}
+	l.Forget("a")
+	if _, ok = l.AllowAt("a", t0.Add(time.Second)); !ok {
+		t.Error("forgotten key should start with a full bucket")
+	}
+
+	for _, bad := range []core.RateLimit{{}, {Rate: -1}, {Rate: 1, Burst: -1}} {
+		if _, err := core.NewRateLimiter(bad); err == nil {
+			t.Errorf("%+v: accepted", bad)
+		}
+	}
+}
+
+func TestRateLimitedResponseRoundTrip(t *testing.T) {
+	resp := &core.NegotiationResponse{RequestID: "r1", Reason: core.ReasonRateLimited, RetryAfter: int64(250 * time.Millisecond)}
+	data, _ := resp.Encode()
+	got, err := core.DecodeNegotiationResponse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wait, ok := got.RateLimited(); !ok || wait != 250*time.Millisecond {
+		t.Errorf("RateLimited() = %s, %t", wait, ok)
+	}
+	if _, ok := (&core.NegotiationResponse{Reason: "busy"}).RateLimited(); ok {
+		t.Error("other rejections are not rate limited")
+	}
+}
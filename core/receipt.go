@@ -0,0 +1,159 @@
+package core
+
+// receipt.go — Signed completion receipts, the evidence behind billing.
+//
+// When an agent serves an intent it may issue a CompletionReceipt: a record,
+// signed with its DID key, of who asked for which capability, when, and what
+// it cost.  Receipts carry the issuer's public key, so anyone holding one can
+// check it offline.  A ReceiptLog appends receipts to a JSON-lines file that
+// BuildBillingReport later aggregates.
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Cost is what serving one intent is charged.
+type Cost struct {
+	Amount   int64  `json:"amount"`             // In the currency's smallest unit
+	Currency string `json:"currency,omitempty"` // e.g. "USD", "credits"
+	Units    int64  `json:"units,omitempty"`    // Metered quantity, e.g. tokens
+}
+
+// PriceFunc prices an accepted intent.  ok=false issues no receipt.
+type PriceFunc func(intent *IntentMessage, resp *NegotiationResponse) (cost Cost, ok bool)
+
+// CompletionReceipt records that Provider served IntentID for Requester.
+type CompletionReceipt struct {
+	IntentID    string `json:"intent_id"`
+	Capability  string `json:"capability"`
+	Provider    string `json:"provider"`  // Issuer DID
+	Requester   string `json:"requester"` // Intent sender DID
+	CompletedAt int64  `json:"completed_at,string"`
+	Cost        Cost   `json:"cost"`
+	PublicKey   []byte `json:"public_key"`
+	Signature   []byte `json:"signature,omitempty"`
+}
+
+// IssueReceipt signs a receipt for intent, served by provider at cost.  The
+// receipt names the intent's first capability.
+func IssueReceipt(provider *Agent, intent *IntentMessage, cost Cost) (*CompletionReceipt, error) {
+	r := &CompletionReceipt{
+		IntentID:    intent.ID,
+		Provider:    provider.DID.String(),
+		Requester:   intent.DID,
+		CompletedAt: now(),
+		Cost:        cost,
+		PublicKey:   provider.PublicKey(),
+	}
+	if len(intent.Capabilities) > 0 {
+		r.Capability = intent.Capabilities[0]
+	}
+	sig, err := provider.Sign(r.signingData())
+	if err != nil {
+		return nil, fmt.Errorf("receipt: sign: %w", err)
+	}
+	r.Signature = sig
+	return r, nil
+}
+
+// Verify checks that PublicKey belongs to Provider and signed the receipt.
+func (r *CompletionReceipt) Verify() error {
+	d, err := ParseDID(r.Provider)
+	if err != nil {
+		return fmt.Errorf("receipt: %w", err)
+	}
+	if !d.ValidateBinding(r.PublicKey) {
+		return fmt.Errorf("receipt: public key does not match %s", r.Provider)
+	}
+	if len(r.Signature) == 0 {
+		return fmt.Errorf("receipt %s is unsigned", r.IntentID)
+	}
+	signer, err := DIDFromPublicKey(r.PublicKey)
+	if err != nil {
+		return fmt.Errorf("receipt: %w", err)
+	}
+	if !signer.Verify(r.signingData(), r.Signature) {
+		return fmt.Errorf("receipt %s: %w", r.IntentID, ErrSignatureInvalid)
+	}
+	return nil
+}
+
+// Time returns CompletedAt as a time.Time.
+func (r *CompletionReceipt) Time() time.Time { return time.Unix(0, r.CompletedAt) }
+
+// signingData is the receipt's JSON encoding without the signature.
+func (r *CompletionReceipt) signingData() []byte {
+	c := *r
+	c.Signature = nil
+	data, _ := json.Marshal(c)
+	return data
+}
+
+// ReceiptLog appends receipts to a JSON-lines file.
+type ReceiptLog struct {
+	mu   sync.Mutex
+	f    *os.File
+	path string
+}
+
+// OpenReceiptLog opens path for appending, creating it if needed.
+func OpenReceiptLog(path string) (*ReceiptLog, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("receipt: open %s: %w", path, err)
+	}
+	return &ReceiptLog{f: f, path: path}, nil
+}
+
+// Append writes r as one line.
+func (l *ReceiptLog) Append(r *CompletionReceipt) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("receipt: encode: %w", err)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err = l.f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("receipt: write %s: %w", l.path, err)
+	}
+	return nil
+}
+
+// Close closes the underlying file.
+func (l *ReceiptLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.f.Close()
+}
+
+// ReadReceipts reads every receipt in a file written by ReceiptLog.
+// Signatures are not checked; BuildBillingReport does that.
+func ReadReceipts(path string) ([]*CompletionReceipt, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("receipt: open %s: %w", path, err)
+	}
+	defer func() { _ = f.Close() }()
+	var out []*CompletionReceipt
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 1<<20)
+	for line := 1; sc.Scan(); line++ {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var r CompletionReceipt
+		if err = json.Unmarshal(sc.Bytes(), &r); err != nil {
+			return nil, fmt.Errorf("receipt: %s:%d: %w", path, line, err)
+		}
+		out = append(out, &r)
+	}
+	if err = sc.Err(); err != nil {
+		return nil, fmt.Errorf("receipt: read %s: %w", path, err)
+	}
+	return out, nil
+}
@@ -0,0 +1,150 @@
+package core
+
+// relay.go — Forwarding intents through agents that cannot serve them.
+//
+// In a mesh with partial connectivity the originator of an intent may not
+// reach any agent with the capability it needs.  A relay that cannot serve
+// the intent itself wraps it, unchanged, in a ForwardEnvelope and sends it
+// to a peer that can:
+//
+//   - The envelope carries the originator's key, so the responder can verify
+//     the intent without a handshake, and is signed by the relay, so every
+//     hop knows who handed the intent on.
+//   - Hops bounds how many further relays may get involved, and Path lists
+//     the relays so far, so forwarding loops are refused.
+//   - The responder signs its NegotiationResponse as usual and adds its key.
+//     The response travels back the way the intent came, and each relay
+//     puts its DID in front of RelayPath.
+
+import (
+	"fmt"
+	"slices"
+)
+
+// DefaultRelayHops is how many relays an intent may pass through.
+const DefaultRelayHops = 3
+
+// ReasonNoRoute is the Reason of a NegotiationResponse from a hop that can
+// neither serve a forwarded intent nor forward it further.
+const ReasonNoRoute = "no_route"
+
+// ErrHopLimit is returned when forwarding an envelope that has no hops left.
+var ErrHopLimit = fmt.Errorf("forward: hop limit reached")
+
+// NewForwardEnvelope wraps intent, signed by the holder of originKey, for
+// its first relay hop.  hops is how many more relays may forward it after
+// the next hop.
+func NewForwardEnvelope(relay *Agent, intent *IntentMessage, originKey []byte, hops int32) (*ForwardEnvelope, error) {
+	if hops < 0 {
+		return nil, ErrHopLimit
+	}
+	return signEnvelope(relay, &ForwardEnvelope{
+		Intent:    intent,
+		OriginKey: append([]byte(nil), originKey...),
+		Hops:      hops,
+	})
+}
+
+// Next re-wraps m for one more relay hop by relay.
+func (m *ForwardEnvelope) Next(relay *Agent) (*ForwardEnvelope, error) {
+	if m.Hops <= 0 {
+		return nil, ErrHopLimit
+	}
+	if did := relay.DID.String(); slices.Contains(m.Path, did) || did == m.Intent.DID {
+		return nil, fmt.Errorf("forward: %s already carried intent %s", did, m.Intent.ID)
+	}
+	return signEnvelope(relay, &ForwardEnvelope{
+		Intent:    m.Intent,
+		OriginKey: m.OriginKey,
+		Path:      slices.Clone(m.Path),
+		Hops:      m.Hops - 1,
+	})
+}
+
+// signEnvelope appends relay to m.Path and signs m as relay.
+func signEnvelope(relay *Agent, m *ForwardEnvelope) (*ForwardEnvelope, error) {
+	m.RelayDID = relay.DID.String()
+	m.RelayKey = relay.PublicKey()
+	m.Path = append(m.Path, m.RelayDID)
+	m.Timestamp = now()
+	sig, err := relay.Sign(envelopeSigningData(m))
+	if err != nil {
+		return nil, fmt.Errorf("forward: sign: %w", err)
+	}
+	m.Signature = sig
+	return m, nil
+}
+
+// VerifyForwardEnvelope checks the relay's signature on m, the originator's
+// signature on the intent, and that Path holds no loop.  Unsigned envelopes
+// and unsigned intents are always rejected.
+func VerifyForwardEnvelope(m *ForwardEnvelope) error {
+	if m.Intent == nil {
+		return fmt.Errorf("forward: no intent")
+	}
+	if len(m.Signature) == 0 {
+		return fmt.Errorf("forward: envelope from %s is unsigned", m.RelayDID)
+	}
+	if m.Hops < 0 {
+		return fmt.Errorf("forward: negative hop count")
+	}
+	if n := len(m.Path); n == 0 || m.Path[n-1] != m.RelayDID {
+		return fmt.Errorf("forward: path does not end with %s", m.RelayDID)
+	}
+	for i, did := range m.Path {
+		if did == m.Intent.DID || slices.Contains(m.Path[:i], did) {
+			return fmt.Errorf("forward: %s appears twice on the path of intent %s", did, m.Intent.ID)
+		}
+	}
+	relay, err := boundKey(m.RelayDID, m.RelayKey)
+	if err != nil {
+		return fmt.Errorf("forward: relay: %w", err)
+	}
+	if !relay.Verify(envelopeSigningData(m), m.Signature) {
+		return fmt.Errorf("forward: %w from %s", ErrSignatureInvalid, m.RelayDID)
+	}
+	if len(m.Intent.Signature) == 0 {
+		return fmt.Errorf("forward: intent %s is unsigned", m.Intent.ID)
+	}
+	if _, err = boundKey(m.Intent.DID, m.OriginKey); err != nil {
+		return fmt.Errorf("forward: originator: %w", err)
+	}
+	if !VerifyIntentSignature(m.Intent, m.OriginKey) {
+		return fmt.Errorf("forward: intent from %s: %w", m.Intent.DID, ErrSignatureInvalid)
+	}
+	return nil
+}
+
+// VerifyRelayedResponse checks a response to a relayed intent against the
+// key it carries.  Unsigned responses are rejected.
+func VerifyRelayedResponse(resp *NegotiationResponse) error {
+	if len(resp.Signature) == 0 {
+		return fmt.Errorf("forward: response from %s is unsigned", resp.DID)
+	}
+	if _, err := boundKey(resp.DID, resp.ResponderKey); err != nil {
+		return fmt.Errorf("forward: responder: %w", err)
+	}
+	if !VerifyResponseSignature(resp, resp.ResponderKey) {
+		return fmt.Errorf("forward: response from %s: %w", resp.DID, ErrSignatureInvalid)
+	}
+	return nil
+}
+
+// boundKey returns the DID of key after checking that it belongs to did.
+func boundKey(did string, key []byte) (*DID, error) {
+	d, err := ParseDID(did)
+	if err != nil {
+		return nil, err
+	}
+	if !d.ValidateBinding(key) {
+		return nil, fmt.Errorf("key does not match %s", did)
+	}
+	return DIDFromPublicKey(key)
+}
+
+func envelopeSigningData(m *ForwardEnvelope) []byte {
+	c := *m
+	c.Signature = nil
+	data, _ := c.Encode()
+	return data
+}
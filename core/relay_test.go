@@ -0,0 +1,96 @@
+package core_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func TestForwardEnvelope(t *testing.T) {
+	origin, _ := core.NewAgent("origin", nil)
+	r1, _ := core.NewAgent("r1", nil)
+	r2, _ := core.NewAgent("r2", nil)
+	intent, _ := core.CreateIntent(origin, nil, []string{"ocr"}, "scan")
+
+	env, err := core.NewForwardEnvelope(r1, intent, origin.PublicKey(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := env.Encode()
+	got, err := core.DecodeForwardEnvelope(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = core.VerifyForwardEnvelope(got); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+
+	next, err := got.Next(r2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.Hops != 0 || len(next.Path) != 2 || next.RelayDID != r2.DID.String() {
+		t.Errorf("next hop: hops=%d path=%v relay=%s", next.Hops, next.Path, next.RelayDID)
+	}
+	if err = core.VerifyForwardEnvelope(next); err != nil {
+		t.Errorf("verify next: %v", err)
+	}
+	if _, err = next.Next(r1); !errors.Is(err, core.ErrHopLimit) {
+		t.Errorf("past the hop limit: got %v, want ErrHopLimit", err)
+	}
+
+	tampered := *next
+	tampered.Hops = 5
+	if core.VerifyForwardEnvelope(&tampered) == nil {
+		t.Error("raised hop count accepted")
+	}
+	forged := *next
+	forged.OriginKey = r1.PublicKey()
+	if core.VerifyForwardEnvelope(&forged) == nil {
+		t.Error("originator key of another DID accepted")
+	}
+	if _, err = got.Next(r1); err == nil {
+		t.Error("relay allowed to carry the same intent twice")
+	}
+}
+
+func TestVerifyRelayedResponse(t *testing.T) {
+	origin, _ := core.NewAgent("origin", nil)
+	responder, _ := core.NewAgent("responder", []string{"ocr"})
+	intent, _ := core.CreateIntent(origin, nil, []string{"ocr"}, "scan")
+	resp, err := core.DefaultNegotiationHandler(responder)(intent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.ResponderKey = responder.PublicKey()
+	resp.RelayPath = []string{"did:agent-semantic-protocol:relay"}
+
+	data, _ := resp.Encode()
+	got, err := core.DecodeNegotiationResponse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.RelayPath) != 1 {
+		t.Errorf("relay path lost: %v", got.RelayPath)
+	}
+	if err = core.VerifyRelayedResponse(got); err != nil {
+		t.Errorf("verify: %v", err)
+	}
+	// A relay cannot change the answer it carries back.
+	for name, tamper := range map[string]func(r *core.NegotiationResponse){
+		"accepted":    func(r *core.NegotiationResponse) { r.Accepted = !r.Accepted },
+		"trust delta": func(r *core.NegotiationResponse) { r.TrustDelta = -1 },
+		"cost":        func(r *core.NegotiationResponse) { r.Cost = &core.CostEstimate{Amount: 1} },
+	} {
+		c := *got
+		tamper(&c)
+		if core.VerifyRelayedResponse(&c) == nil {
+			t.Errorf("response verified with a changed %s", name)
+		}
+	}
+	got.ResponderKey = origin.PublicKey()
+	if core.VerifyRelayedResponse(got) == nil {
+		t.Error("response verified against another agent's key")
+	}
+}
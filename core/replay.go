@@ -0,0 +1,110 @@
+package core
+
+// replay.go — Intent expiry and duplicate suppression.
+//
+// An intent may carry ExpiresAt; receivers drop it once that time has
+// passed.  Within its lifetime a captured intent could still be replayed,
+// so receivers also remember the IDs they have served in a ReplayCache and
+// drop repeats.  An ID is remembered until the intent expires, or for the
+// cache's window if it never does, but never for longer than
+// MaxIntentLifetime; receivers drop intents that expire later than that.
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultReplayWindow is how long a ReplayCache remembers intents that have
+// no ExpiresAt.
+const DefaultReplayWindow = 10 * time.Minute
+
+// MaxIntentLifetime is how far past its receipt an intent's ExpiresAt may
+// lie.  A ReplayCache remembers no intent for longer than this or its
+// window, whichever is longer.
+const MaxIntentLifetime = 24 * time.Hour
+
+// replayPurgeInterval bounds how often Seen scans for expired IDs.
+const replayPurgeInterval = time.Second
+
+// WithTTL sets m.ExpiresAt to ttl after m.Timestamp (or now, if unset) and
+// returns m.
+func (m *IntentMessage) WithTTL(ttl time.Duration) *IntentMessage {
+	base := m.Timestamp
+	if base == 0 {
+		base = now()
+	}
+	m.ExpiresAt = base + int64(ttl)
+	return m
+}
+
+// Expired reports whether m has an expiry that lies before t.
+func (m *IntentMessage) Expired(t time.Time) bool {
+	return m.ExpiresAt != 0 && t.UnixNano() > m.ExpiresAt
+}
+
+// ExpiresTooLate reports whether m's expiry lies more than
+// MaxIntentLifetime after t, past the time a ReplayCache remembers it.
+func (m *IntentMessage) ExpiresTooLate(t time.Time) bool {
+	return m.ExpiresAt > t.Add(MaxIntentLifetime).UnixNano()
+}
+
+// ReplayCache remembers the intents a receiver has accepted.
+// It is concurrency-safe.
+type ReplayCache struct {
+	mu        sync.Mutex
+	window    time.Duration
+	seen      map[string]int64 // key → forget after (Unix nanoseconds)
+	lastPurge time.Time
+	replays   uint64
+}
+
+// NewReplayCache creates a cache remembering intents without an expiry for
+// window (DefaultReplayWindow if 0).
+func NewReplayCache(window time.Duration) *ReplayCache {
+	if window <= 0 {
+		window = DefaultReplayWindow
+	}
+	return &ReplayCache{window: window, seen: make(map[string]int64)}
+}
+
+// Seen records m and reports whether an intent with the same sender and ID
+// was already recorded.
+func (c *ReplayCache) Seen(m *IntentMessage) bool {
+	t := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if t.Sub(c.lastPurge) >= replayPurgeInterval {
+		for k, until := range c.seen {
+			if t.UnixNano() > until {
+				delete(c.seen, k)
+			}
+		}
+		c.lastPurge = t
+	}
+
+	key := m.DID + "/" + m.ID
+	if until, ok := c.seen[key]; ok && t.UnixNano() <= until {
+		c.replays++
+		return true
+	}
+	until := t.Add(c.window).UnixNano()
+	if m.ExpiresAt > until {
+		until = min(m.ExpiresAt, t.Add(max(c.window, MaxIntentLifetime)).UnixNano())
+	}
+	c.seen[key] = until
+	return false
+}
+
+// Len returns the number of IDs currently remembered.
+func (c *ReplayCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.seen)
+}
+
+// Replays returns the number of duplicates Seen has reported.
+func (c *ReplayCache) Replays() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.replays
+}
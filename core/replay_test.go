@@ -0,0 +1,65 @@
+package core_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func TestReplayCache(t *testing.T) {
+	c := core.NewReplayCache(time.Minute)
+	a := &core.IntentMessage{ID: "i1", DID: "did:a"}
+	if c.Seen(a) {
+		t.Fatal("first delivery reported as a replay")
+	}
+	if !c.Seen(a) {
+		t.Error("second delivery not reported as a replay")
+	}
+	// Same ID from another sender is a different intent.
+	if c.Seen(&core.IntentMessage{ID: "i1", DID: "did:b"}) {
+		t.Error("intent from another sender reported as a replay")
+	}
+	if c.Len() != 2 || c.Replays() != 1 {
+		t.Errorf("Len=%d Replays=%d, want 2 and 1", c.Len(), c.Replays())
+	}
+}
+
+func TestIntentExpiresTooLate(t *testing.T) {
+	now := time.Now()
+	for _, tc := range []struct {
+		expiresAt int64
+		late      bool
+	}{
+		{0, false},
+		{now.Add(time.Hour).UnixNano(), false},
+		{now.Add(core.MaxIntentLifetime + time.Second).UnixNano(), true},
+		{math.MaxInt64, true},
+	} {
+		m := &core.IntentMessage{ExpiresAt: tc.expiresAt}
+		if got := m.ExpiresTooLate(now); got != tc.late {
+			t.Errorf("ExpiresAt %d: ExpiresTooLate = %v, want %v", tc.expiresAt, got, tc.late)
+		}
+	}
+}
+
+func TestIntentExpiry(t *testing.T) {
+	a, _ := core.NewAgent("a", []string{"nlp"})
+	intent, _ := core.CreateIntent(a, nil, []string{"nlp"}, "summarise")
+	if intent.Expired(time.Now()) {
+		t.Fatal("intent without ExpiresAt reported expired")
+	}
+
+	h := core.DefaultNegotiationHandler(a)
+	intent.WithTTL(time.Minute)
+	if resp, _ := h(intent); !resp.Accepted {
+		t.Errorf("live intent rejected: %s", resp.Reason)
+	}
+
+	intent.ExpiresAt = time.Now().Add(-time.Second).UnixNano()
+	resp, _ := h(intent)
+	if resp.Accepted || resp.Reason != "intent expired" {
+		t.Errorf("expired intent: accepted=%v reason=%q", resp.Accepted, resp.Reason)
+	}
+}
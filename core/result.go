@@ -0,0 +1,63 @@
+package core
+
+// result.go — Inline results.
+//
+// Simple capabilities — an echo, a quick lookup — can answer an intent in
+// the NegotiationResponse itself instead of through a workflow.  Each side
+// of a handshake states in HandshakeMessage.MaxResultSize the largest
+// ResultPayload it accepts; a responder must not put a bigger one in a
+// response, and a requester refuses one that is.  A peer that states no
+// limit predates inline results and gets none.
+
+import "fmt"
+
+// DefaultMaxResultSize is the largest ResultPayload, in bytes of Data,
+// hosts accept unless configured otherwise.
+const DefaultMaxResultSize = 64 * 1024
+
+// ReasonResultTooLarge is the Reason of a NegotiationResponse whose result
+// was dropped because it exceeded the requester's limit.  The requester
+// should ask for the result through a workflow instead.
+const ReasonResultTooLarge = "result_too_large"
+
+// ErrResultTooLarge is returned (wrapped) for a result beyond the
+// negotiated limit.
+var ErrResultTooLarge = fmt.Errorf("result: payload exceeds the negotiated limit")
+
+// Size returns the size of r's data; zero for a nil r.
+func (r *ResultPayload) Size() int64 {
+	if r == nil {
+		return 0
+	}
+	return int64(len(r.Data))
+}
+
+// CheckResultSize returns an error wrapping ErrResultTooLarge if resp
+// carries a result bigger than limit.
+func CheckResultSize(resp *NegotiationResponse, limit int64) error {
+	if n := resp.Result.Size(); resp.Result != nil && n > limit {
+		return fmt.Errorf("%w: %d bytes, limit %d", ErrResultTooLarge, n, limit)
+	}
+	return nil
+}
+
+// SignResponse sets resp.Signature to agent's signature over the response
+// without RelayPath and ResponderKey, which relays and the responder's host
+// fill in afterwards.  Call it again after changing any other field.
+func SignResponse(agent *Agent, resp *NegotiationResponse) error {
+	sig, err := agent.Sign(responseSigningData(resp))
+	if err != nil {
+		return fmt.Errorf("response: sign: %w", err)
+	}
+	resp.Signature = sig
+	return nil
+}
+
+// responseSigningData is what a response's signature covers: its encoding
+// without Signature, RelayPath and ResponderKey.
+func responseSigningData(resp *NegotiationResponse) []byte {
+	c := *resp
+	c.Signature, c.RelayPath, c.ResponderKey = nil, nil, nil
+	data, _ := c.Encode()
+	return data
+}
@@ -0,0 +1,81 @@
+package core_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func TestResultPayload(t *testing.T) {
+	agent, _ := core.NewAgent("echo", []string{"echo"})
+	resp := &core.NegotiationResponse{
+		RequestID: "intent-1",
+		Accepted:  true,
+		Reason:    "echoed",
+		Result:    &core.ResultPayload{ContentType: "text/plain", Data: []byte("hello")},
+	}
+	if err := core.SignResponse(agent, resp); err != nil {
+		t.Fatalf("SignResponse: %v", err)
+	}
+
+	for _, c := range []core.Codec{core.ProtobufCodec, core.JSONCodec, core.CBORCodec} {
+		frame, err := core.FrameWith(c, resp)
+		if err != nil {
+			t.Fatalf("%s: FrameWith: %v", c.Name(), err)
+		}
+		msg, err := core.DecodeFrame(frame)
+		if err != nil {
+			t.Fatalf("%s: DecodeFrame: %v", c.Name(), err)
+		}
+		got := msg.(*core.NegotiationResponse)
+		if got.Result == nil || got.Result.ContentType != "text/plain" || string(got.Result.Data) != "hello" {
+			t.Fatalf("%s: result = %+v", c.Name(), got.Result)
+		}
+		if !core.VerifyResponseSignature(got, agent.PublicKey()) {
+			t.Errorf("%s: signature does not verify", c.Name())
+		}
+	}
+
+	tampered := *resp
+	tampered.Result = &core.ResultPayload{ContentType: "text/plain", Data: []byte("hullo")}
+	if core.VerifyResponseSignature(&tampered, agent.PublicKey()) {
+		t.Error("signature verifies over a tampered result")
+	}
+
+	// Relays fill in RelayPath and ResponderKey after the responder signed.
+	relayed := *resp
+	relayed.RelayPath, relayed.ResponderKey = []string{"did:key:relay"}, agent.PublicKey()
+	if !core.VerifyResponseSignature(&relayed, agent.PublicKey()) {
+		t.Error("signature does not verify once relayed")
+	}
+
+	if err := core.CheckResultSize(resp, 5); err != nil {
+		t.Errorf("CheckResultSize(5) = %v", err)
+	}
+	if err := core.CheckResultSize(resp, 4); !errors.Is(err, core.ErrResultTooLarge) {
+		t.Errorf("CheckResultSize(4) = %v, want ErrResultTooLarge", err)
+	}
+	if err := core.CheckResultSize(&core.NegotiationResponse{RequestID: "intent-2"}, 0); err != nil {
+		t.Errorf("CheckResultSize without result = %v", err)
+	}
+}
+
+func TestHandshakeMaxResultSize(t *testing.T) {
+	a, _ := core.NewAgent("a", nil)
+	b, _ := core.NewAgent("b", nil)
+	ours, _ := core.StartHandshake(a)
+	encoded, _ := ours.Encode()
+	decoded, err := core.DecodeHandshakeMessage(encoded)
+	if err != nil || decoded.MaxResultSize != core.DefaultMaxResultSize {
+		t.Fatalf("MaxResultSize = %d, %v", decoded.MaxResultSize, err)
+	}
+	resp, err := core.RespondHandshake(b, decoded)
+	if err != nil {
+		t.Fatalf("RespondHandshake: %v", err)
+	}
+	resp.MaxResultSize = 10
+	if res := core.NewHandshakeResult(resp); res.PeerMaxResult != 10 {
+		t.Errorf("PeerMaxResult = %d, want 10", res.PeerMaxResult)
+	}
+}
@@ -0,0 +1,220 @@
+package core
+
+// retention.go — Storage quotas and background cleanup.
+//
+// Long-running agents accumulate conversation memory, audit logs and other
+// history.  Each store that can shed old data implements Prunable; a Janitor
+// holds one RetentionPolicy per registered store ("table") and enforces them
+// on every sweep:
+//
+//	j := core.NewJanitor()
+//	j.Register("memory", memStore, core.RetentionPolicy{MaxAge: 30 * 24 * time.Hour, MaxBytes: 64 << 20})
+//	j.Register("audit", logger, core.RetentionPolicy{MaxAge: 90 * 24 * time.Hour})
+//	j.Start(time.Minute, done)
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrQuotaExceeded is returned by Janitor.Check (and stores guarded by it)
+// while a QuotaReject table is over its quota.
+var ErrQuotaExceeded = fmt.Errorf("retention: quota exceeded")
+
+// Prunable is a store whose old data a Janitor may delete.
+// Implementations must be concurrency-safe.
+type Prunable interface {
+	// Usage reports the number of entries and their approximate size.
+	Usage() (entries int, bytes int64, err error)
+	// Prune deletes entries older than before, then the oldest remaining
+	// entries until at most maxEntries and maxBytes remain (0 = unlimited).
+	// It returns the number of entries deleted.
+	Prune(before time.Time, maxEntries int, maxBytes int64) (int, error)
+}
+
+// QuotaAction is what a Janitor does when a table exceeds its quota after
+// age-based pruning.
+type QuotaAction int
+
+const (
+	QuotaEvict  QuotaAction = iota // Delete the oldest entries until within quota
+	QuotaReject                    // Keep data; Check fails until usage drops
+	QuotaAlert                     // Keep data; only call the OnQuota hook
+)
+
+// RetentionPolicy bounds one table.  Zero fields are unlimited.
+type RetentionPolicy struct {
+	MaxAge     time.Duration
+	MaxEntries int
+	MaxBytes   int64
+	OnQuota    QuotaAction
+}
+
+// TableStats reports a table's usage and the janitor's work on it.
+type TableStats struct {
+	Entries   int
+	Bytes     int64
+	Evicted   uint64 // Entries deleted since the janitor started
+	OverQuota bool   // Usage exceeded the quota at the last sweep
+	LastSweep time.Time
+	LastError error
+}
+
+type janitorTable struct {
+	store  Prunable
+	policy RetentionPolicy
+	stats  TableStats
+}
+
+// Janitor enforces RetentionPolicies.  It is concurrency-safe.
+type Janitor struct {
+	mu      sync.Mutex
+	tables  map[string]*janitorTable
+	onQuota func(table string, stats TableStats)
+}
+
+// NewJanitor creates a janitor with no tables.
+func NewJanitor() *Janitor {
+	return &Janitor{tables: make(map[string]*janitorTable)}
+}
+
+// Register adds (or replaces) the table name.
+func (j *Janitor) Register(name string, store Prunable, policy RetentionPolicy) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.tables[name] = &janitorTable{store: store, policy: policy}
+}
+
+// OnQuota sets a hook called from Sweep for every table found over quota,
+// whatever its QuotaAction.
+func (j *Janitor) OnQuota(fn func(table string, stats TableStats)) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.onQuota = fn
+}
+
+// Check returns ErrQuotaExceeded if name is a QuotaReject table that was
+// over quota at the last sweep.  Writers call it before adding data.
+func (j *Janitor) Check(name string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	t, ok := j.tables[name]
+	if ok && t.policy.OnQuota == QuotaReject && t.stats.OverQuota {
+		return fmt.Errorf("%w: %s", ErrQuotaExceeded, name)
+	}
+	return nil
+}
+
+// Sweep enforces every table's policy once.
+func (j *Janitor) Sweep() {
+	j.mu.Lock()
+	names := make([]string, 0, len(j.tables))
+	for name := range j.tables {
+		names = append(names, name)
+	}
+	onQuota := j.onQuota
+	j.mu.Unlock()
+	sort.Strings(names)
+
+	for _, name := range names {
+		j.mu.Lock()
+		t, ok := j.tables[name]
+		j.mu.Unlock()
+		if !ok {
+			continue
+		}
+		st := sweepTable(t.store, t.policy)
+		j.mu.Lock()
+		st.Evicted += t.stats.Evicted
+		t.stats = st
+		j.mu.Unlock()
+		if st.OverQuota && onQuota != nil {
+			onQuota(name, st)
+		}
+	}
+}
+
+// Start runs Sweep every interval until done is closed.
+func (j *Janitor) Start(interval time.Duration, done <-chan struct{}) {
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				j.Sweep()
+			case <-done:
+				return
+			}
+		}
+	}()
+}
+
+// Stats returns a snapshot of every table's stats.
+func (j *Janitor) Stats() map[string]TableStats {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	out := make(map[string]TableStats, len(j.tables))
+	for name, t := range j.tables {
+		out[name] = t.stats
+	}
+	return out
+}
+
+func sweepTable(store Prunable, p RetentionPolicy) TableStats {
+	st := TableStats{LastSweep: time.Now()}
+	var before time.Time
+	if p.MaxAge > 0 {
+		before = st.LastSweep.Add(-p.MaxAge)
+	}
+	maxEntries, maxBytes := 0, int64(0)
+	if p.OnQuota == QuotaEvict {
+		maxEntries, maxBytes = p.MaxEntries, p.MaxBytes
+	}
+	if !before.IsZero() || maxEntries > 0 || maxBytes > 0 {
+		n, err := store.Prune(before, maxEntries, maxBytes)
+		st.Evicted = uint64(n)
+		if err != nil {
+			st.LastError = err
+		}
+	}
+	entries, bytes, err := store.Usage()
+	if err != nil {
+		st.LastError = err
+		return st
+	}
+	st.Entries, st.Bytes = entries, bytes
+	st.OverQuota = (p.MaxEntries > 0 && entries > p.MaxEntries) || (p.MaxBytes > 0 && bytes > p.MaxBytes)
+	return st
+}
+
+// ------------------------------------------------------------------ guarded stores
+
+// GuardMemoryStore returns a MemoryStore whose Append fails with
+// ErrQuotaExceeded while j reports table as over quota.
+func GuardMemoryStore(store MemoryStore, j *Janitor, table string) MemoryStore {
+	return guardedMemory{MemoryStore: store, j: j, table: table}
+}
+
+type guardedMemory struct {
+	MemoryStore
+	j     *Janitor
+	table string
+}
+
+func (g guardedMemory) Append(did string, e MemoryEntry) error {
+	if err := g.j.Check(g.table); err != nil {
+		return err
+	}
+	return g.MemoryStore.Append(did, e)
+}
+
+// AllEntries forwards to the guarded store when it is a MemoryExporter.
+func (g guardedMemory) AllEntries() (map[string][]MemoryEntry, error) {
+	if e, ok := g.MemoryStore.(MemoryExporter); ok {
+		return e.AllEntries()
+	}
+	return nil, fmt.Errorf("memory: %T cannot list its entries", g.MemoryStore)
+}
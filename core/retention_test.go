@@ -0,0 +1,114 @@
+package core_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func TestJanitorEvictsByAgeAndCount(t *testing.T) {
+	store := core.NewInMemoryStore(0)
+	old := time.Now().Add(-48 * time.Hour).UnixNano()
+	_ = store.Append("did:a", core.MemoryEntry{Summary: "old", Timestamp: old})
+	for i := 0; i < 5; i++ {
+		_ = store.Append("did:a", core.MemoryEntry{Summary: "a", Timestamp: time.Now().UnixNano()})
+		_ = store.Append("did:b", core.MemoryEntry{Summary: "b", Timestamp: time.Now().UnixNano()})
+	}
+
+	j := core.NewJanitor()
+	j.Register("memory", store, core.RetentionPolicy{MaxAge: 24 * time.Hour, MaxEntries: 4})
+	j.Sweep()
+
+	st := j.Stats()["memory"]
+	if st.Entries != 4 || st.Evicted != 7 || st.OverQuota {
+		t.Errorf("stats: got %+v", st)
+	}
+	// The survivors are the newest entries across both peers.
+	a, _ := store.Entries("did:a")
+	b, _ := store.Entries("did:b")
+	if len(a)+len(b) != 4 {
+		t.Errorf("remaining: a=%d b=%d", len(a), len(b))
+	}
+	for _, e := range a {
+		if e.Summary == "old" {
+			t.Error("expired entry survived")
+		}
+	}
+}
+
+func TestJanitorRejectsWritesOverQuota(t *testing.T) {
+	store := core.NewInMemoryStore(0)
+	for i := 0; i < 3; i++ {
+		_ = store.Append("did:a", core.MemoryEntry{Summary: "x", Timestamp: time.Now().UnixNano()})
+	}
+	j := core.NewJanitor()
+	j.Register("memory", store, core.RetentionPolicy{MaxEntries: 2, OnQuota: core.QuotaReject})
+	var alerted string
+	j.OnQuota(func(table string, _ core.TableStats) { alerted = table })
+	j.Sweep()
+
+	if alerted != "memory" {
+		t.Errorf("OnQuota not called")
+	}
+	guarded := core.GuardMemoryStore(store, j, "memory")
+	if err := guarded.Append("did:a", core.MemoryEntry{}); !errors.Is(err, core.ErrQuotaExceeded) {
+		t.Errorf("Append over quota: got %v", err)
+	}
+	if n, _, _ := store.Usage(); n != 3 {
+		t.Errorf("QuotaReject evicted data: %d entries left", n)
+	}
+
+	_ = store.Forget("did:a")
+	j.Sweep()
+	if err := guarded.Append("did:a", core.MemoryEntry{}); err != nil {
+		t.Errorf("Append after cleanup: %v", err)
+	}
+}
+
+func TestFileMemoryStorePrune(t *testing.T) {
+	dir := t.TempDir()
+	store, err := core.OpenFileMemoryStore(dir, 0)
+	if err != nil {
+		t.Fatalf("OpenFileMemoryStore: %v", err)
+	}
+	old := time.Now().Add(-time.Hour).UnixNano()
+	_ = store.Append("did:gone", core.MemoryEntry{Summary: "stale", Timestamp: old})
+	_ = store.Append("did:kept", core.MemoryEntry{Summary: "fresh", Timestamp: time.Now().UnixNano()})
+
+	n, err := store.Prune(time.Now().Add(-time.Minute), 0, 0)
+	if err != nil || n != 1 {
+		t.Fatalf("Prune: n=%d err=%v", n, err)
+	}
+	files, _ := os.ReadDir(dir)
+	if len(files) != 1 {
+		t.Errorf("expected one file left, got %d", len(files))
+	}
+}
+
+func TestFileLoggerPrune(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	old := time.Now().Add(-2 * time.Hour).Format(time.RFC3339)
+	if err := os.WriteFile(path, []byte(old+" | ID: 1 | Type: intent | Details: old\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	l, err := core.NewFileLogger(path)
+	if err != nil {
+		t.Fatalf("NewFileLogger: %v", err)
+	}
+	defer l.Close()
+	_ = l.LogMessage("2", "intent", "new")
+
+	if n, err := l.Prune(time.Now().Add(-time.Hour), 0, 0); err != nil || n != 1 {
+		t.Fatalf("Prune: n=%d err=%v", n, err)
+	}
+	_ = l.LogMessage("3", "intent", "after prune")
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "ID: 1 ") || !strings.Contains(string(data), "ID: 3 ") {
+		t.Errorf("log after prune:\n%s", data)
+	}
+}
@@ -0,0 +1,218 @@
+package core
+
+// revocation.go — Signed revocation lists for compromised DIDs.
+//
+// Operators publish a RevocationList: the complete set of DIDs they have
+// revoked, signed with their DID key and numbered by Sequence.  Hosts fetch
+// lists over HTTP or receive them from peers, keep the newest list per
+// trusted issuer in a RevocationSet, and refuse handshakes, intents and
+// capability announcements from any revoked DID.  Unlike AlertMessages,
+// lists are idempotent snapshots: replaying an old one has no effect.
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// maxRevocationListSize bounds a list fetched over HTTP.
+const maxRevocationListSize = 4 * 1024 * 1024
+
+// ErrUntrustedIssuer is returned by RevocationSet.Apply for lists signed by
+// an issuer the set was not configured to trust.
+var ErrUntrustedIssuer = fmt.Errorf("revocation: untrusted issuer")
+
+// RevocationPoint identifies where a revoked DID was refused.
+type RevocationPoint int
+
+const (
+	RevokedAtHandshake RevocationPoint = iota
+	RevokedAtIntent
+	RevokedAtDiscovery
+	numRevocationPoints
+)
+
+// RevocationStats counts accepted lists and the traffic they refused.
+type RevocationStats struct {
+	Lists      int    // Issuers with an accepted list
+	Revoked    int    // Distinct revoked DIDs
+	Handshakes uint64 // Handshakes refused
+	Intents    uint64 // Intents and responses refused
+	Discovery  uint64 // Announcements dropped
+}
+
+// NewRevocationList creates and signs issuer's list.  seq must increase with
+// every list the issuer publishes.
+func NewRevocationList(issuer *Agent, seq int64, entries []RevocationEntry) (*RevocationList, error) {
+	l := &RevocationList{
+		IssuerDID: issuer.DID.String(),
+		IssuerKey: issuer.PublicKey(),
+		Sequence:  seq,
+		IssuedAt:  now(),
+		Entries:   append([]RevocationEntry(nil), entries...),
+	}
+	sig, err := issuer.Sign(revocationSigningData(l))
+	if err != nil {
+		return nil, fmt.Errorf("revocation: sign: %w", err)
+	}
+	l.Signature = sig
+	return l, nil
+}
+
+// VerifyRevocationList checks that l is signed by the key bound to IssuerDID.
+func VerifyRevocationList(l *RevocationList) error {
+	if len(l.Signature) == 0 {
+		return fmt.Errorf("revocation: list %d from %s is unsigned", l.Sequence, l.IssuerDID)
+	}
+	d, err := ParseDID(l.IssuerDID)
+	if err != nil {
+		return fmt.Errorf("revocation: %w", err)
+	}
+	if !d.ValidateBinding(l.IssuerKey) {
+		return fmt.Errorf("revocation: issuer key does not match %s", l.IssuerDID)
+	}
+	pub, err := DIDFromPublicKey(l.IssuerKey)
+	if err != nil {
+		return fmt.Errorf("revocation: %w", err)
+	}
+	if !pub.Verify(revocationSigningData(l), l.Signature) {
+		return fmt.Errorf("revocation: %w on list %d from %s", ErrSignatureInvalid, l.Sequence, l.IssuerDID)
+	}
+	return nil
+}
+
+// FetchRevocationList downloads a wire-encoded RevocationList from url.
+// The result is not verified; pass it to RevocationSet.Apply.
+func FetchRevocationList(ctx context.Context, url string) (*RevocationList, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("revocation: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("revocation: fetch %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("revocation: fetch %s: %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRevocationListSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("revocation: fetch %s: %w", url, err)
+	}
+	if len(data) > maxRevocationListSize {
+		return nil, fmt.Errorf("revocation: list at %s exceeds %d bytes", url, maxRevocationListSize)
+	}
+	return DecodeRevocationList(data)
+}
+
+// RevocationSet holds the newest list from each trusted issuer.
+// It is concurrency-safe.
+type RevocationSet struct {
+	mu      sync.RWMutex
+	issuers map[string]bool
+	lists   map[string]*RevocationList
+	revoked map[string]RevocationEntry
+	hits    [numRevocationPoints]uint64
+}
+
+// NewRevocationSet creates a set accepting lists signed by issuers.
+func NewRevocationSet(issuers ...string) *RevocationSet {
+	s := &RevocationSet{
+		issuers: make(map[string]bool),
+		lists:   make(map[string]*RevocationList),
+		revoked: make(map[string]RevocationEntry),
+	}
+	for _, d := range issuers {
+		s.issuers[d] = true
+	}
+	return s
+}
+
+// AddIssuer trusts lists signed by did.
+func (s *RevocationSet) AddIssuer(did string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.issuers[did] = true
+}
+
+// Apply verifies l and, if it is newer than the issuer's current list,
+// replaces it.  It reports whether the set changed.
+func (s *RevocationSet) Apply(l *RevocationList) (bool, error) {
+	if err := VerifyRevocationList(l); err != nil {
+		return false, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.issuers[l.IssuerDID] {
+		return false, fmt.Errorf("%w: %s", ErrUntrustedIssuer, l.IssuerDID)
+	}
+	if cur, ok := s.lists[l.IssuerDID]; ok && cur.Sequence >= l.Sequence {
+		return false, nil
+	}
+	s.lists[l.IssuerDID] = l
+	s.revoked = make(map[string]RevocationEntry)
+	for _, cur := range s.lists {
+		for _, e := range cur.Entries {
+			if prev, ok := s.revoked[e.DID]; !ok || e.RevokedAt < prev.RevokedAt {
+				s.revoked[e.DID] = e
+			}
+		}
+	}
+	return true, nil
+}
+
+// IsRevoked reports whether did appears on any accepted list.
+func (s *RevocationSet) IsRevoked(did string) (RevocationEntry, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	e, ok := s.revoked[did]
+	return e, ok
+}
+
+// Check is IsRevoked that also counts a hit at point.
+func (s *RevocationSet) Check(did string, point RevocationPoint) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.revoked[did]; !ok {
+		return false
+	}
+	s.hits[point]++
+	return true
+}
+
+// Lists returns the accepted lists, ordered by issuer DID.
+func (s *RevocationSet) Lists() []*RevocationList {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]*RevocationList, 0, len(s.lists))
+	for _, l := range s.lists {
+		out = append(out, l)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].IssuerDID < out[j].IssuerDID })
+	return out
+}
+
+// Stats returns a snapshot of the set's counters.
+func (s *RevocationSet) Stats() RevocationStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return RevocationStats{
+		Lists:      len(s.lists),
+		Revoked:    len(s.revoked),
+		Handshakes: s.hits[RevokedAtHandshake],
+		Intents:    s.hits[RevokedAtIntent],
+		Discovery:  s.hits[RevokedAtDiscovery],
+	}
+}
+
+// revocationSigningData is the encoded list with the signature cleared.
+func revocationSigningData(l *RevocationList) []byte {
+	c := *l
+	c.Signature = nil
+	data, _ := c.Encode()
+	return data
+}
@@ -0,0 +1,76 @@
+package core_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func TestRevocationListRoundTrip(t *testing.T) {
+	op, _ := core.NewAgent("operator", nil)
+	l, err := core.NewRevocationList(op, 1, []core.RevocationEntry{
+		{DID: "did:agent:aaaa", Reason: "key leaked", RevokedAt: 42},
+		{DID: "did:agent:bbbb"},
+	})
+	if err != nil {
+		t.Fatalf("NewRevocationList: %v", err)
+	}
+	data, _ := l.Encode()
+	got, err := core.DecodeRevocationList(data)
+	if err != nil {
+		t.Fatalf("DecodeRevocationList: %v", err)
+	}
+	if len(got.Entries) != 2 || got.Entries[0].Reason != "key leaked" || got.Entries[0].RevokedAt != 42 {
+		t.Errorf("entries: got %+v", got.Entries)
+	}
+	if err = core.VerifyRevocationList(got); err != nil {
+		t.Errorf("VerifyRevocationList: %v", err)
+	}
+	got.Entries = got.Entries[:1]
+	if core.VerifyRevocationList(got) == nil {
+		t.Error("expected truncated list to fail verification")
+	}
+}
+
+func TestRevocationSetApply(t *testing.T) {
+	op, _ := core.NewAgent("operator", nil)
+	rogue, _ := core.NewAgent("rogue", nil)
+	victim, _ := core.NewAgent("victim", nil)
+	did := victim.DID.String()
+
+	s := core.NewRevocationSet(op.DID.String())
+
+	forged, _ := core.NewRevocationList(rogue, 1, []core.RevocationEntry{{DID: did}})
+	if _, err := s.Apply(forged); !errors.Is(err, core.ErrUntrustedIssuer) {
+		t.Errorf("untrusted issuer: got %v", err)
+	}
+
+	v1, _ := core.NewRevocationList(op, 1, []core.RevocationEntry{{DID: did}})
+	if changed, err := s.Apply(v1); err != nil || !changed {
+		t.Fatalf("Apply v1: changed=%v err=%v", changed, err)
+	}
+	if !s.Check(did, core.RevokedAtIntent) {
+		t.Error("victim not revoked")
+	}
+
+	// A replayed older list must not un-revoke anything.
+	v0, _ := core.NewRevocationList(op, 0, nil)
+	if changed, _ := s.Apply(v0); changed {
+		t.Error("older list replaced newer one")
+	}
+
+	// A newer list that omits the DID reinstates it.
+	v2, _ := core.NewRevocationList(op, 2, nil)
+	if changed, _ := s.Apply(v2); !changed {
+		t.Fatal("newer list not applied")
+	}
+	if s.Check(did, core.RevokedAtIntent) {
+		t.Error("victim still revoked after v2")
+	}
+
+	st := s.Stats()
+	if st.Lists != 1 || st.Revoked != 0 || st.Intents != 1 {
+		t.Errorf("stats: got %+v", st)
+	}
+}
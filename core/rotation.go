@@ -0,0 +1,217 @@
+package core
+
+// rotation.go — Replacing an agent's key without losing its reputation.
+//
+// A DID is derived from its key, so a new key means a new DID.  A
+// KeyRotation links the two: the retiring key signs the new DID and key,
+// and the new key countersigns, so nobody can claim someone else's DID as
+// their replacement.  Receivers record rotations in a KeyRotationSet,
+// refuse the retired DID from then on, and move the trust they held about
+// it to the new DID with TrustGraph.Migrate.
+//
+// A DID is rotated at most once: the first verified rotation wins, and a
+// later one naming a different replacement is refused.  An attacker holding
+// a stolen key can therefore race the owner, so rotations complement
+// revocation lists rather than replace them.
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ErrConflictingRotation is returned by KeyRotationSet.Apply for a rotation
+// of a DID already rotated to a different replacement, or to a DID that has
+// itself been retired.
+var ErrConflictingRotation = fmt.Errorf("rotation: conflicts with an earlier rotation")
+
+// NewKeyRotation retires old in favour of next, signed by both.
+func NewKeyRotation(old, next *Agent, reason string) (*KeyRotation, error) {
+	r := &KeyRotation{
+		OldDID:    old.DID.String(),
+		OldKey:    old.PublicKey(),
+		NewDID:    next.DID.String(),
+		NewKey:    next.PublicKey(),
+		Reason:    reason,
+		RotatedAt: now(),
+	}
+	data := rotationSigningData(r)
+	sig, err := old.Sign(data)
+	if err != nil {
+		return nil, fmt.Errorf("rotation: sign: %w", err)
+	}
+	newSig, err := next.Sign(data)
+	if err != nil {
+		return nil, fmt.Errorf("rotation: sign: %w", err)
+	}
+	r.Signature, r.NewSignature = sig, newSig
+	return r, nil
+}
+
+// Rotate creates a successor to a with a fresh key and the same ID,
+// capabilities, manifest, specs, intent ID generator and random source, and
+// the rotation
+// linking the two.  Credentials are not carried over: they name a's DID
+// and must be reissued.
+func (a *Agent) Rotate(reason string) (*Agent, *KeyRotation, error) {
+	next, err := NewAgentWithEntropy(a.ID, a.Capabilities, a.Entropy)
+	if err != nil {
+		return nil, nil, err
+	}
+	next.Manifest = a.Manifest
+	next.Specs = a.Specs
+	next.IntentIDs = a.IntentIDs
+	r, err := NewKeyRotation(a, next, reason)
+	if err != nil {
+		return nil, nil, err
+	}
+	return next, r, nil
+}
+
+// VerifyKeyRotation checks that r is signed by the keys bound to both
+// OldDID and NewDID.
+func VerifyKeyRotation(r *KeyRotation) error {
+	if len(r.Signature) == 0 || len(r.NewSignature) == 0 {
+		return fmt.Errorf("rotation: of %s is unsigned", r.OldDID)
+	}
+	if r.OldDID == r.NewDID {
+		return fmt.Errorf("rotation: of %s names itself", r.OldDID)
+	}
+	data := rotationSigningData(r)
+	for _, k := range []struct {
+		did      string
+		key, sig []byte
+		side     string
+	}{
+		{r.OldDID, r.OldKey, r.Signature, "old"},
+		{r.NewDID, r.NewKey, r.NewSignature, "new"},
+	} {
+		d, err := ParseDID(k.did)
+		if err != nil {
+			return fmt.Errorf("rotation: %w", err)
+		}
+		if !d.ValidateBinding(k.key) {
+			return fmt.Errorf("rotation: %s key does not match %s", k.side, k.did)
+		}
+		pub, err := DIDFromPublicKey(k.key)
+		if err != nil {
+			return fmt.Errorf("rotation: %w", err)
+		}
+		if !pub.Verify(data, k.sig) {
+			return fmt.Errorf("rotation: %w by the %s key on rotation of %s", ErrSignatureInvalid, k.side, r.OldDID)
+		}
+	}
+	return nil
+}
+
+// KeyRotationSet holds the rotations an agent has accepted, keyed by the
+// retired DID.  It is concurrency-safe.
+type KeyRotationSet struct {
+	mu      sync.RWMutex
+	retired map[string]*KeyRotation
+}
+
+// NewKeyRotationSet creates an empty set.
+func NewKeyRotationSet() *KeyRotationSet {
+	return &KeyRotationSet{retired: make(map[string]*KeyRotation)}
+}
+
+// Apply verifies r and records it.  It reports whether the set changed:
+// replaying an accepted rotation has no effect.
+func (s *KeyRotationSet) Apply(r *KeyRotation) (bool, error) {
+	if err := VerifyKeyRotation(r); err != nil {
+		return false, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cur, ok := s.retired[r.OldDID]; ok {
+		if cur.NewDID == r.NewDID {
+			return false, nil
+		}
+		return false, fmt.Errorf("%w: %s already rotated to %s", ErrConflictingRotation, r.OldDID, cur.NewDID)
+	}
+	// Refusing retired replacements also keeps chains free of cycles.
+	if _, ok := s.retired[r.NewDID]; ok {
+		return false, fmt.Errorf("%w: %s is retired", ErrConflictingRotation, r.NewDID)
+	}
+	s.retired[r.OldDID] = r
+	return true, nil
+}
+
+// Retired returns the rotation that retired did, if any.
+func (s *KeyRotationSet) Retired(did string) (*KeyRotation, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	r, ok := s.retired[did]
+	return r, ok
+}
+
+// Current follows rotations from did to the DID now in use, which is did
+// itself if it has not been rotated.
+func (s *KeyRotationSet) Current(did string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for {
+		r, ok := s.retired[did]
+		if !ok {
+			return did
+		}
+		did = r.NewDID
+	}
+}
+
+// Rotations returns the accepted rotations, oldest first.
+func (s *KeyRotationSet) Rotations() []*KeyRotation {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]*KeyRotation, 0, len(s.retired))
+	for _, r := range s.retired {
+		out = append(out, r)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].RotatedAt < out[j].RotatedAt })
+	return out
+}
+
+// Migrate copies every trust edge from or to oldDID onto newDID, so the
+// trust history of a rotated key carries over.  Edges newDID already has
+// are kept, and the retired edges stay in place.  Like Set, it returns
+// only persistence errors.
+func (tg *TrustGraph) Migrate(oldDID, newDID string) error {
+	tg.mu.Lock()
+	defer tg.mu.Unlock()
+	moved := make(map[string]float32)
+	for k, v := range tg.scores {
+		from, to, _ := SplitTrustKey(k)
+		switch {
+		case from == oldDID && to != newDID:
+			from = newDID
+		case to == oldDID && from != newDID:
+			to = newDID
+		default:
+			continue
+		}
+		if _, ok := tg.scores[trustKey(from, to)]; !ok {
+			moved[trustKey(from, to)] = v
+		}
+	}
+	var firstErr error
+	for k, v := range moved {
+		tg.scores[k] = v
+		if tg.store == nil {
+			continue
+		}
+		from, to, _ := SplitTrustKey(k)
+		if err := tg.store.Set(from, to, v); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// rotationSigningData is the encoded rotation without either signature.
+func rotationSigningData(r *KeyRotation) []byte {
+	c := *r
+	c.Signature, c.NewSignature = nil, nil
+	data, _ := c.Encode()
+	return data
+}
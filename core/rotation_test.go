@@ -0,0 +1,110 @@
+package core_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func TestKeyRotationRoundTrip(t *testing.T) {
+	old, _ := core.NewAgent("a", []string{"summarise"})
+	next, r, err := old.Rotate("scheduled")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.ID != old.ID || next.DID.String() == old.DID.String() || r.NewDID != next.DID.String() {
+		t.Fatalf("Rotate: successor %s/%s, rotation to %s", next.ID, next.DID, r.NewDID)
+	}
+	for _, c := range []core.Codec{core.ProtobufCodec, core.JSONCodec, core.CBORCodec} {
+		frame, err := core.FrameWith(c, r)
+		if err != nil {
+			t.Fatalf("%s: %v", c.Name(), err)
+		}
+		got, err := core.DecodeFrame(frame)
+		if err != nil {
+			t.Fatalf("%s: %v", c.Name(), err)
+		}
+		if !reflect.DeepEqual(got, r) {
+			t.Errorf("%s: got %+v, want %+v", c.Name(), got, r)
+		}
+		if err = core.VerifyKeyRotation(got.(*core.KeyRotation)); err != nil {
+			t.Errorf("%s: %v", c.Name(), err)
+		}
+	}
+
+	// Claiming someone else's DID as the replacement fails: their key
+	// never countersigned.
+	victim, _ := core.NewAgent("v", nil)
+	hijack := *r
+	hijack.NewDID, hijack.NewKey = victim.DID.String(), victim.PublicKey()
+	if core.VerifyKeyRotation(&hijack) == nil {
+		t.Error("rotation to a foreign DID verified")
+	}
+	unsigned := *r
+	unsigned.NewSignature = nil
+	if core.VerifyKeyRotation(&unsigned) == nil {
+		t.Error("rotation without the new key's signature verified")
+	}
+}
+
+func TestKeyRotationSet(t *testing.T) {
+	a, _ := core.NewAgent("a", nil)
+	b, ab, _ := a.Rotate("")
+	c, bc, _ := b.Rotate("")
+	s := core.NewKeyRotationSet()
+
+	for _, r := range []*core.KeyRotation{ab, bc} {
+		if changed, err := s.Apply(r); !changed || err != nil {
+			t.Fatalf("Apply: %v, %v", changed, err)
+		}
+	}
+	if changed, err := s.Apply(ab); changed || err != nil {
+		t.Errorf("replayed Apply: %v, %v; want no change", changed, err)
+	}
+	if got := s.Current(a.DID.String()); got != c.DID.String() {
+		t.Errorf("Current(a) = %s; want %s", got, c.DID)
+	}
+	if _, ok := s.Retired(c.DID.String()); ok {
+		t.Error("current DID reported retired")
+	}
+
+	other, _ := core.NewAgent("x", nil)
+	fork, _ := core.NewKeyRotation(a, other, "")
+	if _, err := s.Apply(fork); !errors.Is(err, core.ErrConflictingRotation) {
+		t.Errorf("second rotation of a: %v; want ErrConflictingRotation", err)
+	}
+	back, _ := core.NewKeyRotation(c, a, "")
+	if _, err := s.Apply(back); !errors.Is(err, core.ErrConflictingRotation) {
+		t.Errorf("rotation to a retired DID: %v; want ErrConflictingRotation", err)
+	}
+	if got := len(s.Rotations()); got != 2 {
+		t.Errorf("Rotations: %d; want 2", got)
+	}
+}
+
+func TestTrustGraphMigrate(t *testing.T) {
+	tg := core.NewTrustGraph()
+	_ = tg.Set("me", "old", 0.9)
+	_ = tg.Set("old", "peer", 0.7)
+	_ = tg.Set("other", "old", 0.4)
+	_ = tg.Set("other", "new", 0.2) // Earned since the rotation; kept.
+
+	if err := tg.Migrate("old", "new"); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		from, to string
+		want     float32
+	}{
+		{"me", "new", 0.9},
+		{"new", "peer", 0.7},
+		{"other", "new", 0.2},
+		{"me", "old", 0.9},
+	} {
+		if got := tg.Get(c.from, c.to); got != c.want {
+			t.Errorf("%s->%s = %v; want %v", c.from, c.to, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,421 @@
+package core
+
+// schema.go — A JSON Schema subset for capability contracts.
+//
+// CapabilitySpecs describe their params and results with JSON Schema.  The
+// validator here covers the keywords contracts need without a third-party
+// dependency: type (a name or a list), enum, const, properties, required,
+// additionalProperties, items, minItems/maxItems, minLength/maxLength,
+// pattern, minimum/maximum and exclusiveMinimum/exclusiveMaximum (as
+// numbers), plus allOf/anyOf/oneOf/not.  Annotations such as title and
+// description are ignored; $ref and other keywords that change validation
+// are rejected when the schema is compiled, so a schema never passes data
+// it was meant to refuse.
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+)
+
+// ErrSchemaViolation is wrapped by errors reporting data that does not
+// match a schema.
+var ErrSchemaViolation = fmt.Errorf("schema violation")
+
+// Schema is a compiled JSON Schema.
+type Schema struct {
+	types      []string
+	enum       []interface{}
+	constVal   interface{}
+	hasConst   bool
+	properties map[string]*Schema
+	required   []string
+	additional *Schema // nil: anything; see noAdditional
+	noAddl     bool
+	items      *Schema
+	minItems   *int
+	maxItems   *int
+	minLength  *int
+	maxLength  *int
+	pattern    *regexp.Regexp
+	minimum    *float64
+	maximum    *float64
+	exclMin    *float64
+	exclMax    *float64
+	allOf      []*Schema
+	anyOf      []*Schema
+	oneOf      []*Schema
+	not        *Schema
+}
+
+// schemaAnnotations are keywords that do not affect validation.
+var schemaAnnotations = map[string]bool{
+	"$schema": true, "$id": true, "$comment": true, "title": true, "description": true,
+	"default": true, "examples": true, "deprecated": true, "readOnly": true, "writeOnly": true,
+	"format": true,
+}
+
+// CompileSchema parses a JSON Schema.  An empty schema accepts anything.
+func CompileSchema(data []byte) (*Schema, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return &Schema{}, nil
+	}
+	var raw interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("schema: %w", err)
+	}
+	return compileSchema(raw, "#")
+}
+
+func compileSchema(raw interface{}, at string) (*Schema, error) {
+	s := &Schema{}
+	switch v := raw.(type) {
+	case bool:
+		if !v {
+			s.not = &Schema{}
+		}
+		return s, nil
+	case map[string]interface{}:
+		for _, k := range sortedKeys(v) {
+			if err := s.keyword(k, v[k], at); err != nil {
+				return nil, err
+			}
+		}
+		return s, nil
+	}
+	return nil, fmt.Errorf("schema: %s: must be an object or boolean", at)
+}
+
+func (s *Schema) keyword(k string, v interface{}, at string) error {
+	var err error
+	switch k {
+	case "type":
+		switch t := v.(type) {
+		case string:
+			s.types = []string{t}
+		case []interface{}:
+			for _, x := range t {
+				name, ok := x.(string)
+				if !ok {
+					return fmt.Errorf("schema: %s/type: must list type names", at)
+				}
+				s.types = append(s.types, name)
+			}
+		default:
+			return fmt.Errorf("schema: %s/type: must be a string or a list", at)
+		}
+		for _, t := range s.types {
+			switch t {
+			case "null", "boolean", "object", "array", "number", "integer", "string":
+			default:
+				return fmt.Errorf("schema: %s/type: unknown type %q", at, t)
+			}
+		}
+	case "enum":
+		list, ok := v.([]interface{})
+		if !ok {
+			return fmt.Errorf("schema: %s/enum: must be a list", at)
+		}
+		s.enum = list
+	case "const":
+		s.constVal, s.hasConst = v, true
+	case "properties":
+		props, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("schema: %s/properties: must be an object", at)
+		}
+		s.properties = make(map[string]*Schema, len(props))
+		for _, name := range sortedKeys(props) {
+			if s.properties[name], err = compileSchema(props[name], at+"/properties/"+name); err != nil {
+				return err
+			}
+		}
+	case "required":
+		list, ok := v.([]interface{})
+		if !ok {
+			return fmt.Errorf("schema: %s/required: must be a list", at)
+		}
+		for _, x := range list {
+			name, ok := x.(string)
+			if !ok {
+				return fmt.Errorf("schema: %s/required: must list property names", at)
+			}
+			s.required = append(s.required, name)
+		}
+	case "additionalProperties":
+		if b, ok := v.(bool); ok {
+			s.noAddl = !b
+			return nil
+		}
+		s.additional, err = compileSchema(v, at+"/additionalProperties")
+	case "items":
+		s.items, err = compileSchema(v, at+"/items")
+	case "allOf", "anyOf", "oneOf":
+		list, ok := v.([]interface{})
+		if !ok || len(list) == 0 {
+			return fmt.Errorf("schema: %s/%s: must be a non-empty list", at, k)
+		}
+		subs := make([]*Schema, len(list))
+		for i, x := range list {
+			if subs[i], err = compileSchema(x, fmt.Sprintf("%s/%s/%d", at, k, i)); err != nil {
+				return err
+			}
+		}
+		switch k {
+		case "allOf":
+			s.allOf = subs
+		case "anyOf":
+			s.anyOf = subs
+		default:
+			s.oneOf = subs
+		}
+	case "not":
+		s.not, err = compileSchema(v, at+"/not")
+	case "minItems", "maxItems", "minLength", "maxLength":
+		n, ok := v.(float64)
+		if !ok || n < 0 || n != math.Trunc(n) {
+			return fmt.Errorf("schema: %s/%s: must be a non-negative integer", at, k)
+		}
+		i := int(n)
+		switch k {
+		case "minItems":
+			s.minItems = &i
+		case "maxItems":
+			s.maxItems = &i
+		case "minLength":
+			s.minLength = &i
+		default:
+			s.maxLength = &i
+		}
+	case "minimum", "maximum", "exclusiveMinimum", "exclusiveMaximum":
+		n, ok := v.(float64)
+		if !ok {
+			return fmt.Errorf("schema: %s/%s: must be a number", at, k)
+		}
+		switch k {
+		case "minimum":
+			s.minimum = &n
+		case "maximum":
+			s.maximum = &n
+		case "exclusiveMinimum":
+			s.exclMin = &n
+		default:
+			s.exclMax = &n
+		}
+	case "pattern":
+		p, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("schema: %s/pattern: must be a string", at)
+		}
+		if s.pattern, err = regexp.Compile(p); err != nil {
+			return fmt.Errorf("schema: %s/pattern: %w", at, err)
+		}
+	default:
+		if !schemaAnnotations[k] {
+			return fmt.Errorf("schema: %s: unsupported keyword %q", at, k)
+		}
+	}
+	return err
+}
+
+// Validate checks the JSON document data against s.
+func (s *Schema) Validate(data []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return fmt.Errorf("%w: invalid JSON: %v", ErrSchemaViolation, err)
+	}
+	return s.ValidateValue(v)
+}
+
+// ValidateValue checks a value decoded by encoding/json against s.
+func (s *Schema) ValidateValue(v interface{}) error {
+	if msg := s.check(v, "$"); msg != "" {
+		return fmt.Errorf("%w: %s", ErrSchemaViolation, msg)
+	}
+	return nil
+}
+
+// check returns a description of the first violation, or "".
+func (s *Schema) check(v interface{}, path string) string {
+	if len(s.types) > 0 && !typeMatches(s.types, v) {
+		return fmt.Sprintf("%s: expected %s, got %s", path, joinTypes(s.types), jsonType(v))
+	}
+	if s.hasConst && !reflect.DeepEqual(v, s.constVal) {
+		return fmt.Sprintf("%s: must equal %s", path, mustJSON(s.constVal))
+	}
+	if s.enum != nil {
+		found := false
+		for _, e := range s.enum {
+			if reflect.DeepEqual(v, e) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Sprintf("%s: %s is not one of %s", path, mustJSON(v), mustJSON(s.enum))
+		}
+	}
+
+	switch x := v.(type) {
+	case map[string]interface{}:
+		for _, name := range s.required {
+			if _, ok := x[name]; !ok {
+				return fmt.Sprintf("%s: missing required property %q", path, name)
+			}
+		}
+		for _, name := range sortedKeys(x) {
+			sub, ok := s.properties[name]
+			switch {
+			case ok:
+			case s.noAddl:
+				return fmt.Sprintf("%s: unexpected property %q", path, name)
+			case s.additional != nil:
+				sub = s.additional
+			default:
+				continue
+			}
+			if msg := sub.check(x[name], path+"."+name); msg != "" {
+				return msg
+			}
+		}
+	case []interface{}:
+		if s.minItems != nil && len(x) < *s.minItems {
+			return fmt.Sprintf("%s: %d items, fewer than %d", path, len(x), *s.minItems)
+		}
+		if s.maxItems != nil && len(x) > *s.maxItems {
+			return fmt.Sprintf("%s: %d items, more than %d", path, len(x), *s.maxItems)
+		}
+		if s.items != nil {
+			for i, item := range x {
+				if msg := s.items.check(item, fmt.Sprintf("%s[%d]", path, i)); msg != "" {
+					return msg
+				}
+			}
+		}
+	case string:
+		n := utf8.RuneCountInString(x)
+		if s.minLength != nil && n < *s.minLength {
+			return fmt.Sprintf("%s: shorter than %d characters", path, *s.minLength)
+		}
+		if s.maxLength != nil && n > *s.maxLength {
+			return fmt.Sprintf("%s: longer than %d characters", path, *s.maxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(x) {
+			return fmt.Sprintf("%s: does not match %q", path, s.pattern)
+		}
+	case float64:
+		switch {
+		case s.minimum != nil && x < *s.minimum:
+			return fmt.Sprintf("%s: %v is less than %v", path, x, *s.minimum)
+		case s.maximum != nil && x > *s.maximum:
+			return fmt.Sprintf("%s: %v is greater than %v", path, x, *s.maximum)
+		case s.exclMin != nil && x <= *s.exclMin:
+			return fmt.Sprintf("%s: %v is not greater than %v", path, x, *s.exclMin)
+		case s.exclMax != nil && x >= *s.exclMax:
+			return fmt.Sprintf("%s: %v is not less than %v", path, x, *s.exclMax)
+		}
+	}
+
+	for _, sub := range s.allOf {
+		if msg := sub.check(v, path); msg != "" {
+			return msg
+		}
+	}
+	if s.anyOf != nil {
+		ok := false
+		for _, sub := range s.anyOf {
+			if sub.check(v, path) == "" {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return fmt.Sprintf("%s: matches none of anyOf", path)
+		}
+	}
+	if s.oneOf != nil {
+		n := 0
+		for _, sub := range s.oneOf {
+			if sub.check(v, path) == "" {
+				n++
+			}
+		}
+		if n != 1 {
+			return fmt.Sprintf("%s: matches %d of oneOf, want exactly 1", path, n)
+		}
+	}
+	if s.not != nil && s.not.check(v, path) == "" {
+		return fmt.Sprintf("%s: matches a schema it must not", path)
+	}
+	return ""
+}
+
+func typeMatches(types []string, v interface{}) bool {
+	got := jsonType(v)
+	for _, t := range types {
+		if t == got || t == "number" && got == "integer" {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonType names the JSON type of v; numbers without a fraction are
+// "integer".
+func jsonType(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		if x == math.Trunc(x) && !math.IsInf(x, 0) {
+			return "integer"
+		}
+		return "number"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func joinTypes(types []string) string {
+	if len(types) == 1 {
+		return types[0]
+	}
+	out := ""
+	for i, t := range types {
+		if i > 0 {
+			out += " or "
+		}
+		out += t
+	}
+	return out
+}
+
+func mustJSON(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return strconv.Quote(fmt.Sprint(v))
+	}
+	return string(b)
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
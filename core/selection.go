@@ -0,0 +1,213 @@
+package core
+
+// selection.go — Fair choice among equivalent responders.
+//
+// RankCandidates orders peers by how well their profile matches an intent,
+// so when many peers are equally able the same one wins every time.  A
+// Selector reorders the ranked candidates before one is picked:
+//
+//	BestMatch           keep the ranking (the default)
+//	RoundRobin          rotate through the candidates, per capability
+//	LeastRecentlyUsed   prefer the candidate picked longest ago
+//	PowerOfTwoChoices   of two random candidates, prefer the less loaded
+//
+// A SelectionPolicy holds a default Selector and per-capability overrides.
+
+import (
+	"math/rand/v2"
+	"sort"
+	"sync"
+)
+
+// Selection is the input to a Selector.
+type Selection struct {
+	Capability string
+	// Candidates are ranked best match first.
+	Candidates []AgentProfile
+	// Load reports the outstanding work of an agent; nil means unknown
+	// (treated as zero).
+	Load func(agentID string) int
+	// Assessed holds, by AgentID, the SimilarityScore each candidate
+	// reported for the request when it has already been offered to them;
+	// nil otherwise.
+	Assessed map[string]float32
+}
+
+func (s Selection) load(agentID string) int {
+	if s.Load == nil {
+		return 0
+	}
+	return s.Load(agentID)
+}
+
+// Selector orders candidates for one request.  Implementations must be
+// concurrency-safe.
+type Selector interface {
+	// Order returns s.Candidates in the order they should be tried.
+	Order(s Selection) []AgentProfile
+	// Picked tells the selector which candidate was finally used.
+	Picked(capability, agentID string)
+}
+
+// BestMatch returns the Selector that keeps the ranking.
+func BestMatch() Selector { return bestMatch{} }
+
+type bestMatch struct{}
+
+func (bestMatch) Order(s Selection) []AgentProfile { return s.Candidates }
+func (bestMatch) Picked(string, string)            {}
+
+// RoundRobin rotates the starting candidate on every request, separately
+// for each capability.
+type RoundRobin struct {
+	mu   sync.Mutex
+	next map[string]int
+}
+
+// NewRoundRobin creates a RoundRobin selector.
+func NewRoundRobin() *RoundRobin { return &RoundRobin{next: make(map[string]int)} }
+
+// Order implements Selector.  Candidates are rotated in agent ID order, so
+// the rotation is stable while scores fluctuate.
+func (r *RoundRobin) Order(s Selection) []AgentProfile {
+	if len(s.Candidates) < 2 {
+		return s.Candidates
+	}
+	byID := append([]AgentProfile(nil), s.Candidates...)
+	sort.SliceStable(byID, func(i, j int) bool { return byID[i].AgentID < byID[j].AgentID })
+	r.mu.Lock()
+	start := r.next[s.Capability] % len(byID)
+	r.next[s.Capability] = start + 1
+	r.mu.Unlock()
+	out := make([]AgentProfile, 0, len(byID))
+	out = append(out, byID[start:]...)
+	return append(out, byID[:start]...)
+}
+
+// Picked implements Selector.
+func (r *RoundRobin) Picked(string, string) {}
+
+// LeastRecentlyUsed prefers the candidate picked longest ago; candidates
+// never picked come first, in ranked order.
+type LeastRecentlyUsed struct {
+	mu    sync.Mutex
+	clock uint64
+	last  map[string]uint64 // capability + "\x00" + agent ID → pick number
+}
+
+// NewLeastRecentlyUsed creates a LeastRecentlyUsed selector.
+func NewLeastRecentlyUsed() *LeastRecentlyUsed {
+	return &LeastRecentlyUsed{last: make(map[string]uint64)}
+}
+
+// Order implements Selector.
+func (l *LeastRecentlyUsed) Order(s Selection) []AgentProfile {
+	out := append([]AgentProfile(nil), s.Candidates...)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	sort.SliceStable(out, func(i, j int) bool {
+		return l.last[s.Capability+"\x00"+out[i].AgentID] < l.last[s.Capability+"\x00"+out[j].AgentID]
+	})
+	return out
+}
+
+// Picked implements Selector.
+func (l *LeastRecentlyUsed) Picked(capability, agentID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.clock++
+	l.last[capability+"\x00"+agentID] = l.clock
+}
+
+// PowerOfTwoChoices samples two candidates at random and tries the less
+// loaded one first, then the other, then the rest in ranked order.
+type PowerOfTwoChoices struct {
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+// NewPowerOfTwoChoices creates a PowerOfTwoChoices selector.  The same seed
+// gives the same sequence of choices.
+func NewPowerOfTwoChoices(seed uint64) *PowerOfTwoChoices {
+	return &PowerOfTwoChoices{rnd: rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))}
+}
+
+// Order implements Selector.
+func (p *PowerOfTwoChoices) Order(s Selection) []AgentProfile {
+	n := len(s.Candidates)
+	if n < 2 {
+		return s.Candidates
+	}
+	p.mu.Lock()
+	i := p.rnd.IntN(n)
+	j := p.rnd.IntN(n - 1)
+	p.mu.Unlock()
+	if j >= i {
+		j++
+	}
+	if s.load(s.Candidates[j].AgentID) < s.load(s.Candidates[i].AgentID) {
+		i, j = j, i
+	}
+	out := make([]AgentProfile, 0, n)
+	out = append(out, s.Candidates[i], s.Candidates[j])
+	for k, c := range s.Candidates {
+		if k != i && k != j {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// Picked implements Selector.
+func (p *PowerOfTwoChoices) Picked(string, string) {}
+
+// SelectionPolicy maps capabilities to Selectors.  A nil policy selects by
+// best match.
+type SelectionPolicy struct {
+	mu    sync.RWMutex
+	def   Selector
+	byCap map[string]Selector
+}
+
+// NewSelectionPolicy creates a policy using def (BestMatch if nil) for
+// capabilities without their own Selector.
+func NewSelectionPolicy(def Selector) *SelectionPolicy {
+	if def == nil {
+		def = BestMatch()
+	}
+	return &SelectionPolicy{def: def, byCap: make(map[string]Selector)}
+}
+
+// SetDefault replaces the default Selector.
+func (p *SelectionPolicy) SetDefault(s Selector) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.def = s
+}
+
+// Set uses s for capability; a nil s reverts to the default.
+func (p *SelectionPolicy) Set(capability string, s Selector) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if s == nil {
+		delete(p.byCap, capability)
+		return
+	}
+	p.byCap[capability] = s
+}
+
+// For returns the Selector for capability.
+func (p *SelectionPolicy) For(capability string) Selector {
+	if p == nil {
+		return BestMatch()
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if s, ok := p.byCap[capability]; ok {
+		return s
+	}
+	if p.def == nil {
+		return BestMatch()
+	}
+	return p.def
+}
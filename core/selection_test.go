@@ -0,0 +1,71 @@
+package core_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func pick(s core.Selector, capability string, candidates []core.AgentProfile, load func(string) int) string {
+	ordered := s.Order(core.Selection{Capability: capability, Candidates: candidates, Load: load})
+	s.Picked(capability, ordered[0].AgentID)
+	return ordered[0].AgentID
+}
+
+func TestSelectors(t *testing.T) {
+	candidates := []core.AgentProfile{{AgentID: "c"}, {AgentID: "a"}, {AgentID: "b"}}
+
+	if got := pick(core.BestMatch(), "x", candidates, nil); got != "c" {
+		t.Errorf("BestMatch picked %q, want the top-ranked c", got)
+	}
+
+	rr := core.NewRoundRobin()
+	var seq []string
+	for i := 0; i < 4; i++ {
+		seq = append(seq, pick(rr, "x", candidates, nil))
+	}
+	if want := []string{"a", "b", "c", "a"}; !slices.Equal(seq, want) {
+		t.Errorf("RoundRobin picked %v, want %v", seq, want)
+	}
+	if got := pick(rr, "y", candidates, nil); got != "a" {
+		t.Errorf("RoundRobin for another capability started at %q, want a", got)
+	}
+
+	lru := core.NewLeastRecentlyUsed()
+	seq = nil
+	for i := 0; i < 4; i++ {
+		seq = append(seq, pick(lru, "x", candidates, nil))
+	}
+	if want := []string{"c", "a", "b", "c"}; !slices.Equal(seq, want) {
+		t.Errorf("LeastRecentlyUsed picked %v, want %v", seq, want)
+	}
+
+	// With one idle candidate among busy ones, two choices always prefer it
+	// whenever it is sampled, so it must win more often than its share.
+	p2c := core.NewPowerOfTwoChoices(1)
+	load := func(id string) int {
+		if id == "b" {
+			return 0
+		}
+		return 10
+	}
+	wins := 0
+	for i := 0; i < 300; i++ {
+		if pick(p2c, "x", candidates, load) == "b" {
+			wins++
+		}
+	}
+	if wins < 150 {
+		t.Errorf("PowerOfTwoChoices chose the idle peer %d/300 times, want most", wins)
+	}
+
+	policy := core.NewSelectionPolicy(nil)
+	policy.Set("x", rr)
+	if policy.For("x") != core.Selector(rr) {
+		t.Error("policy ignored the per-capability selector")
+	}
+	if got := pick(policy.For("z"), "z", candidates, nil); got != "c" {
+		t.Errorf("default selector picked %q, want c", got)
+	}
+}
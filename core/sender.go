@@ -0,0 +1,30 @@
+package core
+
+// sender.go — What the transport established about an intent's sender.
+//
+// An IntentMessage names its sender in DID, but that field is only a claim.
+// The host that received the intent knows which DID the connection
+// handshaked as and whether it checked the intent's signature against that
+// DID's key, and passes both to context-aware handlers.
+
+import "context"
+
+// Sender describes the peer an intent arrived from.
+type Sender struct {
+	DID      string // DID the connection handshaked as; empty if it never did
+	Verified bool   // The intent's signature was checked against DID's key
+}
+
+type senderKey struct{}
+
+// ContextWithSender returns a context carrying s.
+func ContextWithSender(ctx context.Context, s Sender) context.Context {
+	return context.WithValue(ctx, senderKey{}, s)
+}
+
+// SenderFromContext returns the sender placed in ctx by the host.  Without
+// one the zero Sender is returned: unknown and unverified.
+func SenderFromContext(ctx context.Context) Sender {
+	s, _ := ctx.Value(senderKey{}).(Sender)
+	return s
+}
@@ -0,0 +1,281 @@
+package core
+
+// session.go — Multi-round negotiation with counter-offers.
+//
+// A plain IntentMessage/NegotiationResponse exchange is single-shot.  A
+// NegotiationSession lets both sides keep bargaining after the intent: the
+// responder may propose alternative capabilities, a reduced workflow, or
+// price/latency terms, and the initiator may accept, counter, or abort.
+// Each party keeps its own session object, feeding it the moves it makes
+// (Propose, Accept, Abort) and the moves it receives (Receive).
+//
+//	initiator                         responder
+//	   ── IntentMessage ───────────────▶
+//	   ◀────────────── CounterOffer(propose, round 1)
+//	   ── CounterOffer(propose, round 2) ▶
+//	   ◀────────────── CounterOffer(accept, round 3)   → both sessions Agreed
+
+import (
+	"fmt"
+	"sync"
+)
+
+// SessionState is the state of a NegotiationSession.
+type SessionState int
+
+const (
+	SessionOpen     SessionState = iota // Moves may still be made
+	SessionAgreed                       // A proposal (or the intent itself) was accepted
+	SessionRejected                     // The responder declined; a counter-offer reopens it
+	SessionAborted                      // A party aborted or the round limit was hit
+)
+
+func (s SessionState) String() string {
+	switch s {
+	case SessionOpen:
+		return "open"
+	case SessionAgreed:
+		return "agreed"
+	case SessionRejected:
+		return "rejected"
+	case SessionAborted:
+		return "aborted"
+	}
+	return fmt.Sprintf("SessionState(%d)", int(s))
+}
+
+// DefaultMaxRounds bounds a session when NewNegotiationSession gets maxRounds <= 0.
+const DefaultMaxRounds = 8
+
+// Session errors.
+var (
+	ErrSessionClosed   = fmt.Errorf("session: already closed")
+	ErrNotYourTurn     = fmt.Errorf("session: not this party's turn")
+	ErrNothingToAccept = fmt.Errorf("session: no open proposal from the peer")
+	ErrTooManyRounds   = fmt.Errorf("session: round limit reached")
+)
+
+// NegotiationSession tracks one multi-round negotiation from the point of
+// view of a single party.  It is safe for concurrent use.
+type NegotiationSession struct {
+	Intent    *IntentMessage
+	MaxRounds int32
+
+	mu      sync.Mutex
+	self    *Agent
+	peerKey []byte
+	state   SessionState
+	history []*CounterOffer
+	agreed  *OfferTerms
+}
+
+// NewNegotiationSession starts a session for intent on behalf of self, which
+// may be either the initiator or the responder.
+func NewNegotiationSession(self *Agent, intent *IntentMessage, maxRounds int) *NegotiationSession {
+	if maxRounds <= 0 {
+		maxRounds = DefaultMaxRounds
+	}
+	return &NegotiationSession{Intent: intent, MaxRounds: int32(maxRounds), self: self}
+}
+
+// SetPeerKey requires every received CounterOffer to be signed by pubKey,
+// typically the key learned during the handshake.
+func (s *NegotiationSession) SetPeerKey(pubKey []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.peerKey = append([]byte(nil), pubKey...)
+}
+
+// State returns the current session state.
+func (s *NegotiationSession) State() SessionState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state
+}
+
+// History returns every move made so far, oldest first.
+func (s *NegotiationSession) History() []*CounterOffer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*CounterOffer(nil), s.history...)
+}
+
+// Agreed returns the accepted terms once the session is SessionAgreed.
+func (s *NegotiationSession) Agreed() (OfferTerms, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.agreed == nil {
+		return OfferTerms{}, false
+	}
+	return *s.agreed, true
+}
+
+// HandleResponse applies a plain NegotiationResponse to the intent.
+// Acceptance agrees on the intent's capabilities and the returned workflow;
+// rejection moves the session to SessionRejected until a counter-offer arrives.
+func (s *NegotiationSession) HandleResponse(resp *NegotiationResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if resp.RequestID != s.Intent.ID {
+		return fmt.Errorf("session: response answers %q, not %q", resp.RequestID, s.Intent.ID)
+	}
+	if s.state != SessionOpen {
+		return ErrSessionClosed
+	}
+	if !resp.Accepted {
+		s.state = SessionRejected
+		return nil
+	}
+	s.state = SessionAgreed
+	s.agreed = &OfferTerms{
+		Capabilities:  append([]string(nil), s.Intent.Capabilities...),
+		WorkflowSteps: append([]string(nil), resp.WorkflowSteps...),
+	}
+	return nil
+}
+
+// Propose makes a counter-offer with terms.  It fails once the round limit
+// is reached; the party must then accept or abort.
+func (s *NegotiationSession) Propose(terms OfferTerms, reason string) (*CounterOffer, error) {
+	return s.move(CounterPropose, terms, reason)
+}
+
+// Accept accepts the peer's last proposal.
+func (s *NegotiationSession) Accept(reason string) (*CounterOffer, error) {
+	return s.move(CounterAccept, OfferTerms{}, reason)
+}
+
+// Abort ends the session without agreement.  It is valid on any turn.
+func (s *NegotiationSession) Abort(reason string) (*CounterOffer, error) {
+	return s.move(CounterAbort, OfferTerms{}, reason)
+}
+
+// Receive validates and applies a CounterOffer sent by the peer.
+func (s *NegotiationSession) Receive(m *CounterOffer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if m.RequestID != s.Intent.ID {
+		return fmt.Errorf("session: offer for %q, not %q", m.RequestID, s.Intent.ID)
+	}
+	if m.DID == s.self.DID.String() {
+		return fmt.Errorf("session: offer claims to come from this agent")
+	}
+	if want := int32(len(s.history)) + 1; m.Round != want {
+		return fmt.Errorf("session: offer round %d, expected %d", m.Round, want)
+	}
+	if s.peerKey != nil {
+		if len(m.Signature) == 0 || !VerifyCounterOfferSignature(m, s.peerKey) {
+			return fmt.Errorf("session: invalid counter-offer signature from %s", m.DID)
+		}
+	}
+	if err := s.validate(m.DID, m.Action); err != nil {
+		return err
+	}
+	s.apply(m)
+	return nil
+}
+
+// move builds, signs, and applies one of our own moves.
+func (s *NegotiationSession) move(action CounterAction, terms OfferTerms, reason string) (*CounterOffer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	did := s.self.DID.String()
+	if err := s.validate(did, action); err != nil {
+		return nil, err
+	}
+	m := &CounterOffer{
+		RequestID: s.Intent.ID,
+		Round:     int32(len(s.history)) + 1,
+		AgentID:   s.self.ID,
+		DID:       did,
+		Action:    action,
+		Terms:     terms,
+		Reason:    reason,
+		Timestamp: now(),
+	}
+	if err := SignCounterOffer(s.self, m); err != nil {
+		return nil, err
+	}
+	s.apply(m)
+	return m, nil
+}
+
+// validate checks that the party did may make action now.  Caller holds s.mu.
+func (s *NegotiationSession) validate(did string, action CounterAction) error {
+	if s.state == SessionAgreed || s.state == SessionAborted {
+		return ErrSessionClosed
+	}
+	var last *CounterOffer
+	if len(s.history) > 0 {
+		last = s.history[len(s.history)-1]
+	}
+	switch action {
+	case CounterAbort:
+		return nil
+	case CounterAccept:
+		if last == nil || last.DID == did || last.Action != CounterPropose {
+			return ErrNothingToAccept
+		}
+		return nil
+	case CounterPropose:
+		if last != nil && last.DID == did {
+			return ErrNotYourTurn
+		}
+		if int32(len(s.history)) >= s.MaxRounds {
+			return ErrTooManyRounds
+		}
+		return nil
+	}
+	return fmt.Errorf("session: unknown action %d", action)
+}
+
+// apply records m and updates the state.  Caller holds s.mu.
+func (s *NegotiationSession) apply(m *CounterOffer) {
+	s.history = append(s.history, m)
+	switch m.Action {
+	case CounterPropose:
+		s.state = SessionOpen
+	case CounterAccept:
+		t := s.history[len(s.history)-2].Terms
+		s.agreed = &t
+		s.state = SessionAgreed
+	case CounterAbort:
+		s.state = SessionAborted
+	}
+}
+
+// SignCounterOffer sets m.Signature to agent's signature over the encoded
+// offer with the signature field cleared.
+func SignCounterOffer(agent *Agent, m *CounterOffer) error {
+	m.Signature = nil
+	data, err := m.Encode()
+	if err != nil {
+		return err
+	}
+	sig, err := agent.Sign(data)
+	if err != nil {
+		return fmt.Errorf("counter: sign: %w", err)
+	}
+	m.Signature = sig
+	return nil
+}
+
+// VerifyCounterOfferSignature returns true if m.Signature is a valid Ed25519
+// signature of the encoded offer (without its signature) by the owner of pubKey.
+// Returns true when Signature is empty (unsigned messages are accepted).
+func VerifyCounterOfferSignature(m *CounterOffer, pubKey []byte) bool {
+	if len(m.Signature) == 0 {
+		return true
+	}
+	d, err := DIDFromPublicKey(pubKey)
+	if err != nil {
+		return false
+	}
+	unsigned := *m
+	unsigned.Signature = nil
+	data, err := unsigned.Encode()
+	if err != nil {
+		return false
+	}
+	return d.Verify(data, m.Signature)
+}
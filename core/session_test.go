@@ -0,0 +1,112 @@
+package core_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func TestNegotiationSessionCounterOffers(t *testing.T) {
+	alice, _ := core.NewAgent("alice", nil)
+	bob, _ := core.NewAgent("bob", []string{"summarisation"})
+
+	intent, err := core.CreateIntent(alice, []float32{1}, []string{"translation"}, "doc")
+	if err != nil {
+		t.Fatalf("CreateIntent: %v", err)
+	}
+	as := core.NewNegotiationSession(alice, intent, 4)
+	bs := core.NewNegotiationSession(bob, intent, 4)
+	as.SetPeerKey(bob.PublicKey())
+	bs.SetPeerKey(alice.PublicKey())
+
+	// Bob cannot translate but offers a summary instead.
+	offer, err := bs.Propose(core.OfferTerms{Capabilities: []string{"summarisation"}, Price: 2}, "no translation")
+	if err != nil {
+		t.Fatalf("bob Propose: %v", err)
+	}
+	if _, err = bs.Propose(core.OfferTerms{Price: 1}, "again"); !errors.Is(err, core.ErrNotYourTurn) {
+		t.Errorf("expected ErrNotYourTurn, got %v", err)
+	}
+
+	// The offer survives the wire.
+	data, _ := offer.Encode()
+	decoded, err := core.DecodeCounterOffer(data)
+	if err != nil {
+		t.Fatalf("DecodeCounterOffer: %v", err)
+	}
+	if err = as.Receive(decoded); err != nil {
+		t.Fatalf("alice Receive: %v", err)
+	}
+
+	// Alice counters on price; Bob accepts.
+	counter, err := as.Propose(core.OfferTerms{Capabilities: []string{"summarisation"}, Price: 1.5}, "cheaper")
+	if err != nil {
+		t.Fatalf("alice Propose: %v", err)
+	}
+	if err = bs.Receive(counter); err != nil {
+		t.Fatalf("bob Receive: %v", err)
+	}
+	accept, err := bs.Accept("deal")
+	if err != nil {
+		t.Fatalf("bob Accept: %v", err)
+	}
+	if err = as.Receive(accept); err != nil {
+		t.Fatalf("alice Receive accept: %v", err)
+	}
+
+	for name, s := range map[string]*core.NegotiationSession{"alice": as, "bob": bs} {
+		terms, ok := s.Agreed()
+		if s.State() != core.SessionAgreed || !ok || terms.Price != 1.5 {
+			t.Errorf("%s: state %v, terms %+v", name, s.State(), terms)
+		}
+	}
+	if _, err = as.Abort("too late"); !errors.Is(err, core.ErrSessionClosed) {
+		t.Errorf("expected ErrSessionClosed, got %v", err)
+	}
+}
+
+func TestNegotiationSessionRejectsForgedOffer(t *testing.T) {
+	alice, _ := core.NewAgent("alice", nil)
+	bob, _ := core.NewAgent("bob", nil)
+	mallory, _ := core.NewAgent("mallory", nil)
+
+	intent, _ := core.CreateIntent(alice, []float32{1}, []string{"x"}, "")
+	as := core.NewNegotiationSession(alice, intent, 0)
+	as.SetPeerKey(bob.PublicKey())
+
+	forged := core.NewNegotiationSession(mallory, intent, 0)
+	offer, err := forged.Propose(core.OfferTerms{Price: 100}, "")
+	if err != nil {
+		t.Fatalf("Propose: %v", err)
+	}
+	offer.DID = bob.DID.String()
+	if err = as.Receive(offer); err == nil {
+		t.Fatal("expected forged offer to be rejected")
+	}
+	if as.State() != core.SessionOpen {
+		t.Errorf("state changed on rejected offer: %v", as.State())
+	}
+}
+
+func TestNegotiationSessionRoundLimit(t *testing.T) {
+	alice, _ := core.NewAgent("alice", nil)
+	bob, _ := core.NewAgent("bob", nil)
+	intent, _ := core.CreateIntent(alice, []float32{1}, []string{"x"}, "")
+
+	as := core.NewNegotiationSession(alice, intent, 1)
+	bs := core.NewNegotiationSession(bob, intent, 1)
+	offer, err := bs.Propose(core.OfferTerms{Price: 3}, "")
+	if err != nil {
+		t.Fatalf("Propose: %v", err)
+	}
+	if err = as.Receive(offer); err != nil {
+		t.Fatalf("Receive: %v", err)
+	}
+	if _, err = as.Propose(core.OfferTerms{Price: 2}, ""); !errors.Is(err, core.ErrTooManyRounds) {
+		t.Errorf("expected ErrTooManyRounds, got %v", err)
+	}
+	if _, err = as.Abort("no deal"); err != nil || as.State() != core.SessionAborted {
+		t.Errorf("Abort: %v, state %v", err, as.State())
+	}
+}
@@ -1,9 +1,14 @@
 package core_test
 
 import (
+	"context"
+	"errors"
+	"math"
 	"testing"
+	"time"
 
 	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/embeddings"
 )
 
 // ------------------------------------------------------------------ VerifyIntentSignature
@@ -98,11 +103,56 @@ func TestIntentMessageSigning(t *testing.T) {
 		t.Fatal("CreateIntent should set a non-empty Signature")
 	}
 
-	if !agent.DID.Verify([]byte(intent.ID+intent.Payload), intent.Signature) {
+	if !core.VerifyIntentSignature(intent, agent.PublicKey()) {
 		t.Error("Signature failed to verify against sender DID")
 	}
 }
 
+// TestIntentSignatureCoversAllFields verifies that changing any field of a
+// signed intent invalidates its signature.
+func TestIntentSignatureCoversAllFields(t *testing.T) {
+	agent, _ := core.NewAgent("signer", []string{"nlp"})
+	tampers := map[string]func(m *core.IntentMessage){
+		"vector":       func(m *core.IntentMessage) { m.IntentVector[0] = 0.6 },
+		"capabilities": func(m *core.IntentMessage) { m.Capabilities = []string{"admin"} },
+		"timestamp":    func(m *core.IntentMessage) { m.Timestamp++ },
+		"trust score":  func(m *core.IntentMessage) { m.TrustScore = 1 },
+		"metadata":     func(m *core.IntentMessage) { m.Metadata["priority"] = "critical" },
+		"expires_at":   func(m *core.IntentMessage) { m.ExpiresAt = math.MaxInt64 },
+		"budget":       func(m *core.IntentMessage) { m.Budget = &core.Budget{MaxAmount: 1 << 40} },
+		"deadline":     func(m *core.IntentMessage) { m.Deadline = 1 },
+		"max latency":  func(m *core.IntentMessage) { m.MaxLatency = 1 },
+	}
+	for name, tamper := range tampers {
+		intent, _ := core.CreateIntent(agent, []float32{0.5, 0.5}, []string{"nlp"}, "hello")
+		intent.Metadata["a"], intent.Metadata["b"], intent.Metadata["c"] = "1", "2", "3"
+		if err := core.SignIntent(agent, intent); err != nil {
+			t.Fatalf("SignIntent: %v", err)
+		}
+		if !core.VerifyIntentSignature(intent, agent.PublicKey()) {
+			t.Fatalf("%s: the untouched intent does not verify", name)
+		}
+		tamper(intent)
+		if core.VerifyIntentSignature(intent, agent.PublicKey()) {
+			t.Errorf("%s: the signature still verifies after the change", name)
+		}
+	}
+}
+
+func TestCreateIntentFromText(t *testing.T) {
+	agent, _ := core.NewAgent("signer", []string{"nlp"})
+	intent, err := core.CreateIntentFromText(context.Background(), agent, embeddings.NewHashing(64), []string{"nlp"}, "summarise the report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(intent.IntentVector) != 64 || intent.Payload != "summarise the report" {
+		t.Errorf("got %d-dim vector, payload %q", len(intent.IntentVector), intent.Payload)
+	}
+	if !core.VerifyIntentSignature(intent, agent.PublicKey()) {
+		t.Error("intent from text is not signed")
+	}
+}
+
 func TestIntentSignatureRoundTrip(t *testing.T) {
 	agent, err := core.NewAgent("signer", []string{"nlp"})
 	if err != nil {
@@ -128,7 +178,7 @@ func TestIntentSignatureRoundTrip(t *testing.T) {
 		t.Error("Signature not preserved across encode/decode round-trip")
 	}
 
-	if !agent.DID.Verify([]byte(decoded.ID+decoded.Payload), decoded.Signature) {
+	if !core.VerifyIntentSignature(decoded, agent.PublicKey()) {
 		t.Error("Decoded signature failed to verify")
 	}
 }
@@ -155,11 +205,47 @@ func TestNegotiationResponseSigning(t *testing.T) {
 		t.Fatal("NegotiationResponse should carry a Signature")
 	}
 
-	if !agent.DID.Verify([]byte(resp.RequestID+resp.Reason), resp.Signature) {
+	if !core.VerifyResponseSignature(resp, agent.PublicKey()) {
 		t.Error("NegotiationResponse Signature failed to verify")
 	}
 }
 
+// TestResponseSignatureCoversAllFields verifies that changing any field but
+// RelayPath and ResponderKey breaks a response's signature.
+func TestResponseSignatureCoversAllFields(t *testing.T) {
+	agent, _ := core.NewAgent("responder", []string{"code-gen"})
+	resp := &core.NegotiationResponse{
+		RequestID:      "test-id",
+		Accepted:       true,
+		DID:            agent.DID.String(),
+		ResponseVector: []float32{0.5, 0.5},
+		Reason:         "ok",
+		TrustDelta:     0.1,
+		Cost:           &core.CostEstimate{Amount: 10, Currency: "EUR"},
+		Bid:            &core.Bid{Cost: 1, Confidence: 0.9},
+	}
+	if err := core.SignResponse(agent, resp); err != nil {
+		t.Fatalf("SignResponse: %v", err)
+	}
+	tampers := map[string]func(r *core.NegotiationResponse){
+		"accepted":    func(r *core.NegotiationResponse) { r.Accepted = false },
+		"trust delta": func(r *core.NegotiationResponse) { r.TrustDelta = 1 },
+		"vector":      func(r *core.NegotiationResponse) { r.ResponseVector = []float32{1, 0} },
+		"steps":       func(r *core.NegotiationResponse) { r.WorkflowSteps = []string{"rm -rf"} },
+		"similarity":  func(r *core.NegotiationResponse) { r.SimilarityScore = 1 },
+		"retry after": func(r *core.NegotiationResponse) { r.RetryAfter = int64(time.Hour) },
+		"cost":        func(r *core.NegotiationResponse) { r.Cost = &core.CostEstimate{Amount: 1, Currency: "EUR"} },
+		"bid":         func(r *core.NegotiationResponse) { r.Bid = &core.Bid{Cost: 0, Confidence: 1} },
+	}
+	for name, tamper := range tampers {
+		c := *resp
+		tamper(&c)
+		if core.VerifyResponseSignature(&c, agent.PublicKey()) {
+			t.Errorf("signature verifies with a changed %s", name)
+		}
+	}
+}
+
 func TestNegotiationResponseSignatureRoundTrip(t *testing.T) {
 	agent, err := core.NewAgent("responder", []string{"nlp"})
 	if err != nil {
@@ -187,3 +273,39 @@ func TestNegotiationResponseSignatureRoundTrip(t *testing.T) {
 		t.Error("Signature not preserved across encode/decode round-trip")
 	}
 }
+
+// ------------------------------------------------------------------ SignAnnouncement
+
+func TestAnnouncementSignature(t *testing.T) {
+	agent, _ := core.NewAgent("announcer", []string{"nlp"})
+	ann := core.BuildAnnouncement(agent, 60)
+	if err := core.VerifyAnnouncement(ann); err == nil {
+		t.Error("unsigned announcement verified")
+	}
+	if err := core.SignAnnouncement(agent, ann); err != nil {
+		t.Fatalf("SignAnnouncement: %v", err)
+	}
+	data, _ := ann.Encode()
+	decoded, err := core.DecodeCapabilityAnnouncement(data)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if err = core.VerifyAnnouncement(decoded); err != nil {
+		t.Errorf("VerifyAnnouncement after a round trip: %v", err)
+	}
+
+	tampered := *decoded
+	tampered.Capabilities = []string{"nlp", "payments"}
+	if err = core.VerifyAnnouncement(&tampered); !errors.Is(err, core.ErrSignatureInvalid) {
+		t.Errorf("tampered capabilities: got %v, want ErrSignatureInvalid", err)
+	}
+
+	// Another agent cannot announce as this one, even with a valid signature.
+	mallory, _ := core.NewAgent("mallory", nil)
+	forged := core.BuildAnnouncement(mallory, 60)
+	forged.DID = agent.DID.String()
+	_ = core.SignAnnouncement(mallory, forged)
+	if err = core.VerifyAnnouncement(forged); err == nil {
+		t.Error("announcement signed by another key verified")
+	}
+}
@@ -0,0 +1,142 @@
+package core
+
+// snapshot.go — Portable agent state for migrating between hosts.
+//
+// An AgentSnapshot carries everything an agent has accumulated: its identity
+// key, the trust scores it has learned, its discovery registry, per-peer
+// memory, intents still waiting to be delivered and workflow checkpoints.
+// SealSnapshot encrypts it into a PEM bundle with the same scheme as
+// encrypted identity files (AES-256-GCM under a PBKDF2-SHA256 key), so the
+// bundle can be copied to new hardware and opened there with OpenSnapshot:
+//
+//	bundle, _ := core.SealSnapshot(snap, passphrase)
+//	...
+//	snap, err := core.OpenSnapshot(bundle, passphrase)
+//	agent, err := snap.Agent()
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+)
+
+const (
+	pemEncryptedSnapshot = "ENCRYPTED AGENT SNAPSHOT"
+	snapshotVersion      = 1
+)
+
+// QueuedIntent is an intent that had not been delivered when the snapshot
+// was taken.
+type QueuedIntent struct {
+	Peer   string         `json:"peer"` // Recipient, as understood by the transport (e.g. a libp2p peer ID)
+	Intent *IntentMessage `json:"intent"`
+}
+
+// AgentSnapshot is an agent's complete state.
+type AgentSnapshot struct {
+	Version      int                      `json:"version"`
+	AgentID      string                   `json:"agent_id"`
+	Capabilities []string                 `json:"capabilities,omitempty"`
+	PrivateKey   []byte                   `json:"private_key"` // PKCS#8 DER
+	TakenAt      int64                    `json:"taken_at"`    // Unix nanoseconds
+	Trust        map[string]float32       `json:"trust,omitempty"`
+	Discovery    []DiscoveryRecord        `json:"discovery,omitempty"`
+	Memory       map[string][]MemoryEntry `json:"memory,omitempty"`
+	Outbox       []QueuedIntent           `json:"outbox,omitempty"`
+	Checkpoints  map[string][]byte        `json:"checkpoints,omitempty"` // Opaque workflow state keyed by workflow ID
+}
+
+// NewAgentSnapshot creates a snapshot holding agent's identity.  The caller
+// fills in the remaining state.
+func NewAgentSnapshot(agent *Agent) (*AgentSnapshot, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(ed25519.PrivateKey(agent.privKey))
+	if err != nil {
+		return nil, fmt.Errorf("snapshot: marshal key: %w", err)
+	}
+	return &AgentSnapshot{
+		Version:      snapshotVersion,
+		AgentID:      agent.ID,
+		Capabilities: append([]string(nil), agent.Capabilities...),
+		PrivateKey:   der,
+		TakenAt:      now(),
+	}, nil
+}
+
+// Agent reconstructs the snapshotted agent.  Its DID is unchanged.
+func (s *AgentSnapshot) Agent() (*Agent, error) {
+	key, err := x509.ParsePKCS8PrivateKey(s.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot: parse key: %w", err)
+	}
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("snapshot: key is %T, not Ed25519", key)
+	}
+	return NewAgentFromKey(s.AgentID, append([]string(nil), s.Capabilities...), priv)
+}
+
+// RestoreTrust writes the snapshot's trust scores into tg.
+func (s *AgentSnapshot) RestoreTrust(tg *TrustGraph) error {
+	for k, v := range s.Trust {
+		from, to, ok := SplitTrustKey(k)
+		if !ok {
+			return fmt.Errorf("snapshot: invalid trust key %q", k)
+		}
+		if err := tg.Set(from, to, v); err != nil {
+			return fmt.Errorf("snapshot: restore trust: %w", err)
+		}
+	}
+	return nil
+}
+
+// RestoreMemory appends the snapshot's memory entries to store.
+func (s *AgentSnapshot) RestoreMemory(store MemoryStore) error {
+	for did, entries := range s.Memory {
+		for _, e := range entries {
+			if err := store.Append(did, e); err != nil {
+				return fmt.Errorf("snapshot: restore memory: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// SealSnapshot encrypts s under passphrase.  The bundle contains the
+// agent's private key, so a passphrase is required.
+func SealSnapshot(s *AgentSnapshot, passphrase []byte) ([]byte, error) {
+	if len(passphrase) == 0 {
+		return nil, fmt.Errorf("snapshot: a passphrase is required")
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot: encode: %w", err)
+	}
+	block, err := sealPEM(pemEncryptedSnapshot, data, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(block), nil
+}
+
+// OpenSnapshot decrypts a bundle written by SealSnapshot.  A wrong
+// passphrase yields ErrBadPassphrase.
+func OpenSnapshot(bundle, passphrase []byte) (*AgentSnapshot, error) {
+	block, _ := pem.Decode(bundle)
+	if block == nil || block.Type != pemEncryptedSnapshot {
+		return nil, fmt.Errorf("snapshot: not an agent snapshot")
+	}
+	data, err := openPEM(block, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	var s AgentSnapshot
+	if err = json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("snapshot: decode: %w", err)
+	}
+	if s.Version != snapshotVersion {
+		return nil, fmt.Errorf("snapshot: unsupported version %d", s.Version)
+	}
+	return &s, nil
+}
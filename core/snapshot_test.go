@@ -0,0 +1,96 @@
+package core_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func TestSnapshotSealOpen(t *testing.T) {
+	a, _ := core.NewAgent("alpha", []string{"nlp"})
+	tg := core.NewTrustGraph()
+	_ = tg.Set(a.DID.String(), "did:peer", 0.8)
+	reg := core.NewDiscoveryRegistry()
+	reg.Announce(core.AgentProfile{AgentID: "beta", DID: "did:beta", Capabilities: []string{"ocr"}}, 3600)
+	mem := core.NewInMemoryStore(0)
+	_ = mem.Append("did:peer", core.MemoryEntry{IntentID: "i1", Summary: "done", Accepted: true, Timestamp: 1})
+	intent, _ := core.CreateIntent(a, []float32{0.5}, []string{"ocr"}, "scan")
+
+	snap, err := core.NewAgentSnapshot(a)
+	if err != nil {
+		t.Fatalf("NewAgentSnapshot: %v", err)
+	}
+	snap.Trust = tg.Snapshot()
+	snap.Discovery = reg.Export()
+	if snap.Memory, err = mem.AllEntries(); err != nil {
+		t.Fatalf("AllEntries: %v", err)
+	}
+	snap.Outbox = []core.QueuedIntent{{Peer: "12D3Koo", Intent: intent}}
+	snap.Checkpoints = map[string][]byte{"wf-1": []byte("step-3")}
+
+	bundle, err := core.SealSnapshot(snap, []byte("pass"))
+	if err != nil {
+		t.Fatalf("SealSnapshot: %v", err)
+	}
+	if _, err = core.OpenSnapshot(bundle, []byte("wrong")); !errors.Is(err, core.ErrBadPassphrase) {
+		t.Fatalf("wrong passphrase: got %v", err)
+	}
+	got, err := core.OpenSnapshot(bundle, []byte("pass"))
+	if err != nil {
+		t.Fatalf("OpenSnapshot: %v", err)
+	}
+
+	b, err := got.Agent()
+	if err != nil {
+		t.Fatalf("Agent: %v", err)
+	}
+	if b.DID.String() != a.DID.String() || b.ID != "alpha" {
+		t.Errorf("identity changed: %s %s", b.ID, b.DID)
+	}
+
+	tg2 := core.NewTrustGraph()
+	if err = got.RestoreTrust(tg2); err != nil {
+		t.Fatalf("RestoreTrust: %v", err)
+	}
+	if s := tg2.Get(a.DID.String(), "did:peer"); s != 0.8 {
+		t.Errorf("trust: got %v", s)
+	}
+	reg2 := core.NewDiscoveryRegistry()
+	reg2.Import(got.Discovery)
+	if p, ok := reg2.FindByDID("did:beta"); !ok || p.Capabilities[0] != "ocr" {
+		t.Errorf("discovery: got %+v %v", p, ok)
+	}
+	mem2 := core.NewInMemoryStore(0)
+	if err = got.RestoreMemory(mem2); err != nil {
+		t.Fatalf("RestoreMemory: %v", err)
+	}
+	if e, _ := mem2.Entries("did:peer"); len(e) != 1 || e[0].IntentID != "i1" {
+		t.Errorf("memory: got %+v", e)
+	}
+	if len(got.Outbox) != 1 || !core.VerifyIntentSignature(got.Outbox[0].Intent, a.PublicKey()) {
+		t.Error("outbox intent lost or no longer verifies")
+	}
+	if string(got.Checkpoints["wf-1"]) != "step-3" {
+		t.Errorf("checkpoints: got %q", got.Checkpoints)
+	}
+}
+
+func TestSnapshotRequiresPassphrase(t *testing.T) {
+	a, _ := core.NewAgent("alpha", nil)
+	snap, _ := core.NewAgentSnapshot(a)
+	if _, err := core.SealSnapshot(snap, nil); err == nil {
+		t.Error("expected error sealing without a passphrase")
+	}
+}
+
+func TestDiscoveryImportSkipsExpired(t *testing.T) {
+	reg := core.NewDiscoveryRegistry()
+	reg.Import([]core.DiscoveryRecord{
+		{Profile: core.AgentProfile{AgentID: "old"}, ExpiresAt: 1},
+		{Profile: core.AgentProfile{AgentID: "forever"}},
+	})
+	if all := reg.All(); len(all) != 1 || all[0].AgentID != "forever" {
+		t.Errorf("got %+v", all)
+	}
+}
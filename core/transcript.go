@@ -0,0 +1,243 @@
+package core
+
+// transcript.go — Verifiable negotiation transcripts for dispute resolution.
+//
+// A Transcript records every message of one negotiation (intent, responses,
+// counter-offers, receipts) in wire-encoded form, hash-chained in the order
+// they were observed.  The party exporting it seals the chain with its DID
+// key.  An auditor who receives the JSON bundle can check, without talking to
+// either agent:
+//
+//   - every party's public key hashes to the DID it claims,
+//   - each entry's hash links to the previous one (nothing inserted/removed),
+//   - each signed message verifies against its sender's key, and
+//   - the exporter's signature covers the final chain hash.
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+)
+
+// TranscriptParty binds a DID to the public key used to verify its messages.
+type TranscriptParty struct {
+	DID       string `json:"did"`
+	PublicKey []byte `json:"public_key"`
+}
+
+// TranscriptEntry is one recorded message.
+type TranscriptEntry struct {
+	MsgType    MessageType `json:"msg_type"`
+	Message    []byte      `json:"message"`     // Wire-encoded message (see Encode)
+	RecordedAt int64       `json:"recorded_at"` // Unix nanoseconds, recorder's clock
+	Hash       []byte      `json:"hash"`        // sha256(prev hash || type || recorded_at || message)
+}
+
+// Transcript is an exportable, sealed record of one negotiation.
+type Transcript struct {
+	ID          string            `json:"id"` // Usually the intent ID
+	Parties     []TranscriptParty `json:"parties"`
+	Entries     []TranscriptEntry `json:"entries"`
+	ExporterDID string            `json:"exporter_did,omitempty"`
+	Signature   []byte            `json:"signature,omitempty"` // Exporter's signature of Head()
+}
+
+// NewTranscript starts an empty transcript for the negotiation id.
+func NewTranscript(id string) *Transcript {
+	return &Transcript{ID: id}
+}
+
+// AddParty registers a participant's public key after checking that it
+// hashes to did.  Adding the same DID twice is a no-op.
+func (t *Transcript) AddParty(did string, pubKey []byte) error {
+	d, err := ParseDID(did)
+	if err != nil {
+		return fmt.Errorf("transcript: %w", err)
+	}
+	if !d.ValidateBinding(pubKey) {
+		return fmt.Errorf("transcript: public key does not match %s", did)
+	}
+	if t.party(did) != nil {
+		return nil
+	}
+	t.Parties = append(t.Parties, TranscriptParty{DID: did, PublicKey: append([]byte(nil), pubKey...)})
+	return nil
+}
+
+// Record appends msg to the chain.  Recording after Seal invalidates the seal.
+func (t *Transcript) Record(msg Encoder) error {
+	data, err := msg.Encode()
+	if err != nil {
+		return fmt.Errorf("transcript: encode: %w", err)
+	}
+	e := TranscriptEntry{MsgType: msg.MsgType(), Message: data, RecordedAt: now()}
+	e.Hash = entryHash(t.Head(), e)
+	t.Entries = append(t.Entries, e)
+	t.Signature = nil
+	return nil
+}
+
+// Head returns the hash of the last entry, or nil for an empty transcript.
+func (t *Transcript) Head() []byte {
+	if len(t.Entries) == 0 {
+		return nil
+	}
+	return t.Entries[len(t.Entries)-1].Hash
+}
+
+// Seal signs the transcript head with the exporter's DID key.  The exporter
+// is added as a party so that Verify can check the seal.
+func (t *Transcript) Seal(exporter *Agent) error {
+	if err := t.AddParty(exporter.DID.String(), exporter.PublicKey()); err != nil {
+		return err
+	}
+	sig, err := exporter.Sign(t.sealData())
+	if err != nil {
+		return fmt.Errorf("transcript: seal: %w", err)
+	}
+	t.ExporterDID = exporter.DID.String()
+	t.Signature = sig
+	return nil
+}
+
+// Verify checks party bindings, the hash chain, every known message
+// signature, and the exporter's seal.  Messages in a dispute bundle must be
+// signed: unlike VerifyIntentSignature, an empty signature is an error.
+func (t *Transcript) Verify() error {
+	for _, p := range t.Parties {
+		d, err := ParseDID(p.DID)
+		if err != nil || !d.ValidateBinding(p.PublicKey) {
+			return fmt.Errorf("transcript: party %s has a mismatched key", p.DID)
+		}
+	}
+
+	var prev []byte
+	for i, e := range t.Entries {
+		if !bytes.Equal(entryHash(prev, e), e.Hash) {
+			return fmt.Errorf("transcript: entry %d: hash chain broken", i)
+		}
+		if err := t.verifyEntry(e); err != nil {
+			return fmt.Errorf("transcript: entry %d: %w", i, err)
+		}
+		prev = e.Hash
+	}
+
+	if len(t.Signature) == 0 {
+		return fmt.Errorf("transcript: not sealed")
+	}
+	exp := t.party(t.ExporterDID)
+	if exp == nil {
+		return fmt.Errorf("transcript: exporter %s is not a party", t.ExporterDID)
+	}
+	d, err := DIDFromPublicKey(exp.PublicKey)
+	if err != nil {
+		return fmt.Errorf("transcript: exporter key: %w", err)
+	}
+	if !d.Verify(t.sealData(), t.Signature) {
+		return fmt.Errorf("transcript: exporter: %w", ErrSignatureInvalid)
+	}
+	return nil
+}
+
+// Export serialises the transcript as a self-contained JSON bundle.
+func (t *Transcript) Export() ([]byte, error) {
+	return json.MarshalIndent(t, "", "  ")
+}
+
+// ImportTranscript parses a bundle produced by Export.  Call Verify on the
+// result before trusting any of its contents.
+func ImportTranscript(data []byte) (*Transcript, error) {
+	var t Transcript
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("transcript: parse: %w", err)
+	}
+	return &t, nil
+}
+
+// Messages decodes every entry, in order, using Decode.
+func (t *Transcript) Messages() ([]interface{}, error) {
+	out := make([]interface{}, 0, len(t.Entries))
+	for i, e := range t.Entries {
+		m, err := Decode(e.MsgType, e.Message)
+		if err != nil {
+			return nil, fmt.Errorf("transcript: entry %d: %w", i, err)
+		}
+		out = append(out, m)
+	}
+	return out, nil
+}
+
+// ------------------------------------------------------------------ helpers
+
+func (t *Transcript) party(did string) *TranscriptParty {
+	for i := range t.Parties {
+		if t.Parties[i].DID == did {
+			return &t.Parties[i]
+		}
+	}
+	return nil
+}
+
+// verifyEntry checks the sender signature of message types that carry one.
+// Other types are protected by the hash chain and the exporter's seal only.
+func (t *Transcript) verifyEntry(e TranscriptEntry) error {
+	var sender string
+	var ok func(pub []byte) bool
+	switch e.MsgType {
+	case MsgIntent:
+		m, err := DecodeIntentMessage(e.Message)
+		if err != nil {
+			return err
+		}
+		if len(m.Signature) == 0 {
+			return fmt.Errorf("intent %s is unsigned", m.ID)
+		}
+		sender, ok = m.DID, func(pub []byte) bool { return VerifyIntentSignature(m, pub) }
+	case MsgNegotiation:
+		m, err := DecodeNegotiationResponse(e.Message)
+		if err != nil {
+			return err
+		}
+		if len(m.Signature) == 0 {
+			return fmt.Errorf("response to %s is unsigned", m.RequestID)
+		}
+		sender, ok = m.DID, func(pub []byte) bool { return VerifyResponseSignature(m, pub) }
+	case MsgCounter:
+		m, err := DecodeCounterOffer(e.Message)
+		if err != nil {
+			return err
+		}
+		if len(m.Signature) == 0 {
+			return fmt.Errorf("counter-offer %d for %s is unsigned", m.Round, m.RequestID)
+		}
+		sender, ok = m.DID, func(pub []byte) bool { return VerifyCounterOfferSignature(m, pub) }
+	default:
+		return nil
+	}
+	p := t.party(sender)
+	if p == nil {
+		return fmt.Errorf("sender %s is not a party", sender)
+	}
+	if !ok(p.PublicKey) {
+		return fmt.Errorf("%w by %s", ErrSignatureInvalid, sender)
+	}
+	return nil
+}
+
+// sealData is what the exporter signs: the transcript ID and the chain head.
+func (t *Transcript) sealData() []byte {
+	return append([]byte(t.ID), t.Head()...)
+}
+
+func entryHash(prev []byte, e TranscriptEntry) []byte {
+	h := sha256.New()
+	h.Write(prev)
+	h.Write([]byte{byte(e.MsgType)})
+	var ts [8]byte
+	binary.BigEndian.PutUint64(ts[:], uint64(e.RecordedAt))
+	h.Write(ts[:])
+	h.Write(e.Message)
+	return h.Sum(nil)
+}
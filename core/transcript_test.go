@@ -0,0 +1,84 @@
+package core_test
+
+import (
+	"testing"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func buildTranscript(t *testing.T) (*core.Transcript, *core.Agent) {
+	t.Helper()
+	requester, _ := core.NewAgent("req", nil)
+	responder, _ := core.NewAgent("resp", []string{"code-gen"})
+
+	intent, err := core.CreateIntent(requester, []float32{0.3}, []string{"code-gen"}, "write a parser")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, _ := core.DefaultNegotiationHandler(responder)(intent)
+
+	tr := core.NewTranscript(intent.ID)
+	if err := tr.AddParty(requester.DID.String(), requester.PublicKey()); err != nil {
+		t.Fatalf("AddParty: %v", err)
+	}
+	if err := tr.AddParty(responder.DID.String(), responder.PublicKey()); err != nil {
+		t.Fatalf("AddParty: %v", err)
+	}
+	if err := tr.Record(intent); err != nil {
+		t.Fatalf("Record intent: %v", err)
+	}
+	if err := tr.Record(resp); err != nil {
+		t.Fatalf("Record response: %v", err)
+	}
+	if err := tr.Seal(requester); err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	return tr, requester
+}
+
+func TestTranscriptExportVerify(t *testing.T) {
+	tr, _ := buildTranscript(t)
+
+	bundle, err := tr.Export()
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	imported, err := core.ImportTranscript(bundle)
+	if err != nil {
+		t.Fatalf("ImportTranscript: %v", err)
+	}
+	if err := imported.Verify(); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	msgs, err := imported.Messages()
+	if err != nil {
+		t.Fatalf("Messages: %v", err)
+	}
+	if len(msgs) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(msgs))
+	}
+	if _, ok := msgs[1].(*core.NegotiationResponse); !ok {
+		t.Errorf("entry 1: expected *NegotiationResponse, got %T", msgs[1])
+	}
+}
+
+func TestTranscriptDetectsTampering(t *testing.T) {
+	tr, _ := buildTranscript(t)
+	tr.Entries = tr.Entries[1:] // drop the intent
+	if err := tr.Verify(); err == nil {
+		t.Error("expected Verify to fail after removing an entry")
+	}
+
+	tr2, _ := buildTranscript(t)
+	tr2.Entries[0].Message[len(tr2.Entries[0].Message)-1] ^= 0xFF
+	if err := tr2.Verify(); err == nil {
+		t.Error("expected Verify to fail after modifying a message")
+	}
+}
+
+func TestTranscriptUnsealed(t *testing.T) {
+	tr := core.NewTranscript("x")
+	if err := tr.Verify(); err == nil {
+		t.Error("expected Verify to fail for an unsealed transcript")
+	}
+}
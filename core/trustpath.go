@@ -0,0 +1,67 @@
+package core
+
+// trustpath.go — Transitive trust.
+//
+// A TrustGraph only records the trust an agent has formed in the peers it
+// has dealt with directly.  ComputeTransitiveTrust extends that to peers it
+// has never met, through intermediaries: if A trusts B 0.8 and B trusts C
+// 0.5, A trusts C 0.4 through B.  Each hop multiplies by the next edge, so
+// trust decays with every intermediary and a path is never stronger than its
+// weakest edge; of all paths within maxHops the strongest one counts.
+
+// DefaultTrustHops is the path length ComputeTransitiveTrust callers use
+// when they have no better bound.
+const DefaultTrustHops = 3
+
+// ComputeTransitiveTrust returns the trust `from` places in `to` along the
+// strongest path of at most maxHops edges, where a path's trust is the
+// product of its edge scores.  A direct edge is a one-hop path.  It returns
+// 1 when from and to are the same agent and 0 when no path exists.
+func (tg *TrustGraph) ComputeTransitiveTrust(from, to string, maxHops int) float32 {
+	if from == to {
+		return 1
+	}
+	if maxHops < 1 {
+		return 0
+	}
+	tg.mu.RLock()
+	edges := make(map[string]map[string]float32)
+	for k, v := range tg.scores {
+		f, t, ok := SplitTrustKey(k)
+		if !ok || v <= 0 {
+			continue
+		}
+		if edges[f] == nil {
+			edges[f] = make(map[string]float32)
+		}
+		edges[f][t] = v
+	}
+	tg.mu.RUnlock()
+
+	// best[n] is the strongest path to n found so far; frontier holds the
+	// nodes whose best path grew in the last round, so only they can
+	// improve others in the next.
+	best := map[string]float32{from: 1}
+	frontier := map[string]float32{from: 1}
+	for hop := 0; hop < maxHops && len(frontier) > 0; hop++ {
+		next := make(map[string]float32)
+		for n, score := range frontier {
+			for t, w := range edges[n] {
+				if s := score * w; s > best[t] {
+					best[t] = s
+					next[t] = s
+				}
+			}
+		}
+		frontier = next
+	}
+	return best[to]
+}
+
+// hasEdge reports whether from has a direct trust score for to.
+func (tg *TrustGraph) hasEdge(from, to string) bool {
+	tg.mu.RLock()
+	defer tg.mu.RUnlock()
+	_, ok := tg.scores[trustKey(from, to)]
+	return ok
+}
@@ -0,0 +1,78 @@
+package core_test
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func TestComputeTransitiveTrust(t *testing.T) {
+	tg := core.NewTrustGraph()
+	_ = tg.Set("a", "b", 0.8)
+	_ = tg.Set("b", "c", 0.5)
+	_ = tg.Set("a", "d", 0.9)
+	_ = tg.Set("d", "c", 0.9)
+	_ = tg.Set("c", "e", 1)
+	_ = tg.Set("a", "f", 0.1)
+
+	cases := []struct {
+		from, to string
+		hops     int
+		want     float32
+	}{
+		{"a", "a", 0, 1},
+		{"a", "b", 1, 0.8},
+		{"a", "c", 1, 0},    // No direct edge
+		{"a", "c", 2, 0.81}, // a->d->c beats a->b->c (0.4)
+		{"a", "e", 2, 0},    // Needs three hops
+		{"a", "e", 3, 0.81},
+		{"c", "a", 5, 0}, // Edges are directed
+		{"a", "f", 0, 0},
+	}
+	for _, c := range cases {
+		if got := tg.ComputeTransitiveTrust(c.from, c.to, c.hops); math.Abs(float64(got-c.want)) > 1e-6 {
+			t.Errorf("%s->%s within %d hops: got %v, want %v", c.from, c.to, c.hops, got, c.want)
+		}
+	}
+
+	// A longer path wins when it is stronger than the direct edge.
+	_ = tg.Set("a", "c", 0.3)
+	if got := tg.ComputeTransitiveTrust("a", "c", 2); math.Abs(float64(got-0.81)) > 1e-6 {
+		t.Errorf("with weak direct edge: got %v", got)
+	}
+}
+
+func TestNegotiationRequiresTransitiveTrust(t *testing.T) {
+	self, _ := core.NewAgent("worker", []string{"ocr"})
+	friend, _ := core.NewAgent("friend", nil)
+	vouched, _ := core.NewAgent("vouched", nil)
+	stranger, _ := core.NewAgent("stranger", nil)
+
+	tg := core.NewTrustGraph()
+	_ = tg.Set(self.DID.String(), friend.DID.String(), 0.8)
+	_ = tg.Set(friend.DID.String(), vouched.DID.String(), 0.5)
+	h := core.DefaultNegotiationHandlerWithOptions(self, core.NegotiationOptions{Trust: tg, MinTrust: 0.3})
+
+	for _, c := range []struct {
+		sender *core.Agent
+		want   bool
+	}{
+		{friend, true},    // Direct edge: not subject to the threshold
+		{vouched, true},   // 0.8 * 0.5 = 0.4
+		{stranger, false}, // No path
+	} {
+		intent, _ := core.CreateIntent(c.sender, nil, []string{"ocr"}, "scan")
+		resp, err := h(intent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Accepted != c.want {
+			t.Errorf("%s: accepted=%t (%s)", c.sender.ID, resp.Accepted, resp.Reason)
+		}
+		if !c.want && !strings.Contains(resp.Reason, "insufficient transitive trust: 0.00 below 0.30") {
+			t.Errorf("%s: reason %q", c.sender.ID, resp.Reason)
+		}
+	}
+}
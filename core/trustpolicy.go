@@ -0,0 +1,168 @@
+package core
+
+// trustpolicy.go — Trust delta functions.
+//
+// Every exchange moves the trust the two agents place in each other.  How
+// far is a TrustPolicy's call: it sees whether the intent was accepted,
+// how long the answer took, whether the price charged kept to the price
+// agreed, and which capability was asked for.  TrustPolicies picks a policy
+// per capability, so a mesh can forgive a slow batch job while holding a
+// low-latency lookup to account.
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// TrustOutcome describes one exchange for a TrustPolicy.
+type TrustOutcome struct {
+	Capability string        // The intent's first capability
+	Accepted   bool          // Whether the intent was accepted
+	Latency    time.Duration // Time to the answer; zero if unknown
+	CostRatio  float64       // Price charged over price agreed; zero if unknown
+	Suggested  float32       // NegotiationResponse.TrustDelta, when judging a response
+}
+
+// TrustPolicy turns the outcome of an exchange into a trust delta.
+type TrustPolicy interface {
+	TrustDelta(o TrustOutcome) float32
+}
+
+// TrustPolicyFunc adapts a function to TrustPolicy.
+type TrustPolicyFunc func(o TrustOutcome) float32
+
+// TrustDelta calls f(o).
+func (f TrustPolicyFunc) TrustDelta(o TrustOutcome) float32 { return f(o) }
+
+// StepTrustPolicy rewards every accepted intent and penalises every
+// refusal by fixed amounts.
+type StepTrustPolicy struct {
+	Reward  float32
+	Penalty float32 // Subtracted; positive
+}
+
+// TrustDelta returns Reward or -Penalty.
+func (p StepTrustPolicy) TrustDelta(o TrustOutcome) float32 {
+	if o.Accepted {
+		return p.Reward
+	}
+	return -p.Penalty
+}
+
+// WeightedTrustPolicy scales the reward for an accepted intent down
+// for answers slower than TargetLatency and for charges above the agreed
+// price: twice the target latency, or twice the price, halves it.
+type WeightedTrustPolicy struct {
+	Reward        float32
+	Penalty       float32       // Subtracted for a refusal; positive
+	TargetLatency time.Duration // Zero ignores latency
+}
+
+// TrustDelta returns the weighted reward, or -Penalty.
+func (p WeightedTrustPolicy) TrustDelta(o TrustOutcome) float32 {
+	if !o.Accepted {
+		return -p.Penalty
+	}
+	w := 1.0
+	if p.TargetLatency > 0 && o.Latency > p.TargetLatency {
+		w *= float64(p.TargetLatency) / float64(o.Latency)
+	}
+	if o.CostRatio > 1 {
+		w /= o.CostRatio
+	}
+	return p.Reward * float32(w)
+}
+
+// Built-in trust policies.
+var (
+	// DefaultTrustPolicy is +0.05 per accepted intent and −0.02 per refusal.
+	DefaultTrustPolicy TrustPolicy = StepTrustPolicy{Reward: 0.05, Penalty: 0.02}
+	// ConservativeTrustPolicy earns trust slowly and loses it quickly.
+	ConservativeTrustPolicy TrustPolicy = StepTrustPolicy{Reward: 0.01, Penalty: 0.05}
+	// AggressiveTrustPolicy earns trust quickly and forgives refusals.
+	AggressiveTrustPolicy TrustPolicy = StepTrustPolicy{Reward: 0.1, Penalty: 0.01}
+	// OutcomeWeightedTrustPolicy is DefaultTrustPolicy with the reward
+	// weighted for answers slower than a second and for overcharging.
+	OutcomeWeightedTrustPolicy TrustPolicy = WeightedTrustPolicy{Reward: 0.05, Penalty: 0.02, TargetLatency: time.Second}
+)
+
+var trustPolicies = map[string]TrustPolicy{
+	"default":          DefaultTrustPolicy,
+	"conservative":     ConservativeTrustPolicy,
+	"aggressive":       AggressiveTrustPolicy,
+	"outcome-weighted": OutcomeWeightedTrustPolicy,
+}
+
+// TrustPolicyByName returns the built-in policy called name: "default",
+// "conservative", "aggressive" or "outcome-weighted".
+func TrustPolicyByName(name string) (TrustPolicy, error) {
+	p, ok := trustPolicies[name]
+	if !ok {
+		return nil, fmt.Errorf("trust policy: unknown %q (want one of %v)", name, TrustPolicyNames())
+	}
+	return p, nil
+}
+
+// TrustPolicyNames lists the built-in policies, sorted.
+func TrustPolicyNames() []string {
+	names := make([]string, 0, len(trustPolicies))
+	for name := range trustPolicies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// TrustPolicies selects a TrustPolicy by capability.  It is itself a
+// TrustPolicy that dispatches on TrustOutcome.Capability.
+type TrustPolicies struct {
+	Default      TrustPolicy            // For capabilities not in ByCapability; nil for DefaultTrustPolicy
+	ByCapability map[string]TrustPolicy // Keyed by capability name
+}
+
+// Lookup returns the policy configured for capability, falling back to
+// Default.  ok is false when neither is set.
+func (p *TrustPolicies) Lookup(capability string) (policy TrustPolicy, ok bool) {
+	if p == nil {
+		return nil, false
+	}
+	if policy, ok = p.ByCapability[capability]; ok {
+		return policy, true
+	}
+	return p.Default, p.Default != nil
+}
+
+// For returns the policy for capability; DefaultTrustPolicy if none is
+// configured.
+func (p *TrustPolicies) For(capability string) TrustPolicy {
+	if policy, ok := p.Lookup(capability); ok {
+		return policy
+	}
+	return DefaultTrustPolicy
+}
+
+// TrustDelta applies the policy for o.Capability.
+func (p *TrustPolicies) TrustDelta(o TrustOutcome) float32 {
+	return p.For(o.Capability).TrustDelta(o)
+}
+
+// intentCapability returns the capability a TrustOutcome for intent is
+// filed under: its first one, or "" if it asks for none.
+func intentCapability(intent *IntentMessage) string {
+	if len(intent.Capabilities) == 0 {
+		return ""
+	}
+	return intent.Capabilities[0]
+}
+
+// OutcomeOf describes the answer resp to intent, received after latency,
+// for a TrustPolicy.
+func OutcomeOf(intent *IntentMessage, resp *NegotiationResponse, latency time.Duration) TrustOutcome {
+	return TrustOutcome{
+		Capability: intentCapability(intent),
+		Accepted:   resp.Accepted,
+		Latency:    latency,
+		Suggested:  resp.TrustDelta,
+	}
+}
@@ -0,0 +1,72 @@
+package core_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func TestTrustPolicies(t *testing.T) {
+	accepted := core.TrustOutcome{Capability: "nlp", Accepted: true}
+	refused := core.TrustOutcome{Capability: "nlp"}
+	if got := core.DefaultTrustPolicy.TrustDelta(accepted); got != 0.05 {
+		t.Errorf("default accepted = %v, want 0.05", got)
+	}
+	if got := core.DefaultTrustPolicy.TrustDelta(refused); got != -0.02 {
+		t.Errorf("default refused = %v, want -0.02", got)
+	}
+
+	w := core.WeightedTrustPolicy{Reward: 0.1, Penalty: 0.05, TargetLatency: time.Second}
+	for _, tc := range []struct {
+		latency time.Duration
+		cost    float64
+		want    float32
+	}{
+		{500 * time.Millisecond, 0, 0.1},
+		{2 * time.Second, 0, 0.05},
+		{time.Second, 2, 0.05},
+		{4 * time.Second, 0.5, 0.025},
+	} {
+		o := core.TrustOutcome{Accepted: true, Latency: tc.latency, CostRatio: tc.cost}
+		if got := w.TrustDelta(o); got < tc.want-1e-6 || got > tc.want+1e-6 {
+			t.Errorf("weighted(%v, %v) = %v, want %v", tc.latency, tc.cost, got, tc.want)
+		}
+	}
+	if got := w.TrustDelta(refused); got != -0.05 {
+		t.Errorf("weighted refused = %v, want -0.05", got)
+	}
+
+	ps := &core.TrustPolicies{ByCapability: map[string]core.TrustPolicy{"nlp": core.AggressiveTrustPolicy}}
+	if _, ok := ps.Lookup("vision"); ok {
+		t.Error("Lookup found a policy for an unconfigured capability")
+	}
+	if got := ps.TrustDelta(accepted); got != 0.1 {
+		t.Errorf("nlp accepted = %v, want 0.1", got)
+	}
+	if got := ps.TrustDelta(core.TrustOutcome{Capability: "vision", Accepted: true}); got != 0.05 {
+		t.Errorf("vision accepted = %v, want the default 0.05", got)
+	}
+	ps.Default = core.ConservativeTrustPolicy
+	if got := ps.TrustDelta(core.TrustOutcome{Capability: "vision"}); got != -0.05 {
+		t.Errorf("vision refused = %v, want -0.05", got)
+	}
+
+	for _, name := range core.TrustPolicyNames() {
+		if _, err := core.TrustPolicyByName(name); err != nil {
+			t.Errorf("TrustPolicyByName(%q): %v", name, err)
+		}
+	}
+	if _, err := core.TrustPolicyByName("lenient"); err == nil {
+		t.Error("TrustPolicyByName accepted an unknown name")
+	}
+
+	// The default handler suggests its policy's delta.
+	agent, _ := core.NewAgent("a", []string{"nlp"})
+	sender, _ := core.NewAgent("b", nil)
+	intent, _ := core.CreateIntent(sender, nil, []string{"nlp"}, "")
+	h := core.DefaultNegotiationHandlerWithOptions(agent, core.NegotiationOptions{TrustPolicy: core.AggressiveTrustPolicy})
+	if resp, _ := h(intent); resp.TrustDelta != 0.1 {
+		t.Errorf("handler TrustDelta = %v, want 0.1", resp.TrustDelta)
+	}
+}
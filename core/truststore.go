@@ -0,0 +1,139 @@
+package core
+
+// truststore.go — Persistent backends for TrustGraph.
+//
+// A TrustStore is the durable half of a TrustGraph: the graph keeps scores in
+// memory for fast lookups and writes every change through to the store.  On
+// restart, NewTrustGraphFromStore reloads the previous state from
+// Snapshot().  The JSON file store below needs nothing beyond the standard
+// library; a BoltDB-backed store lives in the store package.
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// TrustStore persists directed trust scores between DIDs.
+// Implementations must be concurrency-safe.
+type TrustStore interface {
+	// Get returns the stored score that from assigns to to, or 0 if none.
+	Get(from, to string) (float32, error)
+	// Set stores score (already clamped to [0,1]) for the from→to edge.
+	Set(from, to string, score float32) error
+	// Apply adds delta to the stored score, clamps it, and returns the result.
+	Apply(from, to string, delta float32) (float32, error)
+	// Snapshot returns every stored score keyed by "<from>-><to>".
+	Snapshot() (map[string]float32, error)
+}
+
+// FileTrustStore is a TrustStore backed by a single JSON file.
+// The whole file is rewritten atomically on every change, which is fine for
+// the few hundred edges a typical agent accumulates.
+type FileTrustStore struct {
+	mu     sync.Mutex
+	path   string
+	scores map[string]float32
+}
+
+// trustFileEntry is the on-disk representation of one trust edge.
+type trustFileEntry struct {
+	From  string  `json:"from"`
+	To    string  `json:"to"`
+	Score float32 `json:"score"`
+}
+
+// OpenFileTrustStore opens (or creates on first write) the JSON trust file at path.
+func OpenFileTrustStore(path string) (*FileTrustStore, error) {
+	s := &FileTrustStore{path: path, scores: make(map[string]float32)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("truststore: read %s: %w", path, err)
+	}
+	var entries []trustFileEntry
+	if err = json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("truststore: parse %s: %w", path, err)
+	}
+	for _, e := range entries {
+		s.scores[trustKey(e.From, e.To)] = clamp(e.Score)
+	}
+	return s, nil
+}
+
+// Get implements TrustStore.
+func (s *FileTrustStore) Get(from, to string) (float32, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.scores[trustKey(from, to)], nil
+}
+
+// Set implements TrustStore.
+func (s *FileTrustStore) Set(from, to string, score float32) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scores[trustKey(from, to)] = clamp(score)
+	return s.flush()
+}
+
+// Apply implements TrustStore.
+func (s *FileTrustStore) Apply(from, to string, delta float32) (float32, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := trustKey(from, to)
+	s.scores[k] = clamp(s.scores[k] + delta)
+	return s.scores[k], s.flush()
+}
+
+// Snapshot implements TrustStore.
+func (s *FileTrustStore) Snapshot() (map[string]float32, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[string]float32, len(s.scores))
+	for k, v := range s.scores {
+		out[k] = v
+	}
+	return out, nil
+}
+
+// flush writes the current scores to a temp file and renames it into place.
+// Caller must hold s.mu.
+func (s *FileTrustStore) flush() error {
+	entries := make([]trustFileEntry, 0, len(s.scores))
+	for k, v := range s.scores {
+		from, to, _ := SplitTrustKey(k)
+		entries = append(entries, trustFileEntry{From: from, To: to, Score: v})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].From != entries[j].From {
+			return entries[i].From < entries[j].From
+		}
+		return entries[i].To < entries[j].To
+	})
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("truststore: encode: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("truststore: create temp: %w", err)
+	}
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("truststore: write: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("truststore: close: %w", err)
+	}
+	if err = os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("truststore: rename: %w", err)
+	}
+	return nil
+}
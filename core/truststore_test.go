@@ -0,0 +1,66 @@
+package core_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func TestFileTrustStoreReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trust.json")
+
+	store, err := core.OpenFileTrustStore(path)
+	if err != nil {
+		t.Fatalf("OpenFileTrustStore: %v", err)
+	}
+	tg, err := core.NewTrustGraphFromStore(store)
+	if err != nil {
+		t.Fatalf("NewTrustGraphFromStore: %v", err)
+	}
+	if err := tg.Set("did:a", "did:b", 0.5); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if err := tg.Apply("did:a", "did:b", 0.05); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if err := tg.Apply("did:a", "did:c", -0.3); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+
+	// Simulate a restart by reopening the same file.
+	reopened, err := core.OpenFileTrustStore(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	tg2, err := core.NewTrustGraphFromStore(reopened)
+	if err != nil {
+		t.Fatalf("NewTrustGraphFromStore: %v", err)
+	}
+	if got := tg2.Get("did:a", "did:b"); got < 0.549 || got > 0.551 {
+		t.Errorf("a->b after reload: got %v want 0.55", got)
+	}
+	if got := tg2.Get("did:a", "did:c"); got != 0 {
+		t.Errorf("a->c should be clamped to 0, got %v", got)
+	}
+	if n := len(tg2.Snapshot()); n != 2 {
+		t.Errorf("Snapshot: got %d entries want 2", n)
+	}
+}
+
+func TestFileTrustStoreApply(t *testing.T) {
+	store, err := core.OpenFileTrustStore(filepath.Join(t.TempDir(), "trust.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := store.Apply("did:a", "did:b", 1.5)
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if got != 1 {
+		t.Errorf("Apply should clamp to 1, got %v", got)
+	}
+	if v, _ := store.Get("did:a", "did:b"); v != 1 {
+		t.Errorf("Get: got %v want 1", v)
+	}
+}
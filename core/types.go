@@ -2,21 +2,37 @@
 // for the Agent Semantic Protocol semantic agent communication protocol.
 package core
 
-import "time"
+import (
+	"io"
+	"time"
+)
 
 // MessageType identifies the kind of a framed Agent Semantic Protocol message.
 type MessageType byte
 
 const (
-	MsgHandshake   MessageType = 0x01
-	MsgIntent      MessageType = 0x02
-	MsgNegotiation MessageType = 0x03
-	MsgWorkflow    MessageType = 0x04
-	MsgCapability  MessageType = 0x05
+	MsgHandshake      MessageType = 0x01
+	MsgIntent         MessageType = 0x02
+	MsgNegotiation    MessageType = 0x03
+	MsgWorkflow       MessageType = 0x04
+	MsgCapability     MessageType = 0x05
+	MsgCounter        MessageType = 0x06
+	MsgAlert          MessageType = 0x07
+	MsgRevocation     MessageType = 0x08
+	MsgAttestation    MessageType = 0x09
+	MsgForward        MessageType = 0x0A
+	MsgIntentBatch    MessageType = 0x0B
+	MsgResponseBatch  MessageType = 0x0C
+	MsgPing           MessageType = 0x0D
+	MsgPong           MessageType = 0x0E
+	MsgWorkflowResult MessageType = 0x0F
+	MsgKeyRotation    MessageType = 0x10
+	MsgObserve        MessageType = 0x11
+	MsgEvent          MessageType = 0x12
 )
 
 // ProtocolVersion is the current Agent Semantic Protocol wire-protocol version.
-const ProtocolVersion = "1.0.0"
+const ProtocolVersion = "1.2.0"
 
 // Encoder is implemented by every Agent Semantic Protocol message type.
 type Encoder interface {
@@ -30,23 +46,18 @@ type Agent struct {
 	ID           string
 	DID          *DID
 	Capabilities []string
+	Manifest     []CapabilityDescriptor // Optional descriptors advertised in handshakes
+	Specs        []CapabilitySpec       // Optional contracts advertised in handshakes and announcements
+	IntentIDs    *IntentIDGenerator     // Optional; CreateIntent uses random IDs without it
+	Credentials  []VerifiableCredential // Optional claims about the agent, advertised in handshakes and announcements
+	Entropy      io.Reader              // Optional random source for IDs and nonces; crypto/rand if nil (see entropy.go)
 	pubKey       []byte
 	privKey      []byte
 }
 
 // NewAgent creates an Agent, generating a fresh Ed25519 key-pair and DID.
 func NewAgent(id string, capabilities []string) (*Agent, error) {
-	d, err := NewDID()
-	if err != nil {
-		return nil, err
-	}
-	return &Agent{
-		ID:           id,
-		DID:          d,
-		Capabilities: capabilities,
-		pubKey:       d.pubKey,
-		privKey:      d.privKey,
-	}, nil
+	return NewAgentWithEntropy(id, capabilities, nil)
 }
 
 // PublicKey returns the raw Ed25519 public key bytes.
@@ -65,75 +76,384 @@ func (a *Agent) Sign(data []byte) ([]byte, error) {
 
 // IntentMessage carries a semantic intent between agents.
 type IntentMessage struct {
-	ID           string
-	IntentVector []float32         // Semantic embedding (e.g. 384-dim sentence-transformer)
-	Capabilities []string          // Capabilities required to fulfil this intent
-	DID          string            // Sender DID string ("did:agent-semantic-protocol:<id>")
-	Payload      string            // Optional payload (plain text or JSON)
-	Timestamp    int64             // Unix nanoseconds
-	TrustScore   float32           // Sender trust score [0.0, 1.0]
-	Metadata     map[string]string // Arbitrary extension metadata
-	Signature    []byte            // Ed25519 signature of ID+Payload by sender DID key
-	Logger       *Logger            // Logger instance for auditable logs
+	ID           string            `json:"id,omitempty"`
+	IntentVector []float32         `json:"intent_vector,omitempty"`     // Semantic embedding (e.g. 384-dim sentence-transformer)
+	Capabilities []string          `json:"capabilities,omitempty"`      // Capabilities required to fulfil this intent
+	DID          string            `json:"did,omitempty"`               // Sender DID string ("did:agent-semantic-protocol:<id>")
+	Payload      string            `json:"payload,omitempty"`           // Optional payload (plain text or JSON)
+	Timestamp    int64             `json:"timestamp,string,omitempty"`  // Unix nanoseconds
+	TrustScore   float32           `json:"trust_score,omitempty"`       // Sender trust score [0.0, 1.0]
+	Metadata     map[string]string `json:"metadata,omitempty"`          // Arbitrary extension metadata
+	Signature    []byte            `json:"signature,omitempty"`         // Ed25519 signature of every other field by sender DID key
+	ExpiresAt    int64             `json:"expires_at,string,omitempty"` // Unix nanoseconds; 0 = never expires
+
+	// EncryptedPayload replaces Payload when the sender sealed it for one
+	// recipient (see e2e.go).
+	EncryptedPayload *EncryptedPayload `json:"encrypted_payload,omitempty"`
+
+	// Budget bounds what the sender will accept serving the intent to
+	// cost (see cost.go).
+	Budget *Budget `json:"budget,omitempty"`
+
+	// Deadline is when the sender needs the intent served by, in Unix
+	// nanoseconds, and MaxLatency how long it will wait for an answer, in
+	// nanoseconds (see deadline.go).  Zero means no bound.
+	Deadline   int64 `json:"deadline,string,omitempty"`
+	MaxLatency int64 `json:"max_latency,string,omitempty"`
+}
+
+// EncryptedPayload is an intent payload sealed for one recipient DID.
+type EncryptedPayload struct {
+	RecipientDID string `json:"recipient_did"`
+	EphemeralKey []byte `json:"ephemeral_key"` // Sender's one-time X25519 public key
+	Nonce        []byte `json:"nonce"`         // AES-GCM nonce
+	Ciphertext   []byte `json:"ciphertext"`    // AES-256-GCM ciphertext and tag
 }
 
 func (m *IntentMessage) MsgType() MessageType { return MsgIntent }
 
 // HandshakeMessage establishes agent identity and exchanges capabilities.
 type HandshakeMessage struct {
-	AgentID           string
-	DID               string
-	Capabilities      []string
-	Version           string
-	Timestamp         int64
-	PublicKey         []byte // Ed25519 public key
-	Challenge         []byte // Random nonce sent to peer
-	ChallengeResponse []byte // Signature of peer's challenge with own private key
+	AgentID           string                 `json:"agent_id,omitempty"`
+	DID               string                 `json:"did,omitempty"`
+	Capabilities      []string               `json:"capabilities,omitempty"`
+	Version           string                 `json:"version,omitempty"`
+	Timestamp         int64                  `json:"timestamp,string,omitempty"`
+	PublicKey         []byte                 `json:"public_key,omitempty"`         // Ed25519 public key
+	Challenge         []byte                 `json:"challenge,omitempty"`          // Random nonce sent to peer
+	ChallengeResponse []byte                 `json:"challenge_response,omitempty"` // Signature of peer's challenge with own private key
+	Manifest          []CapabilityDescriptor `json:"manifest,omitempty"`
+	EchoTimestamp     int64                  `json:"echo_timestamp,string,omitempty"`  // Responder only: the initiator's Timestamp, echoed back
+	ReceivedAt        int64                  `json:"received_at,string,omitempty"`     // Responder only: when the initiator's message arrived
+	Codecs            []string               `json:"codecs,omitempty"`                 // Initiator: codecs it accepts, preferred first; responder: the one chosen
+	Versions          []string               `json:"versions,omitempty"`               // Protocol versions the sender speaks, highest first (see version.go)
+	Mesh              string                 `json:"mesh,omitempty"`                   // Mesh the sender belongs to; empty for the default mesh (see mesh.go)
+	Specs             []CapabilitySpec       `json:"specs,omitempty"`                  // Capability contracts (see capspec.go)
+	KeyAgreement      []byte                 `json:"key_agreement,omitempty"`          // X25519 public key for sealed payloads (see e2e.go)
+	KeyAgreementSig   []byte                 `json:"key_agreement_sig,omitempty"`      // Signature of KeyAgreement by the DID key
+	Features          []string               `json:"features,omitempty"`               // Optional subsystems the sender supports (see features.go)
+	MaxResultSize     int64                  `json:"max_result_size,string,omitempty"` // Largest ResultPayload the sender accepts; zero for none (see result.go)
+	Credentials       []VerifiableCredential `json:"credentials,omitempty"`            // Issuers' claims about the sender (see credential.go)
 }
 
 func (m *HandshakeMessage) MsgType() MessageType { return MsgHandshake }
 
 // NegotiationResponse answers an IntentMessage.
 type NegotiationResponse struct {
-	RequestID      string
-	AgentID        string
-	Accepted       bool
-	WorkflowSteps  []string
-	DID            string
-	ResponseVector []float32
-	Timestamp      int64
-	Reason         string
-	TrustDelta     float32
-	Signature      []byte // Ed25519 signature of RequestID+Reason by responder DID key
+	RequestID      string    `json:"request_id,omitempty"`
+	AgentID        string    `json:"agent_id,omitempty"`
+	Accepted       bool      `json:"accepted"`
+	WorkflowSteps  []string  `json:"workflow_steps,omitempty"`
+	DID            string    `json:"did,omitempty"`
+	ResponseVector []float32 `json:"response_vector,omitempty"`
+	Timestamp      int64     `json:"timestamp,string,omitempty"`
+	Reason         string    `json:"reason,omitempty"`
+	TrustDelta     float32   `json:"trust_delta,omitempty"`
+	Signature      []byte    `json:"signature,omitempty"` // Ed25519 signature of every other field but RelayPath and ResponderKey by responder DID key
+
+	// SimilarityScore is the responder's own assessment, in [0,1], of how
+	// well the intent matches its capabilities; 0 when it made none.
+	SimilarityScore float32 `json:"similarity_score,omitempty"`
+
+	// RetryAfter, in nanoseconds, is set on rate-limited rejections (Reason
+	// ReasonRateLimited): the sender should wait this long before its next
+	// intent is accepted.
+	RetryAfter int64 `json:"retry_after,string,omitempty"`
+
+	// RelayPath lists the DIDs of the relays that carried the intent to the
+	// responder, the originator's neighbour first (see relay.go).  Relays
+	// set it on the way back; it is not signed.
+	RelayPath []string `json:"relay_path,omitempty"`
+	// ResponderKey is the responder's Ed25519 public key, set when it
+	// answers a relayed intent so the originator can verify the response
+	// without a handshake.  Like RelayPath it is not signed.
+	ResponderKey []byte `json:"responder_key,omitempty"`
+	// Result answers the intent inline, for capabilities simple enough to
+	// need no workflow (see result.go).
+	Result *ResultPayload `json:"result,omitempty"`
+	// Bid is the responder's offer when the intent is auctioned (see
+	// p2p.WorkflowOrchestrator.Auction).
+	Bid *Bid `json:"bid,omitempty"`
+	// Cost is what the responder expects serving the intent to take (see
+	// cost.go).
+	Cost *CostEstimate `json:"cost,omitempty"`
+}
+
+// Bid is what a responder offers for taking on an intent.
+type Bid struct {
+	Cost       float32 `json:"cost,omitempty"`       // Price asked
+	ETA        int64   `json:"eta,string,omitempty"` // Expected time to complete, in nanoseconds
+	Confidence float32 `json:"confidence,omitempty"` // Responder's confidence of success, in [0,1]
+}
+
+// ResultPayload is the answer to an intent carried in its
+// NegotiationResponse.
+type ResultPayload struct {
+	ContentType string `json:"content_type,omitempty"` // MIME type of Data
+	Data        []byte `json:"data,omitempty"`
 }
 
 func (m *NegotiationResponse) MsgType() MessageType { return MsgNegotiation }
 
 // WorkflowMessage carries one step of a distributed workflow.
 type WorkflowMessage struct {
-	WorkflowID string
-	StepID     string
-	NextStepID string
-	AgentID    string
-	DID        string
-	Action     string
-	Params     map[string]string
-	ResultChan string
-	Timestamp  int64
+	WorkflowID string            `json:"workflow_id,omitempty"`
+	StepID     string            `json:"step_id,omitempty"`
+	NextStepID string            `json:"next_step_id,omitempty"`
+	AgentID    string            `json:"agent_id,omitempty"`
+	DID        string            `json:"did,omitempty"`
+	Action     string            `json:"action,omitempty"`
+	Params     map[string]string `json:"params,omitempty"`
+	ResultChan string            `json:"result_chan,omitempty"`
+	Timestamp  int64             `json:"timestamp,string,omitempty"`
 }
 
 func (m *WorkflowMessage) MsgType() MessageType { return MsgWorkflow }
 
 // CapabilityAnnouncement broadcasts capabilities to nearby peers.
 type CapabilityAnnouncement struct {
-	AgentID      string
-	DID          string
-	Capabilities []string
-	Timestamp    int64
-	TTL          int64 // seconds; 0 = indefinite
+	AgentID      string           `json:"agent_id,omitempty"`
+	DID          string           `json:"did,omitempty"`
+	Capabilities []string         `json:"capabilities,omitempty"`
+	Timestamp    int64            `json:"timestamp,string,omitempty"`
+	TTL          int64            `json:"ttl,string,omitempty"` // seconds; 0 = indefinite
+	Specs        []CapabilitySpec `json:"specs,omitempty"`      // Capability contracts (see capspec.go)
+
+	// Provenance tags the capabilities a federation gateway re-announces
+	// from another mesh (see federation.go); empty for an agent's own.
+	Provenance []ProvenanceTag `json:"provenance,omitempty"`
+
+	// Credentials are issuers' claims about the announcing agent (see
+	// credential.go).
+	Credentials []VerifiableCredential `json:"credentials,omitempty"`
+
+	// PublicKey is the announcing agent's Ed25519 key, and Signature its
+	// signature of the encoded announcement without this field (see
+	// SignAnnouncement).
+	PublicKey []byte `json:"public_key,omitempty"`
+	Signature []byte `json:"signature,omitempty"`
 }
 
 func (m *CapabilityAnnouncement) MsgType() MessageType { return MsgCapability }
 
+// VerifiableCredential is an issuer's signed claim about an agent, after
+// the W3C Verifiable Credentials data model (see credential.go).
+type VerifiableCredential struct {
+	ID         string            `json:"id,omitempty"`
+	Types      []string          `json:"types,omitempty"` // e.g. "CertifiedCodeGenerationAgent"
+	IssuerDID  string            `json:"issuer_did,omitempty"`
+	IssuerKey  []byte            `json:"issuer_key,omitempty"` // Issuer's Ed25519 public key, so any hop can verify
+	SubjectDID string            `json:"subject_did,omitempty"`
+	Claims     map[string]string `json:"claims,omitempty"` // Properties of the subject, e.g. "capability": "code-generation"
+	IssuedAt   int64             `json:"issued_at,string,omitempty"`
+	ExpiresAt  int64             `json:"expires_at,string,omitempty"` // Unix nanoseconds; 0 = never expires
+	Signature  []byte            `json:"signature,omitempty"`         // Ed25519 signature of the encoded credential without this field
+}
+
+// ProvenanceTag records that a gateway re-announced capabilities from
+// another mesh.
+type ProvenanceTag struct {
+	Mesh         string   `json:"mesh,omitempty"`         // Mesh the capabilities come from; empty for the default mesh
+	Gateway      string   `json:"gateway,omitempty"`      // DID of the gateway that proxies them
+	Capabilities []string `json:"capabilities,omitempty"` // The capabilities it re-announced
+}
+
+// CounterAction is what a CounterOffer does to a NegotiationSession.
+type CounterAction int32
+
+const (
+	CounterPropose CounterAction = iota // Propose the enclosed terms
+	CounterAccept                       // Accept the peer's last proposal
+	CounterAbort                        // End the session without agreement
+)
+
+// OfferTerms are the negotiable parameters of a counter-offer.
+// Zero values mean "unchanged from the intent".
+type OfferTerms struct {
+	Capabilities  []string `json:"capabilities,omitempty"`          // Alternative capabilities the proposer can provide
+	WorkflowSteps []string `json:"workflow_steps,omitempty"`        // Proposed (possibly reduced) workflow
+	Price         float32  `json:"price,omitempty"`                 // Proposed price in the parties' agreed unit
+	MaxLatencyMs  int64    `json:"max_latency_ms,string,omitempty"` // Proposed latency bound
+}
+
+// CounterOffer is one move in a multi-round negotiation (see NegotiationSession).
+type CounterOffer struct {
+	RequestID string        `json:"request_id,omitempty"` // ID of the IntentMessage that opened the session
+	Round     int32         `json:"round,omitempty"`      // 1-based move number within the session
+	AgentID   string        `json:"agent_id,omitempty"`
+	DID       string        `json:"did,omitempty"`
+	Action    CounterAction `json:"action,omitempty"`
+	Terms     OfferTerms    `json:"terms,omitempty"`
+	Reason    string        `json:"reason,omitempty"`
+	Timestamp int64         `json:"timestamp,string,omitempty"`
+	Signature []byte        `json:"signature,omitempty"` // Ed25519 signature of the encoded offer without this field
+}
+
+func (m *CounterOffer) MsgType() MessageType { return MsgCounter }
+
+// AlertKind classifies a mesh-wide AlertMessage.
+type AlertKind int32
+
+const (
+	AlertAdvisory         AlertKind = iota // Free-form security advisory
+	AlertKeyCompromise                     // Subject is a DID whose key must no longer be trusted
+	AlertCapabilityRecall                  // Subject is a capability the issuer withdraws
+)
+
+// AlertMessage is a signed, flood-propagated notice (see alert.go).
+type AlertMessage struct {
+	ID        string    `json:"id,omitempty"`
+	Kind      AlertKind `json:"kind,omitempty"`
+	IssuerDID string    `json:"issuer_did,omitempty"`
+	IssuerKey []byte    `json:"issuer_key,omitempty"` // Issuer's Ed25519 public key, so any hop can verify
+	Subject   string    `json:"subject,omitempty"`    // DID or capability, depending on Kind
+	Detail    string    `json:"detail,omitempty"`
+	Timestamp int64     `json:"timestamp,string,omitempty"`
+	Hops      int32     `json:"hops,omitempty"`      // Remaining forwarding hops; not covered by the signature
+	Signature []byte    `json:"signature,omitempty"` // Ed25519 signature of the encoded alert with Hops and Signature cleared
+}
+
+func (m *AlertMessage) MsgType() MessageType { return MsgAlert }
+
+// RevocationEntry revokes one DID.
+type RevocationEntry struct {
+	DID       string `json:"did,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+	RevokedAt int64  `json:"revoked_at,string,omitempty"` // Unix nanoseconds
+}
+
+// RevocationList is a signed, complete list of the DIDs an issuer has
+// revoked (see revocation.go).  A list with a higher Sequence replaces the
+// issuer's previous one.
+type RevocationList struct {
+	IssuerDID string            `json:"issuer_did,omitempty"`
+	IssuerKey []byte            `json:"issuer_key,omitempty"` // Issuer's Ed25519 public key, so any hop can verify
+	Sequence  int64             `json:"sequence,string,omitempty"`
+	IssuedAt  int64             `json:"issued_at,string,omitempty"`
+	Entries   []RevocationEntry `json:"entries,omitempty"`
+	Signature []byte            `json:"signature,omitempty"` // Ed25519 signature of the encoded list without this field
+}
+
+func (m *RevocationList) MsgType() MessageType { return MsgRevocation }
+
+// TrustAttestation is an agent's signed statement of the trust it places in
+// another agent (see attestation.go).
+type TrustAttestation struct {
+	IssuerDID  string  `json:"issuer_did,omitempty"`
+	IssuerKey  []byte  `json:"issuer_key,omitempty"` // Issuer's Ed25519 public key, so any hop can verify
+	SubjectDID string  `json:"subject_did,omitempty"`
+	Score      float32 `json:"score,omitempty"` // Issuer's trust in the subject, [0,1]
+	IssuedAt   int64   `json:"issued_at,string,omitempty"`
+	ExpiresAt  int64   `json:"expires_at,string,omitempty"` // Unix nanoseconds; 0 = never expires
+	Signature  []byte  `json:"signature,omitempty"`         // Ed25519 signature of the encoded attestation without this field
+}
+
+func (m *TrustAttestation) MsgType() MessageType { return MsgAttestation }
+
+// KeyRotation links a DID whose key is being retired to the DID of its
+// replacement (see rotation.go).
+type KeyRotation struct {
+	OldDID       string `json:"old_did,omitempty"`
+	OldKey       []byte `json:"old_key,omitempty"` // Retired Ed25519 public key, so any hop can verify
+	NewDID       string `json:"new_did,omitempty"`
+	NewKey       []byte `json:"new_key,omitempty"` // Replacement Ed25519 public key
+	Reason       string `json:"reason,omitempty"`
+	RotatedAt    int64  `json:"rotated_at,string,omitempty"`
+	Signature    []byte `json:"signature,omitempty"`     // Old key's signature of the encoded rotation without either signature
+	NewSignature []byte `json:"new_signature,omitempty"` // New key's signature of the same bytes, proving possession
+}
+
+func (m *KeyRotation) MsgType() MessageType { return MsgKeyRotation }
+
+// ForwardEnvelope carries another agent's intent from a relay to the next
+// hop (see relay.go).
+type ForwardEnvelope struct {
+	Intent    *IntentMessage `json:"intent,omitempty"`     // The originator's intent, exactly as signed
+	OriginKey []byte         `json:"origin_key,omitempty"` // Originator's Ed25519 public key, so the responder can verify
+	RelayDID  string         `json:"relay_did,omitempty"`  // The relay that sent this envelope
+	RelayKey  []byte         `json:"relay_key,omitempty"`  // Relay's Ed25519 public key, so the next hop can verify
+	Path      []string       `json:"path,omitempty"`       // Relays so far, oldest first; ends with RelayDID
+	Hops      int32          `json:"hops,omitempty"`       // Further relays allowed after the next hop
+	Timestamp int64          `json:"timestamp,string,omitempty"`
+	Signature []byte         `json:"signature,omitempty"` // Ed25519 signature of the encoded envelope without this field
+}
+
+func (m *ForwardEnvelope) MsgType() MessageType { return MsgForward }
+
+// IntentBatch carries several intents to one peer in a single frame.
+type IntentBatch struct {
+	Intents []*IntentMessage `json:"intents,omitempty"`
+}
+
+func (m *IntentBatch) MsgType() MessageType { return MsgIntentBatch }
+
+// ResponseBatch answers an IntentBatch.  Intents the responder dropped have
+// no response; the others are matched by RequestID.
+type ResponseBatch struct {
+	Responses []*NegotiationResponse `json:"responses,omitempty"`
+}
+
+func (m *ResponseBatch) MsgType() MessageType { return MsgResponseBatch }
+
+// PingMessage is a liveness probe (see liveness.go).  The receiver answers
+// with a PongMessage echoing Nonce and SentAt.
+type PingMessage struct {
+	Nonce  []byte `json:"nonce,omitempty"`
+	SentAt int64  `json:"sent_at,string,omitempty"` // Unix nanoseconds, by the sender's clock
+}
+
+func (m *PingMessage) MsgType() MessageType { return MsgPing }
+
+// PongMessage answers a PingMessage.
+type PongMessage struct {
+	Nonce     []byte `json:"nonce,omitempty"`             // The ping's Nonce
+	SentAt    int64  `json:"sent_at,string,omitempty"`    // The ping's SentAt
+	RepliedAt int64  `json:"replied_at,string,omitempty"` // Unix nanoseconds, by the responder's clock
+}
+
+func (m *PongMessage) MsgType() MessageType { return MsgPong }
+
+// WorkflowResult reports the outcome of a workflow step to the
+// orchestrator that sent it (see workflowresult.go).
+type WorkflowResult struct {
+	WorkflowID string         `json:"workflow_id,omitempty"`
+	StepID     string         `json:"step_id,omitempty"`
+	RequestID  string         `json:"request_id,omitempty"` // ID of the intent the step was sent as
+	DID        string         `json:"did,omitempty"`        // The executing agent
+	Status     string         `json:"status,omitempty"`     // WorkflowStatusCompleted or WorkflowStatusFailed
+	Output     *ResultPayload `json:"output,omitempty"`     // Set when completed
+	Error      string         `json:"error,omitempty"`      // Set when failed
+	Timestamp  int64          `json:"timestamp,string,omitempty"`
+	Signature  []byte         `json:"signature,omitempty"` // Ed25519 signature of the encoded result without this field
+}
+
+func (m *WorkflowResult) MsgType() MessageType { return MsgWorkflowResult }
+
+// ObserveRequest subscribes a monitoring agent to a host's events (see
+// observe.go).  The host answers with a NegotiationResponse and, if it
+// accepts, streams EventMessages.
+type ObserveRequest struct {
+	Kinds []string `json:"kinds,omitempty"` // Event kinds to stream; empty streams every kind
+}
+
+func (m *ObserveRequest) MsgType() MessageType { return MsgObserve }
+
+// EventMessage is one host event streamed to an observer.
+type EventMessage struct {
+	Kind         string   `json:"kind,omitempty"`
+	PeerID       string   `json:"peer_id,omitempty"`
+	AgentID      string   `json:"agent_id,omitempty"`
+	DID          string   `json:"did,omitempty"`
+	IntentID     string   `json:"intent_id,omitempty"`
+	Capabilities []string `json:"capabilities,omitempty"`
+	Reason       string   `json:"reason,omitempty"`
+	Delta        float32  `json:"delta,omitempty"`
+	Trust        float32  `json:"trust,omitempty"`
+	Timestamp    int64    `json:"timestamp,string,omitempty"`
+	Dropped      int64    `json:"dropped,string,omitempty"` // Events lost before this one because the observer read too slowly
+}
+
+func (m *EventMessage) MsgType() MessageType { return MsgEvent }
+
 // now returns current time as Unix nanoseconds.
 func now() int64 { return time.Now().UnixNano() }
@@ -0,0 +1,103 @@
+package core
+
+// vecmath.go — Vector kernels and top-K ranking.
+//
+// CosineSimilarity sits on every ranking path.  Its kernel, dotNorms, is
+// written in assembly for amd64 CPUs with AVX2 and FMA (vecmath_amd64.s):
+// eight elements per step, widened to float64 before they are multiplied,
+// so results match the scalar loop up to rounding.  Other CPUs, and builds
+// tagged purego, use dotNormsGeneric.
+//
+// RankCandidates sorts every candidate; TopK keeps only the best k in a
+// min-heap, which for a registry of thousands of agents and a handful of
+// wanted peers is O(n log k) instead of O(n log n).
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+)
+
+// dotNormsGeneric returns a·b, a·a and b·b for equal-length a and b.
+func dotNormsGeneric(a, b []float32) (dot, normA, normB float64) {
+	b = b[:len(a)]
+	for i, x := range a {
+		x, y := float64(x), float64(b[i])
+		dot += x * y
+		normA += x * x
+		normB += y * y
+	}
+	return dot, normA, normB
+}
+
+// scoredProfile is a candidate with its ranking score; index breaks ties
+// in favour of the earlier candidate.
+type scoredProfile struct {
+	profile AgentProfile
+	score   float64
+	index   int
+}
+
+// worse reports whether r ranks below s.
+func (r scoredProfile) worse(s scoredProfile) bool {
+	if r.score != s.score {
+		return r.score < s.score
+	}
+	return r.index > s.index
+}
+
+// topHeap is a min-heap of the best candidates seen so far, worst on top.
+type topHeap []scoredProfile
+
+func (h topHeap) Len() int           { return len(h) }
+func (h topHeap) Less(i, j int) bool { return h[i].worse(h[j]) }
+func (h topHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *topHeap) Push(x any)        { *h = append(*h, x.(scoredProfile)) }
+func (h *topHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// TopK returns the k candidates most similar to the intent vector, highest
+// first: the first k of RankCandidates, without sorting the rest.  All
+// candidates are returned, ranked, when k exceeds their number; none when
+// k is not positive.
+func TopK(intentVector []float32, candidates []AgentProfile, k int) []AgentProfile {
+	return TopKAssessed(intentVector, candidates, nil, 0, k)
+}
+
+// TopKAssessed is TopK ranking as RankAssessed does.
+func TopKAssessed(intentVector []float32, candidates []AgentProfile, assessed map[string]float32, weight float64, k int) []AgentProfile {
+	if k <= 0 {
+		return nil
+	}
+	if k > len(candidates) {
+		k = len(candidates)
+	}
+	weight = math.Min(math.Max(weight, 0), 1)
+	h := make(topHeap, 0, k)
+	for i, c := range candidates {
+		score := CosineSimilarity(intentVector, c.EmbeddingVector)
+		if a, ok := assessed[c.AgentID]; ok && a > 0 {
+			score = (1-weight)*score + weight*float64(a)
+		}
+		s := scoredProfile{c, score, i}
+		switch {
+		case k == len(candidates):
+			h = append(h, s) // Ranking everything: no heap needed
+		case len(h) < k:
+			heap.Push(&h, s)
+		case h[0].worse(s):
+			h[0] = s
+			heap.Fix(&h, 0)
+		}
+	}
+	sort.Slice(h, func(i, j int) bool { return h[j].worse(h[i]) })
+	out := make([]AgentProfile, len(h))
+	for i, s := range h {
+		out[i] = s.profile
+	}
+	return out
+}
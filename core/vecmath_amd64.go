@@ -0,0 +1,20 @@
+//go:build amd64 && !purego
+
+package core
+
+import "golang.org/x/sys/cpu"
+
+var useAVX2 = cpu.X86.HasAVX2 && cpu.X86.HasFMA
+
+// dotNormsAVX2 is dotNormsGeneric in AVX2 and FMA; len(b) >= len(a).
+//
+//go:noescape
+func dotNormsAVX2(a, b []float32) (dot, normA, normB float64)
+
+// dotNorms returns a·b, a·a and b·b for equal-length a and b.
+func dotNorms(a, b []float32) (dot, normA, normB float64) {
+	if useAVX2 {
+		return dotNormsAVX2(a, b[:len(a)])
+	}
+	return dotNormsGeneric(a, b)
+}
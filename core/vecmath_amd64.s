@@ -0,0 +1,69 @@
+//go:build amd64 && !purego
+
+#include "textflag.h"
+
+// func dotNormsAVX2(a, b []float32) (dot, normA, normB float64)
+//
+// Y0/Y1 accumulate a·b, Y2/Y3 a·a and Y4/Y5 b·b, four float64 lanes each,
+// over eight elements a step; the tail is summed one element at a time.
+TEXT ·dotNormsAVX2(SB), NOSPLIT, $0-72
+	MOVQ a_base+0(FP), SI
+	MOVQ a_len+8(FP), CX
+	MOVQ b_base+24(FP), DI
+	XORQ AX, AX
+	VXORPD Y0, Y0, Y0
+	VXORPD Y1, Y1, Y1
+	VXORPD Y2, Y2, Y2
+	VXORPD Y3, Y3, Y3
+	VXORPD Y4, Y4, Y4
+	VXORPD Y5, Y5, Y5
+	MOVQ CX, DX
+	ANDQ $-8, DX
+
+loop8:
+	CMPQ AX, DX
+	JGE  reduce
+	VCVTPS2PD   (SI)(AX*4), Y6
+	VCVTPS2PD   16(SI)(AX*4), Y7
+	VCVTPS2PD   (DI)(AX*4), Y8
+	VCVTPS2PD   16(DI)(AX*4), Y9
+	VFMADD231PD Y6, Y8, Y0
+	VFMADD231PD Y7, Y9, Y1
+	VFMADD231PD Y6, Y6, Y2
+	VFMADD231PD Y7, Y7, Y3
+	VFMADD231PD Y8, Y8, Y4
+	VFMADD231PD Y9, Y9, Y5
+	ADDQ        $8, AX
+	JMP         loop8
+
+reduce:
+	VADDPD       Y1, Y0, Y0
+	VADDPD       Y3, Y2, Y2
+	VADDPD       Y5, Y4, Y4
+	VEXTRACTF128 $1, Y0, X1
+	VADDPD       X1, X0, X0
+	VHADDPD      X0, X0, X0
+	VEXTRACTF128 $1, Y2, X3
+	VADDPD       X3, X2, X2
+	VHADDPD      X2, X2, X2
+	VEXTRACTF128 $1, Y4, X5
+	VADDPD       X5, X4, X4
+	VHADDPD      X4, X4, X4
+
+tail:
+	CMPQ        AX, CX
+	JGE         done
+	VCVTSS2SD   (SI)(AX*4), X6, X6
+	VCVTSS2SD   (DI)(AX*4), X8, X8
+	VFMADD231SD X6, X8, X0
+	VFMADD231SD X6, X6, X2
+	VFMADD231SD X8, X8, X4
+	INCQ        AX
+	JMP         tail
+
+done:
+	VZEROUPPER
+	MOVSD X0, dot+48(FP)
+	MOVSD X2, normA+56(FP)
+	MOVSD X4, normB+64(FP)
+	RET
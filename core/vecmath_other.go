@@ -0,0 +1,8 @@
+//go:build !amd64 || purego
+
+package core
+
+// dotNorms returns a·b, a·a and b·b for equal-length a and b.
+func dotNorms(a, b []float32) (dot, normA, normB float64) {
+	return dotNormsGeneric(a, b)
+}
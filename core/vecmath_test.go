@@ -0,0 +1,117 @@
+package core_test
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func randVector(r *rand.Rand, dim int) []float32 {
+	v := make([]float32, dim)
+	for i := range v {
+		v[i] = r.Float32()*2 - 1
+	}
+	return v
+}
+
+// scalarCosine is the plain loop CosineSimilarity is measured against.
+func scalarCosine(a, b []float32) float64 {
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+func registry(r *rand.Rand, n, dim int) []core.AgentProfile {
+	agents := make([]core.AgentProfile, n)
+	for i := range agents {
+		agents[i] = core.AgentProfile{AgentID: fmt.Sprintf("agent-%d", i)}
+		if i%10 != 0 {
+			agents[i].EmbeddingVector = randVector(r, dim)
+		}
+	}
+	return agents
+}
+
+func TestCosineSimilarityMatchesScalar(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, dim := range []int{1, 3, 4, 7, 384, 1537} {
+		a, b := randVector(r, dim), randVector(r, dim)
+		if got, want := core.CosineSimilarity(a, b), scalarCosine(a, b); math.Abs(got-want) > 1e-9 {
+			t.Errorf("dim %d: CosineSimilarity = %v, want %v", dim, got, want)
+		}
+	}
+}
+
+func TestTopK(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	agents := registry(r, 500, 64)
+	intent := randVector(r, 64)
+	ranked := core.RankCandidates(intent, agents)
+
+	for _, k := range []int{1, 5, 50, 500, 600} {
+		top := core.TopK(intent, agents, k)
+		if want := min(k, len(agents)); len(top) != want {
+			t.Fatalf("TopK(%d) returned %d agents, want %d", k, len(top), want)
+		}
+		for i := range top {
+			if top[i].AgentID != ranked[i].AgentID {
+				t.Fatalf("TopK(%d)[%d] = %s, want %s as ranked by RankCandidates", k, i, top[i].AgentID, ranked[i].AgentID)
+			}
+		}
+	}
+	if top := core.TopK(intent, agents, 0); len(top) != 0 {
+		t.Errorf("TopK(0) returned %d agents", len(top))
+	}
+	if last := ranked[len(ranked)-1]; last.EmbeddingVector != nil && core.CosineSimilarity(intent, last.EmbeddingVector) > 0 {
+		t.Errorf("RankCandidates ranked %s with a positive score last", last.AgentID)
+	}
+
+	assessed := map[string]float32{agents[0].AgentID: 1}
+	if top := core.TopKAssessed(intent, agents, assessed, 1, 1); top[0].AgentID != agents[0].AgentID {
+		t.Errorf("TopKAssessed ranked %s first, want the assessed %s", top[0].AgentID, agents[0].AgentID)
+	}
+}
+
+var cosineSink float64
+
+func BenchmarkCosineSimilarity(b *testing.B) {
+	r := rand.New(rand.NewSource(3))
+	for _, dim := range []int{384, 1536} {
+		x, y := randVector(r, dim), randVector(r, dim)
+		b.Run(fmt.Sprintf("scalar/%d", dim), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				cosineSink += scalarCosine(x, y)
+			}
+		})
+		b.Run(fmt.Sprintf("CosineSimilarity/%d", dim), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				cosineSink += core.CosineSimilarity(x, y)
+			}
+		})
+	}
+}
+
+func BenchmarkRanking(b *testing.B) {
+	r := rand.New(rand.NewSource(4))
+	agents := registry(r, 5000, 384)
+	intent := randVector(r, 384)
+	b.Run("RankCandidates", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = core.RankCandidates(intent, agents)[:10]
+		}
+	})
+	b.Run("TopK", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			core.TopK(intent, agents, 10)
+		}
+	})
+}
@@ -0,0 +1,162 @@
+package core
+
+// version.go — Protocol version negotiation.
+//
+// The initiator lists every version it speaks in HandshakeMessage.Versions,
+// highest first; the responder picks the highest version both sides support
+// and returns it in Version.  Peers from before negotiation existed send
+// only Version, which is treated as a single-entry offer.
+//
+// Each version has an entry in a compatibility matrix naming the payload
+// codecs and optional features it allows, so a 1.1.0 node talking to a
+// 1.0.0 node falls back to protobuf over per-request streams.  From 1.2.0
+// peers may send v2 frames (see frame.go); every version reads both frame
+// formats, so the libp2p protocol ID does not change.
+
+import (
+	"fmt"
+	"slices"
+)
+
+// ErrVersionMismatch is returned when two peers share no protocol version.
+var ErrVersionMismatch = fmt.Errorf("handshake: no common protocol version")
+
+// legacyVersion is assumed for handshakes that carry no version at all.
+const legacyVersion = "1.0.0"
+
+// Feature is an optional protocol behaviour gated by version.
+type Feature string
+
+const (
+	FeatureCodecNegotiation Feature = "codec-negotiation" // HandshakeMessage.Codecs is honoured
+	FeatureMultiplexing     Feature = "multiplexing"      // Intents may share a session stream
+	FeatureFrameV2          Feature = "frame-v2"          // Messages after the handshake use v2 frames
+)
+
+// VersionInfo describes what one protocol version allows.
+type VersionInfo struct {
+	Version  string
+	Codecs   []string // Payload codecs that may be negotiated
+	Features []Feature
+}
+
+// Has reports whether v enables f.
+func (v VersionInfo) Has(f Feature) bool { return slices.Contains(v.Features, f) }
+
+// AllowsCodec reports whether the codec called name may be used under v.
+func (v VersionInfo) AllowsCodec(name string) bool { return slices.Contains(v.Codecs, name) }
+
+// NegotiateCodec is NegotiateCodec restricted to the codecs v allows.
+// Without FeatureCodecNegotiation it always returns ProtobufCodec.
+func (v VersionInfo) NegotiateCodec(offered []string) Codec {
+	if !v.Has(FeatureCodecNegotiation) {
+		return ProtobufCodec
+	}
+	allowed := make([]string, 0, len(offered))
+	for _, name := range offered {
+		if v.AllowsCodec(name) {
+			allowed = append(allowed, name)
+		}
+	}
+	return NegotiateCodec(allowed)
+}
+
+// compatibility lists every supported version, highest first.
+var compatibility = []VersionInfo{
+	{
+		Version:  "1.2.0",
+		Codecs:   []string{"protobuf", "json", "cbor"},
+		Features: []Feature{FeatureCodecNegotiation, FeatureMultiplexing, FeatureFrameV2},
+	},
+	{
+		Version:  "1.1.0",
+		Codecs:   []string{"protobuf", "json", "cbor"},
+		Features: []Feature{FeatureCodecNegotiation, FeatureMultiplexing},
+	},
+	{
+		Version: "1.0.0",
+		Codecs:  []string{"protobuf"},
+	},
+}
+
+// SupportedVersions returns the versions this build speaks, highest first.
+func SupportedVersions() []string {
+	out := make([]string, len(compatibility))
+	for i, v := range compatibility {
+		out[i] = v.Version
+	}
+	return out
+}
+
+// VersionsWith returns the supported versions that enable f, highest first.
+func VersionsWith(f Feature) []string {
+	var out []string
+	for _, v := range compatibility {
+		if v.Has(f) {
+			out = append(out, v.Version)
+		}
+	}
+	return out
+}
+
+// LookupVersion returns the compatibility entry for version.
+func LookupVersion(version string) (VersionInfo, bool) {
+	for _, v := range compatibility {
+		if v.Version == version {
+			return v, true
+		}
+	}
+	return VersionInfo{}, false
+}
+
+// NegotiateVersion returns the highest supported version in offered.
+func NegotiateVersion(offered []string) (VersionInfo, error) {
+	for _, v := range compatibility {
+		if slices.Contains(offered, v.Version) {
+			return v, nil
+		}
+	}
+	return VersionInfo{}, fmt.Errorf("%w: peer offers %v, we support %v", ErrVersionMismatch, offered, SupportedVersions())
+}
+
+// OfferedVersions returns the versions m's sender speaks, falling back to
+// its single Version for peers that predate negotiation.
+func OfferedVersions(m *HandshakeMessage) []string {
+	switch {
+	case len(m.Versions) > 0:
+		return m.Versions
+	case m.Version != "":
+		return []string{m.Version}
+	default:
+		return []string{legacyVersion}
+	}
+}
+
+// AcceptedVersion checks the version a responder chose.  A responder that
+// found no common version replies with an empty Version and its own list in
+// Versions.
+func AcceptedVersion(resp *HandshakeMessage) (VersionInfo, error) {
+	if resp.Version == "" && len(resp.Versions) > 0 {
+		return VersionInfo{}, fmt.Errorf("%w: peer supports %v, we support %v", ErrVersionMismatch, resp.Versions, SupportedVersions())
+	}
+	chosen := resp.Version
+	if chosen == "" {
+		chosen = legacyVersion
+	}
+	v, ok := LookupVersion(chosen)
+	if !ok {
+		return VersionInfo{}, fmt.Errorf("%w: peer chose %q, we support %v", ErrVersionMismatch, resp.Version, SupportedVersions())
+	}
+	return v, nil
+}
+
+// VersionMismatchReply is the handshake a responder sends when
+// RespondHandshake fails with ErrVersionMismatch, so the initiator can
+// report which versions would have worked.
+func VersionMismatchReply(responder *Agent) *HandshakeMessage {
+	return &HandshakeMessage{
+		AgentID:  responder.ID,
+		DID:      responder.DID.String(),
+		Versions: SupportedVersions(),
+	}
+}
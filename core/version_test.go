@@ -0,0 +1,72 @@
+package core_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func TestNegotiateVersion(t *testing.T) {
+	v, err := core.NegotiateVersion([]string{"2.0.0", "1.0.0", "1.1.0"})
+	if err != nil || v.Version != "1.1.0" {
+		t.Fatalf("got %q, %v; want 1.1.0", v.Version, err)
+	}
+	if _, err = core.NegotiateVersion([]string{"0.9.0"}); !errors.Is(err, core.ErrVersionMismatch) {
+		t.Errorf("got %v, want ErrVersionMismatch", err)
+	}
+	if core.SupportedVersions()[0] != core.ProtocolVersion {
+		t.Errorf("ProtocolVersion %s is not the highest supported version", core.ProtocolVersion)
+	}
+}
+
+func TestHandshakeVersionNegotiation(t *testing.T) {
+	a, _ := core.NewAgent("a", nil)
+	b, _ := core.NewAgent("b", nil)
+
+	// A legacy 1.0.0 initiator gets 1.0.0 and no codec negotiation.
+	legacy, _ := core.StartHandshake(a)
+	legacy.Versions = nil
+	legacy.Version = "1.0.0"
+	legacy.Codecs = []string{"json"}
+	resp, err := core.RespondHandshake(b, legacy)
+	if err != nil {
+		t.Fatalf("RespondHandshake: %v", err)
+	}
+	if resp.Version != "1.0.0" || len(resp.Codecs) != 1 || resp.Codecs[0] != "protobuf" {
+		t.Errorf("legacy: got version %q codecs %v", resp.Version, resp.Codecs)
+	}
+	if err = core.FinishHandshake(legacy.Challenge, resp); err != nil {
+		t.Errorf("FinishHandshake: %v", err)
+	}
+
+	// A current initiator gets the highest version and its codec.
+	cur, _ := core.StartHandshake(a)
+	cur.Codecs = []string{"cbor"}
+	resp, _ = core.RespondHandshake(b, cur)
+	if resp.Version != core.ProtocolVersion || resp.Codecs[0] != "cbor" {
+		t.Errorf("current: got version %q codecs %v", resp.Version, resp.Codecs)
+	}
+
+	// No common version.
+	future, _ := core.StartHandshake(a)
+	future.Versions = []string{"9.0.0"}
+	if _, err = core.RespondHandshake(b, future); !errors.Is(err, core.ErrVersionMismatch) {
+		t.Errorf("RespondHandshake: got %v, want ErrVersionMismatch", err)
+	}
+	if _, err = core.AcceptedVersion(core.VersionMismatchReply(b)); !errors.Is(err, core.ErrVersionMismatch) {
+		t.Errorf("AcceptedVersion: got %v, want ErrVersionMismatch", err)
+	}
+}
+
+func TestVersionsWith(t *testing.T) {
+	got := core.VersionsWith(core.FeatureFrameV2)
+	if len(got) == 0 || got[0] != core.ProtocolVersion {
+		t.Errorf("VersionsWith(frame-v2) = %v", got)
+	}
+	for _, v := range got {
+		if info, _ := core.LookupVersion(v); !info.Has(core.FeatureFrameV2) {
+			t.Errorf("%s lacks frame-v2", v)
+		}
+	}
+}
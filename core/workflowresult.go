@@ -0,0 +1,91 @@
+package core
+
+// workflowresult.go — Step results delivered after the negotiation.
+//
+// A NegotiationResponse says whether an agent takes on a workflow step,
+// and at most carries a small inline result (see result.go).  A step whose
+// output comes later, or is too big to inline, is reported with a
+// WorkflowResult: the executing agent sends one, signed, to the
+// orchestrator once the step has finished.  The orchestrator asks for it
+// by setting Metadata[AwaitResultMetadataKey] on the step's intent.
+
+import "fmt"
+
+// Intent metadata set by orchestrators on workflow steps.
+const (
+	WorkflowIDMetadataKey  = "workflow_id"
+	StepIDMetadataKey      = "step_id"
+	AwaitResultMetadataKey = "await_result" // "true": answer with a WorkflowResult once done
+)
+
+// Status values of a WorkflowResult.
+const (
+	WorkflowStatusCompleted = "completed"
+	WorkflowStatusFailed    = "failed"
+)
+
+// NewWorkflowResult builds and signs agent's result for the workflow step
+// intent asked for: output if stepErr is nil, stepErr otherwise.
+func NewWorkflowResult(agent *Agent, intent *IntentMessage, output *ResultPayload, stepErr error) (*WorkflowResult, error) {
+	r := &WorkflowResult{
+		WorkflowID: intent.Metadata[WorkflowIDMetadataKey],
+		StepID:     intent.Metadata[StepIDMetadataKey],
+		RequestID:  intent.ID,
+		DID:        agent.DID.String(),
+		Status:     WorkflowStatusCompleted,
+		Output:     output,
+		Timestamp:  now(),
+	}
+	if stepErr != nil {
+		r.Status, r.Output, r.Error = WorkflowStatusFailed, nil, stepErr.Error()
+	}
+	if err := SignWorkflowResult(agent, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// SignWorkflowResult sets r.Signature to agent's signature over the rest
+// of r.
+func SignWorkflowResult(agent *Agent, r *WorkflowResult) error {
+	sig, err := agent.Sign(workflowResultSigningData(r))
+	if err != nil {
+		return fmt.Errorf("workflow result: sign: %w", err)
+	}
+	r.Signature = sig
+	return nil
+}
+
+// VerifyWorkflowResult checks that r is signed by pubKey, the key of
+// r.DID.  Unsigned results are always rejected.
+func VerifyWorkflowResult(r *WorkflowResult, pubKey []byte) error {
+	if len(r.Signature) == 0 {
+		return fmt.Errorf("workflow result: from %s is unsigned", r.DID)
+	}
+	d, err := DIDFromPublicKey(pubKey)
+	if err != nil {
+		return fmt.Errorf("workflow result: %w", err)
+	}
+	if d.String() != r.DID {
+		return fmt.Errorf("workflow result: key does not match %s", r.DID)
+	}
+	if !d.Verify(workflowResultSigningData(r), r.Signature) {
+		return fmt.Errorf("workflow result: %w from %s", ErrSignatureInvalid, r.DID)
+	}
+	switch r.Status {
+	case WorkflowStatusCompleted, WorkflowStatusFailed:
+	default:
+		return fmt.Errorf("workflow result: unknown status %q", r.Status)
+	}
+	return nil
+}
+
+// Failed reports whether the step failed.
+func (r *WorkflowResult) Failed() bool { return r.Status == WorkflowStatusFailed }
+
+func workflowResultSigningData(r *WorkflowResult) []byte {
+	c := *r
+	c.Signature = nil
+	data, _ := c.Encode()
+	return data
+}
@@ -0,0 +1,65 @@
+package core_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+func TestWorkflowResultRoundTrip(t *testing.T) {
+	worker, _ := core.NewAgent("summariser", []string{"summarise"})
+	orchestrator, _ := core.NewAgent("orchestrator", nil)
+	intent, _ := core.CreateIntent(orchestrator, []float32{1, 0}, []string{"summarise"}, "report.txt")
+	intent.Metadata[core.WorkflowIDMetadataKey] = "wf-1"
+	intent.Metadata[core.StepIDMetadataKey] = "summarise"
+
+	r, err := core.NewWorkflowResult(worker, intent, &core.ResultPayload{ContentType: "text/plain", Data: []byte("tl;dr")}, nil)
+	if err != nil {
+		t.Fatalf("NewWorkflowResult: %v", err)
+	}
+	if r.WorkflowID != "wf-1" || r.StepID != "summarise" || r.RequestID != intent.ID || r.Failed() {
+		t.Fatalf("result = %+v", r)
+	}
+	data, _ := r.Encode()
+	decoded, err := core.Decode(core.MsgWorkflowResult, data)
+	if err != nil || !reflect.DeepEqual(decoded, r) {
+		t.Fatalf("decoded = %+v, %v; want %+v", decoded, err, r)
+	}
+	if err := core.VerifyWorkflowResult(decoded.(*core.WorkflowResult), worker.DID.PublicKey()); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+
+	failed, _ := core.NewWorkflowResult(worker, intent, nil, errors.New("disk full"))
+	if !failed.Failed() || failed.Error != "disk full" || failed.Output != nil {
+		t.Errorf("failed result = %+v", failed)
+	}
+}
+
+func TestVerifyWorkflowResultRejects(t *testing.T) {
+	worker, _ := core.NewAgent("summariser", []string{"summarise"})
+	other, _ := core.NewAgent("impostor", nil)
+	intent, _ := core.CreateIntent(worker, []float32{1}, []string{"summarise"}, "")
+
+	tampered, _ := core.NewWorkflowResult(worker, intent, &core.ResultPayload{Data: []byte("ok")}, nil)
+	tampered.Output.Data = []byte("forged")
+	unsigned, _ := core.NewWorkflowResult(worker, intent, nil, nil)
+	unsigned.Signature = nil
+	wrongKey, _ := core.NewWorkflowResult(worker, intent, nil, nil)
+
+	for name, tc := range map[string]struct {
+		r    *core.WorkflowResult
+		key  []byte
+		want string
+	}{
+		"tampered":  {tampered, worker.DID.PublicKey(), "invalid signature"},
+		"unsigned":  {unsigned, worker.DID.PublicKey(), "unsigned"},
+		"wrong key": {wrongKey, other.DID.PublicKey(), "does not match"},
+	} {
+		if err := core.VerifyWorkflowResult(tc.r, tc.key); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: Verify = %v; want error containing %q", name, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+// Package embeddings turns natural-language text into intent vectors.
+//
+// An Embedder maps text to a float32 vector.  OpenAI and Ollama call the
+// respective HTTP APIs; Hashing needs no model or network at all and is the
+// fallback for tests, demos and air-gapped deployments.  Vectors from
+// different embedders (or models) are not comparable, so every agent in a
+// mesh should use the same one.
+//
+// The package depends only on the standard library.
+package embeddings
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"unicode"
+)
+
+// Embedder produces a semantic vector for text.
+type Embedder interface {
+	Embed(ctx context.Context, text string) ([]float32, error)
+}
+
+// DefaultDim is the dimension of Hashing vectors when none is given.  It
+// matches common sentence encoders such as all-MiniLM-L6-v2.
+const DefaultDim = 384
+
+// Hashing embeds text by feature hashing: each lower-cased word and each
+// word bigram is hashed to a signed bucket, and the result is L2-normalised.
+// Texts sharing words get similar vectors, but there is no notion of
+// synonyms.  It is deterministic and safe for concurrent use.
+type Hashing struct {
+	Dim int
+}
+
+// NewHashing returns a Hashing embedder producing dim-dimensional vectors
+// (DefaultDim if dim <= 0).
+func NewHashing(dim int) *Hashing {
+	if dim <= 0 {
+		dim = DefaultDim
+	}
+	return &Hashing{Dim: dim}
+}
+
+// Embed implements Embedder.  Empty text yields a zero vector.
+func (h *Hashing) Embed(_ context.Context, text string) ([]float32, error) {
+	dim := h.Dim
+	if dim <= 0 {
+		dim = DefaultDim
+	}
+	vec := make([]float32, dim)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	add := func(feature string, weight float32) {
+		f := fnv.New64a()
+		_, _ = f.Write([]byte(feature))
+		sum := f.Sum64()
+		sign := float32(1)
+		if sum>>63 == 1 {
+			sign = -1
+		}
+		vec[sum%uint64(dim)] += sign * weight
+	}
+	for i, w := range words {
+		add(w, 1)
+		if i > 0 {
+			add(words[i-1]+" "+w, 0.5)
+		}
+	}
+	normalize(vec)
+	return vec, nil
+}
+
+// normalize scales v to unit length in place.
+func normalize(v []float32) {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return
+	}
+	inv := float32(1 / math.Sqrt(sum))
+	for i := range v {
+		v[i] *= inv
+	}
+}
+
+// maxResponse bounds the size of an embedding API response.
+const maxResponse = 16 << 20
+
+// postJSON sends body to url and decodes the JSON reply into out.
+func postJSON(ctx context.Context, client *http.Client, url string, header http.Header, body, out interface{}) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponse))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,86 @@
+package embeddings_test
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/olserra/agent-semantic-protocol/embeddings"
+)
+
+func cosine(a, b []float32) float64 {
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	return dot / math.Sqrt(na*nb)
+}
+
+func TestHashing(t *testing.T) {
+	ctx := context.Background()
+	h := embeddings.NewHashing(0)
+	a, _ := h.Embed(ctx, "Summarise this quarterly report")
+	b, _ := h.Embed(ctx, "summarise the quarterly report!")
+	c, _ := h.Embed(ctx, "generate a python sort function")
+	again, _ := h.Embed(ctx, "Summarise this quarterly report")
+
+	if len(a) != embeddings.DefaultDim {
+		t.Fatalf("dim: got %d", len(a))
+	}
+	if cosine(a, again) < 0.9999 {
+		t.Error("hashing is not deterministic")
+	}
+	if cosine(a, b) <= cosine(a, c) {
+		t.Errorf("related texts (%.3f) not closer than unrelated (%.3f)", cosine(a, b), cosine(a, c))
+	}
+}
+
+func TestOpenAI(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" || r.Header.Get("Authorization") != "Bearer sk-test" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		var req struct{ Model, Input string }
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if req.Model != "text-embedding-3-small" || req.Input != "hello" {
+			http.Error(w, "bad body", http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":[{"embedding":[0.1,0.2,0.3]}]}`))
+	}))
+	defer srv.Close()
+
+	e := embeddings.NewOpenAI("sk-test", "")
+	e.BaseURL = srv.URL + "/v1"
+	vec, err := e.Embed(context.Background(), "hello")
+	if err != nil || len(vec) != 3 || vec[2] != 0.3 {
+		t.Fatalf("got %v, %v", vec, err)
+	}
+
+	e.APIKey = "wrong"
+	if _, err = e.Embed(context.Background(), "hello"); err == nil {
+		t.Error("expected an error for a rejected request")
+	}
+}
+
+func TestOllama(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/embed" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"embeddings":[[1,0,0,0]]}`))
+	}))
+	defer srv.Close()
+
+	vec, err := embeddings.NewOllama(srv.URL, "").Embed(context.Background(), "hello")
+	if err != nil || len(vec) != 4 || vec[0] != 1 {
+		t.Fatalf("got %v, %v", vec, err)
+	}
+}
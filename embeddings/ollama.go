@@ -0,0 +1,52 @@
+package embeddings
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Ollama embeds text with a local Ollama server.
+type Ollama struct {
+	BaseURL string       // Default "http://localhost:11434"
+	Model   string       // Default "nomic-embed-text"
+	Client  *http.Client // Default http.DefaultClient
+}
+
+// NewOllama returns an Ollama embedder for the server at baseURL using model
+// (the defaults if empty).
+func NewOllama(baseURL, model string) *Ollama {
+	return &Ollama{BaseURL: baseURL, Model: model}
+}
+
+type ollamaRequest struct {
+	Model string `json:"model"`
+	Input string `json:"input"`
+}
+
+type ollamaResponse struct {
+	Embeddings [][]float32 `json:"embeddings"`
+}
+
+// Embed implements Embedder.
+func (o *Ollama) Embed(ctx context.Context, text string) ([]float32, error) {
+	model := o.Model
+	if model == "" {
+		model = "nomic-embed-text"
+	}
+	base := o.BaseURL
+	if base == "" {
+		base = "http://localhost:11434"
+	}
+
+	var resp ollamaResponse
+	req := ollamaRequest{Model: model, Input: text}
+	if err := postJSON(ctx, o.Client, strings.TrimRight(base, "/")+"/api/embed", nil, req, &resp); err != nil {
+		return nil, fmt.Errorf("embeddings ollama: %w", err)
+	}
+	if len(resp.Embeddings) == 0 || len(resp.Embeddings[0]) == 0 {
+		return nil, fmt.Errorf("embeddings ollama: empty response")
+	}
+	return resp.Embeddings[0], nil
+}
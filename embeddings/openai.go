@@ -0,0 +1,61 @@
+package embeddings
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// OpenAI embeds text with the OpenAI embeddings API (or any server that
+// speaks it, via BaseURL).
+type OpenAI struct {
+	APIKey     string
+	Model      string       // Default "text-embedding-3-small"
+	BaseURL    string       // Default "https://api.openai.com/v1"
+	Dimensions int          // Optional: ask the model to shorten its vectors
+	Client     *http.Client // Default http.DefaultClient
+}
+
+// NewOpenAI returns an OpenAI embedder using model (the default if empty).
+func NewOpenAI(apiKey, model string) *OpenAI {
+	return &OpenAI{APIKey: apiKey, Model: model}
+}
+
+type openAIRequest struct {
+	Model      string `json:"model"`
+	Input      string `json:"input"`
+	Dimensions int    `json:"dimensions,omitempty"`
+}
+
+type openAIResponse struct {
+	Data []struct {
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+// Embed implements Embedder.
+func (o *OpenAI) Embed(ctx context.Context, text string) ([]float32, error) {
+	model := o.Model
+	if model == "" {
+		model = "text-embedding-3-small"
+	}
+	base := o.BaseURL
+	if base == "" {
+		base = "https://api.openai.com/v1"
+	}
+	header := http.Header{}
+	if o.APIKey != "" {
+		header.Set("Authorization", "Bearer "+o.APIKey)
+	}
+
+	var resp openAIResponse
+	req := openAIRequest{Model: model, Input: text, Dimensions: o.Dimensions}
+	if err := postJSON(ctx, o.Client, strings.TrimRight(base, "/")+"/embeddings", header, req, &resp); err != nil {
+		return nil, fmt.Errorf("embeddings openai: %w", err)
+	}
+	if len(resp.Data) == 0 || len(resp.Data[0].Embedding) == 0 {
+		return nil, fmt.Errorf("embeddings openai: empty response")
+	}
+	return resp.Data[0].Embedding, nil
+}
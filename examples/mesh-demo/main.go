@@ -0,0 +1,346 @@
+// mesh-demo — Five heterogeneous agents form a mesh over the Kademlia DHT
+// and run a dependency-ordered workflow.  The first summarisation request is
+// made to stall; the orchestrator's replan policy abandons the stalled peer
+// and re-routes the step, and the demo prints per-step metrics at the end.
+//
+// Agents:
+//
+//	relay        DHT bootstrap node; also serves a small fallback summariser
+//	llm          LLM-backed summariser (a deterministic stand-in model here)
+//	storage      document store and keyword index
+//	validator    checks summaries before they are stored
+//	coordinator  discovers the others by capability and runs the workflow
+//
+// Run:
+//
+//	go run ./examples/mesh-demo/main.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/embeddings"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+const document = "The Agent Semantic Protocol lets autonomous agents discover each other " +
+	"by capability, negotiate work with signed semantic intents, and build trust " +
+	"from the outcome of every exchange. Workflows spread across many peers and " +
+	"survive the failure of any single one."
+
+// work is what an agent does with an accepted step payload.
+type work func(ctx context.Context, payload string) (string, error)
+
+// faultInjector makes the first summarisation request in the mesh stall, to
+// simulate a hung model.
+type faultInjector struct {
+	mu    sync.Mutex
+	fired bool
+}
+
+func (f *faultInjector) stall(ctx context.Context, agentID string) error {
+	f.mu.Lock()
+	first := !f.fired
+	f.fired = true
+	f.mu.Unlock()
+	if !first {
+		return nil
+	}
+	fmt.Printf("  ⚠ [%s] injected fault: model stalled\n", agentID)
+	select {
+	case <-ctx.Done():
+	case <-time.After(10 * time.Second):
+	}
+	return fmt.Errorf("model stalled")
+}
+
+func main() {
+	ctx, cancel := context.WithTimeout(context.Background(), 90*time.Second)
+	defer cancel()
+
+	fmt.Println("╔══════════════════════════════════════════════╗")
+	fmt.Println("║  Agent Semantic Protocol — 5-Node Mesh Demo  ║")
+	fmt.Println("╚══════════════════════════════════════════════╝")
+	fmt.Println()
+
+	faults := &faultInjector{}
+	store := &docStore{docs: map[string]string{"doc-1": document}}
+
+	// ── Mesh ──────────────────────────────────────────────────────────────────
+	fmt.Println("── Starting mesh ────────────────────────────────────────────────────")
+	relayAgent, relay := startAgent(ctx, "relay", []string{"summarise"}, nil)
+	defer relay.Close()
+	bootstrap := relay.AddrInfo()
+
+	llmAgent, llm := startAgent(ctx, "llm", []string{"summarise"}, &bootstrap)
+	defer llm.Close()
+	storageAgent, storage := startAgent(ctx, "storage", []string{"storage.read", "storage.write", "storage.index"}, &bootstrap)
+	defer storage.Close()
+	validatorAgent, validator := startAgent(ctx, "validator", []string{"validate"}, &bootstrap)
+	defer validator.Close()
+	coordinatorAgent, coordinator := startAgent(ctx, "coordinator", []string{"orchestration"}, &bootstrap)
+	defer coordinator.Close()
+
+	serve(relayAgent, relay, func(ctx context.Context, text string) (string, error) {
+		if err := faults.stall(ctx, "relay"); err != nil {
+			return "", err
+		}
+		return firstSentence(text), nil
+	})
+	serve(llmAgent, llm, func(ctx context.Context, text string) (string, error) {
+		if err := faults.stall(ctx, "llm"); err != nil {
+			return "", err
+		}
+		return model(text), nil
+	})
+	serve(storageAgent, storage, func(_ context.Context, payload string) (string, error) {
+		return store.handle(payload)
+	})
+	serve(validatorAgent, validator, func(_ context.Context, summary string) (string, error) {
+		if n := len(strings.Fields(summary)); n == 0 || n > 40 {
+			return "", fmt.Errorf("summary has %d words, want 1–40", n)
+		}
+		return summary, nil
+	})
+
+	// ── Discovery ─────────────────────────────────────────────────────────────
+	fmt.Println("\n── DHT discovery ────────────────────────────────────────────────────")
+	// Two summarisers are expected so the workflow has somewhere to re-route.
+	wanted := []struct {
+		capability string
+		peers      int
+	}{{"summarise", 2}, {"storage.read", 1}, {"storage.write", 1}, {"storage.index", 1}, {"validate", 1}}
+	handshaken := make(map[peer.ID]bool)
+	for _, w := range wanted {
+		infos, err := discover(ctx, coordinator, w.capability, w.peers)
+		if err != nil {
+			log.Fatalf("discover %q: %v", w.capability, err)
+		}
+		for _, info := range infos {
+			if handshaken[info.ID] {
+				continue
+			}
+			handshaken[info.ID] = true
+			result, err := p2p.DiscoverAndHandshake(ctx, coordinator, info)
+			if err != nil {
+				log.Fatalf("handshake %s: %v", info.ID.ShortString(), err)
+			}
+			fmt.Printf("  ✓ %-14s → %-9s caps=%v\n", w.capability, result.PeerAgentID, result.PeerCapabilities)
+		}
+	}
+
+	// ── Workflow ──────────────────────────────────────────────────────────────
+	embedder := embeddings.NewHashing(embeddings.DefaultDim)
+	vec := func(text string) []float32 {
+		v, err := embedder.Embed(ctx, text)
+		if err != nil {
+			log.Fatalf("embed: %v", err)
+		}
+		return v
+	}
+	graph, err := p2p.NewWorkflowGraph(
+		p2p.WorkflowStep{ID: "fetch", Capability: "storage.read",
+			IntentVector: vec("read a document"), Payload: "get doc-1"},
+		p2p.WorkflowStep{ID: "summarise", Capability: "summarise", DependsOn: []string{"fetch"},
+			IntentVector: vec("summarise a document"), Payload: "{{steps.fetch.output}}", Retries: 1},
+		p2p.WorkflowStep{ID: "index", Capability: "storage.index", DependsOn: []string{"fetch"},
+			IntentVector: vec("index document keywords"), Payload: "index doc-1"},
+		p2p.WorkflowStep{ID: "validate", Capability: "validate", DependsOn: []string{"summarise"},
+			IntentVector: vec("validate a summary"), Payload: "{{steps.summarise.output}}"},
+		p2p.WorkflowStep{ID: "store", Capability: "storage.write", DependsOn: []string{"validate", "index"},
+			IntentVector: vec("store a summary"), Payload: "put doc-1.summary {{steps.validate.output}}"},
+	)
+	if err != nil {
+		log.Fatalf("workflow graph: %v", err)
+	}
+	levels, _ := graph.Levels()
+	fmt.Println("\n── Running workflow ─────────────────────────────────────────────────")
+	fmt.Printf("  Levels: %v\n", levels)
+
+	orch := p2p.NewOrchestrator(coordinator, 2*time.Second)
+	orch.SetReplanPolicy(p2p.ReplanPolicy{MaxStepLatency: time.Second})
+	start := time.Now()
+	results, err := orch.RunGraph(ctx, "mesh-demo", graph)
+	elapsed := time.Since(start)
+	if err != nil {
+		log.Fatalf("workflow: %v", err)
+	}
+
+	// ── Results and metrics ───────────────────────────────────────────────────
+	fmt.Println("\n── Step results ─────────────────────────────────────────────────────")
+	for _, r := range results {
+		status := "✓"
+		if !r.Accepted {
+			status = "✗"
+		}
+		fmt.Printf("  %s %-10s by %-9s %s\n", status, r.StepID, r.AgentID, clip(r.Output, 60))
+	}
+
+	printMetrics(orch.History("mesh-demo"), elapsed)
+
+	fmt.Println("\n── Trust (coordinator's view) ───────────────────────────────────────")
+	for _, p := range coordinator.Discovery().All() {
+		fmt.Printf("  trust(coordinator → %-9s) = %.2f\n", p.AgentID,
+			coordinator.Trust().Get(coordinatorAgent.DID.String(), p.DID))
+	}
+
+	fmt.Printf("\n  Stored summary: %q\n", store.get("doc-1.summary"))
+	fmt.Println("\n✓ Demo complete.")
+}
+
+// startAgent creates an agent and its DHT-enabled host and advertises its
+// capabilities.  A nil bootstrap makes the host a bootstrap node.
+func startAgent(ctx context.Context, id string, caps []string, bootstrap *peer.AddrInfo) (*core.Agent, *p2p.AgentHost) {
+	agent, err := core.NewAgent(id, caps)
+	if err != nil {
+		log.Fatalf("create %s: %v", id, err)
+	}
+	opt := p2p.WithDHT()
+	if bootstrap != nil {
+		opt = p2p.WithDHT(*bootstrap)
+	}
+	h, err := p2p.NewHost(ctx, agent, opt)
+	if err != nil {
+		log.Fatalf("host %s: %v", id, err)
+	}
+	if err = h.AdvertiseCapabilities(ctx); err != nil {
+		log.Fatalf("advertise %s: %v", id, err)
+	}
+	fmt.Printf("  ✓ %-11s %s  caps=%v\n", id, h.PeerID().ShortString(), caps)
+	return agent, h
+}
+
+// serve answers streamed step intents on h by running w on the payload.
+func serve(agent *core.Agent, h *p2p.AgentHost, w work) {
+	accept := core.DefaultNegotiationHandler(agent)
+	h.OnStreamIntent(func(_ peer.ID, intent *core.IntentMessage) (*core.NegotiationResponse, p2p.StreamWorker) {
+		resp, err := accept(intent)
+		if err != nil || !resp.Accepted {
+			return resp, nil
+		}
+		return resp, func(ctx context.Context, send func(*core.WorkflowMessage) error) error {
+			out, err := w(ctx, intent.Payload)
+			if err != nil {
+				return err
+			}
+			return send(core.NewProgress(agent, intent.ID, intent.Metadata["step_id"], core.ProgressStepCompleted, out))
+		}
+	})
+}
+
+// discover polls the DHT until at least want peers advertise capability.
+func discover(ctx context.Context, h *p2p.AgentHost, capability string, want int) ([]peer.AddrInfo, error) {
+	for {
+		infos, err := h.DiscoverPeers(ctx, capability)
+		if err == nil && len(infos) >= want {
+			return infos, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("no peer found: %w", ctx.Err())
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
+
+// printMetrics prints per-step latency and re-planning from the workflow
+// history.
+func printMetrics(history []p2p.WorkflowEvent, elapsed time.Duration) {
+	fmt.Println("\n── Metrics ──────────────────────────────────────────────────────────")
+	sent := make(map[string]time.Time)
+	attempts := make(map[string]int)
+	replans := 0
+	for _, ev := range history {
+		switch ev.Kind {
+		case p2p.WorkflowStepSent:
+			attempts[ev.StepID]++
+			sent[ev.StepID+"/"+ev.AgentID] = ev.Timestamp
+		case p2p.WorkflowStepDone:
+			took := ev.Timestamp.Sub(sent[ev.StepID+"/"+ev.AgentID])
+			fmt.Printf("  %-10s %-9s %8s  attempts=%d\n", ev.StepID, ev.AgentID,
+				took.Round(time.Millisecond), attempts[ev.StepID])
+		case p2p.WorkflowReplanned:
+			replans++
+			fmt.Printf("  ↻ re-planned away from %s: %s\n", ev.AgentID, ev.Reason)
+		}
+	}
+	fmt.Printf("  steps=%d  replans=%d  wall=%s\n", len(attempts), replans, elapsed.Round(time.Millisecond))
+}
+
+// model stands in for an LLM call: it keeps the two most informative
+// sentences of text.
+func model(text string) string {
+	sentences := strings.SplitAfter(text, ". ")
+	if len(sentences) > 2 {
+		sentences = sentences[:2]
+	}
+	return strings.TrimSpace(strings.Join(sentences, ""))
+}
+
+// firstSentence is the relay's cheap fallback summariser.
+func firstSentence(text string) string {
+	if i := strings.Index(text, ". "); i >= 0 {
+		return text[:i+1]
+	}
+	return text
+}
+
+func clip(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n-1] + "…"
+}
+
+// docStore is the storage agent's backing store.  Payloads are
+// "get KEY", "put KEY VALUE" or "index KEY".
+type docStore struct {
+	mu   sync.Mutex
+	docs map[string]string
+}
+
+func (s *docStore) handle(payload string) (string, error) {
+	op, rest, _ := strings.Cut(payload, " ")
+	key, value, _ := strings.Cut(rest, " ")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch op {
+	case "get":
+		doc, ok := s.docs[key]
+		if !ok {
+			return "", fmt.Errorf("no document %q", key)
+		}
+		return doc, nil
+	case "put":
+		s.docs[key] = value
+		return "stored " + key, nil
+	case "index":
+		words := make(map[string]bool)
+		for _, w := range strings.Fields(strings.ToLower(s.docs[key])) {
+			if w = strings.Trim(w, ".,"); len(w) > 7 {
+				words[w] = true
+			}
+		}
+		keys := make([]string, 0, len(words))
+		for w := range words {
+			keys = append(keys, w)
+		}
+		sort.Strings(keys)
+		return strings.Join(keys, ","), nil
+	}
+	return "", fmt.Errorf("unknown operation %q", op)
+}
+
+func (s *docStore) get(key string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.docs[key]
+}
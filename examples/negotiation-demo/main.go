@@ -15,6 +15,7 @@ import (
 
 	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/embeddings"
 	"github.com/olserra/agent-semantic-protocol/p2p"
 )
 
@@ -119,28 +120,25 @@ func main() {
 	}
 
 	// ── Build a semantic intent ───────────────────────────────────────────────
-	// This vector represents "generate Python code from natural language".
-	// In production, derive this from a sentence-transformer model.
-	intentVector := []float32{
-		0.82, 0.14, 0.76, 0.05, 0.91,
-		0.33, 0.67, 0.48, 0.21, 0.88,
-	}
+	// The hashing embedder needs no model; swap in embeddings.NewOpenAI or
+	// embeddings.NewOllama for real semantic vectors.
+	const goal = "Write a Python function to merge two sorted lists into one sorted list."
 
 	fmt.Println("\n── Negotiation phase ────────────────────────────────────────────────")
 	fmt.Println("  Requester sends intent: 'Generate Python from natural language'")
-	fmt.Printf("  Intent vector (10-dim): %.2f\n", intentVector)
-	fmt.Println()
 
 	// ── Send intent to code-agent ─────────────────────────────────────────────
-	intent, err := core.CreateIntent(
+	intent, err := core.CreateIntentFromText(ctx,
 		requester,
-		intentVector,
+		embeddings.NewHashing(embeddings.DefaultDim),
 		[]string{"code-generation", "python"},
-		"Write a Python function to merge two sorted lists into one sorted list.",
+		goal,
 	)
 	if err != nil {
 		log.Fatalf("create intent: %v", err)
 	}
+	fmt.Printf("  Intent vector (%d-dim, first 5): %.2f\n", len(intent.IntentVector), intent.IntentVector[:5])
+	fmt.Println()
 
 	resp, err := hostR.SendIntent(ctx, hostC.PeerID(), intent)
 	if err != nil {
@@ -167,7 +165,7 @@ func main() {
 	bus := core.NewNegotiationBus()
 	bus.Register("code-agent-local", core.DefaultNegotiationHandler(codeAgent))
 
-	localIntent, _ := core.CreateIntent(requester, intentVector,
+	localIntent, _ := core.CreateIntent(requester, intent.IntentVector,
 		[]string{"code-generation"}, "local negotiation test")
 	localResp, err := bus.Negotiate("code-agent-local", localIntent)
 	if err != nil {
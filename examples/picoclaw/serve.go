@@ -0,0 +1,216 @@
+package picoclaw
+
+// serve.go — Running a Picoclaw service as a full mesh peer.
+//
+// Client only bridges outwards: an ASP agent calls into Picoclaw.  Serve
+// goes the other way.  It gives the service its own core.Agent and
+// p2p.AgentHost, answers handshakes with the capabilities the service
+// currently reports on GET /v1/capabilities, and answers incoming intents by
+// proxying them to POST /v1/intent.
+//
+//	peer, err := picoclaw.NewClient("http://localhost:8080").Serve(ctx, picoclaw.ServeConfig{})
+//	defer peer.Close()
+//	fmt.Println(peer.Host().AddrInfo())
+//
+// The capability set is refreshed every RefreshInterval.  Peers that
+// handshake after a change see the new set; intents are always checked
+// against the latest one.
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+// DefaultRefreshInterval is how often Serve re-reads the service's
+// capabilities when ServeConfig.RefreshInterval is zero.
+const DefaultRefreshInterval = 30 * time.Second
+
+// ServeConfig configures Serve.  The zero value is usable.
+type ServeConfig struct {
+	// Agent is the peer's identity; a fresh one named after the client's
+	// agent ID is created if nil.
+	Agent *core.Agent
+	// RefreshInterval is how often capabilities are re-read from the
+	// service (DefaultRefreshInterval if 0).
+	RefreshInterval time.Duration
+	// IntentTimeout bounds each proxied POST /v1/intent (the client's HTTP
+	// timeout if 0).
+	IntentTimeout time.Duration
+	// HostOptions are passed to p2p.NewHost.
+	HostOptions []p2p.HostOption
+	// OnRefreshError is called when a capability refresh fails; the previous
+	// set stays in force.
+	OnRefreshError func(error)
+}
+
+// Peer is a Picoclaw service running as a mesh peer.
+type Peer struct {
+	client *Client
+	agent  *core.Agent
+	host   *p2p.AgentHost
+	cfg    ServeConfig
+
+	mu   sync.RWMutex
+	caps []string
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// Serve fetches the service's capabilities, starts a host for it and keeps
+// the capabilities in sync until ctx is cancelled or the Peer is closed.
+// It fails if the service cannot be reached.
+func (c *Client) Serve(ctx context.Context, cfg ServeConfig) (*Peer, error) {
+	if cfg.RefreshInterval <= 0 {
+		cfg.RefreshInterval = DefaultRefreshInterval
+	}
+	caps, err := c.FetchCapabilities(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("picoclaw Serve: %w", err)
+	}
+
+	agent := cfg.Agent
+	if agent == nil {
+		agent, err = core.NewAgent(c.agentID, nil)
+		if err != nil {
+			return nil, fmt.Errorf("picoclaw Serve: %w", err)
+		}
+	}
+	// The host reads agent.Capabilities without locking, so give it a copy
+	// and keep the live set in the Peer.
+	hostAgent := *agent
+	hostAgent.Capabilities = append([]string(nil), caps.Capabilities...)
+
+	host, err := p2p.NewHost(ctx, &hostAgent, cfg.HostOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("picoclaw Serve: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	p := &Peer{
+		client: c,
+		agent:  &hostAgent,
+		host:   host,
+		cfg:    cfg,
+		caps:   hostAgent.Capabilities,
+		cancel: cancel,
+	}
+	host.OnHandshake(p.handshake)
+	host.OnIntentContext(p.intent)
+
+	p.wg.Add(1)
+	go p.refreshLoop(ctx)
+	return p, nil
+}
+
+// Host returns the peer's host.
+func (p *Peer) Host() *p2p.AgentHost { return p.host }
+
+// Agent returns the peer's identity.
+func (p *Peer) Agent() *core.Agent { return p.agent }
+
+// Capabilities returns the capabilities last reported by the service.
+func (p *Peer) Capabilities() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return append([]string(nil), p.caps...)
+}
+
+// Refresh re-reads the service's capabilities now.
+func (p *Peer) Refresh(ctx context.Context) error {
+	resp, err := p.client.FetchCapabilities(ctx)
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	p.caps = append([]string(nil), resp.Capabilities...)
+	p.mu.Unlock()
+	return nil
+}
+
+// Close stops the refresh loop and the host.
+func (p *Peer) Close() error {
+	p.cancel()
+	p.wg.Wait()
+	return p.host.Close()
+}
+
+func (p *Peer) refreshLoop(ctx context.Context) {
+	defer p.wg.Done()
+	t := time.NewTicker(p.cfg.RefreshInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		if err := p.Refresh(ctx); err != nil && ctx.Err() == nil && p.cfg.OnRefreshError != nil {
+			p.cfg.OnRefreshError(err)
+		}
+	}
+}
+
+// current returns a copy of the agent carrying the live capability set.
+func (p *Peer) current() *core.Agent {
+	a := *p.agent
+	a.Capabilities = p.Capabilities()
+	return &a
+}
+
+// handshake answers with the live capability set.
+func (p *Peer) handshake(_ peer.ID, msg *core.HandshakeMessage) *core.HandshakeMessage {
+	resp, err := core.RespondHandshake(p.current(), msg)
+	if err != nil {
+		return nil // Fall back to the host's own handling.
+	}
+	return resp
+}
+
+// intent rejects intents the service cannot serve and proxies the rest to
+// POST /v1/intent.
+func (p *Peer) intent(ctx context.Context, _ peer.ID, msg *core.IntentMessage) *core.NegotiationResponse {
+	// The default handler checks capabilities and expiry against the live set.
+	if gate, _ := core.DefaultNegotiationHandler(p.current())(msg); gate != nil && !gate.Accepted {
+		return gate
+	}
+
+	if p.cfg.IntentTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.cfg.IntentTimeout)
+		defer cancel()
+	}
+	resp, err := p.client.SendIntent(ctx, msg)
+	if err != nil {
+		return p.reject(msg, "picoclaw unavailable: "+err.Error())
+	}
+	resp.AgentID = p.agent.ID
+	resp.DID = p.agent.DID.String()
+	p.sign(resp)
+	return resp
+}
+
+func (p *Peer) reject(msg *core.IntentMessage, reason string) *core.NegotiationResponse {
+	resp := &core.NegotiationResponse{
+		RequestID: msg.ID,
+		AgentID:   p.agent.ID,
+		DID:       p.agent.DID.String(),
+		Accepted:  false,
+		Reason:    reason,
+		Timestamp: time.Now().UnixNano(),
+	}
+	p.sign(resp)
+	return resp
+}
+
+// sign signs resp as core.DefaultNegotiationHandler does, so initiators
+// requiring signatures accept proxied answers.
+func (p *Peer) sign(resp *core.NegotiationResponse) {
+	_ = core.SignResponse(p.agent, resp)
+}
@@ -0,0 +1,154 @@
+package picoclaw_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/examples/picoclaw"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+// fakeService is a Picoclaw API with a settable capability set that accepts
+// every intent it is sent.
+type fakeService struct {
+	mu       sync.Mutex
+	caps     []string
+	requests []picoclaw.IntentRequest
+	fetches  atomic.Int64
+}
+
+func (s *fakeService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch r.URL.Path {
+	case "/v1/capabilities":
+		s.fetches.Add(1)
+		_ = json.NewEncoder(w).Encode(picoclaw.CapabilitiesResponse{AgentID: "pico", Capabilities: s.caps})
+	case "/v1/intent":
+		var req picoclaw.IntentRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.requests = append(s.requests, req)
+		_ = json.NewEncoder(w).Encode(picoclaw.IntentResponse{Accepted: true, WorkflowSteps: []string{"ocr:" + req.Payload}})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *fakeService) setCaps(caps ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.caps = caps
+}
+
+func (s *fakeService) served() []picoclaw.IntentRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.requests)
+}
+
+// TestServe verifies that a served Picoclaw peer handshakes with the
+// service's capabilities, proxies an intent to it and answers with a signed
+// response, follows capability changes, and stops on Close.
+func TestServe(t *testing.T) {
+	svc := &fakeService{caps: []string{"ocr"}}
+	srv := httptest.NewServer(svc)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	p, err := picoclaw.NewClient(srv.URL, picoclaw.WithAgentID("pico")).Serve(ctx, picoclaw.ServeConfig{
+		RefreshInterval: 20 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+	closed := false
+	defer func() {
+		if !closed {
+			_ = p.Close()
+		}
+	}()
+
+	alpha, _ := core.NewAgent("alpha", nil)
+	caller, err := p2p.NewHost(ctx, alpha)
+	if err != nil {
+		t.Fatalf("NewHost: %v", err)
+	}
+	defer caller.Close()
+
+	hs, err := p2p.DiscoverAndHandshake(ctx, caller, p.Host().AddrInfo())
+	if err != nil {
+		t.Fatalf("DiscoverAndHandshake: %v", err)
+	}
+	if hs.PeerDID != p.Agent().DID.String() || !slices.Contains(hs.PeerCapabilities, "ocr") {
+		t.Errorf("handshake: %+v", hs)
+	}
+
+	intent, _ := core.CreateIntent(alpha, nil, []string{"ocr"}, "scan.png")
+	resp, err := caller.SendIntent(ctx, p.Host().PeerID(), intent)
+	if err != nil {
+		t.Fatalf("SendIntent: %v", err)
+	}
+	if !resp.Accepted || resp.RequestID != intent.ID || !slices.Equal(resp.WorkflowSteps, []string{"ocr:scan.png"}) {
+		t.Errorf("response: %+v", resp)
+	}
+	if resp.DID != p.Agent().DID.String() || !core.VerifyResponseSignature(resp, p.Agent().PublicKey()) {
+		t.Error("response is not signed by the peer")
+	}
+	if got := svc.served(); len(got) != 1 || got[0].DID != alpha.DID.String() || got[0].Payload != "scan.png" {
+		t.Errorf("service received %+v", got)
+	}
+
+	// The service drops ocr: the peer refuses without calling it.
+	svc.setCaps("translate")
+	for !slices.Equal(p.Capabilities(), []string{"translate"}) {
+		select {
+		case <-ctx.Done():
+			t.Fatalf("capabilities never refreshed: %v", p.Capabilities())
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	again, _ := core.CreateIntent(alpha, nil, []string{"ocr"}, "scan.png")
+	if resp, err = caller.SendIntent(ctx, p.Host().PeerID(), again); err != nil || resp.Accepted {
+		t.Errorf("intent for a dropped capability: %+v, %v", resp, err)
+	}
+	if n := len(svc.served()); n != 1 {
+		t.Errorf("service called %d times, want 1", n)
+	}
+
+	if err = p.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	closed = true
+	fetches := svc.fetches.Load()
+	time.Sleep(100 * time.Millisecond)
+	if n := svc.fetches.Load(); n != fetches {
+		t.Errorf("capabilities fetched %d times after Close", n-fetches)
+	}
+	short, scancel := context.WithTimeout(ctx, time.Second)
+	defer scancel()
+	last, _ := core.CreateIntent(alpha, nil, []string{"translate"}, "hola")
+	if _, err = caller.SendIntent(short, p.Host().PeerID(), last); err == nil {
+		t.Error("closed peer answered an intent")
+	}
+}
+
+func TestServeUnreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	if _, err := picoclaw.NewClient(srv.URL).Serve(context.Background(), picoclaw.ServeConfig{}); err == nil {
+		t.Error("Serve succeeded without a service")
+	}
+}
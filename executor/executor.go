@@ -0,0 +1,305 @@
+// Package executor runs workflow actions locally: allowlisted binaries,
+// container images, or WASM modules, each mapped from a capability name.
+//
+// Negotiation only produces a plan; an Executor turns the plan's steps into
+// executed work.  Every action must pass a strict Policy allowlist both when
+// it is registered and again when it runs, so a policy tightened at runtime
+// takes effect immediately.  Commands are executed directly, never through a
+// shell, with the step payload on stdin, a scrubbed environment, a timeout,
+// and bounded output capture.
+package executor
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+// Kind selects how an Action is executed.
+type Kind int
+
+const (
+	KindCommand   Kind = iota // Run an allowlisted binary directly
+	KindContainer             // Run an allowlisted image with the container runtime
+	KindWASM                  // Run an allowlisted module with the WASM runtime
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindCommand:
+		return "command"
+	case KindContainer:
+		return "container"
+	case KindWASM:
+		return "wasm"
+	}
+	return "Kind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// Limits bounds one execution.  Zero values fall back to DefaultLimits.
+// MemoryMB and CPUs are enforced by the container and WASM runtimes only;
+// use KindContainer when a command needs memory isolation.
+type Limits struct {
+	Timeout   time.Duration
+	MaxOutput int     // Bytes of stdout/stderr kept each; the rest is discarded
+	MemoryMB  int     // Container/WASM memory ceiling
+	CPUs      float64 // Container CPU quota
+}
+
+// DefaultLimits apply to any Limits field left at zero.
+var DefaultLimits = Limits{Timeout: 30 * time.Second, MaxOutput: 1 << 20}
+
+// killGrace is how long Run waits for output pipes to close after the
+// action is killed, in case a straggler still holds them.
+const killGrace = 2 * time.Second
+
+// Action maps a capability to something runnable.
+type Action struct {
+	Capability string
+	Kind       Kind
+	Target     string   // Binary path, image reference, or .wasm path
+	Args       []string // Extra arguments
+	Env        []string // Complete environment ("KEY=value"); nothing is inherited
+	Dir        string   // Working directory for KindCommand
+	Limits     Limits
+}
+
+// Policy is the allowlist every Action must satisfy.  Targets are matched
+// exactly; there is no globbing.
+type Policy struct {
+	Commands         []string // Absolute binary paths
+	Images           []string // Container image references, ideally pinned by digest
+	Modules          []string // Absolute .wasm paths
+	ContainerRuntime string   // Default "docker"
+	WASMRuntime      string   // Default "wasmtime"
+}
+
+// ErrNotAllowed is returned when an action's target is not in the Policy.
+var ErrNotAllowed = errors.New("executor: target not allowed by policy")
+
+// Executor runs registered actions under a Policy.
+type Executor struct {
+	mu      sync.RWMutex
+	policy  Policy
+	actions map[string]Action
+}
+
+// New creates an Executor with the given allowlist.
+func New(policy Policy) *Executor {
+	if policy.ContainerRuntime == "" {
+		policy.ContainerRuntime = "docker"
+	}
+	if policy.WASMRuntime == "" {
+		policy.WASMRuntime = "wasmtime"
+	}
+	return &Executor{policy: policy, actions: make(map[string]Action)}
+}
+
+// SetPolicy replaces the allowlist.  Registered actions that no longer
+// satisfy it fail at Run time.
+func (e *Executor) SetPolicy(p Policy) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if p.ContainerRuntime == "" {
+		p.ContainerRuntime = e.policy.ContainerRuntime
+	}
+	if p.WASMRuntime == "" {
+		p.WASMRuntime = e.policy.WASMRuntime
+	}
+	e.policy = p
+}
+
+// Register maps a.Capability to a.  The target must be allowed by the policy.
+func (e *Executor) Register(a Action) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if a.Capability == "" {
+		return fmt.Errorf("executor: action has no capability")
+	}
+	if err := e.policy.allows(a); err != nil {
+		return err
+	}
+	e.actions[a.Capability] = a
+	return nil
+}
+
+// Capabilities lists the capabilities with a registered action.
+func (e *Executor) Capabilities() []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	out := make([]string, 0, len(e.actions))
+	for c := range e.actions {
+		out = append(out, c)
+	}
+	return out
+}
+
+// Run executes the action registered for step.Capability with step.Payload
+// on stdin.  A non-zero exit is reported in the StepResult, not as an error;
+// errors mean the step could not be run at all.
+func (e *Executor) Run(ctx context.Context, step p2p.WorkflowStep) (p2p.StepResult, error) {
+	e.mu.RLock()
+	a, ok := e.actions[step.Capability]
+	policy := e.policy
+	e.mu.RUnlock()
+	if !ok {
+		return p2p.StepResult{}, fmt.Errorf("executor: no action for capability %q", step.Capability)
+	}
+	if err := policy.allows(a); err != nil {
+		return p2p.StepResult{}, err
+	}
+
+	lim := a.Limits.withDefaults()
+	runCtx, cancel := context.WithTimeout(ctx, lim.Timeout)
+	defer cancel()
+
+	var container string
+	if a.Kind == KindContainer {
+		container = containerName()
+	}
+	cmd := exec.CommandContext(runCtx, policy.runtime(a.Kind, a.Target), commandArgs(a, lim, container)...)
+	cmd.Env = append([]string{}, a.Env...)
+	if a.Kind == KindCommand {
+		cmd.Dir = a.Dir
+	}
+	// Run in a process group of its own and kill all of it on cancel, so
+	// children the action spawned do not outlive it.  Killing the container
+	// client leaves the container running; it is killed by name.
+	cmd.SysProcAttr = newProcessGroup()
+	cmd.Cancel = func() error {
+		if container != "" {
+			_ = exec.Command(policy.ContainerRuntime, "kill", container).Run()
+		}
+		return killProcessGroup(cmd)
+	}
+	cmd.WaitDelay = killGrace
+	cmd.Stdin = strings.NewReader(step.Payload)
+	stdout := &cappedBuffer{max: lim.MaxOutput}
+	stderr := &cappedBuffer{max: lim.MaxOutput}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+
+	err := cmd.Run()
+	res := p2p.StepResult{
+		StepID:    step.ID,
+		Output:    stdout.String(),
+		Timestamp: time.Now(),
+	}
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		res.Accepted = true
+		res.Reason = "completed"
+	case runCtx.Err() == context.DeadlineExceeded:
+		res.ExitCode = -1
+		res.Reason = fmt.Sprintf("timed out after %s", lim.Timeout)
+	case errors.As(err, &exitErr):
+		res.ExitCode = exitErr.ExitCode()
+		res.Reason = strings.TrimSpace(fmt.Sprintf("exit status %d: %s", res.ExitCode, stderr.String()))
+	default:
+		return p2p.StepResult{}, fmt.Errorf("executor: run %s %s: %w", a.Kind, a.Target, err)
+	}
+	return res, nil
+}
+
+// ------------------------------------------------------------------ helpers
+
+func (p Policy) allows(a Action) error {
+	var list []string
+	switch a.Kind {
+	case KindCommand:
+		list = p.Commands
+	case KindContainer:
+		list = p.Images
+	case KindWASM:
+		list = p.Modules
+	default:
+		return fmt.Errorf("executor: unknown action kind %v", a.Kind)
+	}
+	for _, t := range list {
+		if t == a.Target {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s %q", ErrNotAllowed, a.Kind, a.Target)
+}
+
+// runtime returns the program to exec for kind.
+func (p Policy) runtime(kind Kind, target string) string {
+	switch kind {
+	case KindContainer:
+		return p.ContainerRuntime
+	case KindWASM:
+		return p.WASMRuntime
+	}
+	return target
+}
+
+// commandArgs builds the argument list, including runtime resource flags.
+// container names the container for KindContainer.
+func commandArgs(a Action, lim Limits, container string) []string {
+	switch a.Kind {
+	case KindContainer:
+		args := []string{"run", "--rm", "-i", "--network=none", "--read-only", "--name=" + container}
+		if lim.MemoryMB > 0 {
+			args = append(args, "--memory="+strconv.Itoa(lim.MemoryMB)+"m")
+		}
+		if lim.CPUs > 0 {
+			args = append(args, "--cpus="+strconv.FormatFloat(lim.CPUs, 'f', -1, 64))
+		}
+		return append(append(args, a.Target), a.Args...)
+	case KindWASM:
+		args := []string{"run"}
+		if lim.MemoryMB > 0 {
+			args = append(args, "-W", "max-memory-size="+strconv.Itoa(lim.MemoryMB<<20))
+		}
+		return append(append(args, a.Target), a.Args...)
+	}
+	return a.Args
+}
+
+// containerName returns a fresh name for a container Run starts.
+func containerName() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return "asp-exec-" + hex.EncodeToString(b[:])
+}
+
+func (l Limits) withDefaults() Limits {
+	if l.Timeout <= 0 {
+		l.Timeout = DefaultLimits.Timeout
+	}
+	if l.MaxOutput <= 0 {
+		l.MaxOutput = DefaultLimits.MaxOutput
+	}
+	return l
+}
+
+// cappedBuffer keeps the first max bytes written and silently drops the rest,
+// so a chatty process cannot exhaust the agent's memory.
+type cappedBuffer struct {
+	buf bytes.Buffer
+	max int
+}
+
+func (c *cappedBuffer) Write(p []byte) (int, error) {
+	if room := c.max - c.buf.Len(); room > 0 {
+		if len(p) > room {
+			c.buf.Write(p[:room])
+		} else {
+			c.buf.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+func (c *cappedBuffer) String() string { return c.buf.String() }
@@ -0,0 +1,82 @@
+package executor_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/executor"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+func TestRegisterRejectsUnlistedTarget(t *testing.T) {
+	e := executor.New(executor.Policy{Commands: []string{"/bin/cat"}})
+	err := e.Register(executor.Action{Capability: "rm", Kind: executor.KindCommand, Target: "/bin/rm"})
+	if !errors.Is(err, executor.ErrNotAllowed) {
+		t.Fatalf("expected ErrNotAllowed, got %v", err)
+	}
+}
+
+func TestRunCapturesOutput(t *testing.T) {
+	e := executor.New(executor.Policy{Commands: []string{"/bin/cat"}})
+	if err := e.Register(executor.Action{Capability: "echo", Kind: executor.KindCommand, Target: "/bin/cat",
+		Limits: executor.Limits{MaxOutput: 5}}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	res, err := e.Run(context.Background(), p2p.WorkflowStep{ID: "s1", Capability: "echo", Payload: "hello world"})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if !res.Accepted || res.ExitCode != 0 {
+		t.Errorf("expected success, got %+v", res)
+	}
+	if res.Output != "hello" {
+		t.Errorf("output should be capped to 5 bytes, got %q", res.Output)
+	}
+}
+
+func TestRunTimeoutAndPolicyChange(t *testing.T) {
+	e := executor.New(executor.Policy{Commands: []string{"/bin/sleep"}})
+	if err := e.Register(executor.Action{Capability: "wait", Kind: executor.KindCommand, Target: "/bin/sleep",
+		Args: []string{"5"}, Limits: executor.Limits{Timeout: 50 * time.Millisecond}}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	res, err := e.Run(context.Background(), p2p.WorkflowStep{ID: "s1", Capability: "wait"})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if res.Accepted || !strings.Contains(res.Reason, "timed out") {
+		t.Errorf("expected timeout, got %+v", res)
+	}
+
+	e.SetPolicy(executor.Policy{})
+	if _, err = e.Run(context.Background(), p2p.WorkflowStep{ID: "s2", Capability: "wait"}); !errors.Is(err, executor.ErrNotAllowed) {
+		t.Errorf("expected ErrNotAllowed after policy change, got %v", err)
+	}
+}
+
+func TestRunTimeoutKillsChildren(t *testing.T) {
+	e := executor.New(executor.Policy{Commands: []string{"/bin/sh"}})
+	// The child sleep inherits stdout; Run only returns promptly if the
+	// whole process group is killed, not just the shell.
+	if err := e.Register(executor.Action{Capability: "spawn", Kind: executor.KindCommand, Target: "/bin/sh",
+		Args: []string{"-c", "sleep 5 & wait"}, Limits: executor.Limits{Timeout: 50 * time.Millisecond}}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	start := time.Now()
+	res, err := e.Run(context.Background(), p2p.WorkflowStep{ID: "s1", Capability: "spawn"})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if res.Accepted || !strings.Contains(res.Reason, "timed out") {
+		t.Errorf("expected timeout, got %+v", res)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Run took %s: the child outlived the timeout", d)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package executor
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+func newProcessGroup() *syscall.SysProcAttr { return nil }
+
+// killProcessGroup kills cmd; without process groups its children survive.
+func killProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
@@ -0,0 +1,120 @@
+package gateway
+
+// events.go — GET /events: the host's event stream for monitoring agents.
+//
+// The stream is Server-Sent Events.  Each host event (see p2p.Subscribe)
+// is one message whose event name is its kind and whose data is an
+// EventRecord:
+//
+//	event: trust_updated
+//	data: {"kind":"trust_updated","did":"did:...","delta":0.1,"trust":0.6,...}
+//
+// ?kind=a,b restricts the stream to those kinds.  A client that reads too
+// slowly loses events rather than stall the host; the next message is then
+// preceded by a ": dropped N events" comment.  An idle stream carries a
+// ": keepalive" comment every eventKeepalive.
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+// eventBuffer is how many events a slow client may fall behind by before
+// events are dropped.
+const eventBuffer = 256
+
+// eventKeepalive is how often an idle event stream sends a comment, so
+// proxies do not close it.
+const eventKeepalive = 30 * time.Second
+
+// EventRecord is one message of GET /events.
+type EventRecord struct {
+	Kind         p2p.EventKind `json:"kind"`
+	PeerID       string        `json:"peer_id,omitempty"`
+	AgentID      string        `json:"agent_id,omitempty"`
+	DID          string        `json:"did,omitempty"`
+	IntentID     string        `json:"intent_id,omitempty"`
+	Capabilities []string      `json:"capabilities,omitempty"`
+	Reason       string        `json:"reason,omitempty"`
+	Delta        float32       `json:"delta,omitempty"`
+	Trust        float32       `json:"trust,omitempty"`
+	Timestamp    time.Time     `json:"timestamp"`
+}
+
+func eventRecord(ev p2p.Event) EventRecord {
+	rec := EventRecord{
+		Kind:         ev.Kind,
+		AgentID:      ev.AgentID,
+		DID:          ev.DID,
+		IntentID:     ev.IntentID,
+		Capabilities: ev.Capabilities,
+		Reason:       ev.Reason,
+		Delta:        ev.Delta,
+		Trust:        ev.Trust,
+		Timestamp:    ev.Timestamp,
+	}
+	if ev.PeerID != "" {
+		rec.PeerID = ev.PeerID.String()
+	}
+	return rec
+}
+
+// handleEvents streams the host's events until the client goes away.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+	var kinds []p2p.EventKind
+	for _, k := range strings.Split(r.URL.Query().Get("kind"), ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			kinds = append(kinds, p2p.EventKind(k))
+		}
+	}
+
+	events := make(chan p2p.Event, eventBuffer)
+	var dropped atomic.Int64
+	unsubscribe := s.host.Subscribe(func(ev p2p.Event) {
+		select {
+		case events <- ev:
+		default:
+			dropped.Add(1)
+		}
+	}, kinds...)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepalive := time.NewTicker(eventKeepalive)
+	defer keepalive.Stop()
+	for {
+		var err error
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepalive.C:
+			_, err = fmt.Fprint(w, ": keepalive\n\n")
+		case ev := <-events:
+			if n := dropped.Swap(0); n > 0 {
+				_, _ = fmt.Fprintf(w, ": dropped %d events\n\n", n)
+			}
+			data, _ := json.Marshal(eventRecord(ev))
+			_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Kind, data)
+		}
+		if err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
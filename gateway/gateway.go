@@ -0,0 +1,400 @@
+// Package gateway exposes an AgentHost over HTTP/JSON, so applications and
+// dashboards that do not link libp2p can drive an agent.
+//
+//	POST /intents                send an intent, to a given peer or the best match
+//	GET  /peers                  peers this agent has completed a handshake with
+//	GET  /trust                  the agent's trust graph
+//	GET  /topology               the mesh around the agent (see p2p.Topology)
+//	GET  /analytics              capability usage over ?window=1m, 1h (default) or 1d
+//	POST /handshake/{peerID}     connect to (optionally) and handshake with a peer
+//	GET  /quarantine             peers with errors in their budget or in quarantine
+//	DELETE /quarantine/{peerID}  lift a peer's quarantine
+//	GET  /events                 host events as Server-Sent Events (see EventRecord)
+//
+// Errors are returned as {"error": "..."} with a 4xx or 5xx status.  With
+// WithToken every request must carry "Authorization: Bearer <token>".
+// WithObserverToken adds a second, read-only token for monitoring agents:
+// it is accepted for GET requests, including the event stream, and refused
+// with 403 for anything that acts, such as sending an intent.  It is the
+// HTTP counterpart of the host's observer role (see p2p.WithObservers).
+//
+//	gw := gateway.New(host, gateway.WithToken(os.Getenv("ASP_GATEWAY_TOKEN")))
+//	log.Fatal(http.ListenAndServe("127.0.0.1:8080", gw))
+package gateway
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+// DefaultRequestTimeout bounds the network work behind one HTTP request.
+const DefaultRequestTimeout = 30 * time.Second
+
+// maxBodyBytes caps request bodies.
+const maxBodyBytes = 1 << 20
+
+// Server is an http.Handler serving the gateway API for one host.
+type Server struct {
+	host     *p2p.AgentHost
+	token    string
+	observer string // read-only token; see WithObserverToken
+	timeout  time.Duration
+	mux      *http.ServeMux
+}
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithToken requires every request to carry token as a bearer token.
+// An empty token disables authentication.
+func WithToken(token string) Option {
+	return func(s *Server) { s.token = token }
+}
+
+// WithObserverToken lets requests carrying token as a bearer token read
+// the API and stream events, but not send intents, handshake or change
+// state.  With an observer token and no WithToken, the gateway is
+// read-only.  An empty token adds no observer.
+func WithObserverToken(token string) Option {
+	return func(s *Server) { s.observer = token }
+}
+
+// WithRequestTimeout overrides DefaultRequestTimeout.
+func WithRequestTimeout(d time.Duration) Option {
+	return func(s *Server) { s.timeout = d }
+}
+
+// New creates a gateway for host.
+func New(host *p2p.AgentHost, opts ...Option) *Server {
+	s := &Server{
+		host:    host,
+		timeout: DefaultRequestTimeout,
+		mux:     http.NewServeMux(),
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	s.mux.HandleFunc("POST /intents", s.handleIntent)
+	s.mux.HandleFunc("GET /peers", s.handlePeers)
+	s.mux.HandleFunc("GET /trust", s.handleTrust)
+	s.mux.HandleFunc("GET /topology", s.handleTopology)
+	s.mux.HandleFunc("GET /analytics", s.handleAnalytics)
+	s.mux.HandleFunc("POST /handshake/{peerID}", s.handleHandshake)
+	s.mux.HandleFunc("GET /quarantine", s.handleQuarantine)
+	s.mux.HandleFunc("DELETE /quarantine/{peerID}", s.handleUnquarantine)
+	s.mux.HandleFunc("GET /events", s.handleEvents)
+	return s
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.token != "" || s.observer != "" {
+		got := r.Header.Get("Authorization")
+		switch {
+		case bearer(got, s.token):
+		case bearer(got, s.observer):
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				writeError(w, http.StatusForbidden, errors.New("observer token is read-only"))
+				return
+			}
+		default:
+			writeError(w, http.StatusUnauthorized, errors.New("missing or invalid bearer token"))
+			return
+		}
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// bearer reports whether header carries token, which must be set.
+func bearer(header, token string) bool {
+	return token != "" && subtle.ConstantTimeCompare([]byte(header), []byte("Bearer "+token)) == 1
+}
+
+// ------------------------------------------------------------------ API types
+
+// IntentRequest is the body of POST /intents.  Without PeerID the intent
+// goes to the known peer offering Capabilities whose profile best matches
+// IntentVector.
+type IntentRequest struct {
+	PeerID       string            `json:"peer_id,omitempty"`
+	Capabilities []string          `json:"capabilities"`
+	Payload      string            `json:"payload,omitempty"`
+	IntentVector []float32         `json:"intent_vector,omitempty"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+	TTLSeconds   int64             `json:"ttl_seconds,omitempty"`
+}
+
+// IntentResult is the response to POST /intents.
+type IntentResult struct {
+	IntentID string                    `json:"intent_id"`
+	PeerID   string                    `json:"peer_id"`
+	Response *core.NegotiationResponse `json:"response"`
+}
+
+// Peer describes one known peer in GET /peers.
+type Peer struct {
+	PeerID          string   `json:"peer_id"`
+	AgentID         string   `json:"agent_id"`
+	DID             string   `json:"did"`
+	Capabilities    []string `json:"capabilities"`
+	Trust           float32  `json:"trust"`
+	ProtocolVersion string   `json:"protocol_version,omitempty"`
+}
+
+// TrustEdge is one entry of GET /trust.
+type TrustEdge struct {
+	From  string  `json:"from"`
+	To    string  `json:"to"`
+	Score float32 `json:"score"`
+}
+
+// HandshakeRequest is the optional body of POST /handshake/{peerID}: the
+// peer's multiaddrs, if the host does not know how to reach it yet.
+type HandshakeRequest struct {
+	Addrs []string `json:"addrs,omitempty"`
+}
+
+// HandshakeResult is the response to POST /handshake/{peerID}.
+type HandshakeResult struct {
+	PeerID          string   `json:"peer_id"`
+	AgentID         string   `json:"agent_id"`
+	DID             string   `json:"did"`
+	Capabilities    []string `json:"capabilities"`
+	ProtocolVersion string   `json:"protocol_version"`
+}
+
+// ------------------------------------------------------------------ handlers
+
+func (s *Server) handleIntent(w http.ResponseWriter, r *http.Request) {
+	var req IntentRequest
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if len(req.Capabilities) == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("capabilities are required"))
+		return
+	}
+
+	var target peer.ID
+	var err error
+	if req.PeerID != "" {
+		if target, err = peer.Decode(req.PeerID); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("peer_id: %w", err))
+			return
+		}
+	} else if target, err = s.route(req.Capabilities, req.IntentVector); err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	intent, err := core.CreateIntent(s.host.Agent(), req.IntentVector, req.Capabilities, req.Payload)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	for k, v := range req.Metadata {
+		intent.Metadata[k] = v
+	}
+	if req.TTLSeconds > 0 {
+		intent.WithTTL(time.Duration(req.TTLSeconds) * time.Second)
+	}
+	if err = core.SignIntent(s.host.Agent(), intent); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.timeout)
+	defer cancel()
+	resp, err := s.host.SendIntent(ctx, target, intent)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, IntentResult{IntentID: intent.ID, PeerID: target.String(), Response: resp})
+}
+
+// route picks the known peer offering capabilities that best matches vector.
+func (s *Server) route(capabilities []string, vector []float32) (peer.ID, error) {
+	candidates := core.RankCandidates(vector, s.host.Discovery().FindByCapability(capabilities...))
+	known := s.host.KnownPeers()
+	for _, c := range candidates {
+		for pid, profile := range known {
+			if profile.AgentID == c.AgentID {
+				return pid, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no known peer offers %v", capabilities)
+}
+
+func (s *Server) handlePeers(w http.ResponseWriter, _ *http.Request) {
+	self := s.host.Agent().DID.String()
+	out := []Peer{}
+	for pid, profile := range s.host.KnownPeers() {
+		p := Peer{
+			PeerID:       pid.String(),
+			AgentID:      profile.AgentID,
+			DID:          profile.DID,
+			Capabilities: profile.Capabilities,
+			Trust:        s.host.Trust().Get(self, profile.DID),
+		}
+		if v, ok := s.host.PeerVersion(pid); ok {
+			p.ProtocolVersion = v.Version
+		}
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].AgentID < out[j].AgentID })
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) handleTopology(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, s.host.Topology())
+}
+
+// handleAnalytics serves the host's capability usage, busiest first.  It
+// is 404 for hosts created without p2p.WithCapabilityAnalytics.
+func (s *Server) handleAnalytics(w http.ResponseWriter, r *http.Request) {
+	a := s.host.CapabilityAnalytics()
+	if a == nil {
+		writeError(w, http.StatusNotFound, errors.New("capability analytics are not enabled"))
+		return
+	}
+	windows := map[string]time.Duration{"1m": core.WindowMinute, "1h": core.WindowHour, "1d": core.WindowDay}
+	name := r.URL.Query().Get("window")
+	if name == "" {
+		name = "1h"
+	}
+	window, ok := windows[name]
+	if !ok {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("window %q: want 1m, 1h or 1d", name))
+		return
+	}
+	usage, err := a.Snapshot(window)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, usage)
+}
+
+// handleTrust lists trust edges, optionally only those from ?from=<did>.
+func (s *Server) handleTrust(w http.ResponseWriter, r *http.Request) {
+	from := r.URL.Query().Get("from")
+	out := []TrustEdge{}
+	for k, score := range s.host.Trust().Snapshot() {
+		f, t, ok := core.SplitTrustKey(k)
+		if !ok || (from != "" && f != from) {
+			continue
+		}
+		out = append(out, TrustEdge{From: f, To: t, Score: score})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].From != out[j].From {
+			return out[i].From < out[j].From
+		}
+		return out[i].To < out[j].To
+	})
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) handleHandshake(w http.ResponseWriter, r *http.Request) {
+	pid, err := peer.Decode(r.PathValue("peerID"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("peer id: %w", err))
+		return
+	}
+	var req HandshakeRequest
+	if r.ContentLength != 0 {
+		if err = readJSON(r, &req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	info := peer.AddrInfo{ID: pid}
+	for _, a := range req.Addrs {
+		addr, perr := ma.NewMultiaddr(a)
+		if perr != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("addr %q: %w", a, perr))
+			return
+		}
+		info.Addrs = append(info.Addrs, addr)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.timeout)
+	defer cancel()
+	if len(info.Addrs) > 0 {
+		if err = s.host.Connect(ctx, info); err != nil {
+			writeError(w, http.StatusBadGateway, err)
+			return
+		}
+	}
+	resp, err := s.host.Handshake(ctx, pid)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, HandshakeResult{
+		PeerID:          pid.String(),
+		AgentID:         resp.AgentID,
+		DID:             resp.DID,
+		Capabilities:    resp.Capabilities,
+		ProtocolVersion: resp.Version,
+	})
+}
+
+func (s *Server) handleQuarantine(w http.ResponseWriter, _ *http.Request) {
+	out := s.host.QuarantineStatus()
+	if out == nil {
+		out = []core.QuarantineEntry{}
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// handleUnquarantine lifts a peer's quarantine and returns what remains
+// listed, as GET /quarantine does.  It is 404 if the peer was not
+// quarantined.
+func (s *Server) handleUnquarantine(w http.ResponseWriter, r *http.Request) {
+	pid, err := peer.Decode(r.PathValue("peerID"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("peer id: %w", err))
+		return
+	}
+	if !s.host.Unquarantine(pid) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("%s is not quarantined", pid))
+		return
+	}
+	s.handleQuarantine(w, r)
+}
+
+// ------------------------------------------------------------------ helpers
+
+func readJSON(r *http.Request, v interface{}) error {
+	dec := json.NewDecoder(http.MaxBytesReader(nil, r.Body, maxBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
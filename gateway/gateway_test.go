@@ -0,0 +1,195 @@
+package gateway_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/gateway"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+func makeHost(t *testing.T, id string, caps []string) *p2p.AgentHost {
+	t.Helper()
+	a, err := core.NewAgent(id, caps)
+	if err != nil {
+		t.Fatalf("NewAgent(%q): %v", id, err)
+	}
+	h, err := p2p.NewHost(context.Background(), a)
+	if err != nil {
+		t.Fatalf("NewHost: %v", err)
+	}
+	t.Cleanup(func() { _ = h.Close() })
+	return h
+}
+
+func call(t *testing.T, srv *httptest.Server, method, path string, body interface{}, out interface{}) int {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		_ = json.NewEncoder(&buf).Encode(body)
+	}
+	req, _ := http.NewRequest(method, srv.URL+path, &buf)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	if out != nil && resp.StatusCode == http.StatusOK {
+		if err = json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: decode: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestGateway(t *testing.T) {
+	local := makeHost(t, "local", []string{"orchestration"})
+	remote := makeHost(t, "remote", []string{"summarise"})
+	srv := httptest.NewServer(gateway.New(local, gateway.WithToken("secret")))
+	defer srv.Close()
+
+	if resp, err := srv.Client().Get(srv.URL + "/peers"); err != nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("unauthenticated GET /peers: %v %v", resp, err)
+	}
+
+	var addrs []string
+	for _, a := range remote.AddrInfo().Addrs {
+		addrs = append(addrs, a.String())
+	}
+	var hs gateway.HandshakeResult
+	if code := call(t, srv, http.MethodPost, "/handshake/"+remote.PeerID().String(),
+		gateway.HandshakeRequest{Addrs: addrs}, &hs); code != http.StatusOK {
+		t.Fatalf("POST /handshake: status %d", code)
+	}
+	if hs.AgentID != "remote" {
+		t.Errorf("handshake agent = %q, want remote", hs.AgentID)
+	}
+
+	var peers []gateway.Peer
+	if code := call(t, srv, http.MethodGet, "/peers", nil, &peers); code != http.StatusOK {
+		t.Fatalf("GET /peers: status %d", code)
+	}
+	if len(peers) != 1 || peers[0].PeerID != remote.PeerID().String() {
+		t.Fatalf("peers = %+v", peers)
+	}
+
+	var topo p2p.Topology
+	if code := call(t, srv, http.MethodGet, "/topology", nil, &topo); code != http.StatusOK {
+		t.Fatalf("GET /topology: status %d", code)
+	}
+	if len(topo.Nodes) != 2 || !topo.Nodes[0].Self || topo.Nodes[1].AgentID != "remote" || len(topo.Links) == 0 {
+		t.Errorf("topology = %+v", topo)
+	}
+
+	var quarantined []core.QuarantineEntry
+	if code := call(t, srv, http.MethodGet, "/quarantine", nil, &quarantined); code != http.StatusOK || len(quarantined) != 0 {
+		t.Errorf("GET /quarantine: status %d, entries %+v", code, quarantined)
+	}
+	if code := call(t, srv, http.MethodDelete, "/quarantine/"+remote.PeerID().String(), nil, nil); code != http.StatusNotFound {
+		t.Errorf("DELETE /quarantine of a free peer: status %d, want 404", code)
+	}
+
+	if code := call(t, srv, http.MethodGet, "/analytics", nil, nil); code != http.StatusNotFound {
+		t.Errorf("GET /analytics without analytics: status %d, want 404", code)
+	}
+
+	// No peer_id: routed by capability.
+	var res gateway.IntentResult
+	if code := call(t, srv, http.MethodPost, "/intents",
+		gateway.IntentRequest{Capabilities: []string{"summarise"}, Payload: "hello"}, &res); code != http.StatusOK {
+		t.Fatalf("POST /intents: status %d", code)
+	}
+	if !res.Response.Accepted || res.PeerID != remote.PeerID().String() {
+		t.Errorf("intent result = %+v", res)
+	}
+	if code := call(t, srv, http.MethodPost, "/intents",
+		gateway.IntentRequest{Capabilities: []string{"translation"}}, nil); code != http.StatusNotFound {
+		t.Errorf("unroutable intent: status %d, want 404", code)
+	}
+
+	var edges []gateway.TrustEdge
+	if code := call(t, srv, http.MethodGet, "/trust?from="+local.Agent().DID.String(), nil, &edges); code != http.StatusOK {
+		t.Fatalf("GET /trust: status %d", code)
+	}
+	for _, e := range edges {
+		if !strings.HasPrefix(e.From, "did:") {
+			t.Errorf("bad edge %+v", e)
+		}
+	}
+}
+
+func TestObserverToken(t *testing.T) {
+	local := makeHost(t, "local", []string{"orchestration"})
+	remote := makeHost(t, "remote", []string{"summarise"})
+	srv := httptest.NewServer(gateway.New(local, gateway.WithToken("secret"), gateway.WithObserverToken("watch")))
+	defer srv.Close()
+
+	observe := func(method, path string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(method, srv.URL+path, strings.NewReader(`{"capabilities":["summarise"]}`))
+		req.Header.Set("Authorization", "Bearer watch")
+		resp, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		return resp
+	}
+	for _, tc := range []struct {
+		method, path string
+		want         int
+	}{
+		{http.MethodGet, "/peers", http.StatusOK},
+		{http.MethodGet, "/trust", http.StatusOK},
+		{http.MethodPost, "/intents", http.StatusForbidden},
+		{http.MethodPost, "/handshake/" + remote.PeerID().String(), http.StatusForbidden},
+		{http.MethodDelete, "/quarantine/" + remote.PeerID().String(), http.StatusForbidden},
+	} {
+		resp := observe(tc.method, tc.path)
+		resp.Body.Close()
+		if resp.StatusCode != tc.want {
+			t.Errorf("observer %s %s: status %d, want %d", tc.method, tc.path, resp.StatusCode, tc.want)
+		}
+	}
+
+	resp := observe(http.MethodGet, "/events?kind=handshake_completed")
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("GET /events: status %d, content type %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := local.Connect(ctx, remote.AddrInfo()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := local.Handshake(ctx, remote.PeerID()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Unblock the scanner if the event never comes.
+	time.AfterFunc(5*time.Second, func() { resp.Body.Close() })
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() {
+		data, ok := strings.CutPrefix(sc.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var rec gateway.EventRecord
+		if err := json.Unmarshal([]byte(data), &rec); err != nil {
+			t.Fatalf("event data %q: %v", data, err)
+		}
+		if rec.Kind != p2p.EventHandshakeCompleted || rec.AgentID != "remote" || rec.PeerID != remote.PeerID().String() {
+			t.Fatalf("event = %+v", rec)
+		}
+		return
+	}
+	t.Fatalf("event stream ended: %v", sc.Err())
+}
@@ -0,0 +1,108 @@
+// Package apicheck lists the exported surface of a Go package so tests can
+// compare it with a recorded golden file.
+package apicheck
+
+import (
+	"bufio"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Exported returns the exported top-level identifiers declared in the
+// non-test Go files of dir, one per line as "kind Name", sorted.
+func Exported(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	var out []string
+	for _, path := range files {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, path, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, fmt.Errorf("apicheck: %w", err)
+		}
+		for _, decl := range f.Decls {
+			out = append(out, declNames(decl)...)
+		}
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+func declNames(decl ast.Decl) []string {
+	var out []string
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		if d.Recv == nil && d.Name.IsExported() {
+			out = append(out, "func "+d.Name.Name)
+		}
+	case *ast.GenDecl:
+		for _, spec := range d.Specs {
+			switch s := spec.(type) {
+			case *ast.TypeSpec:
+				if s.Name.IsExported() {
+					out = append(out, "type "+s.Name.Name)
+				}
+			case *ast.ValueSpec:
+				for _, n := range s.Names {
+					if n.IsExported() {
+						out = append(out, d.Tok.String()+" "+n.Name)
+					}
+				}
+			}
+		}
+	}
+	return out
+}
+
+// ReadGolden reads a golden file written by Exported: one entry per line,
+// ignoring blank lines and lines starting with '#'.
+func ReadGolden(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var out []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		out = append(out, line)
+	}
+	return out, sc.Err()
+}
+
+// Diff returns the entries of golden missing from current (removed) and
+// those of current missing from golden (added).
+func Diff(golden, current []string) (removed, added []string) {
+	have := make(map[string]bool, len(current))
+	for _, c := range current {
+		have[c] = true
+	}
+	want := make(map[string]bool, len(golden))
+	for _, g := range golden {
+		want[g] = true
+		if !have[g] {
+			removed = append(removed, g)
+		}
+	}
+	for _, c := range current {
+		if !want[c] {
+			added = append(added, c)
+		}
+	}
+	return removed, added
+}
@@ -0,0 +1,63 @@
+// Package yamlconf reads YAML config files with gopkg.in/yaml.v3.
+//
+// Unmarshal maps the document onto a struct through its json tags, so one
+// struct serves both JSON and YAML configs and keeps its JSON decoding
+// (int64 strings, custom UnmarshalJSON methods and the like).
+package yamlconf
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Unmarshal decodes the YAML document data into v using v's json tags.
+// Unknown keys are errors, so typos do not silently disable settings, and
+// so are further documents after the first.
+func Unmarshal(data []byte, v interface{}) error {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	var extra interface{}
+	if err := dec.Decode(&extra); !errors.Is(err, io.EOF) {
+		return errors.New("yaml: multiple documents")
+	}
+	js, err := json.Marshal(stringKeys(doc))
+	if err != nil {
+		return fmt.Errorf("yaml: %w", err)
+	}
+	jd := json.NewDecoder(bytes.NewReader(js))
+	jd.DisallowUnknownFields()
+	if err = jd.Decode(v); err != nil {
+		return fmt.Errorf("yaml: %w", err)
+	}
+	return nil
+}
+
+// stringKeys converts the mappings in doc whose keys are not all strings,
+// such as those keyed by numbers, to string-keyed maps JSON can encode.
+func stringKeys(doc interface{}) interface{} {
+	switch d := doc.(type) {
+	case map[string]interface{}:
+		for k, v := range d {
+			d[k] = stringKeys(v)
+		}
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(d))
+		for k, v := range d {
+			m[fmt.Sprint(k)] = stringKeys(v)
+		}
+		return m
+	case []interface{}:
+		for i, v := range d {
+			d[i] = stringKeys(v)
+		}
+	}
+	return doc
+}
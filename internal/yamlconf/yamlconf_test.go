@@ -0,0 +1,92 @@
+package yamlconf_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/olserra/agent-semantic-protocol/internal/yamlconf"
+)
+
+func TestUnmarshal(t *testing.T) {
+	type agent struct {
+		ID   string   `json:"id"`
+		Caps []string `json:"capabilities"`
+	}
+	type doc struct {
+		Name    string            `json:"name"`
+		Agents  []agent           `json:"agents"`
+		Ratio   float64           `json:"ratio"`
+		Count   int               `json:"count"`
+		Big     int64             `json:"big,string"`
+		Enabled bool              `json:"enabled"`
+		Quoted  string            `json:"quoted"`
+		Note    string            `json:"note"`
+		Labels  map[string]string `json:"labels"`
+		Ports   map[string]string `json:"ports"`
+		Empty   *string           `json:"empty"`
+	}
+	src := `
+# scenario
+---
+name: capability routing   # trailing comment
+agents:
+  - id: alpha
+    capabilities: [nlp, "ocr"]
+  - id: beta
+    capabilities:
+    - math
+    - 'stats'
+ratio: 0.25
+count: 3
+big: "9007199254740993"
+enabled: true
+quoted: "a \"b\" # not a comment"
+note: |
+  two
+  lines
+labels: {tier: gold, region: "eu-west"}
+ports: {80: http}
+empty: ~
+`
+	var got doc
+	if err := yamlconf.Unmarshal([]byte(src), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := doc{
+		Name: "capability routing",
+		Agents: []agent{
+			{ID: "alpha", Caps: []string{"nlp", "ocr"}},
+			{ID: "beta", Caps: []string{"math", "stats"}},
+		},
+		Ratio:   0.25,
+		Count:   3,
+		Big:     9007199254740993,
+		Enabled: true,
+		Quoted:  `a "b" # not a comment`,
+		Note:    "two\nlines\n",
+		Labels:  map[string]string{"tier": "gold", "region": "eu-west"},
+		Ports:   map[string]string{"80": "http"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	var v struct {
+		Name string `json:"name"`
+	}
+	cases := map[string]string{
+		"unknown field":      "nmae: x\n",
+		"already defined":    "name: a\nname: b\n",
+		"multiple documents": "name: a\n---\nname: b\n",
+		"cannot unmarshal":   "name: [a]\n",
+	}
+	for want, src := range cases {
+		err := yamlconf.Unmarshal([]byte(src), &v)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: got %v, want error containing %q", src, err, want)
+		}
+	}
+}
@@ -0,0 +1,326 @@
+// Package metrics collects counters, gauges and histograms and exposes them
+// in the Prometheus text exposition format, without a third-party
+// dependency.
+//
+// A Registry holds named metrics, each optionally split by labels:
+//
+//	reg := metrics.NewRegistry()
+//	sent := reg.Counter("asp_intents_sent_total", "Intents sent.", "outcome")
+//	sent.Inc("accepted")
+//	http.Handle("/metrics", reg.Handler())
+//
+// Asking a Registry twice for the same name returns the same metric, so
+// several components can share one registry.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are histogram upper bounds, in seconds, suited to network
+// round trips.
+var DefaultBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Registry is a set of named metrics.  It is safe for concurrent use.
+type Registry struct {
+	mu      sync.Mutex
+	metrics map[string]metric
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{metrics: make(map[string]metric)}
+}
+
+type metric interface {
+	write(w *bufio.Writer)
+}
+
+// desc is what every metric kind shares.
+type desc struct {
+	name   string
+	help   string
+	labels []string
+}
+
+// key joins label values into a map key; it panics if their number does
+// not match the metric's labels, which is a programming error.
+func (d *desc) key(values []string) string {
+	if len(values) != len(d.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, got %d", d.name, len(d.labels), len(values)))
+	}
+	return strings.Join(values, "\xff")
+}
+
+// series formats name{label="value",...}, adding extra as a last label.
+func (d *desc) series(name, key, extra string) string {
+	var pairs []string
+	if len(d.labels) > 0 {
+		for i, v := range strings.Split(key, "\xff") {
+			pairs = append(pairs, d.labels[i]+`="`+labelEscaper.Replace(v)+`"`)
+		}
+	}
+	if extra != "" {
+		pairs = append(pairs, extra)
+	}
+	if len(pairs) == 0 {
+		return name
+	}
+	return name + "{" + strings.Join(pairs, ",") + "}"
+}
+
+func (d *desc) header(w *bufio.Writer, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", d.name, helpEscaper.Replace(d.help), d.name, kind)
+}
+
+// lookup returns the metric called name, creating it with mk if absent.  It
+// panics if name is registered as a different kind.
+func lookup[M metric](r *Registry, name string, mk func() M) M {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if m, ok := r.metrics[name]; ok {
+		typed, ok := m.(M)
+		if !ok {
+			panic(fmt.Sprintf("metrics: %s is already registered as another kind", name))
+		}
+		return typed
+	}
+	m := mk()
+	r.metrics[name] = m
+	return m
+}
+
+// ------------------------------------------------------------------ counters
+
+// Counter is a monotonically increasing value per label combination.
+type Counter struct {
+	desc
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+// Counter returns the counter called name with the given label names.
+func (r *Registry) Counter(name, help string, labels ...string) *Counter {
+	return lookup(r, name, func() *Counter {
+		return &Counter{desc: desc{name, help, labels}, values: make(map[string]float64)}
+	})
+}
+
+// Inc adds one to the series with the given label values.
+func (c *Counter) Inc(labelValues ...string) { c.Add(1, labelValues...) }
+
+// Add adds v, which must not be negative, to the series with the given
+// label values.
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		panic("metrics: counter decreased")
+	}
+	k := c.key(labelValues)
+	c.mu.Lock()
+	c.values[k] += v
+	c.mu.Unlock()
+}
+
+// Value returns the series' current value.
+func (c *Counter) Value(labelValues ...string) float64 {
+	k := c.key(labelValues)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.values[k]
+}
+
+func (c *Counter) write(w *bufio.Writer) {
+	c.header(w, "counter")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, k := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "%s %s\n", c.series(c.name, k, ""), formatFloat(c.values[k]))
+	}
+}
+
+// ------------------------------------------------------------------ gauges
+
+// Gauge is a value per label combination that may go up and down.
+type Gauge struct {
+	desc
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+// Gauge returns the gauge called name with the given label names.
+func (r *Registry) Gauge(name, help string, labels ...string) *Gauge {
+	return lookup(r, name, func() *Gauge {
+		return &Gauge{desc: desc{name, help, labels}, values: make(map[string]float64)}
+	})
+}
+
+// Set sets the series with the given label values to v.
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	k := g.key(labelValues)
+	g.mu.Lock()
+	g.values[k] = v
+	g.mu.Unlock()
+}
+
+// Delete removes the series with the given label values.
+func (g *Gauge) Delete(labelValues ...string) {
+	k := g.key(labelValues)
+	g.mu.Lock()
+	delete(g.values, k)
+	g.mu.Unlock()
+}
+
+// Value returns the series' current value.
+func (g *Gauge) Value(labelValues ...string) float64 {
+	k := g.key(labelValues)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.values[k]
+}
+
+func (g *Gauge) write(w *bufio.Writer) {
+	g.header(w, "gauge")
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, k := range sortedKeys(g.values) {
+		fmt.Fprintf(w, "%s %s\n", g.series(g.name, k, ""), formatFloat(g.values[k]))
+	}
+}
+
+// ------------------------------------------------------------------ histograms
+
+// Histogram counts observations in cumulative buckets per label
+// combination.
+type Histogram struct {
+	desc
+	buckets []float64
+	mu      sync.Mutex
+	data    map[string]*histSeries
+}
+
+type histSeries struct {
+	counts []uint64 // per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// Histogram returns the histogram called name with the given bucket upper
+// bounds (DefaultBuckets if nil) and label names.
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	return lookup(r, name, func() *Histogram {
+		if buckets == nil {
+			buckets = DefaultBuckets
+		}
+		b := append([]float64(nil), buckets...)
+		sort.Float64s(b)
+		return &Histogram{desc: desc{name, help, labels}, buckets: b, data: make(map[string]*histSeries)}
+	})
+}
+
+// Observe records v in the series with the given label values.
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	k := h.key(labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := h.data[k]
+	if s == nil {
+		s = &histSeries{counts: make([]uint64, len(h.buckets))}
+		h.data[k] = s
+	}
+	if i := sort.SearchFloat64s(h.buckets, v); i < len(h.buckets) {
+		s.counts[i]++
+	}
+	s.count++
+	s.sum += v
+}
+
+// Count returns the number of observations in the series.
+func (h *Histogram) Count(labelValues ...string) uint64 {
+	k := h.key(labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if s := h.data[k]; s != nil {
+		return s.count
+	}
+	return 0
+}
+
+func (h *Histogram) write(w *bufio.Writer) {
+	h.header(w, "histogram")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, k := range sortedKeys(h.data) {
+		s := h.data[k]
+		var cum uint64
+		for i, le := range h.buckets {
+			cum += s.counts[i]
+			fmt.Fprintf(w, "%s %d\n", h.series(h.name+"_bucket", k, `le="`+formatFloat(le)+`"`), cum)
+		}
+		fmt.Fprintf(w, "%s %d\n", h.series(h.name+"_bucket", k, `le="+Inf"`), s.count)
+		fmt.Fprintf(w, "%s %s\n", h.series(h.name+"_sum", k, ""), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s %d\n", h.series(h.name+"_count", k, ""), s.count)
+	}
+}
+
+// ------------------------------------------------------------------ exposition
+
+// ContentType is the media type of WriteText's output.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// WriteText writes every metric in the Prometheus text format, sorted by
+// name.
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	names := sortedKeys(r.metrics)
+	ms := make([]metric, len(names))
+	for i, n := range names {
+		ms[i] = r.metrics[n]
+	}
+	r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, m := range ms {
+		m.write(bw)
+	}
+	return bw.Flush()
+}
+
+// Handler serves the registry for Prometheus to scrape.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		_ = r.WriteText(w)
+	})
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
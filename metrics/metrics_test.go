@@ -0,0 +1,82 @@
+package metrics_test
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/olserra/agent-semantic-protocol/metrics"
+)
+
+func TestRegistryText(t *testing.T) {
+	reg := metrics.NewRegistry()
+	sent := reg.Counter("asp_sent_total", "Intents sent.", "outcome")
+	sent.Inc("accepted")
+	sent.Inc("accepted")
+	sent.Add(3, "rejected")
+	if reg.Counter("asp_sent_total", "ignored", "outcome") != sent {
+		t.Error("same name returned a different counter")
+	}
+
+	trust := reg.Gauge("asp_trust", "Trust.", "did")
+	trust.Set(0.5, `did:key:"x"`)
+	trust.Set(0.25, "did:key:y")
+	trust.Delete("did:key:y")
+
+	rtt := reg.Histogram("asp_rtt_seconds", "Round trip.", []float64{0.1, 1})
+	rtt.Observe(0.05)
+	rtt.Observe(0.5)
+	rtt.Observe(2)
+
+	var b strings.Builder
+	if err := reg.WriteText(&b); err != nil {
+		t.Fatalf("WriteText: %v", err)
+	}
+	want := `# HELP asp_rtt_seconds Round trip.
+# TYPE asp_rtt_seconds histogram
+asp_rtt_seconds_bucket{le="0.1"} 1
+asp_rtt_seconds_bucket{le="1"} 2
+asp_rtt_seconds_bucket{le="+Inf"} 3
+asp_rtt_seconds_sum 2.55
+asp_rtt_seconds_count 3
+# HELP asp_sent_total Intents sent.
+# TYPE asp_sent_total counter
+asp_sent_total{outcome="accepted"} 2
+asp_sent_total{outcome="rejected"} 3
+# HELP asp_trust Trust.
+# TYPE asp_trust gauge
+asp_trust{did="did:key:\"x\""} 0.5
+`
+	if b.String() != want {
+		t.Errorf("text:\n%s\nwant:\n%s", b.String(), want)
+	}
+	if got := rtt.Count(); got != 3 {
+		t.Errorf("Count: got %d want 3", got)
+	}
+}
+
+func TestRegistryKindConflictPanics(t *testing.T) {
+	reg := metrics.NewRegistry()
+	reg.Counter("x", "x")
+	defer func() {
+		if recover() == nil {
+			t.Error("registering x as a gauge did not panic")
+		}
+	}()
+	reg.Gauge("x", "x")
+}
+
+func TestHandler(t *testing.T) {
+	reg := metrics.NewRegistry()
+	reg.Counter("hits_total", "Hits.").Inc()
+	rec := httptest.NewRecorder()
+	reg.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); ct != metrics.ContentType {
+		t.Errorf("Content-Type: %q", ct)
+	}
+	body, _ := io.ReadAll(rec.Body)
+	if !strings.Contains(string(body), "hits_total 1\n") {
+		t.Errorf("body: %s", body)
+	}
+}
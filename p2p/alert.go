@@ -0,0 +1,118 @@
+package p2p
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+// alertCacheTTL is how long alerts are remembered for deduplication.  Older
+// alerts are dropped, since their replays could no longer be recognised.
+const alertCacheTTL = time.Hour
+
+// alertRate bounds the alerts accepted from one issuer.
+var alertRate = core.RateLimit{Rate: 1.0 / 60, Burst: 5}
+
+// AlertCallback is invoked for every new, verified alert, after its policy
+// effects have been applied.  from is the neighbour that forwarded it.
+type AlertCallback func(from peer.ID, alert *core.AlertMessage)
+
+// WithAlertAuthorities lets the listed DIDs report other agents' keys as
+// compromised.  Without it, only self-reported compromises take effect.
+func WithAlertAuthorities(dids ...string) HostOption {
+	return func(ah *AgentHost) {
+		for _, d := range dids {
+			ah.alertAuthorities[d] = true
+		}
+	}
+}
+
+// OnAlert registers the callback for incoming alerts.
+func (ah *AgentHost) OnAlert(fn AlertCallback) {
+	ah.mu.Lock()
+	defer ah.mu.Unlock()
+	ah.onAlert = fn
+}
+
+// BroadcastAlert applies alert locally and floods it to every connected peer.
+func (ah *AgentHost) BroadcastAlert(ctx context.Context, alert *core.AlertMessage) error {
+	if err := core.VerifyAlert(alert); err != nil {
+		return fmt.Errorf("p2p alert: %w", err)
+	}
+	if err := core.CheckAlertTime(alert, alertCacheTTL, time.Now()); err != nil {
+		return fmt.Errorf("p2p alert: %w", err)
+	}
+	ah.alerts.Seen(alert)
+	if core.AlertAuthorized(alert, ah.isAlertAuthority) {
+		_ = core.ApplyAlert(ah.agent, alert, ah.trust, ah.discovery)
+	}
+	ah.floodAlert(ctx, alert, "")
+	return nil
+}
+
+func (ah *AgentHost) handleIncomingAlert(s network.Stream, data []byte) {
+	alert, err := ah.limits.DecodeAlertMessage(data)
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	if core.VerifyAlert(alert) != nil || core.CheckAlertTime(alert, alertCacheTTL, now) != nil || ah.alerts.Seen(alert) {
+		return
+	}
+	// A valid signature is cheap to come by with a fresh key; each issuer
+	// gets a budget so no one can flood the mesh through us.
+	ah.alertLimiter.Prune(now)
+	if _, ok := ah.alertLimiter.AllowAt(alert.IssuerDID, now); !ok {
+		return
+	}
+	// Unauthorised claims about someone else's key are neither applied nor
+	// forwarded, so a single peer cannot knock others out of the mesh.
+	if !core.AlertAuthorized(alert, ah.isAlertAuthority) {
+		return
+	}
+	_ = core.ApplyAlert(ah.agent, alert, ah.trust, ah.discovery)
+
+	from := s.Conn().RemotePeer()
+	ah.mu.RLock()
+	cb := ah.onAlert
+	ah.mu.RUnlock()
+	if cb != nil {
+		cb(from, alert)
+	}
+
+	if alert.Hops > 1 {
+		fwd := *alert
+		fwd.Hops--
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		go func() {
+			defer cancel()
+			ah.floodAlert(ctx, &fwd, from)
+		}()
+	}
+}
+
+// floodAlert sends alert to every connected peer except skip, returning once
+// every send has finished.
+func (ah *AgentHost) floodAlert(ctx context.Context, alert *core.AlertMessage, skip peer.ID) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for _, p := range ah.h.Network().Peers() {
+		if p == skip {
+			continue
+		}
+		wg.Add(1)
+		go func(pid peer.ID) {
+			defer wg.Done()
+			_ = ah.sendOneWay(ctx, pid, alert)
+		}(p)
+	}
+}
+
+func (ah *AgentHost) isAlertAuthority(did string) bool {
+	return ah.alertAuthorities[did]
+}
@@ -0,0 +1,84 @@
+package p2p_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+// TestAlertFloodsAcrossHops verifies that an alert reaches a peer two hops
+// away and that a self-reported key compromise drops trust to zero there.
+func TestAlertFloodsAcrossHops(t *testing.T) {
+	alpha := makeAgent(t, "alpha", []string{"nlp"})
+	beta := makeAgent(t, "beta", nil)
+	gamma := makeAgent(t, "gamma", nil)
+
+	hA := makeHost(t, alpha)
+	hB := makeHost(t, beta)
+	hC := makeHost(t, gamma)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := hA.Connect(ctx, hB.AddrInfo()); err != nil {
+		t.Fatalf("Connect A-B: %v", err)
+	}
+	if err := hB.Connect(ctx, hC.AddrInfo()); err != nil {
+		t.Fatalf("Connect B-C: %v", err)
+	}
+	_ = hC.Trust().Set(gamma.DID.String(), alpha.DID.String(), 0.8)
+
+	got := make(chan *core.AlertMessage, 1)
+	hC.OnAlert(func(_ peer.ID, a *core.AlertMessage) { got <- a })
+
+	alert, err := core.NewAlert(alpha, core.AlertKeyCompromise, alpha.DID.String(), "key leaked")
+	if err != nil {
+		t.Fatalf("NewAlert: %v", err)
+	}
+	if err = hA.BroadcastAlert(ctx, alert); err != nil {
+		t.Fatalf("BroadcastAlert: %v", err)
+	}
+
+	select {
+	case a := <-got:
+		if a.ID != alert.ID || a.Hops != alert.Hops-1 {
+			t.Errorf("unexpected alert at gamma: %+v", a)
+		}
+	case <-ctx.Done():
+		t.Fatal("alert did not reach gamma")
+	}
+	if s := hC.Trust().Get(gamma.DID.String(), alpha.DID.String()); s != 0 {
+		t.Errorf("gamma still trusts alpha: %v", s)
+	}
+}
+
+// TestAlertsRateLimitedPerIssuer verifies that a host stops accepting alerts
+// from an issuer that keeps sending them.
+func TestAlertsRateLimitedPerIssuer(t *testing.T) {
+	alpha := makeAgent(t, "alpha", nil)
+	beta := makeAgent(t, "beta", nil)
+	hA := makeHost(t, alpha)
+	hB := makeHost(t, beta)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := hA.Connect(ctx, hB.AddrInfo()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	var received atomic.Int64
+	hB.OnAlert(func(peer.ID, *core.AlertMessage) { received.Add(1) })
+
+	for range 10 {
+		alert, _ := core.NewAlert(alpha, core.AlertAdvisory, "", "spam")
+		if err := hA.BroadcastAlert(ctx, alert); err != nil {
+			t.Fatalf("BroadcastAlert: %v", err)
+		}
+	}
+	time.Sleep(200 * time.Millisecond)
+	if n := received.Load(); n == 0 || n >= 10 {
+		t.Errorf("beta accepted %d of 10 alerts", n)
+	}
+}
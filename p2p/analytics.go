@@ -0,0 +1,48 @@
+package p2p
+
+// analytics.go — Capability usage analytics.
+//
+// WithCapabilityAnalytics records every intent the host answers, once per
+// requested capability, in a core.CapabilityAnalytics: who asked, whether
+// the host accepted and how long the handler took.
+
+import (
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+// WithCapabilityAnalytics records the host's capability usage in a, or in
+// a new CapabilityAnalytics if a is nil.
+func WithCapabilityAnalytics(a *core.CapabilityAnalytics) HostOption {
+	return func(ah *AgentHost) {
+		if a == nil {
+			a = core.NewCapabilityAnalytics()
+		}
+		ah.analytics = a
+	}
+}
+
+// CapabilityAnalytics returns the host's usage analytics, or nil without
+// WithCapabilityAnalytics.
+func (ah *AgentHost) CapabilityAnalytics() *core.CapabilityAnalytics {
+	return ah.analytics
+}
+
+// recordUsage records the answer to intent, begun at start.  Intents left
+// unanswered are not counted.
+func (ah *AgentHost) recordUsage(intent *core.IntentMessage, resp *core.NegotiationResponse, start time.Time) {
+	if ah.analytics == nil || resp == nil {
+		return
+	}
+	now := time.Now()
+	for _, c := range intent.Capabilities {
+		ah.analytics.Record(core.UsageSample{
+			Capability:   c,
+			RequesterDID: intent.DID,
+			Accepted:     resp.Accepted,
+			Latency:      now.Sub(start),
+			At:           now,
+		})
+	}
+}
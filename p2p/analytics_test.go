@@ -0,0 +1,45 @@
+package p2p_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+func TestCapabilityAnalytics(t *testing.T) {
+	alpha := makeAgent(t, "alpha", []string{"nlp"})
+	beta := makeAgent(t, "beta", []string{"ocr"})
+	hA := makeHost(t, alpha)
+	hB, err := p2p.NewHost(context.Background(), beta, p2p.WithCapabilityAnalytics(nil))
+	if err != nil {
+		t.Fatalf("NewHost: %v", err)
+	}
+	t.Cleanup(func() { _ = hB.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err = p2p.DiscoverAndHandshake(ctx, hA, hB.AddrInfo()); err != nil {
+		t.Fatalf("DiscoverAndHandshake: %v", err)
+	}
+	for _, caps := range [][]string{{"ocr"}, {"ocr"}, {"translation"}} {
+		intent, _ := core.CreateIntent(alpha, nil, caps, "scan")
+		if _, err = hA.SendIntent(ctx, hB.PeerID(), intent); err != nil {
+			t.Fatalf("SendIntent: %v", err)
+		}
+	}
+
+	ocr, _ := hB.CapabilityAnalytics().Query("ocr", core.WindowMinute)
+	if ocr.Invocations != 2 || ocr.Accepted != 2 || ocr.UniqueRequesters != 1 {
+		t.Errorf("ocr usage: %+v", ocr)
+	}
+	tr, _ := hB.CapabilityAnalytics().Query("translation", core.WindowMinute)
+	if tr.Invocations != 1 || tr.Accepted != 0 {
+		t.Errorf("translation usage: %+v", tr)
+	}
+	if hA.CapabilityAnalytics() != nil {
+		t.Error("analytics enabled without the option")
+	}
+}
@@ -0,0 +1,66 @@
+package p2p
+
+// attestation.go — Sharing trust with peers.
+//
+// ShareTrust signs the host's current trust in one agent as a
+// core.TrustAttestation and sends it to a peer.  A host configured with
+// WithAttestationPolicy adopts the attestations it receives into its trust
+// graph as the issuer's edges, where they feed ComputeTransitiveTrust; other
+// hosts ignore them.
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+// WithAttestationPolicy makes the host adopt trust attestations from peers
+// that pass p.  Without it, incoming attestations are dropped.
+func WithAttestationPolicy(p core.AttestationPolicy) HostOption {
+	return func(ah *AgentHost) { ah.attestPolicy = &p }
+}
+
+// Attestations returns the attestations the host has adopted, or nil
+// without WithAttestationPolicy.
+func (ah *AgentHost) Attestations() *core.AttestationRegistry { return ah.attestations }
+
+// ShareTrust signs the local agent's current trust in subjectDID, valid for
+// ttl (core.DefaultAttestationTTL when 0), and sends it to pid.
+func (ah *AgentHost) ShareTrust(ctx context.Context, pid peer.ID, subjectDID string, ttl time.Duration) (*core.TrustAttestation, error) {
+	if ttl == 0 {
+		ttl = core.DefaultAttestationTTL
+	}
+	a, err := core.NewTrustAttestation(ah.agent, subjectDID, ah.trust.Get(ah.agent.DID.String(), subjectDID), ttl)
+	if err != nil {
+		return nil, fmt.Errorf("p2p attestation: %w", err)
+	}
+	if err = ah.sendOneWay(ctx, pid, a); err != nil {
+		return nil, fmt.Errorf("p2p attestation: %w", err)
+	}
+	return a, nil
+}
+
+func (ah *AgentHost) handleIncomingAttestation(s network.Stream, data []byte) {
+	if ah.attestations == nil {
+		return
+	}
+	a, err := ah.limits.DecodeTrustAttestation(data)
+	if err != nil {
+		return
+	}
+	from := s.Conn().RemotePeer()
+	if _, revoked := ah.revocations.IsRevoked(a.IssuerDID); revoked {
+		return
+	}
+	if err = ah.attestations.Accept(a); err != nil {
+		ah.log.Log(context.Background(), slog.LevelWarn, "attestation refused",
+			core.LogKeyPeer, from.String(), core.LogKeyMsgType, "attestation", "error", err)
+		return
+	}
+	ah.emit(Event{Kind: EventTrustAttested, PeerID: from, DID: a.SubjectDID, Trust: a.Score, Reason: a.IssuerDID})
+}
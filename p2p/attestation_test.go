@@ -0,0 +1,54 @@
+package p2p_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+func TestTrustAttestation(t *testing.T) {
+	alpha := makeAgent(t, "alpha", nil)
+	beta := makeAgent(t, "beta", nil)
+	gamma := makeAgent(t, "gamma", nil)
+	hA := makeHost(t, alpha)
+	hB, err := p2p.NewHost(context.Background(), beta, p2p.WithAttestationPolicy(core.AttestationPolicy{MinIssuerTrust: 0.5}))
+	if err != nil {
+		t.Fatalf("NewHost: %v", err)
+	}
+	t.Cleanup(func() { _ = hB.Close() })
+	_ = hA.Trust().Set(alpha.DID.String(), gamma.DID.String(), 0.9)
+	_ = hB.Trust().Set(beta.DID.String(), alpha.DID.String(), 0.6)
+
+	adopted := make(chan p2p.Event, 1)
+	hB.Subscribe(func(ev p2p.Event) { adopted <- ev }, p2p.EventTrustAttested)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err = p2p.DiscoverAndHandshake(ctx, hA, hB.AddrInfo()); err != nil {
+		t.Fatalf("DiscoverAndHandshake: %v", err)
+	}
+	a, err := hA.ShareTrust(ctx, hB.PeerID(), gamma.DID.String(), 0)
+	if err != nil {
+		t.Fatalf("ShareTrust: %v", err)
+	}
+	if a.Score != 0.9 || a.ExpiresAt == 0 {
+		t.Errorf("attestation: %+v", a)
+	}
+	select {
+	case ev := <-adopted:
+		if ev.DID != gamma.DID.String() || ev.Reason != alpha.DID.String() {
+			t.Errorf("event: %+v", ev)
+		}
+	case <-ctx.Done():
+		t.Fatal("attestation not adopted")
+	}
+	if got := hB.Trust().ComputeTransitiveTrust(beta.DID.String(), gamma.DID.String(), 2); got < 0.53 || got > 0.55 {
+		t.Errorf("transitive trust in gamma: %v, want 0.54", got)
+	}
+	if len(hB.Attestations().About(gamma.DID.String())) != 1 || hA.Attestations() != nil {
+		t.Error("Attestations: unexpected registry contents")
+	}
+}
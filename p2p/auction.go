@@ -0,0 +1,138 @@
+package p2p
+
+// auction.go — Offering an intent to several agents and taking the best bid.
+//
+// BroadcastIntent picks among the peers that accept by how well they
+// match; Auction picks by what they offer.  Each candidate answers with a
+// NegotiationResponse that may carry a core.Bid (cost, ETA, confidence)
+// next to the workflow steps it proposes, and the orchestrator's BidScorer
+// ranks the accepting answers.  Responders bid by filling in Bid in their
+// intent handler and signing the response again.  A signed bid only shows who made it, so scorers that
+// weigh bids heavily should also weigh the bidder's trust, which AuctionBid
+// carries.
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+// AuctionBid is one candidate's accepting answer in an auction.
+type AuctionBid struct {
+	Peer     peer.ID
+	Profile  core.AgentProfile // Zero if the candidate has not been handshaken
+	Response *core.NegotiationResponse
+	Trust    float32 // The local agent's trust in the candidate
+	Score    float64 // Set by the orchestrator's BidScorer
+}
+
+// BidScorer scores an accepting answer; the highest score wins.
+type BidScorer func(b AuctionBid) float64
+
+// DefaultBidScore prefers confident, cheap and fast bids:
+// confidence / ((1 + cost) · (1 + ETA in seconds)).  An answer without a
+// Bid scores 0, so it wins only if nobody bid.
+func DefaultBidScore(b AuctionBid) float64 {
+	bid := b.Response.Bid
+	if bid == nil {
+		return 0
+	}
+	eta := time.Duration(bid.ETA).Seconds()
+	return float64(bid.Confidence) / ((1 + float64(bid.Cost)) * (1 + eta))
+}
+
+// AuctionResult is the outcome of Auction.
+type AuctionResult struct {
+	// Winner is the best-scored bid, nil if no candidate accepted.
+	Winner *AuctionBid
+	// Bids holds every accepting answer, best first.
+	Bids []AuctionBid
+	// Rejected and Errors hold the other candidates' answers or failures.
+	// Acceptances whose Cost exceeds the intent's Budget count as rejected.
+	Rejected map[peer.ID]*core.NegotiationResponse
+	Errors   map[peer.ID]error
+}
+
+// SetBidScorer makes Auction rank bids with fn.  Nil restores
+// DefaultBidScore.
+func (o *WorkflowOrchestrator) SetBidScorer(fn BidScorer) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.scoreBid = fn
+}
+
+// Auction sends intent to every candidate in parallel, each bounded as a
+// workflow step would be, and ranks the accepting answers by the
+// orchestrator's BidScorer.  Nil candidates means every known peer
+// offering the intent's required capabilities.  It fails only if there is
+// no candidate to ask.
+func (o *WorkflowOrchestrator) Auction(ctx context.Context, intent *core.IntentMessage, candidates []peer.ID) (*AuctionResult, error) {
+	targets := make(map[peer.ID]core.AgentProfile, len(candidates))
+	if candidates == nil {
+		targets = o.host.ablePeers(intent.Capabilities)
+	} else {
+		known := o.host.KnownPeers()
+		for _, pid := range candidates {
+			targets[pid] = known[pid]
+		}
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("p2p auction: %w: no candidate for %v", core.ErrCapabilityMissing, intent.Capabilities)
+	}
+	o.mu.Lock()
+	score := o.scoreBid
+	o.mu.Unlock()
+	if score == nil {
+		score = DefaultBidScore
+	}
+
+	res := &AuctionResult{
+		Rejected: make(map[peer.ID]*core.NegotiationResponse),
+		Errors:   make(map[peer.ID]error),
+	}
+	self := o.host.agent.DID.String()
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for pid, profile := range targets {
+		wg.Add(1)
+		go func(pid peer.ID, profile core.AgentProfile) {
+			defer wg.Done()
+			sctx, cancel := o.stepContext(ctx, intent)
+			defer cancel()
+			resp, err := o.host.SendIntent(sctx, pid, intent)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err != nil:
+				res.Errors[pid] = err
+			case !resp.Accepted || core.CheckCost(intent, resp) != nil:
+				res.Rejected[pid] = resp
+			default:
+				b := AuctionBid{Peer: pid, Profile: profile, Response: resp}
+				if profile.DID != "" {
+					b.Trust = o.host.trust.Get(self, profile.DID)
+				}
+				b.Score = score(b)
+				res.Bids = append(res.Bids, b)
+			}
+		}(pid, profile)
+	}
+	wg.Wait()
+
+	// Peer ID breaks ties, so equal bids always resolve the same way.
+	sort.Slice(res.Bids, func(i, j int) bool {
+		if res.Bids[i].Score != res.Bids[j].Score {
+			return res.Bids[i].Score > res.Bids[j].Score
+		}
+		return res.Bids[i].Peer < res.Bids[j].Peer
+	})
+	if len(res.Bids) > 0 {
+		res.Winner = &res.Bids[0]
+	}
+	return res, nil
+}
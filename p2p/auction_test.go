@@ -0,0 +1,68 @@
+package p2p_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+func TestWorkflowAuction(t *testing.T) {
+	alpha := makeAgent(t, "alpha", nil)
+	hA := makeHost(t, alpha)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	bids := map[string]*core.Bid{
+		"pricey": {Cost: 10, ETA: int64(time.Second), Confidence: 0.9},
+		"cheap":  {Cost: 1, ETA: int64(time.Second), Confidence: 0.9},
+		"silent": nil,
+	}
+	peers := make(map[string]peer.ID)
+	for id, bid := range bids {
+		a := makeAgent(t, id, []string{"translate"})
+		h := makeHost(t, a)
+		peers[id] = h.PeerID()
+		h.OnIntent(func(_ peer.ID, in *core.IntentMessage) *core.NegotiationResponse {
+			resp, _ := core.DefaultNegotiationHandler(a)(in)
+			resp.Bid = bid
+			_ = core.SignResponse(a, resp)
+			return resp
+		})
+		if _, err := p2p.DiscoverAndHandshake(ctx, hA, h.AddrInfo()); err != nil {
+			t.Fatalf("DiscoverAndHandshake: %v", err)
+		}
+	}
+	intent, _ := core.CreateIntent(alpha, []float32{1, 0}, []string{"translate"}, "hola")
+
+	o := p2p.NewOrchestrator(hA, 5*time.Second)
+	res, err := o.Auction(ctx, intent, nil)
+	if err != nil || res.Winner == nil {
+		t.Fatalf("Auction: %+v, %v", res, err)
+	}
+	if len(res.Bids) != len(bids) || res.Winner.Peer != peers["cheap"] {
+		t.Fatalf("winner = %s of %d bids; want cheap", res.Winner.Profile.AgentID, len(res.Bids))
+	}
+	if got := res.Winner.Response.Bid; got == nil || got.Cost != 1 {
+		t.Errorf("winning bid = %+v", got)
+	}
+
+	// A scorer that ignores cost prefers the pricey bid to no bid at all.
+	o.SetBidScorer(func(b p2p.AuctionBid) float64 {
+		if b.Response.Bid == nil {
+			return 0
+		}
+		return float64(b.Response.Bid.Confidence)
+	})
+	intent, _ = core.CreateIntent(alpha, []float32{1, 0}, []string{"translate"}, "adiós")
+	res, err = o.Auction(ctx, intent, []peer.ID{peers["pricey"], peers["silent"]})
+	if err != nil || res.Winner == nil || len(res.Bids) != 2 {
+		t.Fatalf("Auction: %+v, %v", res, err)
+	}
+	if res.Winner.Peer != peers["pricey"] {
+		t.Errorf("winner = %s; want pricey", res.Winner.Profile.AgentID)
+	}
+}
@@ -0,0 +1,25 @@
+package p2p
+
+// audit.go — Signed audit trail.
+//
+// WithAuditLog appends a record to a core.AuditLog for every message the
+// host acts on: each completed handshake, each intent it answers and each
+// response to an intent it sent.  Failing to write the log does not fail
+// the exchange.
+
+import (
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+// WithAuditLog records the host's processed messages in l.  l must be
+// signed by the host's agent.
+func WithAuditLog(l *core.AuditLog) HostOption {
+	return func(ah *AgentHost) { ah.audit = l }
+}
+
+// auditMsg records msg, exchanged with peerDID, unless there is no log.
+func (ah *AgentHost) auditMsg(msg core.Encoder, peerDID, decision string) {
+	if ah.audit != nil {
+		_, _ = ah.audit.Append(msg, peerDID, decision)
+	}
+}
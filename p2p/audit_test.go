@@ -0,0 +1,47 @@
+package p2p_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+func TestAuditLog(t *testing.T) {
+	alpha := makeAgent(t, "alpha", []string{"nlp"})
+	beta := makeAgent(t, "beta", []string{"ocr"})
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	audit, err := core.OpenAuditLog(path, beta)
+	if err != nil {
+		t.Fatalf("OpenAuditLog: %v", err)
+	}
+	t.Cleanup(func() { _ = audit.Close() })
+	hA := makeHost(t, alpha)
+	hB, err := p2p.NewHost(context.Background(), beta, p2p.WithAuditLog(audit))
+	if err != nil {
+		t.Fatalf("NewHost: %v", err)
+	}
+	t.Cleanup(func() { _ = hB.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err = p2p.DiscoverAndHandshake(ctx, hA, hB.AddrInfo()); err != nil {
+		t.Fatalf("DiscoverAndHandshake: %v", err)
+	}
+	intent, _ := core.CreateIntent(alpha, nil, []string{"translation"}, "translate")
+	if _, err = hA.SendIntent(ctx, hB.PeerID(), intent); err != nil {
+		t.Fatalf("SendIntent: %v", err)
+	}
+
+	did, records, err := core.VerifyAuditLog(path)
+	if err != nil || did != beta.DID.String() {
+		t.Fatalf("VerifyAuditLog: %s %v", did, err)
+	}
+	if len(records) != 2 || records[0].MsgType != core.MsgHandshake ||
+		records[1].MessageID != intent.ID || records[1].Decision != "rejected" || records[1].PeerDID != alpha.DID.String() {
+		t.Errorf("records: %+v", records)
+	}
+}
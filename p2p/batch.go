@@ -0,0 +1,290 @@
+package p2p
+
+// batch.go — Batched one-way messages.
+//
+// Workflow steps and results, capability announcements, alerts, revocation
+// lists and trust attestations expect no reply, yet by default each one opens a stream
+// of its own.  With WithSendBatching the host instead keeps one long-lived
+// stream per peer on BatchProtocol and queues one-way messages for it.  The
+// queue writes its frames back to back, in ordinary framing, with a single
+// Write once it holds maxBatchBytes or flushInterval after the first frame
+// arrived, whichever comes first.  A burst of small messages then costs one
+// write instead of one stream setup each.
+//
+// The receiver reads frames until the stream closes and dispatches them in
+// order.  Handshakes and intents expect a reply and are ignored on batch
+// streams.  Peers that do not speak BatchProtocol are still served with a
+// stream per message.
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+// BatchProtocol is the libp2p protocol identifier for batched one-way
+// message streams.
+const BatchProtocol protocol.ID = "/agent-semantic-protocol/batch/1.0.0"
+
+const (
+	// DefaultFlushInterval is how long a queued frame waits for company
+	// when WithSendBatching is given 0.
+	DefaultFlushInterval = 2 * time.Millisecond
+	// DefaultMaxBatchBytes is the batch size that triggers an immediate
+	// write when WithSendBatching is given 0.
+	DefaultMaxBatchBytes = 32 * 1024
+)
+
+// batchWriteTimeout bounds one batched write.
+const batchWriteTimeout = 30 * time.Second
+
+// WithSendBatching queues one-way messages per peer and writes them in
+// batches over a persistent stream, flushing after flushInterval or once
+// maxBatchBytes are waiting.  Zero values select DefaultFlushInterval and
+// DefaultMaxBatchBytes.  A send returns once its batch has been written.
+func WithSendBatching(flushInterval time.Duration, maxBatchBytes int) HostOption {
+	return func(ah *AgentHost) {
+		ah.batchEnabled = true
+		ah.batchInterval = flushInterval
+		ah.batchMaxBytes = maxBatchBytes
+	}
+}
+
+// sendQueue coalesces the frames sent to one peer.
+type sendQueue struct {
+	stream   network.Stream
+	interval time.Duration
+	maxBytes int
+	observe  func(frames int)
+
+	mu      sync.Mutex
+	buf     []byte
+	waiters []chan error // one per frame in buf
+	err     error        // set once the queue is closed
+	wake    chan struct{}
+	full    chan struct{}
+	done    chan struct{} // closed with err
+}
+
+// sendOneWay delivers msg to pid, through its send queue when batching is
+// enabled and the peer supports it, or over a stream of its own.
+func (ah *AgentHost) sendOneWay(ctx context.Context, pid peer.ID, msg core.Encoder) error {
+	if ah.batchEnabled {
+		if q, err := ah.sendQueue(ctx, pid); err == nil {
+			var frame bytes.Buffer
+			if err = ah.writePeerMsg(&frame, pid, msg); err != nil {
+				return fmt.Errorf("send: %w", err)
+			}
+			select {
+			case err = <-q.enqueue(frame.Bytes()):
+			case <-ctx.Done():
+				err = ctx.Err()
+			}
+			if err != nil {
+				return fmt.Errorf("send: %w", err)
+			}
+			return nil
+		}
+	}
+	stream, err := ah.newStream(ctx, pid, ah.proto)
+	if err != nil {
+		return fmt.Errorf("open stream: %w", err)
+	}
+	defer stream.Close()
+	if err = ah.writePeerMsg(stream, pid, msg); err != nil {
+		return fmt.Errorf("send: %w", err)
+	}
+	return nil
+}
+
+// sendQueue returns the open send queue for pid, creating it if needed.
+func (ah *AgentHost) sendQueue(ctx context.Context, pid peer.ID) (*sendQueue, error) {
+	ah.queueMu.Lock()
+	defer ah.queueMu.Unlock()
+	if q, ok := ah.queues[pid]; ok {
+		return q, nil
+	}
+	stream, err := ah.newStream(ctx, pid, ah.batchProto)
+	if err != nil {
+		return nil, fmt.Errorf("p2p batch: open stream: %w", err)
+	}
+	interval, maxBytes := ah.batchInterval, ah.batchMaxBytes
+	if interval <= 0 {
+		interval = DefaultFlushInterval
+	}
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBatchBytes
+	}
+	q := &sendQueue{
+		stream:   stream,
+		interval: interval,
+		maxBytes: maxBytes,
+		observe:  ah.observeBatch,
+		wake:     make(chan struct{}, 1),
+		full:     make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+	ah.queues[pid] = q
+	go q.run()
+	go func() {
+		// The peer never writes on a batch stream; EOF or a reset means it
+		// has gone away.
+		_, _ = io.Copy(io.Discard, stream)
+		q.close(ErrSessionClosed)
+	}()
+	go func() {
+		select {
+		case <-ah.done:
+			q.close(ErrSessionClosed)
+		case <-q.done:
+		}
+		ah.queueMu.Lock()
+		if ah.queues[pid] == q {
+			delete(ah.queues, pid)
+		}
+		ah.queueMu.Unlock()
+	}()
+	return q, nil
+}
+
+// dropSendQueue closes the send queue for pid, if any, failing its unsent
+// frames with ErrPeerDisconnected.
+func (ah *AgentHost) dropSendQueue(pid peer.ID) {
+	ah.queueMu.Lock()
+	q, ok := ah.queues[pid]
+	delete(ah.queues, pid)
+	ah.queueMu.Unlock()
+	if ok {
+		q.close(ErrPeerDisconnected)
+	}
+}
+
+// enqueue adds frame to the next batch.  The returned channel receives the
+// outcome of the write that carries it.
+func (q *sendQueue) enqueue(frame []byte) <-chan error {
+	sent := make(chan error, 1)
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.err != nil {
+		sent <- q.err
+		return sent
+	}
+	q.buf = append(q.buf, frame...)
+	q.waiters = append(q.waiters, sent)
+	signal(q.wake)
+	if len(q.buf) >= q.maxBytes {
+		signal(q.full)
+	}
+	return sent
+}
+
+// run flushes the queue until it is closed.  Each batch is given until
+// interval after its first frame to fill up.
+func (q *sendQueue) run() {
+	for {
+		select {
+		case <-q.wake:
+		case <-q.done:
+			return
+		}
+		t := time.NewTimer(q.interval)
+		select {
+		case <-t.C:
+		case <-q.full:
+		case <-q.done:
+			t.Stop()
+			return
+		}
+		t.Stop()
+		if err := q.flush(); err != nil {
+			q.close(fmt.Errorf("p2p batch: write: %w", err))
+			return
+		}
+	}
+}
+
+// flush writes everything queued so far in one Write.
+func (q *sendQueue) flush() error {
+	q.mu.Lock()
+	buf, waiters := q.buf, q.waiters
+	q.buf, q.waiters = nil, nil
+	q.mu.Unlock()
+	if len(waiters) == 0 {
+		return nil
+	}
+	_ = q.stream.SetWriteDeadline(time.Now().Add(batchWriteTimeout))
+	_, err := q.stream.Write(buf)
+	for _, w := range waiters {
+		w <- err
+	}
+	if err == nil {
+		q.observe(len(waiters))
+	}
+	return err
+}
+
+// close fails every queued frame with err and resets the stream.
+func (q *sendQueue) close(err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.err != nil {
+		return
+	}
+	q.err = err
+	close(q.done)
+	for _, w := range q.waiters {
+		w <- err
+	}
+	q.buf, q.waiters = nil, nil
+	_ = q.stream.Reset()
+}
+
+// signal wakes the receiver of c without blocking.
+func signal(c chan struct{}) {
+	select {
+	case c <- struct{}{}:
+	default:
+	}
+}
+
+// ------------------------------------------------------------------ receiver side
+
+// handleBatchStream dispatches the one-way messages arriving on a batch
+// stream, in order, until the stream closes.
+func (ah *AgentHost) handleBatchStream(s network.Stream) {
+	defer s.Close()
+	from := s.Conn().RemotePeer()
+	for {
+		msgType, data, err := ah.readMsg(s)
+		if err != nil {
+			return
+		}
+		if !ah.inspect(from, SourceBatch, msgType, data) {
+			continue
+		}
+		switch msgType {
+		case core.MsgWorkflow:
+			ah.handleIncomingWorkflow(s, data)
+		case core.MsgCapability:
+			ah.handleIncomingCapability(s, data)
+		case core.MsgAlert:
+			ah.handleIncomingAlert(s, data)
+		case core.MsgRevocation:
+			ah.handleIncomingRevocation(s, data)
+		case core.MsgKeyRotation:
+			ah.handleIncomingKeyRotation(s, data)
+		case core.MsgAttestation:
+			ah.handleIncomingAttestation(s, data)
+		case core.MsgWorkflowResult:
+			ah.handleIncomingWorkflowResult(s, data)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package p2p_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/metrics"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+func TestSendBatching(t *testing.T) {
+	alpha := makeAgent(t, "alpha", nil)
+	beta := makeAgent(t, "beta", nil)
+	reg := metrics.NewRegistry()
+	hA, err := p2p.NewHost(context.Background(), alpha, p2p.WithSendBatching(20*time.Millisecond, 0), p2p.WithMetrics(reg))
+	if err != nil {
+		t.Fatalf("NewHost: %v", err)
+	}
+	t.Cleanup(func() { _ = hA.Close() })
+	hB := makeHost(t, beta)
+
+	const n = 40
+	got := make(chan *core.WorkflowMessage, n)
+	hB.OnWorkflow(func(_ peer.ID, msg *core.WorkflowMessage) { got <- msg })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err = p2p.DiscoverAndHandshake(ctx, hA, hB.AddrInfo()); err != nil {
+		t.Fatalf("DiscoverAndHandshake: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			step := &core.WorkflowMessage{WorkflowID: "wf", StepID: fmt.Sprintf("s%d", i), DID: alpha.DID.String()}
+			if err := hA.SendWorkflow(ctx, hB.PeerID(), step); err != nil {
+				t.Errorf("SendWorkflow: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	seen := make(map[string]bool)
+	for len(seen) < n {
+		select {
+		case msg := <-got:
+			seen[msg.StepID] = true
+		case <-ctx.Done():
+			t.Fatalf("received %d of %d steps", len(seen), n)
+		}
+	}
+	writes := reg.Histogram("asp_send_batch_frames", "", nil).Count()
+	if writes == 0 || writes >= n {
+		t.Errorf("%d steps went out in %d writes, want them batched", n, writes)
+	}
+
+	// Frames sent one after another arrive in order.
+	for i := 0; i < 3; i++ {
+		step := &core.WorkflowMessage{WorkflowID: "wf-seq", StepID: fmt.Sprint(i), DID: alpha.DID.String()}
+		if err = hA.SendWorkflow(ctx, hB.PeerID(), step); err != nil {
+			t.Fatalf("SendWorkflow: %v", err)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		if msg := <-got; msg.StepID != fmt.Sprint(i) {
+			t.Errorf("step %d arrived as %q", i, msg.StepID)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package p2p
+
+// broadcast.go — Sending one intent to every able peer and picking a winner.
+//
+// BroadcastIntent offers an intent to every known peer that has all of its
+// required capabilities and waits for all answers.  Among the peers that
+// accepted, the winner is chosen by the host's SelectionPolicy for the
+// intent's first capability, so load spreads over equivalent responders
+// instead of always going to the best cosine match.  With
+// WithAssessmentWeight, the ranking the policy starts from also counts each
+// responder's own SimilarityScore.
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+// BroadcastResult is the outcome of BroadcastIntent.
+type BroadcastResult struct {
+	// Winner is the chosen accepting response, nil if nobody accepted.
+	Winner     *core.NegotiationResponse
+	WinnerPeer peer.ID
+	// Responses and Errors hold every peer's answer or failure.
+	Responses map[peer.ID]*core.NegotiationResponse
+	Errors    map[peer.ID]error
+}
+
+// WithAssessmentWeight makes BroadcastIntent rank the peers that accepted by
+// blending its local cosine similarity with the SimilarityScore each one
+// reported, weight in [0,1] going to the latter (see core.RankAssessed).
+// The default, 0, ignores responders' assessments.
+func WithAssessmentWeight(weight float64) HostOption {
+	return func(ah *AgentHost) { ah.assessed = weight }
+}
+
+// BroadcastIntent sends intent to every known peer offering its required
+// capabilities and picks a winner among those that accept.  It fails only
+// if no peer is able to take the intent.
+func (ah *AgentHost) BroadcastIntent(ctx context.Context, intent *core.IntentMessage) (*BroadcastResult, error) {
+	targets := ah.ablePeers(intent.Capabilities)
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("p2p broadcast: %w: no peer offers %v", core.ErrCapabilityMissing, intent.Capabilities)
+	}
+
+	res := &BroadcastResult{
+		Responses: make(map[peer.ID]*core.NegotiationResponse, len(targets)),
+		Errors:    make(map[peer.ID]error),
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for pid := range targets {
+		wg.Add(1)
+		go func(pid peer.ID) {
+			defer wg.Done()
+			resp, err := ah.SendIntent(ctx, pid, intent)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				res.Errors[pid] = err
+				return
+			}
+			res.Responses[pid] = resp
+		}(pid)
+	}
+	wg.Wait()
+
+	var accepted []core.AgentProfile
+	byAgent := make(map[string]peer.ID)
+	assessed := make(map[string]float32)
+	for pid, resp := range res.Responses {
+		if resp.Accepted {
+			accepted = append(accepted, targets[pid])
+			byAgent[targets[pid].AgentID] = pid
+			if resp.SimilarityScore > 0 {
+				assessed[targets[pid].AgentID] = resp.SimilarityScore
+			}
+		}
+	}
+	if len(accepted) == 0 {
+		return res, nil
+	}
+	var capability string
+	if len(intent.Capabilities) > 0 {
+		capability = intent.Capabilities[0]
+	}
+	sel := ah.selection.For(capability)
+	ordered := sel.Order(core.Selection{
+		Capability: capability,
+		Candidates: core.RankAssessed(intent.IntentVector, accepted, assessed, ah.assessed),
+		Assessed:   assessed,
+	})
+	winner := ordered[0].AgentID
+	sel.Picked(capability, winner)
+	res.WinnerPeer = byAgent[winner]
+	res.Winner = res.Responses[res.WinnerPeer]
+	return res, nil
+}
+
+// ablePeers returns the known peers offering all of capabilities.
+func (ah *AgentHost) ablePeers(capabilities []string) map[peer.ID]core.AgentProfile {
+	able := make(map[string]bool)
+	for _, p := range ah.discovery.FindByCapability(capabilities...) {
+		able[p.DID] = true
+	}
+	targets := make(map[peer.ID]core.AgentProfile)
+	for pid, profile := range ah.KnownPeers() {
+		if able[profile.DID] {
+			targets[pid] = profile
+		}
+	}
+	return targets
+}
@@ -0,0 +1,46 @@
+package p2p_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+func TestBroadcastIntentRoundRobin(t *testing.T) {
+	requester := makeAgent(t, "requester", nil)
+	policy := core.NewSelectionPolicy(nil)
+	policy.Set("ocr", core.NewRoundRobin())
+	hA, err := p2p.NewHost(context.Background(), requester, p2p.WithSelection(policy))
+	if err != nil {
+		t.Fatalf("NewHost: %v", err)
+	}
+	t.Cleanup(func() { _ = hA.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for _, id := range []string{"ocr-1", "ocr-2"} {
+		w := makeHost(t, makeAgent(t, id, []string{"ocr"}))
+		if _, err = p2p.DiscoverAndHandshake(ctx, hA, w.AddrInfo()); err != nil {
+			t.Fatalf("DiscoverAndHandshake(%s): %v", id, err)
+		}
+	}
+
+	var winners []string
+	for i := 0; i < 2; i++ {
+		intent, _ := core.CreateIntent(requester, nil, []string{"ocr"}, "page")
+		res, err := hA.BroadcastIntent(ctx, intent)
+		if err != nil {
+			t.Fatalf("BroadcastIntent: %v", err)
+		}
+		if len(res.Responses) != 2 || res.Winner == nil {
+			t.Fatalf("broadcast result: %+v", res)
+		}
+		winners = append(winners, res.Winner.AgentID)
+	}
+	if winners[0] == winners[1] {
+		t.Errorf("round robin picked %s twice", winners[0])
+	}
+}
@@ -0,0 +1,271 @@
+package p2p
+
+// checkpoint.go — Persisting workflow state and resuming workflows.
+//
+// With SetWorkflowStore the orchestrator saves a WorkflowState after each
+// step of RunSequential and RunGraph finishes, and after each
+// compensation.  If the orchestrator process dies, Resume reloads the state
+// in a new one: steps that succeeded are not run again, and a workflow that
+// was rolling back finishes its compensations.  Steps that were
+// outstanding when the process died are sent again, so agents should treat
+// step intents as idempotent per workflow_id and step_id.
+//
+// Scheduling hints from WithWorkflowOptions are not stored; pass them to
+// Resume's context again.  FileWorkflowStore keeps one JSON file per
+// workflow; the store package has a database-backed implementation.
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrWorkflowNotFound is returned by WorkflowStore.Load for a workflow it
+// has no state for.
+var ErrWorkflowNotFound = fmt.Errorf("p2p: workflow not found")
+
+// Status values of a WorkflowState.
+const (
+	WorkflowRunning      = "running"
+	WorkflowCompensating = "compensating" // A FailCompensate step failed; compensations are running
+	WorkflowSucceeded    = "succeeded"
+	WorkflowFailed       = "failed"
+)
+
+// WorkflowState is the checkpoint of one RunSequential or RunGraph call.
+type WorkflowState struct {
+	WorkflowID  string         `json:"workflow_id"`
+	Graph       bool           `json:"graph,omitempty"` // Run by RunGraph rather than RunSequential
+	Steps       []WorkflowStep `json:"steps"`
+	Results     []StepResult   `json:"results,omitempty"` // Finished steps, in the order they finished
+	Next        string         `json:"next,omitempty"`    // RunSequential: the step to run next
+	Status      string         `json:"status"`
+	Error       string         `json:"error,omitempty"`       // Why the workflow failed
+	Compensated []string       `json:"compensated,omitempty"` // Steps already compensated
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+// succeeded returns the steps that succeeded, in the order they finished,
+// leaving out those already compensated.
+func (s *WorkflowState) succeeded() []WorkflowStep {
+	byID := make(map[string]WorkflowStep, len(s.Steps))
+	for _, st := range s.Steps {
+		byID[st.ID] = st
+	}
+	compensated := make(map[string]bool, len(s.Compensated))
+	for _, id := range s.Compensated {
+		compensated[id] = true
+	}
+	var out []WorkflowStep
+	for _, r := range s.Results {
+		if r.Accepted && !compensated[r.StepID] {
+			out = append(out, byID[r.StepID])
+		}
+	}
+	return out
+}
+
+// resultMap returns the finished steps' results by step ID.
+func (s *WorkflowState) resultMap() map[string]StepResult {
+	m := make(map[string]StepResult, len(s.Results))
+	for _, r := range s.Results {
+		m[r.StepID] = r
+	}
+	return m
+}
+
+// WorkflowStore persists workflow state.  Implementations must be safe for
+// concurrent use.
+type WorkflowStore interface {
+	Save(state *WorkflowState) error
+	// Load returns ErrWorkflowNotFound if there is no state for workflowID.
+	Load(workflowID string) (*WorkflowState, error)
+	Delete(workflowID string) error
+	// List returns the IDs of all stored workflows.
+	List() ([]string, error)
+}
+
+// SetWorkflowStore makes RunSequential and RunGraph checkpoint workflows
+// started from now on to s.  Nil stops checkpointing.
+func (o *WorkflowOrchestrator) SetWorkflowStore(s WorkflowStore) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.store = s
+}
+
+// Resume continues workflowID from its last checkpoint.  Succeeded steps
+// are not run again; a workflow that failed while rolling back finishes its
+// compensations.  A workflow that had already finished is not run again:
+// its results are returned, with an error if it failed.
+func (o *WorkflowOrchestrator) Resume(ctx context.Context, workflowID string) ([]StepResult, error) {
+	o.mu.Lock()
+	store := o.store
+	o.mu.Unlock()
+	if store == nil {
+		return nil, fmt.Errorf("workflow %q: no workflow store", workflowID)
+	}
+	state, err := store.Load(workflowID)
+	if err != nil {
+		return nil, fmt.Errorf("workflow %q: %w", workflowID, err)
+	}
+
+	var g *WorkflowGraph
+	if state.Graph {
+		if g, err = NewWorkflowGraph(state.Steps...); err != nil {
+			return nil, fmt.Errorf("workflow %q: %w", workflowID, err)
+		}
+	}
+	switch state.Status {
+	case WorkflowRunning:
+		if g != nil {
+			return o.runGraph(ctx, g, state)
+		}
+		return o.runSequential(ctx, state)
+	case WorkflowCompensating:
+		ctx, stop := context.WithCancel(ctx)
+		defer stop()
+		run := o.newRun(ctx, workflowID, state.Steps, state)
+		err = o.compensate(ctx, run, state.succeeded(), state.resultMap(), errors.New(state.Error))
+		return stateResults(g, state), err
+	case WorkflowFailed:
+		return stateResults(g, state), fmt.Errorf("workflow %q: %s", workflowID, state.Error)
+	}
+	return stateResults(g, state), nil
+}
+
+// stateResults returns the results of state as its Run method would: in
+// the order of g.Steps() for graphs, in execution order otherwise.
+func stateResults(g *WorkflowGraph, state *WorkflowState) []StepResult {
+	if g == nil {
+		return append([]StepResult(nil), state.Results...)
+	}
+	return g.order(state.resultMap())
+}
+
+// checkpoint saves the run's state, if the orchestrator has a store.  A
+// failed save is logged; the workflow carries on.
+func (r *workflowRun) checkpoint() {
+	if r.store == nil {
+		return
+	}
+	r.state.UpdatedAt = time.Now()
+	if err := r.store.Save(r.state); err != nil {
+		r.o.host.log.Log(context.Background(), slog.LevelWarn, "workflow checkpoint failed",
+			"workflow_id", r.id, "error", err)
+	}
+}
+
+// finish records that the workflow ended with err, or succeeded.
+func (r *workflowRun) finish(err error) {
+	r.state.Status, r.state.Next = WorkflowSucceeded, ""
+	if err != nil {
+		r.state.Status, r.state.Error = WorkflowFailed, err.Error()
+	}
+	r.checkpoint()
+}
+
+// ------------------------------------------------------------------ file store
+
+// FileWorkflowStore is a WorkflowStore keeping one JSON file per workflow
+// in a directory.  Files are replaced atomically on every Save.
+type FileWorkflowStore struct {
+	mu  sync.Mutex
+	dir string
+}
+
+// OpenFileWorkflowStore uses dir (created if missing) for workflow files.
+func OpenFileWorkflowStore(dir string) (*FileWorkflowStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("workflow store: create %s: %w", dir, err)
+	}
+	return &FileWorkflowStore{dir: dir}, nil
+}
+
+// Save implements WorkflowStore.
+func (s *FileWorkflowStore) Save(state *WorkflowState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("workflow store: encode: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	path := s.path(state.WorkflowID)
+	tmp, err := os.CreateTemp(s.dir, filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("workflow store: create temp: %w", err)
+	}
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("workflow store: write: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("workflow store: close: %w", err)
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("workflow store: rename: %w", err)
+	}
+	return nil
+}
+
+// Load implements WorkflowStore.
+func (s *FileWorkflowStore) Load(workflowID string) (*WorkflowState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := os.ReadFile(s.path(workflowID))
+	if os.IsNotExist(err) {
+		return nil, ErrWorkflowNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("workflow store: read %s: %w", workflowID, err)
+	}
+	var state WorkflowState
+	if err = json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("workflow store: parse %s: %w", workflowID, err)
+	}
+	return &state, nil
+}
+
+// Delete implements WorkflowStore.
+func (s *FileWorkflowStore) Delete(workflowID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.Remove(s.path(workflowID)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("workflow store: delete %s: %w", workflowID, err)
+	}
+	return nil
+}
+
+// List implements WorkflowStore.
+func (s *FileWorkflowStore) List() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	files, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("workflow store: list %s: %w", s.dir, err)
+	}
+	var ids []string
+	for _, f := range files {
+		name, ok := strings.CutSuffix(f.Name(), ".json")
+		if !ok || f.IsDir() {
+			continue
+		}
+		if id, err := url.PathUnescape(name); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (s *FileWorkflowStore) path(workflowID string) string {
+	return filepath.Join(s.dir, url.PathEscape(workflowID)+".json")
+}
@@ -0,0 +1,107 @@
+package p2p_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+func TestFileWorkflowStore(t *testing.T) {
+	s, err := p2p.OpenFileWorkflowStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("OpenFileWorkflowStore: %v", err)
+	}
+	state := &p2p.WorkflowState{
+		WorkflowID: "orders/42",
+		Steps:      []p2p.WorkflowStep{{ID: "charge", Capability: "charge", OnFailure: p2p.FailCompensate}},
+		Results:    []p2p.StepResult{{StepID: "charge", AgentID: "shop", Accepted: true, Timestamp: time.Unix(1, 0).UTC()}},
+		Status:     p2p.WorkflowRunning,
+		UpdatedAt:  time.Unix(2, 0).UTC(),
+	}
+	if err = s.Save(state); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	got, err := s.Load("orders/42")
+	if err != nil || !reflect.DeepEqual(got, state) {
+		t.Fatalf("Load = %+v, %v; want %+v", got, err, state)
+	}
+	if ids, err := s.List(); err != nil || len(ids) != 1 || ids[0] != "orders/42" {
+		t.Errorf("List = %v, %v", ids, err)
+	}
+	if err = s.Delete("orders/42"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err = s.Load("orders/42"); !errors.Is(err, p2p.ErrWorkflowNotFound) {
+		t.Errorf("Load after Delete: %v, want ErrWorkflowNotFound", err)
+	}
+}
+
+func TestWorkflowResume(t *testing.T) {
+	alpha := makeAgent(t, "alpha", nil)
+	beta := makeAgent(t, "beta", []string{"fetch", "parse"})
+	hA := makeHost(t, alpha)
+	hB := makeHost(t, beta)
+
+	var mu sync.Mutex
+	sent := make(map[string]int) // capability → intents received
+	hB.OnIntent(func(_ peer.ID, in *core.IntentMessage) *core.NegotiationResponse {
+		mu.Lock()
+		sent[in.Capabilities[0]]++
+		mu.Unlock()
+		resp, _ := core.DefaultNegotiationHandler(beta)(in)
+		return resp
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := p2p.DiscoverAndHandshake(ctx, hA, hB.AddrInfo()); err != nil {
+		t.Fatalf("DiscoverAndHandshake: %v", err)
+	}
+	store, err := p2p.OpenFileWorkflowStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The checkpoint a crashed orchestrator left behind after "fetch".
+	steps := []p2p.WorkflowStep{{ID: "fetch", Capability: "fetch"}, {ID: "parse", Capability: "parse"}}
+	_ = store.Save(&p2p.WorkflowState{
+		WorkflowID: "wf",
+		Steps:      steps,
+		Results:    []p2p.StepResult{{StepID: "fetch", AgentID: beta.ID, Accepted: true}},
+		Next:       "parse",
+		Status:     p2p.WorkflowRunning,
+	})
+
+	o := p2p.NewOrchestrator(hA, 5*time.Second)
+	o.SetWorkflowStore(store)
+	results, err := o.Resume(ctx, "wf")
+	if err != nil || len(results) != 2 || !results[1].Accepted {
+		t.Fatalf("Resume: %+v, %v", results, err)
+	}
+	mu.Lock()
+	if sent["fetch"] != 0 || sent["parse"] != 1 {
+		t.Errorf("intents sent on resume = %v; want only parse", sent)
+	}
+	mu.Unlock()
+	state, err := store.Load("wf")
+	if err != nil || state.Status != p2p.WorkflowSucceeded || len(state.Results) != 2 {
+		t.Errorf("final checkpoint = %+v, %v", state, err)
+	}
+
+	// A finished workflow is not run again.
+	if _, err = o.Resume(ctx, "wf"); err != nil {
+		t.Errorf("Resume(finished): %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if sent["parse"] != 1 {
+		t.Errorf("finished workflow ran again: %v", sent)
+	}
+}
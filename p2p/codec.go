@@ -0,0 +1,98 @@
+package p2p
+
+import (
+	"slices"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+// WithCodecs offers the named payload codecs (see core.Codec), preferred
+// first, when this host initiates a handshake.  Peers that accept one reply
+// in it from then on; everyone else keeps using protobuf.  Incoming frames
+// are accepted in any registered codec regardless of this option.
+func WithCodecs(names ...string) HostOption {
+	return func(ah *AgentHost) { ah.codecs = append([]string(nil), names...) }
+}
+
+// WithProtocolVersions limits the protocol versions the host offers and
+// accepts to versions, highest first, so a rollout can hold a mesh at an
+// older version (or frame format) until every agent runs the new build.
+// Versions this build does not support are ignored.
+func WithProtocolVersions(versions ...string) HostOption {
+	return func(ah *AgentHost) {
+		ah.versions = nil
+		for _, v := range versions {
+			if _, ok := core.LookupVersion(v); ok {
+				ah.versions = append(ah.versions, v)
+			}
+		}
+	}
+}
+
+// offeredVersions returns the versions the host offers and accepts.
+func (ah *AgentHost) offeredVersions() []string {
+	if len(ah.versions) == 0 {
+		return core.SupportedVersions()
+	}
+	return ah.versions
+}
+
+// negotiateVersion picks the version to use with a peer offering offered.
+func (ah *AgentHost) negotiateVersion(offered []string) (core.VersionInfo, error) {
+	if len(ah.versions) == 0 {
+		return core.NegotiateVersion(offered)
+	}
+	return core.NegotiateVersion(slices.DeleteFunc(slices.Clone(offered), func(v string) bool {
+		return !slices.Contains(ah.versions, v)
+	}))
+}
+
+// PeerCodec returns the codec negotiated with peerID, or core.ProtobufCodec.
+func (ah *AgentHost) PeerCodec(peerID peer.ID) core.Codec {
+	ah.mu.RLock()
+	defer ah.mu.RUnlock()
+	if c, ok := ah.peerCodecs[peerID]; ok {
+		return c
+	}
+	return core.ProtobufCodec
+}
+
+// setPeerCodec records the codec chosen in a handshake with peerID.
+func (ah *AgentHost) setPeerCodec(peerID peer.ID, c core.Codec) {
+	ah.mu.Lock()
+	defer ah.mu.Unlock()
+	ah.peerCodecs[peerID] = c
+}
+
+// acceptedCodec returns the codec a handshake responder chose, provided it
+// is one we offered and the negotiated version allows it.
+func (ah *AgentHost) acceptedCodec(resp *core.HandshakeMessage, v core.VersionInfo) core.Codec {
+	if len(resp.Codecs) == 0 || !slices.Contains(ah.codecs, resp.Codecs[0]) {
+		return core.ProtobufCodec
+	}
+	return v.NegotiateCodec(resp.Codecs[:1])
+}
+
+// PeerVersion returns the protocol version negotiated with peerID.  ok is
+// false before the first handshake.
+func (ah *AgentHost) PeerVersion(peerID peer.ID) (v core.VersionInfo, ok bool) {
+	ah.mu.RLock()
+	defer ah.mu.RUnlock()
+	v, ok = ah.peerVersions[peerID]
+	return v, ok
+}
+
+// setPeerVersion records the version agreed in a handshake with peerID.
+func (ah *AgentHost) setPeerVersion(peerID peer.ID, v core.VersionInfo) {
+	ah.mu.Lock()
+	defer ah.mu.Unlock()
+	ah.peerVersions[peerID] = v
+}
+
+// peerAllows reports whether f may be used with peerID.  Peers we have not
+// shaken hands with get the benefit of the doubt.
+func (ah *AgentHost) peerAllows(peerID peer.ID, f core.Feature) bool {
+	v, ok := ah.PeerVersion(peerID)
+	return !ok || v.Has(f)
+}
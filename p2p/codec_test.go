@@ -0,0 +1,48 @@
+package p2p_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+// TestJSONCodecNegotiated verifies that a host offering JSON gets it from a
+// default peer and that intents still round-trip.
+func TestJSONCodecNegotiated(t *testing.T) {
+	alpha := makeAgent(t, "alpha", []string{"nlp"})
+	beta := makeAgent(t, "beta", []string{"summarisation"})
+
+	hA, err := p2p.NewHost(context.Background(), alpha, p2p.WithCodecs("json"))
+	if err != nil {
+		t.Fatalf("NewHost: %v", err)
+	}
+	t.Cleanup(func() { _ = hA.Close() })
+	hB := makeHost(t, beta)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err = hA.Connect(ctx, hB.AddrInfo()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	if _, err = hA.Handshake(ctx, hB.PeerID()); err != nil {
+		t.Fatalf("Handshake: %v", err)
+	}
+	if c := hA.PeerCodec(hB.PeerID()); c.Name() != "json" {
+		t.Errorf("alpha's codec for beta: got %s want json", c.Name())
+	}
+	if c := hB.PeerCodec(hA.PeerID()); c.Name() != "json" {
+		t.Errorf("beta's codec for alpha: got %s want json", c.Name())
+	}
+
+	intent, _ := core.CreateIntent(alpha, []float32{0.9}, []string{"summarisation"}, "doc")
+	resp, err := hA.SendIntent(ctx, hB.PeerID(), intent)
+	if err != nil {
+		t.Fatalf("SendIntent: %v", err)
+	}
+	if !resp.Accepted {
+		t.Errorf("expected accepted, got reason: %s", resp.Reason)
+	}
+}
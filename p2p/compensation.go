@@ -0,0 +1,94 @@
+package p2p
+
+// compensation.go — Saga-style rollback of failed workflows.
+//
+// A step may name a Compensate step that undoes it.  When a FailCompensate
+// step fails for good, RunSequential and RunGraph stop the workflow and run
+// the Compensate step of every step that had succeeded, most recent first.
+// Each compensation goes to the agent that executed the step it undoes,
+// since that agent holds whatever state the step created; it is not handed
+// to another candidate.  The outcome of the whole rollback is returned as a
+// *RollbackError wrapping the original failure.
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// CompensationResult is the outcome of undoing one succeeded step.
+type CompensationResult struct {
+	StepID  string     // The step undone
+	AgentID string     // The agent that executed it, and was asked to undo it
+	Result  StepResult // The Compensate step's result
+	Err     error      // nil if the compensation succeeded
+}
+
+// RollbackError is returned when a FailCompensate step fails.  It wraps
+// the step's failure, so errors.Is and errors.As see through it.
+type RollbackError struct {
+	Cause         error
+	Compensations []CompensationResult // Most recent step first; steps without Compensate are left out
+}
+
+func (e *RollbackError) Error() string {
+	if failed := e.Failed(); len(failed) > 0 {
+		return fmt.Sprintf("%v (compensation failed for %s)", e.Cause, strings.Join(failed, ", "))
+	}
+	return fmt.Sprintf("%v (rolled back %d steps)", e.Cause, len(e.Compensations))
+}
+
+func (e *RollbackError) Unwrap() error { return e.Cause }
+
+// Complete reports whether every compensation succeeded.
+func (e *RollbackError) Complete() bool { return len(e.Failed()) == 0 }
+
+// Failed returns the IDs of the steps whose compensation failed.
+func (e *RollbackError) Failed() []string {
+	var ids []string
+	for _, c := range e.Compensations {
+		if c.Err != nil {
+			ids = append(ids, c.StepID)
+		}
+	}
+	return ids
+}
+
+// compensate runs the Compensate step of each succeeded step, most recent
+// first, each on the agent recorded in results, and returns cause wrapped
+// in a RollbackError.  The run is checkpointed after each compensation.
+// RunGraph passes its parent context, since the run's own context is
+// cancelled by the failure.
+func (o *WorkflowOrchestrator) compensate(
+	ctx context.Context,
+	run *workflowRun,
+	succeeded []WorkflowStep,
+	results map[string]StepResult,
+	cause error,
+) error {
+	rollback := &RollbackError{Cause: cause}
+	for i := len(succeeded) - 1; i >= 0; i-- {
+		s := succeeded[i]
+		if s.Compensate == nil {
+			continue
+		}
+		c := *s.Compensate
+		c.agent = results[s.ID].AgentID
+		r, err := o.runStep(ctx, run, c, results)
+		reason := r.Reason
+		if err != nil {
+			reason = err.Error()
+		}
+		rollback.Compensations = append(rollback.Compensations, CompensationResult{
+			StepID:  s.ID,
+			AgentID: c.agent,
+			Result:  r,
+			Err:     err,
+		})
+		o.record(run.id, WorkflowEvent{Kind: WorkflowCompensated, StepID: s.ID, AgentID: c.agent, Reason: reason})
+		run.state.Compensated = append(run.state.Compensated, s.ID)
+		run.checkpoint()
+	}
+	run.finish(rollback)
+	return rollback
+}
@@ -0,0 +1,71 @@
+package p2p_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+func TestWorkflowCompensation(t *testing.T) {
+	alpha := makeAgent(t, "alpha", nil)
+	shop := makeAgent(t, "shop", []string{"charge", "refund"})
+	courier := makeAgent(t, "courier", []string{"ship", "refund"})
+	hA := makeHost(t, alpha)
+	hS := makeHost(t, shop)
+	hC := makeHost(t, courier)
+
+	var mu sync.Mutex
+	refunds := make(map[string]int) // agent ID → refunds received
+	serve := func(a *core.Agent) p2p.IntentCallback {
+		return func(_ peer.ID, in *core.IntentMessage) *core.NegotiationResponse {
+			resp, _ := core.DefaultNegotiationHandler(a)(in)
+			switch in.Capabilities[0] {
+			case "refund":
+				mu.Lock()
+				refunds[a.ID]++
+				mu.Unlock()
+			case "ship":
+				resp.Accepted, resp.Reason = false, "out of stock"
+				_ = core.SignResponse(a, resp)
+			}
+			return resp
+		}
+	}
+	hS.OnIntent(serve(shop))
+	hC.OnIntent(serve(courier))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for _, h := range []*p2p.AgentHost{hS, hC} {
+		if _, err := p2p.DiscoverAndHandshake(ctx, hA, h.AddrInfo()); err != nil {
+			t.Fatalf("DiscoverAndHandshake: %v", err)
+		}
+	}
+
+	o := p2p.NewOrchestrator(hA, 5*time.Second)
+	_, err := o.RunSequential(ctx, "order", []p2p.WorkflowStep{
+		{ID: "charge", Capability: "charge", Compensate: &p2p.WorkflowStep{ID: "refund", Capability: "refund"}},
+		{ID: "ship", Capability: "ship", OnFailure: p2p.FailCompensate},
+	})
+	var rollback *p2p.RollbackError
+	if !errors.As(err, &rollback) {
+		t.Fatalf("RunSequential: got %v, want a RollbackError", err)
+	}
+	if !rollback.Complete() || len(rollback.Compensations) != 1 {
+		t.Fatalf("rollback = %+v", rollback)
+	}
+	if c := rollback.Compensations[0]; c.StepID != "charge" || c.AgentID != shop.ID || !c.Result.Accepted {
+		t.Errorf("compensation = %+v", c)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if refunds[shop.ID] != 1 || refunds[courier.ID] != 0 {
+		t.Errorf("refunds = %v; want one, sent to the agent that charged", refunds)
+	}
+}
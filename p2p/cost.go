@@ -0,0 +1,42 @@
+package p2p
+
+// cost.go — Holding responses to the intent's budget.
+//
+// Both sides enforce an intent's core.Budget against the core.CostEstimate
+// a response carries.  When answering, the host replaces an acceptance
+// whose estimate exceeds the budget with a rejection giving
+// core.ReasonOverBudget; the rejection keeps the estimate, so the
+// requester sees what the intent would have taken.  The orchestrator
+// treats an acceptance over a step's Budget from a peer that does not
+// enforce it as a rejection too, so the step can fail over, and Auction
+// counts such bids as rejected.
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+// fitBudget returns resp, or a rejection of intent if resp accepts it at
+// a cost beyond its Budget.
+func (ah *AgentHost) fitBudget(intent *core.IntentMessage, resp *core.NegotiationResponse) *core.NegotiationResponse {
+	if err := core.CheckCost(intent, resp); err != nil {
+		ah.log.Log(context.Background(), slog.LevelInfo, "intent over budget",
+			core.LogKeyIntentID, intent.ID, "error", err)
+		rejection := ah.rejection(intent, core.ReasonOverBudget)
+		rejection.Cost = resp.Cost
+		_ = core.SignResponse(ah.agent, rejection)
+		return rejection
+	}
+	return resp
+}
+
+// checkStepCost records resp's cost estimate in r and turns an acceptance
+// beyond intent's Budget into a rejection, so the step can fail over.
+func checkStepCost(intent *core.IntentMessage, resp *core.NegotiationResponse, r *StepResult) {
+	r.Cost = resp.Cost
+	if core.CheckCost(intent, resp) != nil {
+		r.Accepted, r.Reason = false, core.ReasonOverBudget
+	}
+}
@@ -0,0 +1,58 @@
+package p2p_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+func TestWorkflowStepBudget(t *testing.T) {
+	alpha := makeAgent(t, "alpha", nil)
+	hA := makeHost(t, alpha)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	peers := make(map[string]peer.ID)
+	for id, amount := range map[string]int64{"pricey": 500, "cheap": 100} {
+		a := makeAgent(t, id, []string{"translate"})
+		h := makeHost(t, a)
+		peers[id] = h.PeerID()
+		h.OnIntent(func(_ peer.ID, in *core.IntentMessage) *core.NegotiationResponse {
+			resp, _ := core.DefaultNegotiationHandler(a)(in)
+			resp.Cost = &core.CostEstimate{Amount: amount, Currency: "USD"}
+			_ = core.SignResponse(a, resp)
+			return resp
+		})
+		if _, err := p2p.DiscoverAndHandshake(ctx, hA, h.AddrInfo()); err != nil {
+			t.Fatalf("DiscoverAndHandshake: %v", err)
+		}
+	}
+	budget := &core.Budget{MaxAmount: 200, Currency: "USD"}
+
+	intent, _ := core.CreateIntent(alpha, []float32{1, 0}, []string{"translate"}, "hola")
+	intent.Budget = budget
+	_ = core.SignIntent(alpha, intent)
+	resp, err := hA.SendIntent(ctx, peers["pricey"], intent)
+	if err != nil {
+		t.Fatalf("SendIntent: %v", err)
+	}
+	if resp.Accepted || resp.Reason != core.ReasonOverBudget || resp.Cost == nil || resp.Cost.Amount != 500 {
+		t.Fatalf("pricey answered %+v; want an over-budget rejection keeping its estimate", resp)
+	}
+
+	o := p2p.NewOrchestrator(hA, 5*time.Second)
+	if err := o.SetFailover(p2p.FailoverPolicy{MaxCandidates: 2}); err != nil {
+		t.Fatalf("SetFailover: %v", err)
+	}
+	results, err := o.RunWorkflow(ctx, "wf", []p2p.WorkflowStep{{ID: "t", Capability: "translate", Budget: budget}})
+	if err != nil || len(results) != 1 {
+		t.Fatalf("RunWorkflow: %+v, %v", results, err)
+	}
+	if r := results[0]; !r.Accepted || r.AgentID != "cheap" || r.Cost == nil || r.Cost.Amount != 100 {
+		t.Errorf("step result = %+v; want accepted by cheap at 100", r)
+	}
+}
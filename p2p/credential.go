@@ -0,0 +1,72 @@
+package p2p
+
+// credential.go — Requiring verifiable credentials.
+//
+// Hosts present the agent's core.VerifiableCredentials in handshakes and
+// announcements, and record the ones peers present in their profiles.
+// Handshakes fail and announcements are dropped when a credential does not
+// verify or is about another agent.  WithCredentialPolicy makes the host
+// refuse intents whose sender lacks a required credential, with
+// core.ReasonCredentialRequired; SetCredentialPolicy makes an orchestrator
+// send steps only to agents that hold the credentials required for the
+// step's capability.
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+// WithCredentialPolicy makes the host refuse intents whose sender does not
+// meet p with the credentials it presented.
+func WithCredentialPolicy(p core.CredentialPolicy) HostOption {
+	return func(ah *AgentHost) { ah.credPolicy = p }
+}
+
+// checkCredentials returns a rejection of intent if its sender does not
+// meet the host's credential policy, nil otherwise.  The sender's
+// credentials come from its handshake with from, or else from discovery.
+func (ah *AgentHost) checkCredentials(from peer.ID, intent *core.IntentMessage) *core.NegotiationResponse {
+	if len(ah.credPolicy.Requirements) == 0 {
+		return nil
+	}
+	ah.mu.RLock()
+	profile, ok := ah.known[from.String()]
+	ah.mu.RUnlock()
+	if !ok || profile.DID != intent.DID {
+		profile, _ = ah.discovery.FindByDID(intent.DID)
+	}
+	err := ah.credPolicy.Check(intent.Capabilities, intent.DID, profile.Credentials, time.Now())
+	if err == nil {
+		return nil
+	}
+	ah.log.Log(context.Background(), slog.LevelInfo, "intent refused",
+		core.LogKeyPeer, from.String(), core.LogKeyIntentID, intent.ID, "error", err)
+	return ah.rejection(intent, core.ReasonCredentialRequired)
+}
+
+// SetCredentialPolicy makes the orchestrator send steps only to agents
+// whose credentials meet p for the step's capability.  It applies to steps
+// dispatched from now on; compensations are exempt, since they must go to
+// the agent that ran the step.
+func (o *WorkflowOrchestrator) SetCredentialPolicy(p core.CredentialPolicy) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.credentials = p
+	return nil
+}
+
+// credentialed reports whether c meets the orchestrator's credential
+// policy for capability.
+func (o *WorkflowOrchestrator) credentialed(c core.AgentProfile, capability string) bool {
+	o.mu.Lock()
+	p := o.credentials
+	o.mu.Unlock()
+	return p.Check([]string{capability}, c.DID, c.Credentials, time.Now()) == nil
+}
@@ -0,0 +1,59 @@
+package p2p_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+func TestCredentialPolicyRefusesIntents(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	issuer := makeAgent(t, "issuer", nil)
+	beta := makeAgent(t, "beta", []string{"summarisation"})
+	hB, err := p2p.NewHost(ctx, beta, p2p.WithCredentialPolicy(core.CredentialPolicy{
+		Requirements: []core.CredentialRequirement{
+			{Capability: "summarisation", Type: "Auditor", Issuers: []string{issuer.DID.String()}},
+		},
+	}))
+	if err != nil {
+		t.Fatalf("NewHost: %v", err)
+	}
+	t.Cleanup(func() { _ = hB.Close() })
+
+	alpha := makeAgent(t, "alpha", nil)
+	gamma := makeAgent(t, "gamma", nil)
+	cred, err := core.NewCredential(issuer, gamma.DID.String(), []string{"Auditor"}, nil, time.Hour)
+	if err != nil {
+		t.Fatalf("NewCredential: %v", err)
+	}
+	gamma.Credentials = []core.VerifiableCredential{cred}
+
+	for _, tc := range []struct {
+		agent  *core.Agent
+		accept bool
+	}{{alpha, false}, {gamma, true}} {
+		h := makeHost(t, tc.agent)
+		if _, err := p2p.DiscoverAndHandshake(ctx, h, hB.AddrInfo()); err != nil {
+			t.Fatalf("DiscoverAndHandshake(%s): %v", tc.agent.ID, err)
+		}
+		intent, err := core.CreateIntent(tc.agent, []float32{0.9, 0.1, 0.5}, []string{"summarisation"}, "summarise this doc")
+		if err != nil {
+			t.Fatalf("CreateIntent: %v", err)
+		}
+		resp, err := h.SendIntent(ctx, hB.PeerID(), intent)
+		if err != nil {
+			t.Fatalf("SendIntent(%s): %v", tc.agent.ID, err)
+		}
+		if resp.Accepted != tc.accept {
+			t.Errorf("%s: accepted = %v (%s); want %v", tc.agent.ID, resp.Accepted, resp.Reason, tc.accept)
+		}
+		if !tc.accept && resp.Reason != core.ReasonCredentialRequired {
+			t.Errorf("%s: reason = %q; want %q", tc.agent.ID, resp.Reason, core.ReasonCredentialRequired)
+		}
+	}
+}
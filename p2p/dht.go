@@ -0,0 +1,110 @@
+package p2p
+
+// dht.go — Kademlia DHT capability discovery.
+//
+// Without a DHT, peers must be connected manually by AddrInfo.  With WithDHT
+// the host joins an Agent Semantic Protocol Kademlia overlay (a DHT with its
+// own protocol prefix, separate from the public IPFS DHT) and can:
+//
+//   - advertise each of its capabilities under a rendezvous key
+//     ("/agent-semantic-protocol/capability/<name>"), and
+//   - look up remote agents by capability string with DiscoverPeers.
+//
+// Discovered peers still need a Handshake before they appear in the
+// DiscoveryRegistry; the DHT only answers "who claims to offer X".
+
+import (
+	"context"
+	"fmt"
+
+	dht "github.com/libp2p/go-libp2p-kad-dht"
+	"github.com/libp2p/go-libp2p/core/discovery"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	drouting "github.com/libp2p/go-libp2p/p2p/discovery/routing"
+	dutil "github.com/libp2p/go-libp2p/p2p/discovery/util"
+)
+
+// dhtProtocolPrefix isolates the Agent Semantic Protocol overlay from the IPFS DHT.
+const dhtProtocolPrefix = "/agent-semantic-protocol"
+
+// capabilityNamespacePrefix is prepended to a capability to form its rendezvous key.
+const capabilityNamespacePrefix = "/agent-semantic-protocol/capability/"
+
+// maxDiscoveredPeers bounds a single DiscoverPeers lookup.
+const maxDiscoveredPeers = 100
+
+// WithDHT enables Kademlia DHT discovery.  bootstrap lists peers used to join
+// the overlay; the first node of a mesh may pass none and act as a bootstrap
+// node for the others.
+func WithDHT(bootstrap ...peer.AddrInfo) HostOption {
+	return func(ah *AgentHost) {
+		ah.dhtEnabled = true
+		ah.dhtBootstrap = append([]peer.AddrInfo(nil), bootstrap...)
+	}
+}
+
+// CapabilityNamespace returns the DHT rendezvous key for capability.
+func CapabilityNamespace(capability string) string {
+	return capabilityNamespacePrefix + capability
+}
+
+// startDHT creates the DHT, connects to bootstrap peers, and bootstraps the
+// routing table.  Bootstrap peers that cannot be reached are skipped.
+func (ah *AgentHost) startDHT(ctx context.Context) error {
+	kdht, err := dht.New(ctx, ah.h,
+		dht.Mode(dht.ModeAutoServer),
+		dht.ProtocolPrefix(protocol.ID(ah.meshed(dhtProtocolPrefix))),
+		dht.BootstrapPeers(ah.dhtBootstrap...),
+	)
+	if err != nil {
+		return fmt.Errorf("p2p dht: create: %w", err)
+	}
+	for _, info := range ah.dhtBootstrap {
+		if info.ID == ah.h.ID() {
+			continue
+		}
+		_ = ah.h.Connect(ctx, info)
+	}
+	if err := kdht.Bootstrap(ctx); err != nil {
+		_ = kdht.Close()
+		return fmt.Errorf("p2p dht: bootstrap: %w", err)
+	}
+	ah.dht = kdht
+	ah.routing = drouting.NewRoutingDiscovery(kdht)
+	return nil
+}
+
+// AdvertiseCapabilities publishes every capability of the local agent under
+// its rendezvous key and keeps re-advertising in the background until ctx is
+// cancelled.  Returns an error if the host was created without WithDHT.
+func (ah *AgentHost) AdvertiseCapabilities(ctx context.Context) error {
+	if ah.routing == nil {
+		return fmt.Errorf("p2p dht: not enabled (use WithDHT)")
+	}
+	for _, c := range ah.agent.Capabilities {
+		dutil.Advertise(ctx, ah.routing, CapabilityNamespace(c))
+	}
+	return nil
+}
+
+// DiscoverPeers looks up agents advertising capability on the DHT.
+// The local host and peers without known addresses are omitted.
+func (ah *AgentHost) DiscoverPeers(ctx context.Context, capability string) ([]peer.AddrInfo, error) {
+	if ah.routing == nil {
+		return nil, fmt.Errorf("p2p dht: not enabled (use WithDHT)")
+	}
+	found, err := dutil.FindPeers(ctx, ah.routing, CapabilityNamespace(capability),
+		discovery.Limit(maxDiscoveredPeers))
+	if err != nil {
+		return nil, fmt.Errorf("p2p dht: find peers for %q: %w", capability, err)
+	}
+	out := found[:0]
+	for _, info := range found {
+		if info.ID == ah.h.ID() || len(info.Addrs) == 0 {
+			continue
+		}
+		out = append(out, info)
+	}
+	return out, nil
+}
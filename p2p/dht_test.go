@@ -0,0 +1,67 @@
+package p2p_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+// TestDHTDiscovery verifies that a capability advertised by one host is
+// found by another through a third, bootstrap host.
+func TestDHTDiscovery(t *testing.T) {
+	newDHTHost := func(id string, caps []string, bootstrap ...peer.AddrInfo) *p2p.AgentHost {
+		h, err := p2p.NewHost(context.Background(), makeAgent(t, id, caps), p2p.WithDHT(bootstrap...))
+		if err != nil {
+			t.Fatalf("NewHost(%s): %v", id, err)
+		}
+		t.Cleanup(func() { _ = h.Close() })
+		return h
+	}
+	boot := newDHTHost("boot", nil)
+	ocr := newDHTHost("ocr-agent", []string{"ocr"}, boot.AddrInfo())
+	seeker := newDHTHost("seeker", nil, boot.AddrInfo())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	if err := ocr.AdvertiseCapabilities(ctx); err != nil {
+		t.Fatalf("AdvertiseCapabilities: %v", err)
+	}
+
+	// Advertising runs in the background, so poll until the record lands.
+	for {
+		found, err := seeker.DiscoverPeers(ctx, "ocr")
+		if err == nil && slices.ContainsFunc(found, func(info peer.AddrInfo) bool { return info.ID == ocr.PeerID() }) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatalf("DiscoverPeers never found the ocr agent: %v, %v", found, err)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	found, err := ocr.DiscoverPeers(ctx, "ocr")
+	if err != nil {
+		t.Fatalf("DiscoverPeers: %v", err)
+	}
+	if slices.ContainsFunc(found, func(info peer.AddrInfo) bool { return info.ID == ocr.PeerID() }) {
+		t.Error("DiscoverPeers returned the local host")
+	}
+}
+
+func TestDHTDisabled(t *testing.T) {
+	h := makeHost(t, makeAgent(t, "alpha", []string{"nlp"}))
+	if err := h.AdvertiseCapabilities(context.Background()); err == nil {
+		t.Error("AdvertiseCapabilities succeeded without WithDHT")
+	}
+	if _, err := h.DiscoverPeers(context.Background(), "nlp"); err == nil {
+		t.Error("DiscoverPeers succeeded without WithDHT")
+	}
+	if got := p2p.CapabilityNamespace("ocr"); got != "/agent-semantic-protocol/capability/ocr" {
+		t.Errorf("CapabilityNamespace = %q", got)
+	}
+}
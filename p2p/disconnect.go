@@ -0,0 +1,195 @@
+package p2p
+
+// disconnect.go — Reacting to dropped connections.
+//
+// The host subscribes to libp2p network notifications.  When the last
+// connection to a peer closes, the host:
+//
+//   - marks the peer's profile unreachable, so discovery stops offering it;
+//   - fails SendIntent calls in flight to that peer at once with
+//     ErrPeerDisconnected instead of letting them run into their timeout;
+//   - applies the DisconnectPolicy's trust penalty, if any;
+//   - calls the OnPeerDisconnect callback; and
+//   - if the policy asks for it, re-dials the peer with exponential backoff.
+//
+// A new connection to a known peer marks it reachable again.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+// ErrPeerDisconnected is returned (wrapped) by calls that failed because the
+// connection to the peer dropped while they were in flight.
+var ErrPeerDisconnected = fmt.Errorf("p2p: peer disconnected")
+
+// PeerDisconnectCallback is invoked after the last connection to peerID
+// closed.  profile is the peer's handshake profile; known is false if the
+// peer never completed a handshake.
+type PeerDisconnectCallback func(peerID peer.ID, profile core.AgentProfile, known bool)
+
+// DisconnectPolicy configures what a host does beyond marking a dropped
+// peer unreachable.  The zero value does nothing more.
+type DisconnectPolicy struct {
+	// TrustPenalty is subtracted from the local agent's trust in a known
+	// peer each time it disconnects.
+	TrustPenalty float32
+	// Redial re-connects to known peers that dropped, up to MaxAttempts
+	// times (0 means until the host closes), waiting Backoff before the
+	// first attempt and doubling the wait up to MaxBackoff.
+	Redial      bool
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+}
+
+// Default redial backoff bounds used when a DisconnectPolicy leaves them zero.
+const (
+	DefaultRedialBackoff    = time.Second
+	DefaultRedialMaxBackoff = time.Minute
+)
+
+// WithDisconnectPolicy sets how the host reacts to dropped peers.
+func WithDisconnectPolicy(p DisconnectPolicy) HostOption {
+	return func(ah *AgentHost) { ah.disconnects = p }
+}
+
+// OnPeerDisconnect registers fn to be called when a peer disconnects.
+func (ah *AgentHost) OnPeerDisconnect(fn PeerDisconnectCallback) {
+	ah.mu.Lock()
+	defer ah.mu.Unlock()
+	ah.onDisconnect = fn
+}
+
+// watchConnections subscribes to the host's connection notifications.
+func (ah *AgentHost) watchConnections() {
+	ah.h.Network().Notify(&network.NotifyBundle{
+		ConnectedF: func(n network.Network, c network.Conn) {
+			// Only the first connection to a peer counts as a connect.
+			if len(n.ConnsToPeer(c.RemotePeer())) <= 1 {
+				ah.peerConnected(c.RemotePeer())
+			}
+		},
+		DisconnectedF: func(n network.Network, c network.Conn) {
+			// Only the last connection to a peer counts as a disconnect.
+			if n.Connectedness(c.RemotePeer()) != network.Connected {
+				go ah.peerDisconnected(c.RemotePeer())
+			}
+		},
+	})
+}
+
+func (ah *AgentHost) peerConnected(pid peer.ID) {
+	ah.mu.RLock()
+	profile, known := ah.known[pid.String()]
+	ah.mu.RUnlock()
+	if known {
+		ah.discovery.SetReachable(profile.DID, true)
+	}
+	ah.emit(Event{Kind: EventPeerConnected, PeerID: pid, AgentID: profile.AgentID, DID: profile.DID})
+}
+
+func (ah *AgentHost) peerDisconnected(pid peer.ID) {
+	ah.failInflight(pid)
+
+	ah.mu.RLock()
+	profile, known := ah.known[pid.String()]
+	cb := ah.onDisconnect
+	ah.mu.RUnlock()
+	if known {
+		ah.discovery.SetReachable(profile.DID, false)
+		if ah.disconnects.TrustPenalty > 0 {
+			ah.applyTrust(profile.DID, -ah.disconnects.TrustPenalty)
+		}
+	}
+	ah.dropSession(pid)
+	ah.dropSendQueue(pid)
+	ah.emit(Event{Kind: EventPeerDisconnected, PeerID: pid, AgentID: profile.AgentID, DID: profile.DID})
+	if cb != nil {
+		cb(pid, profile, known)
+	}
+	if known && ah.disconnects.Redial {
+		ah.redial(pid)
+	}
+}
+
+// redial re-connects to pid with exponential backoff until it succeeds, the
+// attempts run out, the peer reconnects by itself or the host closes.
+func (ah *AgentHost) redial(pid peer.ID) {
+	wait := ah.disconnects.Backoff
+	if wait <= 0 {
+		wait = DefaultRedialBackoff
+	}
+	maxWait := ah.disconnects.MaxBackoff
+	if maxWait <= 0 {
+		maxWait = DefaultRedialMaxBackoff
+	}
+	for attempt := 1; ah.disconnects.MaxAttempts == 0 || attempt <= ah.disconnects.MaxAttempts; attempt++ {
+		t := time.NewTimer(wait)
+		select {
+		case <-ah.done:
+			t.Stop()
+			return
+		case <-t.C:
+		}
+		if ah.h.Network().Connectedness(pid) == network.Connected {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), wait)
+		err := ah.h.Connect(ctx, peer.AddrInfo{ID: pid})
+		cancel()
+		if err == nil {
+			return
+		}
+		if wait *= 2; wait > maxWait {
+			wait = maxWait
+		}
+	}
+}
+
+// trackCall derives a context for one call to pid that is cancelled with
+// ErrPeerDisconnected if pid disconnects.  release must be called when the
+// call returns.
+func (ah *AgentHost) trackCall(ctx context.Context, pid peer.ID) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	ah.callMu.Lock()
+	ah.callSeq++
+	id := ah.callSeq
+	if ah.calls[pid] == nil {
+		ah.calls[pid] = make(map[uint64]context.CancelCauseFunc)
+	}
+	ah.calls[pid][id] = cancel
+	ah.callMu.Unlock()
+	return ctx, func() {
+		ah.callMu.Lock()
+		delete(ah.calls[pid], id)
+		if len(ah.calls[pid]) == 0 {
+			delete(ah.calls, pid)
+		}
+		ah.callMu.Unlock()
+		cancel(nil)
+	}
+}
+
+// failInflight cancels every tracked call to pid.
+func (ah *AgentHost) failInflight(pid peer.ID) {
+	ah.callMu.Lock()
+	calls := ah.calls[pid]
+	delete(ah.calls, pid)
+	ah.callMu.Unlock()
+	for _, cancel := range calls {
+		cancel(ErrPeerDisconnected)
+	}
+}
+
+// disconnected reports whether ctx, from trackCall, was cancelled because
+// its peer dropped.
+func disconnected(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), ErrPeerDisconnected)
+}
@@ -0,0 +1,78 @@
+package p2p_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+func TestPeerDisconnectFailsInflightIntent(t *testing.T) {
+	alpha := makeAgent(t, "alpha", nil)
+	beta := makeAgent(t, "beta", []string{"ocr"})
+	hA, err := p2p.NewHost(context.Background(), alpha, p2p.WithDisconnectPolicy(p2p.DisconnectPolicy{TrustPenalty: 0.1}))
+	if err != nil {
+		t.Fatalf("NewHost: %v", err)
+	}
+	t.Cleanup(func() { _ = hA.Close() })
+	hB, err := p2p.NewHost(context.Background(), beta)
+	if err != nil {
+		t.Fatalf("NewHost: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err = p2p.DiscoverAndHandshake(ctx, hA, hB.AddrInfo()); err != nil {
+		t.Fatalf("DiscoverAndHandshake: %v", err)
+	}
+	_ = hA.Trust().Set(alpha.DID.String(), beta.DID.String(), 0.5)
+
+	dropped := make(chan string, 1)
+	hA.OnPeerDisconnect(func(_ peer.ID, profile core.AgentProfile, known bool) {
+		if known {
+			dropped <- profile.AgentID
+		}
+	})
+	started := make(chan struct{})
+	hB.OnIntent(func(peer.ID, *core.IntentMessage) *core.NegotiationResponse {
+		close(started)
+		time.Sleep(time.Minute)
+		return nil
+	})
+
+	errc := make(chan error, 1)
+	go func() {
+		intent, _ := core.CreateIntent(alpha, nil, []string{"ocr"}, "page")
+		_, err := hA.SendIntent(ctx, hB.PeerID(), intent)
+		errc <- err
+	}()
+	<-started
+	_ = hB.Close()
+
+	select {
+	case err = <-errc:
+		if !errors.Is(err, p2p.ErrPeerDisconnected) {
+			t.Errorf("SendIntent error = %v, want ErrPeerDisconnected", err)
+		}
+	case <-ctx.Done():
+		t.Fatal("SendIntent did not fail after the peer disconnected")
+	}
+	select {
+	case id := <-dropped:
+		if id != "beta" {
+			t.Errorf("OnPeerDisconnect reported %q", id)
+		}
+	case <-ctx.Done():
+		t.Fatal("OnPeerDisconnect not called")
+	}
+	if got := hA.Discovery().FindByCapability("ocr"); len(got) != 0 {
+		t.Errorf("discovery still offers %v", got)
+	}
+	if score := hA.Trust().Get(alpha.DID.String(), beta.DID.String()); score >= 0.5 {
+		t.Errorf("trust after disconnect = %v, want penalised below 0.5", score)
+	}
+}
@@ -0,0 +1,42 @@
+package p2p
+
+// e2e.go — Sealing intent payloads for their recipient.
+//
+// With WithPayloadEncryption, SendIntent seals the payload of every intent
+// the host's own agent sends for the peer it is sent to, as long as that
+// peer advertised a key-agreement key in its handshake.  Every host opens
+// payloads sealed for it during admission, before handlers, logs, audit
+// and memory see the intent (see core.EncryptIntentPayload).
+
+import (
+	"fmt"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+// WithPayloadEncryption seals outgoing intent payloads end to end for peers
+// that support it.  Intents to other peers, and intents the host relays for
+// other agents, are sent as they are.
+func WithPayloadEncryption() HostOption {
+	return func(ah *AgentHost) { ah.sealPayloads = true }
+}
+
+// sealIntent returns a copy of intent with its payload sealed for peerID,
+// or intent itself if there is nothing to seal or no key to seal it for.
+func (ah *AgentHost) sealIntent(peerID peer.ID, intent *core.IntentMessage) (*core.IntentMessage, error) {
+	if intent.Payload == "" || intent.EncryptedPayload != nil || intent.DID != ah.agent.DID.String() {
+		return intent, nil
+	}
+	ah.mu.RLock()
+	profile, known := ah.known[peerID.String()]
+	ah.mu.RUnlock()
+	if !known || len(profile.KeyAgreement) == 0 {
+		return intent, nil
+	}
+	cp := *intent
+	if err := core.EncryptIntentPayload(ah.agent, &cp, profile.DID, profile.KeyAgreement); err != nil {
+		return nil, fmt.Errorf("p2p intent: %w", err)
+	}
+	return &cp, nil
+}
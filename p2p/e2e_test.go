@@ -0,0 +1,66 @@
+package p2p_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+func TestPayloadEncryption(t *testing.T) {
+	alpha := makeAgent(t, "alpha", nil)
+	beta := makeAgent(t, "beta", []string{"ocr"})
+	hA, err := p2p.NewHost(context.Background(), alpha, p2p.WithPayloadEncryption())
+	if err != nil {
+		t.Fatalf("NewHost: %v", err)
+	}
+	t.Cleanup(func() { _ = hA.Close() })
+
+	var onWire string
+	var sealed bool
+	hB, err := p2p.NewHost(context.Background(), beta, p2p.WithInspector(func(f p2p.InspectedFrame) error {
+		if in, ok := f.Message.(*core.IntentMessage); ok {
+			onWire, sealed = in.Payload, in.EncryptedPayload != nil
+		}
+		return nil
+	}))
+	if err != nil {
+		t.Fatalf("NewHost: %v", err)
+	}
+	t.Cleanup(func() { _ = hB.Close() })
+
+	var served string
+	hB.OnIntent(func(_ peer.ID, in *core.IntentMessage) *core.NegotiationResponse {
+		served = in.Payload
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err = hA.Connect(ctx, hB.AddrInfo()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	if _, err = hA.Handshake(ctx, hB.PeerID()); err != nil {
+		t.Fatalf("Handshake: %v", err)
+	}
+	intent, _ := core.CreateIntent(alpha, nil, []string{"ocr"}, "account 1234")
+	resp, err := hA.SendIntent(ctx, hB.PeerID(), intent)
+	if err != nil {
+		t.Fatalf("SendIntent: %v", err)
+	}
+	if !resp.Accepted {
+		t.Fatalf("rejected: %s", resp.Reason)
+	}
+	if !sealed || onWire != "" {
+		t.Errorf("payload on the wire: sealed=%t plaintext=%q", sealed, onWire)
+	}
+	if served != "account 1234" {
+		t.Errorf("handler saw payload %q", served)
+	}
+	if intent.Payload != "account 1234" {
+		t.Errorf("SendIntent modified the caller's intent: %+v", intent)
+	}
+}
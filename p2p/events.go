@@ -0,0 +1,199 @@
+package p2p
+
+// events.go — Host-level event bus.
+//
+// Applications that build metrics, audit trails or UIs subscribe to the
+// host's events instead of wrapping every callback.  Events are delivered
+// synchronously, in the goroutine that caused them, to every subscriber of
+// their kind; a subscriber must return quickly and hand slow work off.
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+// EventKind identifies the type of an Event.
+type EventKind string
+
+// Event kinds.
+const (
+	// EventHandshakeCompleted: a handshake with PeerID finished, initiated
+	// by either side.  AgentID, DID and Capabilities describe the peer.
+	EventHandshakeCompleted EventKind = "handshake_completed"
+	// EventIntentReceived: an intent from PeerID passed admission (signature,
+	// revocation, timestamp and replay checks) and is about to be served.
+	EventIntentReceived EventKind = "intent_received"
+	// EventIntentRejected: the host answered an intent with Accepted false;
+	// Reason says why.
+	EventIntentRejected EventKind = "intent_rejected"
+	// EventTrustUpdated: the local agent's trust in DID changed by Delta to
+	// Trust.
+	EventTrustUpdated EventKind = "trust_updated"
+	// EventTrustAttested: a peer's attestation that it trusts DID with
+	// Trust was adopted; Reason is the issuer's DID.
+	EventTrustAttested EventKind = "trust_attested"
+	// EventPeerConnected: the first connection to PeerID opened.
+	EventPeerConnected EventKind = "peer_connected"
+	// EventPeerDisconnected: the last connection to PeerID closed.
+	EventPeerDisconnected EventKind = "peer_disconnected"
+	// EventAgentIDConflict: DID announced AgentID, which other registered
+	// DIDs already use; Reason lists them.
+	EventAgentIDConflict EventKind = "agent_id_conflict"
+	// EventIntentRelayed: an intent from DID that the host cannot serve was
+	// forwarded to PeerID, whose agent is AgentID, and answered.
+	EventIntentRelayed EventKind = "intent_relayed"
+	// EventIntentFederated: an intent from DID was proxied by a federation
+	// gateway to PeerID, in the other mesh, whose agent is AgentID, and
+	// answered.
+	EventIntentFederated EventKind = "intent_federated"
+	// EventPeerQuarantined: PeerID exhausted its error budget and is
+	// quarantined; Reason says which error tipped it and until when.
+	EventPeerQuarantined EventKind = "peer_quarantined"
+	// EventPeerUnquarantined: PeerID's quarantine was lifted by Unquarantine.
+	EventPeerUnquarantined EventKind = "peer_unquarantined"
+	// EventPeerUnhealthy: the health monitor's probes of DID, whose agent is
+	// AgentID, went unanswered too often; Reason says how often.
+	EventPeerUnhealthy EventKind = "peer_unhealthy"
+	// EventPeerHealthy: an unhealthy DID answered a probe again.
+	EventPeerHealthy EventKind = "peer_healthy"
+	// EventDIDRevoked: an accepted revocation list revoked DID; Reason is
+	// the list's reason and PeerID the neighbour that sent it, empty if
+	// the list was published or fetched locally.
+	EventDIDRevoked EventKind = "did_revoked"
+	// EventReachabilityChanged: AutoNAT (WithAutoNAT) found the host
+	// publicly reachable or not; Reason is "Public", "Private" or
+	// "Unknown".
+	EventReachabilityChanged EventKind = "reachability_changed"
+)
+
+// Event is one occurrence reported to subscribers.  Fields not relevant to
+// Kind are zero.
+type Event struct {
+	Kind         EventKind
+	PeerID       peer.ID
+	AgentID      string
+	DID          string
+	IntentID     string
+	Capabilities []string
+	Reason       string
+	Delta        float32
+	Trust        float32
+	Timestamp    time.Time
+
+	// Dropped is set on events received through Observe: how many events
+	// the observed host dropped before this one because the observer fell
+	// behind.
+	Dropped int64
+}
+
+// EventHandler receives events from Subscribe.
+type EventHandler func(Event)
+
+type subscriber struct {
+	fn    EventHandler
+	kinds map[EventKind]bool // nil: every kind
+}
+
+// Subscribe registers fn for events of the given kinds, or of every kind if
+// none are given.  The returned function cancels the subscription.
+func (ah *AgentHost) Subscribe(fn EventHandler, kinds ...EventKind) (unsubscribe func()) {
+	sub := &subscriber{fn: fn}
+	if len(kinds) > 0 {
+		sub.kinds = make(map[EventKind]bool, len(kinds))
+		for _, k := range kinds {
+			sub.kinds[k] = true
+		}
+	}
+	ah.evMu.Lock()
+	ah.subscribers = append(ah.subscribers, sub)
+	ah.evMu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			ah.evMu.Lock()
+			defer ah.evMu.Unlock()
+			for i, s := range ah.subscribers {
+				if s == sub {
+					ah.subscribers = append(ah.subscribers[:i:i], ah.subscribers[i+1:]...)
+					break
+				}
+			}
+		})
+	}
+}
+
+// emit delivers ev to its subscribers.
+func (ah *AgentHost) emit(ev Event) {
+	ah.evMu.Lock()
+	subs := ah.subscribers
+	ah.evMu.Unlock()
+	if len(subs) == 0 {
+		return
+	}
+	ev.Timestamp = time.Now()
+	for _, s := range subs {
+		if s.kinds == nil || s.kinds[ev.Kind] {
+			s.fn(ev)
+		}
+	}
+}
+
+// applySenderTrust applies the trust delta of an answer to the DID from
+// shook hands under.  Peers without a handshake speak for no DID, so the
+// DIDs their intents claim keep their trust.
+func (ah *AgentHost) applySenderTrust(from peer.ID, delta float32) {
+	if did, ok := ah.peerDID(from); ok {
+		ah.applyTrust(did, delta)
+	}
+}
+
+// applyTrust adjusts the local agent's trust in did and reports a change.
+// A persistence failure must not fail the exchange, so it is not returned.
+func (ah *AgentHost) applyTrust(did string, delta float32) {
+	self := ah.agent.DID.String()
+	_ = ah.trust.Apply(self, did, delta)
+	if delta != 0 {
+		ah.emit(Event{Kind: EventTrustUpdated, DID: did, Delta: delta, Trust: ah.trust.Get(self, did)})
+	}
+}
+
+// answered reports resp, the reply to intent from `from`, if it refuses it.
+func (ah *AgentHost) answered(from peer.ID, intent *core.IntentMessage, resp *core.NegotiationResponse) {
+	if resp == nil || resp.Accepted {
+		return
+	}
+	ah.emit(Event{
+		Kind:         EventIntentRejected,
+		PeerID:       from,
+		DID:          intent.DID,
+		IntentID:     intent.ID,
+		Capabilities: intent.Capabilities,
+		Reason:       resp.Reason,
+	})
+}
+
+// agentIDConflict reports a discovery entry contesting another's AgentID.
+func (ah *AgentHost) agentIDConflict(c core.AgentIDConflict) {
+	ah.emit(Event{
+		Kind:    EventAgentIDConflict,
+		AgentID: c.AgentID,
+		DID:     c.DID,
+		Reason:  "also announced by " + strings.Join(c.Others, ", "),
+	})
+}
+
+// handshakeCompleted reports a completed handshake with pid.
+func (ah *AgentHost) handshakeCompleted(pid peer.ID, profile core.AgentProfile) {
+	ah.emit(Event{
+		Kind:         EventHandshakeCompleted,
+		PeerID:       pid,
+		AgentID:      profile.AgentID,
+		DID:          profile.DID,
+		Capabilities: profile.Capabilities,
+	})
+}
@@ -0,0 +1,76 @@
+package p2p_test
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+// TestEventBus verifies that subscribers receive handshake, intent and
+// trust events, filtered by kind.
+func TestEventBus(t *testing.T) {
+	alpha := makeAgent(t, "alpha", []string{"nlp"})
+	beta := makeAgent(t, "beta", []string{"ocr"})
+	hA := makeHost(t, alpha)
+	hB := makeHost(t, beta)
+
+	var mu sync.Mutex
+	var got []p2p.Event
+	unsubscribe := hB.Subscribe(func(ev p2p.Event) {
+		mu.Lock()
+		got = append(got, ev)
+		mu.Unlock()
+	}, p2p.EventHandshakeCompleted, p2p.EventIntentReceived, p2p.EventIntentRejected)
+	var trustEvents int
+	hA.Subscribe(func(ev p2p.Event) {
+		mu.Lock()
+		trustEvents++
+		mu.Unlock()
+	}, p2p.EventTrustUpdated)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := p2p.DiscoverAndHandshake(ctx, hA, hB.AddrInfo()); err != nil {
+		t.Fatalf("DiscoverAndHandshake: %v", err)
+	}
+	accepted, _ := core.CreateIntent(alpha, nil, []string{"ocr"}, "scan")
+	if _, err := hA.SendIntent(ctx, hB.PeerID(), accepted); err != nil {
+		t.Fatalf("SendIntent: %v", err)
+	}
+	rejected, _ := core.CreateIntent(alpha, nil, []string{"translate"}, "hola")
+	if _, err := hA.SendIntent(ctx, hB.PeerID(), rejected); err != nil {
+		t.Fatalf("SendIntent: %v", err)
+	}
+	unsubscribe()
+	late, _ := core.CreateIntent(alpha, nil, []string{"ocr"}, "again")
+	if _, err := hA.SendIntent(ctx, hB.PeerID(), late); err != nil {
+		t.Fatalf("SendIntent: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	var kinds []p2p.EventKind
+	for _, ev := range got {
+		kinds = append(kinds, ev.Kind)
+	}
+	want := []p2p.EventKind{
+		p2p.EventHandshakeCompleted,
+		p2p.EventIntentReceived,
+		p2p.EventIntentReceived,
+		p2p.EventIntentRejected,
+	}
+	if !reflect.DeepEqual(kinds, want) {
+		t.Fatalf("events: got %v want %v", kinds, want)
+	}
+	if got[0].AgentID != "alpha" || got[3].IntentID != rejected.ID {
+		t.Errorf("unexpected event details: %+v", got)
+	}
+	if trustEvents == 0 {
+		t.Error("no trust_updated events for accepted intent")
+	}
+}
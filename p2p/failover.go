@@ -0,0 +1,81 @@
+package p2p
+
+// failover.go — Offering a workflow step to further candidates.
+//
+// Without a FailoverPolicy a step goes to the best-ranked usable peer and
+// fails if that peer rejects it or does not answer; only the step's
+// Retries send it again, to whichever peer ranks best then.  With
+// SetFailover a rejected or failed step moves straight on to the next
+// candidate, up to MaxCandidates in all.  Each move is recorded as a
+// step_failover event in the workflow's history, the accepting agent is
+// the step's AgentID and the ones passed over are listed in Tried.  Peers
+// that reject a step or fail it can also lose trust, so they rank lower
+// for later steps.
+
+import (
+	"fmt"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// WorkflowStepFailover is the history event kind recorded when a step
+// moves on from a candidate that rejected or failed it.
+const WorkflowStepFailover = "step_failover"
+
+// FailoverPolicy says how many candidates a workflow step is offered to
+// and what refusing it costs them.  The zero value offers each step to
+// one candidate and penalizes nobody.
+type FailoverPolicy struct {
+	MaxCandidates  int     // Candidates tried per attempt; 0 = 1
+	RejectPenalty  float32 // Trust lost by a peer that rejects a step
+	TimeoutPenalty float32 // Trust lost by a peer that fails a step or does not answer in time
+}
+
+// Validate checks that p describes a usable policy.
+func (p FailoverPolicy) Validate() error {
+	if p.MaxCandidates < 0 {
+		return fmt.Errorf("failover: max candidates must not be negative")
+	}
+	if p.RejectPenalty < 0 || p.RejectPenalty > 1 || p.TimeoutPenalty < 0 || p.TimeoutPenalty > 1 {
+		return fmt.Errorf("failover: penalties must be within [0,1]")
+	}
+	return nil
+}
+
+func (p FailoverPolicy) candidates() int {
+	return max(p.MaxCandidates, 1)
+}
+
+// SetFailover sets the failover policy for steps dispatched from now on.
+func (o *WorkflowOrchestrator) SetFailover(p FailoverPolicy) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.failover = p
+	return nil
+}
+
+// penalty is the trust a peer loses for rejecting a step, or for failing
+// it with err.
+func (p FailoverPolicy) penalty(err error) float32 {
+	if err != nil {
+		return p.TimeoutPenalty
+	}
+	return p.RejectPenalty
+}
+
+// penalize lowers the local agent's trust in the agent at peerID by
+// penalty.
+func (o *WorkflowOrchestrator) penalize(peerID peer.ID, penalty float32) {
+	if penalty == 0 {
+		return
+	}
+	o.host.mu.RLock()
+	profile, known := o.host.known[peerID.String()]
+	o.host.mu.RUnlock()
+	if known {
+		o.host.applyTrust(profile.DID, -penalty)
+	}
+}
@@ -0,0 +1,82 @@
+package p2p_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+func TestWorkflowFailover(t *testing.T) {
+	alpha := makeAgent(t, "alpha", nil)
+	hA := makeHost(t, alpha)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var mu sync.Mutex
+	rejected := make(map[string]int) // agent ID → steps rejected
+	agents := make(map[string]*core.Agent)
+	for _, id := range []string{"busy-1", "busy-2", "idle"} {
+		a := makeAgent(t, id, []string{"translate"})
+		h := makeHost(t, a)
+		agents[id] = a
+		h.OnIntent(func(_ peer.ID, in *core.IntentMessage) *core.NegotiationResponse {
+			resp, _ := core.DefaultNegotiationHandler(a)(in)
+			if a.ID != "idle" {
+				mu.Lock()
+				rejected[a.ID]++
+				mu.Unlock()
+				resp.Accepted, resp.Reason, resp.TrustDelta = false, "busy", 0
+				_ = core.SignResponse(a, resp)
+			}
+			return resp
+		})
+		if _, err := p2p.DiscoverAndHandshake(ctx, hA, h.AddrInfo()); err != nil {
+			t.Fatalf("DiscoverAndHandshake: %v", err)
+		}
+	}
+
+	o := p2p.NewOrchestrator(hA, 5*time.Second)
+	if err := o.SetFailover(p2p.FailoverPolicy{MaxCandidates: 3, RejectPenalty: 0.1}); err != nil {
+		t.Fatalf("SetFailover: %v", err)
+	}
+	self := alpha.DID.String()
+	for _, a := range agents {
+		_ = hA.Trust().Set(self, a.DID.String(), 0.5)
+	}
+
+	results, err := o.RunWorkflow(ctx, "wf", []p2p.WorkflowStep{{ID: "t", Capability: "translate"}})
+	if err != nil || len(results) != 1 {
+		t.Fatalf("RunWorkflow: %+v, %v", results, err)
+	}
+	r := results[0]
+	if !r.Accepted || r.AgentID != "idle" {
+		t.Fatalf("step result = %+v; want accepted by idle", r)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(r.Tried) != len(rejected) {
+		t.Errorf("Tried = %v; rejected by %v", r.Tried, rejected)
+	}
+	for _, id := range r.Tried {
+		if rejected[id] != 1 {
+			t.Errorf("%s in Tried but rejected %d steps", id, rejected[id])
+		}
+		if got := hA.Trust().Get(self, agents[id].DID.String()); got >= 0.5 {
+			t.Errorf("trust in %s = %v; want it penalized below 0.5", id, got)
+		}
+	}
+	failovers := 0
+	for _, ev := range o.History("wf") {
+		if ev.Kind == p2p.WorkflowStepFailover {
+			failovers++
+		}
+	}
+	if failovers != len(r.Tried) {
+		t.Errorf("%d failover events, want %d", failovers, len(r.Tried))
+	}
+}
@@ -0,0 +1,70 @@
+package p2p
+
+// features.go — Degrading gracefully with peers that lack a subsystem.
+//
+// Every handshake carries the optional subsystems each side supports (see
+// core.Feature).  The host only uses a subsystem with a peer when both
+// advertise it: frames to a peer without compression go out uncompressed,
+// SendIntent to a peer without sessions uses per-request streams, and
+// StreamIntent to a peer without streaming fails before anything is sent.
+// WithFeatures switches subsystems off for the local host.
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+// ErrFeatureUnsupported is returned (wrapped) when an operation needs an
+// optional subsystem the peer or the host does not support.
+var ErrFeatureUnsupported = fmt.Errorf("p2p: feature not supported")
+
+// WithFeatures limits the optional subsystems the host advertises and uses
+// to fs (core.DefaultFeatures() by default).  A host advertising none is
+// indistinguishable from one that predates feature flags, so peers assume
+// core.LegacyFeatures of it; it still refuses to use what it left out.
+func WithFeatures(fs ...core.Feature) HostOption {
+	return func(ah *AgentHost) { ah.features = append([]core.Feature{}, fs...) }
+}
+
+// hasFeature reports whether the host itself supports f.
+func (ah *AgentHost) hasFeature(f core.Feature) bool {
+	return slices.Contains(ah.features, f)
+}
+
+// PeerFeatures returns the optional subsystems peerID advertised in its last
+// handshake.  ok is false before the first handshake.
+func (ah *AgentHost) PeerFeatures(peerID peer.ID) (fs []core.Feature, ok bool) {
+	ah.mu.RLock()
+	defer ah.mu.RUnlock()
+	fs, ok = ah.peerFeatures[peerID]
+	return slices.Clone(fs), ok
+}
+
+// setPeerFeatures records the features advertised in a handshake with peerID.
+func (ah *AgentHost) setPeerFeatures(peerID peer.ID, m *core.HandshakeMessage) {
+	ah.mu.Lock()
+	defer ah.mu.Unlock()
+	ah.peerFeatures[peerID] = core.PeerFeatures(m)
+}
+
+// SharedFeature reports whether both the host and peerID support f.  Peers
+// we have not shaken hands with get the benefit of the doubt.
+func (ah *AgentHost) SharedFeature(peerID peer.ID, f core.Feature) bool {
+	if !ah.hasFeature(f) {
+		return false
+	}
+	fs, ok := ah.PeerFeatures(peerID)
+	return !ok || slices.Contains(fs, f)
+}
+
+// requireFeature returns an error wrapping ErrFeatureUnsupported unless f
+// may be used with peerID.
+func (ah *AgentHost) requireFeature(peerID peer.ID, f core.Feature) error {
+	if !ah.SharedFeature(peerID, f) {
+		return fmt.Errorf("%w: %s with %s", ErrFeatureUnsupported, f, peerID)
+	}
+	return nil
+}
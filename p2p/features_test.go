@@ -0,0 +1,55 @@
+package p2p_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+// TestFeatureFlags verifies that hosts learn each other's optional
+// subsystems at handshake time and refuse to stream to a peer without
+// streaming.
+func TestFeatureFlags(t *testing.T) {
+	alpha := makeAgent(t, "alpha", []string{"nlp"})
+	beta := makeAgent(t, "beta", []string{"ocr"})
+
+	hA := makeHost(t, alpha)
+	hB, err := p2p.NewHost(context.Background(), beta, p2p.WithFeatures(core.FeatureCompression))
+	if err != nil {
+		t.Fatalf("NewHost: %v", err)
+	}
+	t.Cleanup(func() { _ = hB.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := hA.Connect(ctx, hB.AddrInfo()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	if _, err := hA.Handshake(ctx, hB.PeerID()); err != nil {
+		t.Fatalf("Handshake: %v", err)
+	}
+
+	fs, ok := hA.PeerFeatures(hB.PeerID())
+	if !ok || len(fs) != 1 || fs[0] != core.FeatureCompression {
+		t.Errorf("alpha sees beta's features %v, %v", fs, ok)
+	}
+	if fs, _ = hB.PeerFeatures(hA.PeerID()); len(fs) != len(core.DefaultFeatures()) {
+		t.Errorf("beta sees alpha's features %v", fs)
+	}
+	if !hA.SharedFeature(hB.PeerID(), core.FeatureCompression) || hA.SharedFeature(hB.PeerID(), core.FeatureSessions) {
+		t.Error("SharedFeature disagrees with the advertised features")
+	}
+
+	intent, _ := core.CreateIntent(alpha, nil, []string{"ocr"}, "")
+	if _, _, err = hA.StreamIntent(ctx, hB.PeerID(), intent); !errors.Is(err, p2p.ErrFeatureUnsupported) {
+		t.Errorf("StreamIntent: got %v, want ErrFeatureUnsupported", err)
+	}
+	// Plain intents still work.
+	if _, err = hA.SendIntent(ctx, hB.PeerID(), intent); err != nil {
+		t.Errorf("SendIntent: %v", err)
+	}
+}
@@ -0,0 +1,149 @@
+package p2p
+
+// federation.go — Gateways between meshes.
+//
+// A federation gateway runs one AgentHost per mesh.  Federate(ctx, from,
+// to, policy) makes the capabilities of from's mesh that policy exports
+// available in to's mesh: to adds them to its announcements under a
+// core.ProvenanceTag naming from's mesh, and proxies the intents it
+// receives for them through from to an agent there (see core.ProxyIntent).
+// Call Federate twice, with the hosts swapped, to federate both ways.
+//
+// Only peers that advertise a capability in their own handshake are used
+// as sources, so capabilities another gateway proxies into from's mesh are
+// never exported again and cannot loop between meshes.
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+// federationLink is what a host proxies and where to.
+type federationLink struct {
+	from   *AgentHost
+	policy core.FederationPolicy
+}
+
+// Federate makes the capabilities of from's mesh that policy exports
+// reachable through to, in another mesh, and announces them there.  A host
+// proxies for at most one other; Federate fails if to already does, if
+// both hosts are in the same mesh or if policy exports nothing.
+func Federate(ctx context.Context, from, to *AgentHost, policy core.FederationPolicy) error {
+	if from.mesh == to.mesh {
+		return fmt.Errorf("p2p federation: both hosts are in %s", meshName(from.mesh))
+	}
+	if len(policy.Capabilities) == 0 {
+		return fmt.Errorf("p2p federation: policy exports no capabilities")
+	}
+	policy.Capabilities = from.aliases.Normalize(policy.Capabilities)
+	to.mu.Lock()
+	if to.federation != nil {
+		to.mu.Unlock()
+		return fmt.Errorf("p2p federation: host already proxies %s", meshName(to.federation.from.mesh))
+	}
+	to.federation = &federationLink{from: from, policy: policy}
+	to.mu.Unlock()
+	to.AnnounceCapabilities(ctx)
+	return nil
+}
+
+func meshName(mesh string) string {
+	if mesh == "" {
+		return "the default mesh"
+	}
+	return fmt.Sprintf("mesh %q", mesh)
+}
+
+func (ah *AgentHost) federationLink() *federationLink {
+	ah.mu.RLock()
+	defer ah.mu.RUnlock()
+	return ah.federation
+}
+
+// federationTag describes the capabilities the host proxies that some peer
+// in the other mesh currently serves.
+func (ah *AgentHost) federationTag() (core.ProvenanceTag, bool) {
+	link := ah.federationLink()
+	if link == nil {
+		return core.ProvenanceTag{}, false
+	}
+	tag := core.ProvenanceTag{Mesh: link.from.mesh, Gateway: ah.agent.DID.String()}
+	for _, c := range link.policy.Capabilities {
+		if len(link.from.federationSources([]string{c}, nil)) > 0 {
+			tag.Capabilities = append(tag.Capabilities, c)
+		}
+	}
+	return tag, len(tag.Capabilities) > 0
+}
+
+// federationSources lists the handshaked peers that advertise every one of
+// caps themselves, best match for vector first.
+func (ah *AgentHost) federationSources(caps []string, vector []float32) []hop {
+	ah.mu.RLock()
+	var capable []core.AgentProfile
+	pids := make(map[string]peer.ID)
+	for key, p := range ah.known {
+		pid, err := peer.Decode(key)
+		if err != nil || p.Federated() || !hasAll(p.Capabilities, caps) {
+			continue
+		}
+		pids[p.DID] = pid
+		capable = append(capable, p)
+	}
+	ah.mu.RUnlock()
+
+	var out []hop
+	for _, p := range core.RankCandidates(vector, capable) {
+		out = append(out, hop{pids[p.DID], p})
+	}
+	return out
+}
+
+// federate proxies intent, received from `from`, into the other mesh if
+// the host is a gateway and the intent needs a capability it proxies.
+// Intents the policy refuses are rejected with core.ReasonFederationDenied,
+// and those no source answers with core.ReasonNoRoute.  It returns nil when
+// the intent should be served locally.
+func (ah *AgentHost) federate(ctx context.Context, from peer.ID, intent *core.IntentMessage) *core.NegotiationResponse {
+	link := ah.federationLink()
+	if link == nil || ah.serves(intent) || core.IsHealthIntent(intent) ||
+		!slices.ContainsFunc(intent.Capabilities, link.policy.Exports) {
+		return nil
+	}
+	if err := link.policy.Admit(intent); err != nil {
+		ah.log.Log(context.Background(), slog.LevelWarn, "federated intent refused",
+			core.LogKeyPeer, from.String(), core.LogKeyIntentID, intent.ID, "error", err)
+		return ah.rejection(intent, core.ReasonFederationDenied)
+	}
+	proxied, err := core.ProxyIntent(link.from.agent, intent)
+	if err != nil {
+		return nil
+	}
+	for _, h := range link.from.federationSources(intent.Capabilities, intent.IntentVector) {
+		fctx, cancel := context.WithTimeout(ctx, relayTimeout)
+		resp, err := link.from.SendIntent(fctx, h.pid, proxied)
+		cancel()
+		if err != nil || !resp.Accepted && resp.Reason == core.ReasonNoRoute {
+			continue
+		}
+		out, err := core.ProxyResponse(ah.agent, intent, resp)
+		if err != nil {
+			return nil
+		}
+		ah.emit(Event{
+			Kind:         EventIntentFederated,
+			PeerID:       h.pid,
+			AgentID:      h.profile.AgentID,
+			DID:          intent.DID,
+			IntentID:     intent.ID,
+			Capabilities: intent.Capabilities,
+		})
+		return out
+	}
+	return ah.rejection(intent, core.ReasonNoRoute)
+}
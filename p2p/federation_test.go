@@ -0,0 +1,98 @@
+package p2p_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+// TestFederation verifies that a gateway with a host in each of two meshes
+// re-announces exported capabilities with provenance and proxies intents
+// for them, subject to its policy.
+func TestFederation(t *testing.T) {
+	gw := makeAgent(t, "gateway", nil)
+	translator := makeAgent(t, "translator", []string{"translate", "billing"})
+	requester := makeAgent(t, "requester", nil)
+
+	newHost := func(agent *core.Agent, mesh string) *p2p.AgentHost {
+		h, err := p2p.NewHost(context.Background(), agent, p2p.WithMesh(mesh))
+		if err != nil {
+			t.Fatalf("NewHost: %v", err)
+		}
+		t.Cleanup(func() { _ = h.Close() })
+		return h
+	}
+	hT, hGA := newHost(translator, "alpha"), newHost(gw, "alpha")
+	hR, hGB := newHost(requester, "beta"), newHost(gw, "beta")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for _, pair := range [][2]*p2p.AgentHost{{hGA, hT}, {hR, hGB}} {
+		if err := pair[0].Connect(ctx, pair[1].AddrInfo()); err != nil {
+			t.Fatalf("Connect: %v", err)
+		}
+		if _, err := pair[0].Handshake(ctx, pair[1].PeerID()); err != nil {
+			t.Fatalf("Handshake: %v", err)
+		}
+	}
+
+	if err := p2p.Federate(ctx, hGA, hT, core.FederationPolicy{Capabilities: []string{"translate"}}); err == nil {
+		t.Error("Federate within one mesh succeeded")
+	}
+	policy := core.FederationPolicy{
+		Capabilities: []string{"translate"},
+		Requesters:   []string{requester.DID.String()},
+	}
+	if err := p2p.Federate(ctx, hGA, hGB, policy); err != nil {
+		t.Fatalf("Federate: %v", err)
+	}
+	if err := p2p.Federate(ctx, hGA, hGB, policy); err == nil {
+		t.Error("second Federate into the same host succeeded")
+	}
+
+	var profile core.AgentProfile
+	for deadline := time.Now().Add(3 * time.Second); time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+		if found := hR.Discovery().FindByCapability("translate"); len(found) > 0 {
+			profile = found[0]
+			break
+		}
+	}
+	if profile.DID != gw.DID.String() || !profile.Federated() {
+		t.Fatalf("requester discovered %+v, want the gateway with provenance", profile)
+	}
+	if tag := profile.Provenance[0]; tag.Mesh != "alpha" || tag.Gateway != gw.DID.String() || !slices.Equal(tag.Capabilities, []string{"translate"}) {
+		t.Fatalf("Provenance = %+v", tag)
+	}
+	if slices.Contains(profile.Capabilities, "billing") {
+		t.Error("gateway announced a capability the policy does not export")
+	}
+
+	var federated []p2p.Event
+	hGB.Subscribe(func(ev p2p.Event) { federated = append(federated, ev) }, p2p.EventIntentFederated)
+
+	intent, _ := core.CreateIntent(requester, nil, []string{"translate"}, "bonjour")
+	resp, err := hR.SendIntent(ctx, hGB.PeerID(), intent)
+	if err != nil {
+		t.Fatalf("SendIntent: %v", err)
+	}
+	if !resp.Accepted || resp.DID != gw.DID.String() || resp.RequestID != intent.ID {
+		t.Fatalf("federated intent answered %+v", resp)
+	}
+	if len(federated) != 1 || federated[0].PeerID != hT.PeerID() {
+		t.Fatalf("federation events = %+v", federated)
+	}
+
+	// billing is served in alpha but not exported.
+	intent, _ = core.CreateIntent(requester, nil, []string{"translate", "billing"}, "")
+	resp, err = hR.SendIntent(ctx, hGB.PeerID(), intent)
+	if err != nil {
+		t.Fatalf("SendIntent: %v", err)
+	}
+	if resp.Accepted || resp.Reason != core.ReasonFederationDenied {
+		t.Fatalf("unexported capability: accepted = %v (%s), want %s", resp.Accepted, resp.Reason, core.ReasonFederationDenied)
+	}
+}
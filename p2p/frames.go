@@ -0,0 +1,88 @@
+package p2p
+
+// frames.go — Migration from v1 to v2 frames.
+//
+// Hosts read both frame formats (see core.ReadFrame) and write v2 frames to
+// every peer whose negotiated version has core.FeatureFrameV2; handshakes,
+// which come before any negotiation, and multiplexed session streams stay
+// on v1.  Each handshake with a peer that can only read v1 frames is
+// reported to the host's DeprecationTracker as core.DeprecatedFrameV1 and
+// logged, so WithDeprecationSummary doubles as a migration audit.  Once the
+// whole mesh has upgraded, WithFrameV1Cutoff refuses the stragglers.
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+// ErrFrameV1Refused is returned (wrapped) by Handshake when the peer can
+// only read v1 frames and the host's v1 cutoff has passed.
+var ErrFrameV1Refused = fmt.Errorf("p2p: peer requires v1 frames, which are no longer accepted")
+
+// WithFrameV1Cutoff refuses handshakes, in either direction, with peers
+// that can only read v1 frames from cutoff on.
+func WithFrameV1Cutoff(cutoff time.Time) HostOption {
+	return func(ah *AgentHost) { ah.v1Cutoff = cutoff }
+}
+
+// WithVectorQuantization packs the IntentVectors and ResponseVectors the
+// host sends in v2 frames as q, for peers that advertise
+// core.FeatureQuantizedVectors.  Vectors lose precision on the way; the
+// host rounds the vectors of its own intents before signing them, and sends
+// other agents' signed vectors as float32.  The default, core.QuantizeNone,
+// sends them all as float32.
+func WithVectorQuantization(q core.Quantization) HostOption {
+	return func(ah *AgentHost) { ah.quantization = q }
+}
+
+// writePeerMsg writes msg to w in the codec and frame format negotiated
+// with pid.
+func (ah *AgentHost) writePeerMsg(w io.Writer, pid peer.ID, msg core.Encoder) error {
+	c := ah.PeerCodec(pid)
+	if v, ok := ah.PeerVersion(pid); !ok || !v.Has(core.FeatureFrameV2) {
+		return writeMsg(w, c, msg)
+	}
+	compress := ah.SharedFeature(pid, core.FeatureCompression)
+	if q := ah.peerQuantization(pid); q != core.QuantizeNone {
+		return core.WriteQuantizedFrame(w, c, msg, compress, q)
+	}
+	return core.WriteFrameV2(w, c, msg, compress)
+}
+
+// peerQuantization returns how the vectors the host sends pid are packed.
+func (ah *AgentHost) peerQuantization(pid peer.ID) core.Quantization {
+	if ah.quantization == core.QuantizeNone || ah.PeerCodec(pid).ID() != core.CodecProtobuf {
+		return core.QuantizeNone
+	}
+	if v, ok := ah.PeerVersion(pid); !ok || !v.Has(core.FeatureFrameV2) || !ah.SharedFeature(pid, core.FeatureQuantizedVectors) {
+		return core.QuantizeNone
+	}
+	return ah.quantization
+}
+
+// checkFrames audits the frame format version v allows for the peer pid
+// with DID did, and reports whether the handshake may go ahead.
+func (ah *AgentHost) checkFrames(pid peer.ID, did string, v core.VersionInfo) bool {
+	if v.Has(core.FeatureFrameV2) {
+		return true
+	}
+	ah.deprecations.Report(core.DeprecatedFrameV1, did, fmt.Sprintf("peer %s negotiated %s", pid, v.Version))
+	refused := !ah.v1Cutoff.IsZero() && !time.Now().Before(ah.v1Cutoff)
+	ah.log.Log(context.Background(), slog.LevelWarn, "peer requires v1 frames",
+		core.LogKeyPeer, pid.String(), "did", did, "version", v.Version, "refused", refused)
+	return !refused
+}
+
+// v1RefusedReply is the handshake reply to a peer refused by checkFrames:
+// like a version mismatch, but offering only versions with v2 frames.
+func (ah *AgentHost) v1RefusedReply() *core.HandshakeMessage {
+	reply := core.VersionMismatchReply(ah.agent)
+	reply.Versions = core.VersionsWith(core.FeatureFrameV2)
+	return reply
+}
@@ -0,0 +1,130 @@
+package p2p_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+func TestFrameMigration(t *testing.T) {
+	newHost := func(id string, opts ...p2p.HostOption) *p2p.AgentHost {
+		h, err := p2p.NewHost(context.Background(), makeAgent(t, id, []string{"ocr"}), opts...)
+		if err != nil {
+			t.Fatalf("NewHost: %v", err)
+		}
+		t.Cleanup(func() { _ = h.Close() })
+		return h
+	}
+	current := newHost("current")
+	legacy := newHost("legacy", p2p.WithProtocolVersions("1.1.0"))
+	strict := newHost("strict", p2p.WithFrameV1Cutoff(time.Now().Add(-time.Hour)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Dual stack: v2 frames with current peers, v1 with the legacy one.
+	for _, peerHost := range []*p2p.AgentHost{strict, legacy} {
+		if _, err := p2p.DiscoverAndHandshake(ctx, current, peerHost.AddrInfo()); err != nil {
+			t.Fatalf("DiscoverAndHandshake: %v", err)
+		}
+		intent, _ := core.CreateIntent(current.Agent(), nil, []string{"ocr"}, strings.Repeat("page ", 500))
+		if resp, err := current.SendIntent(ctx, peerHost.PeerID(), intent); err != nil || !resp.Accepted {
+			t.Fatalf("SendIntent to %s: %v %v", peerHost.Agent().ID, resp, err)
+		}
+	}
+	if v, _ := current.PeerVersion(legacy.PeerID()); v.Has(core.FeatureFrameV2) {
+		t.Errorf("legacy peer negotiated %s", v.Version)
+	}
+
+	// The audit names the legacy peer only.
+	var audited []string
+	for _, s := range current.Deprecations().Summary() {
+		if s.Kind == core.DeprecatedFrameV1 {
+			audited = s.Peers
+		}
+	}
+	if len(audited) != 1 || audited[0] != legacy.Agent().DID.String() {
+		t.Errorf("v1 frame audit: %v", audited)
+	}
+
+	// After the cutoff the legacy peer is refused in both directions.
+	if _, err := p2p.DiscoverAndHandshake(ctx, strict, legacy.AddrInfo()); !errors.Is(err, p2p.ErrFrameV1Refused) {
+		t.Errorf("strict → legacy: got %v, want ErrFrameV1Refused", err)
+	}
+	if _, err := p2p.DiscoverAndHandshake(ctx, legacy, strict.AddrInfo()); !errors.Is(err, core.ErrVersionMismatch) {
+		t.Errorf("legacy → strict: got %v, want ErrVersionMismatch", err)
+	}
+}
+
+func TestVectorQuantization(t *testing.T) {
+	alpha := makeAgent(t, "alpha", []string{"nlp"})
+	beta := makeAgent(t, "beta", []string{"summarisation"})
+
+	hA, err := p2p.NewHost(context.Background(), alpha, p2p.WithVectorQuantization(core.QuantizeInt8))
+	if err != nil {
+		t.Fatalf("NewHost: %v", err)
+	}
+	t.Cleanup(func() { _ = hA.Close() })
+	hB := makeHost(t, beta)
+
+	if _, err := p2p.NewHost(context.Background(), beta, p2p.WithVectorQuantization(core.Quantization(9))); err == nil {
+		t.Error("expected an error for an unknown quantization")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := hA.Connect(ctx, hB.AddrInfo()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	if _, err := hA.Handshake(ctx, hB.PeerID()); err != nil {
+		t.Fatalf("Handshake: %v", err)
+	}
+
+	var got []float32
+	hB.OnIntent(func(_ peer.ID, in *core.IntentMessage) *core.NegotiationResponse {
+		got = in.IntentVector
+		h := core.DefaultNegotiationHandler(beta)
+		resp, _ := h(in)
+		return resp
+	})
+
+	vec := []float32{0.9, -0.45, 0.1, 0, 0.3}
+	intent, err := core.CreateIntent(alpha, vec, []string{"summarisation"}, "")
+	if err != nil {
+		t.Fatalf("CreateIntent: %v", err)
+	}
+	resp, err := hA.SendIntent(ctx, hB.PeerID(), intent)
+	if err != nil {
+		t.Fatalf("SendIntent: %v", err)
+	}
+	if !resp.Accepted {
+		t.Fatalf("intent rejected: %s", resp.Reason)
+	}
+	if len(got) != len(vec) {
+		t.Fatalf("received %d elements, want %d", len(got), len(vec))
+	}
+	for i := range vec {
+		if d := got[i] - vec[i]; d > 0.01 || d < -0.01 {
+			t.Errorf("element %d = %v, want about %v", i, got[i], vec[i])
+		}
+	}
+	// The host rounded its vector before signing, so it went out packed.
+	if want := core.QuantizeVector(vec, core.QuantizeInt8).Floats(); !slices.Equal(got, want) {
+		t.Errorf("received %v, want the int8 rounding %v", got, want)
+	}
+}
+
+func TestDeprecationSummaryIntervalValidated(t *testing.T) {
+	_, err := p2p.NewHost(context.Background(), makeAgent(t, "a", nil),
+		p2p.WithDeprecationSummary(0, func([]core.DeprecationSummary) {}))
+	if err == nil {
+		t.Error("NewHost accepted a zero deprecation summary interval")
+	}
+}
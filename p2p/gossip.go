@@ -0,0 +1,139 @@
+package p2p
+
+// gossip.go — GossipSub capability broadcasting.
+//
+// AnnounceCapabilities opens one stream per connected peer, which does not
+// scale past small meshes.  With WithGossipSub the host joins the
+// CapabilityTopic; announcements are published once and propagated by the
+// GossipSub mesh, and every subscriber feeds its DiscoveryRegistry from the
+// topic automatically.
+//
+// The host also joins RevocationTopic, on which RevocationLists and
+// KeyRotations are shared (see revocation.go and rotation.go).  Topic messages use the same framing as streams:
+//
+//	[4-byte big-endian length] [1-byte MessageType] [N-byte protobuf payload]
+
+import (
+	"context"
+	"fmt"
+
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+// CapabilityTopic is the GossipSub topic carrying CapabilityAnnouncements.
+const CapabilityTopic = "/agent-semantic-protocol/capabilities"
+
+// announcementTTL is the TTL, in seconds, attached to our own announcements.
+const announcementTTL = 300
+
+// WithGossipSub enables GossipSub capability broadcasting on CapabilityTopic.
+func WithGossipSub() HostOption {
+	return func(ah *AgentHost) { ah.gossipEnabled = true }
+}
+
+// startGossip joins CapabilityTopic and starts the subscription loop, which
+// runs until the host is closed.
+func (ah *AgentHost) startGossip(ctx context.Context) error {
+	ps, err := pubsub.NewGossipSub(ctx, ah.h)
+	if err != nil {
+		return fmt.Errorf("p2p gossip: create: %w", err)
+	}
+	capName, revName := ah.meshed(CapabilityTopic), ah.meshed(RevocationTopic)
+	topic, err := ps.Join(capName)
+	if err != nil {
+		return fmt.Errorf("p2p gossip: join %s: %w", capName, err)
+	}
+	sub, err := topic.Subscribe()
+	if err != nil {
+		_ = topic.Close()
+		return fmt.Errorf("p2p gossip: subscribe %s: %w", capName, err)
+	}
+	revTopic, err := ps.Join(revName)
+	if err != nil {
+		sub.Cancel()
+		_ = topic.Close()
+		return fmt.Errorf("p2p gossip: join %s: %w", revName, err)
+	}
+	revSub, err := revTopic.Subscribe()
+	if err != nil {
+		sub.Cancel()
+		_ = topic.Close()
+		_ = revTopic.Close()
+		return fmt.Errorf("p2p gossip: subscribe %s: %w", revName, err)
+	}
+	ah.pubsub = ps
+	ah.capTopic = topic
+	ah.revTopic = revTopic
+
+	subCtx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-ah.done
+		cancel()
+	}()
+	go ah.gossipLoop(subCtx, sub)
+	go ah.gossipLoop(subCtx, revSub)
+	return nil
+}
+
+// PublishCapabilities publishes this agent's CapabilityAnnouncement on
+// CapabilityTopic.  Returns an error if the host was created without
+// WithGossipSub.
+func (ah *AgentHost) PublishCapabilities(ctx context.Context) error {
+	if ah.capTopic == nil {
+		return fmt.Errorf("p2p gossip: not enabled (use WithGossipSub)")
+	}
+	ann := ah.announcement()
+	payload, err := ann.Encode()
+	if err != nil {
+		return fmt.Errorf("p2p gossip: encode announcement: %w", err)
+	}
+	if err := ah.capTopic.Publish(ctx, core.Frame(ann.MsgType(), payload)); err != nil {
+		return fmt.Errorf("p2p gossip: publish: %w", err)
+	}
+	return nil
+}
+
+func (ah *AgentHost) gossipLoop(ctx context.Context, sub *pubsub.Subscription) {
+	defer sub.Cancel()
+	for {
+		msg, err := sub.Next(ctx)
+		if err != nil {
+			return // subscription cancelled or host closed
+		}
+		if msg.GetFrom() == ah.h.ID() {
+			continue
+		}
+		msgType, data, err := core.Unframe(msg.GetData())
+		if err != nil || !ah.inspect(msg.GetFrom(), SourceGossip, msgType, data) {
+			continue
+		}
+		ah.handleGossip(msg.GetFrom(), msgType, data)
+	}
+}
+
+// handleGossip applies one topic message.  GossipSub propagates messages
+// itself, so nothing is forwarded here.
+func (ah *AgentHost) handleGossip(from peer.ID, msgType core.MessageType, data []byte) {
+	switch msgType {
+	case core.MsgCapability:
+		ann, err := ah.limits.DecodeCapabilityAnnouncement(data)
+		if err != nil {
+			return
+		}
+		ah.acceptAnnouncement(from, ann)
+	case core.MsgRevocation:
+		l, err := ah.limits.DecodeRevocationList(data)
+		if err != nil {
+			return
+		}
+		_, _ = ah.acceptRevocations(from, l)
+	case core.MsgKeyRotation:
+		r, err := ah.limits.DecodeKeyRotation(data)
+		if err != nil {
+			return
+		}
+		_, _ = ah.acceptRotation(r)
+	}
+}
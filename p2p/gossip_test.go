@@ -0,0 +1,46 @@
+package p2p_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+// TestGossipCapabilities verifies that an announcement published on the
+// capability topic reaches a subscribed peer's DiscoveryRegistry.
+func TestGossipCapabilities(t *testing.T) {
+	alpha := makeAgent(t, "alpha", []string{"nlp", "reasoning"})
+	beta := makeAgent(t, "beta", []string{"code-gen"})
+
+	hA, err := p2p.NewHost(context.Background(), alpha, p2p.WithGossipSub())
+	if err != nil {
+		t.Fatalf("NewHost: %v", err)
+	}
+	t.Cleanup(func() { _ = hA.Close() })
+	hB, err := p2p.NewHost(context.Background(), beta, p2p.WithGossipSub())
+	if err != nil {
+		t.Fatalf("NewHost: %v", err)
+	}
+	t.Cleanup(func() { _ = hB.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := hB.Connect(ctx, hA.AddrInfo()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+
+	// The GossipSub mesh forms asynchronously; republish until beta sees alpha.
+	for ctx.Err() == nil {
+		if err := hA.PublishCapabilities(ctx); err != nil {
+			t.Fatalf("PublishCapabilities: %v", err)
+		}
+		time.Sleep(200 * time.Millisecond)
+		if len(hB.Discovery().FindByCapability("reasoning")) > 0 {
+			return
+		}
+	}
+	t.Error("expected alpha to be discoverable by beta via gossip")
+}
@@ -0,0 +1,243 @@
+package p2p
+
+// graph.go — DAG workflows with fan-out, fan-in and partial failure.
+//
+// A WorkflowGraph orders steps by their DependsOn edges.  RunGraph starts
+// every step whose dependencies have succeeded, so independent branches run
+// concurrently (fan-out) and a step with several dependencies waits for all
+// of them (fan-in).  Payloads may reference any finished step with the
+// templates described in pipeline.go.
+//
+// When a step fails its OnFailure policy decides what happens next:
+//
+//	FailContinue    its dependents are skipped; other branches carry on
+//	FailAbort       nothing new is started and running steps are cancelled
+//	FailCompensate  as FailAbort, then succeeded steps are undone in reverse
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Workflow history event kinds recorded by RunGraph.
+const (
+	WorkflowStepSkipped = "step_skipped"
+	WorkflowCompensated = "compensated"
+)
+
+// WorkflowGraph is a validated, acyclic set of workflow steps.
+type WorkflowGraph struct {
+	steps      []WorkflowStep
+	index      map[string]int
+	dependents map[string][]string
+}
+
+// NewWorkflowGraph builds a graph from steps.  It fails on duplicate IDs,
+// dependencies on unknown steps and cycles.
+func NewWorkflowGraph(steps ...WorkflowStep) (*WorkflowGraph, error) {
+	g := &WorkflowGraph{
+		steps:      append([]WorkflowStep(nil), steps...),
+		index:      make(map[string]int, len(steps)),
+		dependents: make(map[string][]string),
+	}
+	for i, s := range steps {
+		if _, dup := g.index[s.ID]; dup {
+			return nil, fmt.Errorf("workflow graph: duplicate step %q", s.ID)
+		}
+		g.index[s.ID] = i
+	}
+	for _, s := range steps {
+		for _, d := range s.DependsOn {
+			if _, ok := g.index[d]; !ok {
+				return nil, fmt.Errorf("workflow graph: step %q depends on unknown step %q", s.ID, d)
+			}
+			g.dependents[d] = append(g.dependents[d], s.ID)
+		}
+	}
+	if _, err := g.Levels(); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// Steps returns the graph's steps in the order they were given.
+func (g *WorkflowGraph) Steps() []WorkflowStep { return append([]WorkflowStep(nil), g.steps...) }
+
+// Levels returns the step IDs in topological order, grouped so that every
+// step's dependencies lie in earlier groups.  Steps in one group may run
+// concurrently.
+func (g *WorkflowGraph) Levels() ([][]string, error) {
+	remaining := make(map[string]int, len(g.steps))
+	var level []string
+	for _, s := range g.steps {
+		remaining[s.ID] = len(s.DependsOn)
+		if len(s.DependsOn) == 0 {
+			level = append(level, s.ID)
+		}
+	}
+	var out [][]string
+	seen := 0
+	for len(level) > 0 {
+		out = append(out, level)
+		seen += len(level)
+		var next []string
+		for _, id := range level {
+			for _, d := range g.dependents[id] {
+				if remaining[d]--; remaining[d] == 0 {
+					next = append(next, d)
+				}
+			}
+		}
+		level = next
+	}
+	if seen != len(g.steps) {
+		return nil, fmt.Errorf("workflow graph: dependency cycle")
+	}
+	return out, nil
+}
+
+// RunGraph runs g, starting each step as soon as all of its dependencies
+// have succeeded.  Steps are sent as streamed intents, as in RunSequential.
+//
+// Results are returned in the order of g.Steps(); steps that never ran have
+// Accepted false and a Reason saying why.  The error is non-nil if a
+// FailAbort or FailCompensate step failed; for the latter it is a
+// *RollbackError.
+func (o *WorkflowOrchestrator) RunGraph(
+	ctx context.Context,
+	workflowID string,
+	g *WorkflowGraph,
+) ([]StepResult, error) {
+	return o.runGraph(ctx, g, &WorkflowState{WorkflowID: workflowID, Graph: true, Steps: g.steps, Status: WorkflowRunning})
+}
+
+// runGraph runs the steps of g that have no result in state yet,
+// checkpointing after each one.
+func (o *WorkflowOrchestrator) runGraph(ctx context.Context, g *WorkflowGraph, state *WorkflowState) ([]StepResult, error) {
+	parent := ctx
+	ctx, stop := context.WithCancel(ctx)
+	run := o.newRun(ctx, state.WorkflowID, g.steps, state)
+	var wg sync.WaitGroup
+	run.watch(ctx, &wg)
+	defer wg.Wait()
+	defer stop()
+
+	type outcome struct {
+		step WorkflowStep
+		r    StepResult
+		err  error
+	}
+	finished := make(chan outcome)
+	results := state.resultMap()
+	waiting := make(map[string]int, len(g.steps))
+	var failure, interrupted error
+	var failedStep WorkflowStep
+	running := 0
+
+	launch := func(s WorkflowStep) {
+		// Give the step its own view of the results it may reference.
+		view := make(map[string]StepResult, len(results))
+		for id, r := range results {
+			view[id] = r
+		}
+		running++
+		go func() {
+			r, err := o.runStep(ctx, run, s, view)
+			finished <- outcome{s, r, err}
+		}()
+	}
+	run.checkpoint()
+	for _, s := range g.steps {
+		for _, d := range s.DependsOn {
+			if !results[d].Accepted {
+				waiting[s.ID]++
+			}
+		}
+		if _, ran := results[s.ID]; !ran && waiting[s.ID] == 0 {
+			launch(s)
+		}
+	}
+
+	for running > 0 {
+		out := <-finished
+		running--
+		results[out.step.ID] = out.r
+		if out.err != nil && failure == nil && parent.Err() != nil {
+			// Cancelled, not failed: the step is run again on Resume.
+			if interrupted == nil {
+				interrupted = fmt.Errorf("step %q: %w", out.step.ID, out.err)
+			}
+			continue
+		}
+		state.Results = append(state.Results, out.r)
+		if out.err != nil {
+			if out.step.OnFailure == FailContinue {
+				o.skipDependents(g, run, out.step.ID, results)
+			} else if failure == nil {
+				failure = fmt.Errorf("step %q: %w", out.step.ID, out.err)
+				failedStep = out.step
+				if failedStep.OnFailure == FailCompensate {
+					state.Status, state.Error = WorkflowCompensating, failure.Error()
+				}
+				stop()
+			}
+			run.checkpoint()
+			continue
+		}
+		run.checkpoint()
+		if failure != nil {
+			continue
+		}
+		for _, id := range g.dependents[out.step.ID] {
+			if waiting[id]--; waiting[id] == 0 {
+				if _, skipped := results[id]; !skipped {
+					launch(g.steps[g.index[id]])
+				}
+			}
+		}
+	}
+
+	ordered := g.order(results)
+	switch {
+	case failure == nil && interrupted != nil:
+		return ordered, interrupted
+	case failure != nil && failedStep.OnFailure == FailCompensate:
+		failure = o.compensate(parent, run, state.succeeded(), results, failure)
+	default:
+		run.finish(failure)
+	}
+	return ordered, failure
+}
+
+// order returns results in the order of g.Steps(), with a placeholder for
+// steps that never ran.
+func (g *WorkflowGraph) order(results map[string]StepResult) []StepResult {
+	ordered := make([]StepResult, len(g.steps))
+	for i, s := range g.steps {
+		r, ok := results[s.ID]
+		if !ok {
+			r = StepResult{StepID: s.ID, Reason: "not run: workflow aborted", Timestamp: time.Now()}
+		}
+		ordered[i] = r
+	}
+	return ordered
+}
+
+// skipDependents marks every step downstream of failedID as skipped.
+func (o *WorkflowOrchestrator) skipDependents(g *WorkflowGraph, run *workflowRun, failedID string, results map[string]StepResult) {
+	queue := append([]string(nil), g.dependents[failedID]...)
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if _, done := results[id]; done {
+			continue
+		}
+		reason := fmt.Sprintf("skipped: dependency %q failed", failedID)
+		results[id] = StepResult{StepID: id, Reason: reason, Timestamp: time.Now()}
+		run.state.Results = append(run.state.Results, results[id])
+		o.record(run.id, WorkflowEvent{Kind: WorkflowStepSkipped, StepID: id, Reason: reason})
+		queue = append(queue, g.dependents[id]...)
+	}
+}
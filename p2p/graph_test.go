@@ -0,0 +1,35 @@
+package p2p_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+func TestWorkflowGraphLevels(t *testing.T) {
+	g, err := p2p.NewWorkflowGraph(
+		p2p.WorkflowStep{ID: "fetch"},
+		p2p.WorkflowStep{ID: "ocr", DependsOn: []string{"fetch"}},
+		p2p.WorkflowStep{ID: "translate", DependsOn: []string{"fetch"}},
+		p2p.WorkflowStep{ID: "merge", DependsOn: []string{"ocr", "translate"}},
+	)
+	if err != nil {
+		t.Fatalf("NewWorkflowGraph: %v", err)
+	}
+	levels, _ := g.Levels()
+	if got := fmt.Sprint(levels); got != "[[fetch] [ocr translate] [merge]]" {
+		t.Errorf("levels: %s", got)
+	}
+
+	_, err = p2p.NewWorkflowGraph(
+		p2p.WorkflowStep{ID: "a", DependsOn: []string{"b"}},
+		p2p.WorkflowStep{ID: "b", DependsOn: []string{"a"}},
+	)
+	if err == nil {
+		t.Error("expected an error for a dependency cycle")
+	}
+	if _, err = p2p.NewWorkflowGraph(p2p.WorkflowStep{ID: "a", DependsOn: []string{"missing"}}); err == nil {
+		t.Error("expected an error for an unknown dependency")
+	}
+}
@@ -0,0 +1,129 @@
+package p2p
+
+// health.go — The built-in health capability.
+//
+// A host created with WithHealthCapability advertises core.HealthCapability
+// and answers health intents itself, before any registered callback, with
+// its Health status.  Health intents are answered while the host drains, so
+// a sweep shows draining agents as such rather than as failures.
+// SweepHealth asks every connected peer that advertises the capability.
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+// healthErrorLimit is how many recent errors a HealthStatus reports.
+const healthErrorLimit = 8
+
+// WithHealthCapability makes the host answer health intents and adds
+// core.HealthCapability to the capabilities it advertises.  The agent passed
+// to NewHost is left unchanged; Agent returns the host's copy.
+func WithHealthCapability() HostOption {
+	return func(ah *AgentHost) { ah.health = true }
+}
+
+// Health reports the host's current status.
+func (ah *AgentHost) Health() core.HealthStatus {
+	s := core.HealthStatus{
+		AgentID:       ah.agent.ID,
+		DID:           ah.agent.DID.String(),
+		Version:       core.ProtocolVersion,
+		UptimeSeconds: time.Since(ah.started).Seconds(),
+		Peers:         len(ah.h.Network().Peers()),
+		CheckedAt:     time.Now(),
+	}
+	ah.pendMu.Lock()
+	for e := range ah.pending {
+		if e.info.State == IntentExecuting {
+			s.Executing++
+		} else {
+			s.Queued++
+		}
+	}
+	s.Draining = ah.rejecting
+	ah.pendMu.Unlock()
+	ah.errMu.Lock()
+	s.LastErrors = slices.Clone(ah.lastErrors)
+	ah.errMu.Unlock()
+	return s
+}
+
+// answerHealth answers intent if it is a health intent the host serves.
+// The intent is itself tracked as queued, so it is left out of the count.
+func (ah *AgentHost) answerHealth(intent *core.IntentMessage) *core.NegotiationResponse {
+	if !ah.health || !core.IsHealthIntent(intent) {
+		return nil
+	}
+	s := ah.Health()
+	s.Queued = max(0, s.Queued-1)
+	resp, err := core.HealthResponse(ah.agent, intent, s)
+	if err != nil {
+		return nil
+	}
+	return resp
+}
+
+// noteError remembers a failure for Health.
+func (ah *AgentHost) noteError(op string, err error) {
+	if err == nil {
+		return
+	}
+	ah.errMu.Lock()
+	defer ah.errMu.Unlock()
+	ah.lastErrors = append(ah.lastErrors, core.HealthError{At: time.Now(), Op: op, Error: err.Error()})
+	if n := len(ah.lastErrors); n > healthErrorLimit {
+		ah.lastErrors = slices.Delete(ah.lastErrors, 0, n-healthErrorLimit)
+	}
+}
+
+// CheckHealth sends a health intent to peerID and returns its status.
+func (ah *AgentHost) CheckHealth(ctx context.Context, peerID peer.ID) (*core.HealthStatus, error) {
+	intent, err := core.NewHealthIntent(ah.agent)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := ah.SendIntent(ctx, peerID, intent)
+	if err != nil {
+		return nil, err
+	}
+	return core.ParseHealthResponse(resp)
+}
+
+// PeerHealth is one peer's entry in a health sweep.
+type PeerHealth struct {
+	PeerID  peer.ID
+	AgentID string
+	Status  *core.HealthStatus // nil if Err is set
+	Err     error
+}
+
+// SweepHealth checks, concurrently, every connected peer that advertised
+// core.HealthCapability in its handshake, and returns the results ordered
+// by peer ID.
+func (ah *AgentHost) SweepHealth(ctx context.Context) []PeerHealth {
+	var out []PeerHealth
+	for pid, p := range ah.KnownPeers() {
+		if slices.Contains(p.Capabilities, core.HealthCapability) && ah.h.Network().Connectedness(pid) == network.Connected {
+			out = append(out, PeerHealth{PeerID: pid, AgentID: p.AgentID})
+		}
+	}
+	var wg sync.WaitGroup
+	for i := range out {
+		wg.Add(1)
+		go func(r *PeerHealth) {
+			defer wg.Done()
+			r.Status, r.Err = ah.CheckHealth(ctx, r.PeerID)
+		}(&out[i])
+	}
+	wg.Wait()
+	sort.Slice(out, func(i, j int) bool { return out[i].PeerID < out[j].PeerID })
+	return out
+}
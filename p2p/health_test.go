@@ -0,0 +1,133 @@
+package p2p_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+func TestDrainRejectsNewIntents(t *testing.T) {
+	alpha := makeAgent(t, "alpha", nil)
+	beta := makeAgent(t, "beta", []string{"ocr"})
+	hA := makeHost(t, alpha)
+	hB := makeHost(t, beta)
+
+	release := make(chan struct{})
+	hB.OnIntent(func(peer.ID, *core.IntentMessage) *core.NegotiationResponse {
+		<-release
+		return nil // default handler answers
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := hA.Connect(ctx, hB.AddrInfo()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	first, _ := core.CreateIntent(alpha, nil, []string{"ocr"}, "page 1")
+	firstDone := make(chan *core.NegotiationResponse, 1)
+	go func() {
+		resp, _ := hA.SendIntent(ctx, hB.PeerID(), first)
+		firstDone <- resp
+	}()
+	for {
+		pend := hB.PendingIntents()
+		if len(pend) == 1 && pend[0].State == p2p.IntentExecuting && pend[0].IntentID == first.ID {
+			break
+		}
+		if ctx.Err() != nil {
+			t.Fatalf("intent never showed as executing: %+v", pend)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	drained := make(chan error, 1)
+	go func() { drained <- hB.Drain(ctx) }()
+
+	second, _ := core.CreateIntent(alpha, nil, []string{"ocr"}, "page 2")
+	resp, err := hA.SendIntent(ctx, hB.PeerID(), second)
+	if err != nil {
+		t.Fatalf("SendIntent during drain: %v", err)
+	}
+	if resp.Accepted || resp.Reason != p2p.DrainReason {
+		t.Errorf("intent during drain: accepted=%v reason=%q", resp.Accepted, resp.Reason)
+	}
+
+	close(release)
+	if resp := <-firstDone; resp == nil || !resp.Accepted {
+		t.Errorf("executing intent was not answered normally: %+v", resp)
+	}
+	if err = <-drained; err != nil {
+		t.Errorf("Drain: %v", err)
+	}
+	if n := len(hB.PendingIntents()); n != 0 {
+		t.Errorf("%d intents pending after drain", n)
+	}
+}
+
+// TestHealthCapability verifies that a host with the health capability
+// reports its status and shows up in a sweep.
+func TestHealthCapability(t *testing.T) {
+	alpha := makeAgent(t, "alpha", []string{"nlp"})
+	beta := makeAgent(t, "beta", []string{"ocr"})
+
+	hA := makeHost(t, alpha)
+	hB, err := p2p.NewHost(context.Background(), beta, p2p.WithHealthCapability())
+	if err != nil {
+		t.Fatalf("NewHost: %v", err)
+	}
+	t.Cleanup(func() { _ = hB.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := hA.Connect(ctx, hB.AddrInfo()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	if _, err := hA.Handshake(ctx, hB.PeerID()); err != nil {
+		t.Fatalf("Handshake: %v", err)
+	}
+
+	status, err := hA.CheckHealth(ctx, hB.PeerID())
+	if err != nil {
+		t.Fatalf("CheckHealth: %v", err)
+	}
+	if status.AgentID != "beta" || status.Version != core.ProtocolVersion || status.Peers != 1 {
+		t.Errorf("status: %+v", status)
+	}
+	if status.Queued != 0 || status.Executing != 0 {
+		t.Errorf("idle host reports load: %+v", status)
+	}
+
+	sweep := hA.SweepHealth(ctx)
+	if len(sweep) != 1 || sweep[0].PeerID != hB.PeerID() || sweep[0].Err != nil {
+		t.Fatalf("sweep: %+v", sweep)
+	}
+
+	// Hosts without the capability refuse health intents.
+	if _, err = hB.CheckHealth(ctx, hA.PeerID()); err == nil {
+		t.Error("expected an error from a host without the health capability")
+	}
+}
+
+// TestHealthCapabilitySharedAgent verifies that WithHealthCapability leaves
+// the caller's agent alone when it backs more than one host.
+func TestHealthCapabilitySharedAgent(t *testing.T) {
+	beta := makeAgent(t, "beta", []string{"ocr"})
+	for range 2 {
+		h, err := p2p.NewHost(context.Background(), beta, p2p.WithHealthCapability())
+		if err != nil {
+			t.Fatalf("NewHost: %v", err)
+		}
+		t.Cleanup(func() { _ = h.Close() })
+		if got := h.Agent().Capabilities; !slices.Equal(got, []string{"ocr", core.HealthCapability}) {
+			t.Errorf("host capabilities: %v", got)
+		}
+	}
+	if !slices.Equal(beta.Capabilities, []string{"ocr"}) {
+		t.Errorf("caller's agent was modified: %v", beta.Capabilities)
+	}
+}
@@ -4,27 +4,50 @@
 // "/agent-semantic-protocol/1.0.0" protocol ID.  Messages are framed using core.Frame/Unframe:
 //
 //	[4-byte big-endian length] [1-byte MessageType] [N-byte protobuf payload]
+//
+// or, with peers that negotiated protocol 1.2.0 or later, as core.FrameV2
+// frames (see frames.go).
 package p2p
 
 import (
 	"context"
-	"encoding/binary"
 	"fmt"
 	"io"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
 	libp2p "github.com/libp2p/go-libp2p"
+	dht "github.com/libp2p/go-libp2p-kad-dht"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	"github.com/libp2p/go-libp2p/core/crypto"
 	"github.com/libp2p/go-libp2p/core/host"
 	"github.com/libp2p/go-libp2p/core/network"
 	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/libp2p/go-libp2p/core/protocol"
+	"github.com/libp2p/go-libp2p/p2p/discovery/mdns"
+	drouting "github.com/libp2p/go-libp2p/p2p/discovery/routing"
 	"github.com/olserra/agent-semantic-protocol/core"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
 // AgentSemanticProtocol is the libp2p protocol identifier for Agent Semantic Protocol v1.
+// Hosts in a named mesh append the mesh name (see WithMesh).
 const AgentSemanticProtocol protocol.ID = "/agent-semantic-protocol/1.0.0"
 
+// ErrProtocolMismatch is returned (wrapped) when a peer does not speak the
+// host's protocol ID, usually because it belongs to another mesh.
+var ErrProtocolMismatch = fmt.Errorf("p2p: peer does not speak our protocol")
+
+// ErrPeerUnreachable is returned (wrapped, together with the libp2p error)
+// when a peer cannot be dialled or a stream to it cannot be opened.
+var ErrPeerUnreachable = fmt.Errorf("p2p: peer unreachable")
+
 // HandshakeCallback is invoked when a peer initiates a handshake.
 // Return a HandshakeMessage to respond, or nil to reject.
 type HandshakeCallback func(peerID peer.ID, msg *core.HandshakeMessage) *core.HandshakeMessage
@@ -33,46 +56,592 @@ type HandshakeCallback func(peerID peer.ID, msg *core.HandshakeMessage) *core.Ha
 // Return a NegotiationResponse to reply.
 type IntentCallback func(peerID peer.ID, msg *core.IntentMessage) *core.NegotiationResponse
 
+// IntentContextCallback is like IntentCallback but also receives a context
+// carrying the sender's core.PeerMemory when the host has a MemoryStore, and
+// the span serving the intent; pass it on to SendIntent to continue the
+// sender's trace.
+type IntentContextCallback func(ctx context.Context, peerID peer.ID, msg *core.IntentMessage) *core.NegotiationResponse
+
+// WorkflowCallback is invoked when a peer sends a WorkflowMessage.
+type WorkflowCallback func(peerID peer.ID, msg *core.WorkflowMessage)
+
 // AgentHost wraps a libp2p host with Agent Semantic Protocol protocol logic.
 type AgentHost struct {
-	h         host.Host
-	agent     *core.Agent
-	discovery *core.DiscoveryRegistry
-	trust     *core.TrustGraph
+	h            host.Host
+	listenAddrs  []string
+	mesh         string      // empty: the default mesh
+	proto        protocol.ID // AgentSemanticProtocol, suffixed with mesh
+	muxProto     protocol.ID // MuxProtocol, suffixed with mesh
+	batchProto   protocol.ID // BatchProtocol, suffixed with mesh
+	observeProto protocol.ID // ObserverProtocol, suffixed with mesh
+	agent        *core.Agent
+	discovery    *core.DiscoveryRegistry
+	trust        *core.TrustGraph
 
-	onHandshake HandshakeCallback
-	onIntent    IntentCallback
-	mu          sync.RWMutex
+	onHandshake    HandshakeCallback
+	onIntent       IntentCallback
+	onIntentCtx    IntentContextCallback
+	onStreamIntent StreamIntentCallback
+	onWorkflow     WorkflowCallback
+	onResult       WorkflowResultCallback
+	onAlert        AlertCallback
+	onRevocation   RevocationCallback
+	inspectors     []Inspector
+	mu             sync.RWMutex
 
 	// known stores capability profiles by peer.ID string for quick lookup.
 	known map[string]core.AgentProfile
+
+	trustStore     core.TrustStore
+	discoveryFile  string // see WithDiscoveryFile
+	discoveryEvery time.Duration
+	trustPolicies  core.TrustPolicies // see WithTrustPolicy
+	memory         core.MemoryStore
+
+	receipts *core.ReceiptLog // nil: no completion receipts are issued
+	price    core.PriceFunc
+
+	janitor         *core.Janitor
+	retention       core.RetentionPolicy
+	janitorInterval time.Duration
+
+	codecs       []string                     // offered when initiating a handshake
+	versions     []string                     // offered and accepted; nil for every supported version
+	peerCodecs   map[peer.ID]core.Codec       // negotiated per peer; guarded by mu
+	peerVersions map[peer.ID]core.VersionInfo // negotiated per peer; guarded by mu
+	peerFeatures map[peer.ID][]core.Feature   // advertised per peer; guarded by mu
+	features     []core.Feature               // optional subsystems the host uses
+	peerResults  map[peer.ID]int64            // result limits advertised per peer; guarded by mu
+	maxResult    int64                        // largest inline result accepted (see WithMaxResultSize)
+
+	aliases   *core.CapabilityAliases  // nil: capability names are used as sent
+	catalog   *core.CapabilityCatalog  // nil: no semantic matching or intent scoring
+	service   map[string]time.Duration // Expected service time per capability, for the default handler
+	selection *core.SelectionPolicy    // how equivalent responders are chosen
+	assessed  float64                  // weight of responders' SimilarityScore in BroadcastIntent
+
+	revocations        *core.RevocationSet
+	rotations          *core.KeyRotationSet
+	revocationURL      string
+	revocationInterval time.Duration
+
+	attestPolicy *core.AttestationPolicy
+	attestations *core.AttestationRegistry // nil without WithAttestationPolicy
+
+	alerts           *core.AlertCache
+	alertLimiter     *core.RateLimiter
+	alertAuthorities map[string]bool
+	sigPolicy        SignaturePolicy
+	clock            *core.ClockSkewTable
+	// maxIntentAge / maxIntentFuture bound skew-compensated intent
+	// timestamps; zero disables the check (see WithTimestampWindow).
+	maxIntentAge    time.Duration
+	maxIntentFuture time.Duration
+	replays         *core.ReplayCache // intents already served, to drop replays
+	analytics       *core.CapabilityAnalytics
+	v1Cutoff        time.Time         // zero: v1 frames are always accepted
+	quantization    core.Quantization // vectors sent to peers with FeatureQuantizedVectors
+	limits          core.DecodeLimits // bounds on every message read from peers
+	audit           *core.AuditLog
+	log             core.Logger
+	deprecations    *core.DeprecationTracker
+	depInterval     time.Duration
+	depSummary      func([]core.DeprecationSummary)
+	done            chan struct{} // closed by Close to stop background loops
+	closeOnce       sync.Once
+
+	memNet *MemoryNetwork // Set by WithMemoryNetwork, which replaces listening
+
+	autoNAT      bool
+	holePunch    bool
+	relayService bool
+	relays       []peer.AddrInfo
+	reachability network.Reachability // Guarded by mu; set by AutoNAT
+
+	psk     []byte     // Private network key; see WithPrivateNetwork
+	private bool       // WithPrivateNetwork was given, even with an empty key
+	allow   *AllowList // Peers admitted; nil admits every peer
+
+	dhtEnabled   bool
+	dhtBootstrap []peer.AddrInfo
+	dht          *dht.IpfsDHT
+	routing      *drouting.RoutingDiscovery
+
+	gossipEnabled bool
+	pubsub        *pubsub.PubSub
+	capTopic      *pubsub.Topic
+	revTopic      *pubsub.Topic
+
+	muxEnabled  bool
+	muxInflight int
+	muxTimeout  time.Duration
+	sessMu      sync.Mutex
+	sessions    map[peer.ID]*muxSession
+
+	batchEnabled  bool
+	batchInterval time.Duration
+	batchMaxBytes int
+	queueMu       sync.Mutex
+	queues        map[peer.ID]*sendQueue
+
+	sealPayloads bool // WithPayloadEncryption
+	relayHops    int  // WithRelay; 0: intents are never relayed
+	health       bool // WithHealthCapability
+
+	federation *federationLink // Set by Federate; guarded by mu
+
+	started    time.Time // when NewHost returned, for Health
+	errMu      sync.Mutex
+	lastErrors []core.HealthError // newest last, at most healthErrorLimit
+
+	rateLimit   *core.RateLimit   // nil: no rate limiting
+	ratePenalty float32           // trust lost per rate-limited intent
+	limiter     *core.RateLimiter // built from rateLimit by NewHost
+	errorBudget *core.ErrorBudget // nil: no quarantine
+	quarantine  *core.Quarantine  // built from errorBudget by NewHost
+	credPolicy  core.CredentialPolicy
+
+	resultMu      sync.Mutex
+	resultWaiters map[string]resultWaiter // keyed by intent ID; see expectResult
+
+	monitorCfg *core.HealthMonitorConfig // nil: peers are not probed
+	monitor    *core.HealthMonitor       // built from monitorCfg by NewHost
+
+	onDisconnect PeerDisconnectCallback
+	disconnects  DisconnectPolicy
+	callMu       sync.Mutex
+	callSeq      uint64
+	calls        map[peer.ID]map[uint64]context.CancelCauseFunc // in-flight SendIntent calls
+
+	mdnsMu      sync.Mutex
+	mdns        mdns.Service
+	mdnsPending map[peer.ID]bool // peers being handshaked after an mDNS announcement
+
+	peerCfg *PeerManagerConfig // set by WithPeerManager
+	peers   *PeerManager
+
+	pendMu       sync.Mutex
+	pending      map[*pendingEntry]struct{} // intents being served
+	rejecting    bool                       // set by RejectAll and Drain
+	rejectReason string
+	idle         []chan struct{} // closed when pending empties (Drain)
+
+	evMu        sync.Mutex
+	subscribers []*subscriber // replaced, never modified in place
+
+	observers map[string]bool // DIDs with the observer role; see WithObservers
+
+	metrics *hostMetrics // nil without WithMetrics
+	tracer  trace.Tracer
+}
+
+// HostOption configures an AgentHost at construction time.
+type HostOption func(*AgentHost)
+
+// WithTrustStore backs the host's TrustGraph with a persistent store, so trust
+// scores learned in earlier runs are reloaded on startup.
+func WithTrustStore(store core.TrustStore) HostOption {
+	return func(ah *AgentHost) { ah.trustStore = store }
 }
 
-// NewHost creates a new Agent Semantic Protocol P2P host listening on an available TCP port.
+// WithDiscoveryFile restores the host's DiscoveryRegistry from path on
+// startup and saves it there every interval (a minute if zero) and on
+// Close, so a restarted agent knows its peers without waiting for them to
+// re-announce.  See core.DiscoveryRegistry.Save.
+func WithDiscoveryFile(path string, interval time.Duration) HostOption {
+	return func(ah *AgentHost) {
+		ah.discoveryFile = path
+		ah.discoveryEvery = interval
+	}
+}
+
+// WithTimestampWindow rejects incoming intents whose timestamp, corrected
+// for the sender's estimated clock offset, is older than maxAge or further
+// than maxFuture in the future.  Zero disables either bound.
+func WithTimestampWindow(maxAge, maxFuture time.Duration) HostOption {
+	return func(ah *AgentHost) {
+		ah.maxIntentAge = maxAge
+		ah.maxIntentFuture = maxFuture
+	}
+}
+
+// WithDecodeLimits bounds the fields of every message the host reads from
+// peers, in place of core.DefaultDecodeLimits.  A message over a limit is
+// dropped as soon as the decoder reaches the offending field.
+func WithDecodeLimits(l core.DecodeLimits) HostOption {
+	return func(ah *AgentHost) { ah.limits = l }
+}
+
+// WithReplayWindow sets how long the host remembers served intents that
+// carry no ExpiresAt, so a replay within window is dropped.  Intents with an
+// expiry are remembered until they expire; the host drops intents that
+// expire more than core.MaxIntentLifetime ahead.  The default is
+// core.DefaultReplayWindow.
+func WithReplayWindow(window time.Duration) HostOption {
+	return func(ah *AgentHost) { ah.replays = core.NewReplayCache(window) }
+}
+
+// WithDeprecationSummary calls fn every interval with the deprecated
+// behaviour observed from peers so far (see core.DeprecationTracker).
+// interval must be positive.
+func WithDeprecationSummary(interval time.Duration, fn func([]core.DeprecationSummary)) HostOption {
+	return func(ah *AgentHost) {
+		ah.depInterval = interval
+		ah.depSummary = fn
+	}
+}
+
+// WithMemoryStore records every negotiation in store under the DID the
+// peer handshaked as and exposes that history to OnIntentContext handlers.
+// Exchanges with peers that never handshaked are not remembered.
+func WithMemoryStore(store core.MemoryStore) HostOption {
+	return func(ah *AgentHost) { ah.memory = store }
+}
+
+// WithReceipts issues a signed core.CompletionReceipt for every intent the
+// host accepts and appends it to log.  price sets each receipt's cost; a nil
+// price issues receipts with zero cost.
+func WithReceipts(log *core.ReceiptLog, price core.PriceFunc) HostOption {
+	return func(ah *AgentHost) {
+		ah.receipts = log
+		ah.price = price
+	}
+}
+
+// WithRetention enforces policy on the host's MemoryStore (see
+// WithMemoryStore) with a janitor sweeping every interval.  Register further
+// tables on Janitor().  With core.QuotaReject, exchanges are not remembered
+// while the store is over quota.
+func WithRetention(policy core.RetentionPolicy, interval time.Duration) HostOption {
+	return func(ah *AgentHost) {
+		ah.janitor = core.NewJanitor()
+		ah.retention = policy
+		ah.janitorInterval = interval
+	}
+}
+
+// WithCapabilityAliases normalises capability names through aliases in
+// discovery, incoming intents, outgoing announcements and the default
+// negotiation handler.  Peers still using aliases marked deprecated when the
+// host is created are reported to the host's DeprecationTracker.
+func WithCapabilityAliases(aliases *core.CapabilityAliases) HostOption {
+	return func(ah *AgentHost) { ah.aliases = aliases }
+}
+
+// WithCapabilityCatalog lets discovery fall back to semantic matching
+// through catalog when no peer offers the exact capability requested (see
+// core.CapabilityCatalog).  The default negotiation handler also uses it to
+// report a SimilarityScore with its answers.
+func WithCapabilityCatalog(catalog *core.CapabilityCatalog) HostOption {
+	return func(ah *AgentHost) {
+		ah.catalog = catalog
+		ah.discovery.SetCatalog(catalog)
+	}
+}
+
+// WithServiceTimes tells the default negotiation handler how long the agent
+// expects to take per capability, so it rejects intents whose Deadline or
+// MaxLatency it would miss (see core.NegotiationOptions.ServiceTimes).
+func WithServiceTimes(times map[string]time.Duration) HostOption {
+	return func(ah *AgentHost) { ah.service = maps.Clone(times) }
+}
+
+// WithMesh puts the host in the named mesh.  The name is appended to the
+// host's protocol IDs, DHT prefix and GossipSub topics, so hosts in
+// different meshes cannot open streams to each other, and handshakes from
+// another mesh fail with core.ErrMeshMismatch.  Names are one path segment
+// (see core.ValidMeshName); NewHost rejects others.
+func WithMesh(name string) HostOption {
+	return func(ah *AgentHost) { ah.mesh = name }
+}
+
+// Mesh returns the host's mesh name, empty for the default mesh.
+func (ah *AgentHost) Mesh() string { return ah.mesh }
+
+// ProtocolID returns the protocol ID the host serves: AgentSemanticProtocol,
+// suffixed with the mesh name if it has one.
+func (ah *AgentHost) ProtocolID() protocol.ID { return ah.proto }
+
+// meshed appends the host's mesh name, if any, to a protocol ID or topic.
+func (ah *AgentHost) meshed(name string) string {
+	if ah.mesh == "" {
+		return name
+	}
+	return name + "/" + ah.mesh
+}
+
+// newStream opens a stream to pid, reporting a peer that does not speak
+// proto with ErrProtocolMismatch and any other failure with
+// ErrPeerUnreachable.
+func (ah *AgentHost) newStream(ctx context.Context, pid peer.ID, proto protocol.ID) (network.Stream, error) {
+	s, err := ah.h.NewStream(allowRelayed(ctx), pid, proto)
+	if err == nil {
+		return s, nil
+	}
+	ah.streamError("open", err)
+	// libp2p's multistream-select reports "protocols not supported" when
+	// the peer does not serve proto.
+	if strings.Contains(err.Error(), "protocols not supported") {
+		return nil, fmt.Errorf("%w %s (different mesh?): %v", ErrProtocolMismatch, proto, err)
+	}
+	return nil, fmt.Errorf("%w: %s: %w", ErrPeerUnreachable, pid, err)
+}
+
+// DefaultListenAddr is where a host listens without WithListenAddrs: a
+// random loopback TCP port.
+const DefaultListenAddr = "/ip4/127.0.0.1/tcp/0"
+
+// WithListenAddrs sets the multiaddrs the host listens on.
+func WithListenAddrs(addrs ...string) HostOption {
+	return func(ah *AgentHost) {
+		if len(addrs) > 0 {
+			ah.listenAddrs = append([]string(nil), addrs...)
+		}
+	}
+}
+
+// WithSelection replaces the host's selection policy (best match for every
+// capability by default).
+func WithSelection(p *core.SelectionPolicy) HostOption {
+	return func(ah *AgentHost) { ah.selection = p }
+}
+
+// evictionInterval is how often expired DiscoveryRegistry entries are purged.
+const evictionInterval = 30 * time.Second
+
+// defaultSnapshotInterval is how often WithDiscoveryFile saves the
+// DiscoveryRegistry when no interval is given.
+const defaultSnapshotInterval = time.Minute
+
+// NewHost creates a new Agent Semantic Protocol P2P host listening on an
+// available TCP port, or attached to a MemoryNetwork (WithMemoryNetwork).
 // The host's identity is derived from the agent's Ed25519 key.
-func NewHost(ctx context.Context, agent *core.Agent) (*AgentHost, error) {
-	h, err := libp2p.New(
-		libp2p.ListenAddrStrings(
-			"/ip4/127.0.0.1/tcp/0",
-		),
-	)
+func NewHost(ctx context.Context, agent *core.Agent, opts ...HostOption) (*AgentHost, error) {
+	ah := &AgentHost{
+		agent:            agent,
+		discovery:        core.NewDiscoveryRegistry(),
+		trust:            core.NewTrustGraph(),
+		clock:            core.NewClockSkewTable(),
+		deprecations:     core.NewDeprecationTracker(),
+		alerts:           core.NewAlertCache(alertCacheTTL),
+		alertAuthorities: make(map[string]bool),
+		revocations:      core.NewRevocationSet(),
+		rotations:        core.NewKeyRotationSet(),
+		replays:          core.NewReplayCache(core.DefaultReplayWindow),
+		selection:        core.NewSelectionPolicy(nil),
+		known:            make(map[string]core.AgentProfile),
+		sessions:         make(map[peer.ID]*muxSession),
+		queues:           make(map[peer.ID]*sendQueue),
+		calls:            make(map[peer.ID]map[uint64]context.CancelCauseFunc),
+		pending:          make(map[*pendingEntry]struct{}),
+		peerCodecs:       make(map[peer.ID]core.Codec),
+		peerVersions:     make(map[peer.ID]core.VersionInfo),
+		peerFeatures:     make(map[peer.ID][]core.Feature),
+		features:         core.DefaultFeatures(),
+		peerResults:      make(map[peer.ID]int64),
+		maxResult:        core.DefaultMaxResultSize,
+		limits:           core.DefaultDecodeLimits,
+		listenAddrs:      []string{DefaultListenAddr},
+		tracer:           noop.NewTracerProvider().Tracer(tracerName),
+		log:              core.DiscardLogger(),
+		done:             make(chan struct{}),
+	}
+	for _, o := range opts {
+		o(ah)
+	}
+	if !core.ValidMeshName(ah.mesh) {
+		return nil, fmt.Errorf("p2p: invalid mesh name %q", ah.mesh)
+	}
+	if ah.health && !slices.Contains(agent.Capabilities, core.HealthCapability) {
+		// Advertise it from a copy: the caller's agent may back other hosts.
+		withHealth := *agent
+		withHealth.Capabilities = append(slices.Clone(agent.Capabilities), core.HealthCapability)
+		ah.agent = &withHealth
+	}
+	if ah.relayHops < 0 {
+		return nil, fmt.Errorf("p2p: negative relay hop limit %d", ah.relayHops)
+	}
+	if ah.maxResult < 0 {
+		return nil, fmt.Errorf("p2p: negative result size limit %d", ah.maxResult)
+	}
+	if err := ah.limits.Validate(); err != nil {
+		return nil, fmt.Errorf("p2p: %w", err)
+	}
+	if err := ah.quantization.Validate(); err != nil {
+		return nil, fmt.Errorf("p2p: %w", err)
+	}
+	if err := ah.credPolicy.Validate(); err != nil {
+		return nil, fmt.Errorf("p2p: %w", err)
+	}
+	if ah.depSummary != nil && ah.depInterval <= 0 {
+		return nil, fmt.Errorf("p2p: deprecation summary interval %v must be positive", ah.depInterval)
+	}
+	if ah.private && len(ah.psk) != PSKSize {
+		return nil, fmt.Errorf("p2p: pre-shared key is %d bytes, want %d", len(ah.psk), PSKSize)
+	}
+	if ah.private && ah.memNet != nil {
+		return nil, fmt.Errorf("p2p: a MemoryNetwork cannot apply a pre-shared key")
+	}
+	if ah.rateLimit != nil {
+		l, err := core.NewRateLimiter(*ah.rateLimit)
+		if err != nil {
+			return nil, fmt.Errorf("p2p: %w", err)
+		}
+		ah.limiter = l
+	}
+	ah.alertLimiter, _ = core.NewRateLimiter(alertRate) // valid by construction
+	if ah.errorBudget != nil {
+		q, err := core.NewQuarantine(*ah.errorBudget)
+		if err != nil {
+			return nil, fmt.Errorf("p2p: %w", err)
+		}
+		ah.quarantine = q
+	}
+	if ah.monitorCfg != nil {
+		m, err := core.NewHealthMonitor(ah.discovery, ah.probe, *ah.monitorCfg)
+		if err != nil {
+			return nil, fmt.Errorf("p2p: %w", err)
+		}
+		m.OnChange(ah.livenessChanged)
+		ah.monitor = m
+	}
+	ah.proto = protocol.ID(ah.meshed(string(AgentSemanticProtocol)))
+	ah.muxProto = protocol.ID(ah.meshed(string(MuxProtocol)))
+	ah.batchProto = protocol.ID(ah.meshed(string(BatchProtocol)))
+	ah.observeProto = protocol.ID(ah.meshed(string(ObserverProtocol)))
+	ah.discovery.OnConflict(ah.agentIDConflict)
+	if ah.aliases != nil {
+		ah.discovery.SetAliases(ah.aliases)
+		ah.aliases.RegisterDeprecations(ah.deprecations)
+	}
+	if ah.trustStore != nil {
+		tg, err := core.NewTrustGraphFromStore(ah.trustStore)
+		if err != nil {
+			return nil, fmt.Errorf("p2p: load trust store: %w", err)
+		}
+		ah.trust = tg
+	}
+	if ah.discoveryFile != "" {
+		if _, err := ah.discovery.Load(ah.discoveryFile); err != nil {
+			return nil, fmt.Errorf("p2p: %w", err)
+		}
+	}
+	if ah.attestPolicy != nil {
+		ah.attestations = core.NewAttestationRegistry(agent, ah.trust, *ah.attestPolicy)
+	}
+
+	// Reuse the agent's Ed25519 key so a persisted identity (core.LoadAgent)
+	// also yields a stable libp2p peer ID.
+	sk, err := crypto.UnmarshalPrivateKey(agent.MarshalLibp2pKey())
+	if err != nil {
+		return nil, fmt.Errorf("p2p: agent key: %w", err)
+	}
+	var h host.Host
+	if ah.memNet != nil {
+		h, err = ah.memNet.addPeer(sk)
+	} else {
+		lopts := []libp2p.Option{
+			libp2p.Identity(sk),
+			libp2p.ListenAddrStrings(ah.listenAddrs...),
+		}
+		lopts = append(lopts, ah.natOptions()...)
+		lopts = append(lopts, ah.privateOptions()...)
+		h, err = libp2p.New(lopts...)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("p2p: create host: %w", err)
 	}
-
-	ah := &AgentHost{
-		h:         h,
-		agent:     agent,
-		discovery: core.NewDiscoveryRegistry(),
-		trust:     core.NewTrustGraph(),
-		known:     make(map[string]core.AgentProfile),
+	ah.h = h
+	if ah.allow != nil {
+		ah.enforceAllowList()
+	}
+	if ah.autoNAT && ah.memNet == nil {
+		if err := ah.watchReachability(); err != nil {
+			_ = h.Close()
+			return nil, err
+		}
+	}
+	h.SetStreamHandler(ah.proto, ah.handleStream)
+	if ah.hasFeature(core.FeatureSessions) {
+		h.SetStreamHandler(ah.muxProto, ah.handleMuxStream)
+	}
+	h.SetStreamHandler(ah.batchProto, ah.handleBatchStream)
+	if len(ah.observers) > 0 {
+		h.SetStreamHandler(ah.observeProto, ah.handleObserveStream)
+	}
+	ah.watchConnections()
+	if ah.dhtEnabled {
+		if err := ah.startDHT(ctx); err != nil {
+			_ = h.Close()
+			return nil, err
+		}
+	}
+	if ah.gossipEnabled {
+		if err := ah.startGossip(ctx); err != nil {
+			_ = ah.Close()
+			return nil, err
+		}
+	}
+	ah.discovery.StartEvictionLoop(evictionInterval, ah.done)
+	if ah.monitor != nil {
+		ah.monitor.Start(ah.done)
+	}
+	if ah.discoveryFile != "" {
+		interval := ah.discoveryEvery
+		if interval <= 0 {
+			interval = defaultSnapshotInterval
+		}
+		ah.discovery.StartSnapshotLoop(ah.discoveryFile, interval, ah.done, func(err error) {
+			ah.log.Log(context.Background(), slog.LevelWarn, "discovery snapshot failed", "error", err)
+		})
+	}
+	if ah.depSummary != nil {
+		ah.deprecations.StartSummaryLoop(ah.depInterval, ah.done, ah.depSummary)
+	}
+	if ah.revocationURL != "" {
+		go ah.revocationFeedLoop()
+	}
+	if ah.limiter != nil {
+		go ah.pruneRateLoop()
+	}
+	if ah.janitor != nil {
+		if p, ok := ah.memory.(core.Prunable); ok {
+			ah.janitor.Register("memory", p, ah.retention)
+			ah.memory = core.GuardMemoryStore(ah.memory, ah.janitor, "memory")
+		}
+		ah.janitor.Start(ah.janitorInterval, ah.done)
+	}
+	if ah.metrics != nil {
+		ah.watchMetrics()
 	}
-	h.SetStreamHandler(AgentSemanticProtocol, ah.handleStream)
+	if ah.peerCfg != nil {
+		ah.peers = NewPeerManager(ah, *ah.peerCfg)
+	}
+	ah.started = time.Now()
 	return ah, nil
 }
 
-// Close shuts down the libp2p host.
-func (ah *AgentHost) Close() error { return ah.h.Close() }
+// Close stops background loops and shuts down the libp2p host.
+func (ah *AgentHost) Close() error {
+	var err error
+	ah.closeOnce.Do(func() {
+		close(ah.done)
+		if ah.discoveryFile != "" {
+			err = ah.discovery.Save(ah.discoveryFile)
+		}
+	})
+	ah.closeMDNS()
+	if ah.dht != nil {
+		_ = ah.dht.Close()
+	}
+	if cerr := ah.h.Close(); cerr != nil {
+		return cerr
+	}
+	if err != nil {
+		return fmt.Errorf("p2p: %w", err)
+	}
+	return nil
+}
 
 // PeerID returns the underlying libp2p peer.ID.
 func (ah *AgentHost) PeerID() peer.ID { return ah.h.ID() }
@@ -82,17 +651,92 @@ func (ah *AgentHost) AddrInfo() peer.AddrInfo {
 	return peer.AddrInfo{ID: ah.h.ID(), Addrs: ah.h.Addrs()}
 }
 
-// Connect establishes a libp2p connection to a peer.
+// Connect establishes a libp2p connection to a peer.  Failures wrap
+// ErrPeerUnreachable.
 func (ah *AgentHost) Connect(ctx context.Context, info peer.AddrInfo) error {
-	return ah.h.Connect(ctx, info)
+	if err := ah.h.Connect(ctx, info); err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrPeerUnreachable, info.ID, err)
+	}
+	return nil
+}
+
+// Agent returns the local agent.
+func (ah *AgentHost) Agent() *core.Agent { return ah.agent }
+
+// KnownPeers returns the profiles of the peers this host has completed a
+// handshake with, keyed by peer ID.
+func (ah *AgentHost) KnownPeers() map[peer.ID]core.AgentProfile {
+	ah.mu.RLock()
+	defer ah.mu.RUnlock()
+	out := make(map[peer.ID]core.AgentProfile, len(ah.known))
+	for pid, profile := range ah.known {
+		id, err := peer.Decode(pid)
+		if err != nil {
+			continue
+		}
+		out[id] = profile
+	}
+	return out
 }
 
 // Discovery returns the agent's local DiscoveryRegistry.
 func (ah *AgentHost) Discovery() *core.DiscoveryRegistry { return ah.discovery }
 
+// Selection returns the policy used to choose among equivalent responders,
+// by BroadcastIntent and by orchestrators on this host.
+func (ah *AgentHost) Selection() *core.SelectionPolicy { return ah.selection }
+
 // Trust returns the agent's TrustGraph.
 func (ah *AgentHost) Trust() *core.TrustGraph { return ah.trust }
 
+// Memory returns the host's MemoryStore, or nil if none was configured.
+func (ah *AgentHost) Memory() core.MemoryStore { return ah.memory }
+
+// Janitor returns the host's retention janitor, or nil without
+// WithRetention.
+func (ah *AgentHost) Janitor() *core.Janitor { return ah.janitor }
+
+// Deprecations returns the tracker of deprecated behaviour observed from
+// peers.  Use it to register legacy capabilities or an event callback.
+func (ah *AgentHost) Deprecations() *core.DeprecationTracker { return ah.deprecations }
+
+// ClockSkew returns the per-peer clock offsets estimated during handshakes,
+// keyed by peer ID.
+func (ah *AgentHost) ClockSkew() *core.ClockSkewTable { return ah.clock }
+
+// peerDID returns the DID pid presented in its handshake.
+func (ah *AgentHost) peerDID(pid peer.ID) (string, bool) {
+	ah.mu.RLock()
+	defer ah.mu.RUnlock()
+	p, ok := ah.known[pid.String()]
+	return p.DID, ok
+}
+
+// intentSender reports what the host established about the sender of an
+// intent from `from`.  admitIntent has already checked the signature of an
+// intent that carries one and the handshake DID, unless the policy is
+// VerifyOff.
+func (ah *AgentHost) intentSender(from peer.ID, intent *core.IntentMessage) core.Sender {
+	did, known := ah.peerDID(from)
+	if !known {
+		return core.Sender{}
+	}
+	return core.Sender{
+		DID:      did,
+		Verified: did == intent.DID && len(intent.Signature) > 0 && ah.sigPolicy != VerifyOff,
+	}
+}
+
+// memoryDID returns the DID an intent from `from` is remembered under: the
+// handshake DID, or for a relayed intent the originator its envelope
+// verified.  It reports false for peers that never handshaked.
+func (ah *AgentHost) memoryDID(from peer.ID, intent *core.IntentMessage, e *pendingEntry) (string, bool) {
+	if e.relay != nil {
+		return intent.DID, true
+	}
+	return ah.peerDID(from)
+}
+
 // OnHandshake registers the callback for incoming handshakes.
 func (ah *AgentHost) OnHandshake(fn HandshakeCallback) {
 	ah.mu.Lock()
@@ -107,12 +751,40 @@ func (ah *AgentHost) OnIntent(fn IntentCallback) {
 	ah.onIntent = fn
 }
 
+// OnIntentContext registers a context-aware intent callback.  It takes
+// precedence over OnIntent when both are set.
+func (ah *AgentHost) OnIntentContext(fn IntentContextCallback) {
+	ah.mu.Lock()
+	defer ah.mu.Unlock()
+	ah.onIntentCtx = fn
+}
+
+// OnWorkflow registers the callback for incoming workflow step messages.
+func (ah *AgentHost) OnWorkflow(fn WorkflowCallback) {
+	ah.mu.Lock()
+	defer ah.mu.Unlock()
+	ah.onWorkflow = fn
+}
+
 // ------------------------------------------------------------------ outgoing messages
 
 // Handshake initiates a Agent Semantic Protocol handshake with peerID.
 // Returns the peer's HandshakeMessage on success.
 func (ah *AgentHost) Handshake(ctx context.Context, peerID peer.ID) (*core.HandshakeMessage, error) {
-	stream, err := ah.h.NewStream(ctx, peerID, AgentSemanticProtocol)
+	start := time.Now()
+	ctx, span := ah.startSpan(ctx, "asp.handshake", peerID)
+	resp, err := ah.handshake(ctx, peerID)
+	if resp != nil {
+		span.SetAttributes(attribute.String("peer.agent_id", resp.AgentID))
+	}
+	endSpan(span, nil, err)
+	ah.logHandshake(ctx, peerID, start, err)
+	return resp, err
+}
+
+func (ah *AgentHost) handshake(ctx context.Context, peerID peer.ID) (*core.HandshakeMessage, error) {
+	start := time.Now()
+	stream, err := ah.newStream(ctx, peerID, ah.proto)
 	if err != nil {
 		return nil, fmt.Errorf("p2p handshake: open stream: %w", err)
 	}
@@ -123,124 +795,392 @@ func (ah *AgentHost) Handshake(ctx context.Context, peerID peer.ID) (*core.Hands
 	if err != nil {
 		return nil, err
 	}
-	if err = writeMsg(stream, ours); err != nil {
+	ours.Codecs = ah.codecs
+	ours.Mesh = ah.mesh
+	ours.Versions = ah.offeredVersions()
+	ours.Version = ours.Versions[0]
+	ours.Features = core.FeatureNames(ah.features)
+	ours.MaxResultSize = ah.maxResult
+	if err = writeMsg(stream, core.ProtobufCodec, ours); err != nil {
+		ah.streamError("write", err)
 		return nil, fmt.Errorf("p2p handshake: send: %w", err)
 	}
 
 	// Read peer's response.
-	msgType, data, err := readMsg(stream)
+	msgType, data, err := ah.readMsg(stream)
 	if err != nil {
+		ah.streamError("read", err)
 		return nil, fmt.Errorf("p2p handshake: recv: %w", err)
 	}
+	receivedAt := time.Now().UnixNano()
 	if msgType != core.MsgHandshake {
 		return nil, fmt.Errorf("p2p handshake: expected MsgHandshake, got 0x%02x", msgType)
 	}
-	resp, err := core.DecodeHandshakeMessage(data)
+	resp, err := ah.limits.DecodeHandshakeMessage(data)
 	if err != nil {
 		return nil, fmt.Errorf("p2p handshake: decode response: %w", err)
 	}
+	if ah.revocations.Check(resp.DID, core.RevokedAtHandshake) {
+		return nil, fmt.Errorf("p2p handshake: %s is revoked", resp.DID)
+	}
+	if r, retired := ah.rotations.Retired(resp.DID); retired {
+		return nil, fmt.Errorf("p2p handshake: %s was rotated to %s", resp.DID, r.NewDID)
+	}
+	if err = core.CheckMesh(ah.mesh, resp); err != nil {
+		return nil, fmt.Errorf("p2p handshake: %w", err)
+	}
+	version, err := core.AcceptedVersion(resp)
+	if err != nil {
+		return nil, fmt.Errorf("p2p handshake: %w", err)
+	}
+	if !ah.checkFrames(peerID, resp.DID, version) {
+		return nil, fmt.Errorf("p2p handshake: %s: %w", peerID, ErrFrameV1Refused)
+	}
 
-	// Verify the peer signed our challenge.
-	if len(resp.ChallengeResponse) > 0 {
-		if err := core.FinishHandshake(ours.Challenge, resp); err != nil {
-			return nil, err
-		}
+	// Verify the peer signed our challenge.  Without this the profile
+	// cached below would bind the DID to a key the peer chose.
+	if err = core.FinishHandshake(ours.Challenge, resp); err != nil {
+		return nil, fmt.Errorf("p2p handshake: %w", err)
+	}
+
+	ah.deprecations.ObserveHandshake(resp, true)
+	ah.setPeerVersion(peerID, version)
+	ah.setPeerCodec(peerID, ah.acceptedCodec(resp, version))
+	ah.setPeerFeatures(peerID, resp)
+	ah.setPeerMaxResult(peerID, resp)
+	// Only now has the peer proved its key; its peer ID, which the
+	// transport authenticates, keys the offset.
+	if offset, _, ok := core.HandshakeClockOffset(resp, receivedAt); ok {
+		ah.clock.Record(peerID.String(), offset)
 	}
 
 	// Cache the peer's profile for later lookups.
-	ah.mu.Lock()
-	ah.known[peerID.String()] = core.AgentProfile{
+	kx, _ := core.PeerKeyAgreement(resp)
+	profile := core.AgentProfile{
 		AgentID:      resp.AgentID,
 		DID:          resp.DID,
 		Capabilities: append([]string(nil), resp.Capabilities...),
 		PublicKey:    append([]byte(nil), resp.PublicKey...),
+		Manifest:     resp.Manifest,
+		Specs:        resp.Specs,
+		KeyAgreement: kx,
+		Credentials:  resp.Credentials,
 	}
+	ah.mu.Lock()
+	ah.known[peerID.String()] = profile
 	ah.mu.Unlock()
-	ah.discovery.Announce(ah.known[peerID.String()], 0)
+	ah.discovery.Announce(profile, 0)
+	ah.auditMsg(resp, resp.DID, "accepted")
+	ah.handshakeCompleted(peerID, profile)
+	ah.observeHandshake(start)
 
 	return resp, nil
 }
 
+// outgoing returns the intent that goes to peerID in place of intent: a
+// copy with the trace context of ctx, the stream mark if streamed and, for
+// a peer that receives quantized vectors, its vector rounded to what the
+// peer will read.  The signature covers all of them, so the host signs its
+// own intents again.  Intents signed by other agents get only the stream
+// mark, and intents whose signature no longer holds are not signed again.
+func (ah *AgentHost) outgoing(ctx context.Context, peerID peer.ID, intent *core.IntentMessage, streamed bool) (*core.IntentMessage, error) {
+	signed := len(intent.Signature) > 0
+	resign := signed && intent.DID == ah.agent.DID.String() && core.VerifyIntentSignature(intent, ah.agent.PublicKey())
+	out := intent
+	if resign || !signed {
+		out = traced(ctx, intent)
+		if q := ah.peerQuantization(peerID); q != core.QuantizeNone && len(out.IntentVector) > 0 {
+			cp := *out
+			cp.IntentVector = core.QuantizeVector(out.IntentVector, q).Floats()
+			out = &cp
+		}
+	}
+	if streamed {
+		cp := *out
+		cp.Metadata = make(map[string]string, len(out.Metadata)+1)
+		maps.Copy(cp.Metadata, out.Metadata)
+		cp.Metadata[core.StreamMetadataKey] = "true"
+		out = &cp
+	}
+	if resign && out != intent {
+		if err := core.SignIntent(ah.agent, out); err != nil {
+			return nil, fmt.Errorf("p2p intent: %w", err)
+		}
+	}
+	return out, nil
+}
+
 // SendIntent sends an IntentMessage to peerID and waits for a NegotiationResponse.
+// With WithMultiplexing the request shares the peer's session stream.  If
+// the peer disconnects meanwhile, the error wraps ErrPeerDisconnected.
+// The host signs its own intents again on the way; callers that change an
+// intent after creating it sign it first (see core.SignIntent).
 func (ah *AgentHost) SendIntent(
 	ctx context.Context,
 	peerID peer.ID,
 	intent *core.IntentMessage,
 ) (*core.NegotiationResponse, error) {
-	stream, err := ah.h.NewStream(ctx, peerID, AgentSemanticProtocol)
+	start := time.Now()
+	ctx, span := ah.startSpan(ctx, "asp.send_intent", peerID)
+	intentAttributes(span, intent)
+	intent, err := ah.outgoing(ctx, peerID, intent, false)
+	if err != nil {
+		endSpan(span, nil, err)
+		return nil, err
+	}
+	if ah.sealPayloads {
+		sealed, err := ah.sealIntent(peerID, intent)
+		if err != nil {
+			endSpan(span, nil, err)
+			return nil, err
+		}
+		intent = sealed
+	}
+	ctx, release := ah.trackCall(ctx, peerID)
+	defer release()
+	resp, err := ah.sendIntent(ctx, peerID, intent)
+	ah.sendError(peerID, err)
+	if err != nil && disconnected(ctx) {
+		err = fmt.Errorf("p2p intent: %s: %w", peerID, ErrPeerDisconnected)
+		resp = nil
+	}
+	ah.observeIntentSent(start, resp, err)
+	endSpan(span, resp, err)
+	ah.logIntent(ctx, "intent sent", peerID, intent, resp, start, err)
+	if resp != nil {
+		ah.auditMsg(resp, resp.DID, core.Decision(resp, err))
+	}
+	return resp, err
+}
+
+func (ah *AgentHost) sendIntent(
+	ctx context.Context,
+	peerID peer.ID,
+	intent *core.IntentMessage,
+) (*core.NegotiationResponse, error) {
+	if err := ah.checkQuarantine(peerID); err != nil {
+		return nil, fmt.Errorf("p2p intent: %w", err)
+	}
+	if ah.muxEnabled && ah.peerAllows(peerID, core.FeatureMultiplexing) && ah.SharedFeature(peerID, core.FeatureSessions) {
+		if resp, ok, err := ah.sendIntentMux(ctx, peerID, intent); ok {
+			return resp, err
+		}
+	}
+	stream, err := ah.newStream(ctx, peerID, ah.proto)
 	if err != nil {
 		return nil, fmt.Errorf("p2p intent: open stream: %w", err)
 	}
 	defer stream.Close()
+	// Unblock the read below at once if the peer drops or ctx ends.
+	stop := context.AfterFunc(ctx, func() { _ = stream.Reset() })
+	defer stop()
+	resp, err := ah.exchangeIntent(stream, peerID, intent)
+	if err != nil && ctx.Err() != nil {
+		return nil, fmt.Errorf("p2p intent: %w", context.Cause(ctx))
+	}
+	return resp, err
+}
 
-	if err = writeMsg(stream, intent); err != nil {
+// exchangeIntent writes intent to an open stream and reads back the verified
+// NegotiationResponse, applying its trust delta.
+func (ah *AgentHost) exchangeIntent(
+	stream network.Stream,
+	peerID peer.ID,
+	intent *core.IntentMessage,
+) (*core.NegotiationResponse, error) {
+	sent := time.Now()
+	if err := ah.writePeerMsg(stream, peerID, intent); err != nil {
+		ah.streamError("write", err)
 		return nil, fmt.Errorf("p2p intent: send: %w", err)
 	}
 
-	msgType, data, err := readMsg(stream)
+	msgType, data, err := ah.readMsg(stream)
 	if err != nil {
+		ah.streamError("read", err)
 		return nil, fmt.Errorf("p2p intent: recv: %w", err)
 	}
+	return ah.acceptResponse(peerID, intent, sent, msgType, data)
+}
+
+// acceptResponse decodes the reply to intent, sent at sent, received from
+// peerID and hands it to checkedResponse.
+func (ah *AgentHost) acceptResponse(
+	peerID peer.ID,
+	intent *core.IntentMessage,
+	sent time.Time,
+	msgType core.MessageType,
+	data []byte,
+) (*core.NegotiationResponse, error) {
 	if msgType != core.MsgNegotiation {
 		return nil, fmt.Errorf("p2p intent: expected MsgNegotiation, got 0x%02x", msgType)
 	}
-	resp, err := core.DecodeNegotiationResponse(data)
+	resp, err := ah.limits.DecodeNegotiationResponse(data)
 	if err != nil {
+		ah.peerError(peerID, core.PeerErrorDecode)
 		return nil, fmt.Errorf("p2p intent: decode response: %w", err)
 	}
+	return ah.checkedResponse(peerID, intent, sent, resp)
+}
 
-	// Verify response signature if we know the peer's public key.
+// checkedResponse verifies resp, the reply to intent, sent at sent,
+// received from peerID, then applies the trust delta the host's policy
+// gives it and records it in memory.
+func (ah *AgentHost) checkedResponse(
+	peerID peer.ID,
+	intent *core.IntentMessage,
+	sent time.Time,
+	resp *core.NegotiationResponse,
+) (*core.NegotiationResponse, error) {
+	ah.deprecations.ObserveResponse(resp)
+	if err := ah.checkResult(resp); err != nil {
+		return nil, err
+	}
+	if ah.revocations.Check(resp.DID, core.RevokedAtIntent) {
+		return nil, fmt.Errorf("p2p intent: %s is revoked", resp.DID)
+	}
+
+	// Verify the response signature according to the host's policy.
 	ah.mu.RLock()
 	profile, known := ah.known[peerID.String()]
 	ah.mu.RUnlock()
-	if known && len(resp.Signature) > 0 && !core.VerifyResponseSignature(resp, profile.PublicKey) {
-		return nil, fmt.Errorf("p2p intent: invalid response signature from %s", peerID)
+	if err := ah.checkResponse(profile, known, resp); err != nil {
+		return nil, fmt.Errorf("p2p intent: %w", err)
 	}
 
-	// Update trust graph.
-	ah.trust.Apply(ah.agent.DID.String(), resp.DID, resp.TrustDelta)
+	// Update trust graph and memory.  A persistence failure must not fail
+	// the exchange.
+	ah.applyTrust(resp.DID, ah.responseTrust(intent, resp, sent))
+	// Only a DID the handshake or a relayed signature vouches for gets a
+	// memory of its own.
+	if ah.memory != nil && (known || len(resp.RelayPath) > 0) {
+		_ = ah.memory.Append(resp.DID, core.MemoryEntryFor(intent, resp))
+	}
 	return resp, nil
 }
 
-// AnnounceCapabilities broadcasts this agent's capabilities to all connected peers.
+// SendWorkflow delivers one workflow step to peerID.  Workflow messages are
+// one-way; results travel back as further WorkflowMessages.
+func (ah *AgentHost) SendWorkflow(ctx context.Context, peerID peer.ID, msg *core.WorkflowMessage) error {
+	if err := ah.sendOneWay(ctx, peerID, msg); err != nil {
+		return fmt.Errorf("p2p workflow: %w", err)
+	}
+	return nil
+}
+
+// VerifyPeerManifest runs every example intent from the manifest peerID sent
+// during its handshake and checks each response against the promised shape.
+// Call it once after Handshake, before routing real traffic to the peer.
+func (ah *AgentHost) VerifyPeerManifest(ctx context.Context, peerID peer.ID) error {
+	ah.mu.RLock()
+	profile, known := ah.known[peerID.String()]
+	ah.mu.RUnlock()
+	if !known {
+		return fmt.Errorf("p2p verify: no handshake with %s", peerID)
+	}
+	return core.VerifyManifest(ah.agent, profile.Manifest, func(intent *core.IntentMessage) (*core.NegotiationResponse, error) {
+		return ah.SendIntent(ctx, peerID, intent)
+	})
+}
+
+// AnnounceCapabilities broadcasts this agent's capabilities to all connected
+// peers.  When GossipSub is enabled the announcement is published on
+// CapabilityTopic instead of opening one stream per peer.
 func (ah *AgentHost) AnnounceCapabilities(ctx context.Context) {
-	ann := core.BuildAnnouncement(ah.agent, 300) // 5-minute TTL
+	if ah.capTopic != nil {
+		_ = ah.PublishCapabilities(ctx)
+		return
+	}
+	ann := ah.announcement()
 	for _, p := range ah.h.Network().Peers() {
-		go func(pid peer.ID) {
-			   stream, err := ah.h.NewStream(ctx, pid, AgentSemanticProtocol)
-			if err != nil {
-				return
-			}
-			defer stream.Close()
-			_ = writeMsg(stream, ann)
-		}(p)
+		go func(pid peer.ID) { _ = ah.sendOneWay(ctx, pid, ann) }(p)
 	}
 }
 
+// defaultHandler answers intents that no registered callback answered.
+func (ah *AgentHost) defaultHandler() core.NegotiationHandler {
+	return core.DefaultNegotiationHandlerWithOptions(ah.agent, core.NegotiationOptions{
+		Aliases:      ah.aliases,
+		Catalog:      ah.catalog,
+		TrustPolicy:  &ah.trustPolicies,
+		ServiceTimes: ah.service,
+	})
+}
+
 // ------------------------------------------------------------------ incoming stream handler
 
 func (ah *AgentHost) handleStream(s network.Stream) {
 	defer s.Close()
 	_ = s.SetDeadline(time.Now().Add(30 * time.Second))
 
-	msgType, data, err := readMsg(s)
+	msgType, data, err := ah.readMsg(s)
 	if err != nil {
 		return
 	}
+	if !ah.inspect(s.Conn().RemotePeer(), SourceStream, msgType, data) {
+		return
+	}
 
 	switch msgType {
 	case core.MsgHandshake:
 		ah.handleIncomingHandshake(s, data)
 	case core.MsgIntent:
 		ah.handleIncomingIntent(s, data)
+	case core.MsgWorkflow:
+		ah.handleIncomingWorkflow(s, data)
 	case core.MsgCapability:
-		ah.handleIncomingCapability(data)
+		ah.handleIncomingCapability(s, data)
+	case core.MsgAlert:
+		ah.handleIncomingAlert(s, data)
+	case core.MsgRevocation:
+		ah.handleIncomingRevocation(s, data)
+	case core.MsgKeyRotation:
+		ah.handleIncomingKeyRotation(s, data)
+	case core.MsgAttestation:
+		ah.handleIncomingAttestation(s, data)
+	case core.MsgForward:
+		ah.handleIncomingForward(s, data)
+	case core.MsgIntentBatch:
+		ah.handleIncomingIntentBatch(s, data)
+	case core.MsgPing:
+		ah.handleIncomingPing(s, data)
+	case core.MsgWorkflowResult:
+		ah.handleIncomingWorkflowResult(s, data)
 	}
 }
 
 func (ah *AgentHost) handleIncomingHandshake(s network.Stream, data []byte) {
-	incoming, err := core.DecodeHandshakeMessage(data)
+	receivedAt := time.Now().UnixNano()
+	incoming, err := ah.limits.DecodeHandshakeMessage(data)
+	if err != nil {
+		ah.peerError(s.Conn().RemotePeer(), core.PeerErrorDecode)
+		return
+	}
+	if ah.revocations.Check(incoming.DID, core.RevokedAtHandshake) {
+		return
+	}
+	if _, retired := ah.rotations.Retired(incoming.DID); retired {
+		return
+	}
+
+	if core.CheckMesh(ah.mesh, incoming) != nil {
+		_ = writeMsg(s, core.ProtobufCodec, core.MeshMismatchReply(ah.agent, ah.mesh))
+		return
+	}
+
+	ah.deprecations.ObserveHandshake(incoming, false)
+	version, err := ah.negotiateVersion(core.OfferedVersions(incoming))
 	if err != nil {
+		_ = writeMsg(s, core.ProtobufCodec, core.VersionMismatchReply(ah.agent))
+		return
+	}
+	if !ah.checkFrames(s.Conn().RemotePeer(), incoming.DID, version) {
+		_ = writeMsg(s, core.ProtobufCodec, ah.v1RefusedReply())
+		return
+	}
+
+	// Custom callbacks skip RespondHandshake, so check the DID/key binding
+	// and credentials here, before the profile below is cached.
+	if err := core.CheckPeerIdentity(incoming); err != nil {
 		return
 	}
 
@@ -260,89 +1200,365 @@ func (ah *AgentHost) handleIncomingHandshake(s network.Stream, data []byte) {
 			return
 		}
 	}
+	resp.Version = version.Version
+	resp.Mesh = ah.mesh
+	resp.Features = core.FeatureNames(ah.features)
+	resp.MaxResultSize = ah.maxResult
+	codec := version.NegotiateCodec(incoming.Codecs)
+	if len(incoming.Codecs) > 0 {
+		resp.Codecs = []string{codec.Name()}
+	}
 
-	_ = writeMsg(s, resp)
+	// One-way estimate: biased by the network delay, but the initiator never
+	// sends a third message we could use for a full exchange.  The
+	// initiator proves no key to us, so the offset is keyed by its peer ID:
+	// whatever DID it claims, it can only skew its own.
+	from := s.Conn().RemotePeer()
+	if incoming.Timestamp != 0 {
+		ah.clock.Record(from.String(), time.Duration(incoming.Timestamp-receivedAt))
+	}
 
-	// Cache peer profile.
-	ah.mu.Lock()
-	ah.known[s.Conn().RemotePeer().String()] = core.AgentProfile{
+	// Cache peer profile before replying, so an intent sent as soon as the
+	// initiator's Handshake returns is checked against the right key.
+	kx, _ := core.PeerKeyAgreement(incoming)
+	profile := core.AgentProfile{
 		AgentID:      incoming.AgentID,
 		DID:          incoming.DID,
 		Capabilities: append([]string(nil), incoming.Capabilities...),
 		PublicKey:    append([]byte(nil), incoming.PublicKey...),
+		Manifest:     incoming.Manifest,
+		Specs:        incoming.Specs,
+		KeyAgreement: kx,
+		Credentials:  incoming.Credentials,
 	}
+	ah.mu.Lock()
+	ah.known[from.String()] = profile
 	ah.mu.Unlock()
-	ah.discovery.Announce(ah.known[s.Conn().RemotePeer().String()], 0)
+	ah.discovery.Announce(profile, 0)
+	ah.setPeerVersion(from, version)
+	ah.setPeerCodec(from, codec)
+	ah.setPeerFeatures(from, incoming)
+	ah.setPeerMaxResult(from, incoming)
+
+	ah.auditMsg(incoming, incoming.DID, "accepted")
+	ah.handshakeCompleted(from, profile)
+
+	// The handshake reply itself stays protobuf: the initiator learns the
+	// codec from it.
+	_ = writeMsg(s, core.ProtobufCodec, resp)
 }
 
 func (ah *AgentHost) handleIncomingIntent(s network.Stream, data []byte) {
-	intent, err := core.DecodeIntentMessage(data)
-	if err != nil {
+	from := s.Conn().RemotePeer()
+	intent, ok := ah.admitIntent(from, data)
+	if !ok {
 		return
 	}
 
-	// Verify intent signature if we know the sender's public key.
+	e := ah.trackIntent(from, intent, IntentQueued)
+	defer ah.finishIntent(e)
+	ah.keepForRelay(e, from, data)
+
+	if intent.Metadata[core.StreamMetadataKey] == "true" && ah.hasFeature(core.FeatureStreaming) {
+		ah.mu.RLock()
+		scb := ah.onStreamIntent
+		ah.mu.RUnlock()
+		if scb != nil {
+			ah.serveStreamedIntent(s, intent, e, scb)
+			return
+		}
+	}
+
+	resp := ah.answerIntent(from, intent, e)
+	if resp == nil {
+		return
+	}
+	_ = ah.writePeerMsg(s, from, resp)
+	ah.applySenderTrust(from, resp.TrustDelta)
+}
+
+// admitIntent decodes an intent from peer `from` and applies the signature
+// policy and timestamp window.  It reports false if the intent is dropped.
+func (ah *AgentHost) admitIntent(from peer.ID, data []byte) (*core.IntentMessage, bool) {
+	intent, err := ah.limits.DecodeIntentMessage(data)
+	if err != nil {
+		ah.peerError(from, core.PeerErrorDecode)
+		return nil, false
+	}
+
+	ah.deprecations.ObserveIntent(intent)
+	if ah.revocations.Check(intent.DID, core.RevokedAtIntent) {
+		return nil, false
+	}
+	if _, retired := ah.rotations.Retired(intent.DID); retired {
+		return nil, false
+	}
+
+	// Verify the intent signature according to the host's policy.
 	ah.mu.RLock()
-	profile, known := ah.known[s.Conn().RemotePeer().String()]
+	profile, known := ah.known[from.String()]
 	ah.mu.RUnlock()
-	if known && len(intent.Signature) > 0 && !core.VerifyIntentSignature(intent, profile.PublicKey) {
-		return
+	if err = ah.checkIntent(profile, known, intent); err != nil {
+		return nil, false
+	}
+	return intent, ah.admitVerified(from, profile.AgentID, intent)
+}
+
+// admitVerified applies the timestamp window, expiry and replay checks to
+// an intent whose signature has been checked, decrypts it and normalises
+// its capabilities.  agentID names the peer `from`.  It reports false if the
+// intent is dropped.
+func (ah *AgentHost) admitVerified(from peer.ID, agentID string, intent *core.IntentMessage) bool {
+	// A peer's clock offset says nothing of the agents it relays for.
+	clock := from.String()
+	if did, _ := ah.peerDID(from); did != intent.DID {
+		clock = ""
+	}
+	if err := ah.clock.CheckTimestamp(clock, intent.Timestamp, ah.maxIntentAge, ah.maxIntentFuture); err != nil {
+		return false
+	}
+	if now := time.Now(); intent.Expired(now) || intent.ExpiresTooLate(now) || ah.replays.Seen(intent) {
+		return false
+	}
+	if core.DecryptIntentPayload(ah.agent, intent) != nil {
+		return false
+	}
+	// Structured IDs hash the plaintext payload and the capabilities as sent.
+	if err := core.CheckIntentID(intent); err != nil {
+		return false
+	}
+	intent.Capabilities = ah.aliases.Normalize(intent.Capabilities)
+	ah.emit(Event{
+		Kind:         EventIntentReceived,
+		PeerID:       from,
+		AgentID:      agentID,
+		DID:          intent.DID,
+		IntentID:     intent.ID,
+		Capabilities: intent.Capabilities,
+	})
+	return true
+}
+
+// answerIntent runs the registered intent callback (or the default handler)
+// and records the exchange in memory.  It returns nil if there is no reply.
+// e is the intent's PendingIntents entry; nil tracks it for this call only.
+// While the host is rejecting (RejectAll, Drain) the handler is skipped.
+func (ah *AgentHost) answerIntent(from peer.ID, intent *core.IntentMessage, e *pendingEntry) *core.NegotiationResponse {
+	return ah.answerWith(from, intent, e, "asp.handle_intent", nil)
+}
+
+// answerWith is answerIntent with handle, if not nil, in place of the
+// registered intent callbacks.  spanName names the tracing span.
+func (ah *AgentHost) answerWith(
+	from peer.ID,
+	intent *core.IntentMessage,
+	e *pendingEntry,
+	spanName string,
+	handle func() *core.NegotiationResponse,
+) *core.NegotiationResponse {
+	if e == nil {
+		e = ah.trackIntent(from, intent, IntentQueued)
+		defer ah.finishIntent(e)
+	}
+	ctx, span := ah.serveSpan(spanName, from, intent)
+	var resp *core.NegotiationResponse
+	start := time.Now()
+	defer func() {
+		endSpan(span, resp, nil)
+		ah.recordUsage(intent, resp, start)
+		if resp != nil {
+			ah.logIntent(ctx, "intent answered", from, intent, resp, start, nil)
+			ah.auditMsg(intent, intent.DID, core.Decision(resp, nil))
+		}
+	}()
+
+	if resp = ah.throttle(from, intent); resp != nil {
+		ah.answered(from, intent, resp)
+		return resp
+	}
+	if ah.observers[intent.DID] {
+		resp = ah.rejection(intent, core.ReasonObserverOnly)
+		ah.answered(from, intent, resp)
+		return resp
+	}
+	if resp = ah.answerHealth(intent); resp != nil {
+		return resp
+	}
+	if reason, reject := ah.startIntent(e); reject {
+		resp = ah.rejection(intent, reason)
+		ah.answered(from, intent, resp)
+		return resp
+	}
+	if resp = ah.federate(ctx, from, intent); resp != nil {
+		ah.answered(from, intent, resp)
+		return resp
+	}
+	if resp = ah.relay(ctx, from, intent, e); resp != nil {
+		ah.answered(from, intent, resp)
+		return resp
+	}
+	if resp = ah.checkCredentials(from, intent); resp != nil {
+		ah.answered(from, intent, resp)
+		return resp
 	}
 
 	ah.mu.RLock()
 	cb := ah.onIntent
+	ccb := ah.onIntentCtx
 	ah.mu.RUnlock()
 
-	var resp *core.NegotiationResponse
-	if cb != nil {
-		resp = cb(s.Conn().RemotePeer(), intent)
+	switch {
+	case handle != nil:
+		resp = handle()
+	case ccb != nil:
+		ctx = core.ContextWithSender(ctx, ah.intentSender(from, intent))
+		if did, ok := ah.memoryDID(from, intent, e); ok && ah.memory != nil {
+			ctx = core.ContextWithPeerMemory(ctx, core.PeerMemory{DID: did, Store: ah.memory})
+		}
+		resp = ccb(ctx, from, intent)
+	case cb != nil:
+		resp = cb(from, intent)
 	}
 	if resp == nil {
-		h := core.DefaultNegotiationHandler(ah.agent)
-		resp, _ = h(intent)
+		resp, _ = ah.defaultHandler()(intent)
 	}
 	if resp == nil {
+		return nil
+	}
+	resp = ah.fitResult(from, intent, resp)
+	resp = ah.fitBudget(intent, resp)
+
+	// Remember before replying, so the peer's next intent sees this one.
+	if did, ok := ah.memoryDID(from, intent, e); ok && ah.memory != nil {
+		_ = ah.memory.Append(did, core.MemoryEntryFor(intent, resp))
+	}
+	if resp.Accepted {
+		ah.observeIntentAccepted()
+		if ah.receipts != nil {
+			ah.issueReceipt(intent, resp)
+		}
+	}
+	ah.answered(from, intent, resp)
+	return resp
+}
+
+// issueReceipt prices and logs a receipt for an accepted intent.  Failing
+// to log must not fail the negotiation.
+func (ah *AgentHost) issueReceipt(intent *core.IntentMessage, resp *core.NegotiationResponse) {
+	var cost core.Cost
+	if ah.price != nil {
+		c, ok := ah.price(intent, resp)
+		if !ok {
+			return
+		}
+		cost = c
+	}
+	if r, err := core.IssueReceipt(ah.agent, intent, cost); err == nil {
+		_ = ah.receipts.Append(r)
+	}
+}
+
+func (ah *AgentHost) handleIncomingWorkflow(s network.Stream, data []byte) {
+	msg, err := ah.limits.DecodeWorkflowMessage(data)
+	if err != nil {
 		return
 	}
 
-	_ = writeMsg(s, resp)
-	ah.trust.Apply(ah.agent.DID.String(), intent.DID, resp.TrustDelta)
+	// A handshaked peer may only send steps under its own DID.
+	ah.mu.RLock()
+	profile, known := ah.known[s.Conn().RemotePeer().String()]
+	cb := ah.onWorkflow
+	ah.mu.RUnlock()
+	if known && msg.DID != "" && profile.DID != msg.DID {
+		return
+	}
+	if known && ah.observers[profile.DID] {
+		return
+	}
+	if cb != nil {
+		cb(s.Conn().RemotePeer(), msg)
+	}
 }
 
-func (ah *AgentHost) handleIncomingCapability(data []byte) {
-	ann, err := core.DecodeCapabilityAnnouncement(data)
+func (ah *AgentHost) handleIncomingCapability(s network.Stream, data []byte) {
+	ann, err := ah.limits.DecodeCapabilityAnnouncement(data)
 	if err != nil {
 		return
 	}
-	ah.discovery.AnnounceFromMessage(ann)
+	ah.acceptAnnouncement(s.Conn().RemotePeer(), ann)
 }
 
-// ------------------------------------------------------------------ wire I/O
+// acceptAnnouncement validates an announcement received from peer `from`
+// (directly or via gossip) and registers it in the DiscoveryRegistry.
+func (ah *AgentHost) acceptAnnouncement(from peer.ID, ann *core.CapabilityAnnouncement) {
+	// The DID's own key must sign it, whoever relayed it.
+	if core.VerifyAnnouncement(ann) != nil {
+		return
+	}
+	if ah.revocations.Check(ann.DID, core.RevokedAtDiscovery) {
+		return
+	}
+	if _, retired := ah.rotations.Retired(ann.DID); retired {
+		return
+	}
+	if _, ok := ah.quarantined(from); ok {
+		return
+	}
+	ah.deprecations.ObserveCapabilities(ann.DID, ann.Capabilities)
 
-// writeMsg serialises msg and writes a framed packet to w.
-func writeMsg(w io.Writer, msg core.Encoder) error {
-	payload, err := msg.Encode()
-	if err != nil {
-		return err
+	// If we have handshaked with this peer, the announcement must come from
+	// the identity we verified; otherwise a peer could re-announce as another DID.
+	ah.mu.RLock()
+	profile, known := ah.known[from.String()]
+	ah.mu.RUnlock()
+	if known && profile.DID != ann.DID {
+		return
 	}
-	frame := core.Frame(msg.MsgType(), payload)
-	_, err = w.Write(frame)
-	return err
+	if core.VerifyPeerCredentials(ann.DID, ann.Credentials) != nil {
+		return
+	}
+
+	// Expired entries are purged by the host's eviction loop.
+	ah.discovery.AnnounceFromMessage(ann)
 }
 
-// readMsg reads one framed Agent Semantic Protocol message from r.
-func readMsg(r io.Reader) (core.MessageType, []byte, error) {
-	var hdr [4]byte
-	if _, err := io.ReadFull(r, hdr[:]); err != nil {
-		return 0, nil, fmt.Errorf("readMsg header: %w", err)
+// announcement builds this agent's signed CapabilityAnnouncement with
+// canonical capability names, and the capabilities it proxies if it is a
+// federation gateway.
+func (ah *AgentHost) announcement() *core.CapabilityAnnouncement {
+	ann := core.BuildAnnouncement(ah.agent, announcementTTL)
+	ann.Capabilities = ah.aliases.Normalize(ann.Capabilities)
+	if tag, ok := ah.federationTag(); ok {
+		for _, c := range tag.Capabilities {
+			if !slices.Contains(ann.Capabilities, c) {
+				ann.Capabilities = append(ann.Capabilities, c)
+			}
+		}
+		ann.Provenance = []core.ProvenanceTag{tag}
 	}
-	n := int(binary.BigEndian.Uint32(hdr[:]))
-	if n < 1 || n > 4*1024*1024 { // max 4 MiB
-		return 0, nil, fmt.Errorf("readMsg: invalid length %d", n)
+	_ = core.SignAnnouncement(ah.agent, ann)
+	return ann
+}
+
+// ------------------------------------------------------------------ wire I/O
+
+// writeMsg serialises msg with c and writes a framed packet to w.
+func writeMsg(w io.Writer, c core.Codec, msg core.Encoder) error {
+	return core.WriteFrame(w, c, msg)
+}
+
+// readMsg reads one framed Agent Semantic Protocol message, v1 or v2, from
+// r.  Payloads in other codecs are transcoded within the host's limits, so
+// the returned bytes are always protobuf.
+func (ah *AgentHost) readMsg(r io.Reader) (core.MessageType, []byte, error) {
+	frameType, body, _, err := core.ReadFrame(r)
+	if err != nil {
+		return 0, nil, fmt.Errorf("readMsg: %w", err)
 	}
-	body := make([]byte, n)
-	if _, err := io.ReadFull(r, body); err != nil {
-		return 0, nil, fmt.Errorf("readMsg body: %w", err)
+	msgType, codec := core.SplitFrameType(frameType)
+	payload, err := ah.limits.Transcode(codec, msgType, body)
+	if err != nil {
+		return 0, nil, fmt.Errorf("readMsg: %w", err)
 	}
-	return core.MessageType(body[0]), body[1:], nil
+	return msgType, payload, nil
 }
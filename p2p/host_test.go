@@ -2,6 +2,10 @@ package p2p_test
 
 import (
 	"context"
+	"errors"
+	"math"
+	"path/filepath"
+	"slices"
 	"testing"
 	"time"
 
@@ -58,6 +62,67 @@ func TestHandshake(t *testing.T) {
 	}
 }
 
+// TestHandshakeRequiresChallengeResponse verifies that an initiator refuses a
+// reply that does not sign its challenge, and caches nothing from it.
+func TestHandshakeRequiresChallengeResponse(t *testing.T) {
+	alpha := makeAgent(t, "alpha", []string{"nlp"})
+	beta := makeAgent(t, "beta", []string{"code-gen"})
+
+	hA := makeHost(t, alpha)
+	hB := makeHost(t, beta)
+	hB.OnHandshake(func(_ peer.ID, _ *core.HandshakeMessage) *core.HandshakeMessage {
+		reply, _ := core.StartHandshake(beta) // no ChallengeResponse
+		return reply
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := hA.Connect(ctx, hB.AddrInfo()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	if _, err := hA.Handshake(ctx, hB.PeerID()); !errors.Is(err, core.ErrSignatureInvalid) {
+		t.Fatalf("Handshake: got %v, want ErrSignatureInvalid", err)
+	}
+	if known := hA.KnownPeers(); len(known) != 0 {
+		t.Errorf("initiator cached %+v", known)
+	}
+}
+
+// TestHandshakeChecksBindingWithCallback verifies that a responder with a
+// custom OnHandshake callback still refuses a DID the initiator's key does
+// not match.
+func TestHandshakeChecksBindingWithCallback(t *testing.T) {
+	beta := makeAgent(t, "beta", []string{"code-gen"})
+	mallory := makeAgent(t, "mallory", nil)
+	forged := *mallory
+	forged.DID = beta.DID // claims beta's DID, signs with mallory's key
+
+	victim := makeAgent(t, "victim", nil)
+	hM := makeHost(t, &forged)
+	hV := makeHost(t, victim)
+	hV.OnHandshake(func(_ peer.ID, in *core.HandshakeMessage) *core.HandshakeMessage {
+		reply, _ := core.RespondHandshake(victim, &core.HandshakeMessage{
+			AgentID:   in.AgentID,
+			DID:       mallory.DID.String(),
+			PublicKey: in.PublicKey,
+			Challenge: in.Challenge,
+		})
+		return reply
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := hM.Connect(ctx, hV.AddrInfo()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	if _, err := hM.Handshake(ctx, hV.PeerID()); err == nil {
+		t.Fatal("handshake with a forged DID succeeded")
+	}
+	if known := hV.KnownPeers(); len(known) != 0 {
+		t.Errorf("responder cached %+v", known)
+	}
+}
+
 // TestHandshakeRegistersInDiscovery verifies that a completed handshake registers
 // the remote peer in the local DiscoveryRegistry.
 func TestHandshakeRegistersInDiscovery(t *testing.T) {
@@ -83,6 +148,33 @@ func TestHandshakeRegistersInDiscovery(t *testing.T) {
 	}
 }
 
+// TestClockOffsetKeyedByPeerID verifies that both sides of a handshake
+// record the other's clock offset under its peer ID, not the DID it claims.
+func TestClockOffsetKeyedByPeerID(t *testing.T) {
+	alpha := makeAgent(t, "alpha", nil)
+	beta := makeAgent(t, "beta", nil)
+	hA := makeHost(t, alpha)
+	hB := makeHost(t, beta)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := p2p.DiscoverAndHandshake(ctx, hA, hB.AddrInfo()); err != nil {
+		t.Fatalf("DiscoverAndHandshake: %v", err)
+	}
+	for _, c := range []struct {
+		h    *p2p.AgentHost
+		peer peer.ID
+		did  string
+	}{{hA, hB.PeerID(), beta.DID.String()}, {hB, hA.PeerID(), alpha.DID.String()}} {
+		if _, ok := c.h.ClockSkew().Offset(c.peer.String()); !ok {
+			t.Errorf("no offset recorded for %s", c.peer)
+		}
+		if _, ok := c.h.ClockSkew().Offset(c.did); ok {
+			t.Errorf("offset recorded under the DID %s", c.did)
+		}
+	}
+}
+
 // TestSendIntentAccepted verifies that an intent is accepted when the peer has
 // all required capabilities.
 func TestSendIntentAccepted(t *testing.T) {
@@ -222,3 +314,431 @@ func TestAnnounceCapabilities(t *testing.T) {
 		t.Error("expected alpha to be discoverable by beta after AnnounceCapabilities")
 	}
 }
+
+// TestForgedAnnouncementIgnored verifies that a peer the receiver has not
+// handshaked with cannot announce capabilities under another agent's DID.
+func TestForgedAnnouncementIgnored(t *testing.T) {
+	beta := makeAgent(t, "beta", []string{"code-gen"})
+	mallory := makeAgent(t, "mallory", []string{"payments"})
+	forged := *mallory
+	forged.DID = beta.DID // claims beta's DID, signs with mallory's key
+	gamma := makeAgent(t, "gamma", []string{"nlp"})
+
+	hM := makeHost(t, &forged)
+	hG := makeHost(t, gamma)
+	hV := makeHost(t, makeAgent(t, "victim", nil))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for _, h := range []*p2p.AgentHost{hM, hG} {
+		if err := h.Connect(ctx, hV.AddrInfo()); err != nil {
+			t.Fatalf("Connect: %v", err)
+		}
+	}
+	hM.AnnounceCapabilities(ctx)
+	hG.AnnounceCapabilities(ctx)
+	for len(hV.Discovery().FindByCapability("nlp")) == 0 {
+		select {
+		case <-ctx.Done():
+			t.Fatal("gamma's announcement never registered")
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+	if found := hV.Discovery().FindByCapability("payments"); len(found) != 0 {
+		t.Errorf("forged announcement registered: %+v", found)
+	}
+}
+
+// TestTrustStorePersistsAcrossRestart verifies that a host created with
+// WithTrustStore reloads trust scores written by a previous host instance.
+func TestTrustStorePersistsAcrossRestart(t *testing.T) {
+	alpha := makeAgent(t, "alpha", []string{"nlp"})
+	beta := makeAgent(t, "beta", []string{"summarisation"})
+	path := filepath.Join(t.TempDir(), "trust.json")
+
+	store, err := core.OpenFileTrustStore(path)
+	if err != nil {
+		t.Fatalf("OpenFileTrustStore: %v", err)
+	}
+	hA, err := p2p.NewHost(context.Background(), alpha, p2p.WithTrustStore(store))
+	if err != nil {
+		t.Fatalf("NewHost: %v", err)
+	}
+	hB := makeHost(t, beta)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := hA.Connect(ctx, hB.AddrInfo()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	intent, err := core.CreateIntent(alpha, []float32{0.5}, []string{"summarisation"}, "")
+	if err != nil {
+		t.Fatalf("CreateIntent: %v", err)
+	}
+	if _, err := hA.SendIntent(ctx, hB.PeerID(), intent); err != nil {
+		t.Fatalf("SendIntent: %v", err)
+	}
+	want := hA.Trust().Get(alpha.DID.String(), beta.DID.String())
+	_ = hA.Close()
+
+	reopened, err := core.OpenFileTrustStore(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	hA2, err := p2p.NewHost(context.Background(), alpha, p2p.WithTrustStore(reopened))
+	if err != nil {
+		t.Fatalf("NewHost (restart): %v", err)
+	}
+	t.Cleanup(func() { _ = hA2.Close() })
+
+	if got := hA2.Trust().Get(alpha.DID.String(), beta.DID.String()); got != want || got == 0 {
+		t.Errorf("trust after restart: got %v want %v", got, want)
+	}
+}
+
+// TestRequireSignedRejectsUnknownPeer verifies that a RequireSigned host
+// drops intents from peers that never completed a handshake.
+func TestRequireSignedRejectsUnknownPeer(t *testing.T) {
+	alpha := makeAgent(t, "alpha", []string{"nlp"})
+	beta := makeAgent(t, "beta", []string{"summarisation"})
+
+	hA := makeHost(t, alpha)
+	hB, err := p2p.NewHost(context.Background(), beta, p2p.WithSignaturePolicy(p2p.RequireSigned))
+	if err != nil {
+		t.Fatalf("NewHost: %v", err)
+	}
+	t.Cleanup(func() { _ = hB.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := hA.Connect(ctx, hB.AddrInfo()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+
+	intent, err := core.CreateIntent(alpha, []float32{0.5}, []string{"summarisation"}, "hello")
+	if err != nil {
+		t.Fatalf("CreateIntent: %v", err)
+	}
+	if _, err := hA.SendIntent(ctx, hB.PeerID(), intent); err == nil {
+		t.Fatal("expected intent from unknown peer to be rejected")
+	}
+
+	// After a handshake the same signed intent is accepted.
+	if _, err := hA.Handshake(ctx, hB.PeerID()); err != nil {
+		t.Fatalf("Handshake: %v", err)
+	}
+	resp, err := hA.SendIntent(ctx, hB.PeerID(), intent)
+	if err != nil {
+		t.Fatalf("SendIntent after handshake: %v", err)
+	}
+	if !resp.Accepted {
+		t.Errorf("expected acceptance, got reason %q", resp.Reason)
+	}
+}
+
+// TestMemoryAcrossNegotiations verifies that the responder sees its history
+// with a peer through the handler context.
+func TestMemoryAcrossNegotiations(t *testing.T) {
+	alpha := makeAgent(t, "alpha", []string{"nlp"})
+	beta := makeAgent(t, "beta", []string{"summarisation"})
+
+	hA := makeHost(t, alpha)
+	hB, err := p2p.NewHost(context.Background(), beta, p2p.WithMemoryStore(core.NewInMemoryStore(0)))
+	if err != nil {
+		t.Fatalf("NewHost: %v", err)
+	}
+	t.Cleanup(func() { _ = hB.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := p2p.DiscoverAndHandshake(ctx, hA, hB.AddrInfo()); err != nil {
+		t.Fatalf("DiscoverAndHandshake: %v", err)
+	}
+
+	var seen []int
+	hB.OnIntentContext(func(ctx context.Context, _ peer.ID, msg *core.IntentMessage) *core.NegotiationResponse {
+		mem, ok := core.PeerMemoryFromContext(ctx)
+		if !ok {
+			t.Error("no peer memory in context")
+			return nil
+		}
+		past, _ := mem.Recent(10)
+		seen = append(seen, len(past))
+		return nil
+	})
+
+	for i := 0; i < 2; i++ {
+		intent, err := core.CreateIntent(alpha, []float32{1}, []string{"summarisation"}, "doc")
+		if err != nil {
+			t.Fatalf("CreateIntent: %v", err)
+		}
+		if _, err = hA.SendIntent(ctx, hB.PeerID(), intent); err != nil {
+			t.Fatalf("SendIntent: %v", err)
+		}
+	}
+	if len(seen) != 2 || seen[0] != 0 || seen[1] != 1 {
+		t.Errorf("expected history lengths [0 1], got %v", seen)
+	}
+}
+
+// TestMemoryKeyedByHandshake verifies that the responder remembers only
+// peers whose DID a handshake established.
+func TestMemoryKeyedByHandshake(t *testing.T) {
+	alpha := makeAgent(t, "alpha", []string{"nlp"})
+	beta := makeAgent(t, "beta", []string{"summarisation"})
+	hA := makeHost(t, alpha)
+	store := core.NewInMemoryStore(0)
+	hB, err := p2p.NewHost(context.Background(), beta, p2p.WithMemoryStore(store))
+	if err != nil {
+		t.Fatalf("NewHost: %v", err)
+	}
+	t.Cleanup(func() { _ = hB.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err = hA.Connect(ctx, hB.AddrInfo()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	intent, _ := core.CreateIntent(alpha, nil, []string{"summarisation"}, "doc")
+	if _, err = hA.SendIntent(ctx, hB.PeerID(), intent); err != nil {
+		t.Fatalf("SendIntent: %v", err)
+	}
+	if all, _ := store.AllEntries(); len(all) != 0 {
+		t.Errorf("remembered a peer that never handshaked: %v", all)
+	}
+
+	if _, err = hA.Handshake(ctx, hB.PeerID()); err != nil {
+		t.Fatalf("Handshake: %v", err)
+	}
+	intent, _ = core.CreateIntent(alpha, nil, []string{"summarisation"}, "doc")
+	if _, err = hA.SendIntent(ctx, hB.PeerID(), intent); err != nil {
+		t.Fatalf("SendIntent: %v", err)
+	}
+	if entries, _ := store.Entries(alpha.DID.String()); len(entries) != 1 {
+		t.Errorf("handshaked peer has %d entries, want 1", len(entries))
+	}
+}
+
+// TestIntentSenderInContext verifies that handlers learn the handshake DID
+// and whether the host verified the intent's signature.
+func TestIntentSenderInContext(t *testing.T) {
+	alpha := makeAgent(t, "alpha", []string{"nlp"})
+	beta := makeAgent(t, "beta", []string{"summarisation"})
+	hA := makeHost(t, alpha)
+	hB := makeHost(t, beta)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := p2p.DiscoverAndHandshake(ctx, hA, hB.AddrInfo()); err != nil {
+		t.Fatalf("DiscoverAndHandshake: %v", err)
+	}
+
+	var seen []core.Sender
+	hB.OnIntentContext(func(ctx context.Context, _ peer.ID, msg *core.IntentMessage) *core.NegotiationResponse {
+		seen = append(seen, core.SenderFromContext(ctx))
+		return nil
+	})
+	signed, _ := core.CreateIntent(alpha, nil, []string{"summarisation"}, "doc")
+	unsigned, _ := core.CreateIntent(alpha, nil, []string{"summarisation"}, "doc")
+	unsigned.Signature = nil
+	for _, intent := range []*core.IntentMessage{signed, unsigned} {
+		if _, err := hA.SendIntent(ctx, hB.PeerID(), intent); err != nil {
+			t.Fatalf("SendIntent: %v", err)
+		}
+	}
+	want := []core.Sender{{DID: alpha.DID.String(), Verified: true}, {DID: alpha.DID.String()}}
+	if !slices.Equal(seen, want) {
+		t.Errorf("senders %+v, want %+v", seen, want)
+	}
+}
+
+func TestReplayedIntentDropped(t *testing.T) {
+	alpha := makeAgent(t, "alpha", []string{"nlp"})
+	beta := makeAgent(t, "beta", []string{"summarisation"})
+
+	hA := makeHost(t, alpha)
+	hB, err := p2p.NewHost(context.Background(), beta, p2p.WithReplayWindow(time.Minute))
+	if err != nil {
+		t.Fatalf("NewHost: %v", err)
+	}
+	t.Cleanup(func() { _ = hB.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err = hA.Connect(ctx, hB.AddrInfo()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+
+	intent, _ := core.CreateIntent(alpha, []float32{0.9}, []string{"summarisation"}, "summarise")
+	intent.WithTTL(time.Minute)
+	if _, err = hA.SendIntent(ctx, hB.PeerID(), intent); err != nil {
+		t.Fatalf("SendIntent: %v", err)
+	}
+	short, scancel := context.WithTimeout(ctx, 500*time.Millisecond)
+	defer scancel()
+	if _, err = hA.SendIntent(short, hB.PeerID(), intent); err == nil {
+		t.Error("replayed intent was answered")
+	}
+
+	// The cache would forget an intent valid for longer before it expired.
+	lasting, _ := core.CreateIntent(alpha, []float32{0.9}, []string{"summarisation"}, "summarise")
+	lasting.ExpiresAt = math.MaxInt64
+	_ = core.SignIntent(alpha, lasting)
+	if _, err = hA.SendIntent(short, hB.PeerID(), lasting); err == nil {
+		t.Error("intent expiring past core.MaxIntentLifetime was answered")
+	}
+}
+
+// TestDecodeLimitsPerHost verifies that a host drops messages over its own
+// decode limits while a host with the defaults accepts them.
+func TestDecodeLimitsPerHost(t *testing.T) {
+	alpha := makeAgent(t, "alpha", []string{"nlp"})
+	beta := makeAgent(t, "beta", []string{"nlp"})
+
+	strict := core.DefaultDecodeLimits
+	strict.MaxMapEntries = 1
+	hA := makeHost(t, alpha)
+	hB, err := p2p.NewHost(context.Background(), beta, p2p.WithDecodeLimits(strict))
+	if err != nil {
+		t.Fatalf("NewHost: %v", err)
+	}
+	t.Cleanup(func() { _ = hB.Close() })
+	if _, err = p2p.NewHost(context.Background(), beta, p2p.WithDecodeLimits(core.DecodeLimits{MaxRepeated: -1})); err == nil {
+		t.Error("NewHost accepted a negative limit")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err = p2p.DiscoverAndHandshake(ctx, hA, hB.AddrInfo()); err != nil {
+		t.Fatalf("DiscoverAndHandshake: %v", err)
+	}
+
+	send := func(from *p2p.AgentHost, to peer.ID) error {
+		intent, _ := core.CreateIntent(from.Agent(), nil, []string{"nlp"}, "parse")
+		intent.Metadata = map[string]string{"a": "1", "b": "2"}
+		_ = core.SignIntent(from.Agent(), intent)
+		short, scancel := context.WithTimeout(ctx, time.Second)
+		defer scancel()
+		_, err := from.SendIntent(short, to, intent)
+		return err
+	}
+	if err = send(hA, hB.PeerID()); err == nil {
+		t.Error("intent over the strict host's limits was answered")
+	}
+	if err = send(hB, hA.PeerID()); err != nil {
+		t.Errorf("intent within the default limits: %v", err)
+	}
+}
+
+func TestReceiptsIssuedForAcceptedIntents(t *testing.T) {
+	alpha := makeAgent(t, "alpha", nil)
+	beta := makeAgent(t, "beta", []string{"summarisation"})
+
+	path := filepath.Join(t.TempDir(), "receipts.jsonl")
+	log, err := core.OpenReceiptLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = log.Close() })
+	price := func(*core.IntentMessage, *core.NegotiationResponse) (core.Cost, bool) {
+		return core.Cost{Amount: 3, Currency: "credits"}, true
+	}
+	hB, err := p2p.NewHost(context.Background(), beta, p2p.WithReceipts(log, price))
+	if err != nil {
+		t.Fatalf("NewHost: %v", err)
+	}
+	t.Cleanup(func() { _ = hB.Close() })
+	hA := makeHost(t, alpha)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err = hA.Connect(ctx, hB.AddrInfo()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	for _, caps := range [][]string{{"summarisation"}, {"translation"}} {
+		intent, _ := core.CreateIntent(alpha, nil, caps, "doc")
+		if _, err = hA.SendIntent(ctx, hB.PeerID(), intent); err != nil {
+			t.Fatalf("SendIntent: %v", err)
+		}
+	}
+
+	receipts, err := core.ReadReceipts(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(receipts) != 1 {
+		t.Fatalf("got %d receipts, want 1 for the accepted intent", len(receipts))
+	}
+	r := receipts[0]
+	if err = r.Verify(); err != nil || r.Requester != alpha.DID.String() || r.Cost.Amount != 3 {
+		t.Errorf("receipt %+v (verify: %v)", r, err)
+	}
+}
+
+// TestMeshIsolation verifies that hosts in different meshes cannot talk to
+// each other while hosts in the same mesh can.
+func TestMeshIsolation(t *testing.T) {
+	newMeshHost := func(id, mesh string) *p2p.AgentHost {
+		h, err := p2p.NewHost(context.Background(), makeAgent(t, id, []string{"nlp"}), p2p.WithMesh(mesh))
+		if err != nil {
+			t.Fatalf("NewHost(%s): %v", id, err)
+		}
+		t.Cleanup(func() { _ = h.Close() })
+		return h
+	}
+	prodA := newMeshHost("prod-a", "prod")
+	prodB := newMeshHost("prod-b", "prod")
+	staging := newMeshHost("staging", "staging")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := p2p.DiscoverAndHandshake(ctx, prodA, prodB.AddrInfo()); err != nil {
+		t.Fatalf("same-mesh handshake: %v", err)
+	}
+	_, err := p2p.DiscoverAndHandshake(ctx, prodA, staging.AddrInfo())
+	if !errors.Is(err, p2p.ErrProtocolMismatch) {
+		t.Errorf("cross-mesh handshake: got %v, want ErrProtocolMismatch", err)
+	}
+	if prodA.ProtocolID() == staging.ProtocolID() {
+		t.Errorf("meshes share protocol ID %s", prodA.ProtocolID())
+	}
+
+	if _, err := p2p.NewHost(context.Background(), makeAgent(t, "bad", nil), p2p.WithMesh("a/b")); err == nil {
+		t.Error("NewHost accepted an invalid mesh name")
+	}
+}
+
+// TestUnsignedIntentWithForeignDIDRejected verifies that a handshaked peer
+// cannot dodge the signature check by sending an unsigned intent that claims
+// another agent's DID.
+func TestUnsignedIntentWithForeignDIDRejected(t *testing.T) {
+	alpha := makeAgent(t, "alpha", []string{"nlp"})
+	beta := makeAgent(t, "beta", []string{"summarisation"})
+	hA := makeHost(t, alpha)
+	hB := makeHost(t, beta)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := p2p.DiscoverAndHandshake(ctx, hA, hB.AddrInfo()); err != nil {
+		t.Fatalf("DiscoverAndHandshake: %v", err)
+	}
+
+	var received bool
+	hB.OnIntent(func(_ peer.ID, msg *core.IntentMessage) *core.NegotiationResponse {
+		received = true
+		resp, _ := core.DefaultNegotiationHandler(beta)(msg)
+		return resp
+	})
+	victim := makeAgent(t, "victim", nil)
+	intent, _ := core.CreateIntent(victim, nil, []string{"summarisation"}, "x")
+	intent.Signature = nil
+	short, scancel := context.WithTimeout(ctx, time.Second)
+	defer scancel()
+	_, _ = hA.SendIntent(short, hB.PeerID(), intent)
+	if received {
+		t.Error("beta accepted an unsigned intent claiming a DID its sender never handshaked as")
+	}
+}
@@ -0,0 +1,87 @@
+package p2p
+
+import (
+	"slices"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+// FrameSource says how an inspected frame reached the host.
+type FrameSource int
+
+const (
+	SourceStream FrameSource = iota // One frame per libp2p stream
+	SourceMux                       // A multiplexed session stream
+	SourceGossip                    // A GossipSub topic
+	SourceBatch                     // A batched one-way stream
+)
+
+// String returns the source name.
+func (s FrameSource) String() string {
+	switch s {
+	case SourceStream:
+		return "stream"
+	case SourceMux:
+		return "mux"
+	case SourceGossip:
+		return "gossip"
+	case SourceBatch:
+		return "batch"
+	}
+	return "unknown"
+}
+
+// InspectedFrame is an incoming frame as shown to an Inspector.
+type InspectedFrame struct {
+	From   peer.ID
+	Source FrameSource
+	Type   core.MessageType
+	// Message is the decoded message (*core.IntentMessage,
+	// *core.AlertMessage, ...).  It is decoded separately from the copy the
+	// handlers see, so changing it has no effect on dispatch.
+	Message interface{}
+}
+
+// Inspector examines incoming frames before they are dispatched to
+// handlers, for DLP scanning, anomaly detection or compliance filtering.
+// Returning a non-nil error drops the frame.  Inspectors run in registration
+// order and may be called concurrently.
+type Inspector func(f InspectedFrame) error
+
+// WithInspector registers in on the host (see AddInspector).
+func WithInspector(in Inspector) HostOption {
+	return func(ah *AgentHost) { ah.inspectors = append(ah.inspectors, in) }
+}
+
+// AddInspector registers in to see every frame received from now on.
+func (ah *AgentHost) AddInspector(in Inspector) {
+	ah.mu.Lock()
+	defer ah.mu.Unlock()
+	ah.inspectors = append(slices.Clip(ah.inspectors), in)
+}
+
+// inspect shows a received frame to every inspector and reports whether it
+// may be dispatched.  Without inspectors it does no work.  Frames that
+// cannot be decoded are dropped, since no inspector could vouch for them.
+func (ah *AgentHost) inspect(from peer.ID, source FrameSource, msgType core.MessageType, data []byte) bool {
+	ah.mu.RLock()
+	inspectors := ah.inspectors
+	ah.mu.RUnlock()
+	if len(inspectors) == 0 {
+		return true
+	}
+
+	// Decode a private copy: decoded byte fields may alias their input.
+	msg, err := core.Decode(msgType, slices.Clone(data))
+	if err != nil {
+		return false
+	}
+	f := InspectedFrame{From: from, Source: source, Type: msgType, Message: msg}
+	for _, in := range inspectors {
+		if in(f) != nil {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,60 @@
+package p2p_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+func TestInspectorDropsFrame(t *testing.T) {
+	alpha := makeAgent(t, "alpha", []string{"nlp"})
+	beta := makeAgent(t, "beta", []string{"summarisation"})
+
+	var seen []p2p.InspectedFrame
+	var mu sync.Mutex
+	dlp := func(f p2p.InspectedFrame) error {
+		mu.Lock()
+		seen = append(seen, f)
+		mu.Unlock()
+		if in, ok := f.Message.(*core.IntentMessage); ok && strings.Contains(in.Payload, "SECRET") {
+			return errors.New("payload contains a secret")
+		}
+		return nil
+	}
+
+	hA := makeHost(t, alpha)
+	hB, err := p2p.NewHost(context.Background(), beta, p2p.WithInspector(dlp))
+	if err != nil {
+		t.Fatalf("NewHost: %v", err)
+	}
+	t.Cleanup(func() { _ = hB.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err = hA.Connect(ctx, hB.AddrInfo()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+
+	ok, _ := core.CreateIntent(alpha, []float32{0.9}, []string{"summarisation"}, "summarise")
+	if _, err = hA.SendIntent(ctx, hB.PeerID(), ok); err != nil {
+		t.Fatalf("SendIntent: %v", err)
+	}
+	leak, _ := core.CreateIntent(alpha, []float32{0.9}, []string{"summarisation"}, "SECRET plans")
+	short, scancel := context.WithTimeout(ctx, 500*time.Millisecond)
+	defer scancel()
+	if _, err = hA.SendIntent(short, hB.PeerID(), leak); err == nil {
+		t.Error("inspector did not drop the intent")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(seen) < 2 || seen[0].From != hA.PeerID() {
+		t.Errorf("inspector saw %d frames", len(seen))
+	}
+}
@@ -0,0 +1,244 @@
+package p2p
+
+// intentbatch.go — Sending many intents to one peer in a single exchange.
+//
+// An orchestrator dispatching hundreds of small steps a second spends much
+// of its time opening streams.  SendIntentBatch instead writes every intent
+// in one core.IntentBatch frame on one stream; the responder admits and
+// answers each as if it had arrived alone, concurrently up to its in-flight
+// limit, and replies with one core.ResponseBatch.  Intents the responder
+// drops have no response in the batch and fail with ErrIntentDropped.
+//
+//	Initiator                              Responder
+//	    |-- IntentBatch [i1 i2 … iN] ---------->|
+//	    |<- ResponseBatch [r1 r2 … rN] ---------|
+//
+// Peers that do not advertise core.FeatureIntentBatch get one SendIntent
+// per intent instead.
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+// MaxIntentBatch is the most intents one batch may carry.  Responders drop
+// larger batches unanswered.
+const MaxIntentBatch = 256
+
+// ErrIntentDropped is the error of a BatchResult whose intent the peer did
+// not answer (bad signature, stale timestamp, no handler).
+var ErrIntentDropped = fmt.Errorf("p2p batch: intent dropped by peer")
+
+// BatchResult is the outcome of one intent sent with SendIntentBatch.
+type BatchResult struct {
+	Intent   *core.IntentMessage
+	Response *core.NegotiationResponse // nil when Err is set
+	Err      error
+}
+
+// SendIntentBatch sends intents to peerID in one exchange and returns a
+// result for each, in the order given.  The error is set only when the
+// batch as a whole could not be exchanged; failures of single intents are
+// reported in their results.  Intent IDs must be unique within the batch.
+func (ah *AgentHost) SendIntentBatch(
+	ctx context.Context,
+	peerID peer.ID,
+	intents []*core.IntentMessage,
+) ([]BatchResult, error) {
+	if len(intents) == 0 {
+		return nil, nil
+	}
+	if len(intents) > MaxIntentBatch {
+		return nil, fmt.Errorf("p2p batch: %d intents exceed the limit of %d", len(intents), MaxIntentBatch)
+	}
+	seen := make(map[string]bool, len(intents))
+	for _, intent := range intents {
+		if seen[intent.ID] {
+			return nil, fmt.Errorf("p2p batch: intent %s appears twice", intent.ID)
+		}
+		seen[intent.ID] = true
+	}
+	if err := ah.checkQuarantine(peerID); err != nil {
+		return nil, fmt.Errorf("p2p batch: %w", err)
+	}
+	if !ah.SharedFeature(peerID, core.FeatureIntentBatch) {
+		return ah.sendIntentsOneByOne(ctx, peerID, intents), nil
+	}
+
+	start := time.Now()
+	ctx, span := ah.startSpan(ctx, "asp.send_intent_batch", peerID)
+	results := make([]BatchResult, len(intents))
+	batch := &core.IntentBatch{Intents: make([]*core.IntentMessage, len(intents))}
+	for i, intent := range intents {
+		intent, err := ah.outgoing(ctx, peerID, intent, false)
+		if err != nil {
+			endSpan(span, nil, err)
+			return nil, err
+		}
+		if ah.sealPayloads {
+			sealed, err := ah.sealIntent(peerID, intent)
+			if err != nil {
+				endSpan(span, nil, err)
+				return nil, err
+			}
+			intent = sealed
+		}
+		results[i].Intent = intent
+		batch.Intents[i] = intent
+	}
+
+	ctx, release := ah.trackCall(ctx, peerID)
+	defer release()
+	replies, err := ah.exchangeBatch(ctx, peerID, batch)
+	ah.sendError(peerID, err)
+	if err != nil && disconnected(ctx) {
+		err = fmt.Errorf("p2p batch: %s: %w", peerID, ErrPeerDisconnected)
+	}
+	endSpan(span, nil, err)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]*core.NegotiationResponse, len(replies.Responses))
+	for _, resp := range replies.Responses {
+		byID[resp.RequestID] = resp
+	}
+	for i := range results {
+		r := &results[i]
+		if resp, ok := byID[r.Intent.ID]; ok {
+			r.Response, r.Err = ah.checkedResponse(peerID, r.Intent, start, resp)
+		} else {
+			r.Err = fmt.Errorf("%w: %s", ErrIntentDropped, r.Intent.ID)
+		}
+		if r.Err != nil {
+			r.Response = nil
+		}
+		ah.observeIntentSent(start, r.Response, r.Err)
+		ah.logIntent(ctx, "intent sent", peerID, r.Intent, r.Response, start, r.Err)
+		if r.Response != nil {
+			ah.auditMsg(r.Response, r.Response.DID, core.Decision(r.Response, r.Err))
+		}
+	}
+	return results, nil
+}
+
+// exchangeBatch writes batch to a fresh stream to peerID and reads back the
+// ResponseBatch.
+func (ah *AgentHost) exchangeBatch(ctx context.Context, peerID peer.ID, batch *core.IntentBatch) (*core.ResponseBatch, error) {
+	stream, err := ah.newStream(ctx, peerID, ah.proto)
+	if err != nil {
+		return nil, fmt.Errorf("p2p batch: open stream: %w", err)
+	}
+	defer stream.Close()
+	stop := context.AfterFunc(ctx, func() { _ = stream.Reset() })
+	defer stop()
+
+	if err = ah.writePeerMsg(stream, peerID, batch); err != nil {
+		ah.streamError("write", err)
+		return nil, fmt.Errorf("p2p batch: send: %w", err)
+	}
+	msgType, data, err := ah.readMsg(stream)
+	if err != nil {
+		ah.streamError("read", err)
+		return nil, fmt.Errorf("p2p batch: recv: %w", err)
+	}
+	if msgType != core.MsgResponseBatch {
+		return nil, fmt.Errorf("p2p batch: expected MsgResponseBatch, got 0x%02x", msgType)
+	}
+	replies, err := ah.limits.DecodeResponseBatch(data)
+	if err != nil {
+		ah.peerError(peerID, core.PeerErrorDecode)
+		return nil, fmt.Errorf("p2p batch: decode response: %w", err)
+	}
+	return replies, nil
+}
+
+// sendIntentsOneByOne is SendIntentBatch for peers that cannot answer
+// batches: one concurrent SendIntent per intent.
+func (ah *AgentHost) sendIntentsOneByOne(ctx context.Context, peerID peer.ID, intents []*core.IntentMessage) []BatchResult {
+	results := make([]BatchResult, len(intents))
+	var wg sync.WaitGroup
+	for i, intent := range intents {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := ah.SendIntent(ctx, peerID, intent)
+			results[i] = BatchResult{Intent: intent, Response: resp, Err: err}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// handleIncomingIntentBatch answers every intent in a batch and replies
+// with one ResponseBatch.
+func (ah *AgentHost) handleIncomingIntentBatch(s network.Stream, data []byte) {
+	from := s.Conn().RemotePeer()
+	if !ah.hasFeature(core.FeatureIntentBatch) {
+		return
+	}
+	batch, err := ah.limits.DecodeIntentBatch(data)
+	if err != nil {
+		ah.peerError(from, core.PeerErrorDecode)
+		return
+	}
+	if len(batch.Intents) > MaxIntentBatch {
+		return
+	}
+	limit := ah.muxInflight
+	if limit <= 0 {
+		limit = DefaultMaxInflight
+	}
+	slots := make(chan struct{}, limit)
+	intents := make([]*core.IntentMessage, len(batch.Intents))
+	answers := make([]*core.NegotiationResponse, len(batch.Intents))
+	var wg sync.WaitGroup
+	for i, in := range batch.Intents {
+		raw, err := in.Encode()
+		if err != nil || !ah.inspect(from, SourceStream, core.MsgIntent, raw) {
+			continue
+		}
+		pending := ah.queueIntent(from, raw)
+		select {
+		case slots <- struct{}{}:
+		case <-ah.done:
+			ah.finishIntent(pending)
+			wg.Wait()
+			return
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			defer ah.finishIntent(pending)
+			intent, ok := ah.admitIntent(from, raw)
+			if !ok {
+				return
+			}
+			ah.keepForRelay(pending, from, raw)
+			intents[i], answers[i] = intent, ah.answerIntent(from, intent, pending)
+		}()
+	}
+	wg.Wait()
+
+	reply := &core.ResponseBatch{}
+	for _, resp := range answers {
+		if resp != nil {
+			reply.Responses = append(reply.Responses, resp)
+		}
+	}
+	if err = ah.writePeerMsg(s, from, reply); err != nil {
+		return
+	}
+	for _, resp := range answers {
+		if resp != nil {
+			ah.applySenderTrust(from, resp.TrustDelta)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package p2p_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+// TestSendIntentBatch verifies that a batch of intents is answered in one
+// exchange, in order, and that peers without batching get one stream each.
+func TestSendIntentBatch(t *testing.T) {
+	alpha := makeAgent(t, "alpha", []string{"nlp"})
+	beta := makeAgent(t, "beta", []string{"summarisation"})
+	gamma := makeAgent(t, "gamma", []string{"summarisation"})
+
+	hA := makeHost(t, alpha)
+	hB := makeHost(t, beta)
+	hC, err := p2p.NewHost(context.Background(), gamma, p2p.WithFeatures(core.FeatureCompression))
+	if err != nil {
+		t.Fatalf("NewHost: %v", err)
+	}
+	t.Cleanup(func() { _ = hC.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, h := range []*p2p.AgentHost{hB, hC} {
+		if err := hA.Connect(ctx, h.AddrInfo()); err != nil {
+			t.Fatalf("Connect: %v", err)
+		}
+		if _, err := hA.Handshake(ctx, h.PeerID()); err != nil {
+			t.Fatalf("Handshake: %v", err)
+		}
+	}
+
+	for _, h := range []*p2p.AgentHost{hB, hC} {
+		var intents []*core.IntentMessage
+		for _, capability := range []string{"summarisation", "code-gen", "summarisation"} {
+			intent, err := core.CreateIntent(alpha, nil, []string{capability}, "")
+			if err != nil {
+				t.Fatalf("CreateIntent: %v", err)
+			}
+			intents = append(intents, intent)
+		}
+		results, err := hA.SendIntentBatch(ctx, h.PeerID(), intents)
+		if err != nil {
+			t.Fatalf("SendIntentBatch: %v", err)
+		}
+		if len(results) != len(intents) {
+			t.Fatalf("got %d results, want %d", len(results), len(intents))
+		}
+		for i, r := range results {
+			if r.Err != nil {
+				t.Fatalf("result %d: %v", i, r.Err)
+			}
+			if r.Intent.ID != intents[i].ID || r.Response.RequestID != intents[i].ID {
+				t.Errorf("result %d answers %s, want %s", i, r.Response.RequestID, intents[i].ID)
+			}
+			if want := i != 1; r.Response.Accepted != want {
+				t.Errorf("result %d: accepted = %v, want %v", i, r.Response.Accepted, want)
+			}
+		}
+	}
+
+	// Duplicate IDs are refused before anything is sent.
+	intent, _ := core.CreateIntent(alpha, nil, []string{"summarisation"}, "")
+	if _, err = hA.SendIntentBatch(ctx, hB.PeerID(), []*core.IntentMessage{intent, intent}); err == nil {
+		t.Error("expected an error for duplicate intent IDs")
+	}
+}
@@ -0,0 +1,126 @@
+package p2p
+
+// liveness.go — Ping/pong and the health monitor.
+//
+// Hosts that advertise core.FeaturePing answer a PingMessage with a
+// PongMessage on the same stream, even while draining.  WithHealthMonitor
+// pings every handshaked peer in the DiscoveryRegistry periodically (see
+// core.HealthMonitor), so peers that stop answering drop out of capability
+// lookups before an orchestrator sends them work.  Registry entries the
+// host has no handshake with are not probed.
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+// WithHealthMonitor makes the host probe the peers in its DiscoveryRegistry
+// as cfg describes and record their core.Liveness there.
+func WithHealthMonitor(cfg core.HealthMonitorConfig) HostOption {
+	return func(ah *AgentHost) { ah.monitorCfg = &cfg }
+}
+
+// HealthMonitor returns the host's health monitor, or nil without
+// WithHealthMonitor.
+func (ah *AgentHost) HealthMonitor() *core.HealthMonitor { return ah.monitor }
+
+// Ping sends peerID a PingMessage and returns the round-trip time.  The
+// peer must advertise core.FeaturePing.
+func (ah *AgentHost) Ping(ctx context.Context, peerID peer.ID) (time.Duration, error) {
+	if err := ah.requireFeature(peerID, core.FeaturePing); err != nil {
+		return 0, fmt.Errorf("p2p ping: %w", err)
+	}
+	stream, err := ah.newStream(ctx, peerID, ah.proto)
+	if err != nil {
+		return 0, fmt.Errorf("p2p ping: open stream: %w", err)
+	}
+	defer stream.Close()
+	stop := context.AfterFunc(ctx, func() { _ = stream.Reset() })
+	defer stop()
+
+	nonce := make([]byte, 8)
+	_, _ = rand.Read(nonce)
+	start := time.Now()
+	if err = ah.writePeerMsg(stream, peerID, &core.PingMessage{Nonce: nonce, SentAt: start.UnixNano()}); err != nil {
+		return 0, fmt.Errorf("p2p ping: send: %w", err)
+	}
+	msgType, data, err := ah.readMsg(stream)
+	if err != nil {
+		return 0, fmt.Errorf("p2p ping: recv: %w", err)
+	}
+	rtt := time.Since(start)
+	if msgType != core.MsgPong {
+		return 0, fmt.Errorf("p2p ping: expected MsgPong, got 0x%02x", msgType)
+	}
+	pong, err := ah.limits.DecodePongMessage(data)
+	if err != nil {
+		return 0, fmt.Errorf("p2p ping: decode pong: %w", err)
+	}
+	if !bytes.Equal(pong.Nonce, nonce) {
+		return 0, fmt.Errorf("p2p ping: pong for another ping")
+	}
+	return rtt, nil
+}
+
+// handleIncomingPing answers a PingMessage.
+func (ah *AgentHost) handleIncomingPing(s network.Stream, data []byte) {
+	from := s.Conn().RemotePeer()
+	ping, err := ah.limits.DecodePingMessage(data)
+	if err != nil {
+		ah.peerError(from, core.PeerErrorDecode)
+		return
+	}
+	_ = ah.writePeerMsg(s, from, &core.PongMessage{
+		Nonce:     ping.Nonce,
+		SentAt:    ping.SentAt,
+		RepliedAt: time.Now().UnixNano(),
+	})
+}
+
+// probe is the health monitor's core.ProbeFunc: it pings the handshaked
+// peer whose agent profile describes.
+func (ah *AgentHost) probe(ctx context.Context, profile core.AgentProfile) (time.Duration, error) {
+	pid, ok := ah.peerByDID(profile.DID)
+	if !ok || !ah.SharedFeature(pid, core.FeaturePing) {
+		return 0, core.ErrNotProbed
+	}
+	return ah.Ping(ctx, pid)
+}
+
+// peerByDID returns the handshaked peer whose agent is did.
+func (ah *AgentHost) peerByDID(did string) (peer.ID, bool) {
+	ah.mu.RLock()
+	defer ah.mu.RUnlock()
+	for key, p := range ah.known {
+		if p.DID != did {
+			continue
+		}
+		if pid, err := peer.Decode(key); err == nil {
+			return pid, true
+		}
+	}
+	return "", false
+}
+
+// livenessChanged reports a peer becoming unhealthy or healthy again.
+func (ah *AgentHost) livenessChanged(did string, l core.Liveness) {
+	ev := Event{Kind: EventPeerHealthy, DID: did}
+	if pid, ok := ah.peerByDID(did); ok {
+		ev.PeerID = pid
+	}
+	if p, ok := ah.discovery.FindByDID(did); ok {
+		ev.AgentID = p.AgentID
+	}
+	if !l.Healthy {
+		ev.Kind = EventPeerUnhealthy
+		ev.Reason = fmt.Sprintf("%d probes unanswered, %.0f%% available", l.Failures, l.Availability*100)
+	}
+	ah.emit(ev)
+}
@@ -0,0 +1,56 @@
+package p2p_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+func TestHealthMonitor(t *testing.T) {
+	alpha := makeAgent(t, "alpha", nil)
+	beta := makeAgent(t, "beta", []string{"translate"})
+	hA, err := p2p.NewHost(context.Background(), alpha,
+		p2p.WithHealthMonitor(core.HealthMonitorConfig{Interval: time.Hour, Timeout: time.Second, MaxFailures: 1}))
+	if err != nil {
+		t.Fatalf("NewHost: %v", err)
+	}
+	t.Cleanup(func() { _ = hA.Close() })
+	hB := makeHost(t, beta)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err = hA.Connect(ctx, hB.AddrInfo()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	if _, err = hA.Handshake(ctx, hB.PeerID()); err != nil {
+		t.Fatalf("Handshake: %v", err)
+	}
+	if rtt, err := hA.Ping(ctx, hB.PeerID()); err != nil || rtt <= 0 {
+		t.Fatalf("Ping = %v, %v", rtt, err)
+	}
+
+	m := hA.HealthMonitor()
+	m.ProbeAll(ctx)
+	if l, ok := m.Liveness(beta.DID.String()); !ok || !l.Healthy || l.RTT <= 0 {
+		t.Fatalf("liveness of a live peer = %+v, %v", l, ok)
+	}
+
+	unhealthy := make(chan p2p.Event, 1)
+	hA.Subscribe(func(ev p2p.Event) { unhealthy <- ev }, p2p.EventPeerUnhealthy)
+	_ = hB.Close()
+	m.ProbeAll(ctx)
+	select {
+	case ev := <-unhealthy:
+		if ev.DID != beta.DID.String() || ev.AgentID != "beta" {
+			t.Errorf("event = %+v", ev)
+		}
+	default:
+		t.Fatal("no peer_unhealthy event after the peer went away")
+	}
+	if p, ok := hA.Discovery().FindByDID(beta.DID.String()); !ok || p.Liveness == nil || p.Liveness.Healthy {
+		t.Errorf("profile of a dead peer = %+v", p)
+	}
+}
@@ -0,0 +1,54 @@
+package p2p
+
+// logging.go — Structured logging.
+//
+// WithLogger sends the host's protocol events to a core.Logger, such as a
+// *slog.Logger: handshakes, intents sent and answered, and stream errors,
+// with the fields named by the core.LogKey* constants.  Successful
+// exchanges are logged at Info, failures at Warn.
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+// WithLogger logs the host's protocol events to l.  The default discards
+// them.
+func WithLogger(l core.Logger) HostOption {
+	return func(ah *AgentHost) { ah.log = l }
+}
+
+// logHandshake logs a handshake with pid begun at start.
+func (ah *AgentHost) logHandshake(ctx context.Context, pid peer.ID, start time.Time, err error) {
+	args := []interface{}{
+		core.LogKeyPeer, pid.String(),
+		core.LogKeyMsgType, "handshake",
+		core.LogKeyLatency, time.Since(start),
+	}
+	if err != nil {
+		ah.log.Log(ctx, slog.LevelWarn, "handshake failed", append(args, "error", err)...)
+		return
+	}
+	ah.log.Log(ctx, slog.LevelInfo, "handshake", args...)
+}
+
+// logIntent logs an intent exchanged with pid, begun at start.  msg tells
+// the direction.
+func (ah *AgentHost) logIntent(ctx context.Context, msg string, pid peer.ID, intent *core.IntentMessage, resp *core.NegotiationResponse, start time.Time, err error) {
+	args := []interface{}{
+		core.LogKeyPeer, pid.String(),
+		core.LogKeyMsgType, "intent",
+		core.LogKeyIntentID, intent.ID,
+		core.LogKeyLatency, time.Since(start),
+		core.LogKeyDecision, core.Decision(resp, err),
+	}
+	if err != nil {
+		ah.log.Log(ctx, slog.LevelWarn, msg, append(args, "error", err)...)
+		return
+	}
+	ah.log.Log(ctx, slog.LevelInfo, msg, args...)
+}
@@ -0,0 +1,78 @@
+package p2p_test
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+// recordingLogger keeps the messages and fields of every record.
+type recordingLogger struct {
+	mu      sync.Mutex
+	records []map[string]interface{}
+}
+
+func (l *recordingLogger) Log(_ context.Context, _ slog.Level, msg string, args ...interface{}) {
+	rec := map[string]interface{}{"msg": msg}
+	for i := 0; i+1 < len(args); i += 2 {
+		rec[args[i].(string)] = args[i+1]
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.records = append(l.records, rec)
+}
+
+func (l *recordingLogger) find(msg string) map[string]interface{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, r := range l.records {
+		if r["msg"] == msg {
+			return r
+		}
+	}
+	return nil
+}
+
+func TestStructuredLogging(t *testing.T) {
+	logA, logB := &recordingLogger{}, &recordingLogger{}
+	alpha := makeAgent(t, "alpha", []string{"nlp"})
+	beta := makeAgent(t, "beta", []string{"ocr"})
+	newHost := func(a *core.Agent, l core.Logger) *p2p.AgentHost {
+		h, err := p2p.NewHost(context.Background(), a, p2p.WithLogger(l))
+		if err != nil {
+			t.Fatalf("NewHost: %v", err)
+		}
+		t.Cleanup(func() { _ = h.Close() })
+		return h
+	}
+	hA, hB := newHost(alpha, logA), newHost(beta, logB)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := p2p.DiscoverAndHandshake(ctx, hA, hB.AddrInfo()); err != nil {
+		t.Fatalf("DiscoverAndHandshake: %v", err)
+	}
+	intent, _ := core.CreateIntent(alpha, nil, []string{"translation"}, "translate")
+	if _, err := hA.SendIntent(ctx, hB.PeerID(), intent); err != nil {
+		t.Fatalf("SendIntent: %v", err)
+	}
+
+	if r := logA.find("handshake"); r == nil || r[core.LogKeyPeer] != hB.PeerID().String() {
+		t.Errorf("handshake record: %v", r)
+	}
+	sent := logA.find("intent sent")
+	if sent == nil || sent[core.LogKeyIntentID] != intent.ID || sent[core.LogKeyDecision] != "rejected" {
+		t.Errorf("sent record: %v", sent)
+	}
+	if _, ok := sent[core.LogKeyLatency].(time.Duration); !ok {
+		t.Errorf("latency: %T", sent[core.LogKeyLatency])
+	}
+	if r := logB.find("intent answered"); r == nil || r[core.LogKeyPeer] != hA.PeerID().String() || r[core.LogKeyDecision] != "rejected" {
+		t.Errorf("answered record: %v", r)
+	}
+}
@@ -0,0 +1,92 @@
+package p2p
+
+// mdns.go — Local network discovery over multicast DNS.
+//
+// With EnableMDNS, hosts on the same LAN find each other without any
+// bootstrap address: every peer announced under the same service tag is
+// connected, handshaked and thereby added to the DiscoveryRegistry.
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/p2p/discovery/mdns"
+)
+
+// DefaultMDNSServiceTag is the service tag EnableMDNS uses when given "".
+// Only hosts using the same tag discover each other.
+const DefaultMDNSServiceTag = "agent-semantic-protocol"
+
+// mdnsHandshakeTimeout bounds connecting to and handshaking with one peer
+// found over mDNS.
+const mdnsHandshakeTimeout = 10 * time.Second
+
+// EnableMDNS starts announcing the host on the local network under
+// serviceTag and handshakes with every other host found under the same tag.
+// The service stops when the host is closed.
+func (ah *AgentHost) EnableMDNS(serviceTag string) error {
+	if serviceTag == "" {
+		serviceTag = DefaultMDNSServiceTag
+	}
+	ah.mdnsMu.Lock()
+	defer ah.mdnsMu.Unlock()
+	if ah.mdns != nil {
+		return fmt.Errorf("p2p mdns: already enabled")
+	}
+	svc := mdns.NewMdnsService(ah.h, serviceTag, mdnsNotifee{ah})
+	if err := svc.Start(); err != nil {
+		return fmt.Errorf("p2p mdns: start: %w", err)
+	}
+	ah.mdns = svc
+	ah.mdnsPending = make(map[peer.ID]bool)
+	return nil
+}
+
+// mdnsNotifee receives peers found by the mDNS service.
+type mdnsNotifee struct{ ah *AgentHost }
+
+// HandlePeerFound implements mdns.Notifee.  Peers are announced repeatedly,
+// so peers already handshaked or being handshaked are ignored.
+func (n mdnsNotifee) HandlePeerFound(info peer.AddrInfo) {
+	ah := n.ah
+	if info.ID == ah.h.ID() {
+		return
+	}
+	ah.mu.RLock()
+	_, known := ah.known[info.ID.String()]
+	ah.mu.RUnlock()
+	if known {
+		return
+	}
+	ah.mdnsMu.Lock()
+	if ah.mdnsPending[info.ID] {
+		ah.mdnsMu.Unlock()
+		return
+	}
+	ah.mdnsPending[info.ID] = true
+	ah.mdnsMu.Unlock()
+
+	go func() {
+		defer func() {
+			ah.mdnsMu.Lock()
+			delete(ah.mdnsPending, info.ID)
+			ah.mdnsMu.Unlock()
+		}()
+		ctx, cancel := context.WithTimeout(context.Background(), mdnsHandshakeTimeout)
+		defer cancel()
+		// A failure is retried when the peer is next announced.
+		_, _ = DiscoverAndHandshake(ctx, ah, info)
+	}()
+}
+
+// closeMDNS stops the mDNS service, if running.
+func (ah *AgentHost) closeMDNS() {
+	ah.mdnsMu.Lock()
+	defer ah.mdnsMu.Unlock()
+	if ah.mdns != nil {
+		_ = ah.mdns.Close()
+		ah.mdns = nil
+	}
+}
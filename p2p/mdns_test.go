@@ -0,0 +1,31 @@
+package p2p_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+func TestMDNSDiscovery(t *testing.T) {
+	hA := makeHost(t, makeAgent(t, "alpha", nil))
+	hB := makeHost(t, makeAgent(t, "beta", []string{"ocr"}))
+	tag := fmt.Sprintf("asp-test-%d", time.Now().UnixNano())
+	for _, h := range []*p2p.AgentHost{hA, hB} {
+		if err := h.EnableMDNS(tag); err != nil {
+			t.Fatalf("EnableMDNS: %v", err)
+		}
+	}
+	if err := hA.EnableMDNS(tag); err == nil {
+		t.Error("second EnableMDNS succeeded")
+	}
+
+	deadline := time.Now().Add(15 * time.Second)
+	for len(hA.Discovery().FindByCapability("ocr")) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("beta was not discovered over mDNS")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
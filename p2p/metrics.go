@@ -0,0 +1,217 @@
+package p2p
+
+// metrics.go — Protocol metrics.
+//
+// WithMetrics records the host's protocol operations in a metrics.Registry:
+// intents sent (by outcome), received, accepted and rejected, handshake
+// latency, negotiation round-trip time, the local agent's trust in each
+// connected peer, stream errors, and the number of frames per batched
+// write.  ServeMetrics exposes the registry over HTTP for Prometheus to
+// scrape.
+//
+// asp_peer_trust is labelled by DID, so it only has series for DIDs that
+// connected peers completed a handshake under: trust in DIDs that intents
+// merely claim is not exported, and a peer's series goes when it
+// disconnects.
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/metrics"
+)
+
+// Outcomes of an intent sent, as recorded in asp_intents_sent_total.
+const (
+	OutcomeAccepted = "accepted"
+	OutcomeRejected = "rejected"
+	OutcomeError    = "error"
+)
+
+// hostMetrics are the metrics one host records.
+type hostMetrics struct {
+	reg             *metrics.Registry
+	intentsSent     *metrics.Counter
+	intentsReceived *metrics.Counter
+	intentsAccepted *metrics.Counter
+	intentsRejected *metrics.Counter
+	handshakes      *metrics.Histogram
+	negotiations    *metrics.Histogram
+	trust           *metrics.Gauge
+	streamErrors    *metrics.Counter
+	batchFrames     *metrics.Histogram
+	rateLimited     *metrics.Counter
+
+	mu    sync.Mutex
+	peers map[peer.ID]string // DID of each connected peer that shook hands
+	dids  map[string]int     // number of peers in peers per DID
+}
+
+func newHostMetrics(reg *metrics.Registry) *hostMetrics {
+	return &hostMetrics{
+		reg:             reg,
+		intentsSent:     reg.Counter("asp_intents_sent_total", "Intents sent, by outcome.", "outcome"),
+		intentsReceived: reg.Counter("asp_intents_received_total", "Intents received that passed admission checks."),
+		intentsAccepted: reg.Counter("asp_intents_accepted_total", "Intents received and accepted."),
+		intentsRejected: reg.Counter("asp_intents_rejected_total", "Intents received and rejected."),
+		handshakes:      reg.Histogram("asp_handshake_duration_seconds", "Latency of handshakes this host initiated.", nil),
+		negotiations:    reg.Histogram("asp_negotiation_rtt_seconds", "Round-trip time from sending an intent to its verified response.", nil),
+		trust:           reg.Gauge("asp_peer_trust", "The local agent's trust score in a peer.", "did"),
+		streamErrors:    reg.Counter("asp_stream_errors_total", "Stream failures, by operation.", "op"),
+		batchFrames:     reg.Histogram("asp_send_batch_frames", "Frames per batched write (WithSendBatching).", []float64{1, 2, 4, 8, 16, 32, 64}),
+		rateLimited:     reg.Counter("asp_intents_rate_limited_total", "Intents refused by the per-peer rate limit (WithRateLimit)."),
+		peers:           make(map[peer.ID]string),
+		dids:            make(map[string]int),
+	}
+}
+
+// WithMetrics records the host's metrics in reg, or in a new registry if reg
+// is nil.  Hosts sharing a registry add up into the same series.
+func WithMetrics(reg *metrics.Registry) HostOption {
+	return func(ah *AgentHost) {
+		if reg == nil {
+			reg = metrics.NewRegistry()
+		}
+		ah.metrics = newHostMetrics(reg)
+	}
+}
+
+// Metrics returns the host's metrics registry, or nil without WithMetrics.
+func (ah *AgentHost) Metrics() *metrics.Registry {
+	if ah.metrics == nil {
+		return nil
+	}
+	return ah.metrics.reg
+}
+
+// ServeMetrics serves the host's metrics at /metrics on addr (e.g.
+// "127.0.0.1:9090") until the host is closed, and returns the address
+// actually bound.
+func (ah *AgentHost) ServeMetrics(addr string) (net.Addr, error) {
+	if ah.metrics == nil {
+		return nil, fmt.Errorf("p2p metrics: host was created without WithMetrics")
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("p2p metrics: %w", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", ah.metrics.reg.Handler())
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() { _ = srv.Serve(ln) }()
+	go func() {
+		<-ah.done
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_ = srv.Shutdown(ctx)
+	}()
+	return ln.Addr(), nil
+}
+
+// watchMetrics feeds the host's events into its metrics.
+func (ah *AgentHost) watchMetrics() {
+	m := ah.metrics
+	ah.Subscribe(func(ev Event) {
+		switch ev.Kind {
+		case EventIntentReceived:
+			m.intentsReceived.Inc()
+		case EventIntentRejected:
+			m.intentsRejected.Inc()
+		case EventTrustUpdated:
+			m.mu.Lock()
+			if m.dids[ev.DID] > 0 {
+				m.trust.Set(float64(ev.Trust), ev.DID)
+			}
+			m.mu.Unlock()
+		case EventHandshakeCompleted:
+			if ev.DID != "" {
+				m.mu.Lock()
+				m.dropPeer(ev.PeerID)
+				m.peers[ev.PeerID] = ev.DID
+				m.dids[ev.DID]++
+				m.trust.Set(float64(ah.trust.Get(ah.agent.DID.String(), ev.DID)), ev.DID)
+				m.mu.Unlock()
+			}
+		case EventPeerDisconnected:
+			m.mu.Lock()
+			m.dropPeer(ev.PeerID)
+			m.mu.Unlock()
+		}
+	}, EventIntentReceived, EventIntentRejected, EventTrustUpdated, EventHandshakeCompleted, EventPeerDisconnected)
+}
+
+// dropPeer stops exporting the trust in pid's DID once no other connected
+// peer shook hands under it.  The caller holds m.mu.
+func (m *hostMetrics) dropPeer(pid peer.ID) {
+	did, ok := m.peers[pid]
+	if !ok {
+		return
+	}
+	delete(m.peers, pid)
+	if m.dids[did]--; m.dids[did] == 0 {
+		delete(m.dids, did)
+		m.trust.Delete(did)
+	}
+}
+
+// The helpers below do nothing without WithMetrics.
+
+func (ah *AgentHost) observeHandshake(start time.Time) {
+	if ah.metrics != nil {
+		ah.metrics.handshakes.Observe(time.Since(start).Seconds())
+	}
+}
+
+// observeIntentSent records one SendIntent call.  Only verified responses
+// count towards the round-trip time.
+func (ah *AgentHost) observeIntentSent(start time.Time, resp *core.NegotiationResponse, err error) {
+	if ah.metrics == nil {
+		return
+	}
+	switch {
+	case err != nil || resp == nil:
+		ah.metrics.intentsSent.Inc(OutcomeError)
+		return
+	case resp.Accepted:
+		ah.metrics.intentsSent.Inc(OutcomeAccepted)
+	default:
+		ah.metrics.intentsSent.Inc(OutcomeRejected)
+	}
+	ah.metrics.negotiations.Observe(time.Since(start).Seconds())
+}
+
+// observeBatch records one batched write of frames frames.
+func (ah *AgentHost) observeBatch(frames int) {
+	if ah.metrics != nil {
+		ah.metrics.batchFrames.Observe(float64(frames))
+	}
+}
+
+func (ah *AgentHost) observeRateLimited() {
+	if ah.metrics != nil {
+		ah.metrics.rateLimited.Inc()
+	}
+}
+
+func (ah *AgentHost) observeIntentAccepted() {
+	if ah.metrics != nil {
+		ah.metrics.intentsAccepted.Inc()
+	}
+}
+
+// streamError counts, logs and remembers a stream failure during op
+// ("open", "read", "write").
+func (ah *AgentHost) streamError(op string, err error) {
+	ah.log.Log(context.Background(), slog.LevelWarn, "stream error", "op", op, "error", err)
+	ah.noteError(op, err)
+	if ah.metrics != nil {
+		ah.metrics.streamErrors.Inc(op)
+	}
+}
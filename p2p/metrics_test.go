@@ -0,0 +1,119 @@
+package p2p_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/metrics"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+// TestMetrics verifies that intents and handshakes are counted and that
+// ServeMetrics exposes them.
+func TestMetrics(t *testing.T) {
+	alpha := makeAgent(t, "alpha", []string{"nlp"})
+	beta := makeAgent(t, "beta", []string{"ocr"})
+	reg := metrics.NewRegistry()
+	hA, err := p2p.NewHost(context.Background(), alpha, p2p.WithMetrics(reg))
+	if err != nil {
+		t.Fatalf("NewHost: %v", err)
+	}
+	t.Cleanup(func() { _ = hA.Close() })
+	hB, err := p2p.NewHost(context.Background(), beta, p2p.WithMetrics(nil))
+	if err != nil {
+		t.Fatalf("NewHost: %v", err)
+	}
+	t.Cleanup(func() { _ = hB.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err = p2p.DiscoverAndHandshake(ctx, hA, hB.AddrInfo()); err != nil {
+		t.Fatalf("DiscoverAndHandshake: %v", err)
+	}
+	for _, c := range []string{"ocr", "translate"} {
+		intent, _ := core.CreateIntent(alpha, nil, []string{c}, "x")
+		if _, err = hA.SendIntent(ctx, hB.PeerID(), intent); err != nil {
+			t.Fatalf("SendIntent(%s): %v", c, err)
+		}
+	}
+
+	sent := reg.Counter("asp_intents_sent_total", "", "outcome")
+	if sent.Value(p2p.OutcomeAccepted) != 1 || sent.Value(p2p.OutcomeRejected) != 1 {
+		t.Errorf("sent: accepted=%v rejected=%v", sent.Value(p2p.OutcomeAccepted), sent.Value(p2p.OutcomeRejected))
+	}
+	if n := reg.Histogram("asp_handshake_duration_seconds", "", nil).Count(); n != 1 {
+		t.Errorf("handshakes observed: %d", n)
+	}
+	if reg.Gauge("asp_peer_trust", "", "did").Value(beta.DID.String()) == 0 {
+		t.Error("no trust recorded for beta")
+	}
+	if got := hB.Metrics().Counter("asp_intents_received_total", "").Value(); got != 2 {
+		t.Errorf("received: %v", got)
+	}
+
+	addr, err := hB.ServeMetrics("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ServeMetrics: %v", err)
+	}
+	res, err := http.Get("http://" + addr.String() + "/metrics")
+	if err != nil {
+		t.Fatalf("GET /metrics: %v", err)
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	if !strings.Contains(string(body), "asp_intents_accepted_total 1\n") {
+		t.Errorf("/metrics:\n%s", body)
+	}
+}
+
+// TestPeerTrustMetricHandshakedOnly verifies that asp_peer_trust has series
+// only for DIDs that connected peers shook hands under, and drops them on
+// disconnect.
+func TestPeerTrustMetricHandshakedOnly(t *testing.T) {
+	alpha := makeAgent(t, "alpha", nil)
+	beta := makeAgent(t, "beta", []string{"ocr"})
+	hA := makeHost(t, alpha)
+	hB, err := p2p.NewHost(context.Background(), beta, p2p.WithMetrics(nil))
+	if err != nil {
+		t.Fatalf("NewHost: %v", err)
+	}
+	t.Cleanup(func() { _ = hB.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err = p2p.DiscoverAndHandshake(ctx, hA, hB.AddrInfo()); err != nil {
+		t.Fatalf("DiscoverAndHandshake: %v", err)
+	}
+	intent, _ := core.CreateIntent(alpha, nil, []string{"ocr"}, "x")
+	if _, err = hA.SendIntent(ctx, hB.PeerID(), intent); err != nil {
+		t.Fatalf("SendIntent: %v", err)
+	}
+	// No peer shook hands as the stranger, so its trust stays local.
+	stranger := "did:agent-semantic-protocol:stranger"
+	if hB.RecordOutcome(stranger, core.TrustOutcome{Capability: "ocr", Accepted: true}) == 0 {
+		t.Fatal("no trust recorded for the stranger")
+	}
+	exported := func(did string) bool {
+		var b strings.Builder
+		_ = hB.Metrics().WriteText(&b)
+		return strings.Contains(b.String(), `asp_peer_trust{did="`+did+`"}`)
+	}
+	if !exported(alpha.DID.String()) || exported(stranger) {
+		t.Errorf("alpha exported: %v, stranger exported: %v; want true, false",
+			exported(alpha.DID.String()), exported(stranger))
+	}
+
+	_ = hA.Close()
+	for exported(alpha.DID.String()) {
+		select {
+		case <-ctx.Done():
+			t.Fatal("trust in a disconnected peer still exported")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,353 @@
+package p2p
+
+// mux.go — Multiplexed intent sessions.
+//
+// By default SendIntent opens a fresh libp2p stream per request.  With
+// WithMultiplexing the host instead keeps one long-lived stream per peer on
+// MuxProtocol and runs many intents over it concurrently.  Each frame carries
+// a request ID so responses can arrive in any order:
+//
+//	[4-byte big-endian length] [8-byte request ID] [1-byte type] [payload]
+//
+// The type byte carries the codec as in ordinary frames (core.FrameType).
+// Frames are written in priority order (see sched.go), so responses and
+// control messages overtake queued bulk frames.
+//
+// A reply with MessageType 0 and no payload tells the caller the peer dropped
+// the request (bad signature, stale timestamp, no handler).  Both sides bound
+// the number of requests in flight; callers block once the limit is reached.
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+// MuxProtocol is the libp2p protocol identifier for multiplexed sessions.
+const MuxProtocol protocol.ID = "/agent-semantic-protocol/mux/1.0.0"
+
+const (
+	// DefaultMaxInflight is the per-session request limit used when
+	// WithMultiplexing is given 0.
+	DefaultMaxInflight = 64
+	// DefaultRequestTimeout bounds one multiplexed request when
+	// WithMultiplexing is given 0 and ctx has no earlier deadline.
+	DefaultRequestTimeout = 30 * time.Second
+)
+
+// muxDropped is the reply type for requests the peer did not answer.
+const muxDropped core.MessageType = 0
+
+// ErrSessionClosed is returned to in-flight requests when their session's
+// stream breaks or the host shuts down.
+var ErrSessionClosed = fmt.Errorf("p2p mux: session closed")
+
+// WithMultiplexing makes SendIntent share one stream per peer, with at most
+// maxInflight concurrent requests and a per-request timeout.  Zero values
+// select DefaultMaxInflight and DefaultRequestTimeout.  Peers that do not
+// speak MuxProtocol are still served over per-request streams.
+func WithMultiplexing(maxInflight int, requestTimeout time.Duration) HostOption {
+	return func(ah *AgentHost) {
+		ah.muxEnabled = true
+		ah.muxInflight = maxInflight
+		ah.muxTimeout = requestTimeout
+	}
+}
+
+// muxSession is the initiator side of one multiplexed stream.
+type muxSession struct {
+	stream  network.Stream
+	timeout time.Duration
+	slots   chan struct{} // one token per request in flight
+	out     *frameScheduler
+	limits  core.DecodeLimits // the host's, for replies
+
+	mu      sync.Mutex
+	nextID  uint64
+	pending map[uint64]chan muxReply
+	err     error         // set once the session is closed
+	done    chan struct{} // closed with err
+}
+
+type muxReply struct {
+	msgType core.MessageType
+	data    []byte
+}
+
+// sendIntentMux sends intent over the shared session with peerID.  ok is
+// false if no session could be opened, in which case the caller should fall
+// back to a per-request stream.
+func (ah *AgentHost) sendIntentMux(
+	ctx context.Context,
+	peerID peer.ID,
+	intent *core.IntentMessage,
+) (resp *core.NegotiationResponse, ok bool, err error) {
+	sess, err := ah.session(ctx, peerID)
+	if err != nil {
+		return nil, false, nil
+	}
+	sent := time.Now()
+	msgType, data, err := sess.roundTrip(ctx, ah.PeerCodec(peerID), intent)
+	if err != nil {
+		return nil, true, fmt.Errorf("p2p intent: %w", err)
+	}
+	if msgType == muxDropped {
+		return nil, true, fmt.Errorf("p2p intent: %s dropped the request", peerID)
+	}
+	resp, err = ah.acceptResponse(peerID, intent, sent, msgType, data)
+	return resp, true, err
+}
+
+// session returns the open session with peerID, creating it if needed.
+// The stream is opened without holding sessMu, so a slow dial does not
+// hold up requests to other peers.
+func (ah *AgentHost) session(ctx context.Context, peerID peer.ID) (*muxSession, error) {
+	ah.sessMu.Lock()
+	sess, ok := ah.sessions[peerID]
+	ah.sessMu.Unlock()
+	if ok {
+		return sess, nil
+	}
+	stream, err := ah.newStream(ctx, peerID, ah.muxProto)
+	if err != nil {
+		return nil, fmt.Errorf("p2p mux: open stream: %w", err)
+	}
+	limit, timeout := ah.muxInflight, ah.muxTimeout
+	if limit <= 0 {
+		limit = DefaultMaxInflight
+	}
+	if timeout <= 0 {
+		timeout = DefaultRequestTimeout
+	}
+	ah.sessMu.Lock()
+	defer ah.sessMu.Unlock()
+	if existing, ok := ah.sessions[peerID]; ok {
+		// A concurrent caller won the race; use its session.
+		_ = stream.Reset()
+		return existing, nil
+	}
+	sess = &muxSession{
+		stream:  stream,
+		timeout: timeout,
+		slots:   make(chan struct{}, limit),
+		out:     newFrameScheduler(stream, stream.SetWriteDeadline, timeout),
+		limits:  ah.limits,
+		pending: make(map[uint64]chan muxReply),
+		done:    make(chan struct{}),
+	}
+	ah.sessions[peerID] = sess
+	go func() {
+		sess.readLoop()
+		ah.sessMu.Lock()
+		if ah.sessions[peerID] == sess {
+			delete(ah.sessions, peerID)
+		}
+		ah.sessMu.Unlock()
+	}()
+	return sess, nil
+}
+
+// dropSession closes the session with peerID, if any, failing its requests
+// with ErrPeerDisconnected.
+func (ah *AgentHost) dropSession(peerID peer.ID) {
+	ah.sessMu.Lock()
+	sess, ok := ah.sessions[peerID]
+	delete(ah.sessions, peerID)
+	ah.sessMu.Unlock()
+	if ok {
+		sess.close(ErrPeerDisconnected)
+	}
+}
+
+// roundTrip sends msg, encoded with c, as a new request and waits for its
+// reply.
+func (s *muxSession) roundTrip(ctx context.Context, c core.Codec, msg core.Encoder) (core.MessageType, []byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	// Backpressure: wait for a free slot.
+	select {
+	case s.slots <- struct{}{}:
+	case <-ctx.Done():
+		return 0, nil, ctx.Err()
+	case <-s.done:
+		return 0, nil, s.err
+	}
+	defer func() { <-s.slots }()
+
+	reply := make(chan muxReply, 1)
+	s.mu.Lock()
+	if s.err != nil {
+		s.mu.Unlock()
+		return 0, nil, s.err
+	}
+	s.nextID++
+	id := s.nextID
+	s.pending[id] = reply
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.pending, id)
+		s.mu.Unlock()
+	}()
+
+	payload, err := c.Marshal(msg)
+	if err != nil {
+		return 0, nil, err
+	}
+	frame := muxFrame(id, core.FrameType(msg.MsgType(), c.ID()), payload)
+	if err = s.out.write(ClassifyFrame(msg, len(payload)), frame); err != nil {
+		s.close(fmt.Errorf("p2p mux: send: %w", err))
+		return 0, nil, s.err
+	}
+
+	select {
+	case r := <-reply:
+		return r.msgType, r.data, nil
+	case <-ctx.Done():
+		return 0, nil, ctx.Err()
+	case <-s.done:
+		return 0, nil, s.err
+	}
+}
+
+// readLoop delivers replies to their waiting requests until the stream
+// fails.  Each request takes one reply: replies to requests that already
+// timed out or were already answered are discarded.
+func (s *muxSession) readLoop() {
+	for {
+		id, msgType, data, err := readMuxFrame(s.stream, s.limits)
+		if err != nil {
+			s.close(ErrSessionClosed)
+			return
+		}
+		s.mu.Lock()
+		reply, ok := s.pending[id]
+		delete(s.pending, id)
+		s.mu.Unlock()
+		if ok {
+			reply <- muxReply{msgType: msgType, data: data} // buffered; never blocks
+		}
+	}
+}
+
+// close fails every pending request with err and resets the stream.
+func (s *muxSession) close(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return
+	}
+	s.err = err
+	close(s.done)
+	s.out.close(err)
+	_ = s.stream.Reset()
+}
+
+// ------------------------------------------------------------------ responder side
+
+// handleMuxStream serves requests arriving on a multiplexed stream, running
+// up to the host's in-flight limit concurrently.
+func (ah *AgentHost) handleMuxStream(s network.Stream) {
+	defer s.Close()
+	from := s.Conn().RemotePeer()
+	limit := ah.muxInflight
+	if limit <= 0 {
+		limit = DefaultMaxInflight
+	}
+	slots := make(chan struct{}, limit)
+	out := newFrameScheduler(s, s.SetWriteDeadline, 30*time.Second)
+	defer out.close(ErrSessionClosed)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		id, msgType, data, err := readMuxFrame(s, ah.limits)
+		if err != nil {
+			return
+		}
+		var pending *pendingEntry
+		if msgType == core.MsgIntent {
+			pending = ah.queueIntent(from, data)
+		}
+		select {
+		case slots <- struct{}{}:
+		case <-ah.done:
+			ah.finishIntent(pending)
+			return
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			defer ah.finishIntent(pending)
+
+			var resp *core.NegotiationResponse
+			if msgType == core.MsgIntent && ah.inspect(from, SourceMux, msgType, data) {
+				if in, ok := ah.admitIntent(from, data); ok {
+					ah.keepForRelay(pending, from, data)
+					resp = ah.answerIntent(from, in, pending)
+				}
+			}
+
+			replyType, payload, class := byte(muxDropped), []byte(nil), ClassControl
+			if resp != nil {
+				c := ah.PeerCodec(from)
+				b, encErr := c.Marshal(resp)
+				if encErr != nil {
+					return
+				}
+				replyType, payload = core.FrameType(resp.MsgType(), c.ID()), b
+				class = ClassifyFrame(resp, len(b))
+			}
+			werr := out.write(class, muxFrame(id, replyType, payload))
+			if werr == nil && resp != nil {
+				ah.applySenderTrust(from, resp.TrustDelta)
+			}
+		}()
+	}
+}
+
+// ------------------------------------------------------------------ wire I/O
+
+// muxFrame builds a multiplexed frame.
+func muxFrame(id uint64, frameType byte, payload []byte) []byte {
+	buf := make([]byte, 4+8+1+len(payload))
+	binary.BigEndian.PutUint32(buf, uint32(8+1+len(payload)))
+	binary.BigEndian.PutUint64(buf[4:], id)
+	buf[12] = frameType
+	copy(buf[13:], payload)
+	return buf
+}
+
+func readMuxFrame(r io.Reader, limits core.DecodeLimits) (uint64, core.MessageType, []byte, error) {
+	var hdr [4]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return 0, 0, nil, fmt.Errorf("readMuxFrame header: %w", err)
+	}
+	n := int(binary.BigEndian.Uint32(hdr[:]))
+	if n < 9 {
+		return 0, 0, nil, fmt.Errorf("readMuxFrame: invalid length %d", n)
+	}
+	if n > core.MaxFrameSize {
+		return 0, 0, nil, fmt.Errorf("readMuxFrame: %w: length %d", core.ErrFrameTooLarge, n)
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, 0, nil, fmt.Errorf("readMuxFrame body: %w", err)
+	}
+	msgType, codec := core.SplitFrameType(body[8])
+	payload, err := limits.Transcode(codec, msgType, body[9:])
+	if err != nil {
+		return 0, 0, nil, fmt.Errorf("readMuxFrame: %w", err)
+	}
+	return binary.BigEndian.Uint64(body), msgType, payload, nil
+}
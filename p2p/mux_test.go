@@ -0,0 +1,135 @@
+package p2p_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+// TestMultiplexedIntents verifies that concurrent intents over one session
+// stream are each matched to their own response, with an in-flight limit
+// smaller than the number of callers.
+func TestMultiplexedIntents(t *testing.T) {
+	alpha := makeAgent(t, "alpha", []string{"nlp"})
+	beta := makeAgent(t, "beta", []string{"summarisation"})
+
+	hA, err := p2p.NewHost(context.Background(), alpha, p2p.WithMultiplexing(4, 2*time.Second))
+	if err != nil {
+		t.Fatalf("NewHost: %v", err)
+	}
+	t.Cleanup(func() { _ = hA.Close() })
+	hB := makeHost(t, beta)
+	hB.OnIntent(func(_ peer.ID, in *core.IntentMessage) *core.NegotiationResponse {
+		time.Sleep(20 * time.Millisecond)
+		resp, _ := core.DefaultNegotiationHandler(beta)(in)
+		resp.Reason = in.Payload
+		_ = core.SignResponse(beta, resp)
+		return resp
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err = hA.Connect(ctx, hB.AddrInfo()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+
+	const n = 16
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			payload := fmt.Sprintf("request-%d", i)
+			intent, err := core.CreateIntent(alpha, []float32{0.9, 0.1}, []string{"summarisation"}, payload)
+			if err != nil {
+				errs <- err
+				return
+			}
+			resp, err := hA.SendIntent(ctx, hB.PeerID(), intent)
+			switch {
+			case err != nil:
+				errs <- err
+			case resp.RequestID != intent.ID || resp.Reason != payload:
+				errs <- fmt.Errorf("request %d got response for %s (%q)", i, resp.RequestID, resp.Reason)
+			default:
+				errs <- nil
+			}
+		}(i)
+	}
+	for i := 0; i < n; i++ {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+// TestMultiplexedDuplicateReplies verifies that a peer answering one request
+// several times does not stall the session for later requests.
+func TestMultiplexedDuplicateReplies(t *testing.T) {
+	alpha := makeAgent(t, "alpha", []string{"nlp"})
+	beta := makeAgent(t, "beta", []string{"summarisation"})
+
+	hA, err := p2p.NewHost(context.Background(), alpha, p2p.WithMultiplexing(4, 2*time.Second))
+	if err != nil {
+		t.Fatalf("NewHost: %v", err)
+	}
+	t.Cleanup(func() { _ = hA.Close() })
+
+	// A bare libp2p host that sends every reply three times.
+	raw, err := libp2p.New(libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	if err != nil {
+		t.Fatalf("libp2p.New: %v", err)
+	}
+	t.Cleanup(func() { _ = raw.Close() })
+	raw.SetStreamHandler(p2p.MuxProtocol, func(s network.Stream) {
+		defer s.Close()
+		for {
+			var hdr [4]byte
+			if _, err := io.ReadFull(s, hdr[:]); err != nil {
+				return
+			}
+			body := make([]byte, binary.BigEndian.Uint32(hdr[:]))
+			if _, err := io.ReadFull(s, body); err != nil {
+				return
+			}
+			in, err := core.DecodeIntentMessage(body[9:])
+			if err != nil {
+				return
+			}
+			resp, _ := core.DefaultNegotiationHandler(beta)(in)
+			payload, _ := resp.Encode()
+			frame := make([]byte, 13+len(payload))
+			binary.BigEndian.PutUint32(frame, uint32(9+len(payload)))
+			copy(frame[4:12], body[:8])
+			frame[12] = core.FrameType(resp.MsgType(), core.CodecProtobuf)
+			copy(frame[13:], payload)
+			if _, err := s.Write(bytes.Repeat(frame, 3)); err != nil {
+				return
+			}
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err = hA.Connect(ctx, peer.AddrInfo{ID: raw.ID(), Addrs: raw.Addrs()}); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		intent, _ := core.CreateIntent(alpha, []float32{0.9, 0.1}, []string{"summarisation"}, fmt.Sprintf("request-%d", i))
+		resp, err := hA.SendIntent(ctx, raw.ID(), intent)
+		if err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+		if resp.RequestID != intent.ID {
+			t.Errorf("request %d got response for %s", i, resp.RequestID)
+		}
+	}
+}
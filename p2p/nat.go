@@ -0,0 +1,140 @@
+package p2p
+
+// nat.go — Reaching agents behind NATs.
+//
+// An agent on a home or corporate network cannot accept connections, so
+// its peers cannot handshake with it.  Three libp2p mechanisms fix that:
+//
+//   - AutoNAT (WithAutoNAT) asks peers to dial the host back, so it learns
+//     whether it is publicly reachable, and tries to map a port on the
+//     gateway (UPnP, NAT-PMP).  It also answers other hosts' probes.
+//   - Circuit relay v2: a publicly reachable host started WithRelayService
+//     forwards connections for others.  A host given relays with
+//     WithRelays reserves a slot on them once AutoNAT finds it private,
+//     and advertises the relayed addresses in AddrInfo.
+//   - Hole punching (WithHolePunching) upgrades a relayed connection to a
+//     direct one when both NATs allow it.
+//
+// Relayed connections are limited in duration and data, which is plenty
+// for handshakes and negotiation; the host opens protocol streams over them
+// as over any other connection.  These options have no effect on a host
+// attached to a MemoryNetwork.
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/core/event"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// WithAutoNAT makes the host probe its own reachability, map a port on its
+// gateway where it can, and serve reachability probes for its peers.
+// Reachability reports the result, and changes are emitted as
+// EventReachabilityChanged.
+func WithAutoNAT() HostOption {
+	return func(ah *AgentHost) { ah.autoNAT = true }
+}
+
+// WithHolePunching makes the host try to replace relayed connections with
+// direct ones.
+func WithHolePunching() HostOption {
+	return func(ah *AgentHost) { ah.holePunch = true }
+}
+
+// WithRelayService makes the host a circuit relay for other hosts.  Only a
+// publicly reachable host is useful as one.
+func WithRelayService() HostOption {
+	return func(ah *AgentHost) { ah.relayService = true }
+}
+
+// WithRelays makes the host reserve a slot on relays whenever it is not
+// publicly reachable, so peers can dial it through them.  It implies
+// WithAutoNAT, which tells the host when it needs to.
+func WithRelays(relays ...peer.AddrInfo) HostOption {
+	return func(ah *AgentHost) {
+		ah.relays = append(ah.relays, relays...)
+		ah.autoNAT = true
+	}
+}
+
+// natOptions returns the libp2p options for the host's NAT settings.
+func (ah *AgentHost) natOptions() []libp2p.Option {
+	var opts []libp2p.Option
+	if ah.autoNAT {
+		opts = append(opts, libp2p.NATPortMap(), libp2p.EnableNATService(), libp2p.EnableAutoNATv2())
+	}
+	if ah.holePunch {
+		opts = append(opts, libp2p.EnableHolePunching())
+	}
+	if ah.relayService {
+		opts = append(opts, libp2p.EnableRelayService())
+	}
+	if len(ah.relays) > 0 {
+		opts = append(opts, libp2p.EnableRelay(), libp2p.EnableAutoRelayWithStaticRelays(ah.relays))
+	}
+	return opts
+}
+
+// Reachability returns whether AutoNAT found the host publicly reachable.
+// It is network.ReachabilityUnknown without WithAutoNAT, and until the
+// first probes complete.
+func (ah *AgentHost) Reachability() network.Reachability {
+	ah.mu.RLock()
+	defer ah.mu.RUnlock()
+	return ah.reachability
+}
+
+// watchReachability follows AutoNAT's verdicts until the host closes.
+func (ah *AgentHost) watchReachability() error {
+	sub, err := ah.h.EventBus().Subscribe(new(event.EvtLocalReachabilityChanged))
+	if err != nil {
+		return fmt.Errorf("p2p: autonat: %w", err)
+	}
+	go func() {
+		defer sub.Close()
+		for {
+			select {
+			case <-ah.done:
+				return
+			case e, ok := <-sub.Out():
+				if !ok {
+					return
+				}
+				r := e.(event.EvtLocalReachabilityChanged).Reachability
+				ah.mu.Lock()
+				ah.reachability = r
+				ah.mu.Unlock()
+				ah.emit(Event{Kind: EventReachabilityChanged, PeerID: ah.PeerID(), Reason: r.String()})
+			}
+		}
+	}()
+	return nil
+}
+
+// RelayAddrInfo returns the addresses at which target can be dialled
+// through relay, for a target behind a NAT whose own addresses are
+// unknown or unreachable.  target must hold a reservation on relay.
+func RelayAddrInfo(relay peer.AddrInfo, target peer.ID) (peer.AddrInfo, error) {
+	info := peer.AddrInfo{ID: target}
+	for _, a := range relay.Addrs {
+		circuit, err := ma.NewMultiaddr(fmt.Sprintf("%s/p2p/%s/p2p-circuit", a, relay.ID))
+		if err != nil {
+			return peer.AddrInfo{}, fmt.Errorf("p2p: relay address: %w", err)
+		}
+		info.Addrs = append(info.Addrs, circuit)
+	}
+	if len(info.Addrs) == 0 {
+		return peer.AddrInfo{}, fmt.Errorf("p2p: relay %s has no addresses", relay.ID)
+	}
+	return info, nil
+}
+
+// allowRelayed lets protocol streams use relayed connections, which libp2p
+// otherwise reserves for hole punching.
+func allowRelayed(ctx context.Context) context.Context {
+	return network.WithAllowLimitedConn(ctx, string(AgentSemanticProtocol))
+}
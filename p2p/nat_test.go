@@ -0,0 +1,44 @@
+package p2p_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+func TestNATOptions(t *testing.T) {
+	relay, err := p2p.NewHost(context.Background(), makeAgent(t, "relay", nil), p2p.WithRelayService(), p2p.WithAutoNAT())
+	if err != nil {
+		t.Fatalf("NewHost relay: %v", err)
+	}
+	t.Cleanup(func() { _ = relay.Close() })
+	h, err := p2p.NewHost(context.Background(), makeAgent(t, "behind-nat", nil),
+		p2p.WithRelays(relay.AddrInfo()), p2p.WithHolePunching())
+	if err != nil {
+		t.Fatalf("NewHost with relays: %v", err)
+	}
+	t.Cleanup(func() { _ = h.Close() })
+	if r := makeHost(t, makeAgent(t, "plain", nil)).Reachability(); r != network.ReachabilityUnknown {
+		t.Errorf("Reachability without AutoNAT = %v; want unknown", r)
+	}
+
+	info, err := p2p.RelayAddrInfo(relay.AddrInfo(), h.PeerID())
+	if err != nil {
+		t.Fatalf("RelayAddrInfo: %v", err)
+	}
+	if info.ID != h.PeerID() || len(info.Addrs) != len(relay.AddrInfo().Addrs) {
+		t.Fatalf("RelayAddrInfo = %v", info)
+	}
+	for _, a := range info.Addrs {
+		if !strings.HasSuffix(a.String(), "/p2p/"+relay.PeerID().String()+"/p2p-circuit") {
+			t.Errorf("relay address %s", a)
+		}
+	}
+	if _, err = p2p.RelayAddrInfo(peer.AddrInfo{ID: relay.PeerID()}, h.PeerID()); err == nil {
+		t.Error("RelayAddrInfo accepted a relay without addresses")
+	}
+}
@@ -0,0 +1,237 @@
+package p2p
+
+// observe.go — Read-only observers.
+//
+// A monitoring agent can watch a host's events (see events.go) over
+// ObserverProtocol without being able to act in the mesh.  WithObservers
+// grants DIDs the observer role.  An observer handshakes with the host,
+// which binds its DID to its peer ID, then opens a stream and sends an
+// ObserveRequest naming the event kinds it wants.  The host answers with a
+// NegotiationResponse.  If it accepts, EventMessages follow until either
+// side closes the stream.
+//
+// The role grants sight, not execution rights: the host rejects intents
+// from an observer DID with core.ReasonObserverOnly and ignores its
+// workflow steps.  An observer that reads too slowly loses events rather
+// than stall the host.  The next event it receives then counts the loss in
+// Dropped.
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+// ObserverProtocol is the libp2p protocol identifier for observer event
+// streams.
+const ObserverProtocol protocol.ID = "/agent-semantic-protocol/observe/1.0.0"
+
+// observerBuffer is how many events an observer may fall behind by before
+// events are dropped.
+const observerBuffer = 256
+
+// ErrObserveRefused is returned (wrapped) by Observe when the peer refuses
+// the subscription.
+var ErrObserveRefused = fmt.Errorf("p2p: observation refused")
+
+// WithObservers grants the observer role to the given DIDs.  They may
+// subscribe to the host's events with Observe, but their intents are
+// rejected.  Without observers the host does not serve ObserverProtocol.
+func WithObservers(dids ...string) HostOption {
+	return func(ah *AgentHost) {
+		if ah.observers == nil {
+			ah.observers = make(map[string]bool, len(dids))
+		}
+		for _, did := range dids {
+			ah.observers[did] = true
+		}
+	}
+}
+
+// Observe subscribes to the events of peerID, which must have granted this
+// host's DID the observer role and completed a handshake with it.  Events
+// of the given kinds, or of every kind if none are given, are delivered on
+// the returned channel.  The channel is closed when ctx is cancelled or the
+// stream ends.  Refusals wrap ErrObserveRefused.
+func (ah *AgentHost) Observe(ctx context.Context, peerID peer.ID, kinds ...EventKind) (<-chan Event, error) {
+	stream, err := ah.newStream(ctx, peerID, ah.observeProto)
+	if err != nil {
+		return nil, fmt.Errorf("p2p observe: %w", err)
+	}
+	stop := context.AfterFunc(ctx, func() { _ = stream.Reset() })
+	req := &core.ObserveRequest{}
+	for _, k := range kinds {
+		req.Kinds = append(req.Kinds, string(k))
+	}
+	resp, err := ah.requestObserve(stream, req)
+	if err != nil {
+		stop()
+		_ = stream.Reset()
+		if ctx.Err() != nil {
+			err = context.Cause(ctx)
+		}
+		return nil, fmt.Errorf("p2p observe: %w", err)
+	}
+	if !resp.Accepted {
+		stop()
+		_ = stream.Reset()
+		return nil, fmt.Errorf("p2p observe: %w: %s", ErrObserveRefused, resp.Reason)
+	}
+
+	events := make(chan Event, observerBuffer)
+	go func() {
+		defer close(events)
+		defer func() {
+			stop()
+			_ = stream.Reset()
+		}()
+		for {
+			msgType, data, err := ah.readMsg(stream)
+			if err != nil {
+				return
+			}
+			if msgType != core.MsgEvent {
+				continue
+			}
+			m, err := ah.limits.DecodeEventMessage(data)
+			if err != nil {
+				return
+			}
+			select {
+			case events <- eventFromMessage(m):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
+// requestObserve sends req on stream and reads the host's answer.
+func (ah *AgentHost) requestObserve(stream network.Stream, req *core.ObserveRequest) (*core.NegotiationResponse, error) {
+	if err := writeMsg(stream, core.ProtobufCodec, req); err != nil {
+		return nil, err
+	}
+	msgType, data, err := ah.readMsg(stream)
+	if err != nil {
+		return nil, err
+	}
+	if msgType != core.MsgNegotiation {
+		return nil, fmt.Errorf("unexpected message type 0x%02x", msgType)
+	}
+	return ah.limits.DecodeNegotiationResponse(data)
+}
+
+// handleObserveStream serves one observer until it goes away or the host
+// closes.
+func (ah *AgentHost) handleObserveStream(s network.Stream) {
+	defer s.Close()
+	from := s.Conn().RemotePeer()
+	msgType, data, err := ah.readMsg(s)
+	if err != nil || msgType != core.MsgObserve {
+		_ = s.Reset()
+		return
+	}
+	req, err := ah.limits.DecodeObserveRequest(data)
+	if err != nil {
+		ah.peerError(from, core.PeerErrorDecode)
+		_ = s.Reset()
+		return
+	}
+
+	ah.mu.RLock()
+	profile, known := ah.known[from.String()]
+	ah.mu.RUnlock()
+	resp := &core.NegotiationResponse{
+		AgentID:   ah.agent.ID,
+		DID:       ah.agent.DID.String(),
+		Accepted:  known && ah.observers[profile.DID],
+		Timestamp: time.Now().UnixNano(),
+	}
+	if !resp.Accepted {
+		resp.Reason = core.ReasonNotObserver
+	}
+	if err = writeMsg(s, core.ProtobufCodec, resp); err != nil || !resp.Accepted {
+		return
+	}
+
+	kinds := make([]EventKind, len(req.Kinds))
+	for i, k := range req.Kinds {
+		kinds[i] = EventKind(k)
+	}
+	events := make(chan Event, observerBuffer)
+	var dropped atomic.Int64
+	unsubscribe := ah.Subscribe(func(ev Event) {
+		select {
+		case events <- ev:
+		default:
+			dropped.Add(1)
+		}
+	}, kinds...)
+	defer unsubscribe()
+
+	// The observer sends nothing more; EOF means it went away.
+	gone := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(io.Discard, s)
+		close(gone)
+	}()
+	for {
+		select {
+		case <-ah.done:
+			return
+		case <-gone:
+			return
+		case ev := <-events:
+			m := eventMessage(ev)
+			m.Dropped = dropped.Swap(0)
+			if err := writeMsg(s, core.ProtobufCodec, m); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// eventMessage converts ev to its wire form.
+func eventMessage(ev Event) *core.EventMessage {
+	m := &core.EventMessage{
+		Kind:         string(ev.Kind),
+		AgentID:      ev.AgentID,
+		DID:          ev.DID,
+		IntentID:     ev.IntentID,
+		Capabilities: ev.Capabilities,
+		Reason:       ev.Reason,
+		Delta:        ev.Delta,
+		Trust:        ev.Trust,
+		Timestamp:    ev.Timestamp.UnixNano(),
+	}
+	if ev.PeerID != "" {
+		m.PeerID = ev.PeerID.String()
+	}
+	return m
+}
+
+// eventFromMessage converts an EventMessage received from an observed host.
+func eventFromMessage(m *core.EventMessage) Event {
+	pid, _ := peer.Decode(m.PeerID)
+	return Event{
+		Kind:         EventKind(m.Kind),
+		PeerID:       pid,
+		AgentID:      m.AgentID,
+		DID:          m.DID,
+		IntentID:     m.IntentID,
+		Capabilities: m.Capabilities,
+		Reason:       m.Reason,
+		Delta:        m.Delta,
+		Trust:        m.Trust,
+		Timestamp:    time.Unix(0, m.Timestamp),
+		Dropped:      m.Dropped,
+	}
+}
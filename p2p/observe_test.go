@@ -0,0 +1,80 @@
+package p2p_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+// TestObserver verifies that an observer receives a host's events over
+// ObserverProtocol, cannot send it intents, and that other peers cannot
+// observe.
+func TestObserver(t *testing.T) {
+	watcher := makeAgent(t, "watcher", nil)
+	beta := makeAgent(t, "beta", []string{"ocr"})
+	gamma := makeAgent(t, "gamma", []string{"nlp"})
+
+	hW := makeHost(t, watcher)
+	hB, err := p2p.NewHost(context.Background(), beta, p2p.WithObservers(watcher.DID.String()))
+	if err != nil {
+		t.Fatalf("NewHost: %v", err)
+	}
+	t.Cleanup(func() { _ = hB.Close() })
+	hG := makeHost(t, gamma)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for _, h := range []*p2p.AgentHost{hW, hG} {
+		if _, err = p2p.DiscoverAndHandshake(ctx, h, hB.AddrInfo()); err != nil {
+			t.Fatalf("DiscoverAndHandshake: %v", err)
+		}
+	}
+
+	events, err := hW.Observe(ctx, hB.PeerID(), p2p.EventIntentReceived)
+	if err != nil {
+		t.Fatalf("Observe: %v", err)
+	}
+	intent, _ := core.CreateIntent(gamma, nil, []string{"ocr"}, "scan")
+	if _, err = hG.SendIntent(ctx, hB.PeerID(), intent); err != nil {
+		t.Fatalf("SendIntent: %v", err)
+	}
+	select {
+	case ev := <-events:
+		if ev.Kind != p2p.EventIntentReceived || ev.IntentID != intent.ID || ev.PeerID != hG.PeerID() || ev.DID != gamma.DID.String() {
+			t.Errorf("event: %+v", ev)
+		}
+	case <-ctx.Done():
+		t.Fatal("no event reached the observer")
+	}
+
+	// Observers see, but do not act.
+	own, _ := core.CreateIntent(watcher, nil, []string{"ocr"}, "scan")
+	resp, err := hW.SendIntent(ctx, hB.PeerID(), own)
+	if err != nil {
+		t.Fatalf("SendIntent: %v", err)
+	}
+	if resp.Accepted || resp.Reason != core.ReasonObserverOnly {
+		t.Errorf("observer intent: %+v", resp)
+	}
+
+	if _, err = hG.Observe(ctx, hB.PeerID()); !errors.Is(err, p2p.ErrObserveRefused) {
+		t.Errorf("Observe by a non-observer: got %v, want ErrObserveRefused", err)
+	}
+	if _, err = hB.Observe(ctx, hG.PeerID()); err == nil {
+		t.Error("Observe succeeded against a host without observers")
+	}
+
+	// Cancelling the subscription closes the channel.
+	obsCtx, stop := context.WithCancel(ctx)
+	events, err = hW.Observe(obsCtx, hB.PeerID())
+	if err != nil {
+		t.Fatalf("Observe: %v", err)
+	}
+	stop()
+	for range events {
+	}
+}
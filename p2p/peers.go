@@ -0,0 +1,290 @@
+package p2p
+
+// peers.go — Keeping connections to configured peers alive.
+//
+// A PeerManager owns a list of peers the host should always be connected
+// to, typically its bootstrap peers.  For each one it dials and handshakes,
+// watches for the connection to drop, and re-dials with exponential backoff
+// until it is back, handshaking again after every reconnect so the peer's
+// profile is fresh.  Changes are reported as PeerEvents.
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// PeerEvent kinds.
+const (
+	PeerConnected    = "connected"    // dialled (or reconnected) and handshaked
+	PeerDisconnected = "disconnected" // the last connection closed
+	PeerDialFailed   = "dial_failed"  // a (re)connect attempt failed; Err says why
+	PeerRemoved      = "removed"      // Remove was called
+)
+
+// PeerEvent reports a change in a managed peer's connection.
+type PeerEvent struct {
+	Kind      string
+	PeerID    peer.ID
+	AgentID   string // Known after the first handshake
+	Attempt   int    // For PeerDialFailed: consecutive failures so far
+	Err       error
+	Timestamp time.Time
+}
+
+// PeerManagerConfig configures a PeerManager.  Zero durations select the
+// DefaultRedialBackoff, DefaultRedialMaxBackoff and
+// DefaultPeerCheckInterval.
+type PeerManagerConfig struct {
+	Bootstrap     []peer.AddrInfo
+	Backoff       time.Duration
+	MaxBackoff    time.Duration
+	CheckInterval time.Duration // How often connections are checked between notifications
+	DialTimeout   time.Duration // Bounds one connect+handshake; 0 means MaxBackoff
+	OnEvent       func(PeerEvent)
+}
+
+// DefaultPeerCheckInterval is how often a PeerManager checks its peers'
+// connections when no disconnect notification arrives.
+const DefaultPeerCheckInterval = 5 * time.Second
+
+// WithPeerManager starts a PeerManager for the host once it is listening.
+// Retrieve it with PeerManager to add or remove peers.
+func WithPeerManager(cfg PeerManagerConfig) HostOption {
+	return func(ah *AgentHost) { ah.peerCfg = &cfg }
+}
+
+// PeerManager returns the host's PeerManager, or nil without
+// WithPeerManager.
+func (ah *AgentHost) PeerManager() *PeerManager { return ah.peers }
+
+// PeerManager keeps the host connected to a set of peers.
+type PeerManager struct {
+	host *AgentHost
+	cfg  PeerManagerConfig
+	bus  *network.NotifyBundle
+
+	mu     sync.Mutex
+	peers  map[peer.ID]*managedPeer
+	closed bool
+	wg     sync.WaitGroup
+	done   chan struct{}
+}
+
+type managedPeer struct {
+	info       peer.AddrInfo
+	agentID    string
+	handshaked bool
+	wake       chan struct{} // poked when the peer disconnects
+	stop       chan struct{} // closed by Remove
+}
+
+// ManagedPeer is the state of one managed peer (see PeerManager.Peers).
+type ManagedPeer struct {
+	PeerID    peer.ID
+	AgentID   string
+	Connected bool
+}
+
+// NewPeerManager starts managing cfg.Bootstrap for host.  Call Close to
+// stop; closing the host stops it too.
+func NewPeerManager(host *AgentHost, cfg PeerManagerConfig) *PeerManager {
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = DefaultRedialBackoff
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = DefaultRedialMaxBackoff
+	}
+	if cfg.CheckInterval <= 0 {
+		cfg.CheckInterval = DefaultPeerCheckInterval
+	}
+	if cfg.DialTimeout <= 0 {
+		cfg.DialTimeout = cfg.MaxBackoff
+	}
+	m := &PeerManager{
+		host:  host,
+		cfg:   cfg,
+		peers: make(map[peer.ID]*managedPeer),
+		done:  make(chan struct{}),
+	}
+	m.bus = &network.NotifyBundle{
+		DisconnectedF: func(n network.Network, c network.Conn) {
+			if n.Connectedness(c.RemotePeer()) != network.Connected {
+				m.poke(c.RemotePeer())
+			}
+		},
+	}
+	host.h.Network().Notify(m.bus)
+	for _, info := range cfg.Bootstrap {
+		m.Add(info)
+	}
+	go func() {
+		select {
+		case <-host.done:
+			m.Close()
+		case <-m.done:
+		}
+	}()
+	return m
+}
+
+// Add starts managing info.  Adding a peer already managed is a no-op.
+func (m *PeerManager) Add(info peer.AddrInfo) {
+	if info.ID == m.host.h.ID() {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return
+	}
+	if _, ok := m.peers[info.ID]; ok {
+		return
+	}
+	mp := &managedPeer{info: info, wake: make(chan struct{}, 1), stop: make(chan struct{})}
+	m.peers[info.ID] = mp
+	m.wg.Add(1)
+	go m.run(mp)
+}
+
+// Remove stops managing id.  An open connection is left as it is.
+func (m *PeerManager) Remove(id peer.ID) {
+	m.mu.Lock()
+	mp, ok := m.peers[id]
+	delete(m.peers, id)
+	m.mu.Unlock()
+	if ok {
+		close(mp.stop)
+		m.emit(PeerEvent{Kind: PeerRemoved, PeerID: id})
+	}
+}
+
+// Peers returns the managed peers and whether each is connected.
+func (m *PeerManager) Peers() []ManagedPeer {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]ManagedPeer, 0, len(m.peers))
+	for id, mp := range m.peers {
+		out = append(out, ManagedPeer{
+			PeerID:    id,
+			AgentID:   mp.agentID,
+			Connected: mp.handshaked && m.host.h.Network().Connectedness(id) == network.Connected,
+		})
+	}
+	return out
+}
+
+// Close stops managing every peer and waits for the dial loops to exit.
+func (m *PeerManager) Close() {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return
+	}
+	m.closed = true
+	close(m.done)
+	m.mu.Unlock()
+	m.host.h.Network().StopNotify(m.bus)
+	m.wg.Wait()
+}
+
+func (m *PeerManager) poke(id peer.ID) {
+	m.mu.Lock()
+	mp, ok := m.peers[id]
+	m.mu.Unlock()
+	if !ok {
+		return
+	}
+	select {
+	case mp.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run keeps one peer connected until it is removed or the manager closes.
+func (m *PeerManager) run(mp *managedPeer) {
+	defer m.wg.Done()
+	wait := m.cfg.Backoff
+	attempt := 0
+	check := time.NewTicker(m.cfg.CheckInterval)
+	defer check.Stop()
+	for {
+		connected := m.host.h.Network().Connectedness(mp.info.ID) == network.Connected
+		if !connected && m.handshaked(mp) {
+			m.setHandshaked(mp, false, "")
+			m.emit(PeerEvent{Kind: PeerDisconnected, PeerID: mp.info.ID, AgentID: m.agentID(mp)})
+		}
+
+		var pause *time.Timer
+		if !connected || !m.handshaked(mp) {
+			ctx, cancel := context.WithTimeout(context.Background(), m.cfg.DialTimeout)
+			res, err := DiscoverAndHandshake(ctx, m.host, mp.info)
+			cancel()
+			if err == nil {
+				attempt, wait = 0, m.cfg.Backoff
+				m.setHandshaked(mp, true, res.PeerAgentID)
+				m.emit(PeerEvent{Kind: PeerConnected, PeerID: mp.info.ID, AgentID: res.PeerAgentID})
+			} else {
+				attempt++
+				m.emit(PeerEvent{Kind: PeerDialFailed, PeerID: mp.info.ID, AgentID: m.agentID(mp), Attempt: attempt, Err: err})
+				pause = time.NewTimer(wait)
+				if wait *= 2; wait > m.cfg.MaxBackoff {
+					wait = m.cfg.MaxBackoff
+				}
+			}
+		}
+
+		if pause != nil {
+			select {
+			case <-m.done:
+				pause.Stop()
+				return
+			case <-mp.stop:
+				pause.Stop()
+				return
+			case <-pause.C:
+			}
+			continue
+		}
+		select {
+		case <-m.done:
+			return
+		case <-mp.stop:
+			return
+		case <-mp.wake:
+		case <-check.C:
+		}
+	}
+}
+
+func (m *PeerManager) handshaked(mp *managedPeer) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return mp.handshaked
+}
+
+func (m *PeerManager) agentID(mp *managedPeer) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return mp.agentID
+}
+
+func (m *PeerManager) setHandshaked(mp *managedPeer, ok bool, agentID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	mp.handshaked = ok
+	if agentID != "" {
+		mp.agentID = agentID
+	}
+}
+
+func (m *PeerManager) emit(ev PeerEvent) {
+	if m.cfg.OnEvent == nil {
+		return
+	}
+	ev.Timestamp = time.Now()
+	m.cfg.OnEvent(ev)
+}
@@ -0,0 +1,62 @@
+package p2p_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+func TestPeerManagerReconnects(t *testing.T) {
+	beta := makeAgent(t, "beta", []string{"ocr"})
+	hB, err := p2p.NewHost(context.Background(), beta)
+	if err != nil {
+		t.Fatalf("NewHost: %v", err)
+	}
+	addr := hB.AddrInfo()
+
+	events := make(chan p2p.PeerEvent, 16)
+	hA, err := p2p.NewHost(context.Background(), makeAgent(t, "alpha", nil), p2p.WithPeerManager(p2p.PeerManagerConfig{
+		Bootstrap:     []peer.AddrInfo{addr},
+		Backoff:       50 * time.Millisecond,
+		MaxBackoff:    200 * time.Millisecond,
+		CheckInterval: 100 * time.Millisecond,
+		OnEvent: func(ev p2p.PeerEvent) {
+			if ev.Kind != p2p.PeerDialFailed {
+				events <- ev
+			}
+		},
+	}))
+	if err != nil {
+		t.Fatalf("NewHost: %v", err)
+	}
+	t.Cleanup(func() { _ = hA.Close() })
+
+	next := func(want string) {
+		t.Helper()
+		select {
+		case ev := <-events:
+			if ev.Kind != want || ev.PeerID != addr.ID {
+				t.Fatalf("event %+v, want %s", ev, want)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf("no %s event", want)
+		}
+	}
+	next(p2p.PeerConnected)
+	_ = hB.Close()
+	next(p2p.PeerDisconnected)
+
+	// Restart beta on the same address; the manager re-dials and handshakes.
+	hB, err = p2p.NewHost(context.Background(), beta, p2p.WithListenAddrs(addr.Addrs[0].String()))
+	if err != nil {
+		t.Fatalf("restart: %v", err)
+	}
+	t.Cleanup(func() { _ = hB.Close() })
+	next(p2p.PeerConnected)
+	if peers := hA.PeerManager().Peers(); len(peers) != 1 || !peers[0].Connected || peers[0].AgentID != "beta" {
+		t.Errorf("Peers() = %+v", peers)
+	}
+}
@@ -0,0 +1,176 @@
+package p2p
+
+// pending.go — Responder-side view of intents being served.
+//
+// The host tracks every intent from the moment it arrives until its reply
+// is written.  PendingIntents lists them with their ages: an intent is
+// queued while it waits for a free multiplexing slot and executing while
+// its handler runs.  RejectAll and Drain let an agent shed load explicitly
+// during overload or shutdown: new and still-queued intents are answered at
+// once with a signed rejection instead of timing out at the sender.
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+// PendingIntent states.
+const (
+	IntentQueued    = "queued"
+	IntentExecuting = "executing"
+)
+
+// DrainReason is the rejection reason used by Drain.
+const DrainReason = "agent is draining"
+
+// PendingIntent is one intent the host is serving.
+type PendingIntent struct {
+	IntentID     string
+	PeerID       peer.ID
+	DID          string
+	Capabilities []string
+	State        string
+	Received     time.Time
+	Started      time.Time // Zero while queued
+}
+
+// Age returns how long ago the intent arrived.
+func (p PendingIntent) Age() time.Duration { return time.Since(p.Received) }
+
+// pendingEntry is the tracked state behind a PendingIntent; guarded by
+// pendMu.
+type pendingEntry struct {
+	info  PendingIntent
+	relay *relaySource // nil: the intent is never relayed
+}
+
+// PendingIntents returns the intents being served, oldest first.
+func (ah *AgentHost) PendingIntents() []PendingIntent {
+	ah.pendMu.Lock()
+	defer ah.pendMu.Unlock()
+	out := make([]PendingIntent, 0, len(ah.pending))
+	for e := range ah.pending {
+		out = append(out, e.info)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Received.Before(out[j].Received) })
+	return out
+}
+
+// RejectAll answers every intent that arrives from now on, and every intent
+// still queued, with a rejection carrying reason.  Intents already executing
+// finish normally.  Call AcceptIntents to serve intents again.
+func (ah *AgentHost) RejectAll(reason string) {
+	ah.pendMu.Lock()
+	defer ah.pendMu.Unlock()
+	ah.rejecting = true
+	ah.rejectReason = reason
+}
+
+// AcceptIntents undoes RejectAll.
+func (ah *AgentHost) AcceptIntents() {
+	ah.pendMu.Lock()
+	defer ah.pendMu.Unlock()
+	ah.rejecting = false
+	ah.rejectReason = ""
+}
+
+// Drain rejects new intents with DrainReason and waits until every intent
+// already executing has been answered.  It returns ctx's error if that does
+// not happen in time; the host keeps rejecting either way.
+func (ah *AgentHost) Drain(ctx context.Context) error {
+	ah.pendMu.Lock()
+	ah.rejecting = true
+	ah.rejectReason = DrainReason
+	if len(ah.pending) == 0 {
+		ah.pendMu.Unlock()
+		return nil
+	}
+	idle := make(chan struct{})
+	ah.idle = append(ah.idle, idle)
+	ah.pendMu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// queueIntent starts tracking an intent that arrived on a multiplexed
+// stream and waits for a slot.  The message is decoded here only to list
+// it; admitIntent still checks it before it is served.  Returns nil if data
+// is not an intent.
+func (ah *AgentHost) queueIntent(from peer.ID, data []byte) *pendingEntry {
+	intent, err := ah.limits.DecodeIntentMessage(data)
+	if err != nil {
+		return nil
+	}
+	return ah.trackIntent(from, intent, IntentQueued)
+}
+
+func (ah *AgentHost) trackIntent(from peer.ID, intent *core.IntentMessage, state string) *pendingEntry {
+	now := time.Now()
+	e := &pendingEntry{info: PendingIntent{
+		IntentID:     intent.ID,
+		PeerID:       from,
+		DID:          intent.DID,
+		Capabilities: append([]string(nil), intent.Capabilities...),
+		State:        state,
+		Received:     now,
+	}}
+	if state == IntentExecuting {
+		e.info.Started = now
+	}
+	ah.pendMu.Lock()
+	ah.pending[e] = struct{}{}
+	ah.pendMu.Unlock()
+	return e
+}
+
+// startIntent moves e to executing, or reports the rejection reason if the
+// host is rejecting intents.
+func (ah *AgentHost) startIntent(e *pendingEntry) (reason string, reject bool) {
+	ah.pendMu.Lock()
+	defer ah.pendMu.Unlock()
+	if ah.rejecting {
+		return ah.rejectReason, true
+	}
+	e.info.State = IntentExecuting
+	e.info.Started = time.Now()
+	return "", false
+}
+
+// finishIntent stops tracking e and wakes Drain once nothing is pending.
+func (ah *AgentHost) finishIntent(e *pendingEntry) {
+	if e == nil {
+		return
+	}
+	ah.pendMu.Lock()
+	defer ah.pendMu.Unlock()
+	delete(ah.pending, e)
+	if len(ah.pending) == 0 {
+		for _, ch := range ah.idle {
+			close(ch)
+		}
+		ah.idle = nil
+	}
+}
+
+// rejection builds the signed refusal sent while the host is rejecting.
+func (ah *AgentHost) rejection(intent *core.IntentMessage, reason string) *core.NegotiationResponse {
+	resp := &core.NegotiationResponse{
+		RequestID: intent.ID,
+		AgentID:   ah.agent.ID,
+		Accepted:  false,
+		DID:       ah.agent.DID.String(),
+		Timestamp: time.Now().UnixNano(),
+		Reason:    reason,
+	}
+	_ = core.SignResponse(ah.agent, resp)
+	return resp
+}
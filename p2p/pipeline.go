@@ -0,0 +1,272 @@
+package p2p
+
+// pipeline.go — Sequential workflow execution with data passing.
+//
+// RunWorkflow fires every step at once.  RunSequential runs them one after
+// another, so a step can consume what earlier steps produced: its payload may
+// reference their results with templates such as
+//
+//	{{steps.step-1.output}}   output collected from step-1
+//	{{steps.step-1.reason}}   step-1's accept/reject reason
+//	{{steps.step-1.agent}}    agent ID that served step-1
+//
+// Steps are sent as streamed intents (see StreamIntent) and their output is
+// the concatenation of the responder's partial_output and step_completed
+// updates.  Each step may be retried, and its FailurePolicy decides whether
+// a final failure aborts the workflow.
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+// FailurePolicy says what RunSequential does once a step has failed and
+// exhausted its retries.
+type FailurePolicy int
+
+const (
+	// FailAbort stops the workflow; later steps are not run.  This is the
+	// default.
+	FailAbort FailurePolicy = iota
+	// FailContinue records the failure and moves on to the next step.
+	// RunGraph skips the step's dependents but runs unrelated branches.
+	FailContinue
+	// FailCompensate aborts like FailAbort, then runs the Compensate step of
+	// every step that had succeeded, most recent first, and returns a
+	// *RollbackError (see compensation.go).
+	FailCompensate
+)
+
+// String returns the policy name.
+func (p FailurePolicy) String() string {
+	switch p {
+	case FailAbort:
+		return "abort"
+	case FailContinue:
+		return "continue"
+	case FailCompensate:
+		return "compensate"
+	}
+	return fmt.Sprintf("FailurePolicy(%d)", int(p))
+}
+
+// stepTemplate matches {{steps.<id>.<field>}}.  Step IDs may not contain
+// dots, braces or whitespace.
+var stepTemplate = regexp.MustCompile(`\{\{\s*steps\.([^.{}\s]+)\.(output|reason|agent)\s*\}\}`)
+
+// ExpandStepTemplate substitutes the results of earlier steps into payload.
+// It fails if payload references a step that has no result yet.
+func ExpandStepTemplate(payload string, results map[string]StepResult) (string, error) {
+	var missing string
+	out := stepTemplate.ReplaceAllStringFunc(payload, func(m string) string {
+		sub := stepTemplate.FindStringSubmatch(m)
+		r, ok := results[sub[1]]
+		if !ok {
+			if missing == "" {
+				missing = sub[1]
+			}
+			return m
+		}
+		switch sub[2] {
+		case "output":
+			return r.Output
+		case "reason":
+			return r.Reason
+		default:
+			return r.AgentID
+		}
+	})
+	if missing != "" {
+		return "", fmt.Errorf("template references step %q, which has not run", missing)
+	}
+	return out, nil
+}
+
+// StepReferences returns the IDs of the steps payload references, in order
+// of first appearance.
+func StepReferences(payload string) []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, m := range stepTemplate.FindAllStringSubmatch(payload, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			out = append(out, m[1])
+		}
+	}
+	return out
+}
+
+// RunSequential runs steps one at a time, starting with steps[0].  After each
+// step it continues with the step named by NextStepID, or the following step
+// if that is empty; a step may run at most once.  Payloads are expanded with
+// ExpandStepTemplate before sending.
+//
+// Results are returned in execution order.  The error is non-nil if a
+// FailAbort or FailCompensate step failed or the step graph is invalid.
+func (o *WorkflowOrchestrator) RunSequential(
+	ctx context.Context,
+	workflowID string,
+	steps []WorkflowStep,
+) ([]StepResult, error) {
+	index := make(map[string]int, len(steps))
+	for i, s := range steps {
+		if _, dup := index[s.ID]; dup {
+			return nil, fmt.Errorf("workflow %q: duplicate step %q", workflowID, s.ID)
+		}
+		index[s.ID] = i
+	}
+	for _, s := range steps {
+		if _, ok := index[s.NextStepID]; s.NextStepID != "" && !ok {
+			return nil, fmt.Errorf("workflow %q: step %q: unknown next step %q", workflowID, s.ID, s.NextStepID)
+		}
+	}
+
+	state := &WorkflowState{WorkflowID: workflowID, Steps: steps, Status: WorkflowRunning}
+	if len(steps) > 0 {
+		state.Next = steps[0].ID
+	}
+	return o.runSequential(ctx, state)
+}
+
+// runSequential runs state's steps from state.Next on, checkpointing after
+// each one.
+func (o *WorkflowOrchestrator) runSequential(ctx context.Context, state *WorkflowState) ([]StepResult, error) {
+	steps := state.Steps
+	index := make(map[string]int, len(steps))
+	for i, s := range steps {
+		index[s.ID] = i
+	}
+
+	ctx, stop := context.WithCancel(ctx)
+	run := o.newRun(ctx, state.WorkflowID, steps, state)
+	var wg sync.WaitGroup
+	run.watch(ctx, &wg)
+	defer wg.Wait()
+	defer stop()
+
+	done := state.resultMap()
+	run.checkpoint()
+	for state.Next != "" {
+		i, ok := index[state.Next]
+		if !ok {
+			err := fmt.Errorf("workflow %q: unknown next step %q", state.WorkflowID, state.Next)
+			run.finish(err)
+			return state.Results, err
+		}
+		s := steps[i]
+		if _, ran := done[s.ID]; ran {
+			err := fmt.Errorf("workflow %q: step %q would run twice", state.WorkflowID, s.ID)
+			run.finish(err)
+			return state.Results, err
+		}
+
+		r, err := o.runStep(ctx, run, s, done)
+		if err != nil && ctx.Err() != nil {
+			// Cancelled, not failed: the step is run again on Resume.
+			return append(state.Results, r), fmt.Errorf("step %q: %w", s.ID, err)
+		}
+		done[s.ID] = r
+		state.Results = append(state.Results, r)
+		switch {
+		case s.NextStepID != "":
+			state.Next = s.NextStepID
+		case i+1 < len(steps):
+			state.Next = steps[i+1].ID
+		default:
+			state.Next = ""
+		}
+		switch {
+		case err == nil:
+		case s.OnFailure == FailCompensate:
+			err = fmt.Errorf("step %q: %w", s.ID, err)
+			state.Status, state.Next, state.Error = WorkflowCompensating, "", err.Error()
+			run.checkpoint()
+			return state.Results, o.compensate(ctx, run, state.succeeded(), done, err)
+		case s.OnFailure == FailAbort:
+			err = fmt.Errorf("step %q: %w", s.ID, err)
+			run.finish(err)
+			return state.Results, err
+		}
+		run.checkpoint()
+	}
+	run.finish(nil)
+	return state.Results, nil
+}
+
+// runStep expands s's payload and runs it as a streamed intent, retrying up
+// to s.Retries times.  A rejected step counts as a failure.
+func (o *WorkflowOrchestrator) runStep(
+	ctx context.Context,
+	run *workflowRun,
+	s WorkflowStep,
+	done map[string]StepResult,
+) (StepResult, error) {
+	payload, err := ExpandStepTemplate(s.Payload, done)
+	if err != nil {
+		return StepResult{StepID: s.ID, Reason: err.Error(), Timestamp: time.Now()}, err
+	}
+	s.Payload = payload
+
+	var r StepResult
+	for attempt := 0; attempt <= s.Retries; attempt++ {
+		if err = ctx.Err(); err != nil {
+			break
+		}
+		r, err = o.dispatchStep(ctx, run, s, o.streamStep)
+		if err == nil && !r.Accepted {
+			err = fmt.Errorf("rejected: %s", r.Reason)
+		}
+		if err == nil {
+			return r, nil
+		}
+	}
+	if r.StepID == "" {
+		r = StepResult{StepID: s.ID, Reason: err.Error(), Timestamp: time.Now()}
+	}
+	return r, err
+}
+
+// streamStep is the stepSender for streamed intents: it collects the
+// responder's output until it closes the stream.
+func (o *WorkflowOrchestrator) streamStep(
+	ctx context.Context,
+	peerID peer.ID,
+	step WorkflowStep,
+	intent *core.IntentMessage,
+) (StepResult, error) {
+	resp, updates, err := o.host.StreamIntent(ctx, peerID, intent)
+	if err != nil {
+		return StepResult{}, err
+	}
+	r := StepResult{
+		StepID:   step.ID,
+		AgentID:  resp.AgentID,
+		Accepted: resp.Accepted,
+		Reason:   resp.Reason,
+		Result:   resp.Result,
+	}
+	checkStepCost(intent, resp, &r)
+	var out strings.Builder
+	for u := range updates {
+		switch u.Action {
+		case core.ProgressPartialOutput, core.ProgressStepCompleted:
+			out.WriteString(u.Params[core.ProgressParamOutput])
+		case core.ProgressFailed:
+			r.Accepted = false
+			r.Reason = u.Params[core.ProgressParamError]
+		}
+	}
+	if err = ctx.Err(); err != nil {
+		return StepResult{}, err
+	}
+	r.Output = out.String()
+	r.Timestamp = time.Now()
+	return r, nil
+}
@@ -0,0 +1,20 @@
+package p2p_test
+
+import (
+	"testing"
+
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+func TestExpandStepTemplate(t *testing.T) {
+	done := map[string]p2p.StepResult{
+		"fetch": {StepID: "fetch", AgentID: "beta", Output: "raw text", Reason: "ok"},
+	}
+	got, err := p2p.ExpandStepTemplate("summarise {{steps.fetch.output}} from {{ steps.fetch.agent }}", done)
+	if err != nil || got != "summarise raw text from beta" {
+		t.Errorf("got %q, %v", got, err)
+	}
+	if _, err = p2p.ExpandStepTemplate("{{steps.later.output}}", done); err == nil {
+		t.Error("expected error for a step that has not run")
+	}
+}
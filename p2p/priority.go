@@ -0,0 +1,107 @@
+package p2p
+
+// priority.go — Passing a workflow's scheduling hints on to its steps.
+//
+// A workflow started under WithWorkflowOptions hands its priority, deadline
+// and budget down to every step intent it sends: each intent carries the
+// workflow's priority, is due and expires at the workflow's deadline, and
+// may spend its share of the budget, split by the steps' BudgetWeight.
+// Without an explicit deadline the context's deadline is used.  Plan shows
+// what each step will inherit before anything is sent.
+
+import (
+	"context"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+// WorkflowOptions are the scheduling hints a workflow passes to its steps.
+type WorkflowOptions struct {
+	Priority core.Priority
+	Deadline time.Time // Zero: the context's deadline, if any
+	Budget   float64   // Split across steps by BudgetWeight; 0 = no budget
+}
+
+// StepPlan is what one step inherits from its workflow.
+type StepPlan struct {
+	StepID     string
+	Capability string
+	Priority   core.Priority
+	Deadline   time.Time // Zero: none
+	Budget     float64   // The step's share; 0 when the workflow has none
+}
+
+type workflowOptionsKey struct{}
+
+// WithWorkflowOptions returns a context under which RunWorkflow,
+// RunSequential and RunGraph apply opts to their steps.
+func WithWorkflowOptions(ctx context.Context, opts WorkflowOptions) context.Context {
+	return context.WithValue(ctx, workflowOptionsKey{}, opts)
+}
+
+// workflowOptions returns the options in ctx, taking the deadline from ctx
+// if they set none.
+func workflowOptions(ctx context.Context) WorkflowOptions {
+	opts, _ := ctx.Value(workflowOptionsKey{}).(WorkflowOptions)
+	if d, ok := ctx.Deadline(); ok && opts.Deadline.IsZero() {
+		opts.Deadline = d
+	}
+	return opts
+}
+
+// Plan returns what each of steps would inherit if run under ctx, in the
+// order given.
+func (o *WorkflowOrchestrator) Plan(ctx context.Context, steps []WorkflowStep) []StepPlan {
+	opts := workflowOptions(ctx)
+	var total float64
+	for _, s := range steps {
+		total += s.budgetWeight()
+	}
+	plans := make([]StepPlan, len(steps))
+	for i, s := range steps {
+		plans[i] = StepPlan{
+			StepID:     s.ID,
+			Capability: s.Capability,
+			Priority:   opts.Priority,
+			Deadline:   opts.Deadline,
+		}
+		if opts.Budget > 0 && total > 0 {
+			plans[i].Budget = opts.Budget * s.budgetWeight() / total
+		}
+	}
+	return plans
+}
+
+func (s WorkflowStep) budgetWeight() float64 {
+	if s.BudgetWeight > 0 {
+		return s.BudgetWeight
+	}
+	return 1
+}
+
+// planFor returns the plan of step in run.  Steps outside the plan, such as
+// compensations, inherit priority and deadline but no budget.
+func (r *workflowRun) planFor(step WorkflowStep) StepPlan {
+	if p, ok := r.plan[step.ID]; ok {
+		return p
+	}
+	return StepPlan{
+		StepID:     step.ID,
+		Capability: step.Capability,
+		Priority:   r.opts.Priority,
+		Deadline:   r.opts.Deadline,
+	}
+}
+
+// applyPlan writes p into a step intent.
+func applyPlan(intent *core.IntentMessage, p StepPlan) {
+	intent.SetPriority(p.Priority)
+	if !p.Deadline.IsZero() {
+		intent.ExpiresAt = p.Deadline.UnixNano()
+		intent.Deadline = intent.ExpiresAt
+	}
+	if p.Budget > 0 {
+		intent.SetBudgetShare(p.Budget)
+	}
+}
@@ -0,0 +1,39 @@
+package p2p_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+// TestWorkflowPlan verifies that steps inherit the workflow's priority and
+// deadline and split its budget by weight.
+func TestWorkflowPlan(t *testing.T) {
+	o := p2p.NewOrchestrator(nil, time.Second)
+	deadline := time.Now().Add(time.Minute)
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+	ctx = p2p.WithWorkflowOptions(ctx, p2p.WorkflowOptions{Priority: core.PriorityHigh, Budget: 9})
+
+	plans := o.Plan(ctx, []p2p.WorkflowStep{
+		{ID: "fetch", Capability: "http"},
+		{ID: "ocr", Capability: "ocr", BudgetWeight: 2},
+	})
+	if len(plans) != 2 {
+		t.Fatalf("got %d plans", len(plans))
+	}
+	for _, p := range plans {
+		if p.Priority != core.PriorityHigh {
+			t.Errorf("%s: priority %v", p.StepID, p.Priority)
+		}
+		if !p.Deadline.Equal(deadline) {
+			t.Errorf("%s: deadline %v, want the context's", p.StepID, p.Deadline)
+		}
+	}
+	if plans[0].Budget != 3 || plans[1].Budget != 6 {
+		t.Errorf("budget shares %v and %v, want 3 and 6", plans[0].Budget, plans[1].Budget)
+	}
+}
@@ -0,0 +1,197 @@
+package p2p
+
+// private.go — Isolated meshes for provisioned agents.
+//
+// Connections between hosts are always encrypted and authenticated (Noise
+// or TLS 1.3), but any host can dial any other.  An enterprise mesh closes
+// that off in two layers:
+//
+//   - WithPrivateNetwork wraps every connection in a pre-shared key
+//     (libp2p private networks).  Hosts without the key cannot complete a
+//     connection at all, so they cannot even learn which protocols the
+//     mesh speaks.  Keys use the swarm.key format (LoadSwarmKey).
+//   - WithAllowList admits only the peer IDs on an AllowList.  Peers are
+//     refused after the secure channel authenticates them, both when
+//     dialling and when accepting, and removing a peer from the list
+//     closes its open connections.
+//
+// A peer's ID is derived from its agent's Ed25519 key (PeerIDFromKey), so
+// provisioning an agent means adding its key's ID to the list.  A host on a
+// MemoryNetwork enforces the allow list, but cannot apply a pre-shared key,
+// so NewHost refuses one there.
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/core/control"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/pnet"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// PSKSize is the size of a private network's pre-shared key.
+const PSKSize = 32
+
+// WithPrivateNetwork restricts the host to the private network keyed by
+// psk.  NewHost fails unless psk is PSKSize bytes, so a missing key never
+// leaves the host on the public network.
+func WithPrivateNetwork(psk []byte) HostOption {
+	return func(ah *AgentHost) {
+		ah.psk = append([]byte(nil), psk...)
+		ah.private = true
+	}
+}
+
+// LoadSwarmKey reads a pre-shared key from a swarm.key file, as used by
+// IPFS private networks:
+//
+//	/key/swarm/psk/1.0.0/
+//	/base16/
+//	<64 hex digits>
+func LoadSwarmKey(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("p2p: swarm key: %w", err)
+	}
+	defer f.Close()
+	psk, err := pnet.DecodeV1PSK(f)
+	if err != nil {
+		return nil, fmt.Errorf("p2p: swarm key %s: %w", path, err)
+	}
+	return psk, nil
+}
+
+// AllowList is a set of peer IDs allowed to connect.  It is
+// concurrency-safe and may be shared by several hosts and changed while
+// they run.
+type AllowList struct {
+	mu       sync.RWMutex
+	peers    map[peer.ID]bool
+	onRemove []func(peer.ID)
+}
+
+// NewAllowList creates an allow list holding ids.
+func NewAllowList(ids ...peer.ID) *AllowList {
+	l := &AllowList{peers: make(map[peer.ID]bool, len(ids))}
+	for _, id := range ids {
+		l.peers[id] = true
+	}
+	return l
+}
+
+// Add allows ids.
+func (l *AllowList) Add(ids ...peer.ID) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, id := range ids {
+		l.peers[id] = true
+	}
+}
+
+// Remove disallows id and disconnects it from the hosts using the list.
+func (l *AllowList) Remove(id peer.ID) {
+	l.mu.Lock()
+	delete(l.peers, id)
+	hooks := l.onRemove
+	l.mu.Unlock()
+	for _, fn := range hooks {
+		fn(id)
+	}
+}
+
+// Allowed reports whether id may connect.
+func (l *AllowList) Allowed(id peer.ID) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.peers[id]
+}
+
+// Peers returns the allowed peer IDs, sorted.
+func (l *AllowList) Peers() []peer.ID {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	ids := make([]peer.ID, 0, len(l.peers))
+	for id := range l.peers {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// WithAllowList admits only connections with peers on l.  The host's own
+// ID need not be on it.
+func WithAllowList(l *AllowList) HostOption {
+	return func(ah *AgentHost) { ah.allow = l }
+}
+
+// PeerIDFromKey returns the peer ID of the host for the agent whose
+// Ed25519 public key is pub, such as AgentProfile.PublicKey.
+func PeerIDFromKey(pub []byte) (peer.ID, error) {
+	k, err := crypto.UnmarshalEd25519PublicKey(pub)
+	if err != nil {
+		return "", fmt.Errorf("p2p: %w", err)
+	}
+	id, err := peer.IDFromPublicKey(k)
+	if err != nil {
+		return "", fmt.Errorf("p2p: %w", err)
+	}
+	return id, nil
+}
+
+// privateOptions returns the libp2p options for the host's private network
+// and allow list.
+func (ah *AgentHost) privateOptions() []libp2p.Option {
+	var opts []libp2p.Option
+	if ah.private {
+		opts = append(opts, libp2p.PrivateNetwork(pnet.PSK(ah.psk)))
+	}
+	if ah.allow != nil {
+		opts = append(opts, libp2p.ConnectionGater(allowGater{ah.allow}))
+	}
+	return opts
+}
+
+// enforceAllowList closes connections from peers that are not, or are no
+// longer, on the allow list.  The gater refuses them on a real network;
+// this also covers a MemoryNetwork and removals.
+func (ah *AgentHost) enforceAllowList() {
+	ah.h.Network().Notify(&network.NotifyBundle{
+		ConnectedF: func(_ network.Network, c network.Conn) {
+			if !ah.allow.Allowed(c.RemotePeer()) {
+				go c.Close()
+			}
+		},
+	})
+	ah.allow.mu.Lock()
+	defer ah.allow.mu.Unlock()
+	ah.allow.onRemove = append(ah.allow.onRemove, func(id peer.ID) {
+		select {
+		case <-ah.done:
+		default:
+			_ = ah.h.Network().ClosePeer(id)
+		}
+	})
+}
+
+// allowGater is a connection gater admitting the peers on an AllowList.
+type allowGater struct{ l *AllowList }
+
+func (g allowGater) InterceptPeerDial(p peer.ID) bool { return g.l.Allowed(p) }
+
+func (g allowGater) InterceptAddrDial(p peer.ID, _ ma.Multiaddr) bool { return g.l.Allowed(p) }
+
+// InterceptAccept admits every inbound connection: the peer is known only
+// once the secure channel is up.
+func (g allowGater) InterceptAccept(network.ConnMultiaddrs) bool { return true }
+
+func (g allowGater) InterceptSecured(_ network.Direction, p peer.ID, _ network.ConnMultiaddrs) bool {
+	return g.l.Allowed(p)
+}
+
+func (g allowGater) InterceptUpgraded(network.Conn) (bool, control.DisconnectReason) { return true, 0 }
@@ -0,0 +1,77 @@
+package p2p_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+func TestPrivateMesh(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for _, bad := range [][]byte{[]byte("short"), {}, nil} {
+		if h, err := p2p.NewHost(ctx, makeAgent(t, "short", nil), p2p.WithPrivateNetwork(bad)); err == nil {
+			_ = h.Close()
+			t.Fatalf("NewHost accepted a %d-byte pre-shared key", len(bad))
+		}
+	}
+	full := []byte(strings.Repeat("k", p2p.PSKSize))
+	if h, err := p2p.NewHost(ctx, makeAgent(t, "mem", nil), p2p.WithPrivateNetwork(full), p2p.WithMemoryNetwork(p2p.NewMemoryNetwork())); err == nil {
+		_ = h.Close()
+		t.Fatal("NewHost put a pre-shared key on a MemoryNetwork")
+	}
+
+	path := filepath.Join(t.TempDir(), "swarm.key")
+	key := "/key/swarm/psk/1.0.0/\n/base16/\n" + strings.Repeat("ab", p2p.PSKSize) + "\n"
+	if err := os.WriteFile(path, []byte(key), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	psk, err := p2p.LoadSwarmKey(path)
+	if err != nil || len(psk) != p2p.PSKSize {
+		t.Fatalf("LoadSwarmKey: %d bytes, %v", len(psk), err)
+	}
+
+	alpha, beta, gamma := makeAgent(t, "alpha", nil), makeAgent(t, "beta", nil), makeAgent(t, "gamma", nil)
+	betaID, err := p2p.PeerIDFromKey(beta.PublicKey())
+	if err != nil {
+		t.Fatalf("PeerIDFromKey: %v", err)
+	}
+	allow := p2p.NewAllowList(betaID)
+	newHost := func(a *core.Agent, opts ...p2p.HostOption) *p2p.AgentHost {
+		h, err := p2p.NewHost(ctx, a, opts...)
+		if err != nil {
+			t.Fatalf("NewHost(%s): %v", a.ID, err)
+		}
+		t.Cleanup(func() { _ = h.Close() })
+		return h
+	}
+	hA := newHost(alpha, p2p.WithPrivateNetwork(psk), p2p.WithAllowList(allow))
+	hB := newHost(beta, p2p.WithPrivateNetwork(psk))
+	if hB.PeerID() != betaID {
+		t.Fatalf("PeerIDFromKey = %s; host is %s", betaID, hB.PeerID())
+	}
+	if _, err = p2p.DiscoverAndHandshake(ctx, hB, hA.AddrInfo()); err != nil {
+		t.Fatalf("provisioned peer: %v", err)
+	}
+
+	// gamma has the key but is not provisioned; delta is not on the network.
+	hC := newHost(gamma, p2p.WithPrivateNetwork(psk))
+	if _, err = p2p.DiscoverAndHandshake(ctx, hC, hA.AddrInfo()); err == nil {
+		t.Error("peer missing from the allow list completed a handshake")
+	}
+	hD := makeHost(t, makeAgent(t, "delta", nil))
+	if err = hD.Connect(ctx, hA.AddrInfo()); err == nil {
+		t.Error("peer without the pre-shared key connected")
+	}
+
+	allow.Remove(betaID)
+	if _, err = hB.Handshake(ctx, hA.PeerID()); err == nil {
+		t.Error("removed peer still completed a handshake")
+	}
+}
@@ -7,24 +7,45 @@ package p2p
 // step's required capability vector.
 
 import (
+	"cmp"
 	"context"
 	"fmt"
+	"slices"
 	"sync"
 	"time"
 
 	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/olserra/agent-semantic-protocol/core"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // WorkflowOrchestrator dispatches workflow steps to the best-matching peers.
 type WorkflowOrchestrator struct {
 	host    *AgentHost
 	timeout time.Duration
+
+	mu          sync.Mutex
+	policy      ReplanPolicy
+	history     map[string][]WorkflowEvent
+	load        map[string]int // agent ID → steps outstanding, across runs
+	store       WorkflowStore  // nil: workflows are not checkpointed
+	failover    FailoverPolicy
+	sched       *stepScheduler
+	scoreBid    BidScorer // nil: DefaultBidScore
+	credentials core.CredentialPolicy
 }
 
 // NewOrchestrator creates a WorkflowOrchestrator backed by the given AgentHost.
+// stepTimeout is the MaxLatency of step intents whose step sets none;
+// zero leaves them bounded by the workflow deadline alone.
 func NewOrchestrator(host *AgentHost, stepTimeout time.Duration) *WorkflowOrchestrator {
-	return &WorkflowOrchestrator{host: host, timeout: stepTimeout}
+	return &WorkflowOrchestrator{
+		host:    host,
+		timeout: stepTimeout,
+		history: make(map[string][]WorkflowEvent),
+		load:    make(map[string]int),
+		sched:   newStepScheduler(),
+	}
 }
 
 // StepResult carries the outcome of a single workflow step.
@@ -33,11 +54,18 @@ type StepResult struct {
 	AgentID   string
 	Accepted  bool
 	Reason    string
+	Output    string              // Captured output of local steps, or the step's result as text
+	Result    *core.ResultPayload // Output the executing agent returned, if any
+	Tried     []string            // Agents that rejected or failed the step before AgentID, under a FailoverPolicy
+	Cost      *core.CostEstimate  // What AgentID expected the step to cost, if it said
+	ExitCode  int                 // Process exit code of locally executed steps
 	Timestamp time.Time
 }
 
 // RunWorkflow sends one intent per step to the best-capable peer and collects results.
-// steps is a slice of (capabilityTag, intentVector, payload) tuples.
+// steps is a slice of (capabilityTag, intentVector, payload) tuples.  All steps
+// run concurrently, within the bounds set by SetScheduler; use RunSequential
+// when steps depend on each other.
 func (o *WorkflowOrchestrator) RunWorkflow(
 	ctx context.Context,
 	workflowID string,
@@ -48,12 +76,30 @@ func (o *WorkflowOrchestrator) RunWorkflow(
 	var mu sync.Mutex
 	var firstErr error
 
+	ctx, stop := context.WithCancel(ctx)
+	run := o.newRun(ctx, workflowID, steps, nil)
+	var watchWG sync.WaitGroup
+	run.watch(ctx, &watchWG)
+	defer watchWG.Wait()
+	defer stop()
+
+	// Under a parallelism bound, start no more goroutines than may send.
+	var slots chan struct{}
+	if n := o.sched.maxParallel(); n > 0 {
+		slots = make(chan struct{}, n)
+	}
 	for i, step := range steps {
+		if slots != nil {
+			slots <- struct{}{}
+		}
 		wg.Add(1)
 		go func(idx int, s WorkflowStep) {
 			defer wg.Done()
+			if slots != nil {
+				defer func() { <-slots }()
+			}
 
-			r, err := o.executeStep(ctx, workflowID, s)
+			r, err := o.dispatchStep(ctx, run, s, o.sendStep)
 			mu.Lock()
 			defer mu.Unlock()
 			if err != nil {
@@ -73,57 +119,269 @@ func (o *WorkflowOrchestrator) RunWorkflow(
 
 // WorkflowStep describes one step in a distributed workflow.
 type WorkflowStep struct {
-	ID           string    // Unique step identifier
-	Capability   string    // Required capability for this step
-	IntentVector []float32 // Semantic vector describing the step's goal
-	Payload      string    // Step-specific payload
+	ID           string        // Unique step identifier
+	Capability   string        // Required capability for this step
+	IntentVector []float32     // Semantic vector describing the step's goal
+	Payload      string        // Step-specific payload
+	BudgetWeight float64       // Share of the workflow budget relative to other steps; 0 = 1
+	AwaitResult  bool          // Wait for the agent's WorkflowResult once it accepts the step
+	Budget       *core.Budget  // Most the step may cost; acceptances estimated beyond it count as rejections
+	MaxLatency   time.Duration // Longest the step may take; 0 = the orchestrator's step timeout
+
+	// The remaining fields are used by RunSequential and RunGraph only.
+	NextStepID string        // RunSequential: step to run next; "" = the following step
+	DependsOn  []string      // RunGraph: steps that must succeed first
+	Retries    int           // Extra attempts after a failed or rejected try
+	OnFailure  FailurePolicy // What to do once retries are exhausted
+	Compensate *WorkflowStep // Undoes this step on the same agent when a FailCompensate step fails
+
+	agent string // Set on compensations: the only agent the step may go to
 }
 
-func (o *WorkflowOrchestrator) executeStep(
+// stepSender delivers a prepared step intent to peerID and reports the
+// outcome.
+type stepSender func(ctx context.Context, peerID peer.ID, step WorkflowStep, intent *core.IntentMessage) (StepResult, error)
+
+// dispatchStep sends step to the best usable peer with send.  If that peer is
+// abandoned while the step is outstanding, or rejects or fails the step
+// under a FailoverPolicy, the step moves on to the next candidate;
+// compensations stay with their agent.
+func (o *WorkflowOrchestrator) dispatchStep(
 	ctx context.Context,
-	workflowID string,
+	run *workflowRun,
+	step WorkflowStep,
+	send stepSender,
+) (StepResult, error) {
+	release, err := o.sched.acquire(ctx, step.Capability, run.planFor(step).Priority)
+	if err != nil {
+		return StepResult{}, err
+	}
+	defer release()
+	o.mu.Lock()
+	failover := o.failover
+	o.mu.Unlock()
+	var tried []string // Agents that rejected or failed the step
+	var last StepResult
+	var lastErr error
+	for {
+		peerID, agentID, intent, err := o.prepareStep(run, step, tried)
+		if err != nil && len(tried) > 0 {
+			// Out of candidates: report the last one's answer.
+			if lastErr != nil {
+				return StepResult{}, lastErr
+			}
+			last.Tried = tried[:len(tried)-1]
+			return last, nil
+		}
+		if err != nil {
+			return StepResult{}, err
+		}
+		o.record(run.id, WorkflowEvent{Kind: WorkflowStepSent, StepID: step.ID, AgentID: agentID})
+
+		stepCtx, span := o.host.startSpan(ctx, "asp.workflow_step", peerID)
+		span.SetAttributes(
+			attribute.String("workflow.id", run.id),
+			attribute.String("workflow.step_id", step.ID),
+			attribute.String("workflow.capability", step.Capability),
+			attribute.String("peer.agent_id", agentID),
+		)
+		stepCtx, cancel := o.stepContext(stepCtx, intent)
+		release := run.track(agentID, cancel)
+		o.addLoad(agentID, 1)
+		start := time.Now()
+		var results <-chan *core.WorkflowResult
+		forget := func() {}
+		if step.AwaitResult {
+			results, forget = o.host.expectResult(intent.ID, peerID)
+		}
+		r, err := send(stepCtx, peerID, step, intent)
+		if err == nil && r.Accepted && results != nil {
+			err = awaitResult(stepCtx, results, &r)
+		}
+		forget()
+		o.addLoad(agentID, -1)
+		release()
+		cancel()
+		if err == nil {
+			span.SetAttributes(attribute.Bool("intent.accepted", r.Accepted))
+		}
+		endSpan(span, nil, err)
+		if err != nil && ctx.Err() == nil && step.agent == "" && run.isAbandoned(agentID) {
+			continue
+		}
+		run.observe(peerID, agentID, time.Since(start), err != nil)
+		if (err != nil || !r.Accepted) && ctx.Err() == nil {
+			o.penalize(peerID, failover.penalty(err))
+			if step.agent == "" && len(tried)+1 < failover.candidates() {
+				reason := r.Reason
+				if err != nil {
+					reason = err.Error()
+				}
+				tried = append(tried, agentID)
+				last, lastErr = r, err
+				o.record(run.id, WorkflowEvent{Kind: WorkflowStepFailover, StepID: step.ID, AgentID: agentID, Reason: reason})
+				continue
+			}
+		}
+		if err != nil {
+			return StepResult{}, err
+		}
+		r.Tried = tried
+		o.record(run.id, WorkflowEvent{Kind: WorkflowStepDone, StepID: step.ID, AgentID: agentID, Reason: r.Reason})
+		return r, nil
+	}
+}
+
+// sendStep is the stepSender for plain request/response intents.
+func (o *WorkflowOrchestrator) sendStep(
+	ctx context.Context,
+	peerID peer.ID,
 	step WorkflowStep,
+	intent *core.IntentMessage,
 ) (StepResult, error) {
+	resp, err := o.host.SendIntent(ctx, peerID, intent)
+	if err != nil {
+		return StepResult{}, err
+	}
+
+	r := StepResult{
+		StepID:    step.ID,
+		AgentID:   resp.AgentID,
+		Accepted:  resp.Accepted,
+		Reason:    resp.Reason,
+		Result:    resp.Result,
+		Timestamp: time.Now(),
+	}
+	if resp.Result != nil {
+		r.Output = string(resp.Result.Data)
+	}
+	checkStepCost(intent, resp, &r)
+	return r, nil
+}
+
+// awaitResult waits for the WorkflowResult of an accepted step and records
+// it in r.  A failed step is recorded as rejected with the agent's error.
+func awaitResult(ctx context.Context, results <-chan *core.WorkflowResult, r *StepResult) error {
+	select {
+	case res := <-results:
+		if res.Failed() {
+			r.Accepted, r.Reason = false, res.Error
+			return nil
+		}
+		r.Result = res.Output
+		if res.Output != nil {
+			r.Output = string(res.Output.Data)
+		}
+		r.Timestamp = time.Now()
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for step result: %w", ctx.Err())
+	}
+}
+
+// prepareStep picks the best peer for step that run has not abandoned and
+// that is not in exclude, and builds its intent.
+func (o *WorkflowOrchestrator) prepareStep(run *workflowRun, step WorkflowStep, exclude []string) (peer.ID, string, *core.IntentMessage, error) {
 	// Find peers with the required capability.
 	candidates := o.host.Discovery().FindByCapability(step.Capability)
 	if len(candidates) == 0 {
-		return StepResult{}, fmt.Errorf("no peer with capability %q", step.Capability)
+		return "", "", nil, fmt.Errorf("%w: no peer with capability %q", core.ErrCapabilityMissing, step.Capability)
 	}
 
-	// Rank by cosine similarity.
-	ranked := core.RankCandidates(step.IntentVector, candidates)
-	best := ranked[0]
+	// Rank by cosine similarity, let the capability's selector reorder, and
+	// skip abandoned peers and peers whose advertised contract rejects the
+	// step's payload.
+	sel := o.host.selection.For(step.Capability)
+	ordered := sel.Order(core.Selection{
+		Capability: step.Capability,
+		Candidates: core.RankCandidates(step.IntentVector, candidates),
+		Load:       o.Load,
+	})
+	var best *core.AgentProfile
+	var contractErr error
+	for _, c := range ordered {
+		if step.agent != "" {
+			// A compensation goes to the agent that ran the step, even one
+			// the run has since abandoned.
+			if c.AgentID != step.agent {
+				continue
+			}
+		} else if slices.Contains(exclude, c.AgentID) || !run.usable(c.AgentID, c.DID) || !o.credentialed(c, step.Capability) {
+			continue
+		}
+		if spec, ok := core.FindSpec(c.Specs, step.Capability); ok {
+			if err := spec.ValidateParams([]byte(step.Payload)); err != nil {
+				if contractErr == nil {
+					contractErr = err
+				}
+				continue
+			}
+		}
+		best = &c
+		break
+	}
+	if best == nil {
+		if contractErr != nil {
+			return "", "", nil, contractErr
+		}
+		if step.agent != "" {
+			return "", "", nil, fmt.Errorf("%w: agent %s does not offer capability %q", core.ErrCapabilityMissing, step.agent, step.Capability)
+		}
+		return "", "", nil, fmt.Errorf("%w: no usable peer with capability %q", core.ErrCapabilityMissing, step.Capability)
+	}
+	sel.Picked(step.Capability, best.AgentID)
 
 	// Resolve peer.ID from the known map (best-effort).
 	peerID, err := o.resolvePeerID(best.AgentID)
 	if err != nil {
-		return StepResult{}, err
+		return "", "", nil, err
 	}
 
 	// Build and send intent.
 	intent, err := core.CreateIntent(o.host.agent, step.IntentVector,
 		[]string{step.Capability}, step.Payload)
 	if err != nil {
-		return StepResult{}, err
+		return "", "", nil, err
 	}
-	intent.Metadata["workflow_id"] = workflowID
-	intent.Metadata["step_id"] = step.ID
-
-	stepCtx, cancel := context.WithTimeout(ctx, o.timeout)
-	defer cancel()
+	intent.Metadata[core.WorkflowIDMetadataKey] = run.id
+	intent.Metadata[core.StepIDMetadataKey] = step.ID
+	if step.AwaitResult {
+		intent.Metadata[core.AwaitResultMetadataKey] = "true"
+	}
+	intent.Budget = step.Budget
+	intent.MaxLatency = int64(cmp.Or(step.MaxLatency, o.timeout))
+	applyPlan(intent, run.planFor(step))
+	if err = core.SignIntent(o.host.agent, intent); err != nil {
+		return "", "", nil, err
+	}
+	return peerID, best.AgentID, intent, nil
+}
 
-	resp, err := o.host.SendIntent(stepCtx, peerID, intent)
-	if err != nil {
-		return StepResult{}, err
+// stepContext bounds ctx by intent's Deadline and MaxLatency, or by the
+// orchestrator's step timeout if it sets neither.
+func (o *WorkflowOrchestrator) stepContext(ctx context.Context, intent *core.IntentMessage) (context.Context, context.CancelFunc) {
+	if limit, ok := intent.TimeLimit(time.Now()); ok {
+		return context.WithTimeout(ctx, limit)
+	}
+	if o.timeout > 0 {
+		return context.WithTimeout(ctx, o.timeout)
 	}
+	return context.WithCancel(ctx)
+}
 
-	return StepResult{
-		StepID:    step.ID,
-		AgentID:   resp.AgentID,
-		Accepted:  resp.Accepted,
-		Reason:    resp.Reason,
-		Timestamp: time.Now(),
-	}, nil
+// Load returns the number of steps outstanding with agentID.
+func (o *WorkflowOrchestrator) Load(agentID string) int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.load[agentID]
+}
+
+func (o *WorkflowOrchestrator) addLoad(agentID string, delta int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.load[agentID] += delta; o.load[agentID] <= 0 {
+		delete(o.load, agentID)
+	}
 }
 
 func (o *WorkflowOrchestrator) resolvePeerID(agentID string) (peer.ID, error) {
@@ -145,7 +403,7 @@ func (o *WorkflowOrchestrator) resolvePeerID(agentID string) (peer.ID, error) {
 
 // DiscoverAndHandshake connects to a peer by AddrInfo, performs a handshake,
 // and registers the peer in the discovery registry.
-func DiscoverAndHandshake(ctx context.Context, h *AgentHost, info peer.AddrInfo) (core.HandshakeResult, error) {
+func DiscoverAndHandshake(ctx context.Context, h Transport, info peer.AddrInfo) (core.HandshakeResult, error) {
 	if err := h.Connect(ctx, info); err != nil {
 		return core.HandshakeResult{}, fmt.Errorf("discover: connect: %w", err)
 	}
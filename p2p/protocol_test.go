@@ -0,0 +1,140 @@
+package p2p_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+// TestSendWorkflow verifies that a WorkflowMessage reaches the peer's
+// OnWorkflow callback.
+func TestSendWorkflow(t *testing.T) {
+	alpha := makeAgent(t, "alpha", []string{"nlp"})
+	beta := makeAgent(t, "beta", []string{"summarisation"})
+
+	hA := makeHost(t, alpha)
+	hB := makeHost(t, beta)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := hA.Connect(ctx, hB.AddrInfo()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+
+	got := make(chan *core.WorkflowMessage, 1)
+	hB.OnWorkflow(func(_ peer.ID, msg *core.WorkflowMessage) { got <- msg })
+
+	step := &core.WorkflowMessage{
+		WorkflowID: "wf-1",
+		StepID:     "step-1",
+		AgentID:    alpha.ID,
+		DID:        alpha.DID.String(),
+		Action:     "summarise",
+		Params:     map[string]string{"lang": "en"},
+		Timestamp:  time.Now().UnixNano(),
+	}
+	if err := hA.SendWorkflow(ctx, hB.PeerID(), step); err != nil {
+		t.Fatalf("SendWorkflow: %v", err)
+	}
+
+	select {
+	case msg := <-got:
+		if msg.StepID != "step-1" || msg.Params["lang"] != "en" {
+			t.Errorf("unexpected workflow message: %+v", msg)
+		}
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for workflow message")
+	}
+}
+
+func TestWorkflowStepContract(t *testing.T) {
+	alpha := makeAgent(t, "alpha", []string{"nlp"})
+	ocr := makeAgent(t, "ocr", []string{"ocr"})
+	ocr.Specs = []core.CapabilitySpec{{
+		Name:         "ocr",
+		Version:      "1.0.0",
+		ParamsSchema: json.RawMessage(`{"type": "object", "required": ["url"]}`),
+	}}
+	hA := makeHost(t, alpha)
+	hO := makeHost(t, ocr)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := p2p.DiscoverAndHandshake(ctx, hA, hO.AddrInfo()); err != nil {
+		t.Fatalf("DiscoverAndHandshake: %v", err)
+	}
+	profile, ok := hA.Discovery().FindByDID(ocr.DID.String())
+	if !ok || len(profile.Specs) != 1 {
+		t.Fatalf("peer specs not learned from the handshake: %+v", profile)
+	}
+
+	o := p2p.NewOrchestrator(hA, 5*time.Second)
+	_, err := o.RunSequential(ctx, "wf-bad", []p2p.WorkflowStep{{ID: "scan", Capability: "ocr", Payload: `{"path": "/doc"}`}})
+	if !errors.Is(err, core.ErrSchemaViolation) {
+		t.Fatalf("RunSequential: got %v, want ErrSchemaViolation", err)
+	}
+	for _, ev := range o.History("wf-bad") {
+		if ev.Kind == p2p.WorkflowStepSent {
+			t.Error("step was dispatched despite violating the contract")
+		}
+	}
+
+	results, err := o.RunSequential(ctx, "wf-good", []p2p.WorkflowStep{{ID: "scan", Capability: "ocr", Payload: `{"url": "s3://doc"}`}})
+	if err != nil || len(results) != 1 || !results[0].Accepted {
+		t.Fatalf("RunSequential: %+v, %v", results, err)
+	}
+}
+
+func TestWorkflowStepLatency(t *testing.T) {
+	alpha := makeAgent(t, "alpha", nil)
+	hA := makeHost(t, alpha)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	beta := makeAgent(t, "beta", []string{"translate"})
+	hB, err := p2p.NewHost(context.Background(), beta, p2p.WithServiceTimes(map[string]time.Duration{"translate": 2 * time.Second}))
+	if err != nil {
+		t.Fatalf("NewHost: %v", err)
+	}
+	t.Cleanup(func() { _ = hB.Close() })
+	var mu sync.Mutex
+	var latencies []int64
+	hB.OnIntent(func(_ peer.ID, in *core.IntentMessage) *core.NegotiationResponse {
+		mu.Lock()
+		latencies = append(latencies, in.MaxLatency)
+		mu.Unlock()
+		return nil // The default handler answers.
+	})
+	if _, err := p2p.DiscoverAndHandshake(ctx, hA, hB.AddrInfo()); err != nil {
+		t.Fatalf("DiscoverAndHandshake: %v", err)
+	}
+
+	o := p2p.NewOrchestrator(hA, 5*time.Second)
+	results, err := o.RunWorkflow(ctx, "wf", []p2p.WorkflowStep{
+		{ID: "default", Capability: "translate"},
+		{ID: "tight", Capability: "translate", MaxLatency: time.Second},
+	})
+	if err != nil || len(results) != 2 {
+		t.Fatalf("RunWorkflow: %+v, %v", results, err)
+	}
+	if !results[0].Accepted {
+		t.Errorf("default step rejected: %s", results[0].Reason)
+	}
+	if results[1].Accepted || results[1].Reason != core.ReasonDeadlineUnmeetable {
+		t.Errorf("tight step = %+v; want rejected as unmeetable", results[1])
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !slices.Contains(latencies, int64(5*time.Second)) || !slices.Contains(latencies, int64(time.Second)) {
+		t.Errorf("intents carried MaxLatency %v; want the step timeout and the step's own", latencies)
+	}
+}
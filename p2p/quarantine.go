@@ -0,0 +1,97 @@
+package p2p
+
+// quarantine.go — Shutting out peers that keep failing.
+//
+// With WithErrorBudget the host counts, per peer, the messages it could not
+// decode, the intents that timed out and the workflow steps that breached
+// their latency SLA.  A peer that exhausts its budget is quarantined (see
+// core.Quarantine): the host sends it no intents, answers its intents with
+// a core.ReasonQuarantined rejection and ignores its capability
+// announcements until the quarantine ends or Unquarantine lifts it.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+// ErrPeerQuarantined is returned (wrapped) when sending to a quarantined
+// peer.
+var ErrPeerQuarantined = fmt.Errorf("p2p: peer is quarantined")
+
+// WithErrorBudget quarantines peers that exhaust budget.  NewHost fails if
+// budget is invalid.
+func WithErrorBudget(budget core.ErrorBudget) HostOption {
+	return func(ah *AgentHost) { ah.errorBudget = &budget }
+}
+
+// peerError counts an error of kind against pid, quarantining it if that
+// exhausts its budget.
+func (ah *AgentHost) peerError(pid peer.ID, kind string) {
+	if ah.quarantine == nil {
+		return
+	}
+	until, quarantined := ah.quarantine.Record(pid.String(), kind)
+	if !quarantined {
+		return
+	}
+	ah.mu.RLock()
+	profile := ah.known[pid.String()]
+	ah.mu.RUnlock()
+	ah.emit(Event{
+		Kind:    EventPeerQuarantined,
+		PeerID:  pid,
+		AgentID: profile.AgentID,
+		DID:     profile.DID,
+		Reason:  fmt.Sprintf("error budget exhausted by %s errors; quarantined until %s", kind, until.Format(time.RFC3339)),
+	})
+}
+
+// sendError counts err, returned by an exchange with pid, against pid if it
+// is a timeout.
+func (ah *AgentHost) sendError(pid peer.ID, err error) {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded) {
+		ah.peerError(pid, core.PeerErrorTimeout)
+	}
+}
+
+// quarantined returns when pid's quarantine ends, if it is quarantined.
+func (ah *AgentHost) quarantined(pid peer.ID) (time.Time, bool) {
+	if ah.quarantine == nil {
+		return time.Time{}, false
+	}
+	return ah.quarantine.Quarantined(pid.String())
+}
+
+// checkQuarantine returns an error wrapping ErrPeerQuarantined if pid is
+// quarantined.
+func (ah *AgentHost) checkQuarantine(pid peer.ID) error {
+	if until, ok := ah.quarantined(pid); ok {
+		return fmt.Errorf("%w: %s until %s", ErrPeerQuarantined, pid, until.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// QuarantineStatus lists the peers with errors in their budget window or in
+// quarantine, keyed by peer ID.  It is empty without WithErrorBudget.
+func (ah *AgentHost) QuarantineStatus() []core.QuarantineEntry {
+	if ah.quarantine == nil {
+		return nil
+	}
+	return ah.quarantine.Entries(time.Now())
+}
+
+// Unquarantine lifts pid's quarantine and clears its errors.  It reports
+// whether pid was quarantined.
+func (ah *AgentHost) Unquarantine(pid peer.ID) bool {
+	if ah.quarantine == nil || !ah.quarantine.Release(pid.String()) {
+		return false
+	}
+	ah.emit(Event{Kind: EventPeerUnquarantined, PeerID: pid})
+	return true
+}
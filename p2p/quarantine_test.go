@@ -0,0 +1,73 @@
+package p2p_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+// TestErrorBudgetQuarantine verifies that a peer that times out past its
+// error budget is quarantined in both directions until released.
+func TestErrorBudgetQuarantine(t *testing.T) {
+	alpha := makeAgent(t, "alpha", nil)
+	beta := makeAgent(t, "beta", []string{"ocr"})
+	hA, err := p2p.NewHost(context.Background(), alpha, p2p.WithErrorBudget(core.ErrorBudget{MaxErrors: 0, BaseBackoff: time.Minute}))
+	if err != nil {
+		t.Fatalf("NewHost: %v", err)
+	}
+	t.Cleanup(func() { _ = hA.Close() })
+	hB := makeHost(t, beta)
+	hB.OnIntent(func(_ peer.ID, in *core.IntentMessage) *core.NegotiationResponse {
+		time.Sleep(500 * time.Millisecond)
+		resp, _ := core.DefaultNegotiationHandler(beta)(in)
+		return resp
+	})
+
+	var events []p2p.Event
+	hA.Subscribe(func(ev p2p.Event) { events = append(events, ev) }, p2p.EventPeerQuarantined, p2p.EventPeerUnquarantined)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err = hA.Connect(ctx, hB.AddrInfo()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	if _, err = hA.Handshake(ctx, hB.PeerID()); err != nil {
+		t.Fatalf("Handshake: %v", err)
+	}
+
+	intent, _ := core.CreateIntent(alpha, nil, []string{"ocr"}, "page")
+	short, cancelShort := context.WithTimeout(ctx, 100*time.Millisecond)
+	_, err = hA.SendIntent(short, hB.PeerID(), intent)
+	cancelShort()
+	if err == nil {
+		t.Fatal("expected a timeout")
+	}
+
+	intent, _ = core.CreateIntent(alpha, nil, []string{"ocr"}, "page")
+	if _, err = hA.SendIntent(ctx, hB.PeerID(), intent); !errors.Is(err, p2p.ErrPeerQuarantined) {
+		t.Fatalf("SendIntent: got %v, want ErrPeerQuarantined", err)
+	}
+	status := hA.QuarantineStatus()
+	if len(status) != 1 || status[0].Key != hB.PeerID().String() || status[0].Until.IsZero() {
+		t.Errorf("status: %+v", status)
+	}
+
+	if !hA.Unquarantine(hB.PeerID()) {
+		t.Fatal("Unquarantine reported no quarantine")
+	}
+	if _, err = hA.SendIntent(ctx, hB.PeerID(), intent); err != nil {
+		t.Errorf("SendIntent after release: %v", err)
+	}
+	if len(events) != 2 || events[0].Kind != p2p.EventPeerQuarantined || events[1].Kind != p2p.EventPeerUnquarantined {
+		t.Errorf("events: %+v", events)
+	}
+
+	if _, err = p2p.NewHost(context.Background(), beta, p2p.WithErrorBudget(core.ErrorBudget{MaxErrors: -1})); err == nil {
+		t.Error("NewHost accepted a negative error budget")
+	}
+}
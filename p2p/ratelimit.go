@@ -0,0 +1,75 @@
+package p2p
+
+// ratelimit.go — Per-peer intent rate limiting.
+//
+// With WithRateLimit every remote peer gets a token bucket.  An intent that
+// finds its sender's bucket empty is not served: it is answered at once with
+// a signed rejection whose Reason is core.ReasonRateLimited and whose
+// RetryAfter says when the next intent would be admitted.  The rejection
+// also carries a negative TrustDelta, which the host applies to the DID the
+// sender shook hands under, so a peer that keeps flooding loses trust and
+// eventually standing with trust-gated handlers.
+//
+// Buckets are keyed by peer ID and outlive disconnects, so reconnecting
+// does not refill one early.  Once a minute the host drops the buckets that
+// have refilled anyway.
+
+import (
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+// WithRateLimit limits the intents each peer may send to limit, lowering the
+// local trust in the sender by trustPenalty for every intent refused.
+// NewHost fails if limit is invalid.
+func WithRateLimit(limit core.RateLimit, trustPenalty float32) HostOption {
+	return func(ah *AgentHost) {
+		ah.rateLimit = &limit
+		ah.ratePenalty = trustPenalty
+	}
+}
+
+// throttle charges intent to its sender's bucket and returns the rejection
+// to send instead of serving it, or nil.  Intents from quarantined peers
+// are always refused.
+func (ah *AgentHost) throttle(from peer.ID, intent *core.IntentMessage) *core.NegotiationResponse {
+	if until, ok := ah.quarantined(from); ok {
+		resp := ah.rejection(intent, core.ReasonQuarantined)
+		resp.RetryAfter = int64(time.Until(until))
+		_ = core.SignResponse(ah.agent, resp)
+		return resp
+	}
+	if ah.limiter == nil {
+		return nil
+	}
+	wait, ok := ah.limiter.Allow(from.String())
+	if ok {
+		return nil
+	}
+	resp := ah.rejection(intent, core.ReasonRateLimited)
+	resp.RetryAfter = int64(wait)
+	resp.TrustDelta = -ah.ratePenalty
+	_ = core.SignResponse(ah.agent, resp)
+	ah.observeRateLimited()
+	return resp
+}
+
+// ratePruneInterval is how often the host drops refilled buckets.
+const ratePruneInterval = time.Minute
+
+// pruneRateLoop drops the buckets that have refilled, which behave like new
+// ones, until the host is closed.
+func (ah *AgentHost) pruneRateLoop() {
+	t := time.NewTicker(ratePruneInterval)
+	defer t.Stop()
+	for {
+		select {
+		case now := <-t.C:
+			ah.limiter.Prune(now)
+		case <-ah.done:
+			return
+		}
+	}
+}
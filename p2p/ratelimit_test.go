@@ -0,0 +1,119 @@
+package p2p_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+func TestRateLimit(t *testing.T) {
+	alpha := makeAgent(t, "alpha", nil)
+	beta := makeAgent(t, "beta", []string{"ocr"})
+	hA := makeHost(t, alpha)
+	hB, err := p2p.NewHost(context.Background(), beta, p2p.WithRateLimit(core.RateLimit{Rate: 0.01, Burst: 2}, 0.3))
+	if err != nil {
+		t.Fatalf("NewHost: %v", err)
+	}
+	t.Cleanup(func() { _ = hB.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err = p2p.DiscoverAndHandshake(ctx, hA, hB.AddrInfo()); err != nil {
+		t.Fatalf("DiscoverAndHandshake: %v", err)
+	}
+	_ = hB.Trust().Set(beta.DID.String(), alpha.DID.String(), 0.5)
+
+	for i := 0; i < 3; i++ {
+		intent, _ := core.CreateIntent(alpha, nil, []string{"ocr"}, "page")
+		resp, err := hA.SendIntent(ctx, hB.PeerID(), intent)
+		if err != nil {
+			t.Fatalf("SendIntent %d: %v", i, err)
+		}
+		wait, limited := resp.RateLimited()
+		if limited != (i == 2) {
+			t.Fatalf("intent %d: accepted=%t reason=%q", i, resp.Accepted, resp.Reason)
+		}
+		if limited && wait < 90*time.Second {
+			t.Errorf("retry after %s, want about 100s", wait)
+		}
+		if !core.VerifyResponseSignature(resp, beta.PublicKey()) {
+			t.Errorf("intent %d: response signature does not verify", i)
+		}
+	}
+	if got := hB.Trust().Get(beta.DID.String(), alpha.DID.String()); got >= 0.5 {
+		t.Errorf("trust in flooding peer = %v, want below 0.5", got)
+	}
+
+	if _, err = p2p.NewHost(context.Background(), beta, p2p.WithRateLimit(core.RateLimit{}, 0)); err == nil {
+		t.Error("NewHost accepted a zero rate")
+	}
+}
+
+// TestRateLimitSpoofedSender verifies that a peer flooding under another
+// agent's DID costs that agent no trust, and that reconnecting does not
+// refill the flooder's bucket.
+func TestRateLimitSpoofedSender(t *testing.T) {
+	beta := makeAgent(t, "beta", []string{"ocr"})
+	gamma := makeAgent(t, "gamma", nil)
+	hB, err := p2p.NewHost(context.Background(), beta, p2p.WithRateLimit(core.RateLimit{Rate: 0.01, Burst: 2}, 0.3))
+	if err != nil {
+		t.Fatalf("NewHost: %v", err)
+	}
+	t.Cleanup(func() { _ = hB.Close() })
+	disconnected := make(chan struct{}, 1)
+	hB.Subscribe(func(p2p.Event) { disconnected <- struct{}{} }, p2p.EventPeerDisconnected)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	victim := "did:agent-semantic-protocol:victim"
+	_ = hB.Trust().Set(beta.DID.String(), victim, 0.5)
+
+	// gamma never shakes hands and sends unsigned intents naming the victim.
+	sent := 0
+	send := func(h *p2p.AgentHost) *core.NegotiationResponse {
+		t.Helper()
+		sent++
+		intent := &core.IntentMessage{ID: fmt.Sprintf("spoofed-%d", sent), Capabilities: []string{"ocr"}, DID: victim, Timestamp: time.Now().UnixNano()}
+		resp, err := h.SendIntent(ctx, hB.PeerID(), intent)
+		if err != nil {
+			t.Fatalf("SendIntent: %v", err)
+		}
+		return resp
+	}
+	hG, err := p2p.NewHost(context.Background(), gamma)
+	if err != nil {
+		t.Fatalf("NewHost: %v", err)
+	}
+	if err = hG.Connect(ctx, hB.AddrInfo()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		send(hG)
+	}
+	if got := hB.Trust().Get(beta.DID.String(), victim); got != 0.5 {
+		t.Errorf("trust in the victim = %v, want 0.5", got)
+	}
+
+	// The same peer ID comes back with the bucket still empty.
+	_ = hG.Close()
+	select {
+	case <-disconnected:
+	case <-ctx.Done():
+		t.Fatal("disconnect not seen")
+	}
+	hG, err = p2p.NewHost(context.Background(), gamma)
+	if err != nil {
+		t.Fatalf("NewHost: %v", err)
+	}
+	t.Cleanup(func() { _ = hG.Close() })
+	if err = hG.Connect(ctx, hB.AddrInfo()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	if _, limited := send(hG).RateLimited(); !limited {
+		t.Error("reconnecting refilled the bucket")
+	}
+}
@@ -0,0 +1,294 @@
+package p2p
+
+// relay.go — Relaying intents for capabilities the host lacks.
+//
+// A host created with WithRelay that receives an intent for a capability it
+// does not advertise forwards the intent, unchanged and wrapped in a signed
+// core.ForwardEnvelope, to a peer that has the capability, and returns that
+// peer's response as its own answer.  If no handshaked peer has it but hops
+// remain, the envelope goes to the other neighbours in order of trust, which
+// may relay it further.  A hop that can neither serve nor forward the
+// intent answers core.ReasonNoRoute and the relay tries its next candidate;
+// with no candidate left the intent is served locally as usual, which
+// normally means it is rejected.
+//
+// The originator needs nothing special: it sends a plain intent and gets a
+// response signed by the agent that served it, with RelayPath naming the
+// relays in between.
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+// relayTimeout bounds each attempt to forward an intent, leaving time to
+// answer within the incoming stream's deadline.
+const relayTimeout = 20 * time.Second
+
+// WithRelay lets the host relay intents for capabilities it lacks through at
+// most maxHops relays, itself included (core.DefaultRelayHops if 0).
+// NewHost fails if maxHops is negative.
+func WithRelay(maxHops int) HostOption {
+	return func(ah *AgentHost) {
+		if maxHops == 0 {
+			maxHops = core.DefaultRelayHops
+		}
+		ah.relayHops = maxHops
+	}
+}
+
+// relaySource is what the host needs to relay an intent it is serving.
+type relaySource struct {
+	orig      *core.IntentMessage   // The intent as its originator signed it
+	originKey []byte                // The originator's public key
+	env       *core.ForwardEnvelope // The envelope it arrived in; nil if sent directly
+}
+
+// keepForRelay records in e what relaying the intent encoded in data, sent
+// directly by from, would need.  It does nothing unless the host relays.
+func (ah *AgentHost) keepForRelay(e *pendingEntry, from peer.ID, data []byte) {
+	if ah.relayHops <= 0 || e == nil {
+		return
+	}
+	orig, err := ah.limits.DecodeIntentMessage(data)
+	if err != nil || orig.EncryptedPayload != nil {
+		// A payload sealed for this host is useless to anyone else.
+		return
+	}
+	ah.mu.RLock()
+	profile, known := ah.known[from.String()]
+	ah.mu.RUnlock()
+	if !known || profile.DID != orig.DID || len(orig.Signature) == 0 {
+		return
+	}
+	e.relay = &relaySource{orig: orig, originKey: profile.PublicKey}
+}
+
+// serves reports whether the host advertises every capability intent needs.
+func (ah *AgentHost) serves(intent *core.IntentMessage) bool {
+	for _, c := range intent.Capabilities {
+		if !slices.Contains(ah.agent.Capabilities, c) {
+			return false
+		}
+	}
+	return true
+}
+
+// relay forwards intent, received from `from` and tracked by e, if the host
+// does not serve it.  It returns nil when the intent should be served
+// locally.
+func (ah *AgentHost) relay(ctx context.Context, from peer.ID, intent *core.IntentMessage, e *pendingEntry) *core.NegotiationResponse {
+	src := e.relay
+	if src == nil || ah.serves(intent) || core.IsHealthIntent(intent) {
+		return nil
+	}
+	var env *core.ForwardEnvelope
+	var err error
+	switch {
+	case src.env == nil:
+		env, err = core.NewForwardEnvelope(ah.agent, src.orig, src.originKey, int32(ah.relayHops-1))
+	case ah.relayHops > 0:
+		env, err = src.env.Next(ah.agent)
+	default:
+		err = core.ErrHopLimit
+	}
+	if err == nil {
+		if resp := ah.forward(ctx, from, intent, env); resp != nil {
+			return resp
+		}
+	}
+	if src.env == nil {
+		return nil
+	}
+	return ah.rejection(intent, core.ReasonNoRoute)
+}
+
+// forward sends env to the best candidate next hop, trying the others in
+// turn while hops answer core.ReasonNoRoute or fail.  It returns nil if no
+// candidate produced a response.
+func (ah *AgentHost) forward(ctx context.Context, from peer.ID, intent *core.IntentMessage, env *core.ForwardEnvelope) *core.NegotiationResponse {
+	for _, c := range ah.nextHops(from, intent, env) {
+		fctx, cancel := context.WithTimeout(ctx, relayTimeout)
+		resp, err := ah.sendForward(fctx, c.pid, env)
+		cancel()
+		if err != nil || !resp.Accepted && resp.Reason == core.ReasonNoRoute {
+			continue
+		}
+		ah.emit(Event{
+			Kind:         EventIntentRelayed,
+			PeerID:       c.pid,
+			AgentID:      c.profile.AgentID,
+			DID:          intent.DID,
+			IntentID:     intent.ID,
+			Capabilities: intent.Capabilities,
+		})
+		resp.RelayPath = append([]string{ah.agent.DID.String()}, resp.RelayPath...)
+		return resp
+	}
+	return nil
+}
+
+type hop struct {
+	pid     peer.ID
+	profile core.AgentProfile
+}
+
+// nextHops lists the handshaked peers env may go to: those with every
+// capability intent needs, best match first, then, if env allows a further
+// relay, the others by trust.  The sender, the originator and every relay
+// on the path are skipped.
+func (ah *AgentHost) nextHops(from peer.ID, intent *core.IntentMessage, env *core.ForwardEnvelope) []hop {
+	ah.mu.RLock()
+	var capable, others []core.AgentProfile
+	pids := make(map[string]peer.ID)
+	for key, p := range ah.known {
+		pid, err := peer.Decode(key)
+		if err != nil || pid == from || p.DID == intent.DID || slices.Contains(env.Path, p.DID) {
+			continue
+		}
+		pids[p.DID] = pid
+		if hasAll(p.Capabilities, intent.Capabilities) {
+			capable = append(capable, p)
+		} else {
+			others = append(others, p)
+		}
+	}
+	ah.mu.RUnlock()
+
+	var out []hop
+	for _, p := range core.RankCandidates(intent.IntentVector, capable) {
+		out = append(out, hop{pids[p.DID], p})
+	}
+	if env.Hops > 0 {
+		self := ah.agent.DID.String()
+		slices.SortFunc(others, func(a, b core.AgentProfile) int {
+			ta, tb := ah.trust.Get(self, a.DID), ah.trust.Get(self, b.DID)
+			switch {
+			case ta > tb:
+				return -1
+			case ta < tb:
+				return 1
+			}
+			return 0
+		})
+		for _, p := range others {
+			out = append(out, hop{pids[p.DID], p})
+		}
+	}
+	return out
+}
+
+func hasAll(have, want []string) bool {
+	for _, c := range want {
+		if !slices.Contains(have, c) {
+			return false
+		}
+	}
+	return true
+}
+
+// sendForward sends env to pid and returns the verified response.
+func (ah *AgentHost) sendForward(ctx context.Context, pid peer.ID, env *core.ForwardEnvelope) (*core.NegotiationResponse, error) {
+	stream, err := ah.newStream(ctx, pid, ah.proto)
+	if err != nil {
+		return nil, fmt.Errorf("p2p relay: open stream: %w", err)
+	}
+	defer stream.Close()
+	stop := context.AfterFunc(ctx, func() { _ = stream.Reset() })
+	defer stop()
+
+	if err = ah.writePeerMsg(stream, pid, env); err != nil {
+		ah.streamError("write", err)
+		return nil, fmt.Errorf("p2p relay: send: %w", err)
+	}
+	msgType, data, err := ah.readMsg(stream)
+	if err != nil {
+		ah.streamError("read", err)
+		return nil, fmt.Errorf("p2p relay: recv: %w", err)
+	}
+	if msgType != core.MsgNegotiation {
+		return nil, fmt.Errorf("p2p relay: expected MsgNegotiation, got 0x%02x", msgType)
+	}
+	resp, err := ah.limits.DecodeNegotiationResponse(data)
+	if err != nil {
+		return nil, fmt.Errorf("p2p relay: decode response: %w", err)
+	}
+	if resp.RequestID != env.Intent.ID {
+		return nil, fmt.Errorf("p2p relay: response to %q, want %q", resp.RequestID, env.Intent.ID)
+	}
+	if err = core.VerifyRelayedResponse(resp); err != nil {
+		return nil, fmt.Errorf("p2p relay: %w", err)
+	}
+	return resp, nil
+}
+
+// handleIncomingForward serves or relays an intent forwarded by a relay.
+func (ah *AgentHost) handleIncomingForward(s network.Stream, data []byte) {
+	from := s.Conn().RemotePeer()
+	env, err := ah.limits.DecodeForwardEnvelope(data)
+	if err != nil || core.VerifyForwardEnvelope(env) != nil {
+		return
+	}
+	// The envelope must come from the relay that signed it.
+	ah.mu.RLock()
+	profile, known := ah.known[from.String()]
+	ah.mu.RUnlock()
+	switch {
+	case known && profile.DID != env.RelayDID:
+		return
+	case !known && ah.sigPolicy == RequireSigned:
+		return
+	}
+	if ah.revocations.Check(env.RelayDID, core.RevokedAtIntent) {
+		return
+	}
+
+	// Admission decrypts and normalises the intent; relays need it as signed.
+	orig := env.Intent
+	b, err := orig.Encode()
+	if err != nil {
+		return
+	}
+	intent, err := ah.limits.DecodeIntentMessage(b)
+	if err != nil {
+		return
+	}
+	ah.deprecations.ObserveIntent(intent)
+	if ah.revocations.Check(intent.DID, core.RevokedAtIntent) || !ah.admitVerified(from, profile.AgentID, intent) {
+		return
+	}
+
+	e := ah.trackIntent(from, intent, IntentQueued)
+	defer ah.finishIntent(e)
+	e.relay = &relaySource{orig: orig, originKey: env.OriginKey, env: env}
+
+	resp := ah.answerIntent(from, intent, e)
+	if resp == nil {
+		return
+	}
+	if resp.DID == ah.agent.DID.String() {
+		resp.ResponderKey = ah.agent.PublicKey()
+	}
+	_ = ah.writePeerMsg(s, from, resp)
+	ah.applyTrust(intent.DID, resp.TrustDelta)
+}
+
+// checkRelayedResponse applies the signature policy to a response that
+// reached the host through relays; profile is the neighbour it came from.
+func (ah *AgentHost) checkRelayedResponse(profile core.AgentProfile, known bool, resp *core.NegotiationResponse) error {
+	switch {
+	case ah.sigPolicy == VerifyOff:
+		return nil
+	case known && profile.DID != resp.RelayPath[0]:
+		return fmt.Errorf("response relayed by %s but peer handshaked as %s", resp.RelayPath[0], profile.DID)
+	case !known && ah.sigPolicy == RequireSigned:
+		return fmt.Errorf("no handshake with %s", resp.RelayPath[0])
+	}
+	return core.VerifyRelayedResponse(resp)
+}
@@ -0,0 +1,68 @@
+package p2p_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+// TestRelayIntent verifies that a relay forwards an intent it cannot serve
+// to a peer the originator cannot reach and returns that peer's response.
+func TestRelayIntent(t *testing.T) {
+	alpha := makeAgent(t, "alpha", []string{"nlp"})
+	relay := makeAgent(t, "relay", []string{"routing"})
+	beta := makeAgent(t, "beta", []string{"ocr"})
+
+	hA := makeHost(t, alpha)
+	hR, err := p2p.NewHost(context.Background(), relay, p2p.WithRelay(0))
+	if err != nil {
+		t.Fatalf("NewHost: %v", err)
+	}
+	t.Cleanup(func() { _ = hR.Close() })
+	hB := makeHost(t, beta)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for _, link := range [][2]*p2p.AgentHost{{hA, hR}, {hR, hB}} {
+		if err := link[0].Connect(ctx, link[1].AddrInfo()); err != nil {
+			t.Fatalf("Connect: %v", err)
+		}
+		if _, err := link[0].Handshake(ctx, link[1].PeerID()); err != nil {
+			t.Fatalf("Handshake: %v", err)
+		}
+	}
+	relayed := make(chan p2p.Event, 1)
+	hR.Subscribe(func(ev p2p.Event) { relayed <- ev }, p2p.EventIntentRelayed)
+
+	intent, _ := core.CreateIntent(alpha, nil, []string{"ocr"}, "scan this")
+	resp, err := hA.SendIntent(ctx, hR.PeerID(), intent)
+	if err != nil {
+		t.Fatalf("SendIntent: %v", err)
+	}
+	if !resp.Accepted || resp.DID != beta.DID.String() {
+		t.Fatalf("got accepted=%v from %s (%s), want beta's acceptance", resp.Accepted, resp.DID, resp.Reason)
+	}
+	if len(resp.RelayPath) != 1 || resp.RelayPath[0] != relay.DID.String() {
+		t.Errorf("RelayPath = %v", resp.RelayPath)
+	}
+	select {
+	case ev := <-relayed:
+		if ev.PeerID != hB.PeerID() || ev.IntentID != intent.ID {
+			t.Errorf("relay event: %+v", ev)
+		}
+	default:
+		t.Error("no intent_relayed event")
+	}
+
+	// Nobody offers the capability: the relay answers for itself.
+	missing, _ := core.CreateIntent(alpha, nil, []string{"translate"}, "hola")
+	if resp, err = hA.SendIntent(ctx, hR.PeerID(), missing); err != nil {
+		t.Fatalf("SendIntent: %v", err)
+	}
+	if resp.Accepted || resp.DID != relay.DID.String() || len(resp.RelayPath) != 0 {
+		t.Errorf("unroutable intent: accepted=%v from %s via %v", resp.Accepted, resp.DID, resp.RelayPath)
+	}
+}
@@ -0,0 +1,268 @@
+package p2p
+
+// replan.go — Re-routing workflow steps away from degraded peers.
+//
+// A ReplanPolicy tells the orchestrator when to stop trusting a peer in the
+// middle of a workflow: its trust score drops below a floor, or it breaches
+// the step latency SLA too often.  The peer is then abandoned for the rest of
+// that workflow run: its outstanding intents are cancelled, those steps and
+// every later one are routed to the next-best candidate, and a
+// WorkflowReplanned event is added to the workflow's history.
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+// ReplanPolicy configures automatic re-routing.  The zero value never
+// abandons a peer.
+type ReplanPolicy struct {
+	// MinTrust abandons a peer whose trust score has fallen below it since
+	// the workflow first used the peer.  Zero disables the check.
+	MinTrust float32
+	// MaxStepLatency is the per-step SLA; a step that takes longer, or gets
+	// no answer at all, is a breach.  Zero disables the check.
+	MaxStepLatency time.Duration
+	// MaxBreaches is how many SLA breaches a peer may commit in one run
+	// before it is abandoned (default 1).
+	MaxBreaches int
+	// CheckInterval is how often trust is re-checked for peers with
+	// outstanding intents (default 1s).
+	CheckInterval time.Duration
+}
+
+// Workflow history event kinds.
+const (
+	WorkflowStepSent  = "step_sent"
+	WorkflowStepDone  = "step_done"
+	WorkflowReplanned = "replanned"
+)
+
+// WorkflowEvent is one entry in a workflow's history.
+type WorkflowEvent struct {
+	Kind      string
+	StepID    string // Empty for WorkflowReplanned
+	AgentID   string
+	Reason    string
+	Timestamp time.Time
+}
+
+// SetReplanPolicy sets the policy for workflows started from now on.
+func (o *WorkflowOrchestrator) SetReplanPolicy(p ReplanPolicy) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.policy = p
+}
+
+// History returns the events recorded for workflowID, oldest first.
+func (o *WorkflowOrchestrator) History(workflowID string) []WorkflowEvent {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]WorkflowEvent(nil), o.history[workflowID]...)
+}
+
+// ForgetHistory drops the events recorded for workflowID.
+func (o *WorkflowOrchestrator) ForgetHistory(workflowID string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	delete(o.history, workflowID)
+}
+
+func (o *WorkflowOrchestrator) record(workflowID string, ev WorkflowEvent) {
+	ev.Timestamp = time.Now()
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.history[workflowID] = append(o.history[workflowID], ev)
+}
+
+// workflowRun is the re-planning state of one RunWorkflow/RunSequential call.
+type workflowRun struct {
+	o      *WorkflowOrchestrator
+	id     string
+	policy ReplanPolicy
+	opts   WorkflowOptions
+	plan   map[string]StepPlan // step ID → inherited hints
+
+	mu        sync.Mutex
+	abandoned map[string]string // agentID → reason
+	baseline  map[string]float32
+	dids      map[string]string // agentID → DID
+	breaches  map[string]int
+	inflight  map[string]map[*context.CancelFunc]struct{}
+
+	// state is checkpointed to store, if set, as the run progresses.
+	// Only the goroutine driving the run touches it.
+	state *WorkflowState
+	store WorkflowStore
+}
+
+// newRun starts a run of steps.  state is the checkpoint it continues, or
+// nil for runs that are not checkpointed.
+func (o *WorkflowOrchestrator) newRun(ctx context.Context, workflowID string, steps []WorkflowStep, state *WorkflowState) *workflowRun {
+	plan := make(map[string]StepPlan, len(steps))
+	for _, sp := range o.Plan(ctx, steps) {
+		plan[sp.StepID] = sp
+	}
+	o.mu.Lock()
+	p, store := o.policy, o.store
+	o.mu.Unlock()
+	if state == nil {
+		state = &WorkflowState{WorkflowID: workflowID, Steps: steps, Status: WorkflowRunning}
+		store = nil
+	}
+	if p.MaxBreaches <= 0 {
+		p.MaxBreaches = 1
+	}
+	if p.CheckInterval <= 0 {
+		p.CheckInterval = time.Second
+	}
+	return &workflowRun{
+		o:         o,
+		id:        workflowID,
+		policy:    p,
+		opts:      workflowOptions(ctx),
+		plan:      plan,
+		abandoned: make(map[string]string),
+		baseline:  make(map[string]float32),
+		dids:      make(map[string]string),
+		breaches:  make(map[string]int),
+		inflight:  make(map[string]map[*context.CancelFunc]struct{}),
+		state:     state,
+		store:     store,
+	}
+}
+
+// watch re-checks the trust of peers with outstanding intents until ctx is
+// done.  The caller waits on wg.
+func (r *workflowRun) watch(ctx context.Context, wg *sync.WaitGroup) {
+	if r.policy.MinTrust <= 0 {
+		return
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		t := time.NewTicker(r.policy.CheckInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+			r.mu.Lock()
+			busy := make([]string, 0, len(r.inflight))
+			for agentID := range r.inflight {
+				busy = append(busy, agentID)
+			}
+			r.mu.Unlock()
+			for _, agentID := range busy {
+				r.checkTrust(agentID)
+			}
+		}
+	}()
+}
+
+// trust returns our current trust in agentID.
+func (r *workflowRun) trust(did string) float32 {
+	return r.o.host.trust.Get(r.o.host.agent.DID.String(), did)
+}
+
+// usable reports whether agentID may still be used, recording its trust
+// baseline the first time it is seen.
+func (r *workflowRun) usable(agentID, did string) bool {
+	r.mu.Lock()
+	if _, gone := r.abandoned[agentID]; gone {
+		r.mu.Unlock()
+		return false
+	}
+	if _, seen := r.dids[agentID]; !seen {
+		r.dids[agentID] = did
+		r.baseline[agentID] = r.trust(did)
+	}
+	r.mu.Unlock()
+	return !r.checkTrust(agentID)
+}
+
+// checkTrust abandons agentID if its trust has dropped below the policy
+// floor, and reports whether it is abandoned.
+func (r *workflowRun) checkTrust(agentID string) bool {
+	if r.policy.MinTrust <= 0 {
+		return r.isAbandoned(agentID)
+	}
+	r.mu.Lock()
+	did, base := r.dids[agentID], r.baseline[agentID]
+	r.mu.Unlock()
+	if s := r.trust(did); s < r.policy.MinTrust && s < base {
+		r.abandon(agentID, "trust dropped below threshold")
+		return true
+	}
+	return r.isAbandoned(agentID)
+}
+
+func (r *workflowRun) isAbandoned(agentID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, gone := r.abandoned[agentID]
+	return gone
+}
+
+// abandon stops using agentID for the rest of the run and cancels its
+// outstanding intents.
+func (r *workflowRun) abandon(agentID, reason string) {
+	r.mu.Lock()
+	if _, gone := r.abandoned[agentID]; gone {
+		r.mu.Unlock()
+		return
+	}
+	r.abandoned[agentID] = reason
+	cancels := r.inflight[agentID]
+	delete(r.inflight, agentID)
+	r.mu.Unlock()
+
+	for c := range cancels {
+		(*c)()
+	}
+	r.o.record(r.id, WorkflowEvent{Kind: WorkflowReplanned, AgentID: agentID, Reason: reason})
+}
+
+// track registers cancel as an outstanding intent with agentID.  The
+// returned func unregisters it.
+func (r *workflowRun) track(agentID string, cancel context.CancelFunc) func() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.inflight[agentID] == nil {
+		r.inflight[agentID] = make(map[*context.CancelFunc]struct{})
+	}
+	r.inflight[agentID][&cancel] = struct{}{}
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.inflight[agentID], &cancel)
+		if len(r.inflight[agentID]) == 0 {
+			delete(r.inflight, agentID)
+		}
+	}
+}
+
+// observe counts an SLA breach against agentID, reached at peerID, if the
+// step took too long or failed, abandoning the peer once it has used up its
+// allowance.  Slow answers also count against the peer's error budget.
+func (r *workflowRun) observe(peerID peer.ID, agentID string, took time.Duration, failed bool) {
+	if r.policy.MaxStepLatency <= 0 || (!failed && took <= r.policy.MaxStepLatency) {
+		return
+	}
+	if !failed {
+		r.o.host.peerError(peerID, core.PeerErrorSLA)
+	}
+	r.mu.Lock()
+	r.breaches[agentID]++
+	over := r.breaches[agentID] >= r.policy.MaxBreaches
+	r.mu.Unlock()
+	if over {
+		r.abandon(agentID, "step latency SLA breached")
+	}
+}
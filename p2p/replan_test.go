@@ -0,0 +1,47 @@
+package p2p_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+func TestReplanAwayFromSlowPeer(t *testing.T) {
+	alpha := makeAgent(t, "alpha", []string{"nlp"})
+	hA := makeHost(t, alpha)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for _, id := range []string{"ocr-1", "ocr-2"} {
+		w := makeHost(t, makeAgent(t, id, []string{"ocr"}))
+		if _, err := p2p.DiscoverAndHandshake(ctx, hA, w.AddrInfo()); err != nil {
+			t.Fatalf("DiscoverAndHandshake(%s): %v", id, err)
+		}
+	}
+
+	o := p2p.NewOrchestrator(hA, 5*time.Second)
+	// Every step breaches a 1ns SLA, so each peer serves at most one step.
+	o.SetReplanPolicy(p2p.ReplanPolicy{MaxStepLatency: time.Nanosecond})
+	results, err := o.RunSequential(ctx, "wf-replan", []p2p.WorkflowStep{
+		{ID: "scan-1", Capability: "ocr", Payload: "page 1"},
+		{ID: "scan-2", Capability: "ocr", Payload: "page 2"},
+	})
+	if err != nil {
+		t.Fatalf("RunSequential: %v", err)
+	}
+	if len(results) != 2 || results[0].AgentID == results[1].AgentID {
+		t.Errorf("steps were not re-routed: %+v", results)
+	}
+
+	var replans int
+	for _, ev := range o.History("wf-replan") {
+		if ev.Kind == p2p.WorkflowReplanned {
+			replans++
+		}
+	}
+	if replans != 2 {
+		t.Errorf("got %d replan events, want 2", replans)
+	}
+}
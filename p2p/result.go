@@ -0,0 +1,65 @@
+package p2p
+
+// result.go — Inline result limits.
+//
+// The host advertises WithMaxResultSize in its handshakes and refuses
+// responses whose ResultPayload exceeds it.  When answering, it holds each
+// peer to the limit that peer advertised: a response whose result would not
+// fit is replaced by a rejection with core.ReasonResultTooLarge.
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+// WithMaxResultSize sets the largest ResultPayload, in bytes, the host
+// accepts in a NegotiationResponse (core.DefaultMaxResultSize by default).
+// Zero refuses inline results altogether.
+func WithMaxResultSize(n int64) HostOption {
+	return func(ah *AgentHost) { ah.maxResult = n }
+}
+
+// PeerMaxResultSize returns the largest inline result peerID accepts, as
+// advertised in its last handshake.
+func (ah *AgentHost) PeerMaxResultSize(peerID peer.ID) (n int64, ok bool) {
+	ah.mu.RLock()
+	defer ah.mu.RUnlock()
+	n, ok = ah.peerResults[peerID]
+	return n, ok
+}
+
+// setPeerMaxResult records the result limit advertised in a handshake with
+// peerID.
+func (ah *AgentHost) setPeerMaxResult(peerID peer.ID, m *core.HandshakeMessage) {
+	ah.mu.Lock()
+	defer ah.mu.Unlock()
+	ah.peerResults[peerID] = m.MaxResultSize
+}
+
+// fitResult returns resp, or a rejection of intent if resp carries a result
+// bigger than from accepts.  Peers we have not shaken hands with are held
+// to core.DefaultMaxResultSize.
+func (ah *AgentHost) fitResult(from peer.ID, intent *core.IntentMessage, resp *core.NegotiationResponse) *core.NegotiationResponse {
+	limit, ok := ah.PeerMaxResultSize(from)
+	if !ok {
+		limit = core.DefaultMaxResultSize
+	}
+	if err := core.CheckResultSize(resp, limit); err != nil {
+		ah.log.Log(context.Background(), slog.LevelWarn, "result dropped",
+			core.LogKeyPeer, from.String(), core.LogKeyIntentID, intent.ID, "error", err)
+		return ah.rejection(intent, core.ReasonResultTooLarge)
+	}
+	return resp
+}
+
+// checkResult refuses a response whose result exceeds the host's limit.
+func (ah *AgentHost) checkResult(resp *core.NegotiationResponse) error {
+	if err := core.CheckResultSize(resp, ah.maxResult); err != nil {
+		return fmt.Errorf("p2p intent: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package p2p_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+func TestInlineResult(t *testing.T) {
+	alpha := makeAgent(t, "alpha", []string{"nlp"})
+	beta := makeAgent(t, "beta", []string{"echo"})
+
+	hA, err := p2p.NewHost(context.Background(), alpha, p2p.WithMaxResultSize(8))
+	if err != nil {
+		t.Fatalf("NewHost: %v", err)
+	}
+	t.Cleanup(func() { _ = hA.Close() })
+	hB := makeHost(t, beta)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := hA.Connect(ctx, hB.AddrInfo()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	if _, err := hA.Handshake(ctx, hB.PeerID()); err != nil {
+		t.Fatalf("Handshake: %v", err)
+	}
+	if n, ok := hB.PeerMaxResultSize(hA.PeerID()); !ok || n != 8 {
+		t.Fatalf("PeerMaxResultSize = %d, %v; want 8", n, ok)
+	}
+
+	hB.OnIntent(func(_ peer.ID, in *core.IntentMessage) *core.NegotiationResponse {
+		resp := &core.NegotiationResponse{
+			RequestID: in.ID,
+			AgentID:   beta.ID,
+			Accepted:  true,
+			DID:       beta.DID.String(),
+			Reason:    "echoed",
+			Result:    &core.ResultPayload{ContentType: "text/plain", Data: []byte(in.Payload)},
+		}
+		_ = core.SignResponse(beta, resp)
+		return resp
+	})
+
+	for _, tc := range []struct {
+		payload  string
+		accepted bool
+	}{
+		{"hello", true},
+		{"far too long to fit", false},
+	} {
+		intent, _ := core.CreateIntent(alpha, nil, []string{"echo"}, tc.payload)
+		resp, err := hA.SendIntent(ctx, hB.PeerID(), intent)
+		if err != nil {
+			t.Fatalf("SendIntent(%q): %v", tc.payload, err)
+		}
+		if resp.Accepted != tc.accepted {
+			t.Fatalf("SendIntent(%q): accepted = %v (%s)", tc.payload, resp.Accepted, resp.Reason)
+		}
+		if tc.accepted && (resp.Result == nil || string(resp.Result.Data) != tc.payload) {
+			t.Errorf("SendIntent(%q): result = %+v", tc.payload, resp.Result)
+		}
+		if !tc.accepted && (resp.Reason != core.ReasonResultTooLarge || resp.Result != nil) {
+			t.Errorf("SendIntent(%q): reason %q, result %+v", tc.payload, resp.Reason, resp.Result)
+		}
+	}
+}
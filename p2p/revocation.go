@@ -0,0 +1,171 @@
+package p2p
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+// RevocationTopic is the GossipSub topic carrying RevocationLists and
+// KeyRotations.
+const RevocationTopic = "/agent-semantic-protocol/revocations"
+
+// WithRevocationIssuers accepts RevocationLists signed by the listed DIDs.
+// Lists from any other issuer are ignored.
+func WithRevocationIssuers(dids ...string) HostOption {
+	return func(ah *AgentHost) {
+		for _, d := range dids {
+			ah.revocations.AddIssuer(d)
+		}
+	}
+}
+
+// WithRevocationFeed fetches a RevocationList from url every interval and
+// shares any newer list with connected peers.  The issuer must also be
+// trusted with WithRevocationIssuers.
+func WithRevocationFeed(url string, interval time.Duration) HostOption {
+	return func(ah *AgentHost) {
+		ah.revocationURL = url
+		ah.revocationInterval = interval
+	}
+}
+
+// RevocationCallback is invoked for every DID newly revoked by an accepted
+// list, after the host has forgotten it.  from is the neighbour that sent
+// the list, empty if it was published or fetched locally.
+type RevocationCallback func(from peer.ID, issuerDID string, entry core.RevocationEntry)
+
+// OnRevocation registers the callback for revocations.
+func (ah *AgentHost) OnRevocation(fn RevocationCallback) {
+	ah.mu.Lock()
+	defer ah.mu.Unlock()
+	ah.onRevocation = fn
+}
+
+// Revocations returns the host's accepted revocation lists and hit counters.
+func (ah *AgentHost) Revocations() *core.RevocationSet { return ah.revocations }
+
+// PublishRevocations applies l locally and shares it with the mesh: on
+// RevocationTopic when GossipSub is enabled, otherwise directly with every
+// connected peer.
+func (ah *AgentHost) PublishRevocations(ctx context.Context, l *core.RevocationList) error {
+	if _, err := ah.acceptRevocations("", l); err != nil {
+		return fmt.Errorf("p2p revocation: %w", err)
+	}
+	return ah.share(ctx, l, "")
+}
+
+// FetchRevocations downloads the list at url and publishes it if it is newer
+// than the one already held for its issuer.
+func (ah *AgentHost) FetchRevocations(ctx context.Context, url string) error {
+	l, err := core.FetchRevocationList(ctx, url)
+	if err != nil {
+		return fmt.Errorf("p2p revocation: %w", err)
+	}
+	changed, err := ah.acceptRevocations("", l)
+	if err != nil || !changed {
+		return err
+	}
+	return ah.share(ctx, l, "")
+}
+
+// acceptRevocations applies l, received from `from`, and, if it is new,
+// forgets every peer it revokes and reports the DIDs it newly revokes.
+func (ah *AgentHost) acceptRevocations(from peer.ID, l *core.RevocationList) (bool, error) {
+	var fresh []core.RevocationEntry
+	for _, e := range l.Entries {
+		if _, revoked := ah.revocations.IsRevoked(e.DID); !revoked {
+			fresh = append(fresh, e)
+		}
+	}
+	changed, err := ah.revocations.Apply(l)
+	if err != nil || !changed {
+		return changed, err
+	}
+	ah.mu.Lock()
+	for pid, p := range ah.known {
+		if _, revoked := ah.revocations.IsRevoked(p.DID); revoked {
+			delete(ah.known, pid)
+		}
+	}
+	ah.mu.Unlock()
+	for _, e := range l.Entries {
+		ah.discovery.Remove(e.DID)
+	}
+
+	ah.mu.RLock()
+	cb := ah.onRevocation
+	ah.mu.RUnlock()
+	for _, e := range fresh {
+		ah.emit(Event{Kind: EventDIDRevoked, PeerID: from, DID: e.DID, Reason: e.Reason})
+		if cb != nil {
+			cb(from, l.IssuerDID, e)
+		}
+	}
+	return true, nil
+}
+
+// share forwards msg, a RevocationList or KeyRotation, to the mesh:
+// on RevocationTopic when GossipSub is enabled, otherwise directly to every
+// connected peer but the one it came from.  It returns once every send has
+// finished.
+func (ah *AgentHost) share(ctx context.Context, msg core.Encoder, except peer.ID) error {
+	if ah.revTopic != nil {
+		payload, err := msg.Encode()
+		if err != nil {
+			return fmt.Errorf("p2p share: encode: %w", err)
+		}
+		if err = ah.revTopic.Publish(ctx, core.Frame(msg.MsgType(), payload)); err != nil {
+			return fmt.Errorf("p2p share: publish: %w", err)
+		}
+		return nil
+	}
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for _, p := range ah.h.Network().Peers() {
+		if p == except {
+			continue
+		}
+		wg.Add(1)
+		go func(pid peer.ID) {
+			defer wg.Done()
+			_ = ah.sendOneWay(ctx, pid, msg)
+		}(p)
+	}
+	return nil
+}
+
+func (ah *AgentHost) handleIncomingRevocation(s network.Stream, data []byte) {
+	l, err := ah.limits.DecodeRevocationList(data)
+	if err != nil {
+		return
+	}
+	changed, err := ah.acceptRevocations(s.Conn().RemotePeer(), l)
+	if err != nil || !changed {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_ = ah.share(ctx, l, s.Conn().RemotePeer())
+}
+
+// revocationFeedLoop polls revocationURL until the host is closed.
+func (ah *AgentHost) revocationFeedLoop() {
+	t := time.NewTicker(ah.revocationInterval)
+	defer t.Stop()
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), ah.revocationInterval)
+		_ = ah.FetchRevocations(ctx, ah.revocationURL)
+		cancel()
+		select {
+		case <-t.C:
+		case <-ah.done:
+			return
+		}
+	}
+}
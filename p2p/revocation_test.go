@@ -0,0 +1,79 @@
+package p2p_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+// TestRevocationRefusesHandshake verifies that a published revocation list
+// reaches a peer that trusts its issuer, and that the peer then refuses
+// handshakes from the revoked DID.
+func TestRevocationRefusesHandshake(t *testing.T) {
+	operator := makeAgent(t, "operator", nil)
+	alpha := makeAgent(t, "alpha", nil)
+	beta := makeAgent(t, "beta", nil)
+	mallory := makeAgent(t, "mallory", nil)
+
+	hA := makeHost(t, alpha)
+	hB, err := p2p.NewHost(context.Background(), beta, p2p.WithRevocationIssuers(operator.DID.String()))
+	if err != nil {
+		t.Fatalf("NewHost: %v", err)
+	}
+	t.Cleanup(func() { _ = hB.Close() })
+	hM := makeHost(t, mallory)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err = hA.Connect(ctx, hB.AddrInfo()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+
+	revoked := make(chan core.RevocationEntry, 1)
+	hB.OnRevocation(func(from peer.ID, issuer string, e core.RevocationEntry) {
+		if from == hA.PeerID() && issuer == operator.DID.String() {
+			revoked <- e
+		}
+	})
+
+	hA.Revocations().AddIssuer(operator.DID.String())
+	l, err := core.NewRevocationList(operator, 1, []core.RevocationEntry{{DID: mallory.DID.String(), Reason: "compromised"}})
+	if err != nil {
+		t.Fatalf("NewRevocationList: %v", err)
+	}
+	if err = hA.PublishRevocations(ctx, l); err != nil {
+		t.Fatalf("PublishRevocations: %v", err)
+	}
+	for {
+		if _, ok := hB.Revocations().IsRevoked(mallory.DID.String()); ok {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatal("revocation list did not reach beta")
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+
+	if err = hM.Connect(ctx, hB.AddrInfo()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	if _, err = hM.Handshake(ctx, hB.PeerID()); err == nil {
+		t.Error("expected beta to refuse mallory's handshake")
+	}
+	if hits := hB.Revocations().Stats().Handshakes; hits != 1 {
+		t.Errorf("handshake hits: got %d want 1", hits)
+	}
+	select {
+	case e := <-revoked:
+		if e.DID != mallory.DID.String() || e.Reason != "compromised" {
+			t.Errorf("OnRevocation got %+v", e)
+		}
+	default:
+		t.Error("OnRevocation was not called")
+	}
+}
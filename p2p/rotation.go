@@ -0,0 +1,68 @@
+package p2p
+
+// rotation.go — Propagating key rotations.
+//
+// An agent replacing its key publishes a core.KeyRotation signed by the old
+// and new keys.  Rotations travel like revocation lists: on RevocationTopic
+// with GossipSub, otherwise flooded peer to peer.  A host that accepts one
+// moves the trust it held about the retired DID to the new DID, forgets the
+// retired DID, and refuses its handshakes, intents and announcements from
+// then on.  The agent then restarts its host with the new key and
+// handshakes again.
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+// Rotations returns the key rotations the host has accepted.
+func (ah *AgentHost) Rotations() *core.KeyRotationSet { return ah.rotations }
+
+// PublishKeyRotation applies r locally and shares it with the mesh.
+func (ah *AgentHost) PublishKeyRotation(ctx context.Context, r *core.KeyRotation) error {
+	if _, err := ah.acceptRotation(r); err != nil {
+		return fmt.Errorf("p2p rotation: %w", err)
+	}
+	return ah.share(ctx, r, "")
+}
+
+// acceptRotation applies r and, if it is new, migrates trust to the new DID
+// and forgets the retired one.
+func (ah *AgentHost) acceptRotation(r *core.KeyRotation) (bool, error) {
+	changed, err := ah.rotations.Apply(r)
+	if err != nil || !changed {
+		return changed, err
+	}
+	if err = ah.trust.Migrate(r.OldDID, r.NewDID); err != nil {
+		ah.log.Log(context.Background(), slog.LevelWarn, "trust migration not persisted",
+			"old_did", r.OldDID, "new_did", r.NewDID, "error", err)
+	}
+	ah.mu.Lock()
+	for pid, p := range ah.known {
+		if p.DID == r.OldDID {
+			delete(ah.known, pid)
+		}
+	}
+	ah.mu.Unlock()
+	ah.discovery.Remove(r.OldDID)
+	return true, nil
+}
+
+func (ah *AgentHost) handleIncomingKeyRotation(s network.Stream, data []byte) {
+	r, err := ah.limits.DecodeKeyRotation(data)
+	if err != nil {
+		return
+	}
+	changed, err := ah.acceptRotation(r)
+	if err != nil || !changed {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_ = ah.share(ctx, r, s.Conn().RemotePeer())
+}
@@ -0,0 +1,54 @@
+package p2p_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+func TestKeyRotationMigratesTrust(t *testing.T) {
+	alpha := makeAgent(t, "alpha", []string{"summarisation"})
+	beta := makeAgent(t, "beta", nil)
+	hA, hB := makeHost(t, alpha), makeHost(t, beta)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := p2p.DiscoverAndHandshake(ctx, hB, hA.AddrInfo()); err != nil {
+		t.Fatalf("DiscoverAndHandshake: %v", err)
+	}
+	self, old := beta.DID.String(), alpha.DID.String()
+	_ = hB.Trust().Set(self, old, 0.9)
+
+	next, r, err := alpha.Rotate("key compromised")
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if err = hA.PublishKeyRotation(ctx, r); err != nil {
+		t.Fatalf("PublishKeyRotation: %v", err)
+	}
+	for {
+		if _, ok := hB.Rotations().Retired(old); ok {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatal("rotation did not reach beta")
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+	if got := hB.Trust().Get(self, next.DID.String()); got != 0.9 {
+		t.Errorf("trust in rotated DID = %v; want 0.9", got)
+	}
+	if _, ok := hB.KnownPeers()[hA.PeerID()]; ok {
+		t.Error("beta still knows alpha's retired DID")
+	}
+	if _, err = hB.Handshake(ctx, hA.PeerID()); err == nil {
+		t.Error("expected beta to refuse a handshake with the retired DID")
+	}
+
+	hN := makeHost(t, next)
+	if _, err = p2p.DiscoverAndHandshake(ctx, hB, hN.AddrInfo()); err != nil {
+		t.Fatalf("handshake with the new key: %v", err)
+	}
+}
@@ -0,0 +1,182 @@
+package p2p
+
+// sched.go — Priority scheduling of frames on shared streams.
+//
+// A multiplexed session carries small control messages and large payloads
+// over one stream.  Writing frames in arrival order would leave a
+// negotiation response queued behind a run of partial-output chunks, so each
+// frame is assigned a FrameClass and a single writer per stream always sends
+// the most urgent waiting frame first.  To keep bulk traffic moving under a
+// constant stream of control frames, the lowest waiting class gets one write
+// after every bulkShare consecutive writes from higher classes.
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+// FrameClass is a frame's scheduling priority; lower values go first.
+type FrameClass int
+
+const (
+	ClassControl     FrameClass = iota // Handshakes, responses, alerts, announcements, high-priority intents
+	ClassInteractive                   // Intents and workflow steps
+	ClassBulk                          // Partial output, low-priority intents and oversized frames
+	numFrameClasses
+)
+
+const (
+	// bulkFrameSize is the payload size from which any frame is ClassBulk.
+	bulkFrameSize = 64 * 1024
+	// bulkShare is how many consecutive higher-class frames may be written
+	// while a lower class is waiting.
+	bulkShare = 8
+)
+
+// ClassifyFrame returns the scheduling class of msg encoded in size bytes.
+func ClassifyFrame(msg core.Encoder, size int) FrameClass {
+	if size >= bulkFrameSize {
+		return ClassBulk
+	}
+	switch m := msg.(type) {
+	case *core.IntentMessage:
+		switch p := m.Priority(); {
+		case p >= core.PriorityHigh:
+			return ClassControl
+		case p < core.PriorityNormal:
+			return ClassBulk
+		}
+		return ClassInteractive
+	case *core.WorkflowMessage:
+		if m.Action == core.ProgressPartialOutput {
+			return ClassBulk
+		}
+		return ClassInteractive
+	default:
+		return ClassControl
+	}
+}
+
+type queuedFrame struct {
+	buf  []byte
+	sent chan error
+}
+
+// frameScheduler serialises writes to one stream in priority order.
+type frameScheduler struct {
+	w        io.Writer
+	deadline func(time.Time) error
+	timeout  time.Duration
+
+	mu     sync.Mutex
+	queues [numFrameClasses][]*queuedFrame
+	streak int   // higher-class writes since a lower class last went
+	err    error // set once closed
+	wake   chan struct{}
+	done   chan struct{}
+}
+
+// newFrameScheduler starts a writer for w.  Each write is bounded by
+// timeout through deadline.
+func newFrameScheduler(w io.Writer, deadline func(time.Time) error, timeout time.Duration) *frameScheduler {
+	s := &frameScheduler{
+		w:        w,
+		deadline: deadline,
+		timeout:  timeout,
+		wake:     make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+// write queues buf in class and waits until it has been written.
+func (s *frameScheduler) write(class FrameClass, buf []byte) error {
+	f := &queuedFrame{buf: buf, sent: make(chan error, 1)}
+	s.mu.Lock()
+	if s.err != nil {
+		s.mu.Unlock()
+		return s.err
+	}
+	s.queues[class] = append(s.queues[class], f)
+	s.mu.Unlock()
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	select {
+	case err := <-f.sent:
+		return err
+	case <-s.done:
+		return s.err
+	}
+}
+
+// close stops the writer; queued and future writes fail with err.
+func (s *frameScheduler) close(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return
+	}
+	s.err = err
+	close(s.done)
+}
+
+// next removes and returns the frame to write, or nil if none is waiting.
+func (s *frameScheduler) next() *queuedFrame {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	first, last := -1, -1
+	for c := range s.queues {
+		if len(s.queues[c]) > 0 {
+			if first < 0 {
+				first = c
+			}
+			last = c
+		}
+	}
+	if first < 0 {
+		return nil
+	}
+	pick := first
+	if last != first {
+		if s.streak >= bulkShare {
+			pick = last
+			s.streak = 0
+		} else {
+			s.streak++
+		}
+	} else {
+		s.streak = 0
+	}
+	f := s.queues[pick][0]
+	s.queues[pick][0] = nil
+	s.queues[pick] = s.queues[pick][1:]
+	return f
+}
+
+func (s *frameScheduler) run() {
+	for {
+		f := s.next()
+		if f == nil {
+			select {
+			case <-s.wake:
+				continue
+			case <-s.done:
+				return
+			}
+		}
+		_ = s.deadline(time.Now().Add(s.timeout))
+		_, err := s.w.Write(f.buf)
+		if err != nil {
+			f.sent <- err
+			s.close(err)
+			return
+		}
+		f.sent <- nil
+	}
+}
@@ -0,0 +1,34 @@
+package p2p_test
+
+import (
+	"testing"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+func TestClassifyFrame(t *testing.T) {
+	chunk := &core.WorkflowMessage{Action: core.ProgressPartialOutput}
+	urgent, background := &core.IntentMessage{}, &core.IntentMessage{}
+	urgent.SetPriority(core.PriorityHigh)
+	background.SetPriority(core.PriorityLow)
+	cases := []struct {
+		msg  core.Encoder
+		size int
+		want p2p.FrameClass
+	}{
+		{&core.NegotiationResponse{}, 100, p2p.ClassControl},
+		{&core.AlertMessage{}, 100, p2p.ClassControl},
+		{&core.IntentMessage{}, 100, p2p.ClassInteractive},
+		{&core.WorkflowMessage{Action: core.ProgressStepStarted}, 100, p2p.ClassInteractive},
+		{chunk, 100, p2p.ClassBulk},
+		{&core.IntentMessage{}, 1 << 20, p2p.ClassBulk},
+		{urgent, 100, p2p.ClassControl},
+		{background, 100, p2p.ClassBulk},
+	}
+	for _, c := range cases {
+		if got := p2p.ClassifyFrame(c.msg, c.size); got != c.want {
+			t.Errorf("%T (%d bytes): got %d want %d", c.msg, c.size, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package p2p
+
+// schedule.go — Bounding the steps an orchestrator has outstanding.
+//
+// By default every step an orchestrator dispatches is sent at once: a
+// RunWorkflow of a thousand steps opens a thousand intents.  SetScheduler
+// caps the steps outstanding across all of the orchestrator's workflows,
+// in total and per capability.  A step that finds no free slot waits in a
+// queue; freed slots go to the waiting step of the highest workflow
+// priority (see WithWorkflowOptions), the earliest queued first among
+// equals.  A step whose capability is at its limit does not hold up steps
+// of other capabilities queued behind it.  MaxQueued bounds the queue
+// itself: a step that would exceed it fails with ErrSchedulerFull rather
+// than wait.
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+// ErrSchedulerFull is returned for a step that finds the orchestrator's
+// queue full.
+var ErrSchedulerFull = fmt.Errorf("p2p: workflow step queue is full")
+
+// SchedulerConfig bounds the workflow steps an orchestrator has
+// outstanding.  The zero value sets no bounds.
+type SchedulerConfig struct {
+	MaxParallel   int            // Steps outstanding at once; 0 = unlimited
+	PerCapability map[string]int // Steps outstanding at once per capability; missing or 0 = unlimited
+	MaxQueued     int            // Steps waiting for a slot; 0 = unlimited
+}
+
+// Validate checks that c describes a usable configuration.
+func (c SchedulerConfig) Validate() error {
+	if c.MaxParallel < 0 || c.MaxQueued < 0 {
+		return fmt.Errorf("scheduler: limits must not be negative")
+	}
+	for capability, n := range c.PerCapability {
+		if n < 0 {
+			return fmt.Errorf("scheduler: limit for %q must not be negative", capability)
+		}
+	}
+	return nil
+}
+
+// SchedulerStats is a snapshot of an orchestrator's scheduler.
+type SchedulerStats struct {
+	Running      int            // Steps outstanding
+	Queued       int            // Steps waiting for a slot
+	ByCapability map[string]int // Steps outstanding per capability
+}
+
+// SetScheduler bounds the orchestrator's outstanding steps as cfg says.
+// It applies at once, to steps already waiting too.
+func (o *WorkflowOrchestrator) SetScheduler(cfg SchedulerConfig) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	limits := make(map[string]int, len(cfg.PerCapability))
+	for capability, n := range cfg.PerCapability {
+		limits[capability] = n
+	}
+	cfg.PerCapability = limits
+	o.sched.configure(cfg)
+	return nil
+}
+
+// SchedulerStats returns what the orchestrator's scheduler holds now.
+func (o *WorkflowOrchestrator) SchedulerStats() SchedulerStats {
+	return o.sched.stats()
+}
+
+// stepScheduler hands out slots for outstanding steps.
+type stepScheduler struct {
+	mu      sync.Mutex
+	cfg     SchedulerConfig
+	running int
+	byCap   map[string]int
+	queue   []*stepTicket // Highest priority first, in arrival order among equals
+}
+
+// stepTicket is a step waiting for a slot.
+type stepTicket struct {
+	capability string
+	priority   core.Priority
+	granted    chan struct{}
+}
+
+func newStepScheduler() *stepScheduler {
+	return &stepScheduler{byCap: make(map[string]int)}
+}
+
+func (s *stepScheduler) configure(cfg SchedulerConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cfg = cfg
+	s.grant()
+}
+
+// acquire waits for a slot for a step of capability and returns the func
+// that frees it.
+func (s *stepScheduler) acquire(ctx context.Context, capability string, priority core.Priority) (func(), error) {
+	s.mu.Lock()
+	t := &stepTicket{capability: capability, priority: priority, granted: make(chan struct{})}
+	i := sort.Search(len(s.queue), func(i int) bool { return s.queue[i].priority < priority })
+	s.queue = append(s.queue, nil)
+	copy(s.queue[i+1:], s.queue[i:])
+	s.queue[i] = t
+	s.grant()
+	if s.cfg.MaxQueued > 0 && len(s.queue) > s.cfg.MaxQueued && s.dequeue(t) {
+		s.mu.Unlock()
+		return nil, ErrSchedulerFull
+	}
+	s.mu.Unlock()
+
+	release := func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.free(capability)
+	}
+	select {
+	case <-t.granted:
+		return release, nil
+	case <-ctx.Done():
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.dequeue(t) {
+		// Granted while giving up: hand the slot on.
+		s.free(capability)
+	}
+	return nil, ctx.Err()
+}
+
+// dequeue removes t from the queue and reports whether it was waiting.
+func (s *stepScheduler) dequeue(t *stepTicket) bool {
+	for i, q := range s.queue {
+		if q == t {
+			s.queue = append(s.queue[:i], s.queue[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (s *stepScheduler) fits(capability string) bool {
+	if s.cfg.MaxParallel > 0 && s.running >= s.cfg.MaxParallel {
+		return false
+	}
+	limit := s.cfg.PerCapability[capability]
+	return limit == 0 || s.byCap[capability] < limit
+}
+
+func (s *stepScheduler) take(capability string) {
+	s.running++
+	s.byCap[capability]++
+}
+
+// free returns a slot of capability and hands it on.
+func (s *stepScheduler) free(capability string) {
+	s.running--
+	if s.byCap[capability]--; s.byCap[capability] <= 0 {
+		delete(s.byCap, capability)
+	}
+	s.grant()
+}
+
+// grant hands free slots to waiting steps in queue order, passing over
+// steps whose capability is at its limit.
+func (s *stepScheduler) grant() {
+	kept := s.queue[:0]
+	for _, t := range s.queue {
+		if s.fits(t.capability) {
+			s.take(t.capability)
+			close(t.granted)
+			continue
+		}
+		kept = append(kept, t)
+	}
+	clear(s.queue[len(kept):])
+	s.queue = kept
+}
+
+func (s *stepScheduler) maxParallel() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cfg.MaxParallel
+}
+
+func (s *stepScheduler) stats() SchedulerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := SchedulerStats{Running: s.running, Queued: len(s.queue), ByCapability: make(map[string]int, len(s.byCap))}
+	for capability, n := range s.byCap {
+		st.ByCapability[capability] = n
+	}
+	return st
+}
@@ -0,0 +1,76 @@
+package p2p_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+func TestWorkflowScheduler(t *testing.T) {
+	alpha := makeAgent(t, "alpha", nil)
+	beta := makeAgent(t, "beta", []string{"fast", "slow"})
+	hA := makeHost(t, alpha)
+	hB := makeHost(t, beta)
+
+	var mu sync.Mutex
+	inflight := make(map[string]int)
+	peak := make(map[string]int) // capability, or "" for all → most outstanding at once
+	hB.OnIntent(func(_ peer.ID, in *core.IntentMessage) *core.NegotiationResponse {
+		c := in.Capabilities[0]
+		mu.Lock()
+		inflight[c]++
+		inflight[""]++
+		for _, k := range []string{c, ""} {
+			peak[k] = max(peak[k], inflight[k])
+		}
+		mu.Unlock()
+		time.Sleep(50 * time.Millisecond)
+		mu.Lock()
+		inflight[c]--
+		inflight[""]--
+		mu.Unlock()
+		resp, _ := core.DefaultNegotiationHandler(beta)(in)
+		return resp
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := p2p.DiscoverAndHandshake(ctx, hA, hB.AddrInfo()); err != nil {
+		t.Fatalf("DiscoverAndHandshake: %v", err)
+	}
+
+	o := p2p.NewOrchestrator(hA, 5*time.Second)
+	if err := o.SetScheduler(p2p.SchedulerConfig{MaxParallel: -1}); err == nil {
+		t.Error("SetScheduler accepted a negative limit")
+	}
+	if err := o.SetScheduler(p2p.SchedulerConfig{MaxParallel: 3, PerCapability: map[string]int{"slow": 1}}); err != nil {
+		t.Fatalf("SetScheduler: %v", err)
+	}
+	var steps []p2p.WorkflowStep
+	for i := 0; i < 8; i++ {
+		steps = append(steps, p2p.WorkflowStep{ID: fmt.Sprintf("s%d", i), Capability: []string{"fast", "slow"}[i%2]})
+	}
+	results, err := o.RunWorkflow(ctx, "wf", steps)
+	if err != nil {
+		t.Fatalf("RunWorkflow: %v", err)
+	}
+	for _, r := range results {
+		if !r.Accepted {
+			t.Errorf("step %s not accepted: %s", r.StepID, r.Reason)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if peak[""] > 3 || peak["slow"] > 1 {
+		t.Errorf("peak outstanding = %v; want at most 3 in all and 1 slow", peak)
+	}
+	if st := o.SchedulerStats(); st.Running != 0 || st.Queued != 0 {
+		t.Errorf("SchedulerStats after the run = %+v", st)
+	}
+}
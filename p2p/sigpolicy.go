@@ -0,0 +1,90 @@
+package p2p
+
+import (
+	"fmt"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+// SignaturePolicy controls how an AgentHost verifies the Ed25519 signatures
+// on incoming intents and negotiation responses.  Keys come from the peer's
+// handshake; a message without a prior handshake has no key to check against.
+type SignaturePolicy int
+
+const (
+	// VerifyIfKnown checks signatures from handshaked peers and accepts
+	// unsigned messages and messages from unknown peers.  This is the default.
+	VerifyIfKnown SignaturePolicy = iota
+	// RequireSigned rejects unsigned messages and messages from peers that
+	// have not completed a handshake.
+	RequireSigned
+	// VerifyOff accepts every message without checking signatures.
+	VerifyOff
+)
+
+// String returns the policy name.
+func (p SignaturePolicy) String() string {
+	switch p {
+	case VerifyIfKnown:
+		return "verify-if-known"
+	case RequireSigned:
+		return "require-signed"
+	case VerifyOff:
+		return "off"
+	}
+	return fmt.Sprintf("SignaturePolicy(%d)", int(p))
+}
+
+// WithSignaturePolicy sets the host's signature verification policy.
+func WithSignaturePolicy(p SignaturePolicy) HostOption {
+	return func(ah *AgentHost) { ah.sigPolicy = p }
+}
+
+// checkSignature applies the host's policy to a message from a peer whose
+// handshake profile is (profile, known).  did is the sender DID claimed by
+// the message; verify checks the signature against a public key.
+func (ah *AgentHost) checkSignature(
+	profile core.AgentProfile,
+	known bool,
+	did string,
+	signed bool,
+	verify func(pub []byte) bool,
+) error {
+	switch ah.sigPolicy {
+	case VerifyOff:
+		return nil
+	case RequireSigned:
+		if !signed {
+			return fmt.Errorf("unsigned message from %s", did)
+		}
+		if !known {
+			return fmt.Errorf("no handshake with %s", did)
+		}
+	}
+	// A handshaked peer speaks only for its own DID, signed or not.
+	if known && profile.DID != did {
+		return fmt.Errorf("message claims %s but peer handshaked as %s", did, profile.DID)
+	}
+	if !known || !signed {
+		return nil
+	}
+	if !verify(profile.PublicKey) {
+		return fmt.Errorf("%w from %s", core.ErrSignatureInvalid, did)
+	}
+	return nil
+}
+
+// checkIntent applies the signature policy to an intent from the stream's peer.
+func (ah *AgentHost) checkIntent(profile core.AgentProfile, known bool, intent *core.IntentMessage) error {
+	return ah.checkSignature(profile, known, intent.DID, len(intent.Signature) > 0,
+		func(pub []byte) bool { return core.VerifyIntentSignature(intent, pub) })
+}
+
+// checkResponse applies the signature policy to a NegotiationResponse.
+func (ah *AgentHost) checkResponse(profile core.AgentProfile, known bool, resp *core.NegotiationResponse) error {
+	if len(resp.RelayPath) > 0 {
+		return ah.checkRelayedResponse(profile, known, resp)
+	}
+	return ah.checkSignature(profile, known, resp.DID, len(resp.Signature) > 0,
+		func(pub []byte) bool { return core.VerifyResponseSignature(resp, pub) })
+}
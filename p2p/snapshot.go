@@ -0,0 +1,89 @@
+package p2p
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+// Snapshot captures the host's agent, trust graph, discovery registry and,
+// if configured, memory store.  Callers may add queued intents and workflow
+// checkpoints before sealing it with core.SealSnapshot.
+func (ah *AgentHost) Snapshot() (*core.AgentSnapshot, error) {
+	s, err := core.NewAgentSnapshot(ah.agent)
+	if err != nil {
+		return nil, err
+	}
+	s.Trust = ah.trust.Snapshot()
+	s.Discovery = ah.discovery.Export()
+	if ah.memory != nil {
+		exp, ok := ah.memory.(core.MemoryExporter)
+		if !ok {
+			return nil, fmt.Errorf("p2p snapshot: memory store %T cannot be exported", ah.memory)
+		}
+		if s.Memory, err = exp.AllEntries(); err != nil {
+			return nil, fmt.Errorf("p2p snapshot: %w", err)
+		}
+	}
+	return s, nil
+}
+
+// Export returns Snapshot sealed under passphrase.
+func (ah *AgentHost) Export(passphrase []byte) ([]byte, error) {
+	s, err := ah.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	return core.SealSnapshot(s, passphrase)
+}
+
+// Restore loads s's trust scores, discovery entries and memory into the
+// host.  s must belong to the host's agent.  Queued intents and checkpoints
+// are left to the caller.
+func (ah *AgentHost) Restore(s *core.AgentSnapshot) error {
+	a, err := s.Agent()
+	if err != nil {
+		return err
+	}
+	if a.DID.String() != ah.agent.DID.String() {
+		return fmt.Errorf("p2p restore: snapshot belongs to %s, not %s", a.DID, ah.agent.DID)
+	}
+	if err = s.RestoreTrust(ah.trust); err != nil {
+		return err
+	}
+	ah.discovery.Import(s.Discovery)
+	if ah.memory != nil {
+		if err = s.RestoreMemory(ah.memory); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ImportHost opens a bundle written by Export and starts a host for the
+// migrated agent with its state restored.  The snapshot is returned so the
+// caller can resend s.Outbox and resume s.Checkpoints.
+func ImportHost(
+	ctx context.Context,
+	bundle, passphrase []byte,
+	opts ...HostOption,
+) (*AgentHost, *core.AgentSnapshot, error) {
+	s, err := core.OpenSnapshot(bundle, passphrase)
+	if err != nil {
+		return nil, nil, err
+	}
+	agent, err := s.Agent()
+	if err != nil {
+		return nil, nil, err
+	}
+	ah, err := NewHost(ctx, agent, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = ah.Restore(s); err != nil {
+		_ = ah.Close()
+		return nil, nil, err
+	}
+	return ah, s, nil
+}
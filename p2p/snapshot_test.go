@@ -0,0 +1,43 @@
+package p2p_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+// TestExportImportHost verifies that a migrated agent keeps its peer ID,
+// trust scores and memory.
+func TestExportImportHost(t *testing.T) {
+	alpha := makeAgent(t, "alpha", []string{"nlp"})
+	hA, err := p2p.NewHost(context.Background(), alpha, p2p.WithMemoryStore(core.NewInMemoryStore(0)))
+	if err != nil {
+		t.Fatalf("NewHost: %v", err)
+	}
+	_ = hA.Trust().Set(alpha.DID.String(), "did:peer", 0.9)
+
+	bundle, err := hA.Export([]byte("pass"))
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	oldID := hA.PeerID()
+	_ = hA.Close()
+
+	hB, snap, err := p2p.ImportHost(context.Background(), bundle, []byte("pass"),
+		p2p.WithMemoryStore(core.NewInMemoryStore(0)))
+	if err != nil {
+		t.Fatalf("ImportHost: %v", err)
+	}
+	t.Cleanup(func() { _ = hB.Close() })
+	if hB.PeerID() != oldID {
+		t.Errorf("peer ID changed: %s != %s", hB.PeerID(), oldID)
+	}
+	if s := hB.Trust().Get(alpha.DID.String(), "did:peer"); s != 0.9 {
+		t.Errorf("trust: got %v", s)
+	}
+	if snap.AgentID != "alpha" {
+		t.Errorf("snapshot agent: %s", snap.AgentID)
+	}
+}
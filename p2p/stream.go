@@ -0,0 +1,182 @@
+package p2p
+
+// stream.go — Long-lived intent streams with incremental progress.
+//
+// SendIntent is strictly request/response.  StreamIntent keeps the libp2p
+// stream open after the NegotiationResponse so the responder can report
+// progress as WorkflowMessages (see core.Progress*).  The responder closes
+// the stream when its work is done, which closes the initiator's channel.
+//
+//	Initiator                              Responder
+//	    |-- IntentMessage (stream=true) ------->|
+//	    |<- NegotiationResponse ----------------|
+//	    |<- WorkflowMessage (step_started) -----|
+//	    |<- WorkflowMessage (partial_output) ---|
+//	    |<- WorkflowMessage (step_completed) ---|
+//	    |<- EOF --------------------------------|
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+// StreamWorker performs the work for an accepted streamed intent, emitting
+// progress updates through send.  The stream is closed when it returns; a
+// non-nil error is reported to the initiator as a core.ProgressFailed update.
+type StreamWorker func(ctx context.Context, send func(*core.WorkflowMessage) error) error
+
+// StreamIntentCallback is invoked for intents that request streamed progress.
+// Return the NegotiationResponse and, if it is accepted, the worker that
+// produces updates.  A nil response falls back to the default handler.
+type StreamIntentCallback func(peerID peer.ID, msg *core.IntentMessage) (*core.NegotiationResponse, StreamWorker)
+
+// progressBuffer is the capacity of the channel returned by StreamIntent.
+const progressBuffer = 16
+
+// OnStreamIntent registers the callback for intents that request streamed
+// progress.  Without it, such intents are answered like ordinary intents and
+// the initiator's progress channel closes immediately.
+func (ah *AgentHost) OnStreamIntent(fn StreamIntentCallback) {
+	ah.mu.Lock()
+	defer ah.mu.Unlock()
+	ah.onStreamIntent = fn
+}
+
+// StreamIntent sends intent to peerID and keeps the stream open for progress
+// updates.  It returns once the NegotiationResponse arrives; if the intent was
+// accepted, progress updates are delivered on the returned channel, which is
+// closed when the responder finishes or ctx is cancelled.
+func (ah *AgentHost) StreamIntent(
+	ctx context.Context,
+	peerID peer.ID,
+	intent *core.IntentMessage,
+) (*core.NegotiationResponse, <-chan *core.WorkflowMessage, error) {
+	ctx, span := ah.startSpan(ctx, "asp.stream_intent", peerID)
+	intentAttributes(span, intent)
+	intent, err := ah.outgoing(ctx, peerID, intent, true)
+	if err != nil {
+		endSpan(span, nil, err)
+		return nil, nil, err
+	}
+	resp, updates, err := ah.streamIntent(ctx, peerID, intent)
+	endSpan(span, resp, err)
+	return resp, updates, err
+}
+
+func (ah *AgentHost) streamIntent(
+	ctx context.Context,
+	peerID peer.ID,
+	intent *core.IntentMessage,
+) (*core.NegotiationResponse, <-chan *core.WorkflowMessage, error) {
+	if err := ah.checkQuarantine(peerID); err != nil {
+		return nil, nil, fmt.Errorf("p2p stream intent: %w", err)
+	}
+	if err := ah.requireFeature(peerID, core.FeatureStreaming); err != nil {
+		return nil, nil, fmt.Errorf("p2p stream intent: %w", err)
+	}
+	stream, err := ah.newStream(ctx, peerID, ah.proto)
+	if err != nil {
+		return nil, nil, fmt.Errorf("p2p stream intent: open stream: %w", err)
+	}
+
+	resp, err := ah.exchangeIntent(stream, peerID, intent)
+	if err != nil {
+		_ = stream.Reset()
+		return nil, nil, err
+	}
+
+	updates := make(chan *core.WorkflowMessage, progressBuffer)
+	if !resp.Accepted {
+		_ = stream.Close()
+		close(updates)
+		return resp, updates, nil
+	}
+
+	// Unblock the reader if the caller gives up.
+	stop := context.AfterFunc(ctx, func() { _ = stream.Reset() })
+	go func() {
+		defer close(updates)
+		defer stop()
+		defer stream.Close()
+		for {
+			msgType, data, err := ah.readMsg(stream)
+			if err != nil {
+				return // EOF: responder finished; anything else: stream broken
+			}
+			if msgType != core.MsgWorkflow {
+				continue
+			}
+			update, err := ah.limits.DecodeWorkflowMessage(data)
+			if err != nil || update.WorkflowID != intent.ID {
+				continue
+			}
+			select {
+			case updates <- update:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return resp, updates, nil
+}
+
+// serveStreamedIntent answers a streamed intent through the same path as
+// other intents, with cb as the handler, then runs the worker cb returned,
+// forwarding its updates until it returns or the host shuts down.  The
+// worker runs only if the final answer is still an acceptance: budget and
+// result limits may have turned it into a rejection.
+func (ah *AgentHost) serveStreamedIntent(s network.Stream, intent *core.IntentMessage, e *pendingEntry, cb StreamIntentCallback) {
+	from := s.Conn().RemotePeer()
+	var work StreamWorker
+	resp := ah.answerWith(from, intent, e, "asp.handle_stream_intent", func() *core.NegotiationResponse {
+		resp, w := cb(from, intent)
+		if resp != nil {
+			work = w
+		}
+		return resp
+	})
+	if resp == nil {
+		return
+	}
+	if err := ah.writePeerMsg(s, from, resp); err != nil {
+		return
+	}
+	ah.applySenderTrust(from, resp.TrustDelta)
+	if !resp.Accepted || work == nil {
+		return
+	}
+
+	// The work may outlive the default per-stream deadline.
+	_ = s.SetDeadline(time.Time{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-ah.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	send := func(update *core.WorkflowMessage) error {
+		if update.WorkflowID == "" {
+			update.WorkflowID = intent.ID
+		}
+		_ = s.SetWriteDeadline(time.Now().Add(30 * time.Second))
+		if err := ah.writePeerMsg(s, from, update); err != nil {
+			cancel()
+			return fmt.Errorf("p2p stream intent: send progress: %w", err)
+		}
+		return nil
+	}
+	if err := work(ctx, send); err != nil {
+		failed := core.NewProgress(ah.agent, intent.ID, "", core.ProgressFailed, "")
+		failed.Params[core.ProgressParamError] = err.Error()
+		_ = send(failed)
+	}
+}
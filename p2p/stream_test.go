@@ -0,0 +1,115 @@
+package p2p_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+// TestStreamIntentProgress verifies that a streamed intent delivers the
+// responder's progress updates in order and closes the channel when done.
+func TestStreamIntentProgress(t *testing.T) {
+	alpha := makeAgent(t, "alpha", []string{"nlp"})
+	beta := makeAgent(t, "beta", []string{"summarisation"})
+
+	hA := makeHost(t, alpha)
+	hB := makeHost(t, beta)
+
+	hB.OnStreamIntent(func(_ peer.ID, msg *core.IntentMessage) (*core.NegotiationResponse, p2p.StreamWorker) {
+		resp, _ := core.DefaultNegotiationHandler(beta)(msg)
+		return resp, func(ctx context.Context, send func(*core.WorkflowMessage) error) error {
+			for _, kind := range []string{core.ProgressStepStarted, core.ProgressPartialOutput, core.ProgressStepCompleted} {
+				if err := send(core.NewProgress(beta, msg.ID, "step-1", kind, "chunk")); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := hA.Connect(ctx, hB.AddrInfo()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	intent, err := core.CreateIntent(alpha, []float32{0.5}, []string{"summarisation"}, "doc")
+	if err != nil {
+		t.Fatalf("CreateIntent: %v", err)
+	}
+
+	resp, updates, err := hA.StreamIntent(ctx, hB.PeerID(), intent)
+	if err != nil {
+		t.Fatalf("StreamIntent: %v", err)
+	}
+	if !resp.Accepted {
+		t.Fatalf("expected accepted, got reason: %s", resp.Reason)
+	}
+
+	var kinds []string
+	for u := range updates {
+		kinds = append(kinds, u.Action)
+	}
+	want := []string{core.ProgressStepStarted, core.ProgressPartialOutput, core.ProgressStepCompleted}
+	if len(kinds) != len(want) {
+		t.Fatalf("updates: got %v want %v", kinds, want)
+	}
+	for i := range want {
+		if kinds[i] != want[i] {
+			t.Errorf("update %d: got %q want %q", i, kinds[i], want[i])
+		}
+	}
+}
+
+// TestStreamIntentBudget verifies that streamed intents are held to their
+// budget like ordinary ones, and that StreamIntent leaves the caller's intent
+// alone.
+func TestStreamIntentBudget(t *testing.T) {
+	alpha := makeAgent(t, "alpha", []string{"nlp"})
+	beta := makeAgent(t, "beta", []string{"summarisation"})
+
+	hA := makeHost(t, alpha)
+	hB := makeHost(t, beta)
+
+	worked := make(chan struct{}, 1)
+	hB.OnStreamIntent(func(_ peer.ID, msg *core.IntentMessage) (*core.NegotiationResponse, p2p.StreamWorker) {
+		resp, _ := core.DefaultNegotiationHandler(beta)(msg)
+		resp.Cost = &core.CostEstimate{Amount: 500, Currency: "USD"}
+		return resp, func(context.Context, func(*core.WorkflowMessage) error) error {
+			worked <- struct{}{}
+			return nil
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := hA.Connect(ctx, hB.AddrInfo()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	intent, _ := core.CreateIntent(alpha, []float32{0.5}, []string{"summarisation"}, "doc")
+	intent.Budget = &core.Budget{MaxAmount: 200, Currency: "USD"}
+	intent.Metadata = map[string]string{"trace": "caller"}
+
+	resp, updates, err := hA.StreamIntent(ctx, hB.PeerID(), intent)
+	if err != nil {
+		t.Fatalf("StreamIntent: %v", err)
+	}
+	if resp.Accepted || resp.Reason != core.ReasonOverBudget {
+		t.Fatalf("got %+v; want an over-budget rejection", resp)
+	}
+	for range updates {
+		t.Error("update for a rejected intent")
+	}
+	select {
+	case <-worked:
+		t.Error("worker ran for an intent over budget")
+	case <-time.After(100 * time.Millisecond):
+	}
+	if len(intent.Metadata) != 1 || intent.Metadata["trace"] != "caller" {
+		t.Errorf("StreamIntent modified the caller's metadata: %v", intent.Metadata)
+	}
+}
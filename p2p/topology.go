@@ -0,0 +1,158 @@
+package p2p
+
+// topology.go — A snapshot of the mesh as this host sees it.
+//
+// Topology gathers what the host knows about its surroundings into one
+// graph: a node for itself and for every peer it has handshaked with, is
+// connected to or manages; a link for every open connection; and the trust
+// edges of its TrustGraph.  The structure marshals to JSON for dashboards
+// and `symplex topology`.  Only the host's own connections are known, so
+// links always start or end at the local node.
+
+import (
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+// Topology is a graph of the mesh around one host.
+type Topology struct {
+	Self      string         `json:"self"` // The local node's PeerID
+	Mesh      string         `json:"mesh,omitempty"`
+	Nodes     []TopologyNode `json:"nodes"`
+	Links     []TopologyLink `json:"links"`
+	Trust     []TrustEdge    `json:"trust"`
+	Timestamp int64          `json:"timestamp,string"` // Unix nanoseconds
+}
+
+// TopologyNode is one agent in a Topology.
+type TopologyNode struct {
+	PeerID       string   `json:"peer_id"`
+	AgentID      string   `json:"agent_id,omitempty"` // Empty until a handshake
+	DID          string   `json:"did,omitempty"`
+	Capabilities []string `json:"capabilities,omitempty"`
+	Addrs        []string `json:"addrs,omitempty"`
+	Self         bool     `json:"self,omitempty"`
+	Handshaked   bool     `json:"handshaked,omitempty"`
+	Connected    bool     `json:"connected,omitempty"`
+	Managed      bool     `json:"managed,omitempty"` // Kept connected by the PeerManager
+}
+
+// TopologyLink is one open connection between two nodes.
+type TopologyLink struct {
+	From      string `json:"from"` // PeerID of the dialling side
+	To        string `json:"to"`
+	Addr      string `json:"addr,omitempty"` // The remote multiaddr
+	Streams   int    `json:"streams"`
+	OpenedAt  int64  `json:"opened_at,string,omitempty"` // Unix nanoseconds
+	Direction string `json:"direction"`                  // "inbound" or "outbound", seen from Self
+}
+
+// TrustEdge is one score in the host's TrustGraph, between DIDs.
+type TrustEdge struct {
+	From  string  `json:"from"`
+	To    string  `json:"to"`
+	Score float32 `json:"score"`
+}
+
+// Topology returns a snapshot of the mesh around the host.  Nodes are
+// sorted with the local node first, then by PeerID; links and trust edges
+// are sorted by their endpoints.
+func (ah *AgentHost) Topology() *Topology {
+	self := ah.h.ID()
+	t := &Topology{Self: self.String(), Mesh: ah.mesh, Timestamp: time.Now().UnixNano()}
+
+	nodes := map[peer.ID]*TopologyNode{}
+	node := func(pid peer.ID) *TopologyNode {
+		n := nodes[pid]
+		if n == nil {
+			n = &TopologyNode{PeerID: pid.String()}
+			nodes[pid] = n
+		}
+		return n
+	}
+	describe := func(n *TopologyNode, p core.AgentProfile) {
+		n.AgentID, n.DID = p.AgentID, p.DID
+		n.Capabilities = append([]string(nil), p.Capabilities...)
+	}
+
+	me := node(self)
+	me.Self = true
+	describe(me, core.AgentProfile{AgentID: ah.agent.ID, DID: ah.agent.DID.String(), Capabilities: ah.agent.Capabilities})
+	for _, a := range ah.h.Addrs() {
+		me.Addrs = append(me.Addrs, a.String())
+	}
+
+	for pid, profile := range ah.KnownPeers() {
+		n := node(pid)
+		n.Handshaked = true
+		describe(n, profile)
+	}
+	if ah.peers != nil {
+		for _, mp := range ah.peers.Peers() {
+			node(mp.PeerID).Managed = true
+		}
+	}
+
+	for _, c := range ah.h.Network().Conns() {
+		remote := c.RemotePeer()
+		n := node(remote)
+		n.Connected = true
+		addr := ""
+		if ra := c.RemoteMultiaddr(); ra != nil {
+			addr = ra.String()
+			if !slices.Contains(n.Addrs, addr) {
+				n.Addrs = append(n.Addrs, addr)
+			}
+		}
+		l := TopologyLink{From: self.String(), To: remote.String(), Addr: addr, Streams: len(c.GetStreams()), Direction: "outbound"}
+		st := c.Stat()
+		if st.Direction == network.DirInbound {
+			l.From, l.To, l.Direction = remote.String(), self.String(), "inbound"
+		}
+		if !st.Opened.IsZero() {
+			l.OpenedAt = st.Opened.UnixNano()
+		}
+		t.Links = append(t.Links, l)
+	}
+
+	for k, score := range ah.trust.Snapshot() {
+		if from, to, ok := core.SplitTrustKey(k); ok {
+			t.Trust = append(t.Trust, TrustEdge{From: from, To: to, Score: score})
+		}
+	}
+
+	t.Nodes = make([]TopologyNode, 0, len(nodes))
+	for _, n := range nodes {
+		t.Nodes = append(t.Nodes, *n)
+	}
+	sort.Slice(t.Nodes, func(i, j int) bool {
+		if t.Nodes[i].Self != t.Nodes[j].Self {
+			return t.Nodes[i].Self
+		}
+		return t.Nodes[i].PeerID < t.Nodes[j].PeerID
+	})
+	sort.Slice(t.Links, func(i, j int) bool {
+		if t.Links[i].From != t.Links[j].From {
+			return t.Links[i].From < t.Links[j].From
+		}
+		return t.Links[i].To < t.Links[j].To
+	})
+	sort.Slice(t.Trust, func(i, j int) bool {
+		if t.Trust[i].From != t.Trust[j].From {
+			return t.Trust[i].From < t.Trust[j].From
+		}
+		return t.Trust[i].To < t.Trust[j].To
+	})
+	if t.Links == nil {
+		t.Links = []TopologyLink{}
+	}
+	if t.Trust == nil {
+		t.Trust = []TrustEdge{}
+	}
+	return t
+}
@@ -0,0 +1,51 @@
+package p2p_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+// TestTopology verifies that the topology lists handshaked peers, open
+// connections and trust edges.
+func TestTopology(t *testing.T) {
+	alpha := makeAgent(t, "alpha", []string{"nlp"})
+	beta := makeAgent(t, "beta", []string{"ocr"})
+	hA := makeHost(t, alpha)
+	hB := makeHost(t, beta)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := p2p.DiscoverAndHandshake(ctx, hA, hB.AddrInfo()); err != nil {
+		t.Fatalf("DiscoverAndHandshake: %v", err)
+	}
+	intent, _ := core.CreateIntent(alpha, nil, []string{"ocr"}, "scan")
+	if _, err := hA.SendIntent(ctx, hB.PeerID(), intent); err != nil {
+		t.Fatalf("SendIntent: %v", err)
+	}
+
+	topo := hA.Topology()
+	if len(topo.Nodes) != 2 || !topo.Nodes[0].Self || topo.Nodes[0].AgentID != "alpha" {
+		t.Fatalf("nodes: %+v", topo.Nodes)
+	}
+	if n := topo.Nodes[1]; n.AgentID != "beta" || !n.Handshaked || !n.Connected {
+		t.Errorf("beta node: %+v", n)
+	}
+	if len(topo.Links) == 0 || topo.Links[0].Direction != "outbound" || topo.Links[0].To != hB.PeerID().String() {
+		t.Errorf("links: %+v", topo.Links)
+	}
+	var found bool
+	for _, e := range topo.Trust {
+		found = found || (e.From == alpha.DID.String() && e.To == beta.DID.String() && e.Score > 0)
+	}
+	if !found {
+		t.Errorf("no trust edge alpha->beta in %+v", topo.Trust)
+	}
+	if _, err := json.Marshal(topo); err != nil {
+		t.Errorf("Marshal: %v", err)
+	}
+}
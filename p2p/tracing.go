@@ -0,0 +1,87 @@
+package p2p
+
+// tracing.go — Distributed tracing.
+//
+// The host opens a span around every Handshake, SendIntent, StreamIntent
+// and orchestrator step, and around serving each incoming intent.  Outgoing
+// intents carry the current trace context in their Metadata (see package
+// tracing); the responder continues the trace from it and hands the span's
+// context to IntentContextCallback handlers, so intents they send in turn
+// join the same trace.
+
+import (
+	"context"
+	"maps"
+	"strings"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation name the host asks its
+// TracerProvider for.
+const tracerName = "github.com/olserra/agent-semantic-protocol/p2p"
+
+// WithTracerProvider records the host's spans with tp.  Without it spans
+// are not recorded, but incoming trace contexts are still passed on.
+func WithTracerProvider(tp trace.TracerProvider) HostOption {
+	return func(ah *AgentHost) { ah.tracer = tp.Tracer(tracerName) }
+}
+
+// startSpan starts a span named name about pid.
+func (ah *AgentHost) startSpan(ctx context.Context, name string, pid peer.ID) (context.Context, trace.Span) {
+	ctx, span := ah.tracer.Start(ctx, name)
+	if pid != "" {
+		span.SetAttributes(attribute.String("peer.id", pid.String()))
+	}
+	return ctx, span
+}
+
+// intentAttributes describes intent on span.
+func intentAttributes(span trace.Span, intent *core.IntentMessage) {
+	span.SetAttributes(
+		attribute.String("intent.id", intent.ID),
+		attribute.String("intent.capabilities", strings.Join(intent.Capabilities, ",")),
+	)
+}
+
+// endSpan records the outcome of the operation span covers and ends it.
+func endSpan(span trace.Span, resp *core.NegotiationResponse, err error) {
+	if resp != nil {
+		span.SetAttributes(attribute.Bool("intent.accepted", resp.Accepted))
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// traced returns intent carrying the trace context of ctx.  The intent is
+// copied rather than modified, since callers may send it to several peers
+// at once; the signature does not cover Metadata.
+func traced(ctx context.Context, intent *core.IntentMessage) *core.IntentMessage {
+	md := map[string]string{}
+	tracing.Inject(ctx, md)
+	if len(md) == 0 {
+		return intent
+	}
+	cp := *intent
+	cp.Metadata = make(map[string]string, len(intent.Metadata)+len(md))
+	maps.Copy(cp.Metadata, intent.Metadata)
+	maps.Copy(cp.Metadata, md)
+	return &cp
+}
+
+// serveSpan starts the span for serving intent from `from`, continuing the
+// sender's trace.
+func (ah *AgentHost) serveSpan(name string, from peer.ID, intent *core.IntentMessage) (context.Context, trace.Span) {
+	ctx := tracing.Extract(context.Background(), intent.Metadata)
+	ctx, span := ah.startSpan(ctx, name, from)
+	intentAttributes(span, intent)
+	return ctx, span
+}
@@ -0,0 +1,62 @@
+package p2p_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+	"github.com/olserra/agent-semantic-protocol/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracingPropagates(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	alpha := makeAgent(t, "alpha", []string{"nlp"})
+	beta := makeAgent(t, "beta", []string{"ocr"})
+	newHost := func(a *core.Agent) *p2p.AgentHost {
+		h, err := p2p.NewHost(context.Background(), a, p2p.WithTracerProvider(tp))
+		if err != nil {
+			t.Fatalf("NewHost: %v", err)
+		}
+		t.Cleanup(func() { _ = h.Close() })
+		return h
+	}
+	hA, hB := newHost(alpha), newHost(beta)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := p2p.DiscoverAndHandshake(ctx, hA, hB.AddrInfo()); err != nil {
+		t.Fatalf("DiscoverAndHandshake: %v", err)
+	}
+	intent, _ := core.CreateIntent(alpha, nil, []string{"ocr"}, "scan")
+	if _, err := hA.SendIntent(ctx, hB.PeerID(), intent); err != nil {
+		t.Fatalf("SendIntent: %v", err)
+	}
+	if _, ok := intent.Metadata[tracing.TraceParentKey]; ok {
+		t.Error("SendIntent modified the caller's intent")
+	}
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, s := range rec.Ended() {
+		spans[s.Name()] = s
+	}
+	send, serve := spans["asp.send_intent"], spans["asp.handle_intent"]
+	if _, ok := spans["asp.handshake"]; !ok {
+		t.Error("no handshake span")
+	}
+	if send == nil || serve == nil {
+		t.Fatalf("missing intent spans: %v", spans)
+	}
+	if serve.SpanContext().TraceID() != send.SpanContext().TraceID() || serve.Parent().SpanID() != send.SpanContext().SpanID() {
+		t.Errorf("handle_intent not a child of send_intent: %v / %v", serve.Parent(), send.SpanContext())
+	}
+	if !slices.Contains(serve.Attributes(), attribute.String("intent.id", intent.ID)) {
+		t.Errorf("attributes: %v", serve.Attributes())
+	}
+}
@@ -0,0 +1,151 @@
+package p2p
+
+// transport.go — The protocol surface applications use, and an in-memory
+// network to run it on.
+//
+// Transport is the part of AgentHost that application code needs: dial,
+// handshake, send intents and workflow steps, announce capabilities, and
+// answer peers through callbacks.  Code written against Transport runs
+// unchanged on a host attached to a MemoryNetwork, where hosts in one
+// process reach each other through in-memory links instead of TCP.  Those
+// hosts are ordinary AgentHosts, so handshakes, signature checks, codecs,
+// gossip and every other host feature behave exactly as on a real network;
+// only the listen addresses and NAT options are ignored, and a pre-shared
+// key is refused.
+//
+//	net := p2p.NewMemoryNetwork()
+//	defer net.Close()
+//	a, _ := p2p.NewHost(ctx, agentA, p2p.WithMemoryNetwork(net))
+//	b, _ := p2p.NewHost(ctx, agentB, p2p.WithMemoryNetwork(net))
+//	_, err := p2p.DiscoverAndHandshake(ctx, a, b.AddrInfo())
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peer"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+// Transport is an agent's endpoint on a mesh.  *AgentHost implements it,
+// whether it listens on TCP or is attached to a MemoryNetwork.
+type Transport interface {
+	PeerID() peer.ID
+	AddrInfo() peer.AddrInfo
+	Agent() *core.Agent
+	KnownPeers() map[peer.ID]core.AgentProfile
+
+	Connect(ctx context.Context, info peer.AddrInfo) error
+	Handshake(ctx context.Context, peerID peer.ID) (*core.HandshakeMessage, error)
+	SendIntent(ctx context.Context, peerID peer.ID, intent *core.IntentMessage) (*core.NegotiationResponse, error)
+	SendWorkflow(ctx context.Context, peerID peer.ID, msg *core.WorkflowMessage) error
+	AnnounceCapabilities(ctx context.Context)
+
+	OnHandshake(fn HandshakeCallback)
+	OnIntent(fn IntentCallback)
+	OnIntentContext(fn IntentContextCallback)
+	OnWorkflow(fn WorkflowCallback)
+	Subscribe(fn EventHandler, kinds ...EventKind) (unsubscribe func())
+
+	Close() error
+}
+
+var _ Transport = (*AgentHost)(nil)
+
+// MemoryNetwork connects the hosts attached to it in memory.  Every host
+// can dial every other; Disconnect and Unlink simulate failures.
+type MemoryNetwork struct {
+	mu    sync.Mutex
+	mn    mocknet.Mocknet
+	peers []peer.ID
+}
+
+// NewMemoryNetwork creates an empty in-memory network.
+func NewMemoryNetwork() *MemoryNetwork {
+	return &MemoryNetwork{mn: mocknet.New()}
+}
+
+// WithMemoryNetwork attaches the host to n instead of listening on TCP.
+// WithListenAddrs and the NAT options are ignored; NewHost fails with
+// WithPrivateNetwork.
+func WithMemoryNetwork(n *MemoryNetwork) HostOption {
+	return func(ah *AgentHost) { ah.memNet = n }
+}
+
+// addPeer adds a libp2p host with key sk, linked to every host already on
+// the network.  Its address is unique but never dialled over a socket.
+func (n *MemoryNetwork) addPeer(sk crypto.PrivKey) (host.Host, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	addr, err := ma.NewMultiaddr(fmt.Sprintf("/ip6/100::%x/tcp/4242", len(n.peers)+1))
+	if err != nil {
+		return nil, fmt.Errorf("memory network: %w", err)
+	}
+	h, err := n.mn.AddPeer(sk, addr)
+	if err != nil {
+		return nil, fmt.Errorf("memory network: %w", err)
+	}
+	for _, p := range n.peers {
+		if _, err := n.mn.LinkPeers(h.ID(), p); err != nil {
+			_ = h.Close()
+			return nil, fmt.Errorf("memory network: %w", err)
+		}
+	}
+	n.peers = append(n.peers, h.ID())
+	return h, nil
+}
+
+// SetLatency delays every message between hosts attached from now on by
+// d, so call it before creating them.
+func (n *MemoryNetwork) SetLatency(d time.Duration) {
+	n.mn.SetLinkDefaults(mocknet.LinkOptions{Latency: d})
+}
+
+// Disconnect closes the connections between a and b.  They can dial each
+// other again.
+func (n *MemoryNetwork) Disconnect(a, b peer.ID) error {
+	if err := n.check(a, b); err != nil {
+		return err
+	}
+	if err := n.mn.DisconnectPeers(a, b); err != nil {
+		return fmt.Errorf("memory network: %w", err)
+	}
+	return nil
+}
+
+// Unlink disconnects a and b and stops them from dialling each other, as a
+// network partition would.
+func (n *MemoryNetwork) Unlink(a, b peer.ID) error {
+	if err := n.check(a, b); err != nil {
+		return err
+	}
+	_ = n.mn.DisconnectPeers(a, b)
+	if err := n.mn.UnlinkPeers(a, b); err != nil {
+		return fmt.Errorf("memory network: %w", err)
+	}
+	return nil
+}
+
+// check reports peers that are not on the network.
+func (n *MemoryNetwork) check(ids ...peer.ID) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, id := range ids {
+		if !slices.Contains(n.peers, id) {
+			return fmt.Errorf("memory network: unknown peer %s", id)
+		}
+	}
+	return nil
+}
+
+// Close shuts down every host on the network.
+func (n *MemoryNetwork) Close() error {
+	return n.mn.Close()
+}
@@ -0,0 +1,74 @@
+package p2p_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+func TestTypedTransportErrors(t *testing.T) {
+	alpha := makeAgent(t, "alpha", nil)
+	hA := makeHost(t, alpha)
+	beta := makeAgent(t, "beta", nil)
+	hB, err := p2p.NewHost(context.Background(), beta)
+	if err != nil {
+		t.Fatalf("NewHost: %v", err)
+	}
+	info := hB.AddrInfo()
+	_ = hB.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err = hA.Connect(ctx, info); !errors.Is(err, p2p.ErrPeerUnreachable) {
+		t.Errorf("Connect to a closed host: %v; want ErrPeerUnreachable", err)
+	}
+
+	intent, err := core.CreateIntent(alpha, []float32{1, 0}, []string{"translate"}, "bonjour")
+	if err != nil {
+		t.Fatalf("CreateIntent: %v", err)
+	}
+	if _, err = hA.BroadcastIntent(ctx, intent); !errors.Is(err, core.ErrCapabilityMissing) {
+		t.Errorf("BroadcastIntent without providers: %v; want ErrCapabilityMissing", err)
+	}
+}
+
+func TestMemoryNetwork(t *testing.T) {
+	mem := p2p.NewMemoryNetwork()
+	t.Cleanup(func() { _ = mem.Close() })
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var hosts []p2p.Transport
+	for _, a := range []*core.Agent{makeAgent(t, "alpha", nil), makeAgent(t, "beta", []string{"translate"})} {
+		h, err := p2p.NewHost(ctx, a, p2p.WithMemoryNetwork(mem))
+		if err != nil {
+			t.Fatalf("NewHost: %v", err)
+		}
+		t.Cleanup(func() { _ = h.Close() })
+		hosts = append(hosts, h)
+	}
+	hA, hB := hosts[0], hosts[1]
+	if _, err := p2p.DiscoverAndHandshake(ctx, hA, hB.AddrInfo()); err != nil {
+		t.Fatalf("handshake over the memory network: %v", err)
+	}
+
+	intent, err := core.CreateIntent(hA.Agent(), []float32{1, 0}, []string{"translate"}, "bonjour")
+	if err != nil {
+		t.Fatalf("CreateIntent: %v", err)
+	}
+	resp, err := hA.SendIntent(ctx, hB.PeerID(), intent)
+	if err != nil || !resp.Accepted {
+		t.Fatalf("SendIntent: %+v, %v", resp, err)
+	}
+
+	if err = mem.Unlink(hA.PeerID(), hB.PeerID()); err != nil {
+		t.Fatalf("Unlink: %v", err)
+	}
+	if _, err = hA.SendIntent(ctx, hB.PeerID(), intent); !errors.Is(err, p2p.ErrPeerUnreachable) {
+		t.Errorf("SendIntent across a partition: %v; want ErrPeerUnreachable", err)
+	}
+}
@@ -0,0 +1,52 @@
+package p2p
+
+// trustpolicy.go — Per-host and per-capability trust policies.
+//
+// Without a policy the host applies the trust delta each responder suggests
+// in its NegotiationResponse, and its default handler suggests
+// core.DefaultTrustPolicy's.  With one, the host judges responses itself
+// from their outcome and latency, and its default handler suggests the
+// policy's delta to requesters.
+
+import (
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+// WithTrustPolicy sets the policy the host applies to every capability
+// without one of its own (see WithCapabilityTrustPolicy).
+func WithTrustPolicy(p core.TrustPolicy) HostOption {
+	return func(ah *AgentHost) { ah.trustPolicies.Default = p }
+}
+
+// WithCapabilityTrustPolicy sets the policy the host applies to intents
+// whose first capability is capability.
+func WithCapabilityTrustPolicy(capability string, p core.TrustPolicy) HostOption {
+	return func(ah *AgentHost) {
+		if ah.trustPolicies.ByCapability == nil {
+			ah.trustPolicies.ByCapability = make(map[string]core.TrustPolicy)
+		}
+		ah.trustPolicies.ByCapability[capability] = p
+	}
+}
+
+// responseTrust returns the trust delta to apply to the responder of resp,
+// the answer to intent sent at sent.
+func (ah *AgentHost) responseTrust(intent *core.IntentMessage, resp *core.NegotiationResponse, sent time.Time) float32 {
+	o := core.OutcomeOf(intent, resp, time.Since(sent))
+	if p, ok := ah.trustPolicies.Lookup(o.Capability); ok {
+		return p.TrustDelta(o)
+	}
+	return resp.TrustDelta
+}
+
+// RecordOutcome applies the host's policy for o.Capability to the trust it
+// places in did, and returns the delta applied.  Use it for outcomes the
+// host cannot see for itself, such as a provider that billed more than the
+// price agreed (o.CostRatio above 1).
+func (ah *AgentHost) RecordOutcome(did string, o core.TrustOutcome) float32 {
+	delta := ah.trustPolicies.TrustDelta(o)
+	ah.applyTrust(did, delta)
+	return delta
+}
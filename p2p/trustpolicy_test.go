@@ -0,0 +1,61 @@
+package p2p_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+func TestTrustPolicy(t *testing.T) {
+	alpha := makeAgent(t, "alpha", []string{"nlp"})
+	beta := makeAgent(t, "beta", []string{"summarisation", "translation"})
+
+	hA, err := p2p.NewHost(context.Background(), alpha,
+		p2p.WithTrustPolicy(core.AggressiveTrustPolicy),
+		p2p.WithCapabilityTrustPolicy("translation", core.ConservativeTrustPolicy))
+	if err != nil {
+		t.Fatalf("NewHost: %v", err)
+	}
+	t.Cleanup(func() { _ = hA.Close() })
+	hB := makeHost(t, beta)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := hA.Connect(ctx, hB.AddrInfo()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	if _, err := hA.Handshake(ctx, hB.PeerID()); err != nil {
+		t.Fatalf("Handshake: %v", err)
+	}
+
+	self, peerDID := alpha.DID.String(), beta.DID.String()
+	for _, tc := range []struct {
+		capability string
+		want       float32 // change in alpha's trust in beta
+	}{
+		{"summarisation", 0.1}, // aggressive, although beta suggests 0.05
+		{"translation", 0.01},  // conservative
+		{"code-gen", -0.01},    // refused: aggressive
+	} {
+		before := hA.Trust().Get(self, peerDID)
+		intent, _ := core.CreateIntent(alpha, nil, []string{tc.capability}, "")
+		if _, err := hA.SendIntent(ctx, hB.PeerID(), intent); err != nil {
+			t.Fatalf("SendIntent(%s): %v", tc.capability, err)
+		}
+		if d := hA.Trust().Get(self, peerDID) - before; d < tc.want-1e-4 || d > tc.want+1e-4 {
+			t.Errorf("%s: trust moved by %v, want %v", tc.capability, d, tc.want)
+		}
+	}
+
+	before := hA.Trust().Get(self, peerDID)
+	overcharged := core.TrustOutcome{Capability: "summarisation", Accepted: true, CostRatio: 2}
+	if d := hA.RecordOutcome(peerDID, overcharged); d != 0.1 {
+		t.Errorf("RecordOutcome = %v, want 0.1", d)
+	}
+	if got := hA.Trust().Get(self, peerDID); got-before < 0.1-1e-4 {
+		t.Errorf("trust after RecordOutcome = %v, was %v", got, before)
+	}
+}
@@ -0,0 +1,119 @@
+package p2p
+
+// workflowresult.go — Delivering workflow step results.
+//
+// An orchestrator that sets WorkflowStep.AwaitResult marks the step's
+// intent with core.AwaitResultMetadataKey.  The executing agent accepts
+// the intent as usual and, once the step has run, calls DeliverResult;
+// the host sends a signed core.WorkflowResult back as a one-way message.
+// The orchestrator's host checks the signature against the sender's
+// handshake and hands the result to the step waiting for it, or to the
+// OnWorkflowResult callback if none is.
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+// WorkflowResultCallback is invoked when a peer sends a WorkflowResult no
+// workflow step on this host is waiting for.
+type WorkflowResultCallback func(peerID peer.ID, r *core.WorkflowResult)
+
+// resultWaiter is a workflow step waiting for the result of its intent.
+type resultWaiter struct {
+	peer peer.ID
+	ch   chan *core.WorkflowResult
+}
+
+// OnWorkflowResult registers the callback for workflow results no step is
+// waiting for.
+func (ah *AgentHost) OnWorkflowResult(fn WorkflowResultCallback) {
+	ah.mu.Lock()
+	defer ah.mu.Unlock()
+	ah.onResult = fn
+}
+
+// SendWorkflowResult sends r to peerID.  Use DeliverResult to answer a
+// workflow step intent.
+func (ah *AgentHost) SendWorkflowResult(ctx context.Context, peerID peer.ID, r *core.WorkflowResult) error {
+	if err := ah.sendOneWay(ctx, peerID, r); err != nil {
+		return fmt.Errorf("p2p workflow result: %w", err)
+	}
+	return nil
+}
+
+// DeliverResult reports the outcome of the workflow step intent from
+// peerID to the orchestrator: output if stepErr is nil, stepErr otherwise.
+// It does nothing unless the orchestrator asked for a result.
+func (ah *AgentHost) DeliverResult(ctx context.Context, peerID peer.ID, intent *core.IntentMessage, output *core.ResultPayload, stepErr error) error {
+	if intent.Metadata[core.AwaitResultMetadataKey] != "true" {
+		return nil
+	}
+	r, err := core.NewWorkflowResult(ah.agent, intent, output, stepErr)
+	if err != nil {
+		return fmt.Errorf("p2p workflow result: %w", err)
+	}
+	return ah.SendWorkflowResult(ctx, peerID, r)
+}
+
+// expectResult registers a waiter for the result of the intent requestID
+// sent to pid.  Call the returned func once done waiting.
+func (ah *AgentHost) expectResult(requestID string, pid peer.ID) (<-chan *core.WorkflowResult, func()) {
+	ch := make(chan *core.WorkflowResult, 1)
+	ah.resultMu.Lock()
+	if ah.resultWaiters == nil {
+		ah.resultWaiters = make(map[string]resultWaiter)
+	}
+	ah.resultWaiters[requestID] = resultWaiter{peer: pid, ch: ch}
+	ah.resultMu.Unlock()
+	return ch, func() {
+		ah.resultMu.Lock()
+		if w, ok := ah.resultWaiters[requestID]; ok && w.ch == ch {
+			delete(ah.resultWaiters, requestID)
+		}
+		ah.resultMu.Unlock()
+	}
+}
+
+// handleIncomingWorkflowResult verifies a WorkflowResult and delivers it.
+// Only handshaked peers may send results, signed by the agent they
+// handshaked as.
+func (ah *AgentHost) handleIncomingWorkflowResult(s network.Stream, data []byte) {
+	from := s.Conn().RemotePeer()
+	r, err := ah.limits.DecodeWorkflowResult(data)
+	if err != nil {
+		ah.peerError(from, core.PeerErrorDecode)
+		return
+	}
+	ah.mu.RLock()
+	profile, known := ah.known[from.String()]
+	cb := ah.onResult
+	ah.mu.RUnlock()
+	if !known || profile.DID != r.DID {
+		return
+	}
+	if err := core.VerifyWorkflowResult(r, profile.PublicKey); err != nil {
+		ah.log.Log(context.Background(), slog.LevelWarn, "workflow result refused",
+			core.LogKeyPeer, from.String(), core.LogKeyMsgType, "workflow_result", "error", err)
+		return
+	}
+
+	ah.resultMu.Lock()
+	w, waiting := ah.resultWaiters[r.RequestID]
+	if waiting && w.peer == from {
+		delete(ah.resultWaiters, r.RequestID)
+	}
+	ah.resultMu.Unlock()
+	if waiting && w.peer == from {
+		w.ch <- r
+		return
+	}
+	if cb != nil {
+		cb(from, r)
+	}
+}
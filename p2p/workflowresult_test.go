@@ -0,0 +1,73 @@
+package p2p_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+func TestWorkflowAwaitResult(t *testing.T) {
+	alpha := makeAgent(t, "alpha", nil)
+	beta := makeAgent(t, "beta", []string{"summarise"})
+	hA := makeHost(t, alpha)
+	hB := makeHost(t, beta)
+
+	hB.OnIntent(func(from peer.ID, in *core.IntentMessage) *core.NegotiationResponse {
+		resp, _ := core.DefaultNegotiationHandler(beta)(in)
+		go func() {
+			var err error
+			output := &core.ResultPayload{ContentType: "text/plain", Data: []byte("summary of " + in.Payload)}
+			if in.Payload == "" {
+				output, err = nil, errors.New("nothing to summarise")
+			}
+			time.Sleep(20 * time.Millisecond)
+			if err := hB.DeliverResult(context.Background(), from, in, output, err); err != nil {
+				t.Errorf("DeliverResult: %v", err)
+			}
+		}()
+		return resp
+	})
+	unsolicited := make(chan *core.WorkflowResult, 1)
+	hA.OnWorkflowResult(func(_ peer.ID, r *core.WorkflowResult) { unsolicited <- r })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := p2p.DiscoverAndHandshake(ctx, hA, hB.AddrInfo()); err != nil {
+		t.Fatalf("DiscoverAndHandshake: %v", err)
+	}
+
+	o := p2p.NewOrchestrator(hA, 5*time.Second)
+	results, err := o.RunSequential(ctx, "wf", []p2p.WorkflowStep{
+		{ID: "sum", Capability: "summarise", Payload: "report", AwaitResult: true},
+		{ID: "empty", Capability: "summarise", AwaitResult: true, OnFailure: p2p.FailContinue},
+	})
+	if err != nil || len(results) != 2 {
+		t.Fatalf("RunSequential: %+v, %v", results, err)
+	}
+	if r := results[0]; !r.Accepted || r.Output != "summary of report" || r.Result == nil || r.Result.ContentType != "text/plain" {
+		t.Errorf("completed step = %+v", r)
+	}
+	if r := results[1]; r.Accepted || r.Reason != "nothing to summarise" {
+		t.Errorf("failed step = %+v", r)
+	}
+
+	// A result for an intent nobody waits on goes to the callback.
+	in, _ := core.CreateIntent(alpha, []float32{1}, []string{"summarise"}, "late")
+	in.Metadata[core.AwaitResultMetadataKey] = "true"
+	if err := hB.DeliverResult(ctx, hA.PeerID(), in, nil, nil); err != nil {
+		t.Fatalf("DeliverResult: %v", err)
+	}
+	select {
+	case r := <-unsolicited:
+		if r.RequestID != in.ID || r.DID != beta.DID.String() {
+			t.Errorf("unsolicited result = %+v", r)
+		}
+	case <-ctx.Done():
+		t.Fatal("unsolicited result not delivered to OnWorkflowResult")
+	}
+}
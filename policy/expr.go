@@ -0,0 +1,291 @@
+// Package policy decides, before a negotiation handler runs, whether an
+// incoming intent may be served at all.
+//
+// Expression rules are written in CEL (https://cel.dev) and evaluated
+// against the intent and the sending peer, e.g.
+//
+//	intent.trust_score > 0.6 && !("pii" in intent.metadata)
+//	peer.trust >= 0.3 || "admin" in peer.capabilities
+//
+// Common checks (allow and deny lists, trust thresholds, payload and
+// concurrency limits, per-group capability sets) need no expressions: see
+// Rules, which loads from YAML.
+//
+// Rule sets can be reloaded from a file while the agent is running.
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+// exprCostLimit bounds the work a single rule evaluation may do.
+const exprCostLimit = 10_000
+
+// ExprRule is one named CEL expression.  It must evaluate to a bool; the
+// intent is allowed only if every rule in a set evaluates to true.
+type ExprRule struct {
+	Name string `json:"name"`
+	Expr string `json:"expr"`
+}
+
+// Decision is the outcome of evaluating a rule set.
+type Decision struct {
+	Allowed bool
+	Rule    string // Name of the first rule that denied, if any
+	Reason  string
+}
+
+// Peer is what rules see of the intent's sender.
+type Peer struct {
+	Profile  core.AgentProfile
+	Trust    float32 // Local trust in the sender, [0,1]
+	Verified bool    // The intent's signature was checked against the sender's key
+}
+
+type compiledRule struct {
+	ExprRule
+	prg cel.Program
+}
+
+// ExprSet is a hot-swappable, concurrency-safe set of compiled CEL rules.
+type ExprSet struct {
+	mu    sync.RWMutex
+	env   *cel.Env
+	rules []compiledRule
+}
+
+// NewExprSet compiles rules.  Any compile error rejects the whole set.
+func NewExprSet(rules ...ExprRule) (*ExprSet, error) {
+	env, err := cel.NewEnv(
+		cel.Variable("intent", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("peer", cel.MapType(cel.StringType, cel.DynType)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("policy: cel env: %w", err)
+	}
+	s := &ExprSet{env: env}
+	if err = s.Replace(rules...); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Replace compiles rules and swaps them in atomically.  On error the current
+// rules stay active.
+func (s *ExprSet) Replace(rules ...ExprRule) error {
+	compiled := make([]compiledRule, 0, len(rules))
+	for _, r := range rules {
+		ast, iss := s.env.Compile(r.Expr)
+		if iss != nil && iss.Err() != nil {
+			return fmt.Errorf("policy: rule %q: %w", r.Name, iss.Err())
+		}
+		// Map fields are dyn, so `intent.signed` type-checks as dyn; Evaluate
+		// treats any non-bool result as a denial.
+		if t := ast.OutputType(); !t.IsExactType(cel.BoolType) && !t.IsExactType(cel.DynType) {
+			return fmt.Errorf("policy: rule %q must evaluate to bool", r.Name)
+		}
+		prg, err := s.env.Program(ast, cel.CostLimit(exprCostLimit))
+		if err != nil {
+			return fmt.Errorf("policy: rule %q: %w", r.Name, err)
+		}
+		compiled = append(compiled, compiledRule{ExprRule: r, prg: prg})
+	}
+	s.mu.Lock()
+	s.rules = compiled
+	s.mu.Unlock()
+	return nil
+}
+
+// Reload reads a JSON array of ExprRule from path and swaps it in.
+func (s *ExprSet) Reload(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("policy: read %s: %w", path, err)
+	}
+	var rules []ExprRule
+	if err = json.Unmarshal(data, &rules); err != nil {
+		return fmt.Errorf("policy: parse %s: %w", path, err)
+	}
+	return s.Replace(rules...)
+}
+
+// WatchFile reloads path whenever its modification time changes, checking
+// every interval until done is closed.  Reload errors are passed to onError
+// (which may be nil) and leave the previous rules in force.  interval must
+// be positive.
+func (s *ExprSet) WatchFile(path string, interval time.Duration, done <-chan struct{}, onError func(error)) error {
+	if interval <= 0 {
+		return fmt.Errorf("policy: watch interval %v must be positive", interval)
+	}
+	go func() {
+		var last time.Time
+		if fi, err := os.Stat(path); err == nil {
+			last = fi.ModTime()
+		}
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				fi, err := os.Stat(path)
+				if err != nil || !fi.ModTime().After(last) {
+					continue
+				}
+				last = fi.ModTime()
+				if err = s.Reload(path); err != nil && onError != nil {
+					onError(err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// Evaluate runs every rule against intent and peer.  A rule that fails to
+// evaluate (e.g. a missing map key) denies.
+func (s *ExprSet) Evaluate(intent *core.IntentMessage, peer Peer) Decision {
+	s.mu.RLock()
+	rules := s.rules
+	s.mu.RUnlock()
+
+	vars := map[string]any{
+		"intent": intentVars(intent, peer.Verified),
+		"peer":   peerVars(peer),
+	}
+	for _, r := range rules {
+		out, _, err := r.prg.Eval(vars)
+		if err != nil {
+			return Decision{Rule: r.Name, Reason: fmt.Sprintf("rule %q: %v", r.Name, err)}
+		}
+		if ok, _ := out.Value().(bool); !ok {
+			return Decision{Rule: r.Name, Reason: fmt.Sprintf("denied by rule %q", r.Name)}
+		}
+	}
+	return Decision{Allowed: true}
+}
+
+// Guard wraps next, for AgentHost.OnIntentContext, so that intents denied
+// by the rule set are answered with a signed rejection instead of reaching
+// the handler.  The sender is the DID the connection handshaked as, not the
+// one the intent claims; lookup resolves it and may return a zero Peer for
+// unknown senders.
+func (s *ExprSet) Guard(agent *core.Agent, lookup func(did string) Peer, next p2p.IntentContextCallback) p2p.IntentContextCallback {
+	return func(ctx context.Context, from peer.ID, intent *core.IntentMessage) *core.NegotiationResponse {
+		d := s.Evaluate(intent, sender(ctx, intent, lookup))
+		if d.Allowed {
+			return next(ctx, from, intent)
+		}
+		return deny(agent, intent, "policy: "+d.Reason)
+	}
+}
+
+// sender resolves the handshaked sender the host placed in ctx.  Peers that
+// never handshaked are zero.
+func sender(ctx context.Context, intent *core.IntentMessage, lookup func(did string) Peer) Peer {
+	s := core.SenderFromContext(ctx)
+	if s.DID == "" {
+		return Peer{}
+	}
+	p := lookup(s.DID)
+	p.Verified = s.Verified && s.DID == intent.DID
+	return p
+}
+
+// Reject builds a signed rejection of intent with reason.
+func Reject(agent *core.Agent, intent *core.IntentMessage, reason string) (*core.NegotiationResponse, error) {
+	resp := &core.NegotiationResponse{
+		RequestID: intent.ID,
+		AgentID:   agent.ID,
+		DID:       agent.DID.String(),
+		Timestamp: time.Now().UnixNano(),
+		Reason:    reason,
+	}
+	if err := core.SignResponse(agent, resp); err != nil {
+		return nil, fmt.Errorf("policy: sign rejection: %w", err)
+	}
+	return resp, nil
+}
+
+// deny is Reject for Guard handlers.  They have no error to return, and a
+// nil response would hand the intent to the host's default handler, so a
+// rejection that cannot be signed goes out unsigned.
+func deny(agent *core.Agent, intent *core.IntentMessage, reason string) *core.NegotiationResponse {
+	resp, err := Reject(agent, intent, reason)
+	if err != nil {
+		return &core.NegotiationResponse{
+			RequestID: intent.ID,
+			AgentID:   agent.ID,
+			DID:       agent.DID.String(),
+			Timestamp: time.Now().UnixNano(),
+			Reason:    reason,
+		}
+	}
+	return resp
+}
+
+// RegistryLookup resolves senders from a DiscoveryRegistry and the local
+// TrustGraph; either may be nil.
+func RegistryLookup(self *core.Agent, reg *core.DiscoveryRegistry, trust *core.TrustGraph) func(did string) Peer {
+	return func(did string) Peer {
+		var p Peer
+		if reg != nil {
+			p.Profile, _ = reg.FindByDID(did)
+		}
+		if trust != nil {
+			p.Trust = trust.Get(self.DID.String(), did)
+		}
+		return p
+	}
+}
+
+// ------------------------------------------------------------------ variables
+
+// intentVars exposes m to rules.  signed reports a verified signature, not
+// merely a present one.
+func intentVars(m *core.IntentMessage, verified bool) map[string]any {
+	md := make(map[string]any, len(m.Metadata))
+	for k, v := range m.Metadata {
+		md[k] = v
+	}
+	return map[string]any{
+		"id":           m.ID,
+		"did":          m.DID,
+		"capabilities": stringsAny(m.Capabilities),
+		"payload":      m.Payload,
+		"payload_size": int64(len(m.Payload)),
+		"timestamp":    m.Timestamp,
+		"trust_score":  float64(m.TrustScore),
+		"metadata":     md,
+		"signed":       verified,
+	}
+}
+
+func peerVars(p Peer) map[string]any {
+	return map[string]any{
+		"agent_id":     p.Profile.AgentID,
+		"did":          p.Profile.DID,
+		"capabilities": stringsAny(p.Profile.Capabilities),
+		"known":        len(p.Profile.PublicKey) > 0,
+		"trust":        float64(p.Trust),
+	}
+}
+
+func stringsAny(ss []string) []any {
+	out := make([]any, len(ss))
+	for i, s := range ss {
+		out[i] = s
+	}
+	return out
+}
@@ -0,0 +1,108 @@
+package policy_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/policy"
+)
+
+func TestExprSetEvaluate(t *testing.T) {
+	s, err := policy.NewExprSet(
+		policy.ExprRule{Name: "trusted", Expr: `intent.trust_score > 0.6 && !("pii" in intent.metadata)`},
+		policy.ExprRule{Name: "signed", Expr: `intent.signed`},
+	)
+	if err != nil {
+		t.Fatalf("NewExprSet: %v", err)
+	}
+	sender, _ := core.NewAgent("sender", nil)
+	intent, _ := core.CreateIntent(sender, []float32{1}, []string{"x"}, "")
+
+	intent.TrustScore = 0.9
+	verified := policy.Peer{Verified: true}
+	if d := s.Evaluate(intent, verified); !d.Allowed {
+		t.Errorf("expected allow, got %+v", d)
+	}
+	// A signature nobody checked does not count as signed.
+	if d := s.Evaluate(intent, policy.Peer{}); d.Allowed || d.Rule != "signed" {
+		t.Errorf("expected deny by signed, got %+v", d)
+	}
+	intent.Metadata["pii"] = "true"
+	if d := s.Evaluate(intent, verified); d.Allowed || d.Rule != "trusted" {
+		t.Errorf("expected deny by trusted, got %+v", d)
+	}
+}
+
+func TestExprSetRejectsBadRule(t *testing.T) {
+	if _, err := policy.NewExprSet(policy.ExprRule{Name: "bad", Expr: `intent.trust_score >`}); err == nil {
+		t.Error("expected compile error")
+	}
+	if _, err := policy.NewExprSet(policy.ExprRule{Name: "str", Expr: `"yes"`}); err == nil {
+		t.Error("expected non-bool rule to be rejected")
+	}
+}
+
+func TestExprSetReloadAndGuard(t *testing.T) {
+	self, _ := core.NewAgent("self", []string{"x"})
+	sender, _ := core.NewAgent("sender", nil)
+	intent, _ := core.CreateIntent(sender, []float32{1}, []string{"x"}, "")
+
+	s, err := policy.NewExprSet()
+	if err != nil {
+		t.Fatalf("NewExprSet: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "rules.json")
+	if err = os.WriteFile(path, []byte(`[{"name":"trusted-peer","expr":"peer.trust >= 0.5"}]`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Reload(path); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+
+	trust := core.NewTrustGraph()
+	inner := core.DefaultNegotiationHandler(self)
+	guarded := s.Guard(self, policy.RegistryLookup(self, nil, trust), func(_ context.Context, _ peer.ID, m *core.IntentMessage) *core.NegotiationResponse {
+		resp, _ := inner(m)
+		return resp
+	})
+	// The host puts the handshake DID in the context.
+	ctx := core.ContextWithSender(context.Background(), core.Sender{DID: sender.DID.String(), Verified: true})
+	resp := guarded(ctx, "", intent)
+	if resp.Accepted {
+		t.Error("untrusted peer should be rejected by policy")
+	}
+	if !core.VerifyResponseSignature(resp, self.PublicKey()) {
+		t.Error("policy rejection must be signed")
+	}
+
+	_ = trust.Set(self.DID.String(), sender.DID.String(), 0.8)
+	if resp = guarded(ctx, "", intent); !resp.Accepted {
+		t.Errorf("trusted peer rejected: %s", resp.Reason)
+	}
+
+	// Trust follows the handshake, not the DID the intent claims.
+	mallory, _ := core.NewAgent("mallory", nil)
+	forged := core.ContextWithSender(context.Background(), core.Sender{DID: mallory.DID.String()})
+	if resp = guarded(forged, "", intent); resp.Accepted {
+		t.Error("intent claiming a trusted DID accepted from an untrusted connection")
+	}
+	if resp = guarded(context.Background(), "", intent); resp.Accepted {
+		t.Error("intent accepted from a peer that never handshaked")
+	}
+}
+
+func TestExprSetWatchFileInterval(t *testing.T) {
+	s, err := policy.NewExprSet()
+	if err != nil {
+		t.Fatalf("NewExprSet: %v", err)
+	}
+	done := make(chan struct{})
+	defer close(done)
+	if err = s.WatchFile(filepath.Join(t.TempDir(), "rules.json"), 0, done, nil); err == nil {
+		t.Error("WatchFile accepted a zero interval")
+	}
+}
@@ -0,0 +1,227 @@
+package policy
+
+// rules.go — Declarative rule sets.
+//
+// Most policies do not need an expression language.  A Rules document lists
+// the common checks directly and is usually kept in YAML:
+//
+//	deny: [did:key:z6Mk...bad]
+//	min_trust: 0.3
+//	max_concurrent: 16
+//	max_payload_bytes: 65536
+//	groups:
+//	  - name: partners
+//	    members: [did:key:z6Mk...a, did:key:z6Mk...b]
+//	    capabilities: [ocr, translate]
+//	  - name: everyone
+//	    members: ["*"]
+//	    capabilities: [ping]
+//
+// Checks run in a fixed order: deny list, allow list, trust, payload size,
+// peer groups.  The concurrency limit is enforced by Guard, which counts the
+// intents its handler is serving.
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/internal/yamlconf"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+// AnyPeer as a group member matches every sender.
+const AnyPeer = "*"
+
+// Rules is a declarative rule set.  Zero fields impose no limit.
+type Rules struct {
+	// Allow, when set, lists the only DIDs that may send intents.
+	Allow []string `json:"allow,omitempty"`
+	// Deny lists DIDs whose intents are always refused.
+	Deny []string `json:"deny,omitempty"`
+	// MinTrust is the local trust the sender needs, [0,1].
+	MinTrust float32 `json:"min_trust,omitempty"`
+	// MaxConcurrent bounds the intents a guarded handler serves at once.
+	MaxConcurrent int `json:"max_concurrent,omitempty"`
+	// MaxPayloadBytes bounds the intent payload.
+	MaxPayloadBytes int `json:"max_payload_bytes,omitempty"`
+	// Groups restrict the capabilities their members may request.
+	Groups []PeerGroup `json:"groups,omitempty"`
+}
+
+// PeerGroup names a set of senders and the capabilities they may request.
+// A sender in several groups may request the capabilities of any of them;
+// a sender in none is not restricted by groups.
+type PeerGroup struct {
+	Name         string   `json:"name"`
+	Members      []string `json:"members"`      // DIDs, or AnyPeer
+	Capabilities []string `json:"capabilities"` // empty: none
+}
+
+// Validate checks that r is self-consistent.
+func (r *Rules) Validate() error {
+	if r.MinTrust < 0 || r.MinTrust > 1 {
+		return fmt.Errorf("policy: min_trust %v outside [0,1]", r.MinTrust)
+	}
+	if r.MaxConcurrent < 0 {
+		return fmt.Errorf("policy: max_concurrent must not be negative")
+	}
+	if r.MaxPayloadBytes < 0 {
+		return fmt.Errorf("policy: max_payload_bytes must not be negative")
+	}
+	seen := make(map[string]bool, len(r.Groups))
+	for i, g := range r.Groups {
+		if g.Name == "" {
+			return fmt.Errorf("policy: group %d: missing name", i)
+		}
+		if seen[g.Name] {
+			return fmt.Errorf("policy: duplicate group %q", g.Name)
+		}
+		seen[g.Name] = true
+		if len(g.Members) == 0 {
+			return fmt.Errorf("policy: group %q has no members", g.Name)
+		}
+	}
+	return nil
+}
+
+// ParseRules decodes and validates a YAML rule set.  Unknown keys are errors.
+func ParseRules(data []byte) (*Rules, error) {
+	var r Rules
+	if err := yamlconf.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("policy: %w", err)
+	}
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// LoadRules reads a YAML rule set from path.
+func LoadRules(path string) (*Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("policy: read %s: %w", path, err)
+	}
+	r, err := ParseRules(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return r, nil
+}
+
+// Evaluate applies every check except MaxConcurrent to intent and peer.
+func (r *Rules) Evaluate(intent *core.IntentMessage, peer Peer) Decision {
+	did := intent.DID
+	switch {
+	case slices.Contains(r.Deny, did):
+		return Decision{Rule: "deny", Reason: fmt.Sprintf("%s is denied", did)}
+	case len(r.Allow) > 0 && !slices.Contains(r.Allow, did):
+		return Decision{Rule: "allow", Reason: fmt.Sprintf("%s is not allowed", did)}
+	case peer.Trust < r.MinTrust:
+		return Decision{Rule: "min_trust", Reason: fmt.Sprintf("trust %.2f below %.2f", peer.Trust, r.MinTrust)}
+	case r.MaxPayloadBytes > 0 && len(intent.Payload) > r.MaxPayloadBytes:
+		return Decision{Rule: "max_payload_bytes", Reason: fmt.Sprintf("payload of %d bytes exceeds %d", len(intent.Payload), r.MaxPayloadBytes)}
+	}
+
+	var groups []PeerGroup
+	for _, g := range r.Groups {
+		if slices.Contains(g.Members, did) || slices.Contains(g.Members, AnyPeer) {
+			groups = append(groups, g)
+		}
+	}
+	if len(groups) == 0 {
+		return Decision{Allowed: true}
+	}
+	for _, c := range intent.Capabilities {
+		if !slices.ContainsFunc(groups, func(g PeerGroup) bool { return slices.Contains(g.Capabilities, c) }) {
+			return Decision{Rule: "group:" + groups[0].Name, Reason: fmt.Sprintf("capability %q not allowed for %s", c, did)}
+		}
+	}
+	return Decision{Allowed: true}
+}
+
+// RuleSet is a hot-swappable, concurrency-safe Rules.
+type RuleSet struct {
+	mu       sync.RWMutex
+	rules    *Rules
+	inFlight int // intents being served by Guard handlers
+}
+
+// NewRuleSet validates r and wraps it in a RuleSet.
+func NewRuleSet(r *Rules) (*RuleSet, error) {
+	s := &RuleSet{}
+	if err := s.Replace(r); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Replace validates r and swaps it in.  On error the current rules stay
+// active.  Intents already being served are not affected.
+func (s *RuleSet) Replace(r *Rules) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.rules = r
+	s.mu.Unlock()
+	return nil
+}
+
+// Reload reads a YAML rule set from path and swaps it in.
+func (s *RuleSet) Reload(path string) error {
+	r, err := LoadRules(path)
+	if err != nil {
+		return err
+	}
+	return s.Replace(r)
+}
+
+// Rules returns the rules in force.
+func (s *RuleSet) Rules() *Rules {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.rules
+}
+
+// Evaluate applies the rules in force to intent and peer, as Rules.Evaluate.
+func (s *RuleSet) Evaluate(intent *core.IntentMessage, peer Peer) Decision {
+	return s.Rules().Evaluate(intent, peer)
+}
+
+// Guard wraps next like ExprSet.Guard, and also refuses intents that would
+// exceed MaxConcurrent while next is serving others.
+func (s *RuleSet) Guard(agent *core.Agent, lookup func(did string) Peer, next p2p.IntentContextCallback) p2p.IntentContextCallback {
+	return func(ctx context.Context, from peer.ID, intent *core.IntentMessage) *core.NegotiationResponse {
+		d := s.Evaluate(intent, sender(ctx, intent, lookup))
+		if !d.Allowed {
+			return deny(agent, intent, "policy: "+d.Reason)
+		}
+		if !s.acquire() {
+			return deny(agent, intent, "policy: too many concurrent intents")
+		}
+		defer s.release()
+		return next(ctx, from, intent)
+	}
+}
+
+func (s *RuleSet) acquire() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if max := s.rules.MaxConcurrent; max > 0 && s.inFlight >= max {
+		return false
+	}
+	s.inFlight++
+	return true
+}
+
+func (s *RuleSet) release() {
+	s.mu.Lock()
+	s.inFlight--
+	s.mu.Unlock()
+}
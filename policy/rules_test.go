@@ -0,0 +1,135 @@
+package policy_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/policy"
+)
+
+func TestRulesEvaluate(t *testing.T) {
+	partner, _ := core.NewAgent("partner", nil)
+	stranger, _ := core.NewAgent("stranger", nil)
+	banned, _ := core.NewAgent("banned", nil)
+
+	r, err := policy.ParseRules([]byte(`
+# Operator rules
+deny: [` + banned.DID.String() + `]
+min_trust: 0.2
+max_payload_bytes: 8
+groups:
+  - name: partners
+    members: [` + partner.DID.String() + `]
+    capabilities: [ocr, translate]
+  - name: everyone
+    members: ["*"]
+    capabilities: [ping]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		sender  *core.Agent
+		caps    []string
+		payload string
+		trust   float32
+		rule    string // "" for allowed
+	}{
+		{partner, []string{"ocr", "ping"}, "scan", 0.5, ""},
+		{stranger, []string{"ping"}, "", 0.5, ""},
+		{stranger, []string{"ocr"}, "", 0.5, "group:everyone"},
+		{partner, []string{"ocr"}, "", 0.1, "min_trust"},
+		{partner, []string{"ocr"}, "too large!", 0.5, "max_payload_bytes"},
+		{banned, []string{"ping"}, "", 1, "deny"},
+	} {
+		intent, _ := core.CreateIntent(c.sender, nil, c.caps, c.payload)
+		d := r.Evaluate(intent, policy.Peer{Trust: c.trust})
+		if d.Allowed != (c.rule == "") || d.Rule != c.rule {
+			t.Errorf("%s %v: got %+v, want rule %q", c.sender.ID, c.caps, d, c.rule)
+		}
+	}
+
+	r.Allow = []string{partner.DID.String()}
+	intent, _ := core.CreateIntent(stranger, nil, []string{"ping"}, "")
+	if d := r.Evaluate(intent, policy.Peer{Trust: 1}); d.Rule != "allow" {
+		t.Errorf("allow list: got %+v", d)
+	}
+}
+
+func TestParseRulesRejectsInvalid(t *testing.T) {
+	for _, doc := range []string{
+		"min_trust: 2",
+		"max_concurrent: -1",
+		"deny_list: [x]",
+		"groups:\n  - members: [x]",
+		"groups:\n  - name: a\n    capabilities: [ocr]",
+	} {
+		if _, err := policy.ParseRules([]byte(doc)); err == nil {
+			t.Errorf("%q: parsed, want an error", doc)
+		}
+	}
+}
+
+func TestRuleSetGuard(t *testing.T) {
+	self, _ := core.NewAgent("worker", []string{"ocr"})
+	sender, _ := core.NewAgent("sender", nil)
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(path, []byte("max_concurrent: 1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	r, err := policy.LoadRules(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := policy.NewRuleSet(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entered, release := make(chan struct{}), make(chan struct{})
+	inner := core.DefaultNegotiationHandler(self)
+	guarded := s.Guard(self, func(string) policy.Peer { return policy.Peer{} }, func(_ context.Context, _ peer.ID, m *core.IntentMessage) *core.NegotiationResponse {
+		close(entered)
+		<-release
+		resp, _ := inner(m)
+		return resp
+	})
+	h := func(m *core.IntentMessage) (*core.NegotiationResponse, error) {
+		return guarded(context.Background(), "", m), nil
+	}
+
+	intent, _ := core.CreateIntent(sender, nil, []string{"ocr"}, "")
+	first := make(chan *core.NegotiationResponse)
+	go func() {
+		resp, _ := h(intent)
+		first <- resp
+	}()
+	<-entered
+	resp, err := h(intent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Accepted || !strings.Contains(resp.Reason, "too many concurrent intents") {
+		t.Errorf("second intent: got %+v", resp)
+	}
+	close(release)
+	if resp = <-first; !resp.Accepted {
+		t.Errorf("first intent: got %+v", resp)
+	}
+
+	if err = os.WriteFile(path, []byte("deny: ["+sender.DID.String()+"]\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Reload(path); err != nil {
+		t.Fatal(err)
+	}
+	if resp, _ = h(intent); resp.Accepted || !strings.Contains(resp.Reason, "is denied") {
+		t.Errorf("after reload: got %+v", resp)
+	}
+}
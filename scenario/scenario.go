@@ -0,0 +1,355 @@
+// Package scenario runs declarative regression scenarios for negotiation
+// behaviour, so a case can be written as data rather than as Go.
+//
+// A scenario file (YAML, or JSON, which YAML includes) declares agents
+// and their capabilities, optional aliases and starting trust, and a script
+// of steps.  An intent step sends an intent between two agents and checks
+// the outcome; a trust step asserts a score in one agent's trust graph.
+// Run starts every agent as a p2p.AgentHost on a p2p.MemoryNetwork, so the
+// script exercises the real handshake, signature policy, admission checks,
+// default negotiation handler and trust updates, without opening sockets.
+//
+//	name: missing capability is rejected
+//	agents:
+//	  - id: alpha
+//	    capabilities: [summarise]
+//	  - id: beta
+//	    capabilities: [ocr]
+//	steps:
+//	  - intent: {from: alpha, to: beta, capabilities: [ocr]}
+//	    expect: {accepted: true}
+//	  - intent: {from: alpha, to: beta, capabilities: [math]}
+//	    expect: {accepted: false, reason: missing capabilities}
+//	  - trust: {from: alpha, to: beta, min: 0.03, max: 0.03}
+package scenario
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/internal/yamlconf"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+// Scenario is one scripted negotiation test.
+type Scenario struct {
+	Name    string                 `json:"name"`
+	Agents  []Agent                `json:"agents"`
+	Aliases []core.CapabilityAlias `json:"aliases,omitempty"`
+	Trust   []TrustEdge            `json:"trust,omitempty"` // Scores before the first step
+	Steps   []Step                 `json:"steps"`
+}
+
+// Agent declares one agent of a scenario.
+type Agent struct {
+	ID           string   `json:"id"`
+	Capabilities []string `json:"capabilities"`
+}
+
+// TrustEdge is a trust score one agent assigns another.
+type TrustEdge struct {
+	From  string  `json:"from"`
+	To    string  `json:"to"`
+	Score float32 `json:"score"`
+}
+
+// Step is either an intent, optionally with expectations, or a trust
+// assertion.
+type Step struct {
+	Name   string          `json:"name,omitempty"`
+	Intent *Intent         `json:"intent,omitempty"`
+	Expect *Expectation    `json:"expect,omitempty"`
+	Trust  *TrustAssertion `json:"trust,omitempty"`
+}
+
+// Intent is an intent sent from one agent to another.
+type Intent struct {
+	From         string    `json:"from"`
+	To           string    `json:"to"`
+	Capabilities []string  `json:"capabilities"`
+	Payload      string    `json:"payload,omitempty"`
+	Vector       []float32 `json:"vector,omitempty"`
+	Expired      bool      `json:"expired,omitempty"` // Send with an ExpiresAt in the past
+}
+
+// Expectation is the outcome an intent step must produce.  Unset fields are
+// not checked.
+type Expectation struct {
+	Accepted *bool  `json:"accepted,omitempty"`
+	Reason   string `json:"reason,omitempty"` // Substring of the response's reason
+	// Unanswered expects the responder to drop the intent without a reply,
+	// as hosts do with expired, replayed or badly signed intents.
+	Unanswered bool `json:"unanswered,omitempty"`
+}
+
+// TrustAssertion bounds the score From assigns To, inclusively.
+type TrustAssertion struct {
+	From string   `json:"from"`
+	To   string   `json:"to"`
+	Min  *float32 `json:"min,omitempty"`
+	Max  *float32 `json:"max,omitempty"`
+}
+
+// Load reads the scenario at path.
+func Load(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("scenario: %w", err)
+	}
+	s, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if s.Name == "" {
+		s.Name = filepath.Base(path)
+	}
+	return s, nil
+}
+
+// Parse decodes and validates a YAML or JSON scenario.
+func Parse(data []byte) (*Scenario, error) {
+	var s Scenario
+	if err := yamlconf.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("scenario: %w", err)
+	}
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Validate checks that the scenario only refers to agents it declares and
+// that every step does exactly one thing.
+func (s *Scenario) Validate() error {
+	ids := make(map[string]bool, len(s.Agents))
+	for _, a := range s.Agents {
+		if a.ID == "" {
+			return fmt.Errorf("scenario: agent without an id")
+		}
+		if ids[a.ID] {
+			return fmt.Errorf("scenario: duplicate agent %q", a.ID)
+		}
+		ids[a.ID] = true
+	}
+	known := func(what, id string) error {
+		if !ids[id] {
+			return fmt.Errorf("%s: unknown agent %q", what, id)
+		}
+		return nil
+	}
+	for _, e := range s.Trust {
+		if err := firstErr(known("trust", e.From), known("trust", e.To)); err != nil {
+			return fmt.Errorf("scenario: %w", err)
+		}
+	}
+	for i, st := range s.Steps {
+		var err error
+		switch {
+		case (st.Intent == nil) == (st.Trust == nil):
+			err = fmt.Errorf("needs exactly one of intent and trust")
+		case st.Intent != nil && st.Intent.From == st.Intent.To:
+			err = fmt.Errorf("intent: %q sends to itself", st.Intent.From)
+		case st.Intent != nil && st.Expect != nil && st.Expect.Unanswered && (st.Expect.Accepted != nil || st.Expect.Reason != ""):
+			err = fmt.Errorf("an unanswered intent has no accepted or reason")
+		case st.Intent != nil:
+			err = firstErr(known("intent", st.Intent.From), known("intent", st.Intent.To))
+		case st.Expect != nil:
+			err = fmt.Errorf("expect only applies to intent steps")
+		default:
+			err = firstErr(known("trust", st.Trust.From), known("trust", st.Trust.To))
+		}
+		if err != nil {
+			return fmt.Errorf("scenario: step %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// Result is the outcome of Run.
+type Result struct {
+	Scenario string
+	Steps    []StepResult
+}
+
+// StepResult is the outcome of one step.
+type StepResult struct {
+	Step     int    // 1-based
+	Name     string // The step's name, if it has one
+	Response *core.NegotiationResponse
+	Failures []string // Expectations the step did not meet
+}
+
+// Passed reports whether every step met its expectations.
+func (r *Result) Passed() bool {
+	for _, st := range r.Steps {
+		if len(st.Failures) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Failures returns one line per unmet expectation, prefixed with its step.
+func (r *Result) Failures() []string {
+	var out []string
+	for _, st := range r.Steps {
+		label := fmt.Sprintf("step %d", st.Step)
+		if st.Name != "" {
+			label += " (" + st.Name + ")"
+		}
+		for _, f := range st.Failures {
+			out = append(out, label+": "+f)
+		}
+	}
+	return out
+}
+
+// stepTimeout bounds each intent step, including the handshake before an
+// agent's first intent to a peer.
+const stepTimeout = 10 * time.Second
+
+// Run plays s and reports every step's outcome.  The error is only set when
+// s itself is invalid or its hosts cannot be started.
+func Run(s *Scenario) (*Result, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	aliases := core.NewCapabilityAliases(s.Aliases...)
+	network := p2p.NewMemoryNetwork()
+	defer network.Close()
+	hosts := make(map[string]*p2p.AgentHost, len(s.Agents))
+	defer func() {
+		for _, h := range hosts {
+			_ = h.Close()
+		}
+	}()
+	for _, spec := range s.Agents {
+		a, err := core.NewAgent(spec.ID, spec.Capabilities)
+		if err != nil {
+			return nil, fmt.Errorf("scenario: agent %q: %w", spec.ID, err)
+		}
+		h, err := p2p.NewHost(context.Background(), a,
+			p2p.WithMemoryNetwork(network),
+			p2p.WithCapabilityAliases(aliases),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scenario: agent %q: %w", spec.ID, err)
+		}
+		hosts[spec.ID] = h
+	}
+	did := func(id string) string { return hosts[id].Agent().DID.String() }
+	for _, e := range s.Trust {
+		_ = hosts[e.From].Trust().Set(did(e.From), did(e.To), e.Score)
+	}
+
+	r := &runner{hosts: hosts, handshaken: map[[2]string]bool{}}
+	res := &Result{Scenario: s.Name}
+	for i, st := range s.Steps {
+		sr := StepResult{Step: i + 1, Name: st.Name}
+		if st.Intent != nil {
+			sr.Response, sr.Failures = r.runIntent(st.Intent, st.Expect)
+		} else {
+			sr.Failures = checkTrust(hosts[st.Trust.From].Trust().Get(did(st.Trust.From), did(st.Trust.To)), st.Trust)
+		}
+		res.Steps = append(res.Steps, sr)
+	}
+	return res, nil
+}
+
+// runner holds the hosts of a running scenario.
+type runner struct {
+	hosts      map[string]*p2p.AgentHost
+	handshaken map[[2]string]bool // {from, to} pairs that completed a handshake
+}
+
+func (r *runner) runIntent(in *Intent, want *Expectation) (*core.NegotiationResponse, []string) {
+	if want == nil {
+		want = &Expectation{}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), stepTimeout)
+	defer cancel()
+	from, to := r.hosts[in.From], r.hosts[in.To]
+	if pair := [2]string{in.From, in.To}; !r.handshaken[pair] {
+		if _, err := p2p.DiscoverAndHandshake(ctx, from, to.AddrInfo()); err != nil {
+			return nil, []string{fmt.Sprintf("handshake: %v", err)}
+		}
+		r.handshaken[pair] = true
+	}
+	intent, err := core.CreateIntent(from.Agent(), in.Vector, in.Capabilities, in.Payload)
+	if err != nil {
+		return nil, []string{err.Error()}
+	}
+	if in.Expired {
+		intent.ExpiresAt = time.Now().Add(-time.Second).UnixNano()
+		if err = core.SignIntent(from.Agent(), intent); err != nil {
+			return nil, []string{err.Error()}
+		}
+	}
+	// The responder applies its trust delta after replying; wait for it so
+	// the next step sees the updated graph.
+	trusted := make(chan struct{}, 1)
+	unsubscribe := to.Subscribe(func(ev p2p.Event) {
+		if ev.DID == intent.DID {
+			select {
+			case trusted <- struct{}{}:
+			default:
+			}
+		}
+	}, p2p.EventTrustUpdated)
+	defer unsubscribe()
+	resp, err := from.SendIntent(ctx, to.PeerID(), intent)
+	if err == nil && resp.TrustDelta != 0 {
+		select {
+		case <-trusted:
+		case <-ctx.Done():
+			return resp, []string{"responder never applied its trust delta"}
+		}
+	}
+	switch {
+	case want.Unanswered && err == nil:
+		return resp, []string{fmt.Sprintf("expected no answer, got accepted=%t (%s)", resp.Accepted, resp.Reason)}
+	case want.Unanswered:
+		return nil, nil
+	case err != nil:
+		return nil, []string{fmt.Sprintf("send intent: %v", err)}
+	}
+
+	var failures []string
+	if !core.VerifyResponseSignature(resp, to.Agent().PublicKey()) {
+		failures = append(failures, "response signature does not verify")
+	}
+	if want.Accepted != nil && resp.Accepted != *want.Accepted {
+		failures = append(failures, fmt.Sprintf("expected accepted=%t, got %t (%s)", *want.Accepted, resp.Accepted, resp.Reason))
+	}
+	if want.Reason != "" && !strings.Contains(resp.Reason, want.Reason) {
+		failures = append(failures, fmt.Sprintf("reason %q does not contain %q", resp.Reason, want.Reason))
+	}
+	return resp, failures
+}
+
+func checkTrust(got float32, a *TrustAssertion) []string {
+	// Deltas accumulate in float32; compare with a little slack.
+	const eps = 1e-4
+	var failures []string
+	if a.Min != nil && got < *a.Min-eps {
+		failures = append(failures, fmt.Sprintf("trust %s->%s is %.4f, below %.4f", a.From, a.To, got, *a.Min))
+	}
+	if a.Max != nil && got > *a.Max+eps {
+		failures = append(failures, fmt.Sprintf("trust %s->%s is %.4f, above %.4f", a.From, a.To, got, *a.Max))
+	}
+	return failures
+}
+
+func firstErr(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package scenario_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/olserra/agent-semantic-protocol/scenario"
+)
+
+func TestScenarios(t *testing.T) {
+	files, err := filepath.Glob("testdata/*.yaml")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no scenarios: %v", err)
+	}
+	for _, path := range files {
+		s, err := scenario.Load(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Run(s.Name, func(t *testing.T) {
+			res, err := scenario.Run(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, f := range res.Failures() {
+				t.Error(f)
+			}
+		})
+	}
+}
+
+func TestRunReportsFailures(t *testing.T) {
+	s, err := scenario.Parse([]byte(`
+agents:
+  - {id: alpha, capabilities: []}
+  - {id: beta, capabilities: [ocr]}
+steps:
+  - name: wrong expectation
+    intent: {from: alpha, to: beta, capabilities: [math]}
+    expect: {accepted: true, reason: all capabilities}
+  - trust: {from: alpha, to: beta, min: 0.5}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := scenario.Run(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Passed() {
+		t.Fatal("scenario passed")
+	}
+	got := res.Failures()
+	if len(got) != 3 ||
+		!strings.HasPrefix(got[0], "step 1 (wrong expectation): expected accepted=true, got false") ||
+		!strings.Contains(got[1], "does not contain \"all capabilities\"") ||
+		!strings.HasPrefix(got[2], "step 2: trust alpha->beta is 0.0000, below 0.5000") {
+		t.Errorf("failures: %q", got)
+	}
+	if res.Steps[0].Response == nil || res.Steps[0].Response.Accepted {
+		t.Errorf("response: %+v", res.Steps[0].Response)
+	}
+}
+
+func TestParseRejectsInvalid(t *testing.T) {
+	cases := map[string]string{
+		"unknown agent \"gamma\"": "agents: [{id: alpha}]\nsteps:\n  - intent: {from: alpha, to: gamma}\n",
+		"exactly one of":          "agents: [{id: alpha}]\nsteps:\n  - name: empty\n",
+		"duplicate agent":         "agents: [{id: alpha}, {id: alpha}]\n",
+		"expect only applies":     "agents: [{id: a}]\nsteps:\n  - trust: {from: a, to: a}\n    expect: {accepted: true}\n",
+		"unknown field":           "agents: [{id: alpha, caps: [x]}]\n",
+		"sends to itself":         "agents: [{id: a}]\nsteps:\n  - intent: {from: a, to: a}\n",
+		"unanswered intent":       "agents: [{id: a}, {id: b}]\nsteps:\n  - intent: {from: a, to: b}\n    expect: {unanswered: true, accepted: false}\n",
+	}
+	for want, src := range cases {
+		if _, err := scenario.Parse([]byte(src)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: got %v, want error containing %q", src, err, want)
+		}
+	}
+	if _, err := scenario.Parse([]byte(`{"agents": [{"id": "a"}], "steps": [{"trust": {"from": "a", "to": "b"}}]}`)); err == nil {
+		t.Error("JSON: expected unknown agent error")
+	}
+}
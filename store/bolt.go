@@ -0,0 +1,120 @@
+// Package store provides database-backed implementations of the persistence
+// interfaces defined in core and p2p (TrustStore, WorkflowStore, …).
+//
+// The core package only ships dependency-free file backends; anything that
+// needs an embedded database lives here so that core stays importable by
+// constrained builds.
+package store
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+	bolt "go.etcd.io/bbolt"
+)
+
+var trustBucket = []byte("trust")
+
+// BoltTrustStore is a core.TrustStore backed by a BoltDB file.
+// Each edge is one key ("<from>-><to>") holding a big-endian float32.
+type BoltTrustStore struct {
+	db *bolt.DB
+}
+
+// OpenBoltTrustStore opens or creates the BoltDB database at path.
+// BoltDB holds an exclusive file lock, so only one host may use a file at a time.
+func OpenBoltTrustStore(path string) (*BoltTrustStore, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("store: open bolt %s: %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(trustBucket)
+		return err
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("store: init bolt bucket: %w", err)
+	}
+	return &BoltTrustStore{db: db}, nil
+}
+
+// Close releases the underlying database file.
+func (s *BoltTrustStore) Close() error { return s.db.Close() }
+
+// Get implements core.TrustStore.
+func (s *BoltTrustStore) Get(from, to string) (float32, error) {
+	var score float32
+	err := s.db.View(func(tx *bolt.Tx) error {
+		score = decodeScore(tx.Bucket(trustBucket).Get(boltTrustKey(from, to)))
+		return nil
+	})
+	return score, err
+}
+
+// Set implements core.TrustStore.
+func (s *BoltTrustStore) Set(from, to string, score float32) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(trustBucket).Put(boltTrustKey(from, to), encodeScore(clamp(score)))
+	})
+}
+
+// Apply implements core.TrustStore.  The read-modify-write runs in a single
+// transaction, so concurrent Apply calls never lose updates.
+func (s *BoltTrustStore) Apply(from, to string, delta float32) (float32, error) {
+	var score float32
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(trustBucket)
+		k := boltTrustKey(from, to)
+		score = clamp(decodeScore(b.Get(k)) + delta)
+		return b.Put(k, encodeScore(score))
+	})
+	return score, err
+}
+
+// Snapshot implements core.TrustStore.
+func (s *BoltTrustStore) Snapshot() (map[string]float32, error) {
+	out := make(map[string]float32)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(trustBucket).ForEach(func(k, v []byte) error {
+			out[string(k)] = decodeScore(v)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("store: bolt snapshot: %w", err)
+	}
+	return out, nil
+}
+
+var _ core.TrustStore = (*BoltTrustStore)(nil)
+
+// ------------------------------------------------------------------ helpers
+
+func boltTrustKey(from, to string) []byte { return []byte(from + "->" + to) }
+
+func encodeScore(v float32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, math.Float32bits(v))
+	return b
+}
+
+func decodeScore(b []byte) float32 {
+	if len(b) != 4 {
+		return 0
+	}
+	return math.Float32frombits(binary.BigEndian.Uint32(b))
+}
+
+func clamp(v float32) float32 {
+	if v < 0 {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
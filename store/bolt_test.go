@@ -0,0 +1,86 @@
+package store_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/store"
+)
+
+func TestBoltTrustStoreReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trust.db")
+
+	s, err := store.OpenBoltTrustStore(path)
+	if err != nil {
+		t.Fatalf("OpenBoltTrustStore: %v", err)
+	}
+	tg, err := core.NewTrustGraphFromStore(s)
+	if err != nil {
+		t.Fatalf("NewTrustGraphFromStore: %v", err)
+	}
+	if err := tg.Set("did:a", "did:b", 0.5); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if err := tg.Apply("did:a", "did:b", 0.05); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if err := tg.Apply("did:a", "did:c", -0.3); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	// BoltDB locks the file, so close before reopening.
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	reopened, err := store.OpenBoltTrustStore(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	t.Cleanup(func() { _ = reopened.Close() })
+	tg2, err := core.NewTrustGraphFromStore(reopened)
+	if err != nil {
+		t.Fatalf("NewTrustGraphFromStore: %v", err)
+	}
+	if got := tg2.Get("did:a", "did:b"); got < 0.549 || got > 0.551 {
+		t.Errorf("a->b after reload: got %v want 0.55", got)
+	}
+	if got := tg2.Get("did:a", "did:c"); got != 0 {
+		t.Errorf("a->c should be clamped to 0, got %v", got)
+	}
+	if n := len(tg2.Snapshot()); n != 2 {
+		t.Errorf("Snapshot: got %d entries want 2", n)
+	}
+}
+
+func TestBoltTrustStoreRoundTrip(t *testing.T) {
+	s, err := store.OpenBoltTrustStore(filepath.Join(t.TempDir(), "trust.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+
+	if v, err := s.Get("did:a", "did:b"); err != nil || v != 0 {
+		t.Errorf("Get on empty store: %v, %v", v, err)
+	}
+	if err := s.Set("did:a", "did:b", 0.25); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if v, _ := s.Get("did:a", "did:b"); v != 0.25 {
+		t.Errorf("Get: got %v want 0.25", v)
+	}
+	got, err := s.Apply("did:a", "did:b", 1.5)
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if got != 1 {
+		t.Errorf("Apply should clamp to 1, got %v", got)
+	}
+	snap, err := s.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+	if len(snap) != 1 || snap["did:a->did:b"] != 1 {
+		t.Errorf("Snapshot: %v", snap)
+	}
+}
@@ -0,0 +1,95 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+// SQLiteWorkflowStore is a p2p.WorkflowStore backed by a SQLite database.
+// It takes an open *sql.DB rather than a path, so the application picks
+// the driver (mattn/go-sqlite3, modernc.org/sqlite, …) and this module
+// stays free of cgo.  Each workflow is one row holding its JSON state.
+type SQLiteWorkflowStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteWorkflowStore creates the workflows table in db if missing.
+func NewSQLiteWorkflowStore(db *sql.DB) (*SQLiteWorkflowStore, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS workflows (
+		id         TEXT PRIMARY KEY,
+		status     TEXT NOT NULL,
+		updated_at INTEGER NOT NULL,
+		state      BLOB NOT NULL
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("store: create workflows table: %w", err)
+	}
+	return &SQLiteWorkflowStore{db: db}, nil
+}
+
+// Save implements p2p.WorkflowStore.
+func (s *SQLiteWorkflowStore) Save(state *p2p.WorkflowState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("store: encode workflow %s: %w", state.WorkflowID, err)
+	}
+	_, err = s.db.Exec(`INSERT INTO workflows (id, status, updated_at, state) VALUES (?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET status = excluded.status, updated_at = excluded.updated_at, state = excluded.state`,
+		state.WorkflowID, state.Status, state.UpdatedAt.UnixNano(), data)
+	if err != nil {
+		return fmt.Errorf("store: save workflow %s: %w", state.WorkflowID, err)
+	}
+	return nil
+}
+
+// Load implements p2p.WorkflowStore.
+func (s *SQLiteWorkflowStore) Load(workflowID string) (*p2p.WorkflowState, error) {
+	var data []byte
+	err := s.db.QueryRow(`SELECT state FROM workflows WHERE id = ?`, workflowID).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, p2p.ErrWorkflowNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("store: load workflow %s: %w", workflowID, err)
+	}
+	var state p2p.WorkflowState
+	if err = json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("store: parse workflow %s: %w", workflowID, err)
+	}
+	return &state, nil
+}
+
+// Delete implements p2p.WorkflowStore.
+func (s *SQLiteWorkflowStore) Delete(workflowID string) error {
+	if _, err := s.db.Exec(`DELETE FROM workflows WHERE id = ?`, workflowID); err != nil {
+		return fmt.Errorf("store: delete workflow %s: %w", workflowID, err)
+	}
+	return nil
+}
+
+// List implements p2p.WorkflowStore.
+func (s *SQLiteWorkflowStore) List() ([]string, error) {
+	rows, err := s.db.Query(`SELECT id FROM workflows ORDER BY updated_at`)
+	if err != nil {
+		return nil, fmt.Errorf("store: list workflows: %w", err)
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err = rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("store: list workflows: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: list workflows: %w", err)
+	}
+	return ids, nil
+}
+
+var _ p2p.WorkflowStore = (*SQLiteWorkflowStore)(nil)
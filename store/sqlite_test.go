@@ -0,0 +1,105 @@
+//go:build cgo
+
+package store_test
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+	"github.com/olserra/agent-semantic-protocol/store"
+)
+
+func openSQLite(t *testing.T, path string) (*sql.DB, *store.SQLiteWorkflowStore) {
+	t.Helper()
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	s, err := store.NewSQLiteWorkflowStore(db)
+	if err != nil {
+		_ = db.Close()
+		t.Fatalf("NewSQLiteWorkflowStore: %v", err)
+	}
+	return db, s
+}
+
+func workflowState(id string, updated int64) *p2p.WorkflowState {
+	return &p2p.WorkflowState{
+		WorkflowID: id,
+		Steps:      []p2p.WorkflowStep{{ID: "charge", Capability: "charge", OnFailure: p2p.FailCompensate}},
+		Results:    []p2p.StepResult{{StepID: "charge", AgentID: "shop", Accepted: true, Timestamp: time.Unix(1, 0).UTC()}},
+		Status:     p2p.WorkflowRunning,
+		UpdatedAt:  time.Unix(updated, 0).UTC(),
+	}
+}
+
+func TestSQLiteWorkflowStoreRoundTrip(t *testing.T) {
+	db, s := openSQLite(t, filepath.Join(t.TempDir(), "workflows.db"))
+	t.Cleanup(func() { _ = db.Close() })
+
+	if _, err := s.Load("orders/42"); !errors.Is(err, p2p.ErrWorkflowNotFound) {
+		t.Errorf("Load on empty store: %v, want ErrWorkflowNotFound", err)
+	}
+	first, second := workflowState("orders/42", 2), workflowState("orders/7", 1)
+	for _, st := range []*p2p.WorkflowState{first, second} {
+		if err := s.Save(st); err != nil {
+			t.Fatalf("Save(%s): %v", st.WorkflowID, err)
+		}
+	}
+	got, err := s.Load("orders/42")
+	if err != nil || !reflect.DeepEqual(got, first) {
+		t.Fatalf("Load = %+v, %v; want %+v", got, err, first)
+	}
+	// Oldest update first.
+	if ids, err := s.List(); err != nil || !reflect.DeepEqual(ids, []string{"orders/7", "orders/42"}) {
+		t.Errorf("List = %v, %v", ids, err)
+	}
+
+	// Saving again replaces the row.
+	first.Status, first.Next, first.UpdatedAt = p2p.WorkflowFailed, "ship", time.Unix(3, 0).UTC()
+	if err = s.Save(first); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if got, err = s.Load("orders/42"); err != nil || !reflect.DeepEqual(got, first) {
+		t.Errorf("Load after update = %+v, %v; want %+v", got, err, first)
+	}
+
+	if err = s.Delete("orders/42"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err = s.Load("orders/42"); !errors.Is(err, p2p.ErrWorkflowNotFound) {
+		t.Errorf("Load after Delete: %v, want ErrWorkflowNotFound", err)
+	}
+	if ids, err := s.List(); err != nil || !reflect.DeepEqual(ids, []string{"orders/7"}) {
+		t.Errorf("List after Delete = %v, %v", ids, err)
+	}
+}
+
+func TestSQLiteWorkflowStoreReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "workflows.db")
+	db, s := openSQLite(t, path)
+	state := workflowState("orders/42", 2)
+	if err := s.Save(state); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	// The table already exists: reopening must keep its rows.
+	db, reopened := openSQLite(t, path)
+	t.Cleanup(func() { _ = db.Close() })
+	got, err := reopened.Load("orders/42")
+	if err != nil || !reflect.DeepEqual(got, state) {
+		t.Fatalf("Load after reopen = %+v, %v; want %+v", got, err, state)
+	}
+	if ids, err := reopened.List(); err != nil || len(ids) != 1 || ids[0] != "orders/42" {
+		t.Errorf("List after reopen = %v, %v", ids, err)
+	}
+}
@@ -0,0 +1,38 @@
+// Package tracing carries OpenTelemetry traces across Agent Semantic
+// Protocol hops.
+//
+// The p2p host opens spans around handshakes, intents and workflow steps
+// through the go.opentelemetry.io/otel/trace TracerProvider given to
+// p2p.WithTracerProvider, and carries the trace context to the responder in
+// IntentMessage.Metadata under TraceParentKey, in the W3C Trace Context
+// format.  Any OpenTelemetry SDK or exporter (Jaeger, Tempo, OTLP) plugs in
+// as is; tests can keep spans in memory with the SDK's tracetest package.
+//
+// Without a TracerProvider the host still forwards an incoming trace
+// context to the intents it sends on behalf of a handler, so traces are
+// not broken by agents that do not record spans.
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// TraceParentKey is the IntentMessage.Metadata key carrying the trace
+// context.
+const TraceParentKey = "traceparent"
+
+// propagator reads and writes traceparent (and tracestate, when set).
+var propagator = propagation.TraceContext{}
+
+// Inject writes the trace context of ctx into metadata, if there is one.
+func Inject(ctx context.Context, metadata map[string]string) {
+	propagator.Inject(ctx, propagation.MapCarrier(metadata))
+}
+
+// Extract returns ctx carrying the remote trace context found in metadata,
+// or ctx unchanged if there is none or it is malformed.
+func Extract(ctx context.Context, metadata map[string]string) context.Context {
+	return propagator.Extract(ctx, propagation.MapCarrier(metadata))
+}
@@ -0,0 +1,83 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/olserra/agent-semantic-protocol/tracing"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestPropagation(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tr := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)).Tracer("test")
+
+	ctx, root := tr.Start(context.Background(), "root")
+	md := map[string]string{}
+	tracing.Inject(ctx, md)
+	root.End()
+	if md[tracing.TraceParentKey] == "" {
+		t.Fatalf("no traceparent injected: %v", md)
+	}
+
+	// The remote side continues the trace from the metadata.
+	remote := tracing.Extract(context.Background(), md)
+	_, child := tr.Start(remote, "child")
+	child.RecordError(errors.New("boom"))
+	child.SetStatus(codes.Error, "boom")
+	child.End()
+
+	spans := rec.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("spans: %d", len(spans))
+	}
+	r, c := spans[0], spans[1]
+	if r.Parent().IsValid() {
+		t.Errorf("root has a parent: %v", r.Parent())
+	}
+	if c.SpanContext().TraceID() != r.SpanContext().TraceID() ||
+		c.Parent().SpanID() != r.SpanContext().SpanID() || !c.Parent().IsRemote() {
+		t.Errorf("child not parented to root: %v / %v", c.Parent(), r.SpanContext())
+	}
+	if c.Status().Code != codes.Error || len(c.Events()) == 0 {
+		t.Error("child error not recorded")
+	}
+}
+
+func TestExtractIgnoresMalformed(t *testing.T) {
+	for _, bad := range []string{
+		"",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01",
+		"00-zzf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	} {
+		ctx := tracing.Extract(context.Background(), map[string]string{tracing.TraceParentKey: bad})
+		if trace.SpanContextFromContext(ctx).IsValid() {
+			t.Errorf("Extract(%q) produced a span context", bad)
+		}
+	}
+}
+
+func TestNoopKeepsTraceContext(t *testing.T) {
+	md := map[string]string{tracing.TraceParentKey: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}
+	ctx := tracing.Extract(context.Background(), md)
+	ctx, span := noop.NewTracerProvider().Tracer("test").Start(ctx, "hop")
+	defer span.End()
+
+	out := map[string]string{}
+	tracing.Inject(ctx, out)
+	if out[tracing.TraceParentKey] != md[tracing.TraceParentKey] {
+		t.Errorf("noop span lost the trace context: %q", out[tracing.TraceParentKey])
+	}
+	empty := map[string]string{}
+	tracing.Inject(context.Background(), empty)
+	if len(empty) != 0 {
+		t.Errorf("empty context injected metadata: %v", empty)
+	}
+}
@@ -0,0 +1,215 @@
+package v1
+
+import (
+	"context"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/embeddings"
+	"github.com/olserra/agent-semantic-protocol/p2p"
+)
+
+// ProtocolVersion is the highest protocol version this build speaks.
+const ProtocolVersion = core.ProtocolVersion
+
+// ------------------------------------------------------------------ identity
+
+// Agent is a local agent: an ID, its capabilities and its DID key pair.
+type Agent = core.Agent
+
+// DID is a decentralised identifier backed by an Ed25519 key.
+type DID = core.DID
+
+// NewAgent creates an agent with a fresh identity.
+func NewAgent(id string, capabilities []string) (*Agent, error) {
+	return core.NewAgent(id, capabilities)
+}
+
+// LoadAgent loads the identity stored at path, creating and saving a new one
+// if the file does not exist.  passphrase encrypts the key on disk.
+func LoadAgent(path, id string, capabilities []string, passphrase []byte) (*Agent, error) {
+	return core.LoadAgent(path, id, capabilities, passphrase)
+}
+
+// ------------------------------------------------------------------ messages
+
+// Message types carried on the wire.
+type (
+	IntentMessage          = core.IntentMessage
+	HandshakeMessage       = core.HandshakeMessage
+	NegotiationResponse    = core.NegotiationResponse
+	WorkflowMessage        = core.WorkflowMessage
+	CapabilityAnnouncement = core.CapabilityAnnouncement
+	AgentProfile           = core.AgentProfile
+)
+
+// CreateIntent builds a signed intent.
+func CreateIntent(sender *Agent, vector []float32, capabilities []string, payload string) (*IntentMessage, error) {
+	return core.CreateIntent(sender, vector, capabilities, payload)
+}
+
+// CreateIntentFromText embeds text with e and builds a signed intent
+// carrying it as payload.
+func CreateIntentFromText(ctx context.Context, sender *Agent, e Embedder, capabilities []string, text string) (*IntentMessage, error) {
+	return core.CreateIntentFromText(ctx, sender, e, capabilities, text)
+}
+
+// VerifyIntentSignature reports whether intent was signed by the owner of
+// pubKey.  Unsigned intents verify.
+func VerifyIntentSignature(intent *IntentMessage, pubKey []byte) bool {
+	return core.VerifyIntentSignature(intent, pubKey)
+}
+
+// Embedder turns text into an intent vector (see package embeddings).
+type Embedder = embeddings.Embedder
+
+// ------------------------------------------------------------------ negotiation
+
+// NegotiationHandler decides whether to accept an intent.
+type NegotiationHandler = core.NegotiationHandler
+
+// DefaultNegotiationHandler accepts intents whose required capabilities the
+// agent offers.
+func DefaultNegotiationHandler(agent *Agent) NegotiationHandler {
+	return core.DefaultNegotiationHandler(agent)
+}
+
+// ------------------------------------------------------------------ discovery and trust
+
+// DiscoveryRegistry indexes the capability profiles of known peers.
+type DiscoveryRegistry = core.DiscoveryRegistry
+
+// TrustGraph holds directed trust scores between DIDs.
+type TrustGraph = core.TrustGraph
+
+// TrustStore persists a TrustGraph.
+type TrustStore = core.TrustStore
+
+// CapabilityAliases maps alternative capability names to canonical ones.
+type (
+	CapabilityAliases = core.CapabilityAliases
+	CapabilityAlias   = core.CapabilityAlias
+)
+
+// NewCapabilityAliases creates an alias table.
+func NewCapabilityAliases(aliases ...CapabilityAlias) *CapabilityAliases {
+	return core.NewCapabilityAliases(aliases...)
+}
+
+// CapabilityCatalog enables semantic capability matching.
+type (
+	CapabilityCatalog   = core.CapabilityCatalog
+	CapabilitySemantics = core.CapabilitySemantics
+)
+
+// NewCapabilityCatalog creates a catalog matching at threshold (a default
+// if 0).
+func NewCapabilityCatalog(threshold float64, entries ...CapabilitySemantics) *CapabilityCatalog {
+	return core.NewCapabilityCatalog(threshold, entries...)
+}
+
+// ------------------------------------------------------------------ hosts
+
+// Host is an agent attached to the libp2p network.
+type Host = p2p.AgentHost
+
+// HostOption configures a Host.
+type HostOption = p2p.HostOption
+
+// PeerID identifies a libp2p peer.
+type PeerID = peer.ID
+
+// PeerAddrInfo is a peer ID with its network addresses.
+type PeerAddrInfo = peer.AddrInfo
+
+// Callbacks registered on a Host.
+type (
+	IntentCallback        = p2p.IntentCallback
+	IntentContextCallback = p2p.IntentContextCallback
+	Inspector             = p2p.Inspector
+	InspectedFrame        = p2p.InspectedFrame
+)
+
+// SignaturePolicy controls verification of incoming signatures.
+type SignaturePolicy = p2p.SignaturePolicy
+
+// Signature policies.
+const (
+	VerifyIfKnown = p2p.VerifyIfKnown
+	RequireSigned = p2p.RequireSigned
+	VerifyOff     = p2p.VerifyOff
+)
+
+// NewHost starts a host for agent.
+func NewHost(ctx context.Context, agent *Agent, opts ...HostOption) (*Host, error) {
+	return p2p.NewHost(ctx, agent, opts...)
+}
+
+// WithSignaturePolicy sets how incoming signatures are verified.
+func WithSignaturePolicy(p SignaturePolicy) HostOption { return p2p.WithSignaturePolicy(p) }
+
+// WithTrustStore persists the host's trust graph.
+func WithTrustStore(store TrustStore) HostOption { return p2p.WithTrustStore(store) }
+
+// WithCodecs offers payload codecs by name, preferred first.
+func WithCodecs(names ...string) HostOption { return p2p.WithCodecs(names...) }
+
+// WithMultiplexing shares one stream per peer between concurrent intents.
+func WithMultiplexing(maxInflight int, requestTimeout time.Duration) HostOption {
+	return p2p.WithMultiplexing(maxInflight, requestTimeout)
+}
+
+// WithGossipSub spreads announcements and revocations over GossipSub.
+func WithGossipSub() HostOption { return p2p.WithGossipSub() }
+
+// WithDHT enables Kademlia peer discovery from bootstrap peers.
+func WithDHT(bootstrap ...PeerAddrInfo) HostOption { return p2p.WithDHT(bootstrap...) }
+
+// WithReplayWindow sets how long served intents are remembered.
+func WithReplayWindow(window time.Duration) HostOption { return p2p.WithReplayWindow(window) }
+
+// WithCapabilityAliases normalises capability names through aliases.
+func WithCapabilityAliases(aliases *CapabilityAliases) HostOption {
+	return p2p.WithCapabilityAliases(aliases)
+}
+
+// WithCapabilityCatalog enables semantic capability discovery.
+func WithCapabilityCatalog(catalog *CapabilityCatalog) HostOption {
+	return p2p.WithCapabilityCatalog(catalog)
+}
+
+// WithInspector shows every incoming frame to in before dispatch.
+func WithInspector(in Inspector) HostOption { return p2p.WithInspector(in) }
+
+// ------------------------------------------------------------------ workflows
+
+// Orchestrator runs multi-step workflows across peers.
+type Orchestrator = p2p.WorkflowOrchestrator
+
+// Workflow building blocks.
+type (
+	WorkflowStep  = p2p.WorkflowStep
+	StepResult    = p2p.StepResult
+	WorkflowGraph = p2p.WorkflowGraph
+	WorkflowEvent = p2p.WorkflowEvent
+	ReplanPolicy  = p2p.ReplanPolicy
+	FailurePolicy = p2p.FailurePolicy
+)
+
+// Failure policies.
+const (
+	FailAbort      = p2p.FailAbort
+	FailContinue   = p2p.FailContinue
+	FailCompensate = p2p.FailCompensate
+)
+
+// NewOrchestrator creates an orchestrator on host with a per-step timeout.
+func NewOrchestrator(host *Host, stepTimeout time.Duration) *Orchestrator {
+	return p2p.NewOrchestrator(host, stepTimeout)
+}
+
+// NewWorkflowGraph validates steps as a DAG.
+func NewWorkflowGraph(steps ...WorkflowStep) (*WorkflowGraph, error) {
+	return p2p.NewWorkflowGraph(steps...)
+}
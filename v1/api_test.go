@@ -0,0 +1,29 @@
+package v1_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/olserra/agent-semantic-protocol/internal/apicheck"
+)
+
+// TestAPISurface fails if an identifier recorded in api.txt disappears, or
+// if a new one is exported without being recorded.  Removals break the v1
+// compatibility promise; additions only need api.txt updated.
+func TestAPISurface(t *testing.T) {
+	current, err := apicheck.Exported(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden, err := apicheck.ReadGolden("api.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	removed, added := apicheck.Diff(golden, current)
+	if len(removed) > 0 {
+		t.Errorf("v1 API removed (breaking change):\n\t%s", strings.Join(removed, "\n\t"))
+	}
+	if len(added) > 0 {
+		t.Errorf("v1 API added; record it in api.txt:\n\t%s", strings.Join(added, "\n\t"))
+	}
+}
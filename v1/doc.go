@@ -0,0 +1,25 @@
+// Package v1 is the stable Go API of the Agent Semantic Protocol.
+//
+// The core and p2p packages export everything the implementation needs,
+// including wire-level helpers and types that are still evolving.  This
+// package re-exports the curated subset that applications should build on:
+// agents and identities, the protocol messages, hosts and their options,
+// negotiation handlers, discovery, trust, and workflow orchestration.
+//
+// # Compatibility
+//
+// Everything exported here follows semantic versioning: within v1, names
+// are never removed or renamed, function signatures never change, and
+// struct fields are only added.  The full surface is recorded in api.txt and
+// checked by the package tests, so a breaking change fails CI rather than a
+// downstream build.
+//
+// Types are aliases of their core and p2p counterparts, so values move
+// freely between this package and the lower-level ones when an application
+// needs something not (yet) covered here.  Identifiers used only through
+// core or p2p carry no compatibility promise.
+//
+//	agent, _ := v1.NewAgent("summariser", []string{"summarisation"})
+//	host, _ := v1.NewHost(ctx, agent, v1.WithSignaturePolicy(v1.RequireSigned))
+//	host.OnIntent(func(from v1.PeerID, in *v1.IntentMessage) *v1.NegotiationResponse { ... })
+package v1
@@ -0,0 +1,102 @@
+package wasmplugin
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// encodeAgentProfile serialises the ABI-visible fields of an AgentProfile
+// (PluginAgentProfile in proto/asp.proto).
+func encodeAgentProfile(a core.AgentProfile) []byte {
+	var b []byte
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	b = protowire.AppendString(b, a.AgentID)
+	b = protowire.AppendTag(b, 2, protowire.BytesType)
+	b = protowire.AppendString(b, a.DID)
+	for _, c := range a.Capabilities {
+		b = protowire.AppendTag(b, 3, protowire.BytesType)
+		b = protowire.AppendString(b, c)
+	}
+	if len(a.EmbeddingVector) > 0 {
+		packed := make([]byte, 0, 4*len(a.EmbeddingVector))
+		for _, f := range a.EmbeddingVector {
+			packed = protowire.AppendFixed32(packed, math.Float32bits(f))
+		}
+		b = protowire.AppendTag(b, 4, protowire.BytesType)
+		b = protowire.AppendBytes(b, packed)
+	}
+	return b
+}
+
+// encodeRegistryResult builds a PluginRegistryResult.
+func encodeRegistryResult(agents []core.AgentProfile) []byte {
+	var b []byte
+	for _, a := range agents {
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendBytes(b, encodeAgentProfile(a))
+	}
+	return b
+}
+
+// encodeSelectRequest builds a PluginSelectRequest.
+func encodeSelectRequest(intent *core.IntentMessage, candidates []core.AgentProfile) ([]byte, error) {
+	in, err := intent.Encode()
+	if err != nil {
+		return nil, fmt.Errorf("wasmplugin: encode intent: %w", err)
+	}
+	var b []byte
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	b = protowire.AppendBytes(b, in)
+	for _, c := range candidates {
+		b = protowire.AppendTag(b, 2, protowire.BytesType)
+		b = protowire.AppendBytes(b, encodeAgentProfile(c))
+	}
+	return b, nil
+}
+
+// decodeSelectResponse parses a PluginSelectResponse.  Both packed and
+// unpacked encodings of the repeated order field are accepted.
+func decodeSelectResponse(data []byte) ([]int32, error) {
+	var order []int32
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, fmt.Errorf("select: invalid tag")
+		}
+		data = data[n:]
+
+		switch {
+		case num == 1 && typ == protowire.VarintType:
+			v, n2 := protowire.ConsumeVarint(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("select: invalid order")
+			}
+			order = append(order, int32(v))
+			data = data[n2:]
+		case num == 1 && typ == protowire.BytesType:
+			packed, n2 := protowire.ConsumeBytes(data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("select: invalid order")
+			}
+			for len(packed) > 0 {
+				v, n3 := protowire.ConsumeVarint(packed)
+				if n3 < 0 {
+					return nil, fmt.Errorf("select: invalid order")
+				}
+				order = append(order, int32(v))
+				packed = packed[n3:]
+			}
+			data = data[n2:]
+		default:
+			n2 := protowire.ConsumeFieldValue(num, typ, data)
+			if n2 < 0 {
+				return nil, fmt.Errorf("select: unknown field %d", num)
+			}
+			data = data[n2:]
+		}
+	}
+	return order, nil
+}
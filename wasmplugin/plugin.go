@@ -0,0 +1,287 @@
+// Package wasmplugin loads negotiation handlers and candidate selectors from
+// WebAssembly modules at runtime, so an agent's policy can be replaced
+// without recompiling the agent binary.
+//
+// # Host ABI (version 1)
+//
+// All structured values cross the boundary in the protobuf wire format of
+// proto/asp.proto.  A result is returned as a uint64 packing the guest
+// pointer in the high 32 bits and the length in the low 32 bits; 0 means
+// "no result".
+//
+// Guest exports:
+//
+//	memory                                   linear memory
+//	asp_alloc(size u32) -> ptr u32           allocate size bytes for the host to write into
+//	asp_negotiate(ptr, len u32) -> u64       optional: IntentMessage -> NegotiationResponse
+//	asp_select(ptr, len u32) -> u64          optional: PluginSelectRequest -> PluginSelectResponse
+//
+// Host imports (module "asp"):
+//
+//	registry_find(ptr, len u32) -> u64       capability string -> PluginRegistryResult
+//	log(ptr, len u32)                        write a UTF-8 line to the plugin's logger
+package wasmplugin
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+)
+
+// ABIVersion is the host ABI implemented by this package.
+const ABIVersion = 1
+
+// Guest export and host import names.
+const (
+	exportAlloc     = "asp_alloc"
+	exportNegotiate = "asp_negotiate"
+	exportSelect    = "asp_select"
+	hostModule      = "asp"
+)
+
+// Plugin is one instantiated WASM module.  Calls into the module are
+// serialised; a Plugin is safe for concurrent use.
+type Plugin struct {
+	mu       sync.Mutex
+	rt       wazero.Runtime
+	mod      api.Module
+	registry *core.DiscoveryRegistry
+	logf     func(format string, args ...interface{})
+	timeout  time.Duration
+	pages    uint32
+	closed   bool
+}
+
+// Option configures a Plugin at load time.
+type Option func(*Plugin)
+
+// WithRegistry answers the guest's registry_find calls from reg.
+// Without it, registry_find returns no agents.
+func WithRegistry(reg *core.DiscoveryRegistry) Option {
+	return func(p *Plugin) { p.registry = reg }
+}
+
+// WithLogger receives the guest's log calls.  The default discards them.
+func WithLogger(logf func(format string, args ...interface{})) Option {
+	return func(p *Plugin) { p.logf = logf }
+}
+
+// WithTimeout bounds every call into the guest.  The default is one second.
+func WithTimeout(d time.Duration) Option {
+	return func(p *Plugin) { p.timeout = d }
+}
+
+// WithMemoryLimitPages caps guest memory in 64 KiB pages.  The default is
+// 256 pages (16 MiB).
+func WithMemoryLimitPages(n uint32) Option {
+	return func(p *Plugin) { p.pages = n }
+}
+
+// Load compiles and instantiates a WASM module.
+func Load(ctx context.Context, wasm []byte, opts ...Option) (*Plugin, error) {
+	p := &Plugin{
+		logf:    func(string, ...interface{}) {},
+		timeout: time.Second,
+		pages:   256,
+	}
+	for _, o := range opts {
+		o(p)
+	}
+
+	cfg := wazero.NewRuntimeConfig().
+		WithMemoryLimitPages(p.pages).
+		WithCloseOnContextDone(true)
+	p.rt = wazero.NewRuntimeWithConfig(ctx, cfg)
+
+	_, err := p.rt.NewHostModuleBuilder(hostModule).
+		NewFunctionBuilder().WithFunc(p.hostRegistryFind).Export("registry_find").
+		NewFunctionBuilder().WithFunc(p.hostLog).Export("log").
+		Instantiate(ctx)
+	if err != nil {
+		_ = p.rt.Close(ctx)
+		return nil, fmt.Errorf("wasmplugin: host module: %w", err)
+	}
+	compiled, err := p.rt.CompileModule(ctx, wasm)
+	if err != nil {
+		_ = p.rt.Close(ctx)
+		return nil, fmt.Errorf("wasmplugin: compile: %w", err)
+	}
+	p.mod, err = p.rt.InstantiateModule(ctx, compiled, wazero.NewModuleConfig().WithName(""))
+	if err != nil {
+		_ = p.rt.Close(ctx)
+		return nil, fmt.Errorf("wasmplugin: instantiate: %w", err)
+	}
+	if p.mod.Memory() == nil || p.mod.ExportedFunction(exportAlloc) == nil {
+		_ = p.rt.Close(ctx)
+		return nil, fmt.Errorf("wasmplugin: module must export memory and %s", exportAlloc)
+	}
+	return p, nil
+}
+
+// LoadFile reads and loads the WASM module at path.
+func LoadFile(ctx context.Context, path string, opts ...Option) (*Plugin, error) {
+	wasm, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("wasmplugin: read %s: %w", path, err)
+	}
+	return Load(ctx, wasm, opts...)
+}
+
+// Close releases the module and its runtime.  It waits for a call in
+// progress to finish; later calls fail.
+func (p *Plugin) Close(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil
+	}
+	p.closed = true
+	return p.rt.Close(ctx)
+}
+
+// HasNegotiate reports whether the module exports asp_negotiate.
+func (p *Plugin) HasNegotiate() bool { return p.mod.ExportedFunction(exportNegotiate) != nil }
+
+// HasSelect reports whether the module exports asp_select.
+func (p *Plugin) HasSelect() bool { return p.mod.ExportedFunction(exportSelect) != nil }
+
+// Negotiate runs the guest's asp_negotiate on intent.  The guest's response
+// is completed with agent's identity and signed, so it is indistinguishable
+// on the wire from one produced by a native handler.
+func (p *Plugin) Negotiate(agent *core.Agent, intent *core.IntentMessage) (*core.NegotiationResponse, error) {
+	in, err := intent.Encode()
+	if err != nil {
+		return nil, fmt.Errorf("wasmplugin: encode intent: %w", err)
+	}
+	out, err := p.call(exportNegotiate, in)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := core.DecodeNegotiationResponse(out)
+	if err != nil {
+		return nil, fmt.Errorf("wasmplugin: decode response: %w", err)
+	}
+	resp.RequestID = intent.ID
+	resp.AgentID = agent.ID
+	resp.DID = agent.DID.String()
+	if resp.Timestamp == 0 {
+		resp.Timestamp = time.Now().UnixNano()
+	}
+	if err = core.SignResponse(agent, resp); err != nil {
+		return nil, fmt.Errorf("wasmplugin: %w", err)
+	}
+	return resp, nil
+}
+
+// NegotiationHandler adapts the plugin to core.NegotiationHandler.
+func (p *Plugin) NegotiationHandler(agent *core.Agent) core.NegotiationHandler {
+	return func(intent *core.IntentMessage) (*core.NegotiationResponse, error) {
+		return p.Negotiate(agent, intent)
+	}
+}
+
+// Select orders candidates for intent using the guest's asp_select.  Indices
+// the guest omits are dropped; out-of-range or repeated indices are an error.
+func (p *Plugin) Select(intent *core.IntentMessage, candidates []core.AgentProfile) ([]core.AgentProfile, error) {
+	in, err := encodeSelectRequest(intent, candidates)
+	if err != nil {
+		return nil, err
+	}
+	out, err := p.call(exportSelect, in)
+	if err != nil {
+		return nil, err
+	}
+	order, err := decodeSelectResponse(out)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[int32]bool, len(order))
+	ranked := make([]core.AgentProfile, 0, len(order))
+	for _, i := range order {
+		if i < 0 || int(i) >= len(candidates) || seen[i] {
+			return nil, fmt.Errorf("wasmplugin: select returned invalid index %d", i)
+		}
+		seen[i] = true
+		ranked = append(ranked, candidates[i])
+	}
+	return ranked, nil
+}
+
+// ------------------------------------------------------------------ guest calls
+
+// call copies in into guest memory, invokes fn, and copies the result out.
+func (p *Plugin) call(fn string, in []byte) ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil, fmt.Errorf("wasmplugin: plugin is closed")
+	}
+
+	f := p.mod.ExportedFunction(fn)
+	if f == nil {
+		return nil, fmt.Errorf("wasmplugin: module does not export %s", fn)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	ptr, err := p.write(ctx, p.mod, in)
+	if err != nil {
+		return nil, err
+	}
+	res, err := f.Call(ctx, uint64(ptr), uint64(len(in)))
+	if err != nil {
+		return nil, fmt.Errorf("wasmplugin: %s: %w", fn, err)
+	}
+	if len(res) != 1 || res[0] == 0 {
+		return nil, fmt.Errorf("wasmplugin: %s returned no result", fn)
+	}
+	outPtr, outLen := uint32(res[0]>>32), uint32(res[0])
+	out, ok := p.mod.Memory().Read(outPtr, outLen)
+	if !ok {
+		return nil, fmt.Errorf("wasmplugin: %s result out of bounds", fn)
+	}
+	return append([]byte(nil), out...), nil
+}
+
+// write allocates len(b) bytes in m via asp_alloc and copies b there.
+func (p *Plugin) write(ctx context.Context, m api.Module, b []byte) (uint32, error) {
+	res, err := m.ExportedFunction(exportAlloc).Call(ctx, uint64(len(b)))
+	if err != nil {
+		return 0, fmt.Errorf("wasmplugin: %s: %w", exportAlloc, err)
+	}
+	ptr := uint32(res[0])
+	if !m.Memory().Write(ptr, b) {
+		return 0, fmt.Errorf("wasmplugin: %s returned out-of-bounds pointer", exportAlloc)
+	}
+	return ptr, nil
+}
+
+// ------------------------------------------------------------------ host functions
+
+func (p *Plugin) hostRegistryFind(ctx context.Context, m api.Module, ptr, n uint32) uint64 {
+	capability, ok := m.Memory().Read(ptr, n)
+	if !ok || p.registry == nil {
+		return 0
+	}
+	out := encodeRegistryResult(p.registry.FindByCapability(string(capability)))
+	if len(out) == 0 {
+		return 0
+	}
+	dst, err := p.write(ctx, m, out)
+	if err != nil {
+		return 0
+	}
+	return uint64(dst)<<32 | uint64(len(out))
+}
+
+func (p *Plugin) hostLog(_ context.Context, m api.Module, ptr, n uint32) {
+	if b, ok := m.Memory().Read(ptr, n); ok {
+		p.logf("wasmplugin: %s", b)
+	}
+}
@@ -0,0 +1,169 @@
+package wasmplugin_test
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/wasmplugin"
+)
+
+// The modules under testdata are assembled from the .wat files next to them.
+
+func loadPlugin(t *testing.T, name string, opts ...wasmplugin.Option) *wasmplugin.Plugin {
+	t.Helper()
+	p, err := wasmplugin.LoadFile(context.Background(), "testdata/"+name, opts...)
+	if err != nil {
+		t.Fatalf("LoadFile(%s): %v", name, err)
+	}
+	t.Cleanup(func() { _ = p.Close(context.Background()) })
+	return p
+}
+
+func TestPluginNegotiate(t *testing.T) {
+	reg := core.NewDiscoveryRegistry()
+	reg.Announce(core.AgentProfile{AgentID: "scanner", DID: "did:key:scanner", Capabilities: []string{"ocr"}}, 60)
+	var (
+		mu   sync.Mutex
+		logs []string
+	)
+	p := loadPlugin(t, "policy.wasm", wasmplugin.WithRegistry(reg), wasmplugin.WithLogger(func(format string, args ...interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+		logs = append(logs, fmt.Sprintf(format, args...))
+	}))
+	if !p.HasNegotiate() || !p.HasSelect() {
+		t.Fatal("exports not detected")
+	}
+
+	agent, _ := core.NewAgent("a", nil)
+	intent, _ := core.CreateIntent(agent, []float32{1, 0}, []string{"ocr"}, "scan.png")
+	resp, err := p.Negotiate(agent, intent)
+	if err != nil {
+		t.Fatalf("Negotiate: %v", err)
+	}
+	if !resp.Accepted || resp.Reason != "plugin" || !slices.Equal(resp.WorkflowSteps, []string{"plan"}) {
+		t.Errorf("response: %+v", resp)
+	}
+	if resp.RequestID != intent.ID || resp.DID != agent.DID.String() || !core.VerifyResponseSignature(resp, agent.PublicKey()) {
+		t.Errorf("response not completed and signed: %+v", resp)
+	}
+
+	// The guest logged the registry_find result, which names the agent.
+	mu.Lock()
+	defer mu.Unlock()
+	if len(logs) != 1 || !strings.Contains(logs[0], "scanner") {
+		t.Errorf("guest logs: %q", logs)
+	}
+}
+
+func TestPluginSelect(t *testing.T) {
+	p := loadPlugin(t, "policy.wasm")
+	agent, _ := core.NewAgent("a", nil)
+	intent, _ := core.CreateIntent(agent, nil, []string{"ocr"}, "")
+	ranked, err := p.Select(intent, []core.AgentProfile{{AgentID: "first"}, {AgentID: "second"}})
+	if err != nil {
+		t.Fatalf("Select: %v", err)
+	}
+	if len(ranked) != 2 || ranked[0].AgentID != "second" || ranked[1].AgentID != "first" {
+		t.Errorf("ranked: %+v", ranked)
+	}
+
+	// The guest's order names index 1, which a single candidate lacks.
+	if _, err = p.Select(intent, []core.AgentProfile{{AgentID: "only"}}); err == nil {
+		t.Error("expected an error for an out-of-range index")
+	}
+}
+
+func TestLoadRejectsInvalidModules(t *testing.T) {
+	ctx := context.Background()
+	if _, err := wasmplugin.Load(ctx, []byte("not wasm")); err == nil {
+		t.Error("loaded garbage")
+	}
+	// An empty module exports neither memory nor asp_alloc.
+	if _, err := wasmplugin.Load(ctx, []byte("\x00asm\x01\x00\x00\x00")); err == nil {
+		t.Error("loaded a module without the required exports")
+	}
+}
+
+func TestPluginCallTimeout(t *testing.T) {
+	p := loadPlugin(t, "spin.wasm", wasmplugin.WithTimeout(50*time.Millisecond))
+	if p.HasSelect() {
+		t.Error("spin.wasm has no asp_select")
+	}
+	agent, _ := core.NewAgent("a", nil)
+	intent, _ := core.CreateIntent(agent, nil, []string{"ocr"}, "")
+
+	start := time.Now()
+	if _, err := p.Negotiate(agent, intent); err == nil {
+		t.Fatal("a guest that never returns produced a response")
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("call returned after %s", d)
+	}
+}
+
+// TestSlotSwapDuringCall verifies that swapping a plugin out waits for its
+// call in progress instead of closing the runtime underneath it.
+func TestSlotSwapDuringCall(t *testing.T) {
+	ctx := context.Background()
+	reg := core.NewDiscoveryRegistry()
+	reg.Announce(core.AgentProfile{AgentID: "scanner", Capabilities: []string{"ocr"}}, 60)
+	// The guest logs mid-call; the logger holds the call there.
+	entered, release := make(chan struct{}), make(chan struct{})
+	old, err := wasmplugin.LoadFile(ctx, "testdata/policy.wasm", wasmplugin.WithRegistry(reg),
+		wasmplugin.WithTimeout(5*time.Second),
+		wasmplugin.WithLogger(func(string, ...interface{}) {
+			close(entered)
+			<-release
+		}))
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	var s wasmplugin.Slot
+	if err = s.Swap(ctx, old); err != nil {
+		t.Fatalf("Swap: %v", err)
+	}
+	agent, _ := core.NewAgent("a", nil)
+	intent, _ := core.CreateIntent(agent, nil, []string{"ocr"}, "")
+	handler := s.NegotiationHandler(agent)
+
+	type result struct {
+		resp *core.NegotiationResponse
+		err  error
+	}
+	calls := make(chan result, 1)
+	go func() {
+		resp, err := handler(intent)
+		calls <- result{resp, err}
+	}()
+	<-entered
+
+	swapped := make(chan error, 1)
+	go func() { swapped <- s.Reload(ctx, "testdata/policy.wasm") }()
+	select {
+	case err = <-swapped:
+		close(release)
+		t.Fatalf("Reload closed the old plugin during a call: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+
+	if r := <-calls; r.err != nil || !r.resp.Accepted {
+		t.Errorf("call in progress: %+v, %v", r.resp, r.err)
+	}
+	if err = <-swapped; err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if _, err = old.Negotiate(agent, intent); err == nil {
+		t.Error("a closed plugin answered")
+	}
+	if resp, err := handler(intent); err != nil || !resp.Accepted {
+		t.Errorf("after reload: %+v, %v", resp, err)
+	}
+}
@@ -0,0 +1,70 @@
+package wasmplugin
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+)
+
+// Slot holds the currently active Plugin and lets it be replaced while the
+// agent is running.  Handlers obtained from a Slot always call the plugin
+// that is active at call time.
+type Slot struct {
+	mu     sync.RWMutex
+	plugin *Plugin
+}
+
+// Swap installs p and closes the previously active plugin, if any, once
+// its call in progress has returned.
+func (s *Slot) Swap(ctx context.Context, p *Plugin) error {
+	s.mu.Lock()
+	old := s.plugin
+	s.plugin = p
+	s.mu.Unlock()
+	if old != nil {
+		return old.Close(ctx)
+	}
+	return nil
+}
+
+// Reload loads the module at path and swaps it in.  On failure the active
+// plugin is left untouched.
+func (s *Slot) Reload(ctx context.Context, path string, opts ...Option) error {
+	p, err := LoadFile(ctx, path, opts...)
+	if err != nil {
+		return err
+	}
+	return s.Swap(ctx, p)
+}
+
+func (s *Slot) current() (*Plugin, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.plugin == nil {
+		return nil, fmt.Errorf("wasmplugin: no plugin loaded")
+	}
+	return s.plugin, nil
+}
+
+// NegotiationHandler returns a handler that delegates to the active plugin.
+func (s *Slot) NegotiationHandler(agent *core.Agent) core.NegotiationHandler {
+	return func(intent *core.IntentMessage) (*core.NegotiationResponse, error) {
+		p, err := s.current()
+		if err != nil {
+			return nil, err
+		}
+		return p.Negotiate(agent, intent)
+	}
+}
+
+// Select orders candidates with the active plugin, falling back to
+// core.RankCandidates when no plugin is loaded or it has no asp_select.
+func (s *Slot) Select(intent *core.IntentMessage, candidates []core.AgentProfile) ([]core.AgentProfile, error) {
+	p, err := s.current()
+	if err != nil || !p.HasSelect() {
+		return core.RankCandidates(intent.IntentVector, candidates), nil
+	}
+	return p.Select(intent, candidates)
+}
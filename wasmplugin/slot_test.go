@@ -0,0 +1,30 @@
+package wasmplugin_test
+
+import (
+	"testing"
+
+	"github.com/olserra/agent-semantic-protocol/core"
+	"github.com/olserra/agent-semantic-protocol/wasmplugin"
+)
+
+func TestSlotWithoutPlugin(t *testing.T) {
+	var s wasmplugin.Slot
+	agent, _ := core.NewAgent("a", nil)
+	intent, _ := core.CreateIntent(agent, []float32{1, 0}, []string{"x"}, "")
+
+	if _, err := s.NegotiationHandler(agent)(intent); err == nil {
+		t.Error("expected an error with no plugin loaded")
+	}
+
+	candidates := []core.AgentProfile{
+		{AgentID: "far", EmbeddingVector: []float32{0, 1}},
+		{AgentID: "near", EmbeddingVector: []float32{1, 0}},
+	}
+	ranked, err := s.Select(intent, candidates)
+	if err != nil {
+		t.Fatalf("Select: %v", err)
+	}
+	if len(ranked) != 2 || ranked[0].AgentID != "near" {
+		t.Errorf("expected fallback to cosine ranking, got %+v", ranked)
+	}
+}
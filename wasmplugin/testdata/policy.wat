@@ -0,0 +1,38 @@
+;; policy.wat — test plugin for the wasmplugin host ABI.
+;;
+;; asp_negotiate looks up "ocr" agents through registry_find, logs the raw
+;; PluginRegistryResult if there is one, and accepts every intent with a
+;; one-step plan.  asp_select ranks two candidates in reverse order.
+;;
+;; Rebuild with: wat2wasm policy.wat -o policy.wasm
+(module
+  (import "asp" "registry_find" (func $registry_find (param i32 i32) (result i64)))
+  (import "asp" "log" (func $log (param i32 i32)))
+
+  (memory (export "memory") 1)
+  (global $heap (mut i32) (i32.const 1024))
+
+  ;; Bump allocator that starts over when the page is used up.
+  (func (export "asp_alloc") (param $size i32) (result i32) (local $p i32)
+    (if (i32.gt_u (i32.add (global.get $heap) (local.get $size)) (i32.const 65536))
+      (then (global.set $heap (i32.const 1024))))
+    (local.set $p (global.get $heap))
+    (global.set $heap (i32.add (global.get $heap) (local.get $size)))
+    (local.get $p))
+
+  (func (export "asp_negotiate") (param $ptr i32) (param $len i32) (result i64) (local $r i64)
+    (local.set $r (call $registry_find (i32.const 16) (i32.const 3)))
+    (if (i64.ne (local.get $r) (i64.const 0))
+      (then (call $log
+        (i32.wrap_i64 (i64.shr_u (local.get $r) (i64.const 32)))
+        (i32.wrap_i64 (local.get $r)))))
+    ;; NegotiationResponse{accepted: true, reason: "plugin", workflow_steps: ["plan"]}
+    (i64.const 0x0000002000000010))
+
+  (func (export "asp_select") (param $ptr i32) (param $len i32) (result i64)
+    ;; PluginSelectResponse{order: [1, 0]}
+    (i64.const 0x0000004000000004))
+
+  (data (i32.const 16) "ocr")
+  (data (i32.const 32) "\18\01\42\06plugin\22\04plan")
+  (data (i32.const 64) "\0a\02\01\00"))
@@ -0,0 +1,13 @@
+;; spin.wat — test plugin whose asp_negotiate never returns, for exercising
+;; the call timeout.
+;;
+;; Rebuild with: wat2wasm spin.wat -o spin.wasm
+(module
+  (memory (export "memory") 1)
+
+  (func (export "asp_alloc") (param $size i32) (result i32)
+    (i32.const 1024))
+
+  (func (export "asp_negotiate") (param $ptr i32) (param $len i32) (result i64)
+    (loop $forever (br $forever))
+    (unreachable)))